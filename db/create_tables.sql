@@ -1,30 +1,143 @@
 CREATE TABLE "User" (
     uuid UUID UNIQUE NOT NULL PRIMARY KEY,
     email VARCHAR(255) UNIQUE NOT NULL,
-    password CHAR(60) NOT NULL,
+    password VARCHAR(255) NOT NULL,
     first_name VARCHAR(50),
     last_name VARCHAR(50),
     is_active BOOLEAN NOT NULL DEFAULT FALSE,
     is_superuser BOOLEAN NOT NULL DEFAULT FALSE,
-    created_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+    created_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    password_changed_at TIMESTAMP DEFAULT NULL,
+    activation_sent_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE ServiceAccount (
+    uuid UUID UNIQUE NOT NULL PRIMARY KEY,
+    owner_uuid UUID NOT NULL REFERENCES "User"(uuid),
+    name VARCHAR(150) NOT NULL,
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    scopes TEXT[] NOT NULL DEFAULT '{admin}',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    UNIQUE (owner_uuid, name)
 );
 
 Create TABLE APIKey (
     id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
     user_uuid UUID NOT NULL REFERENCES "User"(uuid),
     prefix CHAR(8),
-    hashed_key VARCHAR(150),
+    hashed_key VARCHAR(150) NOT NULL DEFAULT '',
+    key_digest VARCHAR(64) NOT NULL DEFAULT '',
     name VARCHAR(150) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{admin}',
     created_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
     expires_at TIMESTAMP DEFAULT NULL,
+    previous_prefix VARCHAR(8) NOT NULL DEFAULT '',
+    previous_hashed_key VARCHAR(150) NOT NULL DEFAULT '',
+    previous_key_digest VARCHAR(64) NOT NULL DEFAULT '',
+    previous_expires_at TIMESTAMP DEFAULT NULL,
+    last_used_at TIMESTAMP DEFAULT NULL,
+    last_used_ip VARCHAR(45) NOT NULL DEFAULT '',
+    request_count BIGINT NOT NULL DEFAULT 0,
+    allowed_cidrs TEXT[] NOT NULL DEFAULT '{}',
+    allowed_origins TEXT[] NOT NULL DEFAULT '{}',
+    service_account_uuid UUID DEFAULT NULL REFERENCES ServiceAccount(uuid) ON DELETE CASCADE,
     UNIQUE (user_uuid, name)
 );
 
+CREATE UNIQUE INDEX APIKeyKeyDigestIndex ON APIKey (key_digest) WHERE key_digest <> '';
+CREATE INDEX APIKeyPreviousKeyDigestIndex ON APIKey (previous_key_digest) WHERE previous_key_digest <> '';
+
+CREATE TABLE AuditLog (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    event_type VARCHAR(50) NOT NULL,
+    user_uuid UUID NOT NULL REFERENCES "User"(uuid) ON DELETE CASCADE,
+    actor_uuid UUID REFERENCES "User"(uuid) ON DELETE SET NULL,
+    service_account_uuid UUID REFERENCES ServiceAccount(uuid) ON DELETE SET NULL,
+    api_key_id INT REFERENCES APIKey(id) ON DELETE SET NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+);
+
+CREATE INDEX AuditLogUserUUIDIndex ON AuditLog (user_uuid, created_at);
+
+CREATE TABLE APIKeyUsage (
+    api_key_id INT NOT NULL REFERENCES APIKey(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    request_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (api_key_id, day)
+);
+
+CREATE TABLE Session (
+    id UUID UNIQUE NOT NULL PRIMARY KEY,
+    user_uuid UUID NOT NULL REFERENCES "User"(uuid),
+    refresh_jti UUID NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    last_used_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE TOTPDevice (
+    user_uuid UUID UNIQUE NOT NULL PRIMARY KEY REFERENCES "User"(uuid),
+    secret VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    confirmed_at TIMESTAMP DEFAULT NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE RecoveryCode (
+    id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_uuid UUID NOT NULL REFERENCES "User"(uuid),
+    hashed_code VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    used_at TIMESTAMP DEFAULT NULL,
+    UNIQUE (user_uuid, hashed_code)
+);
+
+CREATE TABLE IssuedJWT (
+    jti UUID UNIQUE NOT NULL PRIMARY KEY,
+    user_uuid UUID NOT NULL REFERENCES "User"(uuid),
+    token_type VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP DEFAULT NULL,
+    attempt_count INT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE OIDCAuthRequest (
+    state VARCHAR(64) UNIQUE NOT NULL PRIMARY KEY,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE LoginThrottle (
+    scope VARCHAR(16) NOT NULL,
+    identifier VARCHAR(255) NOT NULL,
+    failure_count INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP DEFAULT NULL,
+    PRIMARY KEY (scope, identifier)
+);
+
+CREATE TABLE RateLimitBucket (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
 Create TABLE Flag (
     id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
     user_uuid UUID NOT NULL REFERENCES "User"(uuid),
     name VARCHAR(150) NOT NULL,
     is_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    is_client_side BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
     updated_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
     UNIQUE (user_uuid, name)
@@ -42,3 +155,16 @@ CREATE TRIGGER Flag_updated_at
     BEFORE UPDATE ON Flag
     FOR EACH ROW
     EXECUTE PROCEDURE trigger_set_timestamp();
+
+CREATE OR REPLACE FUNCTION refill_rate_limit_bucket(
+    tokens DOUBLE PRECISION,
+    updated_at TIMESTAMP,
+    token_limit DOUBLE PRECISION,
+    refill_rate DOUBLE PRECISION,
+    now TIMESTAMP
+)
+    RETURNS DOUBLE PRECISION AS $$
+    BEGIN
+        RETURN LEAST(token_limit, tokens + GREATEST(EXTRACT(EPOCH FROM (now - updated_at))::DOUBLE PRECISION, 0) * refill_rate);
+    END;
+    $$ LANGUAGE plpgsql IMMUTABLE;
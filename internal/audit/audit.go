@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// EventType represents the type of a security-relevant event recorded in the audit log.
+type EventType string
+
+const (
+	EventTypeAPIKeyIPDenied            EventType = "api_key.ip_denied"
+	EventTypeAPIKeyOriginDenied        EventType = "api_key.origin_denied"
+	EventTypeAdminUsersListed          EventType = "admin.users_listed"
+	EventTypeAdminUserViewed           EventType = "admin.user_viewed"
+	EventTypeAdminUserUpdated          EventType = "admin.user_updated"
+	EventTypeAdminPasswordResetForced  EventType = "admin.password_reset_forced"
+	EventTypeAdminCredentialsRevoked   EventType = "admin.credentials_revoked"
+	EventTypeAdminFlagsViewed          EventType = "admin.flags_viewed"
+	EventTypeAdminImpersonationStarted EventType = "admin.impersonation_started"
+	EventTypeImpersonatedRequest       EventType = "impersonation.request"
+)
+
+// AuditLog represents database table of audit log entries.
+// Events caused by service accounts record the service account UUID alongside the owner User UUID.
+// Events caused by superusers acting on other Users record the superuser's UUID as the actor UUID.
+type AuditLog struct {
+	ID                 int64       `db:"id"`
+	EventType          string      `db:"event_type"`
+	UserUUID           string      `db:"user_uuid"`
+	ActorUUID          pgtype.Text `db:"actor_uuid"`
+	ServiceAccountUUID pgtype.Text `db:"service_account_uuid"`
+	APIKeyID           pgtype.Int4 `db:"api_key_id"`
+	IPAddress          string      `db:"ip_address"`
+	Detail             string      `db:"detail"`
+	CreatedAt          time.Time   `db:"created_at"`
+}
@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository is used to access and update audit log data.
+type Repository interface {
+	CreateAuditLog(dbConn *pgxpool.Conn, auditLog *AuditLog) (*AuditLog, error)
+	ListAuditLogsByUserUUID(dbConn *pgxpool.Conn, userUUID string) ([]*AuditLog, error)
+}
+
+// repository implements Repository.
+type repository struct{}
+
+// NewRepository returns a new repository.
+func NewRepository() *repository {
+	return &repository{}
+}
+
+// CreateAuditLog creates audit log entry from event type, User UUID, actor UUID, service account UUID, API key ID, IP address, and detail.
+func (repo *repository) CreateAuditLog(dbConn *pgxpool.Conn, auditLog *AuditLog) (*AuditLog, error) {
+	createdAuditLog := &AuditLog{}
+
+	q := `
+INSERT INTO AuditLog (
+	event_type,
+	user_uuid,
+	actor_uuid,
+	service_account_uuid,
+	api_key_id,
+	ip_address,
+	detail
+)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7
+)
+RETURNING
+	id,
+	event_type,
+	user_uuid,
+	actor_uuid,
+	service_account_uuid,
+	api_key_id,
+	ip_address,
+	detail,
+	created_at;
+	`
+
+	err := dbConn.QueryRow(
+		context.Background(),
+		q,
+		auditLog.EventType,
+		auditLog.UserUUID,
+		auditLog.ActorUUID,
+		auditLog.ServiceAccountUUID,
+		auditLog.APIKeyID,
+		auditLog.IPAddress,
+		auditLog.Detail,
+	).Scan(
+		&createdAuditLog.ID,
+		&createdAuditLog.EventType,
+		&createdAuditLog.UserUUID,
+		&createdAuditLog.ActorUUID,
+		&createdAuditLog.ServiceAccountUUID,
+		&createdAuditLog.APIKeyID,
+		&createdAuditLog.IPAddress,
+		&createdAuditLog.Detail,
+		&createdAuditLog.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("CreateAuditLog failed to dbConn.Scan: %w", err)
+	}
+
+	return createdAuditLog, nil
+}
+
+// ListAuditLogsByUserUUID fetches audit log entries under a given User UUID, oldest first.
+func (repo *repository) ListAuditLogsByUserUUID(dbConn *pgxpool.Conn, userUUID string) ([]*AuditLog, error) {
+	auditLogs := make([]*AuditLog, 0)
+
+	q := `
+SELECT
+	id,
+	event_type,
+	user_uuid,
+	actor_uuid,
+	service_account_uuid,
+	api_key_id,
+	ip_address,
+	detail,
+	created_at
+FROM
+	AuditLog
+WHERE
+	user_uuid = $1
+ORDER BY
+	created_at,
+	id;
+	`
+
+	rows, err := dbConn.Query(context.Background(), q, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ListAuditLogsByUserUUID failed to dbConn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		auditLog := &AuditLog{}
+		err := rows.Scan(
+			&auditLog.ID,
+			&auditLog.EventType,
+			&auditLog.UserUUID,
+			&auditLog.ActorUUID,
+			&auditLog.ServiceAccountUUID,
+			&auditLog.APIKeyID,
+			&auditLog.IPAddress,
+			&auditLog.Detail,
+			&auditLog.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ListAuditLogsByUserUUID failed to rows.Scan: %w", err)
+		}
+
+		auditLogs = append(auditLogs, auditLog)
+	}
+
+	return auditLogs, nil
+}
@@ -0,0 +1,206 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alvii147/flagger-api/internal/audit"
+	"github.com/alvii147/flagger-api/internal/auth"
+	"github.com/alvii147/flagger-api/internal/testkitinternal"
+	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryCreateAuditLogSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := audit.NewRepository()
+
+	auditLog := &audit.AuditLog{
+		EventType: string(audit.EventTypeAPIKeyIPDenied),
+		UserUUID:  user.UUID,
+		APIKeyID: pgtype.Int4{
+			Int32: int32(apiKey.ID),
+			Valid: true,
+		},
+		IPAddress: "203.0.113.42",
+		Detail:    "GET /api/flags",
+	}
+
+	now := time.Now().UTC()
+	createdAuditLog, err := repo.CreateAuditLog(dbConn, auditLog)
+	require.NoError(t, err)
+
+	require.Equal(t, auditLog.EventType, createdAuditLog.EventType)
+	require.Equal(t, auditLog.UserUUID, createdAuditLog.UserUUID)
+	require.Equal(t, auditLog.APIKeyID, createdAuditLog.APIKeyID)
+	require.Equal(t, auditLog.IPAddress, createdAuditLog.IPAddress)
+	require.Equal(t, auditLog.Detail, createdAuditLog.Detail)
+	testkit.RequireTimeAlmostEqual(t, now, createdAuditLog.CreatedAt)
+}
+
+func TestRepositoryCreateAuditLogWithoutAPIKey(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := audit.NewRepository()
+
+	createdAuditLog, err := repo.CreateAuditLog(dbConn, &audit.AuditLog{
+		EventType: string(audit.EventTypeAPIKeyIPDenied),
+		UserUUID:  user.UUID,
+	})
+	require.NoError(t, err)
+	require.False(t, createdAuditLog.ActorUUID.Valid)
+	require.False(t, createdAuditLog.APIKeyID.Valid)
+	require.Empty(t, createdAuditLog.IPAddress)
+	require.Empty(t, createdAuditLog.Detail)
+}
+
+func TestRepositoryCreateAuditLogWithActor(t *testing.T) {
+	t.Parallel()
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := audit.NewRepository()
+
+	_, err := repo.CreateAuditLog(dbConn, &audit.AuditLog{
+		EventType: string(audit.EventTypeAdminUserUpdated),
+		UserUUID:  user.UUID,
+		ActorUUID: pgtype.Text{
+			String: superUser.UUID,
+			Valid:  true,
+		},
+		IPAddress: "203.0.113.42",
+		Detail:    "is_active=false is_superuser=false",
+	})
+	require.NoError(t, err)
+
+	auditLogs, err := repo.ListAuditLogsByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Len(t, auditLogs, 1)
+	require.Equal(t, string(audit.EventTypeAdminUserUpdated), auditLogs[0].EventType)
+	require.Equal(t, user.UUID, auditLogs[0].UserUUID)
+	require.Equal(t, pgtype.Text{String: superUser.UUID, Valid: true}, auditLogs[0].ActorUUID)
+	require.False(t, auditLogs[0].APIKeyID.Valid)
+}
+
+func TestRepositoryCreateAuditLogNonExistentUser(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := audit.NewRepository()
+
+	_, err := repo.CreateAuditLog(dbConn, &audit.AuditLog{
+		EventType: string(audit.EventTypeAPIKeyIPDenied),
+		UserUUID:  "7b2dd2c1-3b9b-4a5b-95b1-a9a4ba0a3b2e",
+	})
+	require.Error(t, err)
+}
+
+func TestRepositoryListAuditLogsByUserUUID(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := audit.NewRepository()
+
+	ipAddresses := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"}
+	for _, ipAddress := range ipAddresses {
+		_, err := repo.CreateAuditLog(dbConn, &audit.AuditLog{
+			EventType: string(audit.EventTypeAPIKeyIPDenied),
+			UserUUID:  user.UUID,
+			APIKeyID: pgtype.Int4{
+				Int32: int32(apiKey.ID),
+				Valid: true,
+			},
+			IPAddress: ipAddress,
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := repo.CreateAuditLog(dbConn, &audit.AuditLog{
+		EventType: string(audit.EventTypeAPIKeyIPDenied),
+		UserUUID:  otherUser.UUID,
+	})
+	require.NoError(t, err)
+
+	auditLogs, err := repo.ListAuditLogsByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Len(t, auditLogs, len(ipAddresses))
+
+	for i, auditLog := range auditLogs {
+		require.Equal(t, user.UUID, auditLog.UserUUID)
+		require.Equal(t, ipAddresses[i], auditLog.IPAddress)
+	}
+
+	auditLogs, err = repo.ListAuditLogsByUserUUID(dbConn, otherUser.UUID)
+	require.NoError(t, err)
+	require.Len(t, auditLogs, 1)
+}
+
+func TestRepositoryAuditLogKeptAfterAPIKeyDeletion(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := audit.NewRepository()
+
+	_, err := repo.CreateAuditLog(dbConn, &audit.AuditLog{
+		EventType: string(audit.EventTypeAPIKeyIPDenied),
+		UserUUID:  user.UUID,
+		APIKeyID: pgtype.Int4{
+			Int32: int32(apiKey.ID),
+			Valid: true,
+		},
+	})
+	require.NoError(t, err)
+
+	err = auth.NewRepository().DeleteAPIKey(dbConn, apiKey.ID, user.UUID)
+	require.NoError(t, err)
+
+	auditLogs, err := repo.ListAuditLogsByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Len(t, auditLogs, 1)
+	require.False(t, auditLogs[0].APIKeyID.Valid)
+}
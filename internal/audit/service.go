@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alvii147/flagger-api/pkg/logging"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Service performs all audit log related business logic.
+type Service interface {
+	Record(ctx context.Context, auditLog *AuditLog) (*AuditLog, error)
+	ListAuditLogs(ctx context.Context, userUUID string) ([]*AuditLog, error)
+}
+
+// service implements Service.
+type service struct {
+	dbPool     *pgxpool.Pool
+	logger     logging.Logger
+	repository Repository
+}
+
+// NewService returns a new service.
+func NewService(dbPool *pgxpool.Pool, logger logging.Logger, repo Repository) *service {
+	return &service{
+		dbPool:     dbPool,
+		logger:     logger,
+		repository: repo,
+	}
+}
+
+// Record records an entry in the audit log.
+// Failures are also logged, since callers typically record events on paths where they cannot report errors.
+func (svc *service) Record(ctx context.Context, auditLog *AuditLog) (*AuditLog, error) {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		err = fmt.Errorf("Record failed to svc.dbPool.Acquire: %w", err)
+		svc.logger.LogError(err)
+
+		return nil, err
+	}
+	defer dbConn.Release()
+
+	auditLog, err = svc.repository.CreateAuditLog(dbConn, auditLog)
+	if err != nil {
+		err = fmt.Errorf("Record failed to svc.repository.CreateAuditLog: %w", err)
+		svc.logger.LogError(err)
+
+		return nil, err
+	}
+
+	return auditLog, nil
+}
+
+// ListAuditLogs retrieves audit log entries for a given User, oldest first.
+func (svc *service) ListAuditLogs(ctx context.Context, userUUID string) ([]*AuditLog, error) {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListAuditLogs failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	auditLogs, err := svc.repository.ListAuditLogsByUserUUID(dbConn, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ListAuditLogs failed to svc.repository.ListAuditLogsByUserUUID: %w", err)
+	}
+
+	return auditLogs, nil
+}
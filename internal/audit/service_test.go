@@ -0,0 +1,77 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alvii147/flagger-api/internal/audit"
+	"github.com/alvii147/flagger-api/internal/auth"
+	"github.com/alvii147/flagger-api/internal/testkitinternal"
+	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceRecordSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	repo := audit.NewRepository()
+	svc := audit.NewService(dbPool, logger, repo)
+
+	now := time.Now().UTC()
+	auditLog, err := svc.Record(context.Background(), &audit.AuditLog{
+		EventType: string(audit.EventTypeAPIKeyIPDenied),
+		UserUUID:  user.UUID,
+		IPAddress: "203.0.113.42",
+		Detail:    "GET /api/flags",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, string(audit.EventTypeAPIKeyIPDenied), auditLog.EventType)
+	require.Equal(t, user.UUID, auditLog.UserUUID)
+	testkit.RequireTimeAlmostEqual(t, now, auditLog.CreatedAt)
+
+	auditLogs, err := svc.ListAuditLogs(context.Background(), user.UUID)
+	require.NoError(t, err)
+	require.Len(t, auditLogs, 1)
+	require.Equal(t, auditLog.ID, auditLogs[0].ID)
+}
+
+func TestServiceRecordError(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, bufErr, logger := testkit.CreateTestLogger()
+	repo := audit.NewRepository()
+	svc := audit.NewService(dbPool, logger, repo)
+
+	_, err := svc.Record(context.Background(), &audit.AuditLog{
+		EventType: string(audit.EventTypeAPIKeyIPDenied),
+		UserUUID:  "7b2dd2c1-3b9b-4a5b-95b1-a9a4ba0a3b2e",
+	})
+	require.Error(t, err)
+	require.Contains(t, bufErr.String(), "Record failed to svc.repository.CreateAuditLog")
+}
+
+func TestServiceListAuditLogsEmpty(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	repo := audit.NewRepository()
+	svc := audit.NewService(dbPool, logger, repo)
+
+	auditLogs, err := svc.ListAuditLogs(context.Background(), user.UUID)
+	require.NoError(t, err)
+	require.Empty(t, auditLogs)
+}
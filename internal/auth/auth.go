@@ -1,81 +1,379 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"net/netip"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/alvii147/flagger-api/internal/env"
 	"github.com/alvii147/flagger-api/internal/templatesmanager"
 	"github.com/alvii147/flagger-api/pkg/api"
+	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/alvii147/flagger-api/pkg/jwks"
 	"github.com/alvii147/flagger-api/pkg/mailclient"
+	"github.com/alvii147/flagger-api/pkg/oidc"
+	"github.com/alvii147/flagger-api/pkg/passhash"
+	"github.com/alvii147/flagger-api/pkg/totp"
 	"github.com/alvii147/flagger-api/pkg/utils"
+	"github.com/alvii147/flagger-api/pkg/validate"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // User represents database table of users.
 type User struct {
+	UUID              string           `db:"uuid"`
+	Email             string           `db:"email"`
+	Password          string           `db:"password"`
+	FirstName         string           `db:"first_name"`
+	LastName          string           `db:"last_name"`
+	IsActive          bool             `db:"is_active"`
+	IsSuperUser       bool             `db:"is_superuser"`
+	CreatedAt         time.Time        `db:"created_at"`
+	PasswordChangedAt pgtype.Timestamp `db:"password_changed_at"`
+	ActivationSentAt  pgtype.Timestamp `db:"activation_sent_at"`
+}
+
+// APIKey represents database table of API keys.
+type APIKey struct {
+	ID                 int              `db:"id"`
+	UserUUID           string           `db:"user_uuid"`
+	Prefix             string           `db:"prefix"`
+	HashedKey          string           `db:"hashed_key"`
+	KeyDigest          string           `db:"key_digest"`
+	Name               string           `db:"name"`
+	Scopes             []string         `db:"scopes"`
+	CreatedAt          time.Time        `db:"created_at"`
+	ExpiresAt          pgtype.Timestamp `db:"expires_at"`
+	PreviousPrefix     string           `db:"previous_prefix"`
+	PreviousHashedKey  string           `db:"previous_hashed_key"`
+	PreviousKeyDigest  string           `db:"previous_key_digest"`
+	PreviousExpiresAt  pgtype.Timestamp `db:"previous_expires_at"`
+	LastUsedAt         pgtype.Timestamp `db:"last_used_at"`
+	LastUsedIP         string           `db:"last_used_ip"`
+	RequestCount       int64            `db:"request_count"`
+	AllowedCIDRs       []string         `db:"allowed_cidrs"`
+	AllowedOrigins     []string         `db:"allowed_origins"`
+	ServiceAccountUUID pgtype.Text      `db:"service_account_uuid"`
+}
+
+// ServiceAccount represents database table of service accounts.
+// Service accounts are non-login principals owned by a User account,
+// that hold their own API keys and scopes.
+type ServiceAccount struct {
 	UUID        string    `db:"uuid"`
-	Email       string    `db:"email"`
-	Password    string    `db:"password"`
-	FirstName   string    `db:"first_name"`
-	LastName    string    `db:"last_name"`
+	OwnerUUID   string    `db:"owner_uuid"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	Scopes      []string  `db:"scopes"`
 	IsActive    bool      `db:"is_active"`
-	IsSuperUser bool      `db:"is_superuser"`
 	CreatedAt   time.Time `db:"created_at"`
 }
 
-// APIKey represents database table of API keys.
-type APIKey struct {
-	ID        int              `db:"id"`
-	UserUUID  string           `db:"user_uuid"`
-	Prefix    string           `db:"prefix"`
-	HashedKey string           `db:"hashed_key"`
-	Name      string           `db:"name"`
-	CreatedAt time.Time        `db:"created_at"`
-	ExpiresAt pgtype.Timestamp `db:"expires_at"`
+// APIKeyUsage represents database table of daily API key request counts.
+type APIKeyUsage struct {
+	APIKeyID     int       `db:"api_key_id"`
+	Day          time.Time `db:"day"`
+	RequestCount int64     `db:"request_count"`
+}
+
+// IssuedJWT represents database table of issued single-use JWTs.
+type IssuedJWT struct {
+	JTI          string           `db:"jti"`
+	UserUUID     string           `db:"user_uuid"`
+	TokenType    string           `db:"token_type"`
+	CreatedAt    time.Time        `db:"created_at"`
+	ExpiresAt    time.Time        `db:"expires_at"`
+	ConsumedAt   pgtype.Timestamp `db:"consumed_at"`
+	AttemptCount int              `db:"attempt_count"`
+}
+
+// Session represents database table of User authentication sessions.
+// Each session tracks the only refresh JWT that can currently be used to refresh it.
+type Session struct {
+	ID         string           `db:"id"`
+	UserUUID   string           `db:"user_uuid"`
+	RefreshJTI string           `db:"refresh_jti"`
+	IPAddress  string           `db:"ip_address"`
+	UserAgent  string           `db:"user_agent"`
+	CreatedAt  time.Time        `db:"created_at"`
+	LastUsedAt time.Time        `db:"last_used_at"`
+	ExpiresAt  time.Time        `db:"expires_at"`
+	RevokedAt  pgtype.Timestamp `db:"revoked_at"`
+}
+
+// TOTPDevice represents database table of User TOTP authenticators.
+// A device only protects the User's account once it has been confirmed.
+type TOTPDevice struct {
+	UserUUID     string           `db:"user_uuid"`
+	Secret       string           `db:"secret"`
+	CreatedAt    time.Time        `db:"created_at"`
+	ConfirmedAt  pgtype.Timestamp `db:"confirmed_at"`
+	LastUsedStep int64            `db:"last_used_step"`
+}
+
+// RecoveryCode represents database table of hashed single-use two-factor authentication recovery codes.
+type RecoveryCode struct {
+	ID         int              `db:"id"`
+	UserUUID   string           `db:"user_uuid"`
+	HashedCode string           `db:"hashed_code"`
+	CreatedAt  time.Time        `db:"created_at"`
+	UsedAt     pgtype.Timestamp `db:"used_at"`
+}
+
+// OIDCAuthRequest represents database table of pending OpenID Connect authorization requests.
+type OIDCAuthRequest struct {
+	State        string    `db:"state"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// LoginThrottle represents database table of failed login attempts for an account or an IP address.
+type LoginThrottle struct {
+	Scope        string           `db:"scope"`
+	Identifier   string           `db:"identifier"`
+	FailureCount int              `db:"failure_count"`
+	LastFailedAt time.Time        `db:"last_failed_at"`
+	LockedUntil  pgtype.Timestamp `db:"locked_until"`
 }
 
+// LoginThrottleScope is a string representing what failed login attempts are counted against.
+// Allowed strings are "account" and "ip".
+type LoginThrottleScope string
+
+const (
+	LoginThrottleScopeAccount LoginThrottleScope = "account"
+	LoginThrottleScopeIP      LoginThrottleScope = "ip"
+)
+
 // JWTType is a string representing type of JWT.
-// Allowed strings are "access", "refresh", and "activation".
+// Allowed strings are "access", "refresh", "activation", "password_reset", "email_change", and "totp_challenge".
 type JWTType string
 
 const (
-	JWTTypeAccess     JWTType = "access"
-	JWTTypeRefresh    JWTType = "refresh"
-	JWTTypeActivation JWTType = "activation"
+	JWTTypeAccess        JWTType = "access"
+	JWTTypeRefresh       JWTType = "refresh"
+	JWTTypeActivation    JWTType = "activation"
+	JWTTypePasswordReset JWTType = "password_reset"
+	JWTTypeEmailChange   JWTType = "email_change"
+	JWTTypeMagicLink     JWTType = "magic_link"
+	JWTTypeTOTPChallenge JWTType = "totp_challenge"
 )
 
+// APIKeyScope is a string representing a permission granted to an API key.
+// Allowed strings are "flags:client", "flags:evaluate", "flags:read", "flags:write", "user:read", and "admin".
+type APIKeyScope string
+
+const (
+	APIKeyScopeFlagsClient   APIKeyScope = "flags:client"
+	APIKeyScopeFlagsEvaluate APIKeyScope = "flags:evaluate"
+	APIKeyScopeFlagsRead     APIKeyScope = "flags:read"
+	APIKeyScopeFlagsWrite    APIKeyScope = "flags:write"
+	APIKeyScopeUserRead      APIKeyScope = "user:read"
+	APIKeyScopeAdmin         APIKeyScope = "admin"
+)
+
+// apiKeyScopeImplications maps each API key scope to the scopes it implies.
+// Admin implies every other scope, so API keys created before scopes existed keep full access.
+var apiKeyScopeImplications = map[APIKeyScope][]APIKeyScope{
+	APIKeyScopeFlagsClient:   {},
+	APIKeyScopeFlagsEvaluate: {},
+	APIKeyScopeFlagsRead:     {APIKeyScopeFlagsEvaluate},
+	APIKeyScopeFlagsWrite:    {APIKeyScopeFlagsRead, APIKeyScopeFlagsEvaluate},
+	APIKeyScopeUserRead:      {},
+	APIKeyScopeAdmin:         {APIKeyScopeFlagsClient, APIKeyScopeFlagsEvaluate, APIKeyScopeFlagsRead, APIKeyScopeFlagsWrite, APIKeyScopeUserRead},
+}
+
+// activationResendCooldown is the minimum time between two activation emails sent to the same User.
+const activationResendCooldown = time.Minute
+
+// magicLinkCooldown is the minimum time between two magic link emails sent to the same User.
+const magicLinkCooldown = time.Minute
+
+// passwordResetCooldown is the minimum time between two password reset emails sent to the same User.
+const passwordResetCooldown = time.Minute
+
+// sessionTouchInterval is the minimum time between two updates of a session's last use time.
+const sessionTouchInterval = time.Minute
+
+// sessionUserAgentMaxLength is the maximum number of characters of a client's user agent stored in a session.
+const sessionUserAgentMaxLength = 512
+
+// totpIssuer is the issuer name shown in authenticator apps.
+const totpIssuer = "Flagger"
+
+// totpSkew is the number of time steps of clock drift tolerated when validating TOTP codes.
+const totpSkew = 1
+
+// totpChallengeLifetime is the lifetime of challenge JWTs issued to Users with two-factor authentication enabled.
+const totpChallengeLifetime = 5 * time.Minute
+
+// totpChallengeMaxAttempts is the number of codes that can be tried against a single challenge JWT.
+const totpChallengeMaxAttempts = 5
+
+// recoveryCodeCount is the number of recovery codes generated for a User when two-factor authentication is enabled.
+const recoveryCodeCount = 10
+
+// recoveryCodeLength is the number of characters in each recovery code, excluding the separator.
+const recoveryCodeLength = 10
+
+// oidcAuthRequestLifetime is the time within which a User must complete single sign-on after it is started.
+const oidcAuthRequestLifetime = 10 * time.Minute
+
+// loginFailureWindow is the time without failed login attempts after which earlier failures are forgotten.
+const loginFailureWindow = 24 * time.Hour
+
+// loginAccountFailureThreshold is the number of failed login attempts for an account before it is locked.
+const loginAccountFailureThreshold = 5
+
+// loginIPFailureThreshold is the number of failed login attempts from an IP address before it is locked.
+// It is higher than the account threshold since many Users may share an IP address.
+const loginIPFailureThreshold = 20
+
+// loginLockoutBaseDuration is the duration of the first lockout, which doubles with every further failed attempt.
+const loginLockoutBaseDuration = time.Minute
+
+// loginLockoutMaxDuration is the maximum duration of a lockout.
+const loginLockoutMaxDuration = time.Hour
+
+// impersonationLifetime is the lifetime of access JWTs issued to superusers impersonating other Users.
+const impersonationLifetime = 15 * time.Minute
+
+// userNameMaxLength is the maximum number of characters stored in a User's first or last name.
+const userNameMaxLength = 50
+
 // AuthContextKey is a string representing context keys.
 type AuthContextKey string
 
 // AuthContextKeyUserUUID is the key in context where User UUID is stored after authentication.
 const AuthContextKeyUserUUID AuthContextKey = "userUUID"
 
-// hashPassword hashes given password using a given hashing cost.
-func hashPassword(password string, hashingCost int) (string, error) {
-	hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(password), hashingCost)
+// AuthContextKeySessionID is the key in context where session ID is stored after JWT authentication.
+const AuthContextKeySessionID AuthContextKey = "sessionID"
+
+// AuthContextKeyActorUUID is the key in context where superuser UUID is stored
+// after authentication using an impersonation JWT.
+const AuthContextKeyActorUUID AuthContextKey = "actorUUID"
+
+// AuthContextKeyAPIKeyScopes is the key in context where API key scopes are stored after API key authentication.
+const AuthContextKeyAPIKeyScopes AuthContextKey = "apiKeyScopes"
+
+// AuthContextKeyAPIKeyID is the key in context where API key ID is stored after API key authentication.
+const AuthContextKeyAPIKeyID AuthContextKey = "apiKeyID"
+
+// AuthContextKeyServiceAccountUUID is the key in context where service account UUID is stored
+// after authentication using a service account's API key.
+const AuthContextKeyServiceAccountUUID AuthContextKey = "serviceAccountUUID"
+
+// DigestPepper returns the key used to compute API key and recovery code digests.
+// The configured digest pepper is used when set, otherwise the secret key is used,
+// so that digests computed before the digest pepper was introduced are still valid.
+func DigestPepper(config *env.Config) string {
+	if config.DigestPepper == "" {
+		return config.SecretKey
+	}
+
+	return config.DigestPepper
+}
+
+// NewKeySet creates the KeySet used to sign and validate access and refresh JWTs.
+// Access and refresh JWTs are signed using the configured signing key file,
+// and JWTs signed by any of the configured verification key files are still accepted.
+// When no signing key file is configured, JWTs are signed using HS256 and the secret key.
+// JWTs signed using HS256 and the secret key are also accepted when verifying the secret key is enabled,
+// so that existing sessions survive migrating from HS256 to a signing key file.
+func NewKeySet(config *env.Config) (*jwks.KeySet, error) {
+	if config.JWTSigningKeyFile == "" {
+		keySet, err := jwks.NewKeySet(jwks.NewHMACKey([]byte(config.SecretKey)))
+		if err != nil {
+			return nil, fmt.Errorf("NewKeySet failed to jwks.NewKeySet: %w", err)
+		}
+
+		return keySet, nil
+	}
+
+	signingKey, err := jwks.ReadKeyFile(config.JWTSigningKeyFile)
 	if err != nil {
-		return "", fmt.Errorf("hashPassword failed to bcrypt.GenerateFromPassword: %w", err)
+		return nil, fmt.Errorf("NewKeySet failed to jwks.ReadKeyFile: %w", err)
+	}
+
+	verificationKeys := make([]*jwks.Key, 0)
+	for _, path := range strings.Split(config.JWTVerificationKeyFiles, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		key, err := jwks.ReadKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("NewKeySet failed to jwks.ReadKeyFile: %w", err)
+		}
+
+		verificationKeys = append(verificationKeys, key)
+	}
+
+	if config.JWTVerifySecretKey {
+		verificationKeys = append(verificationKeys, jwks.NewHMACKey([]byte(config.SecretKey)))
 	}
-	hashedPassword := string(hashedPasswordBytes)
 
-	return hashedPassword, nil
+	keySet, err := jwks.NewKeySet(signingKey, verificationKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("NewKeySet failed to jwks.NewKeySet: %w", err)
+	}
+
+	return keySet, nil
 }
 
-// createAuthJWT creates JWTs for User authentication of given type.
+// NewPasswordHasher creates the Hasher used for User passwords.
+// New passwords are hashed using the configured hasher type,
+// and passwords hashed using any supported hasher can still be verified.
+func NewPasswordHasher(config *env.Config) passhash.Hasher {
+	argon2idHasher := passhash.NewArgon2idHasher(
+		uint32(config.Argon2idMemory),
+		uint32(config.Argon2idIterations),
+		uint8(config.Argon2idParallelism),
+	)
+	bcryptHasher := passhash.NewBcryptHasher(config.HashingCost)
+
+	if config.PasswordHasherType == passhash.HasherTypeBcrypt {
+		return passhash.NewMultiHasher(bcryptHasher, argon2idHasher)
+	}
+
+	return passhash.NewMultiHasher(argon2idHasher, bcryptHasher)
+}
+
+// NewPasswordPolicy creates the PasswordPolicy that new User passwords must satisfy.
+func NewPasswordPolicy(config *env.Config) *validate.PasswordPolicy {
+	return &validate.PasswordPolicy{
+		MinLength:           config.PasswordMinLength,
+		MinCharacterClasses: config.PasswordMinCharClasses,
+		RejectPersonalInfo:  config.PasswordRejectPersonalInfo,
+		RejectBreached:      config.PasswordRejectBreached,
+	}
+}
+
+// createAuthJWT creates JWTs for User authentication of given type under a given session,
+// and returns the JWT along with its claims.
 // Returns error when token type is not access or refresh.
 func createAuthJWT(
 	userUUID string,
+	sessionID string,
 	tokenType JWTType,
-	secretKey string,
+	keySet *jwks.KeySet,
 	accessLifetime time.Duration,
 	refreshLifetime time.Duration,
-) (string, error) {
+) (string, *api.AuthJWTClaims, error) {
 	var lifetime time.Duration
 	switch tokenType {
 	case JWTTypeAccess:
@@ -83,38 +381,90 @@ func createAuthJWT(
 	case JWTTypeRefresh:
 		lifetime = refreshLifetime
 	default:
-		return "", fmt.Errorf("createAuthJWT received invalid JWT type %s, expected JWT type %s or %s", tokenType, JWTTypeAccess, JWTTypeRefresh)
+		return "", nil, fmt.Errorf("createAuthJWT received invalid JWT type %s, expected JWT type %s or %s", tokenType, JWTTypeAccess, JWTTypeRefresh)
 	}
 
 	now := time.Now().UTC()
-	token := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		&api.AuthJWTClaims{
-			Subject:   userUUID,
-			TokenType: string(tokenType),
-			IssuedAt:  utils.JSONTimeStamp(now),
-			ExpiresAt: utils.JSONTimeStamp(now.Add(lifetime)),
-			JWTID:     uuid.NewString(),
+	claims := &api.AuthJWTClaims{
+		Subject:   userUUID,
+		SessionID: sessionID,
+		TokenType: string(tokenType),
+		IssuedAt:  utils.JSONTimeStamp(now),
+		ExpiresAt: utils.JSONTimeStamp(now.Add(lifetime)),
+		JWTID:     uuid.NewString(),
+	}
+	signedToken, err := keySet.Sign(claims)
+	if err != nil {
+		return "", nil, fmt.Errorf("createAuthJWT failed to keySet.Sign for user.UUID %s of token type %s: %w", userUUID, tokenType, err)
+	}
+
+	return signedToken, claims, nil
+}
+
+// loginAccountIdentifier returns the identifier failed login attempts for a given email are counted against.
+func loginAccountIdentifier(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginLockoutDuration computes how long login attempts are locked after a given number of consecutive failures.
+// No lockout is applied below the threshold, and the lockout doubles with every failure beyond it.
+func loginLockoutDuration(failureCount int, threshold int) time.Duration {
+	if failureCount < threshold {
+		return 0
+	}
+
+	lockout := loginLockoutBaseDuration
+	for i := threshold; i < failureCount && lockout < loginLockoutMaxDuration; i++ {
+		lockout *= 2
+	}
+
+	if lockout > loginLockoutMaxDuration {
+		lockout = loginLockoutMaxDuration
+	}
+
+	return lockout
+}
+
+// createImpersonationJWT creates an access JWT for a superuser to impersonate a User under the superuser's session,
+// and returns the JWT along with its claims.
+// The superuser's UUID is stored in the actor claim.
+func createImpersonationJWT(
+	userUUID string,
+	actorUUID string,
+	sessionID string,
+	readOnly bool,
+	keySet *jwks.KeySet,
+	lifetime time.Duration,
+) (string, *api.AuthJWTClaims, error) {
+	now := time.Now().UTC()
+	claims := &api.AuthJWTClaims{
+		Subject:   userUUID,
+		SessionID: sessionID,
+		TokenType: string(JWTTypeAccess),
+		IssuedAt:  utils.JSONTimeStamp(now),
+		ExpiresAt: utils.JSONTimeStamp(now.Add(lifetime)),
+		JWTID:     uuid.NewString(),
+		Actor: &api.ActorClaims{
+			Subject: actorUUID,
 		},
-	)
-	signedToken, err := token.SignedString([]byte(secretKey))
+		ReadOnly: readOnly,
+	}
+	signedToken, err := keySet.Sign(claims)
 	if err != nil {
-		return "", fmt.Errorf("createAuthJWTWithType failed to token.SignedString for user.UUID %s of token type %s: %w", userUUID, tokenType, err)
+		return "", nil, fmt.Errorf("createImpersonationJWT failed to keySet.Sign for user.UUID %s by actor %s: %w", userUUID, actorUUID, err)
 	}
 
-	return signedToken, nil
+	return signedToken, claims, nil
 }
 
 // validateAuthJWT validates JWT for User authentication,
 // checks that the JWT is not expired,
 // and returns parsed JWT claims.
-func validateAuthJWT(token string, tokenType JWTType, secretKey string) (*api.AuthJWTClaims, bool) {
+func validateAuthJWT(token string, tokenType JWTType, keySet *jwks.KeySet) (*api.AuthJWTClaims, bool) {
 	claims := &api.AuthJWTClaims{}
 	ok := true
 
-	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
-		return []byte(secretKey), nil
-	})
+	parsedToken, err := keySet.Parse(token, claims)
 
 	if err != nil {
 		ok = false
@@ -139,25 +489,82 @@ func validateAuthJWT(token string, tokenType JWTType, secretKey string) (*api.Au
 	return claims, true
 }
 
-// createActivationJWTWithType creates JWT for User activation.
-func createActivationJWT(userUUID string, secretKey string, lifetime time.Duration) (string, error) {
+// truncateUserAgent truncates user agent to the maximum number of characters stored in a session.
+func truncateUserAgent(userAgent string) string {
+	runes := []rune(userAgent)
+	if len(runes) <= sessionUserAgentMaxLength {
+		return userAgent
+	}
+
+	return string(runes[:sessionUserAgentMaxLength])
+}
+
+// truncateUserName truncates first or last name to the maximum number of characters stored for a User.
+func truncateUserName(name string) string {
+	runes := []rune(name)
+	if len(runes) <= userNameMaxLength {
+		return name
+	}
+
+	return string(runes[:userNameMaxLength])
+}
+
+// createOIDCAuthRequest creates OpenID Connect authorization request with random state, nonce, and PKCE code verifier.
+func createOIDCAuthRequest(lifetime time.Duration) (*OIDCAuthRequest, error) {
+	state, err := utils.GenerateRandomString(32, true, true, true)
+	if err != nil {
+		return nil, fmt.Errorf("createOIDCAuthRequest failed to utils.GenerateRandomString state: %w", err)
+	}
+
+	nonce, err := utils.GenerateRandomString(32, true, true, true)
+	if err != nil {
+		return nil, fmt.Errorf("createOIDCAuthRequest failed to utils.GenerateRandomString nonce: %w", err)
+	}
+
+	codeVerifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		return nil, fmt.Errorf("createOIDCAuthRequest failed to oidc.GenerateCodeVerifier: %w", err)
+	}
+
+	now := time.Now().UTC()
+	authRequest := &OIDCAuthRequest{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(lifetime),
+	}
+
+	return authRequest, nil
+}
+
+// createActivationJWT creates JWT for User activation,
+// and returns the issued JWT to be recorded.
+func createActivationJWT(userUUID string, secretKey string, lifetime time.Duration) (string, *IssuedJWT, error) {
 	now := time.Now().UTC()
+	issuedJWT := &IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  userUUID,
+		TokenType: string(JWTTypeActivation),
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+	}
 	token := jwt.NewWithClaims(
 		jwt.SigningMethodHS256,
 		&api.ActivationJWTClaims{
 			Subject:   userUUID,
 			TokenType: string(JWTTypeActivation),
-			IssuedAt:  utils.JSONTimeStamp(now),
-			ExpiresAt: utils.JSONTimeStamp(now.Add(lifetime)),
-			JWTID:     uuid.NewString(),
+			IssuedAt:  utils.JSONTimeStamp(issuedJWT.CreatedAt),
+			ExpiresAt: utils.JSONTimeStamp(issuedJWT.ExpiresAt),
+			JWTID:     issuedJWT.JTI,
 		},
 	)
 	signedToken, err := token.SignedString([]byte(secretKey))
 	if err != nil {
-		return "", fmt.Errorf("createActivationJWT failed to token.SignedString for user.UUID %s of token type %s: %w", userUUID, JWTTypeActivation, err)
+		return "", nil, fmt.Errorf("createActivationJWT failed to token.SignedString for user.UUID %s of token type %s: %w", userUUID, JWTTypeActivation, err)
 	}
 
-	return signedToken, nil
+	return signedToken, issuedJWT, nil
 }
 
 // validateActivationJWT validates JWT for User activation using secret key,
@@ -194,21 +601,15 @@ func validateActivationJWT(token string, secretKey string) (*api.ActivationJWTCl
 	return claims, true
 }
 
-// sendActivationMail sends activation email to User.
+// sendActivationMail sends activation email with given activation JWT to User.
 func sendActivationMail(
 	user *User,
+	activationToken string,
 	mailClient mailclient.Client,
 	templatesManager templatesmanager.Manager,
 	frontendBaseURL string,
 	frontendActivationRoute string,
-	secretKey string,
-	jwtLifetime time.Duration,
 ) error {
-	activationToken, err := createActivationJWT(user.UUID, secretKey, jwtLifetime)
-	if err != nil {
-		return fmt.Errorf("sendActivationMail failed to createActivationJWT: %w", err)
-	}
-
 	activationURL := fmt.Sprintf(frontendBaseURL+frontendActivationRoute, activationToken)
 	tmplData := templatesmanager.ActivationEmailTemplateData{
 		RecipientEmail: user.Email,
@@ -228,8 +629,455 @@ func sendActivationMail(
 	return nil
 }
 
-// createAPIKey creates prefix, secret, and hashed key for API key.
-func createAPIKey(hashingCost int) (string, string, string, error) {
+// passwordFingerprint computes a keyed digest of when a User's password was last changed.
+// This is used to bind tokens to the password they were issued for.
+// Unlike the hashed password, this does not change when the password is rehashed.
+func passwordFingerprint(passwordChangedAt pgtype.Timestamp, secretKey string) string {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	if passwordChangedAt.Valid {
+		mac.Write([]byte(strconv.FormatInt(passwordChangedAt.Time.UnixMicro(), 10)))
+	}
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// createPasswordResetJWT creates JWT for User password reset,
+// and returns the issued JWT to be recorded.
+func createPasswordResetJWT(user *User, secretKey string, lifetime time.Duration) (string, *IssuedJWT, error) {
+	now := time.Now().UTC()
+	issuedJWT := &IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(JWTTypePasswordReset),
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+	}
+	token := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.PasswordResetJWTClaims{
+			Subject:             user.UUID,
+			TokenType:           string(JWTTypePasswordReset),
+			PasswordFingerprint: passwordFingerprint(user.PasswordChangedAt, secretKey),
+			IssuedAt:            utils.JSONTimeStamp(issuedJWT.CreatedAt),
+			ExpiresAt:           utils.JSONTimeStamp(issuedJWT.ExpiresAt),
+			JWTID:               issuedJWT.JTI,
+		},
+	)
+	signedToken, err := token.SignedString([]byte(secretKey))
+	if err != nil {
+		return "", nil, fmt.Errorf("createPasswordResetJWT failed to token.SignedString for user.UUID %s of token type %s: %w", user.UUID, JWTTypePasswordReset, err)
+	}
+
+	return signedToken, issuedJWT, nil
+}
+
+// validatePasswordResetJWT validates JWT for User password reset using secret key,
+// checks that the JWT is not expired,
+// and returns parsed JWT claims.
+func validatePasswordResetJWT(token string, secretKey string) (*api.PasswordResetJWTClaims, bool) {
+	claims := &api.PasswordResetJWTClaims{}
+	ok := true
+
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(secretKey), nil
+	})
+
+	if err != nil {
+		ok = false
+	}
+
+	if parsedToken == nil || !parsedToken.Valid {
+		ok = false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.TokenType), []byte(JWTTypePasswordReset)) == 0 {
+		ok = false
+	}
+
+	if time.Now().UTC().After(time.Time(claims.ExpiresAt)) {
+		ok = false
+	}
+
+	if !ok {
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// sendPasswordResetMail sends password reset email with given password reset JWT to User.
+func sendPasswordResetMail(
+	user *User,
+	passwordResetToken string,
+	mailClient mailclient.Client,
+	templatesManager templatesmanager.Manager,
+	frontendBaseURL string,
+	frontendPasswordResetRoute string,
+) error {
+	passwordResetURL := fmt.Sprintf(frontendBaseURL+frontendPasswordResetRoute, passwordResetToken)
+	tmplData := templatesmanager.PasswordResetEmailTemplateData{
+		RecipientEmail:   user.Email,
+		PasswordResetURL: passwordResetURL,
+	}
+
+	textTmpl, htmlTmpl, err := templatesManager.Load("password_reset")
+	if err != nil {
+		return fmt.Errorf("sendPasswordResetMail failed to templates.LoadTemplate %s: %w", "password_reset", err)
+	}
+
+	err = mailClient.Send([]string{user.Email}, "Reset your Flagger password", textTmpl, htmlTmpl, tmplData)
+	if err != nil {
+		return fmt.Errorf("sendPasswordResetMail failed to mailClient.SendMail for email %s: %w", user.Email, err)
+	}
+
+	return nil
+}
+
+// createEmailChangeJWT creates JWT for changing User email to a new email,
+// and returns the issued JWT to be recorded.
+func createEmailChangeJWT(user *User, newEmail string, secretKey string, lifetime time.Duration) (string, *IssuedJWT, error) {
+	now := time.Now().UTC()
+	issuedJWT := &IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(JWTTypeEmailChange),
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+	}
+	token := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.EmailChangeJWTClaims{
+			Subject:   user.UUID,
+			TokenType: string(JWTTypeEmailChange),
+			Email:     newEmail,
+			OldEmail:  user.Email,
+			IssuedAt:  utils.JSONTimeStamp(issuedJWT.CreatedAt),
+			ExpiresAt: utils.JSONTimeStamp(issuedJWT.ExpiresAt),
+			JWTID:     issuedJWT.JTI,
+		},
+	)
+	signedToken, err := token.SignedString([]byte(secretKey))
+	if err != nil {
+		return "", nil, fmt.Errorf("createEmailChangeJWT failed to token.SignedString for user.UUID %s of token type %s: %w", user.UUID, JWTTypeEmailChange, err)
+	}
+
+	return signedToken, issuedJWT, nil
+}
+
+// validateEmailChangeJWT validates JWT for User email change using secret key,
+// checks that the JWT is not expired,
+// and returns parsed JWT claims.
+func validateEmailChangeJWT(token string, secretKey string) (*api.EmailChangeJWTClaims, bool) {
+	claims := &api.EmailChangeJWTClaims{}
+	ok := true
+
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(secretKey), nil
+	})
+
+	if err != nil {
+		ok = false
+	}
+
+	if parsedToken == nil || !parsedToken.Valid {
+		ok = false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.TokenType), []byte(JWTTypeEmailChange)) == 0 {
+		ok = false
+	}
+
+	if time.Now().UTC().After(time.Time(claims.ExpiresAt)) {
+		ok = false
+	}
+
+	if !ok {
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// sendEmailChangeMail sends email change verification email with given email change JWT to the new email of User.
+func sendEmailChangeMail(
+	newEmail string,
+	emailChangeToken string,
+	mailClient mailclient.Client,
+	templatesManager templatesmanager.Manager,
+	frontendBaseURL string,
+	frontendEmailChangeRoute string,
+) error {
+	emailChangeURL := fmt.Sprintf(frontendBaseURL+frontendEmailChangeRoute, emailChangeToken)
+	tmplData := templatesmanager.EmailChangeEmailTemplateData{
+		RecipientEmail: newEmail,
+		EmailChangeURL: emailChangeURL,
+	}
+
+	textTmpl, htmlTmpl, err := templatesManager.Load("email_change")
+	if err != nil {
+		return fmt.Errorf("sendEmailChangeMail failed to templates.LoadTemplate %s: %w", "email_change", err)
+	}
+
+	err = mailClient.Send([]string{newEmail}, "Verify your new Flagger email", textTmpl, htmlTmpl, tmplData)
+	if err != nil {
+		return fmt.Errorf("sendEmailChangeMail failed to mailClient.SendMail for email %s: %w", newEmail, err)
+	}
+
+	return nil
+}
+
+// sendEmailChangeNoticeMail notifies the current email of User that an email change was requested.
+func sendEmailChangeNoticeMail(
+	user *User,
+	newEmail string,
+	mailClient mailclient.Client,
+	templatesManager templatesmanager.Manager,
+) error {
+	tmplData := templatesmanager.EmailChangeNoticeEmailTemplateData{
+		RecipientEmail: user.Email,
+		NewEmail:       newEmail,
+	}
+
+	textTmpl, htmlTmpl, err := templatesManager.Load("email_change_notice")
+	if err != nil {
+		return fmt.Errorf("sendEmailChangeNoticeMail failed to templates.LoadTemplate %s: %w", "email_change_notice", err)
+	}
+
+	err = mailClient.Send([]string{user.Email}, "Your Flagger email is being changed", textTmpl, htmlTmpl, tmplData)
+	if err != nil {
+		return fmt.Errorf("sendEmailChangeNoticeMail failed to mailClient.SendMail for email %s: %w", user.Email, err)
+	}
+
+	return nil
+}
+
+// sendAccountLockoutMail notifies User that their account was temporarily locked after repeated failed login attempts.
+func sendAccountLockoutMail(
+	user *User,
+	ipAddress string,
+	lockedUntil time.Time,
+	mailClient mailclient.Client,
+	templatesManager templatesmanager.Manager,
+) error {
+	tmplData := templatesmanager.AccountLockoutEmailTemplateData{
+		RecipientEmail: user.Email,
+		IPAddress:      ipAddress,
+		LockedUntil:    lockedUntil.UTC().Format("2006-01-02 15:04:05 MST"),
+	}
+
+	textTmpl, htmlTmpl, err := templatesManager.Load("account_lockout")
+	if err != nil {
+		return fmt.Errorf("sendAccountLockoutMail failed to templates.LoadTemplate %s: %w", "account_lockout", err)
+	}
+
+	err = mailClient.Send([]string{user.Email}, "Your Flagger account was temporarily locked", textTmpl, htmlTmpl, tmplData)
+	if err != nil {
+		return fmt.Errorf("sendAccountLockoutMail failed to mailClient.SendMail for email %s: %w", user.Email, err)
+	}
+
+	return nil
+}
+
+// createMagicLinkJWT creates JWT for User passwordless login,
+// and returns the issued JWT to be recorded.
+func createMagicLinkJWT(user *User, secretKey string, lifetime time.Duration) (string, *IssuedJWT, error) {
+	now := time.Now().UTC()
+	issuedJWT := &IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(JWTTypeMagicLink),
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+	}
+	token := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.MagicLinkJWTClaims{
+			Subject:   user.UUID,
+			TokenType: string(JWTTypeMagicLink),
+			Email:     user.Email,
+			IssuedAt:  utils.JSONTimeStamp(issuedJWT.CreatedAt),
+			ExpiresAt: utils.JSONTimeStamp(issuedJWT.ExpiresAt),
+			JWTID:     issuedJWT.JTI,
+		},
+	)
+	signedToken, err := token.SignedString([]byte(secretKey))
+	if err != nil {
+		return "", nil, fmt.Errorf("createMagicLinkJWT failed to token.SignedString for user.UUID %s of token type %s: %w", user.UUID, JWTTypeMagicLink, err)
+	}
+
+	return signedToken, issuedJWT, nil
+}
+
+// validateMagicLinkJWT validates JWT for User passwordless login using secret key,
+// checks that the JWT is not expired,
+// and returns parsed JWT claims.
+func validateMagicLinkJWT(token string, secretKey string) (*api.MagicLinkJWTClaims, bool) {
+	claims := &api.MagicLinkJWTClaims{}
+	ok := true
+
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(secretKey), nil
+	})
+
+	if err != nil {
+		ok = false
+	}
+
+	if parsedToken == nil || !parsedToken.Valid {
+		ok = false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.TokenType), []byte(JWTTypeMagicLink)) == 0 {
+		ok = false
+	}
+
+	if time.Now().UTC().After(time.Time(claims.ExpiresAt)) {
+		ok = false
+	}
+
+	if !ok {
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// sendMagicLinkMail sends magic link login email with given magic link JWT to User.
+func sendMagicLinkMail(
+	user *User,
+	magicLinkToken string,
+	mailClient mailclient.Client,
+	templatesManager templatesmanager.Manager,
+	frontendBaseURL string,
+	frontendMagicLinkRoute string,
+	lifetime time.Duration,
+) error {
+	magicLinkURL := fmt.Sprintf(frontendBaseURL+frontendMagicLinkRoute, magicLinkToken)
+	tmplData := templatesmanager.MagicLinkEmailTemplateData{
+		RecipientEmail: user.Email,
+		MagicLinkURL:   magicLinkURL,
+		Lifetime:       int64(lifetime / time.Minute),
+	}
+
+	textTmpl, htmlTmpl, err := templatesManager.Load("magic_link")
+	if err != nil {
+		return fmt.Errorf("sendMagicLinkMail failed to templates.LoadTemplate %s: %w", "magic_link", err)
+	}
+
+	err = mailClient.Send([]string{user.Email}, "Log in to Flagger", textTmpl, htmlTmpl, tmplData)
+	if err != nil {
+		return fmt.Errorf("sendMagicLinkMail failed to mailClient.SendMail for email %s: %w", user.Email, err)
+	}
+
+	return nil
+}
+
+// createTOTPChallengeJWT creates JWT for completing authentication of User with two-factor authentication enabled,
+// and returns the issued JWT to be recorded.
+func createTOTPChallengeJWT(userUUID string, secretKey string, lifetime time.Duration) (string, *IssuedJWT, error) {
+	now := time.Now().UTC()
+	issuedJWT := &IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  userUUID,
+		TokenType: string(JWTTypeTOTPChallenge),
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+	}
+	token := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.TOTPChallengeJWTClaims{
+			Subject:   userUUID,
+			TokenType: string(JWTTypeTOTPChallenge),
+			IssuedAt:  utils.JSONTimeStamp(issuedJWT.CreatedAt),
+			ExpiresAt: utils.JSONTimeStamp(issuedJWT.ExpiresAt),
+			JWTID:     issuedJWT.JTI,
+		},
+	)
+	signedToken, err := token.SignedString([]byte(secretKey))
+	if err != nil {
+		return "", nil, fmt.Errorf("createTOTPChallengeJWT failed to token.SignedString for user.UUID %s of token type %s: %w", userUUID, JWTTypeTOTPChallenge, err)
+	}
+
+	return signedToken, issuedJWT, nil
+}
+
+// validateTOTPChallengeJWT validates JWT for completing two-factor authentication using secret key,
+// checks that the JWT is not expired,
+// and returns parsed JWT claims.
+func validateTOTPChallengeJWT(token string, secretKey string) (*api.TOTPChallengeJWTClaims, bool) {
+	claims := &api.TOTPChallengeJWTClaims{}
+	ok := true
+
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(secretKey), nil
+	})
+
+	if err != nil {
+		ok = false
+	}
+
+	if parsedToken == nil || !parsedToken.Valid {
+		ok = false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.TokenType), []byte(JWTTypeTOTPChallenge)) == 0 {
+		ok = false
+	}
+
+	if time.Now().UTC().After(time.Time(claims.ExpiresAt)) {
+		ok = false
+	}
+
+	if !ok {
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// isTOTPCode determines whether a given two-factor authentication code is a TOTP code rather than a recovery code.
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// createRecoveryCodes creates recovery codes of the form XXXXX-XXXXX.
+func createRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := utils.GenerateRandomString(recoveryCodeLength, false, true, true)
+		if err != nil {
+			return nil, fmt.Errorf("createRecoveryCodes failed to utils.GenerateRandomString: %w", err)
+		}
+
+		codes[i] = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+	}
+
+	return codes, nil
+}
+
+// hashRecoveryCode computes a keyed digest of a recovery code.
+// Codes are normalized first, so that separators and letter case do not matter.
+func hashRecoveryCode(code string, pepper string) string {
+	normalizedCode := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	mac := hmac.New(sha256.New, []byte(pepper))
+	mac.Write([]byte(normalizedCode))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// createAPIKey creates prefix, secret, and key digest for API key.
+func createAPIKey(pepper string) (string, string, string, error) {
 	prefix, err := utils.GenerateRandomString(8, true, true, true)
 	if err != nil {
 		return "", "", "", fmt.Errorf("createAPIKey failed to utils.GenerateRandomString: %w", err)
@@ -244,15 +1092,222 @@ func createAPIKey(hashingCost int) (string, string, string, error) {
 
 	rawKey := fmt.Sprintf("%s.%s", prefix, secret)
 
-	hashedKey, err := hashPassword(rawKey, hashingCost)
-	if err != nil {
-		return "", "", "", fmt.Errorf("createAPIKey failed to hashPassword: %w", err)
+	return prefix, rawKey, apiKeyDigest(rawKey, pepper), nil
+}
+
+// apiKeyDigest computes a keyed digest of a raw API key.
+// API key secrets are 32 random bytes, so unlike passwords they do not need a slow hash to resist guessing,
+// and the digest can be used to look up API keys directly.
+func apiKeyDigest(rawKey string, pepper string) string {
+	mac := hmac.New(sha256.New, []byte(pepper))
+	mac.Write([]byte(rawKey))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// normalizeAPIKeyScopes validates and deduplicates API key scopes,
+// defaulting to admin when no scopes are given.
+// Returns false if any scope is unknown, or if the client-side scope is combined with other scopes,
+// since client-side API keys are public and must not grant anything else.
+func normalizeAPIKeyScopes(scopes []string) ([]string, bool) {
+	if len(scopes) == 0 {
+		return []string{string(APIKeyScopeAdmin)}, true
+	}
+
+	normalizedScopes := make([]string, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		_, ok := apiKeyScopeImplications[APIKeyScope(scope)]
+		if !ok {
+			return nil, false
+		}
+
+		if seen[scope] {
+			continue
+		}
+
+		seen[scope] = true
+		normalizedScopes = append(normalizedScopes, scope)
 	}
 
-	return prefix, rawKey, hashedKey, nil
+	if seen[string(APIKeyScopeFlagsClient)] && len(normalizedScopes) > 1 {
+		return nil, false
+	}
+
+	return normalizedScopes, true
+}
+
+// hasAPIKeyScope determines whether or not given API key scopes grant a required scope,
+// either directly or through a scope that implies it.
+func hasAPIKeyScope(scopes []string, requiredScope APIKeyScope) bool {
+	for _, scope := range scopes {
+		if APIKeyScope(scope) == requiredScope {
+			return true
+		}
+
+		for _, impliedScope := range apiKeyScopeImplications[APIKeyScope(scope)] {
+			if impliedScope == requiredScope {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasAPIKeyScopes determines whether or not given granted scopes grant every one of the required scopes.
+func hasAPIKeyScopes(grantedScopes []string, requiredScopes []string) bool {
+	for _, scope := range requiredScopes {
+		if !hasAPIKeyScope(grantedScopes, APIKeyScope(scope)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeAPIKeyCIDRs validates, masks, and deduplicates CIDR ranges an API key is allowed to be used from.
+// Single IP addresses are converted to single-address CIDR ranges.
+// Returns false if any CIDR range is invalid.
+func normalizeAPIKeyCIDRs(cidrs []string) ([]string, bool) {
+	normalizedCIDRs := make([]string, 0, len(cidrs))
+	seen := make(map[string]bool, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := httputils.ParseIPPrefix(cidr)
+		if err != nil {
+			return nil, false
+		}
+
+		normalizedCIDR := prefix.String()
+		if seen[normalizedCIDR] {
+			continue
+		}
+
+		seen[normalizedCIDR] = true
+		normalizedCIDRs = append(normalizedCIDRs, normalizedCIDR)
+	}
+
+	return normalizedCIDRs, true
+}
+
+// apiKeyAllowsIP determines whether or not an API key with given allowed CIDR ranges can be used from an IP address.
+// API keys without allowed CIDR ranges can be used from any IP address.
+func apiKeyAllowsIP(allowedCIDRs []string, ip string) bool {
+	if len(allowedCIDRs) == 0 {
+		return true
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(allowedCIDRs))
+	for _, cidr := range allowedCIDRs {
+		prefix, err := httputils.ParseIPPrefix(cidr)
+		if err != nil {
+			continue
+		}
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	return httputils.PrefixesContainIP(prefixes, ip)
+}
+
+// normalizeAPIKeyOrigins validates, normalizes, and deduplicates origins an API key is allowed to be used from.
+// Returns false if any origin is invalid.
+func normalizeAPIKeyOrigins(origins []string) ([]string, bool) {
+	normalizedOrigins := make([]string, 0, len(origins))
+	seen := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		normalizedOrigin, ok := httputils.NormalizeOrigin(origin)
+		if !ok {
+			return nil, false
+		}
+
+		if seen[normalizedOrigin] {
+			continue
+		}
+
+		seen[normalizedOrigin] = true
+		normalizedOrigins = append(normalizedOrigins, normalizedOrigin)
+	}
+
+	return normalizedOrigins, true
+}
+
+// apiKeyAllowsOrigin determines whether or not an API key with given allowed origins can be used from an origin.
+// API keys without allowed origins can be used from any origin,
+// and requests without an origin, such as those made by servers, are not restricted.
+func apiKeyAllowsOrigin(allowedOrigins []string, origin string) bool {
+	if len(allowedOrigins) == 0 || origin == "" {
+		return true
+	}
+
+	policy := httputils.CORSPolicy{AllowedOrigins: allowedOrigins}
+
+	return policy.AllowsOrigin(origin)
 }
 
 // parseAPIKey parses API key and returns prefix and secret if successful.
 func parseAPIKey(key string) (string, string, bool) {
 	return strings.Cut(key, ".")
 }
+
+// apiKeyUsageKey identifies the daily bucket that API key requests are counted in.
+type apiKeyUsageKey struct {
+	apiKeyID int
+	day      time.Time
+}
+
+// apiKeyUsageRecord accumulates API key requests in a daily bucket that have not yet been written to the database.
+type apiKeyUsageRecord struct {
+	requestCount int64
+	lastUsedAt   time.Time
+	lastUsedIP   string
+}
+
+// apiKeyUsageBuffer buffers API key usage in memory,
+// so that it can be written to the database in batches away from the request path.
+type apiKeyUsageBuffer struct {
+	mu      sync.Mutex
+	records map[apiKeyUsageKey]*apiKeyUsageRecord
+}
+
+// newAPIKeyUsageBuffer returns a new, empty apiKeyUsageBuffer.
+func newAPIKeyUsageBuffer() *apiKeyUsageBuffer {
+	return &apiKeyUsageBuffer{
+		records: make(map[apiKeyUsageKey]*apiKeyUsageRecord),
+	}
+}
+
+// add adds requests made using an API key at a given time from a given IP address.
+func (b *apiKeyUsageBuffer) add(apiKeyID int, usedAt time.Time, ipAddress string, requestCount int64) {
+	usedAt = usedAt.UTC()
+	key := apiKeyUsageKey{
+		apiKeyID: apiKeyID,
+		day:      usedAt.Truncate(24 * time.Hour),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	record, ok := b.records[key]
+	if !ok {
+		record = &apiKeyUsageRecord{}
+		b.records[key] = record
+	}
+
+	record.requestCount += requestCount
+	if !usedAt.Before(record.lastUsedAt) {
+		record.lastUsedAt = usedAt
+		record.lastUsedIP = ipAddress
+	}
+}
+
+// drain empties the buffer and returns the usage it held.
+func (b *apiKeyUsageBuffer) drain() map[apiKeyUsageKey]*apiKeyUsageRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	records := b.records
+	b.records = make(map[apiKeyUsageKey]*apiKeyUsageRecord)
+
+	return records
+}
@@ -4,41 +4,62 @@ import (
 	"errors"
 	"fmt"
 	htmltemplate "html/template"
+	"path/filepath"
 	"regexp"
+	"strings"
+	"sync"
 	"testing"
 	texttemplate "text/template"
 	"time"
 
 	"github.com/alvii147/flagger-api/internal/auth"
+	"github.com/alvii147/flagger-api/internal/env"
 	"github.com/alvii147/flagger-api/internal/templatesmanager"
 	"github.com/alvii147/flagger-api/internal/testkitinternal"
 	"github.com/alvii147/flagger-api/pkg/api"
+	"github.com/alvii147/flagger-api/pkg/jwks"
 	"github.com/alvii147/flagger-api/pkg/mailclient"
+	"github.com/alvii147/flagger-api/pkg/passhash"
 	"github.com/alvii147/flagger-api/pkg/testkit"
 	"github.com/alvii147/flagger-api/pkg/utils"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func TestHashPassword(t *testing.T) {
+func TestNewPasswordHasher(t *testing.T) {
 	t.Parallel()
 
+	password := testkit.GenerateFakePassword()
+
+	argon2idHashedPassword, err := passhash.NewArgon2idHasher(1024, 2, 1).Hash(password)
+	require.NoError(t, err)
+
+	bcryptHashedPassword, err := passhash.NewBcryptHasher(bcrypt.MinCost).Hash(password)
+	require.NoError(t, err)
+
 	testcases := []struct {
-		name        string
-		hashingCost int
-		wantErr     bool
+		name                    string
+		hasherType              string
+		wantHashRegexp          *regexp.Regexp
+		wantArgon2idNeedsRehash bool
+		wantBcryptNeedsRehash   bool
 	}{
 		{
-			name:        "Valid hashing cost",
-			hashingCost: 14,
-			wantErr:     false,
+			name:                    "Argon2id hasher",
+			hasherType:              passhash.HasherTypeArgon2id,
+			wantHashRegexp:          regexp.MustCompile(`^\$argon2id\$v=19\$m=1024,t=2,p=1\$`),
+			wantArgon2idNeedsRehash: false,
+			wantBcryptNeedsRehash:   true,
 		},
 		{
-			name:        "Invalid hashing cost",
-			hashingCost: 32,
-			wantErr:     true,
+			name:                    "Bcrypt hasher",
+			hasherType:              passhash.HasherTypeBcrypt,
+			wantHashRegexp:          regexp.MustCompile(`^\$2a\$04\$`),
+			wantArgon2idNeedsRehash: true,
+			wantBcryptNeedsRehash:   false,
 		},
 	}
 
@@ -46,21 +67,165 @@ func TestHashPassword(t *testing.T) {
 		t.Run(testcase.name, func(t *testing.T) {
 			t.Parallel()
 
-			password := testkit.GenerateFakePassword()
-			hashedPassword, err := auth.HashPassword(password, testcase.hashingCost)
+			hasher := auth.NewPasswordHasher(&env.Config{
+				PasswordHasherType:  testcase.hasherType,
+				HashingCost:         bcrypt.MinCost,
+				Argon2idMemory:      1024,
+				Argon2idIterations:  2,
+				Argon2idParallelism: 1,
+			})
+
+			hashedPassword, err := hasher.Hash(password)
+			require.NoError(t, err)
+			require.Regexp(t, testcase.wantHashRegexp, hashedPassword)
+			require.NoError(t, hasher.Verify(hashedPassword, password))
+			require.ErrorIs(t, hasher.Verify(hashedPassword, "1nc0rr3CTP455w0Rd"), passhash.ErrMismatchedHashAndPassword)
+
+			require.NoError(t, hasher.Verify(argon2idHashedPassword, password))
+			require.NoError(t, hasher.Verify(bcryptHashedPassword, password))
+			require.Equal(t, testcase.wantArgon2idNeedsRehash, hasher.NeedsRehash(argon2idHashedPassword))
+			require.Equal(t, testcase.wantBcryptNeedsRehash, hasher.NeedsRehash(bcryptHashedPassword))
+		})
+	}
+}
+
+func TestNewKeySet(t *testing.T) {
+	t.Parallel()
+
+	keysDir := t.TempDir()
+	signingKey := testkit.MustGenerateEd25519Key()
+	signingKeyFile := testkit.MustWriteKeyFile(keysDir, signingKey)
+	verificationKey := testkit.MustGenerateEd25519Key()
+	verificationKeyFile := testkit.MustWriteKeyFile(keysDir, verificationKey.Public())
+	otherVerificationKeyFile := testkit.MustWriteKeyFile(keysDir, testkit.MustGenerateEd25519Key())
+
+	secretKeySet, err := jwks.NewKeySet(jwks.NewHMACKey([]byte("deadbeef")))
+	require.NoError(t, err)
+
+	secretKeyToken, err := secretKeySet.Sign(&api.AuthJWTClaims{Subject: "somesubject"})
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name                  string
+		signingKeyFile        string
+		verificationKeyFiles  string
+		verifySecretKey       bool
+		wantPublishedKeyCount int
+		wantSigningMethod     jwt.SigningMethod
+		wantSecretKeyAccepted bool
+		wantErr               bool
+	}{
+		{
+			name:                  "No signing key file",
+			signingKeyFile:        "",
+			verificationKeyFiles:  "",
+			verifySecretKey:       false,
+			wantPublishedKeyCount: 0,
+			wantSigningMethod:     jwt.SigningMethodHS256,
+			wantSecretKeyAccepted: true,
+			wantErr:               false,
+		},
+		{
+			name:                  "Signing key file only",
+			signingKeyFile:        signingKeyFile,
+			verificationKeyFiles:  "",
+			verifySecretKey:       false,
+			wantPublishedKeyCount: 1,
+			wantSigningMethod:     jwt.SigningMethodEdDSA,
+			wantSecretKeyAccepted: false,
+			wantErr:               false,
+		},
+		{
+			name:                  "Signing and verification key files",
+			signingKeyFile:        signingKeyFile,
+			verificationKeyFiles:  verificationKeyFile + ", " + otherVerificationKeyFile + ",",
+			verifySecretKey:       false,
+			wantPublishedKeyCount: 3,
+			wantSigningMethod:     jwt.SigningMethodEdDSA,
+			wantSecretKeyAccepted: false,
+			wantErr:               false,
+		},
+		{
+			name:                  "Signing key file with secret key verified",
+			signingKeyFile:        signingKeyFile,
+			verificationKeyFiles:  verificationKeyFile,
+			verifySecretKey:       true,
+			wantPublishedKeyCount: 2,
+			wantSigningMethod:     jwt.SigningMethodEdDSA,
+			wantSecretKeyAccepted: true,
+			wantErr:               false,
+		},
+		{
+			name:                  "Missing signing key file",
+			signingKeyFile:        filepath.Join(keysDir, "missing.pem"),
+			verificationKeyFiles:  "",
+			verifySecretKey:       false,
+			wantPublishedKeyCount: 0,
+			wantSigningMethod:     nil,
+			wantSecretKeyAccepted: false,
+			wantErr:               true,
+		},
+		{
+			name:                  "Missing verification key file",
+			signingKeyFile:        signingKeyFile,
+			verificationKeyFiles:  filepath.Join(keysDir, "missing.pem"),
+			verifySecretKey:       false,
+			wantPublishedKeyCount: 0,
+			wantSigningMethod:     nil,
+			wantSecretKeyAccepted: false,
+			wantErr:               true,
+		},
+		{
+			name:                  "Public signing key file",
+			signingKeyFile:        verificationKeyFile,
+			verificationKeyFiles:  "",
+			verifySecretKey:       false,
+			wantPublishedKeyCount: 0,
+			wantSigningMethod:     nil,
+			wantSecretKeyAccepted: false,
+			wantErr:               true,
+		},
+		{
+			name:                  "Signing key file also used as verification key file",
+			signingKeyFile:        signingKeyFile,
+			verificationKeyFiles:  signingKeyFile,
+			verifySecretKey:       false,
+			wantPublishedKeyCount: 0,
+			wantSigningMethod:     nil,
+			wantSecretKeyAccepted: false,
+			wantErr:               true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
 
+			keySet, err := auth.NewKeySet(&env.Config{
+				SecretKey:               "deadbeef",
+				JWTSigningKeyFile:       testcase.signingKeyFile,
+				JWTVerificationKeyFiles: testcase.verificationKeyFiles,
+				JWTVerifySecretKey:      testcase.verifySecretKey,
+			})
 			if testcase.wantErr {
 				require.Error(t, err)
-			} else {
-				require.NoError(t, err)
+				return
+			}
 
-				err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-				require.NoError(t, err)
+			require.NoError(t, err)
+			require.Len(t, keySet.JSONWebKeySet().Keys, testcase.wantPublishedKeyCount)
 
-				hashedPassword, err = auth.HashPassword(testkit.GenerateFakePassword(), 14)
-				require.NoError(t, err)
+			token, err := keySet.Sign(&api.AuthJWTClaims{Subject: "somesubject"})
+			require.NoError(t, err)
+
+			parsedToken, err := keySet.Parse(token, &api.AuthJWTClaims{})
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantSigningMethod, parsedToken.Method)
 
-				err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+			_, err = keySet.Parse(secretKeyToken, &api.AuthJWTClaims{})
+			if testcase.wantSecretKeyAccepted {
+				require.NoError(t, err)
+			} else {
 				require.Error(t, err)
 			}
 		})
@@ -73,6 +238,9 @@ func TestCreateAuthJWTSuccess(t *testing.T) {
 	accessLifetime := time.Minute
 	refreshLifetime := time.Hour
 
+	keySet, err := jwks.NewKeySet(jwks.NewHMACKey([]byte("deadbeef")))
+	require.NoError(t, err)
+
 	testcases := []struct {
 		name         string
 		tokenType    auth.JWTType
@@ -95,10 +263,12 @@ func TestCreateAuthJWTSuccess(t *testing.T) {
 			t.Parallel()
 
 			userUUID := uuid.NewString()
-			token, err := auth.CreateAuthJWT(
+			sessionID := uuid.NewString()
+			token, createdClaims, err := auth.CreateAuthJWT(
 				userUUID,
+				sessionID,
 				testcase.tokenType,
-				"deadbeef",
+				keySet,
 				accessLifetime,
 				refreshLifetime,
 			)
@@ -113,7 +283,11 @@ func TestCreateAuthJWTSuccess(t *testing.T) {
 			require.NotNil(t, parsedToken)
 			require.True(t, parsedToken.Valid)
 			require.Equal(t, userUUID, claims.Subject)
+			require.Equal(t, sessionID, claims.SessionID)
 			require.Equal(t, string(testcase.tokenType), claims.TokenType)
+			require.Equal(t, createdClaims.JWTID, claims.JWTID)
+			require.Nil(t, claims.Actor)
+			require.False(t, claims.ReadOnly)
 
 			testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
 			testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(testcase.wantLifetime), time.Time(claims.ExpiresAt))
@@ -121,13 +295,158 @@ func TestCreateAuthJWTSuccess(t *testing.T) {
 	}
 }
 
+func TestLoginAccountIdentifier(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "name@example.com", auth.LoginAccountIdentifier("name@example.com"))
+	require.Equal(t, "name@example.com", auth.LoginAccountIdentifier("  Name@Example.COM "))
+}
+
+func TestLoginLockoutDuration(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name         string
+		failureCount int
+		threshold    int
+		wantLockout  time.Duration
+	}{
+		{
+			name:         "Below threshold",
+			failureCount: 4,
+			threshold:    5,
+			wantLockout:  0,
+		},
+		{
+			name:         "At threshold",
+			failureCount: 5,
+			threshold:    5,
+			wantLockout:  auth.LoginLockoutBaseDuration,
+		},
+		{
+			name:         "One failure beyond threshold",
+			failureCount: 6,
+			threshold:    5,
+			wantLockout:  2 * auth.LoginLockoutBaseDuration,
+		},
+		{
+			name:         "Three failures beyond threshold",
+			failureCount: 8,
+			threshold:    5,
+			wantLockout:  8 * auth.LoginLockoutBaseDuration,
+		},
+		{
+			name:         "Lockout is capped",
+			failureCount: 1000,
+			threshold:    5,
+			wantLockout:  auth.LoginLockoutMaxDuration,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testcase.wantLockout, auth.LoginLockoutDuration(testcase.failureCount, testcase.threshold))
+		})
+	}
+}
+
+func TestCreateAuthJWTWithAsymmetricKey(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwks.NewKey(testkit.MustGenerateEd25519Key())
+	require.NoError(t, err)
+
+	keySet, err := jwks.NewKeySet(key)
+	require.NoError(t, err)
+
+	userUUID := uuid.NewString()
+	token, _, err := auth.CreateAuthJWT(
+		userUUID,
+		uuid.NewString(),
+		auth.JWTTypeAccess,
+		keySet,
+		time.Minute,
+		time.Hour,
+	)
+	require.NoError(t, err)
+
+	claims := &api.AuthJWTClaims{}
+	parsedToken, err := keySet.Parse(token, claims)
+	require.NoError(t, err)
+
+	require.True(t, parsedToken.Valid)
+	require.Equal(t, jwt.SigningMethodEdDSA, parsedToken.Method)
+	require.Equal(t, key.ID, parsedToken.Header["kid"])
+	require.Equal(t, userUUID, claims.Subject)
+}
+
+func TestCreateImpersonationJWT(t *testing.T) {
+	t.Parallel()
+
+	keySet, err := jwks.NewKeySet(jwks.NewHMACKey([]byte("deadbeef")))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name     string
+		readOnly bool
+	}{
+		{
+			name:     "Read-only impersonation",
+			readOnly: true,
+		},
+		{
+			name:     "Impersonation with writes allowed",
+			readOnly: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			userUUID := uuid.NewString()
+			actorUUID := uuid.NewString()
+			sessionID := uuid.NewString()
+			token, createdClaims, err := auth.CreateImpersonationJWT(
+				userUUID,
+				actorUUID,
+				sessionID,
+				testcase.readOnly,
+				keySet,
+				time.Minute,
+			)
+			require.NoError(t, err)
+
+			claims, ok := auth.ValidateAuthJWT(token, auth.JWTTypeAccess, keySet)
+			require.True(t, ok)
+
+			require.Equal(t, userUUID, claims.Subject)
+			require.Equal(t, sessionID, claims.SessionID)
+			require.Equal(t, string(auth.JWTTypeAccess), claims.TokenType)
+			require.Equal(t, createdClaims.JWTID, claims.JWTID)
+			require.NotNil(t, claims.Actor)
+			require.Equal(t, actorUUID, claims.Actor.Subject)
+			require.Equal(t, testcase.readOnly, claims.ReadOnly)
+
+			testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
+			testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Minute), time.Time(claims.ExpiresAt))
+		})
+	}
+}
+
 func TestCreateAuthJWTInvalidType(t *testing.T) {
 	t.Parallel()
 
-	_, err := auth.CreateAuthJWT(
+	keySet, err := jwks.NewKeySet(jwks.NewHMACKey([]byte("deadbeef")))
+	require.NoError(t, err)
+
+	_, _, err = auth.CreateAuthJWT(
+		uuid.NewString(),
 		uuid.NewString(),
 		auth.JWTType("invalidtype"),
-		"deadbeef",
+		keySet,
 		time.Minute,
 		time.Hour,
 	)
@@ -143,6 +462,33 @@ func TestValidateAuthJWT(t *testing.T) {
 	oneDayAgo := now.Add(-24 * time.Hour)
 	validSecretKey := "deadbeef"
 
+	validKeySet, err := jwks.NewKeySet(jwks.NewHMACKey([]byte(validSecretKey)))
+	require.NoError(t, err)
+
+	invalidKeySet, err := jwks.NewKeySet(jwks.NewHMACKey([]byte("invalidsecretkey")))
+	require.NoError(t, err)
+
+	oldKey, err := jwks.NewKey(testkit.MustGenerateEd25519Key())
+	require.NoError(t, err)
+
+	oldKeySet, err := jwks.NewKeySet(oldKey)
+	require.NoError(t, err)
+
+	newKey, err := jwks.NewKey(testkit.MustGenerateEd25519Key())
+	require.NoError(t, err)
+
+	rotatedKeySet, err := jwks.NewKeySet(newKey, oldKey)
+	require.NoError(t, err)
+
+	tokenSignedByOldKey, err := oldKeySet.Sign(&api.AuthJWTClaims{
+		Subject:   userUUID,
+		TokenType: string(auth.JWTTypeAccess),
+		IssuedAt:  utils.JSONTimeStamp(now),
+		ExpiresAt: utils.JSONTimeStamp(now.Add(time.Hour)),
+		JWTID:     jti,
+	})
+	require.NoError(t, err)
+
 	validAccessToken, err := jwt.NewWithClaims(
 		jwt.SigningMethodHS256,
 		&api.AuthJWTClaims{
@@ -204,56 +550,77 @@ func TestValidateAuthJWT(t *testing.T) {
 		name      string
 		token     string
 		tokenType auth.JWTType
-		secretKey string
+		keySet    *jwks.KeySet
 		wantOk    bool
 	}{
 		{
 			name:      "Valid access token",
 			token:     validAccessToken,
 			tokenType: auth.JWTTypeAccess,
-			secretKey: validSecretKey,
+			keySet:    validKeySet,
 			wantOk:    true,
 		},
 		{
 			name:      "Valid refresh token",
 			token:     validRefreshToken,
 			tokenType: auth.JWTTypeRefresh,
-			secretKey: validSecretKey,
+			keySet:    validKeySet,
 			wantOk:    true,
 		},
 		{
 			name:      "Invalid secret key",
 			token:     validAccessToken,
 			tokenType: auth.JWTTypeAccess,
-			secretKey: "invalidsecretkey",
+			keySet:    invalidKeySet,
+			wantOk:    false,
+		},
+		{
+			name:      "Token signed by rotated key",
+			token:     tokenSignedByOldKey,
+			tokenType: auth.JWTTypeAccess,
+			keySet:    rotatedKeySet,
+			wantOk:    true,
+		},
+		{
+			name:      "Token signed by unknown key",
+			token:     tokenSignedByOldKey,
+			tokenType: auth.JWTTypeAccess,
+			keySet:    validKeySet,
+			wantOk:    false,
+		},
+		{
+			name:      "HMAC token for asymmetric key set",
+			token:     validAccessToken,
+			tokenType: auth.JWTTypeAccess,
+			keySet:    rotatedKeySet,
 			wantOk:    false,
 		},
 		{
 			name:      "Token of invalid type",
 			token:     tokenOfInvalidType,
 			tokenType: auth.JWTTypeAccess,
-			secretKey: validSecretKey,
+			keySet:    validKeySet,
 			wantOk:    false,
 		},
 		{
 			name:      "Invalid token",
 			token:     "ed0730889507fdb8549acfcd31548ee5",
 			tokenType: auth.JWTTypeAccess,
-			secretKey: validSecretKey,
+			keySet:    validKeySet,
 			wantOk:    false,
 		},
 		{
 			name:      "Expired token",
 			token:     expiredToken,
 			tokenType: auth.JWTTypeAccess,
-			secretKey: validSecretKey,
+			keySet:    validKeySet,
 			wantOk:    false,
 		},
 		{
 			name:      "Token with invalid claim",
 			token:     tokenWithInvalidClaim,
 			tokenType: auth.JWTTypeAccess,
-			secretKey: validSecretKey,
+			keySet:    validKeySet,
 			wantOk:    false,
 		},
 	}
@@ -262,7 +629,7 @@ func TestValidateAuthJWT(t *testing.T) {
 		t.Run(testcase.name, func(t *testing.T) {
 			t.Parallel()
 
-			claims, ok := auth.ValidateAuthJWT(testcase.token, testcase.tokenType, testcase.secretKey)
+			claims, ok := auth.ValidateAuthJWT(testcase.token, testcase.tokenType, testcase.keySet)
 			require.Equal(t, testcase.wantOk, ok)
 
 			if testcase.wantOk {
@@ -273,13 +640,116 @@ func TestValidateAuthJWT(t *testing.T) {
 	}
 }
 
+func TestTruncateUserAgent(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{
+			name:      "Empty user agent",
+			userAgent: "",
+			want:      "",
+		},
+		{
+			name:      "Short user agent",
+			userAgent: "curl/8.5.0",
+			want:      "curl/8.5.0",
+		},
+		{
+			name:      "User agent at maximum length",
+			userAgent: strings.Repeat("a", auth.SessionUserAgentMaxLength),
+			want:      strings.Repeat("a", auth.SessionUserAgentMaxLength),
+		},
+		{
+			name:      "User agent over maximum length",
+			userAgent: strings.Repeat("a", auth.SessionUserAgentMaxLength+10),
+			want:      strings.Repeat("a", auth.SessionUserAgentMaxLength),
+		},
+		{
+			name:      "Multi-byte user agent over maximum length",
+			userAgent: strings.Repeat("é", auth.SessionUserAgentMaxLength+1),
+			want:      strings.Repeat("é", auth.SessionUserAgentMaxLength),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testcase.want, auth.TruncateUserAgent(testcase.userAgent))
+		})
+	}
+}
+
+func TestTruncateUserName(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		userName string
+		want     string
+	}{
+		{
+			name:     "Empty name",
+			userName: "",
+			want:     "",
+		},
+		{
+			name:     "Short name",
+			userName: "Michael",
+			want:     "Michael",
+		},
+		{
+			name:     "Name over maximum length",
+			userName: strings.Repeat("a", auth.UserNameMaxLength+10),
+			want:     strings.Repeat("a", auth.UserNameMaxLength),
+		},
+		{
+			name:     "Multi-byte name over maximum length",
+			userName: strings.Repeat("é", auth.UserNameMaxLength+1),
+			want:     strings.Repeat("é", auth.UserNameMaxLength),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testcase.want, auth.TruncateUserName(testcase.userName))
+		})
+	}
+}
+
+func TestCreateOIDCAuthRequest(t *testing.T) {
+	t.Parallel()
+
+	lifetime := auth.OIDCAuthRequestLifetime
+	authRequest, err := auth.CreateOIDCAuthRequest(lifetime)
+	require.NoError(t, err)
+
+	require.Len(t, authRequest.State, 32)
+	require.Len(t, authRequest.Nonce, 32)
+	require.Len(t, authRequest.CodeVerifier, 43)
+	require.NotEqual(t, authRequest.State, authRequest.Nonce)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), authRequest.CreatedAt)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(lifetime), authRequest.ExpiresAt)
+
+	otherAuthRequest, err := auth.CreateOIDCAuthRequest(lifetime)
+	require.NoError(t, err)
+	require.NotEqual(t, authRequest.State, otherAuthRequest.State)
+	require.NotEqual(t, authRequest.CodeVerifier, otherAuthRequest.CodeVerifier)
+}
+
 func TestCreateActivationJWTSuccess(t *testing.T) {
 	t.Parallel()
 
 	userUUID := uuid.NewString()
 	secretKey := "deadbeef"
 	lifetime := time.Hour
-	token, err := auth.CreateActivationJWT(userUUID, secretKey, lifetime)
+	token, issuedJWT, err := auth.CreateActivationJWT(userUUID, secretKey, lifetime)
 	require.NoError(t, err)
 
 	claims := &api.ActivationJWTClaims{}
@@ -295,6 +765,13 @@ func TestCreateActivationJWTSuccess(t *testing.T) {
 
 	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
 	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(lifetime), time.Time(claims.ExpiresAt))
+
+	require.Equal(t, claims.JWTID, issuedJWT.JTI)
+	require.Equal(t, userUUID, issuedJWT.UserUUID)
+	require.Equal(t, string(auth.JWTTypeActivation), issuedJWT.TokenType)
+	testkit.RequireTimeAlmostEqual(t, time.Time(claims.IssuedAt), issuedJWT.CreatedAt)
+	testkit.RequireTimeAlmostEqual(t, time.Time(claims.ExpiresAt), issuedJWT.ExpiresAt)
+	require.False(t, issuedJWT.ConsumedAt.Valid)
 }
 
 func TestValidateActivationJWT(t *testing.T) {
@@ -429,14 +906,16 @@ func TestSendActivationMailSuccess(t *testing.T) {
 	frontendActivationRoute := "/signup/activate/%s"
 	secretKey := "deadbeef"
 	lifetime := time.Hour
-	err := auth.SendActivationMail(
+	token, _, err := auth.CreateActivationJWT(user.UUID, secretKey, lifetime)
+	require.NoError(t, err)
+
+	err = auth.SendActivationMail(
 		user,
+		token,
 		mailClient,
 		tmplManager,
 		frontendBaseURL,
 		frontendActivationRoute,
-		secretKey,
-		lifetime,
 	)
 	require.NoError(t, err)
 	require.Len(t, mailClient.Logs, 1)
@@ -458,6 +937,8 @@ func TestSendActivationMailSuccess(t *testing.T) {
 	require.Len(t, matches, 2)
 
 	activationToken := matches[1]
+	require.Equal(t, token, activationToken)
+
 	claims := &api.ActivationJWTClaims{}
 	parsedToken, err := jwt.ParseWithClaims(activationToken, claims, func(t *jwt.Token) (any, error) {
 		return []byte(secretKey), nil
@@ -493,17 +974,16 @@ func TestSendActivationMailSendError(t *testing.T) {
 	tmplManager := templatesmanager.NewManager()
 	frontendBaseURL := "http://localhost:3000"
 	frontendActivationRoute := "/signup/activate/%s"
-	secretKey := "deadbeef"
-	lifetime := time.Hour
+	token, _, err := auth.CreateActivationJWT(user.UUID, "deadbeef", time.Hour)
+	require.NoError(t, err)
 
-	err := auth.SendActivationMail(
+	err = auth.SendActivationMail(
 		user,
+		token,
 		mailClient,
 		tmplManager,
 		frontendBaseURL,
 		frontendActivationRoute,
-		secretKey,
-		lifetime,
 	)
 	require.ErrorIs(t, err, mailErr)
 }
@@ -533,65 +1013,1376 @@ func TestSendActivationMailTemplatesError(t *testing.T) {
 	tmplManager := &errTmplManager{}
 	frontendBaseURL := "http://localhost:3000"
 	frontendActivationRoute := "/signup/activate/%s"
-	secretKey := "deadbeef"
-	lifetime := time.Hour
+	token, _, err := auth.CreateActivationJWT(user.UUID, "deadbeef", time.Hour)
+	require.NoError(t, err)
 
-	err := auth.SendActivationMail(
+	err = auth.SendActivationMail(
 		user,
+		token,
 		mailClient,
 		tmplManager,
 		frontendBaseURL,
 		frontendActivationRoute,
-		secretKey,
-		lifetime,
 	)
 	require.ErrorIs(t, err, errTmplLoad)
 	require.Len(t, mailClient.Logs, 0)
 }
 
-func TestCreateAPIKey(t *testing.T) {
+func TestPasswordFingerprint(t *testing.T) {
 	t.Parallel()
 
-	testcases := []struct {
-		name        string
-		hashingCost int
-		wantErr     bool
-	}{
-		{
-			name:        "Valid hashing cost",
-			hashingCost: 14,
-			wantErr:     false,
-		},
-		{
-			name:        "Invalid hashing cost",
-			hashingCost: 32,
-			wantErr:     true,
-		},
-	}
+	passwordChangedAt := pgtype.Timestamp{Time: time.Now().UTC(), Valid: true}
+	otherPasswordChangedAt := pgtype.Timestamp{Time: passwordChangedAt.Time.Add(time.Microsecond), Valid: true}
 
-	for _, testcase := range testcases {
-		t.Run(testcase.name, func(t *testing.T) {
-			t.Parallel()
+	fingerprint := auth.PasswordFingerprint(passwordChangedAt, "deadbeef")
+	require.NotEmpty(t, fingerprint)
+	require.Equal(t, fingerprint, auth.PasswordFingerprint(passwordChangedAt, "deadbeef"))
+	require.NotEqual(t, fingerprint, auth.PasswordFingerprint(otherPasswordChangedAt, "deadbeef"))
+	require.NotEqual(t, fingerprint, auth.PasswordFingerprint(pgtype.Timestamp{}, "deadbeef"))
+	require.NotEqual(t, fingerprint, auth.PasswordFingerprint(passwordChangedAt, "incorrectsecretkey"))
+}
 
-			prefix, rawKey, hashedKey, err := auth.CreateAPIKey(testcase.hashingCost)
-			if testcase.wantErr {
-				require.Error(t, err)
-			} else {
-				require.NoError(t, err)
+func TestCreatePasswordResetJWTSuccess(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:              uuid.NewString(),
+		Email:             testkit.GenerateFakeEmail(),
+		Password:          testkitinternal.MustHashPassword(testkit.GenerateFakePassword()),
+		PasswordChangedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	}
+	secretKey := "deadbeef"
+	lifetime := time.Hour
+	token, issuedJWT, err := auth.CreatePasswordResetJWT(user, secretKey, lifetime)
+	require.NoError(t, err)
+
+	claims := &api.PasswordResetJWTClaims{}
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(secretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, parsedToken)
+	require.True(t, parsedToken.Valid)
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, string(auth.JWTTypePasswordReset), claims.TokenType)
+	require.Equal(t, auth.PasswordFingerprint(user.PasswordChangedAt, secretKey), claims.PasswordFingerprint)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(lifetime), time.Time(claims.ExpiresAt))
+
+	require.Equal(t, claims.JWTID, issuedJWT.JTI)
+	require.Equal(t, user.UUID, issuedJWT.UserUUID)
+	require.Equal(t, string(auth.JWTTypePasswordReset), issuedJWT.TokenType)
+	require.False(t, issuedJWT.ConsumedAt.Valid)
+}
+
+func TestValidatePasswordResetJWT(t *testing.T) {
+	t.Parallel()
+
+	userUUID := uuid.NewString()
+	jti := uuid.NewString()
+	now := time.Now().UTC()
+	oneDayAgo := now.Add(-24 * time.Hour)
+	validSecretKey := "deadbeef"
+	fingerprint := auth.PasswordFingerprint(pgtype.Timestamp{Time: oneDayAgo, Valid: true}, validSecretKey)
+
+	validToken, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.PasswordResetJWTClaims{
+			Subject:             userUUID,
+			TokenType:           string(auth.JWTTypePasswordReset),
+			PasswordFingerprint: fingerprint,
+			IssuedAt:            utils.JSONTimeStamp(now),
+			ExpiresAt:           utils.JSONTimeStamp(now.Add(time.Hour)),
+			JWTID:               jti,
+		},
+	).SignedString([]byte(validSecretKey))
+	require.NoError(t, err)
+
+	tokenOfInvalidType, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.PasswordResetJWTClaims{
+			Subject:             userUUID,
+			TokenType:           string(auth.JWTTypeActivation),
+			PasswordFingerprint: fingerprint,
+			IssuedAt:            utils.JSONTimeStamp(now),
+			ExpiresAt:           utils.JSONTimeStamp(now.Add(time.Hour)),
+			JWTID:               jti,
+		},
+	).SignedString([]byte(validSecretKey))
+	require.NoError(t, err)
+
+	expiredToken, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.PasswordResetJWTClaims{
+			Subject:             userUUID,
+			TokenType:           string(auth.JWTTypePasswordReset),
+			PasswordFingerprint: fingerprint,
+			IssuedAt:            utils.JSONTimeStamp(oneDayAgo),
+			ExpiresAt:           utils.JSONTimeStamp(oneDayAgo.Add(time.Hour)),
+			JWTID:               jti,
+		},
+	).SignedString([]byte(validSecretKey))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name      string
+		token     string
+		secretKey string
+		wantOk    bool
+	}{
+		{
+			name:      "Valid token of correct type",
+			token:     validToken,
+			secretKey: validSecretKey,
+			wantOk:    true,
+		},
+		{
+			name:      "Token of incorrect type",
+			token:     tokenOfInvalidType,
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Invalid token",
+			token:     "ed0730889507fdb8549acfcd31548ee5",
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Expired token",
+			token:     expiredToken,
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Incorrect secret key",
+			token:     validToken,
+			secretKey: "incorrectsecretkey",
+			wantOk:    false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			claims, ok := auth.ValidatePasswordResetJWT(testcase.token, testcase.secretKey)
+			require.Equal(t, testcase.wantOk, ok)
+
+			if testcase.wantOk {
+				require.Equal(t, userUUID, claims.Subject)
+				require.Equal(t, string(auth.JWTTypePasswordReset), claims.TokenType)
+				require.Equal(t, fingerprint, claims.PasswordFingerprint)
+			}
+		})
+	}
+}
+
+func TestSendPasswordResetMailSuccess(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:        uuid.NewString(),
+		Email:       testkit.GenerateFakeEmail(),
+		Password:    testkitinternal.MustHashPassword(testkit.GenerateFakePassword()),
+		FirstName:   testkit.MustGenerateRandomString(8, true, true, false),
+		LastName:    testkit.MustGenerateRandomString(8, true, true, false),
+		IsActive:    true,
+		IsSuperUser: false,
+	}
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	frontendBaseURL := "http://localhost:3000"
+	frontendPasswordResetRoute := "/password-reset/%s"
+	secretKey := "deadbeef"
+	lifetime := time.Hour
+	token, _, err := auth.CreatePasswordResetJWT(user, secretKey, lifetime)
+	require.NoError(t, err)
+
+	err = auth.SendPasswordResetMail(
+		user,
+		token,
+		mailClient,
+		tmplManager,
+		frontendBaseURL,
+		frontendPasswordResetRoute,
+	)
+	require.NoError(t, err)
+	require.Len(t, mailClient.Logs, 1)
+
+	lastMail := mailClient.Logs[len(mailClient.Logs)-1]
+	require.Equal(t, []string{user.Email}, lastMail.To)
+	require.Equal(t, "Reset your Flagger password", lastMail.Subject)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), lastMail.SentAt)
+
+	mailMessage := string(lastMail.Message)
+	require.Contains(t, mailMessage, "Flagger - Reset Your Password")
+
+	pattern := fmt.Sprintf(frontendBaseURL+frontendPasswordResetRoute, `(\S+)`)
+	r, err := regexp.Compile(pattern)
+	require.NoError(t, err)
+
+	matches := r.FindStringSubmatch(mailMessage)
+	require.Len(t, matches, 2)
+
+	passwordResetToken := matches[1]
+	require.Equal(t, token, passwordResetToken)
+
+	claims, ok := auth.ValidatePasswordResetJWT(passwordResetToken, secretKey)
+	require.True(t, ok)
+
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, auth.PasswordFingerprint(user.PasswordChangedAt, secretKey), claims.PasswordFingerprint)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(lifetime), time.Time(claims.ExpiresAt))
+}
+
+func TestSendPasswordResetMailSendError(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:     uuid.NewString(),
+		Email:    testkit.GenerateFakeEmail(),
+		Password: testkitinternal.MustHashPassword(testkit.GenerateFakePassword()),
+	}
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	mailErr := errors.New("Send failed")
+	mailClient.SetSendError(mailErr)
+
+	token, _, err := auth.CreatePasswordResetJWT(user, "deadbeef", time.Hour)
+	require.NoError(t, err)
+
+	err = auth.SendPasswordResetMail(
+		user,
+		token,
+		mailClient,
+		templatesmanager.NewManager(),
+		"http://localhost:3000",
+		"/password-reset/%s",
+	)
+	require.ErrorIs(t, err, mailErr)
+}
+
+func TestSendPasswordResetMailTemplatesError(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:     uuid.NewString(),
+		Email:    testkit.GenerateFakeEmail(),
+		Password: testkitinternal.MustHashPassword(testkit.GenerateFakePassword()),
+	}
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	token, _, err := auth.CreatePasswordResetJWT(user, "deadbeef", time.Hour)
+	require.NoError(t, err)
+
+	err = auth.SendPasswordResetMail(
+		user,
+		token,
+		mailClient,
+		&errTmplManager{},
+		"http://localhost:3000",
+		"/password-reset/%s",
+	)
+	require.ErrorIs(t, err, errTmplLoad)
+	require.Len(t, mailClient.Logs, 0)
+}
+
+func TestCreateEmailChangeJWTSuccess(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+	newEmail := testkit.GenerateFakeEmail()
+	secretKey := "deadbeef"
+	lifetime := time.Hour
+	token, issuedJWT, err := auth.CreateEmailChangeJWT(user, newEmail, secretKey, lifetime)
+	require.NoError(t, err)
+
+	claims := &api.EmailChangeJWTClaims{}
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(secretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, parsedToken)
+	require.True(t, parsedToken.Valid)
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, string(auth.JWTTypeEmailChange), claims.TokenType)
+	require.Equal(t, newEmail, claims.Email)
+	require.Equal(t, user.Email, claims.OldEmail)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(lifetime), time.Time(claims.ExpiresAt))
+
+	require.Equal(t, claims.JWTID, issuedJWT.JTI)
+	require.Equal(t, user.UUID, issuedJWT.UserUUID)
+	require.Equal(t, string(auth.JWTTypeEmailChange), issuedJWT.TokenType)
+	require.False(t, issuedJWT.ConsumedAt.Valid)
+}
+
+func TestValidateEmailChangeJWT(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+	newEmail := testkit.GenerateFakeEmail()
+	validSecretKey := "deadbeef"
+
+	validToken, _, err := auth.CreateEmailChangeJWT(user, newEmail, validSecretKey, time.Hour)
+	require.NoError(t, err)
+
+	expiredToken, _, err := auth.CreateEmailChangeJWT(user, newEmail, validSecretKey, -time.Hour)
+	require.NoError(t, err)
+
+	tokenOfInvalidType, _, err := auth.CreateActivationJWT(user.UUID, validSecretKey, time.Hour)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name      string
+		token     string
+		secretKey string
+		wantOk    bool
+	}{
+		{
+			name:      "Valid token of correct type",
+			token:     validToken,
+			secretKey: validSecretKey,
+			wantOk:    true,
+		},
+		{
+			name:      "Token of incorrect type",
+			token:     tokenOfInvalidType,
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Invalid token",
+			token:     "ed0730889507fdb8549acfcd31548ee5",
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Expired token",
+			token:     expiredToken,
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Incorrect secret key",
+			token:     validToken,
+			secretKey: "incorrectsecretkey",
+			wantOk:    false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			claims, ok := auth.ValidateEmailChangeJWT(testcase.token, testcase.secretKey)
+			require.Equal(t, testcase.wantOk, ok)
+
+			if testcase.wantOk {
+				require.Equal(t, user.UUID, claims.Subject)
+				require.Equal(t, string(auth.JWTTypeEmailChange), claims.TokenType)
+				require.Equal(t, newEmail, claims.Email)
+				require.Equal(t, user.Email, claims.OldEmail)
+			}
+		})
+	}
+}
+
+func TestSendEmailChangeMailSuccess(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+	newEmail := testkit.GenerateFakeEmail()
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	frontendBaseURL := "http://localhost:3000"
+	frontendEmailChangeRoute := "/email-change/%s"
+	secretKey := "deadbeef"
+	lifetime := time.Hour
+	token, _, err := auth.CreateEmailChangeJWT(user, newEmail, secretKey, lifetime)
+	require.NoError(t, err)
+
+	err = auth.SendEmailChangeMail(
+		newEmail,
+		token,
+		mailClient,
+		tmplManager,
+		frontendBaseURL,
+		frontendEmailChangeRoute,
+	)
+	require.NoError(t, err)
+	require.Len(t, mailClient.Logs, 1)
+
+	lastMail := mailClient.Logs[len(mailClient.Logs)-1]
+	require.Equal(t, []string{newEmail}, lastMail.To)
+	require.Equal(t, "Verify your new Flagger email", lastMail.Subject)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), lastMail.SentAt)
+
+	mailMessage := string(lastMail.Message)
+	require.Contains(t, mailMessage, "Flagger - Verify Your New Email")
+
+	pattern := fmt.Sprintf(frontendBaseURL+frontendEmailChangeRoute, `(\S+)`)
+	r, err := regexp.Compile(pattern)
+	require.NoError(t, err)
+
+	matches := r.FindStringSubmatch(mailMessage)
+	require.Len(t, matches, 2)
+
+	emailChangeToken := matches[1]
+	require.Equal(t, token, emailChangeToken)
+
+	claims, ok := auth.ValidateEmailChangeJWT(emailChangeToken, secretKey)
+	require.True(t, ok)
+
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, newEmail, claims.Email)
+	require.Equal(t, user.Email, claims.OldEmail)
+}
+
+func TestSendEmailChangeMailTemplatesError(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+
+	newEmail := testkit.GenerateFakeEmail()
+	token, _, err := auth.CreateEmailChangeJWT(user, newEmail, "deadbeef", time.Hour)
+	require.NoError(t, err)
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	err = auth.SendEmailChangeMail(
+		newEmail,
+		token,
+		mailClient,
+		&errTmplManager{},
+		"http://localhost:3000",
+		"/email-change/%s",
+	)
+	require.ErrorIs(t, err, errTmplLoad)
+	require.Len(t, mailClient.Logs, 0)
+}
+
+func TestSendEmailChangeNoticeMailSuccess(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+	newEmail := testkit.GenerateFakeEmail()
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	err := auth.SendEmailChangeNoticeMail(user, newEmail, mailClient, templatesmanager.NewManager())
+	require.NoError(t, err)
+	require.Len(t, mailClient.Logs, 1)
+
+	lastMail := mailClient.Logs[len(mailClient.Logs)-1]
+	require.Equal(t, []string{user.Email}, lastMail.To)
+	require.Equal(t, "Your Flagger email is being changed", lastMail.Subject)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), lastMail.SentAt)
+
+	mailMessage := string(lastMail.Message)
+	require.Contains(t, mailMessage, "Flagger - Your Email Is Being Changed")
+	require.Contains(t, mailMessage, newEmail)
+}
+
+func TestSendEmailChangeNoticeMailSendError(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	mailErr := errors.New("Send failed")
+	mailClient.SetSendError(mailErr)
+
+	err := auth.SendEmailChangeNoticeMail(user, testkit.GenerateFakeEmail(), mailClient, templatesmanager.NewManager())
+	require.ErrorIs(t, err, mailErr)
+}
+
+func TestSendAccountLockoutMailSuccess(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+	ipAddress := "203.0.113.42"
+	lockedUntil := time.Date(2024, time.January, 29, 1, 40, 12, 0, time.UTC)
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	err := auth.SendAccountLockoutMail(user, ipAddress, lockedUntil, mailClient, templatesmanager.NewManager())
+	require.NoError(t, err)
+	require.Len(t, mailClient.Logs, 1)
+
+	lastMail := mailClient.Logs[len(mailClient.Logs)-1]
+	require.Equal(t, []string{user.Email}, lastMail.To)
+	require.Equal(t, "Your Flagger account was temporarily locked", lastMail.Subject)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), lastMail.SentAt)
+
+	mailMessage := string(lastMail.Message)
+	require.Contains(t, mailMessage, "Flagger - Your Account Was Temporarily Locked")
+	require.Contains(t, mailMessage, ipAddress)
+	require.Contains(t, mailMessage, "2024-01-29 01:40:12 UTC")
+}
+
+func TestSendAccountLockoutMailSendError(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	mailErr := errors.New("Send failed")
+	mailClient.SetSendError(mailErr)
+
+	err := auth.SendAccountLockoutMail(user, "203.0.113.42", time.Now().UTC(), mailClient, templatesmanager.NewManager())
+	require.ErrorIs(t, err, mailErr)
+}
+
+func TestCreateMagicLinkJWTSuccess(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+	secretKey := "deadbeef"
+	lifetime := 15 * time.Minute
+	token, issuedJWT, err := auth.CreateMagicLinkJWT(user, secretKey, lifetime)
+	require.NoError(t, err)
+
+	claims := &api.MagicLinkJWTClaims{}
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(secretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, parsedToken)
+	require.True(t, parsedToken.Valid)
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, string(auth.JWTTypeMagicLink), claims.TokenType)
+	require.Equal(t, user.Email, claims.Email)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(lifetime), time.Time(claims.ExpiresAt))
+
+	require.Equal(t, claims.JWTID, issuedJWT.JTI)
+	require.Equal(t, user.UUID, issuedJWT.UserUUID)
+	require.Equal(t, string(auth.JWTTypeMagicLink), issuedJWT.TokenType)
+	require.False(t, issuedJWT.ConsumedAt.Valid)
+}
+
+func TestValidateMagicLinkJWT(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+	validSecretKey := "deadbeef"
+
+	validToken, _, err := auth.CreateMagicLinkJWT(user, validSecretKey, 15*time.Minute)
+	require.NoError(t, err)
+
+	expiredToken, _, err := auth.CreateMagicLinkJWT(user, validSecretKey, -15*time.Minute)
+	require.NoError(t, err)
+
+	tokenOfInvalidType, _, err := auth.CreateEmailChangeJWT(user, user.Email, validSecretKey, time.Hour)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name      string
+		token     string
+		secretKey string
+		wantOk    bool
+	}{
+		{
+			name:      "Valid token of correct type",
+			token:     validToken,
+			secretKey: validSecretKey,
+			wantOk:    true,
+		},
+		{
+			name:      "Token of incorrect type",
+			token:     tokenOfInvalidType,
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Invalid token",
+			token:     "ed0730889507fdb8549acfcd31548ee5",
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Expired token",
+			token:     expiredToken,
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Incorrect secret key",
+			token:     validToken,
+			secretKey: "incorrectsecretkey",
+			wantOk:    false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			claims, ok := auth.ValidateMagicLinkJWT(testcase.token, testcase.secretKey)
+			require.Equal(t, testcase.wantOk, ok)
+
+			if testcase.wantOk {
+				require.Equal(t, user.UUID, claims.Subject)
+				require.Equal(t, string(auth.JWTTypeMagicLink), claims.TokenType)
+				require.Equal(t, user.Email, claims.Email)
+			}
+		})
+	}
+}
+
+func TestSendMagicLinkMailSuccess(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	frontendBaseURL := "http://localhost:3000"
+	frontendMagicLinkRoute := "/magic-link/%s"
+	secretKey := "deadbeef"
+	lifetime := 15 * time.Minute
+	token, _, err := auth.CreateMagicLinkJWT(user, secretKey, lifetime)
+	require.NoError(t, err)
+
+	err = auth.SendMagicLinkMail(
+		user,
+		token,
+		mailClient,
+		tmplManager,
+		frontendBaseURL,
+		frontendMagicLinkRoute,
+		lifetime,
+	)
+	require.NoError(t, err)
+	require.Len(t, mailClient.Logs, 1)
+
+	lastMail := mailClient.Logs[len(mailClient.Logs)-1]
+	require.Equal(t, []string{user.Email}, lastMail.To)
+	require.Equal(t, "Log in to Flagger", lastMail.Subject)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), lastMail.SentAt)
+
+	mailMessage := string(lastMail.Message)
+	require.Contains(t, mailMessage, "Flagger - Log In")
+	require.Contains(t, mailMessage, "15 minutes")
+
+	pattern := fmt.Sprintf(frontendBaseURL+frontendMagicLinkRoute, `(\S+)`)
+	r, err := regexp.Compile(pattern)
+	require.NoError(t, err)
+
+	matches := r.FindStringSubmatch(mailMessage)
+	require.Len(t, matches, 2)
+
+	magicLinkToken := matches[1]
+	require.Equal(t, token, magicLinkToken)
+
+	claims, ok := auth.ValidateMagicLinkJWT(magicLinkToken, secretKey)
+	require.True(t, ok)
+
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, user.Email, claims.Email)
+}
+
+func TestSendMagicLinkMailSendError(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+
+	token, _, err := auth.CreateMagicLinkJWT(user, "deadbeef", 15*time.Minute)
+	require.NoError(t, err)
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	mailErr := errors.New("Send failed")
+	mailClient.SetSendError(mailErr)
+
+	err = auth.SendMagicLinkMail(
+		user,
+		token,
+		mailClient,
+		templatesmanager.NewManager(),
+		"http://localhost:3000",
+		"/magic-link/%s",
+		15*time.Minute,
+	)
+	require.ErrorIs(t, err, mailErr)
+}
+
+func TestSendMagicLinkMailTemplatesError(t *testing.T) {
+	t.Parallel()
+
+	user := &auth.User{
+		UUID:  uuid.NewString(),
+		Email: testkit.GenerateFakeEmail(),
+	}
+
+	token, _, err := auth.CreateMagicLinkJWT(user, "deadbeef", 15*time.Minute)
+	require.NoError(t, err)
+
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	err = auth.SendMagicLinkMail(
+		user,
+		token,
+		mailClient,
+		&errTmplManager{},
+		"http://localhost:3000",
+		"/magic-link/%s",
+		15*time.Minute,
+	)
+	require.ErrorIs(t, err, errTmplLoad)
+	require.Len(t, mailClient.Logs, 0)
+}
+
+func TestCreateTOTPChallengeJWTSuccess(t *testing.T) {
+	t.Parallel()
+
+	userUUID := uuid.NewString()
+	secretKey := "deadbeef"
+	lifetime := auth.TOTPChallengeLifetime
+	token, issuedJWT, err := auth.CreateTOTPChallengeJWT(userUUID, secretKey, lifetime)
+	require.NoError(t, err)
+
+	claims := &api.TOTPChallengeJWTClaims{}
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(secretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, parsedToken)
+	require.True(t, parsedToken.Valid)
+	require.Equal(t, userUUID, claims.Subject)
+	require.Equal(t, string(auth.JWTTypeTOTPChallenge), claims.TokenType)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(lifetime), time.Time(claims.ExpiresAt))
+
+	require.Equal(t, claims.JWTID, issuedJWT.JTI)
+	require.Equal(t, userUUID, issuedJWT.UserUUID)
+	require.Equal(t, string(auth.JWTTypeTOTPChallenge), issuedJWT.TokenType)
+	testkit.RequireTimeAlmostEqual(t, time.Time(claims.IssuedAt), issuedJWT.CreatedAt)
+	testkit.RequireTimeAlmostEqual(t, time.Time(claims.ExpiresAt), issuedJWT.ExpiresAt)
+	require.False(t, issuedJWT.ConsumedAt.Valid)
+}
+
+func TestValidateTOTPChallengeJWT(t *testing.T) {
+	t.Parallel()
+
+	userUUID := uuid.NewString()
+	jti := uuid.NewString()
+	now := time.Now().UTC()
+	oneDayAgo := now.Add(-24 * time.Hour)
+	validSecretKey := "deadbeef"
+
+	validToken, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.TOTPChallengeJWTClaims{
+			Subject:   userUUID,
+			TokenType: string(auth.JWTTypeTOTPChallenge),
+			IssuedAt:  utils.JSONTimeStamp(now),
+			ExpiresAt: utils.JSONTimeStamp(now.Add(time.Minute)),
+			JWTID:     jti,
+		},
+	).SignedString([]byte(validSecretKey))
+	require.NoError(t, err)
+
+	accessToken, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.TOTPChallengeJWTClaims{
+			Subject:   userUUID,
+			TokenType: string(auth.JWTTypeAccess),
+			IssuedAt:  utils.JSONTimeStamp(now),
+			ExpiresAt: utils.JSONTimeStamp(now.Add(time.Minute)),
+			JWTID:     jti,
+		},
+	).SignedString([]byte(validSecretKey))
+	require.NoError(t, err)
+
+	expiredToken, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.TOTPChallengeJWTClaims{
+			Subject:   userUUID,
+			TokenType: string(auth.JWTTypeTOTPChallenge),
+			IssuedAt:  utils.JSONTimeStamp(oneDayAgo),
+			ExpiresAt: utils.JSONTimeStamp(oneDayAgo.Add(time.Minute)),
+			JWTID:     jti,
+		},
+	).SignedString([]byte(validSecretKey))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name      string
+		token     string
+		secretKey string
+		wantOk    bool
+	}{
+		{
+			name:      "Valid token of correct type",
+			token:     validToken,
+			secretKey: validSecretKey,
+			wantOk:    true,
+		},
+		{
+			name:      "Access token",
+			token:     accessToken,
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Invalid token",
+			token:     "ed0730889507fdb8549acfcd31548ee5",
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Expired token",
+			token:     expiredToken,
+			secretKey: validSecretKey,
+			wantOk:    false,
+		},
+		{
+			name:      "Incorrect secret key",
+			token:     validToken,
+			secretKey: "incorrectsecretkey",
+			wantOk:    false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			claims, ok := auth.ValidateTOTPChallengeJWT(testcase.token, testcase.secretKey)
+			require.Equal(t, testcase.wantOk, ok)
+
+			if testcase.wantOk {
+				require.Equal(t, userUUID, claims.Subject)
+				require.Equal(t, jti, claims.JWTID)
+			}
+		})
+	}
+}
+
+func TestIsTOTPCode(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{
+			name: "Six digits",
+			code: "287082",
+			want: true,
+		},
+		{
+			name: "Five digits",
+			code: "28708",
+			want: false,
+		},
+		{
+			name: "Seven digits",
+			code: "2870823",
+			want: false,
+		},
+		{
+			name: "Non-digit characters",
+			code: "28708A",
+			want: false,
+		},
+		{
+			name: "Recovery code",
+			code: "AB3D9-7KQ2Z",
+			want: false,
+		},
+		{
+			name: "Empty code",
+			code: "",
+			want: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, testcase.want, auth.IsTOTPCode(testcase.code))
+		})
+	}
+}
+
+func TestCreateRecoveryCodes(t *testing.T) {
+	t.Parallel()
+
+	codes, err := auth.CreateRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, auth.RecoveryCodeCount)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		require.Regexp(t, regexp.MustCompile(`^[A-Z0-9]{5}-[A-Z0-9]{5}$`), code)
+		require.False(t, auth.IsTOTPCode(code))
+		require.False(t, seen[code])
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	t.Parallel()
+
+	secretKey := "deadbeef"
+	hashedCode := auth.HashRecoveryCode("AB3D9-7KQ2Z", secretKey)
+	require.Len(t, hashedCode, 64)
+
+	testcases := []struct {
+		name      string
+		code      string
+		secretKey string
+		wantEqual bool
+	}{
+		{
+			name:      "Identical code",
+			code:      "AB3D9-7KQ2Z",
+			secretKey: secretKey,
+			wantEqual: true,
+		},
+		{
+			name:      "Code without separator",
+			code:      "AB3D97KQ2Z",
+			secretKey: secretKey,
+			wantEqual: true,
+		},
+		{
+			name:      "Lower case code with spaces",
+			code:      " ab3d9 7kq2z ",
+			secretKey: secretKey,
+			wantEqual: true,
+		},
+		{
+			name:      "Different code",
+			code:      "AB3D9-7KQ2Y",
+			secretKey: secretKey,
+			wantEqual: false,
+		},
+		{
+			name:      "Different secret key",
+			code:      "AB3D9-7KQ2Z",
+			secretKey: "incorrectsecretkey",
+			wantEqual: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			if testcase.wantEqual {
+				require.Equal(t, hashedCode, auth.HashRecoveryCode(testcase.code, testcase.secretKey))
+			} else {
+				require.NotEqual(t, hashedCode, auth.HashRecoveryCode(testcase.code, testcase.secretKey))
+			}
+		})
+	}
+}
+
+func TestDigestPepper(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name         string
+		secretKey    string
+		digestPepper string
+		wantPepper   string
+	}{
+		{
+			name:         "Digest pepper",
+			secretKey:    "DEADBEEF",
+			digestPepper: "C0FFEE",
+			wantPepper:   "C0FFEE",
+		},
+		{
+			name:         "No digest pepper",
+			secretKey:    "DEADBEEF",
+			digestPepper: "",
+			wantPepper:   "DEADBEEF",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
 
-				err = bcrypt.CompareHashAndPassword([]byte(hashedKey), []byte(rawKey))
-				require.NoError(t, err)
+			pepper := auth.DigestPepper(&env.Config{
+				SecretKey:    testcase.secretKey,
+				DigestPepper: testcase.digestPepper,
+			})
+			require.Equal(t, testcase.wantPepper, pepper)
+		})
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	t.Parallel()
+
+	secretKey := "DEADBEEF"
+	prefix, rawKey, keyDigest, err := auth.CreateAPIKey(secretKey)
+	require.NoError(t, err)
+
+	require.Equal(t, auth.APIKeyDigest(rawKey, secretKey), keyDigest)
+
+	r := regexp.MustCompile(`^(\S+)\.(\S+)$`)
+	matches := r.FindStringSubmatch(rawKey)
+
+	require.Len(t, matches, 3)
+	require.Equal(t, prefix, matches[1])
+
+	_, otherRawKey, otherKeyDigest, err := auth.CreateAPIKey(secretKey)
+	require.NoError(t, err)
+	require.NotEqual(t, rawKey, otherRawKey)
+	require.NotEqual(t, keyDigest, otherKeyDigest)
+}
+
+func TestAPIKeyDigest(t *testing.T) {
+	t.Parallel()
+
+	rawKey := "TqxlYSSQ.Yj2j1jyAMC5407Nctsl51K7E8sOIPqYXn28SqT5Gnfg="
+	secretKey := "DEADBEEF"
+	keyDigest := auth.APIKeyDigest(rawKey, secretKey)
+
+	require.Regexp(t, `^[0-9a-f]{64}$`, keyDigest)
+
+	testcases := []struct {
+		name      string
+		rawKey    string
+		secretKey string
+		wantEqual bool
+	}{
+		{
+			name:      "Same API key and secret key",
+			rawKey:    rawKey,
+			secretKey: secretKey,
+			wantEqual: true,
+		},
+		{
+			name:      "Different API key",
+			rawKey:    "TqxlYSSQ.Yj2j1jyAMC5407Nctsl51K7E8sOIPqYXn28SqT5Gnfh=",
+			secretKey: secretKey,
+			wantEqual: false,
+		},
+		{
+			name:      "Different API key prefix",
+			rawKey:    "TqxlYSSR.Yj2j1jyAMC5407Nctsl51K7E8sOIPqYXn28SqT5Gnfg=",
+			secretKey: secretKey,
+			wantEqual: false,
+		},
+		{
+			name:      "Different secret key",
+			rawKey:    rawKey,
+			secretKey: "FEEDBEEF",
+			wantEqual: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			if testcase.wantEqual {
+				require.Equal(t, keyDigest, auth.APIKeyDigest(testcase.rawKey, testcase.secretKey))
+			} else {
+				require.NotEqual(t, keyDigest, auth.APIKeyDigest(testcase.rawKey, testcase.secretKey))
+			}
+		})
+	}
+}
+
+// BenchmarkVerifyAPIKey compares verifying a raw API key using its key digest
+// against comparing it with a bcrypt hashed key.
+func BenchmarkVerifyAPIKey(b *testing.B) {
+	secretKey := "DEADBEEF"
+	_, rawKey, keyDigest, err := auth.CreateAPIKey(secretKey)
+	require.NoError(b, err)
+
+	b.Run("Key digest", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if auth.APIKeyDigest(rawKey, secretKey) != keyDigest {
+				b.Fatal("key digest mismatch")
+			}
+		}
+	})
+
+	for _, hashingCost := range []int{bcrypt.DefaultCost, 14} {
+		hashedKey, err := bcrypt.GenerateFromPassword([]byte(rawKey), hashingCost)
+		require.NoError(b, err)
+
+		b.Run(fmt.Sprintf("bcrypt cost %d", hashingCost), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				err := bcrypt.CompareHashAndPassword(hashedKey, []byte(rawKey))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeAPIKeyCIDRs(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name      string
+		cidrs     []string
+		wantCIDRs []string
+		wantOk    bool
+	}{
+		{
+			name:      "No CIDR ranges",
+			cidrs:     nil,
+			wantCIDRs: []string{},
+			wantOk:    true,
+		},
+		{
+			name:      "CIDR ranges are masked",
+			cidrs:     []string{"10.1.2.3/8", "2001:db8::1/32"},
+			wantCIDRs: []string{"10.0.0.0/8", "2001:db8::/32"},
+			wantOk:    true,
+		},
+		{
+			name:      "IP addresses are converted to CIDR ranges",
+			cidrs:     []string{"203.0.113.42", "2001:db8::1"},
+			wantCIDRs: []string{"203.0.113.42/32", "2001:db8::1/128"},
+			wantOk:    true,
+		},
+		{
+			name:      "Duplicate CIDR ranges are removed",
+			cidrs:     []string{"10.0.0.0/8", "10.255.0.0/8", "10.0.0.0/8"},
+			wantCIDRs: []string{"10.0.0.0/8"},
+			wantOk:    true,
+		},
+		{
+			name:   "Invalid CIDR range",
+			cidrs:  []string{"10.0.0.0/8", "10.0.0.0/40"},
+			wantOk: false,
+		},
+		{
+			name:   "Hostname",
+			cidrs:  []string{"vpc.example.com"},
+			wantOk: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			cidrs, ok := auth.NormalizeAPIKeyCIDRs(testcase.cidrs)
+			require.Equal(t, testcase.wantOk, ok)
+			if testcase.wantOk {
+				require.Equal(t, testcase.wantCIDRs, cidrs)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAllowsIP(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name         string
+		allowedCIDRs []string
+		ip           string
+		wantAllowed  bool
+	}{
+		{
+			name:         "No allowed CIDR ranges",
+			allowedCIDRs: []string{},
+			ip:           "203.0.113.42",
+			wantAllowed:  true,
+		},
+		{
+			name:         "IP address in allowed CIDR range",
+			allowedCIDRs: []string{"192.168.0.0/16", "10.0.0.0/8"},
+			ip:           "10.20.30.40",
+			wantAllowed:  true,
+		},
+		{
+			name:         "IPv6 address in allowed CIDR range",
+			allowedCIDRs: []string{"2001:db8::/32"},
+			ip:           "2001:db8:1::1",
+			wantAllowed:  true,
+		},
+		{
+			name:         "IP address outside allowed CIDR ranges",
+			allowedCIDRs: []string{"192.168.0.0/16", "10.0.0.0/8"},
+			ip:           "203.0.113.42",
+			wantAllowed:  false,
+		},
+		{
+			name:         "Invalid IP address",
+			allowedCIDRs: []string{"10.0.0.0/8"},
+			ip:           "",
+			wantAllowed:  false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testcase.wantAllowed, auth.APIKeyAllowsIP(testcase.allowedCIDRs, testcase.ip))
+		})
+	}
+}
+
+func TestNormalizeAPIKeyOrigins(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		origins     []string
+		wantOrigins []string
+		wantOk      bool
+	}{
+		{
+			name:        "No origins",
+			origins:     nil,
+			wantOrigins: []string{},
+			wantOk:      true,
+		},
+		{
+			name:        "Origins are normalized",
+			origins:     []string{"HTTPS://App.Example.com/", "http://localhost:3000"},
+			wantOrigins: []string{"https://app.example.com", "http://localhost:3000"},
+			wantOk:      true,
+		},
+		{
+			name:        "Duplicate origins are removed",
+			origins:     []string{"https://app.example.com", "https://APP.example.com"},
+			wantOrigins: []string{"https://app.example.com"},
+			wantOk:      true,
+		},
+		{
+			name:    "Wildcard origin",
+			origins: []string{"*"},
+			wantOk:  false,
+		},
+		{
+			name:    "Origin with path",
+			origins: []string{"https://app.example.com/dashboard"},
+			wantOk:  false,
+		},
+		{
+			name:    "Hostname without scheme",
+			origins: []string{"app.example.com"},
+			wantOk:  false,
+		},
+	}
 
-				r := regexp.MustCompile(`^(\S+)\.(\S+)$`)
-				matches := r.FindStringSubmatch(rawKey)
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
 
-				require.Len(t, matches, 3)
-				require.Equal(t, prefix, matches[1])
+			origins, ok := auth.NormalizeAPIKeyOrigins(testcase.origins)
+			require.Equal(t, testcase.wantOk, ok)
+			if testcase.wantOk {
+				require.Equal(t, testcase.wantOrigins, origins)
 			}
 		})
 	}
 }
 
+func TestAPIKeyAllowsOrigin(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name           string
+		allowedOrigins []string
+		origin         string
+		wantAllowed    bool
+	}{
+		{
+			name:           "No allowed origins",
+			allowedOrigins: []string{},
+			origin:         "https://evil.example.com",
+			wantAllowed:    true,
+		},
+		{
+			name:           "No origin",
+			allowedOrigins: []string{"https://app.example.com"},
+			origin:         "",
+			wantAllowed:    true,
+		},
+		{
+			name:           "Allowed origin",
+			allowedOrigins: []string{"https://www.example.com", "https://app.example.com"},
+			origin:         "https://app.example.com",
+			wantAllowed:    true,
+		},
+		{
+			name:           "Disallowed origin",
+			allowedOrigins: []string{"https://app.example.com"},
+			origin:         "https://evil.example.com",
+			wantAllowed:    false,
+		},
+		{
+			name:           "Invalid origin",
+			allowedOrigins: []string{"https://app.example.com"},
+			origin:         "null",
+			wantAllowed:    false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testcase.wantAllowed, auth.APIKeyAllowsOrigin(testcase.allowedOrigins, testcase.origin))
+		})
+	}
+}
+
 func TestParseAPIKey(t *testing.T) {
 	t.Parallel()
 
@@ -632,3 +2423,206 @@ func TestParseAPIKey(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeAPIKeyScopes(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name       string
+		scopes     []string
+		wantScopes []string
+		wantOk     bool
+	}{
+		{
+			name:       "No scopes defaults to admin",
+			scopes:     nil,
+			wantScopes: []string{"admin"},
+			wantOk:     true,
+		},
+		{
+			name:       "Known scopes are kept in order",
+			scopes:     []string{"flags:read", "user:read"},
+			wantScopes: []string{"flags:read", "user:read"},
+			wantOk:     true,
+		},
+		{
+			name:       "Duplicate scopes are removed",
+			scopes:     []string{"flags:evaluate", "flags:write", "flags:evaluate"},
+			wantScopes: []string{"flags:evaluate", "flags:write"},
+			wantOk:     true,
+		},
+		{
+			name:       "Unknown scope is invalid",
+			scopes:     []string{"flags:read", "flags:delete"},
+			wantScopes: nil,
+			wantOk:     false,
+		},
+		{
+			name:       "Client-side scope on its own",
+			scopes:     []string{"flags:client", "flags:client"},
+			wantScopes: []string{"flags:client"},
+			wantOk:     true,
+		},
+		{
+			name:       "Client-side scope combined with other scopes is invalid",
+			scopes:     []string{"flags:client", "flags:evaluate"},
+			wantScopes: nil,
+			wantOk:     false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			scopes, ok := auth.NormalizeAPIKeyScopes(testcase.scopes)
+			require.Equal(t, testcase.wantOk, ok)
+
+			if testcase.wantOk {
+				require.Equal(t, testcase.wantScopes, scopes)
+			}
+		})
+	}
+}
+
+func TestHasAPIKeyScope(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name          string
+		scopes        []string
+		requiredScope auth.APIKeyScope
+		wantHasScope  bool
+	}{
+		{
+			name:          "Exact scope is granted",
+			scopes:        []string{"flags:read"},
+			requiredScope: auth.APIKeyScopeFlagsRead,
+			wantHasScope:  true,
+		},
+		{
+			name:          "Read scope implies evaluate scope",
+			scopes:        []string{"flags:read"},
+			requiredScope: auth.APIKeyScopeFlagsEvaluate,
+			wantHasScope:  true,
+		},
+		{
+			name:          "Write scope implies read scope",
+			scopes:        []string{"flags:write"},
+			requiredScope: auth.APIKeyScopeFlagsRead,
+			wantHasScope:  true,
+		},
+		{
+			name:          "Evaluate scope does not imply read scope",
+			scopes:        []string{"flags:evaluate"},
+			requiredScope: auth.APIKeyScopeFlagsRead,
+			wantHasScope:  false,
+		},
+		{
+			name:          "Flags scope does not imply user scope",
+			scopes:        []string{"flags:write"},
+			requiredScope: auth.APIKeyScopeUserRead,
+			wantHasScope:  false,
+		},
+		{
+			name:          "Admin scope implies every scope",
+			scopes:        []string{"admin"},
+			requiredScope: auth.APIKeyScopeUserRead,
+			wantHasScope:  true,
+		},
+		{
+			name:          "Admin scope implies client-side scope",
+			scopes:        []string{"admin"},
+			requiredScope: auth.APIKeyScopeFlagsClient,
+			wantHasScope:  true,
+		},
+		{
+			name:          "Client-side scope does not imply evaluate scope",
+			scopes:        []string{"flags:client"},
+			requiredScope: auth.APIKeyScopeFlagsEvaluate,
+			wantHasScope:  false,
+		},
+		{
+			name:          "Evaluate scope does not imply client-side scope",
+			scopes:        []string{"flags:evaluate"},
+			requiredScope: auth.APIKeyScopeFlagsClient,
+			wantHasScope:  false,
+		},
+		{
+			name:          "No scopes grant nothing",
+			scopes:        []string{},
+			requiredScope: auth.APIKeyScopeFlagsEvaluate,
+			wantHasScope:  false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testcase.wantHasScope, auth.HasAPIKeyScope(testcase.scopes, testcase.requiredScope))
+		})
+	}
+}
+
+func TestAPIKeyUsageBuffer(t *testing.T) {
+	t.Parallel()
+
+	today := time.Date(2024, 1, 29, 0, 0, 0, 0, time.UTC)
+	yesterday := today.AddDate(0, 0, -1)
+
+	buffer := auth.NewAPIKeyUsageBuffer()
+	buffer.Add(1, today.Add(2*time.Hour), "10.0.0.2", 1)
+	buffer.Add(1, today.Add(time.Hour), "10.0.0.1", 1)
+	buffer.Add(1, today.Add(3*time.Hour), "10.0.0.3", 2)
+	buffer.Add(1, yesterday.Add(23*time.Hour), "10.0.0.4", 1)
+	buffer.Add(2, today.Add(time.Hour), "10.0.0.5", 1)
+
+	wantRecords := []auth.APIKeyUsageBufferRecord{
+		{
+			APIKeyID:     1,
+			Day:          yesterday,
+			RequestCount: 1,
+			LastUsedAt:   yesterday.Add(23 * time.Hour),
+			LastUsedIP:   "10.0.0.4",
+		},
+		{
+			APIKeyID:     1,
+			Day:          today,
+			RequestCount: 4,
+			LastUsedAt:   today.Add(3 * time.Hour),
+			LastUsedIP:   "10.0.0.3",
+		},
+		{
+			APIKeyID:     2,
+			Day:          today,
+			RequestCount: 1,
+			LastUsedAt:   today.Add(time.Hour),
+			LastUsedIP:   "10.0.0.5",
+		},
+	}
+
+	require.Equal(t, wantRecords, buffer.Drain())
+	require.Empty(t, buffer.Drain())
+}
+
+func TestAPIKeyUsageBufferConcurrentAdd(t *testing.T) {
+	t.Parallel()
+
+	usedAt := time.Date(2024, 1, 29, 12, 0, 0, 0, time.UTC)
+	buffer := auth.NewAPIKeyUsageBuffer()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buffer.Add(42, usedAt, "10.0.0.1", 1)
+		}()
+	}
+	wg.Wait()
+
+	records := buffer.Drain()
+	require.Len(t, records, 1)
+	require.Equal(t, int64(100), records[0].RequestCount)
+}
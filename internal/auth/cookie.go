@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// token transports used to select how access and refresh JWTs are exchanged with clients.
+const (
+	TokenTransportHeader = "header"
+	TokenTransportCookie = "cookie"
+)
+
+// Cookie names used by cookie token transport.
+// The __Host- prefix makes browsers reject these cookies unless they are Secure, scoped to the root path,
+// and have no domain, so they cannot be planted by sibling subdomains to defeat the double-submit CSRF check.
+const (
+	AccessTokenCookieName  = "__Host-flagger_access"
+	RefreshTokenCookieName = "__Host-flagger_refresh"
+	CSRFTokenCookieName    = "__Host-flagger_csrf"
+)
+
+// CSRFTokenHeader is the header in which clients using cookie token transport must echo the CSRF token cookie.
+const CSRFTokenHeader = "X-CSRF-Token"
+
+// csrfTokenLength is the number of random bytes in CSRF tokens.
+const csrfTokenLength = 32
+
+// createCSRFToken creates a random CSRF token.
+func createCSRFToken() (string, error) {
+	tokenBytes := make([]byte, csrfTokenLength)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return "", fmt.Errorf("createCSRFToken failed to rand.Read: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+// newAuthCookie creates a cookie with the attributes shared by all cookies of cookie token transport.
+func newAuthCookie(name string, value string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(maxAge / time.Second),
+		Secure:   true,
+		HttpOnly: httpOnly,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// SetAuthCookies sets refresh JWT in an HttpOnly cookie,
+// along with a new CSRF token in a cookie that is readable by scripts.
+func SetAuthCookies(w http.ResponseWriter, refreshToken string, refreshLifetime time.Duration) error {
+	csrfToken, err := createCSRFToken()
+	if err != nil {
+		return fmt.Errorf("SetAuthCookies failed to createCSRFToken: %w", err)
+	}
+
+	http.SetCookie(w, newAuthCookie(RefreshTokenCookieName, refreshToken, refreshLifetime, true))
+	http.SetCookie(w, newAuthCookie(CSRFTokenCookieName, csrfToken, refreshLifetime, false))
+
+	return nil
+}
+
+// SetAccessTokenCookie sets access JWT in an HttpOnly cookie.
+func SetAccessTokenCookie(w http.ResponseWriter, accessToken string, accessLifetime time.Duration) {
+	http.SetCookie(w, newAuthCookie(AccessTokenCookieName, accessToken, accessLifetime, true))
+}
+
+// ClearAuthCookies expires access JWT, refresh JWT and CSRF token cookies.
+func ClearAuthCookies(w http.ResponseWriter) {
+	for _, name := range []string{AccessTokenCookieName, RefreshTokenCookieName, CSRFTokenCookieName} {
+		cookie := newAuthCookie(name, "", 0, name != CSRFTokenCookieName)
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+	}
+}
+
+// GetAuthCookie returns the value of a given cookie of cookie token transport.
+func GetAuthCookie(r *http.Request, name string) (string, bool) {
+	cookie, err := r.Cookie(name)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+
+	return cookie.Value, true
+}
+
+// CheckCSRFToken determines whether or not the CSRF token header of a request matches its CSRF token cookie.
+// Since other sites can neither read the cookie nor set custom headers on cross-site requests,
+// a match shows that the request was made by the frontend the cookie was issued to.
+func CheckCSRFToken(r *http.Request) bool {
+	cookieToken, ok := GetAuthCookie(r, CSRFTokenCookieName)
+	if !ok {
+		return false
+	}
+
+	headerToken := r.Header.Get(CSRFTokenHeader)
+	if headerToken == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookieToken), []byte(headerToken)) == 1
+}
@@ -0,0 +1,236 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alvii147/flagger-api/internal/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAuthCookies(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	err := auth.SetAuthCookies(rec, "r3fr35ht0k3n", 24*time.Hour)
+	require.NoError(t, err)
+
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range rec.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	require.Len(t, cookies, 2)
+
+	testcases := []struct {
+		name         string
+		cookieName   string
+		wantValue    string
+		wantMaxAge   int
+		wantHttpOnly bool
+	}{
+		{
+			name:         "Refresh token cookie",
+			cookieName:   auth.RefreshTokenCookieName,
+			wantValue:    "r3fr35ht0k3n",
+			wantMaxAge:   86400,
+			wantHttpOnly: true,
+		},
+		{
+			name:         "CSRF token cookie",
+			cookieName:   auth.CSRFTokenCookieName,
+			wantValue:    "",
+			wantMaxAge:   86400,
+			wantHttpOnly: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			cookie, ok := cookies[testcase.cookieName]
+			require.True(t, ok)
+
+			if testcase.wantValue != "" {
+				require.Equal(t, testcase.wantValue, cookie.Value)
+			} else {
+				require.Len(t, cookie.Value, 43)
+			}
+
+			require.Equal(t, "/", cookie.Path)
+			require.Empty(t, cookie.Domain)
+			require.Equal(t, testcase.wantMaxAge, cookie.MaxAge)
+			require.True(t, cookie.Secure)
+			require.Equal(t, testcase.wantHttpOnly, cookie.HttpOnly)
+			require.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+		})
+	}
+}
+
+func TestSetAuthCookiesCreatesNewCSRFToken(t *testing.T) {
+	t.Parallel()
+
+	csrfTokens := make(map[string]struct{})
+	for i := 0; i < 5; i++ {
+		rec := httptest.NewRecorder()
+		err := auth.SetAuthCookies(rec, "r3fr35ht0k3n", time.Hour)
+		require.NoError(t, err)
+
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == auth.CSRFTokenCookieName {
+				csrfTokens[cookie.Value] = struct{}{}
+			}
+		}
+	}
+
+	require.Len(t, csrfTokens, 5)
+}
+
+func TestSetAccessTokenCookie(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	auth.SetAccessTokenCookie(rec, "4cc355t0k3n", 30*time.Minute)
+
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	cookie := cookies[0]
+	require.Equal(t, auth.AccessTokenCookieName, cookie.Name)
+	require.Equal(t, "4cc355t0k3n", cookie.Value)
+	require.Equal(t, "/", cookie.Path)
+	require.Empty(t, cookie.Domain)
+	require.Equal(t, 1800, cookie.MaxAge)
+	require.True(t, cookie.Secure)
+	require.True(t, cookie.HttpOnly)
+	require.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+}
+
+func TestClearAuthCookies(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	auth.ClearAuthCookies(rec)
+
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 3)
+
+	for _, cookie := range cookies {
+		require.Contains(t, []string{auth.AccessTokenCookieName, auth.RefreshTokenCookieName, auth.CSRFTokenCookieName}, cookie.Name)
+		require.Empty(t, cookie.Value)
+		require.Equal(t, "/", cookie.Path)
+		require.Less(t, cookie.MaxAge, 0)
+		require.True(t, cookie.Secure)
+	}
+}
+
+func TestGetAuthCookie(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name      string
+		cookie    *http.Cookie
+		wantValue string
+		wantOk    bool
+	}{
+		{
+			name:      "Cookie is set",
+			cookie:    &http.Cookie{Name: auth.AccessTokenCookieName, Value: "4cc355t0k3n"},
+			wantValue: "4cc355t0k3n",
+			wantOk:    true,
+		},
+		{
+			name:      "Cookie is empty",
+			cookie:    &http.Cookie{Name: auth.AccessTokenCookieName, Value: ""},
+			wantValue: "",
+			wantOk:    false,
+		},
+		{
+			name:      "Different cookie is set",
+			cookie:    &http.Cookie{Name: auth.RefreshTokenCookieName, Value: "r3fr35ht0k3n"},
+			wantValue: "",
+			wantOk:    false,
+		},
+		{
+			name:      "No cookie is set",
+			cookie:    nil,
+			wantValue: "",
+			wantOk:    false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/auth/users/me", http.NoBody)
+			if testcase.cookie != nil {
+				r.AddCookie(testcase.cookie)
+			}
+
+			value, ok := auth.GetAuthCookie(r, auth.AccessTokenCookieName)
+			require.Equal(t, testcase.wantOk, ok)
+			require.Equal(t, testcase.wantValue, value)
+		})
+	}
+}
+
+func TestCheckCSRFToken(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		cookieToken string
+		headerToken string
+		wantOk      bool
+	}{
+		{
+			name:        "Matching tokens",
+			cookieToken: "c5rft0k3n",
+			headerToken: "c5rft0k3n",
+			wantOk:      true,
+		},
+		{
+			name:        "Mismatched tokens",
+			cookieToken: "c5rft0k3n",
+			headerToken: "0th3rc5rft0k3n",
+			wantOk:      false,
+		},
+		{
+			name:        "Missing header",
+			cookieToken: "c5rft0k3n",
+			headerToken: "",
+			wantOk:      false,
+		},
+		{
+			name:        "Missing cookie",
+			cookieToken: "",
+			headerToken: "c5rft0k3n",
+			wantOk:      false,
+		},
+		{
+			name:        "Missing cookie and header",
+			cookieToken: "",
+			headerToken: "",
+			wantOk:      false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPost, "/auth/logout", http.NoBody)
+			if testcase.cookieToken != "" {
+				r.AddCookie(&http.Cookie{Name: auth.CSRFTokenCookieName, Value: testcase.cookieToken})
+			}
+			if testcase.headerToken != "" {
+				r.Header.Set(auth.CSRFTokenHeader, testcase.headerToken)
+			}
+
+			require.Equal(t, testcase.wantOk, auth.CheckCSRFToken(r))
+		})
+	}
+}
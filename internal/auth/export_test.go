@@ -1,12 +1,114 @@
 package auth
 
+import (
+	"sort"
+	"time"
+)
+
+const ActivationResendCooldown = activationResendCooldown
+
+const SessionUserAgentMaxLength = sessionUserAgentMaxLength
+
+const TOTPChallengeLifetime = totpChallengeLifetime
+
+const TOTPChallengeMaxAttempts = totpChallengeMaxAttempts
+
+const RecoveryCodeCount = recoveryCodeCount
+
+const OIDCAuthRequestLifetime = oidcAuthRequestLifetime
+
+const UserNameMaxLength = userNameMaxLength
+
+const ImpersonationLifetime = impersonationLifetime
+
+const LoginFailureWindow = loginFailureWindow
+
+const LoginAccountFailureThreshold = loginAccountFailureThreshold
+
+const LoginIPFailureThreshold = loginIPFailureThreshold
+
+const LoginLockoutBaseDuration = loginLockoutBaseDuration
+
+const LoginLockoutMaxDuration = loginLockoutMaxDuration
+
+const MagicLinkCooldown = magicLinkCooldown
+
+const PasswordResetCooldown = passwordResetCooldown
+
 var (
-	HashPassword          = hashPassword
-	CreateAuthJWT         = createAuthJWT
-	ValidateAuthJWT       = validateAuthJWT
-	CreateActivationJWT   = createActivationJWT
-	ValidateActivationJWT = validateActivationJWT
-	SendActivationMail    = sendActivationMail
-	CreateAPIKey          = createAPIKey
-	ParseAPIKey           = parseAPIKey
+	CreateAuthJWT             = createAuthJWT
+	ValidateAuthJWT           = validateAuthJWT
+	CreateImpersonationJWT    = createImpersonationJWT
+	LoginAccountIdentifier    = loginAccountIdentifier
+	LoginLockoutDuration      = loginLockoutDuration
+	TruncateUserAgent         = truncateUserAgent
+	TruncateUserName          = truncateUserName
+	CreateOIDCAuthRequest     = createOIDCAuthRequest
+	CreateActivationJWT       = createActivationJWT
+	ValidateActivationJWT     = validateActivationJWT
+	SendActivationMail        = sendActivationMail
+	PasswordFingerprint       = passwordFingerprint
+	CreatePasswordResetJWT    = createPasswordResetJWT
+	ValidatePasswordResetJWT  = validatePasswordResetJWT
+	SendPasswordResetMail     = sendPasswordResetMail
+	CreateEmailChangeJWT      = createEmailChangeJWT
+	ValidateEmailChangeJWT    = validateEmailChangeJWT
+	SendEmailChangeMail       = sendEmailChangeMail
+	SendEmailChangeNoticeMail = sendEmailChangeNoticeMail
+	SendAccountLockoutMail    = sendAccountLockoutMail
+	CreateMagicLinkJWT        = createMagicLinkJWT
+	ValidateMagicLinkJWT      = validateMagicLinkJWT
+	SendMagicLinkMail         = sendMagicLinkMail
+	CreateTOTPChallengeJWT    = createTOTPChallengeJWT
+	ValidateTOTPChallengeJWT  = validateTOTPChallengeJWT
+	IsTOTPCode                = isTOTPCode
+	CreateRecoveryCodes       = createRecoveryCodes
+	HashRecoveryCode          = hashRecoveryCode
+	CreateAPIKey              = createAPIKey
+	APIKeyDigest              = apiKeyDigest
+	ParseAPIKey               = parseAPIKey
+	NormalizeAPIKeyScopes     = normalizeAPIKeyScopes
+	NormalizeAPIKeyCIDRs      = normalizeAPIKeyCIDRs
+	APIKeyAllowsIP            = apiKeyAllowsIP
+	NormalizeAPIKeyOrigins    = normalizeAPIKeyOrigins
+	APIKeyAllowsOrigin        = apiKeyAllowsOrigin
+	HasAPIKeyScope            = hasAPIKeyScope
 )
+
+var NewAPIKeyUsageBuffer = newAPIKeyUsageBuffer
+
+// APIKeyUsageBufferRecord is a flattened view of a daily bucket in apiKeyUsageBuffer.
+type APIKeyUsageBufferRecord struct {
+	APIKeyID     int
+	Day          time.Time
+	RequestCount int64
+	LastUsedAt   time.Time
+	LastUsedIP   string
+}
+
+func (b *apiKeyUsageBuffer) Add(apiKeyID int, usedAt time.Time, ipAddress string, requestCount int64) {
+	b.add(apiKeyID, usedAt, ipAddress, requestCount)
+}
+
+func (b *apiKeyUsageBuffer) Drain() []APIKeyUsageBufferRecord {
+	records := make([]APIKeyUsageBufferRecord, 0)
+	for key, record := range b.drain() {
+		records = append(records, APIKeyUsageBufferRecord{
+			APIKeyID:     key.apiKeyID,
+			Day:          key.day,
+			RequestCount: record.requestCount,
+			LastUsedAt:   record.lastUsedAt,
+			LastUsedIP:   record.lastUsedIP,
+		})
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].APIKeyID != records[j].APIKeyID {
+			return records[i].APIKeyID < records[j].APIKeyID
+		}
+
+		return records[i].Day.Before(records[j].Day)
+	})
+
+	return records
+}
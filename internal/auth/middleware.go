@@ -3,19 +3,44 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/alvii147/flagger-api/internal/audit"
 	"github.com/alvii147/flagger-api/pkg/api"
 	"github.com/alvii147/flagger-api/pkg/errutils"
 	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/alvii147/flagger-api/pkg/logging"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-// JWTAuthMiddleware parses and validates JWT from authorization header.
+// JWTAuthMiddleware parses and validates JWT from authorization header,
+// and checks that its session has not been revoked.
+// When access token cookies are enabled, the JWT is read from the access token cookie when there is no authorization header,
+// in which case requests with unsafe methods must also pass the double-submit CSRF check, or are rejected with 403.
 // If authentication fails, it returns 401.
-// If authentication is successful, it sets User UUID in context.
-func JWTAuthMiddleware(next httputils.HandlerFunc, secretKey string) httputils.HandlerFunc {
+// If authentication is successful, it sets User UUID and session ID in context.
+// Requests made using impersonation JWTs also have the superuser's UUID set in context,
+// are tagged in the traffic log, and are recorded in the audit log.
+// If the impersonation JWT is read-only, requests with unsafe methods are rejected with 403.
+func JWTAuthMiddleware(next httputils.HandlerFunc, svc Service, auditService audit.Service, accessTokenCookie bool) httputils.HandlerFunc {
 	return httputils.HandlerFunc(func(w *httputils.ResponseWriter, r *http.Request) {
 		token, ok := httputils.GetAuthorizationHeader(r.Header, "Bearer")
+		if !ok && accessTokenCookie && r.Header.Get("Authorization") == "" {
+			token, ok = GetAuthCookie(r, AccessTokenCookieName)
+			if ok && !httputils.IsSafeMethod(r.Method) && !CheckCSRFToken(r) {
+				w.WriteJSON(
+					api.ErrorResponse{
+						Code:   api.ErrCodePermissionDenied,
+						Detail: api.ErrDetailInvalidCSRFToken,
+					},
+					http.StatusForbidden,
+				)
+				return
+			}
+		}
+
 		if !ok {
 			w.WriteJSON(
 				api.ErrorResponse{
@@ -27,8 +52,8 @@ func JWTAuthMiddleware(next httputils.HandlerFunc, secretKey string) httputils.H
 			return
 		}
 
-		claims, ok := validateAuthJWT(token, JWTTypeAccess, secretKey)
-		if !ok {
+		claims, err := svc.AuthenticateJWT(r.Context(), token)
+		if err != nil {
 			w.WriteJSON(
 				api.ErrorResponse{
 					Code:   api.ErrCodeInvalidCredentials,
@@ -39,14 +64,49 @@ func JWTAuthMiddleware(next httputils.HandlerFunc, secretKey string) httputils.H
 			return
 		}
 
-		next.ServeHTTP(w, r.Clone(context.WithValue(r.Context(), AuthContextKeyUserUUID, claims.Subject)))
+		ctx := context.WithValue(r.Context(), AuthContextKeyUserUUID, claims.Subject)
+		ctx = context.WithValue(ctx, AuthContextKeySessionID, claims.SessionID)
+
+		if claims.Actor != nil {
+			ctx = context.WithValue(ctx, AuthContextKeyActorUUID, claims.Actor.Subject)
+			logging.AddTrafficTag(ctx, "impersonated_by", claims.Actor.Subject)
+
+			defer func() {
+				auditService.Record(r.Context(), &audit.AuditLog{
+					EventType: string(audit.EventTypeImpersonatedRequest),
+					UserUUID:  claims.Subject,
+					ActorUUID: pgtype.Text{
+						String: claims.Actor.Subject,
+						Valid:  true,
+					},
+					IPAddress: httputils.GetClientIP(r),
+					Detail:    fmt.Sprintf("%s %s %d", r.Method, r.URL.Path, w.StatusCode),
+				})
+			}()
+
+			if claims.ReadOnly && !httputils.IsSafeMethod(r.Method) {
+				w.WriteJSON(
+					api.ErrorResponse{
+						Code:   api.ErrCodePermissionDenied,
+						Detail: api.ErrDetailImpersonationReadOnly,
+					},
+					http.StatusForbidden,
+				)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.Clone(ctx))
 	})
 }
 
 // APIKeyAuthMiddleware authenticates user using provided API Key.
 // If authentication fails, it returns 401.
-// If authentication is successful, it sets User UUID in context.
-func APIKeyAuthMiddleware(next httputils.HandlerFunc, svc Service) httputils.HandlerFunc {
+// If the API key is not allowed to be used from the client's IP address,
+// it records the denial in the audit log and returns 403.
+// If authentication is successful, it records usage of the API key,
+// and sets User UUID, API key ID, API key scopes, and service account UUID of service account API keys in context.
+func APIKeyAuthMiddleware(next httputils.HandlerFunc, svc Service, auditService audit.Service) httputils.HandlerFunc {
 	return httputils.HandlerFunc(func(w *httputils.ResponseWriter, r *http.Request) {
 		rawKey, ok := httputils.GetAuthorizationHeader(r.Header, "X-API-Key")
 		if !ok {
@@ -83,6 +143,163 @@ func APIKeyAuthMiddleware(next httputils.HandlerFunc, svc Service) httputils.Han
 			return
 		}
 
-		next.ServeHTTP(w, r.Clone(context.WithValue(r.Context(), AuthContextKeyUserUUID, apiKey.UserUUID)))
+		clientIP := httputils.GetClientIP(r)
+		if !apiKeyAllowsIP(apiKey.AllowedCIDRs, clientIP) {
+			auditService.Record(r.Context(), &audit.AuditLog{
+				EventType:          string(audit.EventTypeAPIKeyIPDenied),
+				UserUUID:           apiKey.UserUUID,
+				ServiceAccountUUID: apiKey.ServiceAccountUUID,
+				APIKeyID: pgtype.Int4{
+					Int32: int32(apiKey.ID),
+					Valid: true,
+				},
+				IPAddress: clientIP,
+				Detail:    fmt.Sprintf("%s %s", r.Method, r.URL.Path),
+			})
+
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodePermissionDenied,
+					Detail: api.ErrDetailAPIKeyIPDenied,
+				},
+				http.StatusForbidden,
+			)
+			return
+		}
+
+		if !apiKeyAllowsOrigin(apiKey.AllowedOrigins, r.Header.Get("Origin")) {
+			auditService.Record(r.Context(), &audit.AuditLog{
+				EventType:          string(audit.EventTypeAPIKeyOriginDenied),
+				UserUUID:           apiKey.UserUUID,
+				ServiceAccountUUID: apiKey.ServiceAccountUUID,
+				APIKeyID: pgtype.Int4{
+					Int32: int32(apiKey.ID),
+					Valid: true,
+				},
+				IPAddress: clientIP,
+				Detail:    fmt.Sprintf("%s %s from %s", r.Method, r.URL.Path, r.Header.Get("Origin")),
+			})
+
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodePermissionDenied,
+					Detail: api.ErrDetailAPIKeyOriginDenied,
+				},
+				http.StatusForbidden,
+			)
+			return
+		}
+
+		svc.RecordAPIKeyUsage(apiKey.ID, clientIP)
+
+		ctx := context.WithValue(r.Context(), AuthContextKeyUserUUID, apiKey.UserUUID)
+		ctx = context.WithValue(ctx, AuthContextKeyAPIKeyID, apiKey.ID)
+		ctx = context.WithValue(ctx, AuthContextKeyAPIKeyScopes, apiKey.Scopes)
+		if apiKey.ServiceAccountUUID.Valid {
+			ctx = context.WithValue(ctx, AuthContextKeyServiceAccountUUID, apiKey.ServiceAccountUUID.String)
+		}
+		next.ServeHTTP(w, r.Clone(ctx))
+	})
+}
+
+// RequireAPIKeyScope checks that the API key authenticated by APIKeyAuthMiddleware grants a given scope.
+// If the scope is not granted, it returns 403.
+func RequireAPIKeyScope(next httputils.HandlerFunc, scope APIKeyScope) httputils.HandlerFunc {
+	return httputils.HandlerFunc(func(w *httputils.ResponseWriter, r *http.Request) {
+		scopes, _ := r.Context().Value(AuthContextKeyAPIKeyScopes).([]string)
+		if !hasAPIKeyScope(scopes, scope) {
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodePermissionDenied,
+					Detail: api.ErrDetailAPIKeyScopeDenied,
+				},
+				http.StatusForbidden,
+			)
+			return
+		}
+
+		next.ServeHTTP(w, r)
 	})
 }
+
+// RequireSuperUser checks that the User authenticated by JWTAuthMiddleware is an active superuser.
+// If the User is not a superuser, or the request is made using an impersonation JWT, it returns 403.
+func RequireSuperUser(next httputils.HandlerFunc, svc Service) httputils.HandlerFunc {
+	return httputils.HandlerFunc(func(w *httputils.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(AuthContextKeyActorUUID).(string); ok {
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodePermissionDenied,
+					Detail: api.ErrDetailSuperUserRequired,
+				},
+				http.StatusForbidden,
+			)
+			return
+		}
+
+		user, err := svc.GetCurrentUser(r.Context())
+		if err != nil && !errors.Is(err, errutils.ErrUserNotFound) {
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+			return
+		}
+
+		if err != nil || !user.IsSuperUser {
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodePermissionDenied,
+					Detail: api.ErrDetailSuperUserRequired,
+				},
+				http.StatusForbidden,
+			)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateLimitKeyAPIKey extracts the ID of the API key authenticated by APIKeyAuthMiddleware.
+// Rate limits using this must be applied after APIKeyAuthMiddleware.
+func RateLimitKeyAPIKey(r *http.Request) (string, bool) {
+	apiKeyID, ok := r.Context().Value(AuthContextKeyAPIKeyID).(int)
+	if !ok {
+		return "", false
+	}
+
+	return strconv.Itoa(apiKeyID), true
+}
+
+// RateLimitKeyAPIKeyClientIP extracts the ID of the API key authenticated by APIKeyAuthMiddleware
+// along with the IP address of the client that sent the request.
+// This is used for client-side API keys, which are shared by every browser the key is shipped to.
+// Rate limits using this must be applied after APIKeyAuthMiddleware.
+func RateLimitKeyAPIKeyClientIP(r *http.Request) (string, bool) {
+	apiKeyID, ok := RateLimitKeyAPIKey(r)
+	if !ok {
+		return "", false
+	}
+
+	clientIP, ok := httputils.RateLimitKeyClientIP(r)
+	if !ok {
+		return "", false
+	}
+
+	return apiKeyID + ":" + clientIP, true
+}
+
+// RateLimitKeyUser extracts the UUID of the User authenticated by JWTAuthMiddleware or APIKeyAuthMiddleware.
+// Rate limits using this must be applied after authentication middleware.
+func RateLimitKeyUser(r *http.Request) (string, bool) {
+	userUUID, ok := r.Context().Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return "", false
+	}
+
+	return userUUID, true
+}
@@ -1,6 +1,7 @@
 package auth_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alvii147/flagger-api/internal/audit"
 	"github.com/alvii147/flagger-api/internal/auth"
 	"github.com/alvii147/flagger-api/internal/env"
 	"github.com/alvii147/flagger-api/internal/templatesmanager"
@@ -20,22 +22,49 @@ import (
 	"github.com/alvii147/flagger-api/pkg/utils"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
 func TestJWTAuthMiddleware(t *testing.T) {
 	t.Parallel()
 
-	userUUID := uuid.NewString()
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+	auditSvc := audit.NewService(dbPool, logger, audit.NewRepository())
+
+	userUUID := user.UUID
 	jti := uuid.NewString()
 	now := time.Now().UTC()
 	oneDayAgo := now.Add(-24 * time.Hour)
-	secretKey := "deadbeef"
+	secretKey := config.SecretKey
+
+	validAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, userUUID)
+
+	revokedSessionAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, userUUID)
+	revokedSessionClaims, ok := auth.ValidateAuthJWT(revokedSessionAccessToken, auth.JWTTypeAccess, keySet)
+	require.True(t, ok)
+	err = repo.RevokeSession(dbConn, revokedSessionClaims.SessionID, userUUID)
+	require.NoError(t, err)
 
-	validAccessToken, err := jwt.NewWithClaims(
+	unknownSessionAccessToken, err := jwt.NewWithClaims(
 		jwt.SigningMethodHS256,
 		&api.AuthJWTClaims{
 			Subject:   userUUID,
+			SessionID: uuid.NewString(),
 			TokenType: string(auth.JWTTypeAccess),
 			IssuedAt:  utils.JSONTimeStamp(now),
 			ExpiresAt: utils.JSONTimeStamp(now.Add(time.Hour)),
@@ -148,6 +177,24 @@ func TestJWTAuthMiddleware(t *testing.T) {
 			setAuthHeader:  true,
 			authHeader:     fmt.Sprintf("Bearer %s", tokenOfInvalidType),
 		},
+		{
+			name:           "Authentication with JWT of revoked session is unauthorized",
+			wantNextCall:   false,
+			wantErr:        true,
+			wantErrCode:    api.ErrCodeInvalidCredentials,
+			wantStatusCode: http.StatusUnauthorized,
+			setAuthHeader:  true,
+			authHeader:     fmt.Sprintf("Bearer %s", revokedSessionAccessToken),
+		},
+		{
+			name:           "Authentication with JWT of unknown session is unauthorized",
+			wantNextCall:   false,
+			wantErr:        true,
+			wantErrCode:    api.ErrCodeInvalidCredentials,
+			wantStatusCode: http.StatusUnauthorized,
+			setAuthHeader:  true,
+			authHeader:     fmt.Sprintf("Bearer %s", unknownSessionAccessToken),
+		},
 		{
 			name:           "Authentication with JWT with invalid claim is unauthorized",
 			wantNextCall:   false,
@@ -166,6 +213,7 @@ func TestJWTAuthMiddleware(t *testing.T) {
 			nextCallCount := 0
 			var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
 				require.Equal(t, userUUID, r.Context().Value(auth.AuthContextKeyUserUUID))
+				require.NotEmpty(t, r.Context().Value(auth.AuthContextKeySessionID))
 				w.WriteJSON(validResponse, validStatusCode)
 				nextCallCount++
 			}
@@ -181,7 +229,7 @@ func TestJWTAuthMiddleware(t *testing.T) {
 				r.Header.Set("Authorization", testcase.authHeader)
 			}
 
-			auth.JWTAuthMiddleware(next, secretKey)(w, r)
+			auth.JWTAuthMiddleware(next, svc, auditSvc, false)(w, r)
 
 			result := rec.Result()
 			t.Cleanup(func() {
@@ -216,6 +264,329 @@ func TestJWTAuthMiddleware(t *testing.T) {
 	}
 }
 
+func TestJWTAuthMiddlewareCookieTransport(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+	auditSvc := audit.NewService(dbPool, logger, audit.NewRepository())
+
+	accessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	csrfToken := "c5rft0k3n"
+
+	testcases := []struct {
+		name              string
+		accessTokenCookie bool
+		method            string
+		authHeader        string
+		accessCookie      string
+		csrfCookie        string
+		csrfHeader        string
+		wantNextCall      bool
+		wantErrCode       string
+		wantErrDetail     string
+		wantStatusCode    int
+	}{
+		{
+			name:              "Access token cookie with safe method is authenticated",
+			accessTokenCookie: true,
+			method:            http.MethodGet,
+			authHeader:        "",
+			accessCookie:      accessToken,
+			csrfCookie:        "",
+			csrfHeader:        "",
+			wantNextCall:      true,
+			wantErrCode:       "",
+			wantErrDetail:     "",
+			wantStatusCode:    http.StatusOK,
+		},
+		{
+			name:              "Access token cookie with unsafe method and matching CSRF token is authenticated",
+			accessTokenCookie: true,
+			method:            http.MethodPost,
+			authHeader:        "",
+			accessCookie:      accessToken,
+			csrfCookie:        csrfToken,
+			csrfHeader:        csrfToken,
+			wantNextCall:      true,
+			wantErrCode:       "",
+			wantErrDetail:     "",
+			wantStatusCode:    http.StatusOK,
+		},
+		{
+			name:              "Access token cookie with unsafe method and no CSRF header is forbidden",
+			accessTokenCookie: true,
+			method:            http.MethodPost,
+			authHeader:        "",
+			accessCookie:      accessToken,
+			csrfCookie:        csrfToken,
+			csrfHeader:        "",
+			wantNextCall:      false,
+			wantErrCode:       api.ErrCodePermissionDenied,
+			wantErrDetail:     api.ErrDetailInvalidCSRFToken,
+			wantStatusCode:    http.StatusForbidden,
+		},
+		{
+			name:              "Access token cookie with unsafe method and mismatched CSRF token is forbidden",
+			accessTokenCookie: true,
+			method:            http.MethodDelete,
+			authHeader:        "",
+			accessCookie:      accessToken,
+			csrfCookie:        csrfToken,
+			csrfHeader:        "0th3rc5rft0k3n",
+			wantNextCall:      false,
+			wantErrCode:       api.ErrCodePermissionDenied,
+			wantErrDetail:     api.ErrDetailInvalidCSRFToken,
+			wantStatusCode:    http.StatusForbidden,
+		},
+		{
+			name:              "Invalid access token cookie is unauthorized",
+			accessTokenCookie: true,
+			method:            http.MethodGet,
+			authHeader:        "",
+			accessCookie:      "ed0730889507fdb8549acfcd31548ee5",
+			csrfCookie:        "",
+			csrfHeader:        "",
+			wantNextCall:      false,
+			wantErrCode:       api.ErrCodeInvalidCredentials,
+			wantErrDetail:     api.ErrDetailInvalidToken,
+			wantStatusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:              "No access token cookie is unauthorized",
+			accessTokenCookie: true,
+			method:            http.MethodGet,
+			authHeader:        "",
+			accessCookie:      "",
+			csrfCookie:        "",
+			csrfHeader:        "",
+			wantNextCall:      false,
+			wantErrCode:       api.ErrCodeMissingCredentials,
+			wantErrDetail:     api.ErrDetailMissingCredentials,
+			wantStatusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:              "Authorization header with unsafe method does not need CSRF token",
+			accessTokenCookie: true,
+			method:            http.MethodPost,
+			authHeader:        fmt.Sprintf("Bearer %s", accessToken),
+			accessCookie:      "",
+			csrfCookie:        "",
+			csrfHeader:        "",
+			wantNextCall:      true,
+			wantErrCode:       "",
+			wantErrDetail:     "",
+			wantStatusCode:    http.StatusOK,
+		},
+		{
+			name:              "Access token cookie is ignored when disabled",
+			accessTokenCookie: false,
+			method:            http.MethodGet,
+			authHeader:        "",
+			accessCookie:      accessToken,
+			csrfCookie:        "",
+			csrfHeader:        "",
+			wantNextCall:      false,
+			wantErrCode:       api.ErrCodeMissingCredentials,
+			wantErrDetail:     api.ErrDetailMissingCredentials,
+			wantStatusCode:    http.StatusUnauthorized,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			nextCallCount := 0
+			var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
+				require.Equal(t, user.UUID, r.Context().Value(auth.AuthContextKeyUserUUID))
+				w.WriteJSON(map[string]any{}, http.StatusOK)
+				nextCallCount++
+			}
+
+			rec := httptest.NewRecorder()
+			w := &httputils.ResponseWriter{
+				ResponseWriter: rec,
+				StatusCode:     -1,
+			}
+			r := httptest.NewRequest(testcase.method, "/auth/logout", http.NoBody)
+
+			if testcase.authHeader != "" {
+				r.Header.Set("Authorization", testcase.authHeader)
+			}
+			if testcase.accessCookie != "" {
+				r.AddCookie(&http.Cookie{Name: auth.AccessTokenCookieName, Value: testcase.accessCookie})
+			}
+			if testcase.csrfCookie != "" {
+				r.AddCookie(&http.Cookie{Name: auth.CSRFTokenCookieName, Value: testcase.csrfCookie})
+			}
+			if testcase.csrfHeader != "" {
+				r.Header.Set(auth.CSRFTokenHeader, testcase.csrfHeader)
+			}
+
+			auth.JWTAuthMiddleware(next, svc, auditSvc, testcase.accessTokenCookie)(w, r)
+
+			result := rec.Result()
+			t.Cleanup(func() {
+				err := result.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, result.StatusCode)
+
+			wantNextCallCount := 0
+			if testcase.wantNextCall {
+				wantNextCallCount = 1
+			}
+			require.Equal(t, wantNextCallCount, nextCallCount)
+
+			if !testcase.wantNextCall {
+				var errResp api.ErrorResponse
+				err := json.NewDecoder(result.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestJWTAuthMiddlewareImpersonation(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+	auditRepo := audit.NewRepository()
+	auditSvc := audit.NewService(dbPool, logger, auditRepo)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	superUserAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, superUser.UUID)
+	superUserClaims, ok := auth.ValidateAuthJWT(superUserAccessToken, auth.JWTTypeAccess, keySet)
+	require.True(t, ok)
+
+	testcases := []struct {
+		name           string
+		readOnly       bool
+		method         string
+		wantNextCall   bool
+		wantStatusCode int
+	}{
+		{
+			name:           "Read-only impersonation allows GET",
+			readOnly:       true,
+			method:         http.MethodGet,
+			wantNextCall:   true,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "Read-only impersonation forbids POST",
+			readOnly:       true,
+			method:         http.MethodPost,
+			wantNextCall:   false,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Read-only impersonation forbids DELETE",
+			readOnly:       true,
+			method:         http.MethodDelete,
+			wantNextCall:   false,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Impersonation with writes allowed allows POST",
+			readOnly:       false,
+			method:         http.MethodPost,
+			wantNextCall:   true,
+			wantStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+				u.IsActive = true
+			})
+
+			ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, superUser.UUID)
+			ctx = context.WithValue(ctx, auth.AuthContextKeySessionID, superUserClaims.SessionID)
+			token, _, err := svc.ImpersonateUser(ctx, user.UUID, testcase.readOnly)
+			require.NoError(t, err)
+
+			nextCallCount := 0
+			var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
+				require.Equal(t, user.UUID, r.Context().Value(auth.AuthContextKeyUserUUID))
+				require.Equal(t, superUser.UUID, r.Context().Value(auth.AuthContextKeyActorUUID))
+				w.WriteHeader(http.StatusOK)
+				nextCallCount++
+			}
+
+			rec := httptest.NewRecorder()
+			w := &httputils.ResponseWriter{
+				ResponseWriter: rec,
+				StatusCode:     -1,
+			}
+			r := httptest.NewRequest(testcase.method, "/flags", http.NoBody)
+			r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			auth.JWTAuthMiddleware(next, svc, auditSvc, false)(w, r)
+
+			result := rec.Result()
+			t.Cleanup(func() {
+				err := result.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, result.StatusCode)
+
+			wantNextCallCount := 0
+			if testcase.wantNextCall {
+				wantNextCallCount = 1
+			} else {
+				var errResp api.ErrorResponse
+				err := json.NewDecoder(result.Body).Decode(&errResp)
+				require.NoError(t, err)
+				require.Equal(t, api.ErrCodePermissionDenied, errResp.Code)
+				require.Equal(t, api.ErrDetailImpersonationReadOnly, errResp.Detail)
+			}
+			require.Equal(t, wantNextCallCount, nextCallCount)
+
+			auditLogs, err := auditRepo.ListAuditLogsByUserUUID(dbConn, user.UUID)
+			require.NoError(t, err)
+			require.Len(t, auditLogs, 1)
+			require.Equal(t, string(audit.EventTypeImpersonatedRequest), auditLogs[0].EventType)
+			require.Equal(t, pgtype.Text{String: superUser.UUID, Valid: true}, auditLogs[0].ActorUUID)
+			require.Equal(t, fmt.Sprintf("%s /flags %d", testcase.method, testcase.wantStatusCode), auditLogs[0].Detail)
+		})
+	}
+}
+
 func TestAPIKeyAuthMiddleware(t *testing.T) {
 	t.Parallel()
 
@@ -231,9 +602,11 @@ func TestAPIKeyAuthMiddleware(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+	auditSvc := audit.NewService(dbPool, logger, audit.NewRepository())
 
-	_, validAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+	apiKey, validAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
 
 	validResponse := map[string]any{
 		"email":      user.Email,
@@ -297,6 +670,9 @@ func TestAPIKeyAuthMiddleware(t *testing.T) {
 			nextCallCount := 0
 			var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
 				require.Equal(t, user.UUID, r.Context().Value(auth.AuthContextKeyUserUUID))
+				require.Equal(t, apiKey.ID, r.Context().Value(auth.AuthContextKeyAPIKeyID))
+				require.Equal(t, []string{"admin"}, r.Context().Value(auth.AuthContextKeyAPIKeyScopes))
+				require.Nil(t, r.Context().Value(auth.AuthContextKeyServiceAccountUUID))
 				w.WriteJSON(validResponse, validStatusCode)
 				nextCallCount++
 			}
@@ -312,7 +688,7 @@ func TestAPIKeyAuthMiddleware(t *testing.T) {
 				r.Header.Set("Authorization", testcase.authHeader)
 			}
 
-			auth.APIKeyAuthMiddleware(next, svc)(w, r)
+			auth.APIKeyAuthMiddleware(next, svc, auditSvc)(w, r)
 
 			result := rec.Result()
 			t.Cleanup(func() {
@@ -346,3 +722,611 @@ func TestAPIKeyAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIKeyAuthMiddlewareAllowedCIDRs(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+	auditSvc := audit.NewService(dbPool, logger, audit.NewRepository())
+
+	_, restrictedRawKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.AllowedCIDRs = []string{"10.0.0.0/8", "2001:db8::/32"}
+	})
+
+	testcases := []struct {
+		name           string
+		remoteAddr     string
+		wantNextCall   bool
+		wantStatusCode int
+	}{
+		{
+			name:           "IPv4 address in allowed range",
+			remoteAddr:     "10.1.2.3:54321",
+			wantNextCall:   true,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "IPv6 address in allowed range",
+			remoteAddr:     "[2001:db8::1]:54321",
+			wantNextCall:   true,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "IPv4 address outside allowed ranges",
+			remoteAddr:     "203.0.113.42:54321",
+			wantNextCall:   false,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			nextCallCount := 0
+			var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
+				w.WriteJSON(map[string]any{}, http.StatusOK)
+				nextCallCount++
+			}
+
+			rec := httptest.NewRecorder()
+			w := &httputils.ResponseWriter{
+				ResponseWriter: rec,
+				StatusCode:     -1,
+			}
+			r := httptest.NewRequest(http.MethodGet, "/api/flags", http.NoBody)
+			r.RemoteAddr = testcase.remoteAddr
+			r.Header.Set("Authorization", "X-API-Key "+restrictedRawKey)
+			r.Header.Set("X-Forwarded-For", "10.0.0.1")
+
+			auth.APIKeyAuthMiddleware(next, svc, auditSvc)(w, r)
+
+			result := rec.Result()
+			t.Cleanup(func() {
+				err := result.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, result.StatusCode)
+
+			wantNextCallCount := 0
+			if testcase.wantNextCall {
+				wantNextCallCount = 1
+			}
+
+			require.Equal(t, wantNextCallCount, nextCallCount)
+
+			if !testcase.wantNextCall {
+				var responseBody api.ErrorResponse
+				err := json.NewDecoder(result.Body).Decode(&responseBody)
+				require.NoError(t, err)
+				require.Equal(t, api.ErrCodePermissionDenied, responseBody.Code)
+				require.Equal(t, api.ErrDetailAPIKeyIPDenied, responseBody.Detail)
+			}
+		})
+	}
+
+	t.Run("Denials are recorded in audit log", func(t *testing.T) {
+		t.Parallel()
+
+		otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+			u.IsActive = true
+		})
+
+		apiKey, rawKey := testkitinternal.MustCreateUserAPIKey(t, otherUser.UUID, func(k *auth.APIKey) {
+			k.AllowedCIDRs = []string{"10.0.0.0/8"}
+		})
+
+		var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
+			w.WriteJSON(map[string]any{}, http.StatusOK)
+		}
+
+		rec := httptest.NewRecorder()
+		w := &httputils.ResponseWriter{
+			ResponseWriter: rec,
+			StatusCode:     -1,
+		}
+		r := httptest.NewRequest(http.MethodGet, "/api/flags", http.NoBody)
+		r.RemoteAddr = "203.0.113.42:54321"
+		r.Header.Set("Authorization", "X-API-Key "+rawKey)
+
+		auth.APIKeyAuthMiddleware(next, svc, auditSvc)(w, r)
+		require.Equal(t, http.StatusForbidden, rec.Result().StatusCode)
+
+		auditLogs, err := auditSvc.ListAuditLogs(context.Background(), otherUser.UUID)
+		require.NoError(t, err)
+		require.Len(t, auditLogs, 1)
+		require.Equal(t, string(audit.EventTypeAPIKeyIPDenied), auditLogs[0].EventType)
+		require.Equal(t, otherUser.UUID, auditLogs[0].UserUUID)
+		require.False(t, auditLogs[0].ServiceAccountUUID.Valid)
+		require.True(t, auditLogs[0].APIKeyID.Valid)
+		require.Equal(t, int32(apiKey.ID), auditLogs[0].APIKeyID.Int32)
+		require.Equal(t, "203.0.113.42", auditLogs[0].IPAddress)
+		require.Equal(t, "GET /api/flags", auditLogs[0].Detail)
+	})
+}
+
+func TestAPIKeyAuthMiddlewareAllowedOrigins(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+	auditSvc := audit.NewService(dbPool, logger, audit.NewRepository())
+
+	_, restrictedRawKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.AllowedOrigins = []string{"https://app.example.com"}
+	})
+
+	testcases := []struct {
+		name           string
+		origin         string
+		wantNextCall   bool
+		wantStatusCode int
+	}{
+		{
+			name:           "Allowed origin",
+			origin:         "https://app.example.com",
+			wantNextCall:   true,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "No origin",
+			origin:         "",
+			wantNextCall:   true,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "Disallowed origin",
+			origin:         "https://evil.example.com",
+			wantNextCall:   false,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			nextCallCount := 0
+			var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
+				w.WriteJSON(map[string]any{}, http.StatusOK)
+				nextCallCount++
+			}
+
+			rec := httptest.NewRecorder()
+			w := &httputils.ResponseWriter{
+				ResponseWriter: rec,
+				StatusCode:     -1,
+			}
+			r := httptest.NewRequest(http.MethodGet, "/api/flags", http.NoBody)
+			r.Header.Set("Authorization", "X-API-Key "+restrictedRawKey)
+			if testcase.origin != "" {
+				r.Header.Set("Origin", testcase.origin)
+			}
+
+			auth.APIKeyAuthMiddleware(next, svc, auditSvc)(w, r)
+
+			result := rec.Result()
+			t.Cleanup(func() {
+				err := result.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, result.StatusCode)
+
+			wantNextCallCount := 0
+			if testcase.wantNextCall {
+				wantNextCallCount = 1
+			}
+
+			require.Equal(t, wantNextCallCount, nextCallCount)
+
+			if !testcase.wantNextCall {
+				var responseBody api.ErrorResponse
+				err := json.NewDecoder(result.Body).Decode(&responseBody)
+				require.NoError(t, err)
+				require.Equal(t, api.ErrCodePermissionDenied, responseBody.Code)
+				require.Equal(t, api.ErrDetailAPIKeyOriginDenied, responseBody.Detail)
+			}
+		})
+	}
+
+	t.Run("Denials are recorded in audit log", func(t *testing.T) {
+		t.Parallel()
+
+		otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+			u.IsActive = true
+		})
+
+		apiKey, rawKey := testkitinternal.MustCreateUserAPIKey(t, otherUser.UUID, func(k *auth.APIKey) {
+			k.AllowedOrigins = []string{"https://app.example.com"}
+		})
+
+		var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
+			w.WriteJSON(map[string]any{}, http.StatusOK)
+		}
+
+		rec := httptest.NewRecorder()
+		w := &httputils.ResponseWriter{
+			ResponseWriter: rec,
+			StatusCode:     -1,
+		}
+		r := httptest.NewRequest(http.MethodGet, "/api/flags", http.NoBody)
+		r.RemoteAddr = "203.0.113.42:54321"
+		r.Header.Set("Authorization", "X-API-Key "+rawKey)
+		r.Header.Set("Origin", "https://evil.example.com")
+
+		auth.APIKeyAuthMiddleware(next, svc, auditSvc)(w, r)
+		require.Equal(t, http.StatusForbidden, rec.Result().StatusCode)
+
+		auditLogs, err := auditSvc.ListAuditLogs(context.Background(), otherUser.UUID)
+		require.NoError(t, err)
+		require.Len(t, auditLogs, 1)
+		require.Equal(t, string(audit.EventTypeAPIKeyOriginDenied), auditLogs[0].EventType)
+		require.Equal(t, otherUser.UUID, auditLogs[0].UserUUID)
+		require.True(t, auditLogs[0].APIKeyID.Valid)
+		require.Equal(t, int32(apiKey.ID), auditLogs[0].APIKeyID.Int32)
+		require.Equal(t, "203.0.113.42", auditLogs[0].IPAddress)
+		require.Equal(t, "GET /api/flags from https://evil.example.com", auditLogs[0].Detail)
+	})
+}
+
+func TestAPIKeyAuthMiddlewareServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+	auditSvc := audit.NewService(dbPool, logger, audit.NewRepository())
+
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, func(sa *auth.ServiceAccount) {
+		sa.Scopes = []string{string(auth.APIKeyScopeFlagsRead)}
+	})
+	apiKey, rawKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{string(auth.APIKeyScopeFlagsRead)}
+		k.AllowedCIDRs = []string{"10.0.0.0/8"}
+		k.ServiceAccountUUID = pgtype.Text{
+			String: serviceAccount.UUID,
+			Valid:  true,
+		}
+	})
+
+	t.Run("Service account UUID is set in context", func(t *testing.T) {
+		t.Parallel()
+
+		nextCallCount := 0
+		var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
+			require.Equal(t, user.UUID, r.Context().Value(auth.AuthContextKeyUserUUID))
+			require.Equal(t, serviceAccount.UUID, r.Context().Value(auth.AuthContextKeyServiceAccountUUID))
+			require.Equal(t, []string{"flags:read"}, r.Context().Value(auth.AuthContextKeyAPIKeyScopes))
+			w.WriteJSON(map[string]any{}, http.StatusOK)
+			nextCallCount++
+		}
+
+		rec := httptest.NewRecorder()
+		w := &httputils.ResponseWriter{
+			ResponseWriter: rec,
+			StatusCode:     -1,
+		}
+		r := httptest.NewRequest(http.MethodGet, "/api/flags", http.NoBody)
+		r.RemoteAddr = "10.1.2.3:54321"
+		r.Header.Set("Authorization", "X-API-Key "+rawKey)
+
+		auth.APIKeyAuthMiddleware(next, svc, auditSvc)(w, r)
+		require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+		require.Equal(t, 1, nextCallCount)
+	})
+
+	t.Run("Denials record service account in audit log", func(t *testing.T) {
+		t.Parallel()
+
+		var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
+			w.WriteJSON(map[string]any{}, http.StatusOK)
+		}
+
+		rec := httptest.NewRecorder()
+		w := &httputils.ResponseWriter{
+			ResponseWriter: rec,
+			StatusCode:     -1,
+		}
+		r := httptest.NewRequest(http.MethodGet, "/api/flags", http.NoBody)
+		r.RemoteAddr = "203.0.113.42:54321"
+		r.Header.Set("Authorization", "X-API-Key "+rawKey)
+
+		auth.APIKeyAuthMiddleware(next, svc, auditSvc)(w, r)
+		require.Equal(t, http.StatusForbidden, rec.Result().StatusCode)
+
+		auditLogs, err := auditSvc.ListAuditLogs(context.Background(), user.UUID)
+		require.NoError(t, err)
+		require.Len(t, auditLogs, 1)
+		require.Equal(t, user.UUID, auditLogs[0].UserUUID)
+		require.Equal(t, pgtype.Text{String: serviceAccount.UUID, Valid: true}, auditLogs[0].ServiceAccountUUID)
+		require.Equal(t, int32(apiKey.ID), auditLogs[0].APIKeyID.Int32)
+	})
+}
+
+func TestRequireAPIKeyScope(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name           string
+		scopes         []string
+		setScopes      bool
+		requiredScope  auth.APIKeyScope
+		wantNextCall   bool
+		wantStatusCode int
+	}{
+		{
+			name:           "API key with required scope is allowed",
+			scopes:         []string{"flags:read"},
+			setScopes:      true,
+			requiredScope:  auth.APIKeyScopeFlagsRead,
+			wantNextCall:   true,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "API key with implying scope is allowed",
+			scopes:         []string{"flags:write"},
+			setScopes:      true,
+			requiredScope:  auth.APIKeyScopeFlagsEvaluate,
+			wantNextCall:   true,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "API key without required scope is forbidden",
+			scopes:         []string{"flags:evaluate"},
+			setScopes:      true,
+			requiredScope:  auth.APIKeyScopeUserRead,
+			wantNextCall:   false,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Request without API key scopes is forbidden",
+			scopes:         nil,
+			setScopes:      false,
+			requiredScope:  auth.APIKeyScopeFlagsEvaluate,
+			wantNextCall:   false,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			nextCallCount := 0
+			var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				nextCallCount++
+			}
+
+			rec := httptest.NewRecorder()
+			w := &httputils.ResponseWriter{
+				ResponseWriter: rec,
+				StatusCode:     -1,
+			}
+			r := httptest.NewRequest(http.MethodGet, "/api/flags", http.NoBody)
+
+			if testcase.setScopes {
+				r = r.WithContext(context.WithValue(r.Context(), auth.AuthContextKeyAPIKeyScopes, testcase.scopes))
+			}
+
+			auth.RequireAPIKeyScope(next, testcase.requiredScope)(w, r)
+
+			result := rec.Result()
+			t.Cleanup(func() {
+				err := result.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, result.StatusCode)
+
+			wantNextCallCount := 0
+			if testcase.wantNextCall {
+				wantNextCallCount = 1
+			}
+
+			require.Equal(t, wantNextCallCount, nextCallCount)
+
+			if !testcase.wantNextCall {
+				var responseBody map[string]any
+				err := json.NewDecoder(result.Body).Decode(&responseBody)
+				require.NoError(t, err)
+				require.Equal(t, api.ErrCodePermissionDenied, responseBody["code"])
+			}
+		})
+	}
+}
+
+func TestRequireSuperUser(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	inactiveSuperUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+		u.IsSuperUser = true
+	})
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = false
+	})
+
+	testcases := []struct {
+		name           string
+		userUUID       string
+		actorUUID      string
+		wantNextCall   bool
+		wantStatusCode int
+	}{
+		{
+			name:           "Active superuser is allowed",
+			userUUID:       superUser.UUID,
+			wantNextCall:   true,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "Inactive superuser is forbidden",
+			userUUID:       inactiveSuperUser.UUID,
+			wantNextCall:   false,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Regular user is forbidden",
+			userUUID:       user.UUID,
+			wantNextCall:   false,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Non-existent user is forbidden",
+			userUUID:       uuid.NewString(),
+			wantNextCall:   false,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Impersonated superuser is forbidden",
+			userUUID:       superUser.UUID,
+			actorUUID:      uuid.NewString(),
+			wantNextCall:   false,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			nextCallCount := 0
+			var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				nextCallCount++
+			}
+
+			rec := httptest.NewRecorder()
+			w := &httputils.ResponseWriter{
+				ResponseWriter: rec,
+				StatusCode:     -1,
+			}
+			r := httptest.NewRequest(http.MethodGet, "/admin/users", http.NoBody)
+			r = r.WithContext(context.WithValue(r.Context(), auth.AuthContextKeyUserUUID, testcase.userUUID))
+			if testcase.actorUUID != "" {
+				r = r.WithContext(context.WithValue(r.Context(), auth.AuthContextKeyActorUUID, testcase.actorUUID))
+			}
+
+			auth.RequireSuperUser(next, svc)(w, r)
+
+			result := rec.Result()
+			t.Cleanup(func() {
+				err := result.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, result.StatusCode)
+
+			wantNextCallCount := 0
+			if testcase.wantNextCall {
+				wantNextCallCount = 1
+			}
+			require.Equal(t, wantNextCallCount, nextCallCount)
+
+			if !testcase.wantNextCall {
+				var errResp api.ErrorResponse
+				err := json.NewDecoder(result.Body).Decode(&errResp)
+				require.NoError(t, err)
+				require.Equal(t, api.ErrCodePermissionDenied, errResp.Code)
+				require.Equal(t, api.ErrDetailSuperUserRequired, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestRateLimitKeyAPIKey(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/api/flags", http.NoBody)
+	_, ok := auth.RateLimitKeyAPIKey(r)
+	require.False(t, ok)
+
+	r = r.WithContext(context.WithValue(r.Context(), auth.AuthContextKeyAPIKeyID, 42))
+	key, ok := auth.RateLimitKeyAPIKey(r)
+	require.True(t, ok)
+	require.Equal(t, "42", key)
+}
+
+func TestRateLimitKeyAPIKeyClientIP(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/api/client/flags", http.NoBody)
+	r.RemoteAddr = "203.0.113.42:54321"
+	_, ok := auth.RateLimitKeyAPIKeyClientIP(r)
+	require.False(t, ok)
+
+	r = r.WithContext(context.WithValue(r.Context(), auth.AuthContextKeyAPIKeyID, 42))
+	key, ok := auth.RateLimitKeyAPIKeyClientIP(r)
+	require.True(t, ok)
+	require.Equal(t, "42:203.0.113.42", key)
+}
+
+func TestRateLimitKeyUser(t *testing.T) {
+	t.Parallel()
+
+	userUUID := uuid.NewString()
+
+	r := httptest.NewRequest(http.MethodPost, "/flags", http.NoBody)
+	_, ok := auth.RateLimitKeyUser(r)
+	require.False(t, ok)
+
+	r = r.WithContext(context.WithValue(r.Context(), auth.AuthContextKeyUserUUID, userUUID))
+	key, ok := auth.RateLimitKeyUser(r)
+	require.True(t, ok)
+	require.Equal(t, userUUID, key)
+}
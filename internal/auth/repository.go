@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/alvii147/flagger-api/internal/database"
 	"github.com/alvii147/flagger-api/pkg/errutils"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -16,15 +17,63 @@ import (
 // Repository is used to access and update auth data.
 type Repository interface {
 	CreateUser(dbConn *pgxpool.Conn, user *User) (*User, error)
-	ActivateUserByUUID(dbConn *pgxpool.Conn, userUUID string) error
+	ActivateUserByUUID(dbConn *pgxpool.Conn, userUUID string, issuedAt time.Time) error
+	RenewUserActivation(dbConn *pgxpool.Conn, email string, sentAt time.Time, lastSentBefore time.Time) (*User, error)
 	GetUserByEmail(dbConn *pgxpool.Conn, email string) (*User, error)
 	GetUserByUUID(dbConn *pgxpool.Conn, userUUID string) (*User, error)
 	UpdateUser(dbConn *pgxpool.Conn, userUUID string, firstName *string, lastName *string) (*User, error)
+	UpdateUserPassword(querier database.Querier, userUUID string, password string) error
+	RehashUserPassword(dbConn *pgxpool.Conn, userUUID string, oldPassword string, newPassword string) error
+	UpdateUserEmail(dbConn *pgxpool.Conn, userUUID string, oldEmail string, newEmail string) error
+	GetAnyUserByUUID(dbConn *pgxpool.Conn, userUUID string) (*User, error)
+	ListUsers(dbConn *pgxpool.Conn, search string) ([]*User, error)
+	UpdateUserStatus(dbConn *pgxpool.Conn, userUUID string, isActive *bool, isSuperUser *bool) (*User, error)
 	CreateAPIKey(dbConn *pgxpool.Conn, apiKey *APIKey) (*APIKey, error)
 	ListAPIKeysByUserUUID(dbConn *pgxpool.Conn, userUUID string) ([]*APIKey, error)
 	ListActiveAPIKeysByPrefix(dbConn *pgxpool.Conn, prefix string) ([]*APIKey, error)
-	UpdateAPIKey(dbConn *pgxpool.Conn, apiKeyID int, userUUID string, name *string, expiresAt *pgtype.Timestamp) (*APIKey, error)
+	UpdateAPIKey(dbConn *pgxpool.Conn, apiKeyID int, userUUID string, name *string, allowedCIDRs *[]string, allowedOrigins *[]string, expiresAt *pgtype.Timestamp) (*APIKey, error)
+	GetActiveAPIKeyByDigest(dbConn *pgxpool.Conn, keyDigest string) (*APIKey, error)
+	MigrateAPIKeyDigest(dbConn *pgxpool.Conn, apiKeyID int, hashedKey string, keyDigest string) error
+	RotateAPIKey(dbConn *pgxpool.Conn, apiKeyID int, userUUID string, prefix string, keyDigest string, previousExpiresAt time.Time) (*APIKey, error)
+	RecordAPIKeyUsage(dbConn *pgxpool.Conn, apiKeyID int, day time.Time, requestCount int64, lastUsedAt time.Time, lastUsedIP string) error
+	ListAPIKeyUsage(dbConn *pgxpool.Conn, apiKeyID int, userUUID string, since time.Time, until time.Time) ([]*APIKeyUsage, error)
 	DeleteAPIKey(dbConn *pgxpool.Conn, apiKeyID int, userUUID string) error
+	DeleteUserAPIKeys(dbConn *pgxpool.Conn, userUUID string) (int64, error)
+	CreateServiceAccount(dbConn *pgxpool.Conn, serviceAccount *ServiceAccount) (*ServiceAccount, error)
+	GetServiceAccountByUUID(dbConn *pgxpool.Conn, serviceAccountUUID string, ownerUUID string) (*ServiceAccount, error)
+	ListServiceAccountsByOwnerUUID(dbConn *pgxpool.Conn, ownerUUID string) ([]*ServiceAccount, error)
+	UpdateServiceAccount(dbConn *pgxpool.Conn, serviceAccountUUID string, ownerUUID string, name *string, description *string, isActive *bool) (*ServiceAccount, error)
+	DeleteServiceAccount(dbConn *pgxpool.Conn, serviceAccountUUID string, ownerUUID string) error
+	CreateIssuedJWT(dbConn *pgxpool.Conn, issuedJWT *IssuedJWT) (*IssuedJWT, error)
+	ConsumeIssuedJWT(querier database.Querier, jti string, userUUID string, tokenType string) error
+	RecordIssuedJWTAttempt(dbConn *pgxpool.Conn, jti string, userUUID string, tokenType string, maxAttempts int) (int, error)
+	CountIssuedJWTs(dbConn *pgxpool.Conn, userUUID string, tokenType string, since time.Time) (int, error)
+	DeleteExpiredIssuedJWTs(dbConn *pgxpool.Conn) (int64, error)
+	CreateSession(dbConn *pgxpool.Conn, session *Session) (*Session, error)
+	GetActiveSession(dbConn *pgxpool.Conn, sessionID string, userUUID string) (*Session, error)
+	ListActiveSessionsByUserUUID(dbConn *pgxpool.Conn, userUUID string) ([]*Session, error)
+	RotateSession(dbConn *pgxpool.Conn, sessionID string, userUUID string, oldRefreshJTI string, newRefreshJTI string, ipAddress string, userAgent string, expiresAt time.Time) error
+	TouchSession(dbConn *pgxpool.Conn, sessionID string, userUUID string) error
+	RevokeSession(dbConn *pgxpool.Conn, sessionID string, userUUID string) error
+	RevokeUserSessions(querier database.Querier, userUUID string) error
+	RevokeOtherUserSessions(querier database.Querier, userUUID string, sessionID string) error
+	DeleteExpiredSessions(dbConn *pgxpool.Conn) (int64, error)
+	UpsertTOTPDevice(dbConn *pgxpool.Conn, userUUID string, secret string) (*TOTPDevice, error)
+	GetTOTPDevice(dbConn *pgxpool.Conn, userUUID string) (*TOTPDevice, error)
+	ConfirmTOTPDevice(dbConn *pgxpool.Conn, userUUID string, step int64) error
+	UseTOTPDeviceStep(dbConn *pgxpool.Conn, userUUID string, step int64) error
+	DeleteTOTPDevice(dbConn *pgxpool.Conn, userUUID string) error
+	CreateRecoveryCodes(dbConn *pgxpool.Conn, userUUID string, hashedCodes []string) error
+	UseRecoveryCode(dbConn *pgxpool.Conn, userUUID string, hashedCode string) error
+	DeleteRecoveryCodes(dbConn *pgxpool.Conn, userUUID string) error
+	CreateOIDCAuthRequest(dbConn *pgxpool.Conn, authRequest *OIDCAuthRequest) (*OIDCAuthRequest, error)
+	ConsumeOIDCAuthRequest(dbConn *pgxpool.Conn, state string) (*OIDCAuthRequest, error)
+	DeleteExpiredOIDCAuthRequests(dbConn *pgxpool.Conn) (int64, error)
+	GetLoginThrottle(dbConn *pgxpool.Conn, scope LoginThrottleScope, identifier string) (*LoginThrottle, error)
+	RecordLoginFailure(dbConn *pgxpool.Conn, scope LoginThrottleScope, identifier string, failedAt time.Time, lastFailedBefore time.Time) (*LoginThrottle, error)
+	LockLoginThrottle(dbConn *pgxpool.Conn, scope LoginThrottleScope, identifier string, lockedUntil time.Time) error
+	DeleteLoginThrottle(dbConn *pgxpool.Conn, scope LoginThrottleScope, identifier string) error
+	DeleteExpiredLoginThrottles(dbConn *pgxpool.Conn, lastFailedBefore time.Time) (int64, error)
 }
 
 // repository implements Repository.
@@ -66,7 +115,9 @@ RETURNING
 	last_name,
 	is_active,
 	is_superuser,
-	created_at;
+	created_at,
+	password_changed_at,
+	activation_sent_at;
 	`
 
 	err := dbConn.QueryRow(
@@ -88,6 +139,8 @@ RETURNING
 		&createdUser.IsActive,
 		&createdUser.IsSuperUser,
 		&createdUser.CreatedAt,
+		&createdUser.PasswordChangedAt,
+		&createdUser.ActivationSentAt,
 	)
 
 	var pgErr *pgconn.PgError
@@ -104,9 +157,10 @@ RETURNING
 	return createdUser, nil
 }
 
-// ActivateUserByUUID activates User.
+// ActivateUserByUUID activates User,
+// given that the activation was issued no earlier than the last activation email.
 // If no User is affected, error is returned.
-func (repo *repository) ActivateUserByUUID(dbConn *pgxpool.Conn, userUUID string) error {
+func (repo *repository) ActivateUserByUUID(dbConn *pgxpool.Conn, userUUID string, issuedAt time.Time) error {
 	q := `
 UPDATE
 	"User"
@@ -114,10 +168,11 @@ SET
 	is_active = TRUE
 WHERE
 	uuid = $1
-	AND is_active = FALSE;
+	AND is_active = FALSE
+	AND (activation_sent_at IS NULL OR DATE_TRUNC('second', activation_sent_at) <= $2);
 	`
 
-	ct, err := dbConn.Exec(context.Background(), q, userUUID)
+	ct, err := dbConn.Exec(context.Background(), q, userUUID, issuedAt)
 	if err != nil {
 		return fmt.Errorf("ActivateUserByUUID failed to dbConn.Exec: %w", err)
 	}
@@ -129,6 +184,69 @@ WHERE
 	return nil
 }
 
+// RenewUserActivation records a new activation email being sent to inactive User,
+// given that no activation email was resent since a given time.
+// If no User is affected, error is returned.
+func (repo *repository) RenewUserActivation(
+	dbConn *pgxpool.Conn,
+	email string,
+	sentAt time.Time,
+	lastSentBefore time.Time,
+) (*User, error) {
+	renewedUser := &User{}
+
+	q := `
+UPDATE
+	"User"
+SET
+	activation_sent_at = $1
+WHERE
+	email = $2
+	AND is_active = FALSE
+	AND (activation_sent_at IS NULL OR activation_sent_at < $3)
+RETURNING
+	uuid,
+	email,
+	password,
+	first_name,
+	last_name,
+	is_active,
+	is_superuser,
+	created_at,
+	password_changed_at,
+	activation_sent_at;
+	`
+
+	err := dbConn.QueryRow(
+		context.Background(),
+		q,
+		sentAt,
+		email,
+		lastSentBefore,
+	).Scan(
+		&renewedUser.UUID,
+		&renewedUser.Email,
+		&renewedUser.Password,
+		&renewedUser.FirstName,
+		&renewedUser.LastName,
+		&renewedUser.IsActive,
+		&renewedUser.IsSuperUser,
+		&renewedUser.CreatedAt,
+		&renewedUser.PasswordChangedAt,
+		&renewedUser.ActivationSentAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("RenewUserActivation failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("RenewUserActivation failed to dbConn.Scan: %w", err)
+	}
+
+	return renewedUser, nil
+}
+
 // GetUserByEmail fetches User by email.
 // If no User found, error is returned.
 func (repo *repository) GetUserByEmail(dbConn *pgxpool.Conn, email string) (*User, error) {
@@ -143,7 +261,9 @@ SELECT
 	last_name,
 	is_active,
 	is_superuser,
-	created_at
+	created_at,
+	password_changed_at,
+	activation_sent_at
 FROM
 	"User"
 WHERE
@@ -160,6 +280,8 @@ WHERE
 		&user.IsActive,
 		&user.IsSuperUser,
 		&user.CreatedAt,
+		&user.PasswordChangedAt,
+		&user.ActivationSentAt,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -187,7 +309,9 @@ SELECT
 	last_name,
 	is_active,
 	is_superuser,
-	created_at
+	created_at,
+	password_changed_at,
+	activation_sent_at
 FROM
 	"User"
 WHERE
@@ -204,6 +328,8 @@ WHERE
 		&user.IsActive,
 		&user.IsSuperUser,
 		&user.CreatedAt,
+		&user.PasswordChangedAt,
+		&user.ActivationSentAt,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -242,7 +368,9 @@ RETURNING
 	last_name,
 	is_active,
 	is_superuser,
-	created_at;
+	created_at,
+	password_changed_at,
+	activation_sent_at;
 	`
 
 	err := dbConn.QueryRow(
@@ -260,6 +388,8 @@ RETURNING
 		&updatedUser.IsActive,
 		&updatedUser.IsSuperUser,
 		&updatedUser.CreatedAt,
+		&updatedUser.PasswordChangedAt,
+		&updatedUser.ActivationSentAt,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -273,7 +403,256 @@ RETURNING
 	return updatedUser, nil
 }
 
-// CreateAPIKey creates API key from user UUID, prefix, hashed key, name, and expiry date.
+// UpdateUserPassword updates User hashed password and records when it was changed.
+// If no User is affected, error is returned.
+func (repo *repository) UpdateUserPassword(querier database.Querier, userUUID string, password string) error {
+	q := `
+UPDATE
+	"User"
+SET
+	password = $1,
+	password_changed_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+WHERE
+	uuid = $2
+	AND is_active = TRUE;
+	`
+
+	ct, err := querier.Exec(context.Background(), q, password, userUUID)
+	if err != nil {
+		return fmt.Errorf("UpdateUserPassword failed to querier.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("UpdateUserPassword failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// RehashUserPassword replaces User hashed password with a new hash of the same password,
+// without recording a password change.
+// The hash is only replaced if it has not changed since it was read.
+// If no User is affected, error is returned.
+func (repo *repository) RehashUserPassword(dbConn *pgxpool.Conn, userUUID string, oldPassword string, newPassword string) error {
+	q := `
+UPDATE
+	"User"
+SET
+	password = $1
+WHERE
+	uuid = $2
+	AND password = $3;
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, newPassword, userUUID, oldPassword)
+	if err != nil {
+		return fmt.Errorf("RehashUserPassword failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("RehashUserPassword failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// UpdateUserEmail updates User email, given that it still matches the old email.
+// If no User is affected, error is returned.
+func (repo *repository) UpdateUserEmail(dbConn *pgxpool.Conn, userUUID string, oldEmail string, newEmail string) error {
+	q := `
+UPDATE
+	"User"
+SET
+	email = $1
+WHERE
+	uuid = $2
+	AND email = $3
+	AND is_active = TRUE;
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, newEmail, userUUID, oldEmail)
+
+	var pgErr *pgconn.PgError
+	ok := errors.As(err, &pgErr)
+
+	if ok && pgErr != nil && pgErr.Code == "23505" {
+		return fmt.Errorf("UpdateUserEmail failed to dbConn.Exec, %w: %w", errutils.ErrDatabaseUniqueViolation, pgErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("UpdateUserEmail failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("UpdateUserEmail failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// GetAnyUserByUUID fetches User by UUID, active or not.
+// If no User found, error is returned.
+func (repo *repository) GetAnyUserByUUID(dbConn *pgxpool.Conn, userUUID string) (*User, error) {
+	user := &User{}
+
+	q := `
+SELECT
+	uuid,
+	email,
+	password,
+	first_name,
+	last_name,
+	is_active,
+	is_superuser,
+	created_at,
+	password_changed_at,
+	activation_sent_at
+FROM
+	"User"
+WHERE
+	uuid = $1;
+	`
+
+	err := dbConn.QueryRow(context.Background(), q, userUUID).Scan(
+		&user.UUID,
+		&user.Email,
+		&user.Password,
+		&user.FirstName,
+		&user.LastName,
+		&user.IsActive,
+		&user.IsSuperUser,
+		&user.CreatedAt,
+		&user.PasswordChangedAt,
+		&user.ActivationSentAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("GetAnyUserByUUID failed: %w", errutils.ErrDatabaseNoRowsReturned)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("GetAnyUserByUUID failed to dbConn.Scan: %w", err)
+	}
+
+	return user, nil
+}
+
+// ListUsers fetches all Users, active or not, oldest first.
+// If search is not empty, only Users whose email, first name, or last name contain it, ignoring case, are fetched.
+func (repo *repository) ListUsers(dbConn *pgxpool.Conn, search string) ([]*User, error) {
+	users := make([]*User, 0)
+
+	q := `
+SELECT
+	uuid,
+	email,
+	password,
+	first_name,
+	last_name,
+	is_active,
+	is_superuser,
+	created_at,
+	password_changed_at,
+	activation_sent_at
+FROM
+	"User"
+WHERE
+	$1 = ''
+	OR POSITION(LOWER($1) IN LOWER(email)) > 0
+	OR POSITION(LOWER($1) IN LOWER(first_name)) > 0
+	OR POSITION(LOWER($1) IN LOWER(last_name)) > 0
+ORDER BY
+	created_at,
+	uuid;
+	`
+
+	rows, err := dbConn.Query(context.Background(), q, search)
+	if err != nil {
+		return nil, fmt.Errorf("ListUsers failed to dbConn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := &User{}
+		err := rows.Scan(
+			&user.UUID,
+			&user.Email,
+			&user.Password,
+			&user.FirstName,
+			&user.LastName,
+			&user.IsActive,
+			&user.IsSuperUser,
+			&user.CreatedAt,
+			&user.PasswordChangedAt,
+			&user.ActivationSentAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ListUsers failed to rows.Scan: %w", err)
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// UpdateUserStatus updates User active and superuser status, active or not.
+// Attributes that are nil are not updated.
+// If no User is affected, error is returned.
+func (repo *repository) UpdateUserStatus(dbConn *pgxpool.Conn, userUUID string, isActive *bool, isSuperUser *bool) (*User, error) {
+	if isActive == nil && isSuperUser == nil {
+		return nil, fmt.Errorf("UpdateUserStatus failed, all attributes are nil: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	updatedUser := &User{}
+
+	q := `
+UPDATE
+	"User"
+SET
+	is_active = COALESCE($1, is_active),
+	is_superuser = COALESCE($2, is_superuser)
+WHERE
+	uuid = $3
+RETURNING
+	uuid,
+	email,
+	password,
+	first_name,
+	last_name,
+	is_active,
+	is_superuser,
+	created_at,
+	password_changed_at,
+	activation_sent_at;
+	`
+
+	err := dbConn.QueryRow(context.Background(), q, isActive, isSuperUser, userUUID).Scan(
+		&updatedUser.UUID,
+		&updatedUser.Email,
+		&updatedUser.Password,
+		&updatedUser.FirstName,
+		&updatedUser.LastName,
+		&updatedUser.IsActive,
+		&updatedUser.IsSuperUser,
+		&updatedUser.CreatedAt,
+		&updatedUser.PasswordChangedAt,
+		&updatedUser.ActivationSentAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("UpdateUserStatus failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateUserStatus failed to dbConn.Scan: %w", err)
+	}
+
+	return updatedUser, nil
+}
+
+// CreateAPIKey creates API key from user UUID, prefix, hashed key, key digest, name, scopes, allowed CIDR ranges,
+// allowed origins, service account UUID, and expiry date.
 func (repo *repository) CreateAPIKey(dbConn *pgxpool.Conn, apiKey *APIKey) (*APIKey, error) {
 	createdAPIKey := &APIKey{}
 
@@ -282,7 +661,12 @@ INSERT INTO APIKey (
 	user_uuid,
 	prefix,
 	hashed_key,
+	key_digest,
 	name,
+	scopes,
+	allowed_cidrs,
+	allowed_origins,
+	service_account_uuid,
 	expires_at
 )
 VALUES (
@@ -290,33 +674,77 @@ VALUES (
 	$2,
 	$3,
 	$4,
-	$5
+	$5,
+	$6,
+	$7,
+	$8,
+	$9,
+	$10
 )
 RETURNING
 	id,
 	user_uuid,
 	prefix,
 	hashed_key,
+	key_digest,
 	name,
+	scopes,
 	created_at,
-	expires_at;
+	expires_at,
+	previous_prefix,
+	previous_hashed_key,
+	previous_key_digest,
+	previous_expires_at,
+	last_used_at,
+	last_used_ip,
+	request_count,
+	allowed_cidrs,
+	allowed_origins,
+	service_account_uuid;
 	`
+	allowedCIDRs := apiKey.AllowedCIDRs
+	if allowedCIDRs == nil {
+		allowedCIDRs = []string{}
+	}
+
+	allowedOrigins := apiKey.AllowedOrigins
+	if allowedOrigins == nil {
+		allowedOrigins = []string{}
+	}
+
 	err := dbConn.QueryRow(
 		context.Background(),
 		q,
 		apiKey.UserUUID,
 		apiKey.Prefix,
 		apiKey.HashedKey,
+		apiKey.KeyDigest,
 		apiKey.Name,
+		apiKey.Scopes,
+		allowedCIDRs,
+		allowedOrigins,
+		apiKey.ServiceAccountUUID,
 		apiKey.ExpiresAt,
 	).Scan(
 		&createdAPIKey.ID,
 		&createdAPIKey.UserUUID,
 		&createdAPIKey.Prefix,
 		&createdAPIKey.HashedKey,
+		&createdAPIKey.KeyDigest,
 		&createdAPIKey.Name,
+		&createdAPIKey.Scopes,
 		&createdAPIKey.CreatedAt,
 		&createdAPIKey.ExpiresAt,
+		&createdAPIKey.PreviousPrefix,
+		&createdAPIKey.PreviousHashedKey,
+		&createdAPIKey.PreviousKeyDigest,
+		&createdAPIKey.PreviousExpiresAt,
+		&createdAPIKey.LastUsedAt,
+		&createdAPIKey.LastUsedIP,
+		&createdAPIKey.RequestCount,
+		&createdAPIKey.AllowedCIDRs,
+		&createdAPIKey.AllowedOrigins,
+		&createdAPIKey.ServiceAccountUUID,
 	)
 
 	var pgErr *pgconn.PgError
@@ -343,9 +771,21 @@ SELECT
 	k.user_uuid,
 	k.prefix,
 	k.hashed_key,
+	k.key_digest,
 	k.name,
+	k.scopes,
 	k.created_at,
-	k.expires_at
+	k.expires_at,
+	k.previous_prefix,
+	k.previous_hashed_key,
+	k.previous_key_digest,
+	k.previous_expires_at,
+	k.last_used_at,
+	k.last_used_ip,
+	k.request_count,
+	k.allowed_cidrs,
+	k.allowed_origins,
+	k.service_account_uuid
 FROM
 	APIKey k
 INNER JOIN
@@ -370,9 +810,21 @@ WHERE
 			&apiKey.UserUUID,
 			&apiKey.Prefix,
 			&apiKey.HashedKey,
+			&apiKey.KeyDigest,
 			&apiKey.Name,
+			&apiKey.Scopes,
 			&apiKey.CreatedAt,
 			&apiKey.ExpiresAt,
+			&apiKey.PreviousPrefix,
+			&apiKey.PreviousHashedKey,
+			&apiKey.PreviousKeyDigest,
+			&apiKey.PreviousExpiresAt,
+			&apiKey.LastUsedAt,
+			&apiKey.LastUsedIP,
+			&apiKey.RequestCount,
+			&apiKey.AllowedCIDRs,
+			&apiKey.AllowedOrigins,
+			&apiKey.ServiceAccountUUID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("ListAPIKeysByUserUUID failed to rows.Scan: %w", err)
@@ -384,9 +836,11 @@ WHERE
 	return apiKeys, nil
 }
 
-// ListActiveAPIKeysByPrefix fetches API keys with a given prefix.
-func (repo *repository) ListActiveAPIKeysByPrefix(dbConn *pgxpool.Conn, prefix string) ([]*APIKey, error) {
-	apiKeys := make([]*APIKey, 0)
+// GetActiveAPIKeyByDigest fetches API key with a given key digest,
+// including rotated API keys whose previous key digest matches and is still within its grace period.
+// API keys of service accounts depend on the service account being active rather than the User.
+func (repo *repository) GetActiveAPIKeyByDigest(dbConn *pgxpool.Conn, keyDigest string) (*APIKey, error) {
+	apiKey := &APIKey{}
 
 	q := `
 SELECT
@@ -394,58 +848,206 @@ SELECT
 	k.user_uuid,
 	k.prefix,
 	k.hashed_key,
+	k.key_digest,
 	k.name,
+	k.scopes,
 	k.created_at,
-	k.expires_at
+	k.expires_at,
+	k.previous_prefix,
+	k.previous_hashed_key,
+	k.previous_key_digest,
+	k.previous_expires_at,
+	k.last_used_at,
+	k.last_used_ip,
+	k.request_count,
+	k.allowed_cidrs,
+	k.allowed_origins,
+	k.service_account_uuid
 FROM
 	APIKey k
 INNER JOIN
 	"User" u
 ON
 	k.user_uuid = u.uuid
+LEFT JOIN
+	ServiceAccount sa
+ON
+	k.service_account_uuid = sa.uuid
 WHERE
-	k.prefix = $1
+	(
+		k.key_digest = $1
+		OR (k.previous_key_digest = $1 AND k.previous_expires_at > CURRENT_TIMESTAMP)
+	)
 	AND (k.expires_at IS NULL OR k.expires_at > CURRENT_TIMESTAMP)
-	AND u.is_active = TRUE;
+	AND (
+		(k.service_account_uuid IS NULL AND u.is_active = TRUE)
+		OR sa.is_active = TRUE
+	)
+LIMIT 1;
 	`
 
-	rows, err := dbConn.Query(context.Background(), q, prefix)
-	if err != nil {
-		return nil, fmt.Errorf("ListActiveAPIKeysByPrefix failed to dbConn.Query: %w", err)
-	}
-	defer rows.Close()
+	err := dbConn.QueryRow(context.Background(), q, keyDigest).Scan(
+		&apiKey.ID,
+		&apiKey.UserUUID,
+		&apiKey.Prefix,
+		&apiKey.HashedKey,
+		&apiKey.KeyDigest,
+		&apiKey.Name,
+		&apiKey.Scopes,
+		&apiKey.CreatedAt,
+		&apiKey.ExpiresAt,
+		&apiKey.PreviousPrefix,
+		&apiKey.PreviousHashedKey,
+		&apiKey.PreviousKeyDigest,
+		&apiKey.PreviousExpiresAt,
+		&apiKey.LastUsedAt,
+		&apiKey.LastUsedIP,
+		&apiKey.RequestCount,
+		&apiKey.AllowedCIDRs,
+		&apiKey.AllowedOrigins,
+		&apiKey.ServiceAccountUUID,
+	)
 
-	for rows.Next() {
-		apiKey := &APIKey{}
-		err := rows.Scan(
-			&apiKey.ID,
-			&apiKey.UserUUID,
-			&apiKey.Prefix,
-			&apiKey.HashedKey,
-			&apiKey.Name,
-			&apiKey.CreatedAt,
-			&apiKey.ExpiresAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("ListActiveAPIKeysByPrefix failed to rows.Scan: %w", err)
-		}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("GetActiveAPIKeyByDigest failed: %w", errutils.ErrDatabaseNoRowsReturned)
+	}
 
-		apiKeys = append(apiKeys, apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("GetActiveAPIKeyByDigest failed to dbConn.Scan: %w", err)
 	}
 
-	return apiKeys, nil
+	return apiKey, nil
 }
 
-// UpdateAPIKey updates an API key's name and expiration date.
-// If no  API key is affected, error is returned.
-func (repo *repository) UpdateAPIKey(
-	dbConn *pgxpool.Conn,
-	apiKeyID int,
-	userUUID string,
-	name *string,
+// MigrateAPIKeyDigest replaces a bcrypt hashed key of an API key with its key digest,
+// whether it is the API key's current or previous hashed key.
+// If the API key no longer has the given hashed key, error is returned.
+func (repo *repository) MigrateAPIKeyDigest(dbConn *pgxpool.Conn, apiKeyID int, hashedKey string, keyDigest string) error {
+	q := `
+UPDATE
+	APIKey
+SET
+	key_digest = CASE WHEN hashed_key = $2 THEN $3 ELSE key_digest END,
+	hashed_key = CASE WHEN hashed_key = $2 THEN '' ELSE hashed_key END,
+	previous_key_digest = CASE WHEN previous_hashed_key = $2 THEN $3 ELSE previous_key_digest END,
+	previous_hashed_key = CASE WHEN previous_hashed_key = $2 THEN '' ELSE previous_hashed_key END
+WHERE
+	id = $1
+	AND (hashed_key = $2 OR previous_hashed_key = $2);
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, apiKeyID, hashedKey, keyDigest)
+	if err != nil {
+		return fmt.Errorf("MigrateAPIKeyDigest failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("MigrateAPIKeyDigest failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// ListActiveAPIKeysByPrefix fetches API keys with a given prefix,
+// including rotated API keys whose previous prefix matches and is still within its grace period.
+// API keys of service accounts depend on the service account being active rather than the User.
+func (repo *repository) ListActiveAPIKeysByPrefix(dbConn *pgxpool.Conn, prefix string) ([]*APIKey, error) {
+	apiKeys := make([]*APIKey, 0)
+
+	q := `
+SELECT
+	k.id,
+	k.user_uuid,
+	k.prefix,
+	k.hashed_key,
+	k.key_digest,
+	k.name,
+	k.scopes,
+	k.created_at,
+	k.expires_at,
+	k.previous_prefix,
+	k.previous_hashed_key,
+	k.previous_key_digest,
+	k.previous_expires_at,
+	k.last_used_at,
+	k.last_used_ip,
+	k.request_count,
+	k.allowed_cidrs,
+	k.allowed_origins,
+	k.service_account_uuid
+FROM
+	APIKey k
+INNER JOIN
+	"User" u
+ON
+	k.user_uuid = u.uuid
+LEFT JOIN
+	ServiceAccount sa
+ON
+	k.service_account_uuid = sa.uuid
+WHERE
+	(
+		k.prefix = $1
+		OR (k.previous_prefix = $1 AND k.previous_expires_at > CURRENT_TIMESTAMP)
+	)
+	AND (k.expires_at IS NULL OR k.expires_at > CURRENT_TIMESTAMP)
+	AND (
+		(k.service_account_uuid IS NULL AND u.is_active = TRUE)
+		OR sa.is_active = TRUE
+	);
+	`
+
+	rows, err := dbConn.Query(context.Background(), q, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("ListActiveAPIKeysByPrefix failed to dbConn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		apiKey := &APIKey{}
+		err := rows.Scan(
+			&apiKey.ID,
+			&apiKey.UserUUID,
+			&apiKey.Prefix,
+			&apiKey.HashedKey,
+			&apiKey.KeyDigest,
+			&apiKey.Name,
+			&apiKey.Scopes,
+			&apiKey.CreatedAt,
+			&apiKey.ExpiresAt,
+			&apiKey.PreviousPrefix,
+			&apiKey.PreviousHashedKey,
+			&apiKey.PreviousKeyDigest,
+			&apiKey.PreviousExpiresAt,
+			&apiKey.LastUsedAt,
+			&apiKey.LastUsedIP,
+			&apiKey.RequestCount,
+			&apiKey.AllowedCIDRs,
+			&apiKey.AllowedOrigins,
+			&apiKey.ServiceAccountUUID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ListActiveAPIKeysByPrefix failed to rows.Scan: %w", err)
+		}
+
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	return apiKeys, nil
+}
+
+// UpdateAPIKey updates an API key's name, allowed CIDR ranges, allowed origins, and expiration date.
+// If no  API key is affected, error is returned.
+func (repo *repository) UpdateAPIKey(
+	dbConn *pgxpool.Conn,
+	apiKeyID int,
+	userUUID string,
+	name *string,
+	allowedCIDRs *[]string,
+	allowedOrigins *[]string,
 	expiresAt *pgtype.Timestamp,
 ) (*APIKey, error) {
-	if name == nil && expiresAt == nil {
+	if name == nil && allowedCIDRs == nil && allowedOrigins == nil && expiresAt == nil {
 		return nil, fmt.Errorf("UpdateUser failed, all attributes are nil: %w", errutils.ErrDatabaseNoRowsAffected)
 	}
 
@@ -456,12 +1058,14 @@ UPDATE
 	APIKey k
 SET
 	name = COALESCE($1, name),
-	expires_at = CASE WHEN $2 THEN $3 ELSE expires_at END
+	allowed_cidrs = COALESCE($2, allowed_cidrs),
+	allowed_origins = COALESCE($3, allowed_origins),
+	expires_at = CASE WHEN $4 THEN $5 ELSE expires_at END
 FROM
 	"User" u
 WHERE
-	k.id = $4
-	AND k.user_uuid = $5
+	k.id = $6
+	AND k.user_uuid = $7
 	AND k.user_uuid = u.uuid
 	AND u.is_active = TRUE
 RETURNING
@@ -469,9 +1073,21 @@ RETURNING
 	k.user_uuid,
 	k.prefix,
 	k.hashed_key,
+	k.key_digest,
 	k.name,
+	k.scopes,
 	k.created_at,
-	k.expires_at;
+	k.expires_at,
+	k.previous_prefix,
+	k.previous_hashed_key,
+	k.previous_key_digest,
+	k.previous_expires_at,
+	k.last_used_at,
+	k.last_used_ip,
+	k.request_count,
+	k.allowed_cidrs,
+	k.allowed_origins,
+	k.service_account_uuid;
 	`
 
 	shouldUpdateExpiresAt := false
@@ -483,10 +1099,28 @@ RETURNING
 		}
 	}
 
+	var updatedAllowedCIDRs []string
+	if allowedCIDRs != nil {
+		updatedAllowedCIDRs = *allowedCIDRs
+		if updatedAllowedCIDRs == nil {
+			updatedAllowedCIDRs = []string{}
+		}
+	}
+
+	var updatedAllowedOrigins []string
+	if allowedOrigins != nil {
+		updatedAllowedOrigins = *allowedOrigins
+		if updatedAllowedOrigins == nil {
+			updatedAllowedOrigins = []string{}
+		}
+	}
+
 	err := dbConn.QueryRow(
 		context.Background(),
 		q,
 		name,
+		updatedAllowedCIDRs,
+		updatedAllowedOrigins,
 		shouldUpdateExpiresAt,
 		updatedExpiresAt,
 		apiKeyID,
@@ -496,15 +1130,34 @@ RETURNING
 		&updatedAPIKey.UserUUID,
 		&updatedAPIKey.Prefix,
 		&updatedAPIKey.HashedKey,
+		&updatedAPIKey.KeyDigest,
 		&updatedAPIKey.Name,
+		&updatedAPIKey.Scopes,
 		&updatedAPIKey.CreatedAt,
 		&updatedAPIKey.ExpiresAt,
+		&updatedAPIKey.PreviousPrefix,
+		&updatedAPIKey.PreviousHashedKey,
+		&updatedAPIKey.PreviousKeyDigest,
+		&updatedAPIKey.PreviousExpiresAt,
+		&updatedAPIKey.LastUsedAt,
+		&updatedAPIKey.LastUsedIP,
+		&updatedAPIKey.RequestCount,
+		&updatedAPIKey.AllowedCIDRs,
+		&updatedAPIKey.AllowedOrigins,
+		&updatedAPIKey.ServiceAccountUUID,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, fmt.Errorf("UpdateAPIKey failed: %w", errutils.ErrDatabaseNoRowsAffected)
 	}
 
+	var pgErr *pgconn.PgError
+	ok := errors.As(err, &pgErr)
+
+	if ok && pgErr != nil && pgErr.Code == "23505" {
+		return nil, fmt.Errorf("UpdateAPIKey failed to dbConn.Scan, %w: %w", errutils.ErrDatabaseUniqueViolation, pgErr)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("UpdateAPIKey failed to dbConn.Scan: %w", err)
 	}
@@ -512,6 +1165,217 @@ RETURNING
 	return updatedAPIKey, nil
 }
 
+// RotateAPIKey replaces an API key's prefix and key digest,
+// keeping the replaced prefix, hashed key, and key digest valid until a given time.
+// If no API key is affected, error is returned.
+func (repo *repository) RotateAPIKey(
+	dbConn *pgxpool.Conn,
+	apiKeyID int,
+	userUUID string,
+	prefix string,
+	keyDigest string,
+	previousExpiresAt time.Time,
+) (*APIKey, error) {
+	rotatedAPIKey := &APIKey{}
+
+	q := `
+UPDATE
+	APIKey k
+SET
+	prefix = $1,
+	hashed_key = '',
+	key_digest = $2,
+	previous_prefix = k.prefix,
+	previous_hashed_key = k.hashed_key,
+	previous_key_digest = k.key_digest,
+	previous_expires_at = $3
+FROM
+	"User" u
+WHERE
+	k.id = $4
+	AND k.user_uuid = $5
+	AND k.user_uuid = u.uuid
+	AND u.is_active = TRUE
+RETURNING
+	k.id,
+	k.user_uuid,
+	k.prefix,
+	k.hashed_key,
+	k.key_digest,
+	k.name,
+	k.scopes,
+	k.created_at,
+	k.expires_at,
+	k.previous_prefix,
+	k.previous_hashed_key,
+	k.previous_key_digest,
+	k.previous_expires_at,
+	k.last_used_at,
+	k.last_used_ip,
+	k.request_count,
+	k.allowed_cidrs,
+	k.allowed_origins,
+	k.service_account_uuid;
+	`
+
+	err := dbConn.QueryRow(
+		context.Background(),
+		q,
+		prefix,
+		keyDigest,
+		previousExpiresAt,
+		apiKeyID,
+		userUUID,
+	).Scan(
+		&rotatedAPIKey.ID,
+		&rotatedAPIKey.UserUUID,
+		&rotatedAPIKey.Prefix,
+		&rotatedAPIKey.HashedKey,
+		&rotatedAPIKey.KeyDigest,
+		&rotatedAPIKey.Name,
+		&rotatedAPIKey.Scopes,
+		&rotatedAPIKey.CreatedAt,
+		&rotatedAPIKey.ExpiresAt,
+		&rotatedAPIKey.PreviousPrefix,
+		&rotatedAPIKey.PreviousHashedKey,
+		&rotatedAPIKey.PreviousKeyDigest,
+		&rotatedAPIKey.PreviousExpiresAt,
+		&rotatedAPIKey.LastUsedAt,
+		&rotatedAPIKey.LastUsedIP,
+		&rotatedAPIKey.RequestCount,
+		&rotatedAPIKey.AllowedCIDRs,
+		&rotatedAPIKey.AllowedOrigins,
+		&rotatedAPIKey.ServiceAccountUUID,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("RotateAPIKey failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("RotateAPIKey failed to dbConn.Scan: %w", err)
+	}
+
+	return rotatedAPIKey, nil
+}
+
+// RecordAPIKeyUsage adds requests to an API key's request count and daily usage,
+// and updates when and where the API key was last used.
+// Usage of API keys that no longer exist is discarded.
+func (repo *repository) RecordAPIKeyUsage(
+	dbConn *pgxpool.Conn,
+	apiKeyID int,
+	day time.Time,
+	requestCount int64,
+	lastUsedAt time.Time,
+	lastUsedIP string,
+) error {
+	q := `
+WITH updated AS (
+	UPDATE
+		APIKey
+	SET
+		request_count = request_count + $2,
+		last_used_ip = CASE WHEN last_used_at IS NULL OR last_used_at <= $3 THEN $4 ELSE last_used_ip END,
+		last_used_at = GREATEST(last_used_at, $3)
+	WHERE
+		id = $1
+	RETURNING
+		id
+)
+INSERT INTO APIKeyUsage (
+	api_key_id,
+	day,
+	request_count
+)
+SELECT
+	id,
+	$5,
+	$2
+FROM
+	updated
+ON CONFLICT (api_key_id, day) DO UPDATE
+SET
+	request_count = APIKeyUsage.request_count + EXCLUDED.request_count;
+	`
+
+	_, err := dbConn.Exec(
+		context.Background(),
+		q,
+		apiKeyID,
+		requestCount,
+		lastUsedAt,
+		lastUsedIP,
+		day,
+	)
+	if err != nil {
+		return fmt.Errorf("RecordAPIKeyUsage failed to dbConn.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// ListAPIKeyUsage fetches daily request counts of an API key between two days, inclusive,
+// with a zero count for days without requests.
+// If no API key is found, an empty list is returned.
+func (repo *repository) ListAPIKeyUsage(
+	dbConn *pgxpool.Conn,
+	apiKeyID int,
+	userUUID string,
+	since time.Time,
+	until time.Time,
+) ([]*APIKeyUsage, error) {
+	usage := make([]*APIKeyUsage, 0)
+
+	q := `
+SELECT
+	k.id,
+	d.day::DATE,
+	COALESCE(ku.request_count, 0)
+FROM
+	APIKey k
+INNER JOIN
+	"User" u
+ON
+	k.user_uuid = u.uuid
+CROSS JOIN
+	generate_series($3::DATE, $4::DATE, INTERVAL '1 day') AS d(day)
+LEFT JOIN
+	APIKeyUsage ku
+ON
+	ku.api_key_id = k.id
+	AND ku.day = d.day::DATE
+WHERE
+	k.id = $1
+	AND k.user_uuid = $2
+	AND u.is_active = TRUE
+ORDER BY
+	d.day;
+	`
+
+	rows, err := dbConn.Query(context.Background(), q, apiKeyID, userUUID, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("ListAPIKeyUsage failed to dbConn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		dailyUsage := &APIKeyUsage{}
+		err := rows.Scan(
+			&dailyUsage.APIKeyID,
+			&dailyUsage.Day,
+			&dailyUsage.RequestCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ListAPIKeyUsage failed to rows.Scan: %w", err)
+		}
+
+		usage = append(usage, dailyUsage)
+	}
+
+	return usage, nil
+}
+
 // DeleteAPIKey deletes API key by ID.
 // If no API key found, error is returned.
 func (repo *repository) DeleteAPIKey(dbConn *pgxpool.Conn, apiKeyID int, userUUID string) error {
@@ -539,3 +1403,1294 @@ WHERE
 
 	return nil
 }
+
+// DeleteUserAPIKeys deletes all API keys of a given User, active or not,
+// including API keys of the User's service accounts,
+// and returns the number of API keys deleted.
+func (repo *repository) DeleteUserAPIKeys(dbConn *pgxpool.Conn, userUUID string) (int64, error) {
+	q := `
+DELETE FROM
+	APIKey
+WHERE
+	user_uuid = $1;
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, userUUID)
+
+	if err != nil {
+		return 0, fmt.Errorf("DeleteUserAPIKeys failed to dbConn.Exec: %w", err)
+	}
+
+	return ct.RowsAffected(), nil
+}
+
+// CreateServiceAccount creates service account from UUID, owner UUID, name, description, scopes, and active state.
+func (repo *repository) CreateServiceAccount(dbConn *pgxpool.Conn, serviceAccount *ServiceAccount) (*ServiceAccount, error) {
+	createdServiceAccount := &ServiceAccount{}
+
+	q := `
+INSERT INTO ServiceAccount (
+	uuid,
+	owner_uuid,
+	name,
+	description,
+	scopes,
+	is_active
+)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6
+)
+RETURNING
+	uuid,
+	owner_uuid,
+	name,
+	description,
+	scopes,
+	is_active,
+	created_at;
+	`
+
+	err := dbConn.QueryRow(
+		context.Background(),
+		q,
+		serviceAccount.UUID,
+		serviceAccount.OwnerUUID,
+		serviceAccount.Name,
+		serviceAccount.Description,
+		serviceAccount.Scopes,
+		serviceAccount.IsActive,
+	).Scan(
+		&createdServiceAccount.UUID,
+		&createdServiceAccount.OwnerUUID,
+		&createdServiceAccount.Name,
+		&createdServiceAccount.Description,
+		&createdServiceAccount.Scopes,
+		&createdServiceAccount.IsActive,
+		&createdServiceAccount.CreatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	ok := errors.As(err, &pgErr)
+
+	if ok && pgErr != nil && pgErr.Code == "23505" {
+		return nil, fmt.Errorf("CreateServiceAccount failed to dbConn.Scan, %w: %w", errutils.ErrDatabaseUniqueViolation, pgErr)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateServiceAccount failed to dbConn.Scan: %w", err)
+	}
+
+	return createdServiceAccount, nil
+}
+
+// GetServiceAccountByUUID fetches service account by UUID under a given owner User UUID.
+func (repo *repository) GetServiceAccountByUUID(dbConn *pgxpool.Conn, serviceAccountUUID string, ownerUUID string) (*ServiceAccount, error) {
+	serviceAccount := &ServiceAccount{}
+
+	q := `
+SELECT
+	sa.uuid,
+	sa.owner_uuid,
+	sa.name,
+	sa.description,
+	sa.scopes,
+	sa.is_active,
+	sa.created_at
+FROM
+	ServiceAccount sa
+INNER JOIN
+	"User" u
+ON
+	sa.owner_uuid = u.uuid
+WHERE
+	sa.uuid = $1
+	AND sa.owner_uuid = $2
+	AND u.is_active = TRUE;
+	`
+
+	err := dbConn.QueryRow(context.Background(), q, serviceAccountUUID, ownerUUID).Scan(
+		&serviceAccount.UUID,
+		&serviceAccount.OwnerUUID,
+		&serviceAccount.Name,
+		&serviceAccount.Description,
+		&serviceAccount.Scopes,
+		&serviceAccount.IsActive,
+		&serviceAccount.CreatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("GetServiceAccountByUUID failed: %w", errutils.ErrDatabaseNoRowsReturned)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("GetServiceAccountByUUID failed to dbConn.Scan: %w", err)
+	}
+
+	return serviceAccount, nil
+}
+
+// ListServiceAccountsByOwnerUUID fetches service accounts under a given owner User UUID.
+func (repo *repository) ListServiceAccountsByOwnerUUID(dbConn *pgxpool.Conn, ownerUUID string) ([]*ServiceAccount, error) {
+	serviceAccounts := make([]*ServiceAccount, 0)
+
+	q := `
+SELECT
+	sa.uuid,
+	sa.owner_uuid,
+	sa.name,
+	sa.description,
+	sa.scopes,
+	sa.is_active,
+	sa.created_at
+FROM
+	ServiceAccount sa
+INNER JOIN
+	"User" u
+ON
+	sa.owner_uuid = u.uuid
+WHERE
+	sa.owner_uuid = $1
+	AND u.is_active = TRUE
+ORDER BY
+	sa.created_at;
+	`
+
+	rows, err := dbConn.Query(context.Background(), q, ownerUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ListServiceAccountsByOwnerUUID failed to dbConn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		serviceAccount := &ServiceAccount{}
+		err := rows.Scan(
+			&serviceAccount.UUID,
+			&serviceAccount.OwnerUUID,
+			&serviceAccount.Name,
+			&serviceAccount.Description,
+			&serviceAccount.Scopes,
+			&serviceAccount.IsActive,
+			&serviceAccount.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ListServiceAccountsByOwnerUUID failed to rows.Scan: %w", err)
+		}
+
+		serviceAccounts = append(serviceAccounts, serviceAccount)
+	}
+
+	return serviceAccounts, nil
+}
+
+// UpdateServiceAccount updates service account's name, description, and active state.
+// Attributes that are nil are not updated.
+// If no service account is affected, error is returned.
+func (repo *repository) UpdateServiceAccount(
+	dbConn *pgxpool.Conn,
+	serviceAccountUUID string,
+	ownerUUID string,
+	name *string,
+	description *string,
+	isActive *bool,
+) (*ServiceAccount, error) {
+	if name == nil && description == nil && isActive == nil {
+		return nil, fmt.Errorf("UpdateServiceAccount failed, all attributes are nil: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	updatedServiceAccount := &ServiceAccount{}
+
+	q := `
+UPDATE
+	ServiceAccount sa
+SET
+	name = COALESCE($1, sa.name),
+	description = COALESCE($2, sa.description),
+	is_active = COALESCE($3, sa.is_active)
+FROM
+	"User" u
+WHERE
+	sa.uuid = $4
+	AND sa.owner_uuid = $5
+	AND sa.owner_uuid = u.uuid
+	AND u.is_active = TRUE
+RETURNING
+	sa.uuid,
+	sa.owner_uuid,
+	sa.name,
+	sa.description,
+	sa.scopes,
+	sa.is_active,
+	sa.created_at;
+	`
+
+	err := dbConn.QueryRow(
+		context.Background(),
+		q,
+		name,
+		description,
+		isActive,
+		serviceAccountUUID,
+		ownerUUID,
+	).Scan(
+		&updatedServiceAccount.UUID,
+		&updatedServiceAccount.OwnerUUID,
+		&updatedServiceAccount.Name,
+		&updatedServiceAccount.Description,
+		&updatedServiceAccount.Scopes,
+		&updatedServiceAccount.IsActive,
+		&updatedServiceAccount.CreatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("UpdateServiceAccount failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	var pgErr *pgconn.PgError
+	ok := errors.As(err, &pgErr)
+
+	if ok && pgErr != nil && pgErr.Code == "23505" {
+		return nil, fmt.Errorf("UpdateServiceAccount failed to dbConn.Scan, %w: %w", errutils.ErrDatabaseUniqueViolation, pgErr)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateServiceAccount failed to dbConn.Scan: %w", err)
+	}
+
+	return updatedServiceAccount, nil
+}
+
+// DeleteServiceAccount deletes service account by UUID, along with its API keys.
+// If no service account found, error is returned.
+func (repo *repository) DeleteServiceAccount(dbConn *pgxpool.Conn, serviceAccountUUID string, ownerUUID string) error {
+	q := `
+DELETE FROM
+	ServiceAccount sa
+USING
+	"User" u
+WHERE
+	sa.uuid = $1
+	AND sa.owner_uuid = $2
+	AND sa.owner_uuid = u.uuid
+	AND u.is_active = TRUE;
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, serviceAccountUUID, ownerUUID)
+	if err != nil {
+		return fmt.Errorf("DeleteServiceAccount failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("DeleteServiceAccount failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// CreateIssuedJWT records issued single-use JWT from JTI, user UUID, token type, and issue and expiry dates.
+func (repo *repository) CreateIssuedJWT(dbConn *pgxpool.Conn, issuedJWT *IssuedJWT) (*IssuedJWT, error) {
+	createdIssuedJWT := &IssuedJWT{}
+
+	q := `
+INSERT INTO IssuedJWT (
+	jti,
+	user_uuid,
+	token_type,
+	created_at,
+	expires_at
+)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5
+)
+RETURNING
+	jti,
+	user_uuid,
+	token_type,
+	created_at,
+	expires_at,
+	consumed_at,
+	attempt_count;
+	`
+	err := dbConn.QueryRow(
+		context.Background(),
+		q,
+		issuedJWT.JTI,
+		issuedJWT.UserUUID,
+		issuedJWT.TokenType,
+		issuedJWT.CreatedAt,
+		issuedJWT.ExpiresAt,
+	).Scan(
+		&createdIssuedJWT.JTI,
+		&createdIssuedJWT.UserUUID,
+		&createdIssuedJWT.TokenType,
+		&createdIssuedJWT.CreatedAt,
+		&createdIssuedJWT.ExpiresAt,
+		&createdIssuedJWT.ConsumedAt,
+		&createdIssuedJWT.AttemptCount,
+	)
+
+	var pgErr *pgconn.PgError
+	ok := errors.As(err, &pgErr)
+
+	if ok && pgErr != nil && pgErr.Code == "23505" {
+		return nil, fmt.Errorf("CreateIssuedJWT failed to dbConn.Scan, %w: %w", errutils.ErrDatabaseUniqueViolation, pgErr)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateIssuedJWT failed to dbConn.Scan: %w", err)
+	}
+
+	return createdIssuedJWT, nil
+}
+
+// ConsumeIssuedJWT marks issued JWT as consumed, given that it is unexpired and has not been consumed before.
+// If no issued JWT is affected, error is returned.
+func (repo *repository) ConsumeIssuedJWT(querier database.Querier, jti string, userUUID string, tokenType string) error {
+	q := `
+UPDATE
+	IssuedJWT
+SET
+	consumed_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+WHERE
+	jti = $1
+	AND user_uuid = $2
+	AND token_type = $3
+	AND consumed_at IS NULL
+	AND expires_at > (CURRENT_TIMESTAMP AT TIME ZONE 'UTC');
+	`
+
+	ct, err := querier.Exec(context.Background(), q, jti, userUUID, tokenType)
+
+	if err != nil {
+		return fmt.Errorf("ConsumeIssuedJWT failed to querier.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("ConsumeIssuedJWT failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// RecordIssuedJWTAttempt counts an attempt to use issued JWT, and returns the number of attempts made so far,
+// given that it is unexpired, has not been consumed, and has been attempted fewer than a given maximum number of times.
+// If no issued JWT is affected, error is returned.
+func (repo *repository) RecordIssuedJWTAttempt(
+	dbConn *pgxpool.Conn,
+	jti string,
+	userUUID string,
+	tokenType string,
+	maxAttempts int,
+) (int, error) {
+	q := `
+UPDATE
+	IssuedJWT
+SET
+	attempt_count = attempt_count + 1
+WHERE
+	jti = $1
+	AND user_uuid = $2
+	AND token_type = $3
+	AND attempt_count < $4
+	AND consumed_at IS NULL
+	AND expires_at > (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+RETURNING
+	attempt_count;
+	`
+
+	var attemptCount int
+	err := dbConn.QueryRow(context.Background(), q, jti, userUUID, tokenType, maxAttempts).Scan(&attemptCount)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("RecordIssuedJWTAttempt failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("RecordIssuedJWTAttempt failed to dbConn.Scan: %w", err)
+	}
+
+	return attemptCount, nil
+}
+
+// CountIssuedJWTs counts JWTs of a given type issued to User since a given time.
+func (repo *repository) CountIssuedJWTs(dbConn *pgxpool.Conn, userUUID string, tokenType string, since time.Time) (int, error) {
+	q := `
+SELECT
+	COUNT(*)
+FROM
+	IssuedJWT
+WHERE
+	user_uuid = $1
+	AND token_type = $2
+	AND created_at > $3;
+	`
+
+	var count int
+	err := dbConn.QueryRow(context.Background(), q, userUUID, tokenType, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("CountIssuedJWTs failed to dbConn.Scan: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteExpiredIssuedJWTs deletes issued JWTs that have expired, and returns the number of deleted JWTs.
+func (repo *repository) DeleteExpiredIssuedJWTs(dbConn *pgxpool.Conn) (int64, error) {
+	q := `
+DELETE FROM
+	IssuedJWT
+WHERE
+	expires_at <= (CURRENT_TIMESTAMP AT TIME ZONE 'UTC');
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q)
+
+	if err != nil {
+		return 0, fmt.Errorf("DeleteExpiredIssuedJWTs failed to dbConn.Exec: %w", err)
+	}
+
+	return ct.RowsAffected(), nil
+}
+
+// CreateSession creates session from ID, user UUID, current refresh JWT ID, client details, and expiry date.
+func (repo *repository) CreateSession(dbConn *pgxpool.Conn, session *Session) (*Session, error) {
+	createdSession := &Session{}
+
+	q := `
+INSERT INTO Session (
+	id,
+	user_uuid,
+	refresh_jti,
+	ip_address,
+	user_agent,
+	expires_at
+)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6
+)
+RETURNING
+	id,
+	user_uuid,
+	refresh_jti,
+	ip_address,
+	user_agent,
+	created_at,
+	last_used_at,
+	expires_at,
+	revoked_at;
+	`
+	err := dbConn.QueryRow(
+		context.Background(),
+		q,
+		session.ID,
+		session.UserUUID,
+		session.RefreshJTI,
+		session.IPAddress,
+		session.UserAgent,
+		session.ExpiresAt,
+	).Scan(
+		&createdSession.ID,
+		&createdSession.UserUUID,
+		&createdSession.RefreshJTI,
+		&createdSession.IPAddress,
+		&createdSession.UserAgent,
+		&createdSession.CreatedAt,
+		&createdSession.LastUsedAt,
+		&createdSession.ExpiresAt,
+		&createdSession.RevokedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	ok := errors.As(err, &pgErr)
+
+	if ok && pgErr != nil && pgErr.Code == "23505" {
+		return nil, fmt.Errorf("CreateSession failed to dbConn.Scan, %w: %w", errutils.ErrDatabaseUniqueViolation, pgErr)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateSession failed to dbConn.Scan: %w", err)
+	}
+
+	return createdSession, nil
+}
+
+// GetActiveSession fetches unrevoked and unexpired session by session ID.
+func (repo *repository) GetActiveSession(dbConn *pgxpool.Conn, sessionID string, userUUID string) (*Session, error) {
+	session := &Session{}
+
+	q := `
+SELECT
+	id,
+	user_uuid,
+	refresh_jti,
+	ip_address,
+	user_agent,
+	created_at,
+	last_used_at,
+	expires_at,
+	revoked_at
+FROM
+	Session
+WHERE
+	id = $1
+	AND user_uuid = $2
+	AND revoked_at IS NULL
+	AND expires_at > (CURRENT_TIMESTAMP AT TIME ZONE 'UTC');
+	`
+	err := dbConn.QueryRow(context.Background(), q, sessionID, userUUID).Scan(
+		&session.ID,
+		&session.UserUUID,
+		&session.RefreshJTI,
+		&session.IPAddress,
+		&session.UserAgent,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("GetActiveSession failed to dbConn.Scan, %w: %w", errutils.ErrDatabaseNoRowsReturned, err)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("GetActiveSession failed to dbConn.Scan: %w", err)
+	}
+
+	return session, nil
+}
+
+// ListActiveSessionsByUserUUID fetches all unrevoked and unexpired sessions of a given User,
+// most recently used first.
+func (repo *repository) ListActiveSessionsByUserUUID(dbConn *pgxpool.Conn, userUUID string) ([]*Session, error) {
+	q := `
+SELECT
+	id,
+	user_uuid,
+	refresh_jti,
+	ip_address,
+	user_agent,
+	created_at,
+	last_used_at,
+	expires_at,
+	revoked_at
+FROM
+	Session
+WHERE
+	user_uuid = $1
+	AND revoked_at IS NULL
+	AND expires_at > (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+ORDER BY
+	last_used_at DESC,
+	created_at DESC;
+	`
+
+	rows, err := dbConn.Query(context.Background(), q, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ListActiveSessionsByUserUUID failed to dbConn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*Session, 0)
+	for rows.Next() {
+		session := &Session{}
+		err = rows.Scan(
+			&session.ID,
+			&session.UserUUID,
+			&session.RefreshJTI,
+			&session.IPAddress,
+			&session.UserAgent,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+			&session.RevokedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ListActiveSessionsByUserUUID failed to rows.Scan: %w", err)
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// RotateSession replaces the current refresh JWT ID of an unrevoked and unexpired session, extends its expiry date,
+// and records the client details of its last use, given that the old refresh JWT ID is still the current one.
+// If no session is affected, error is returned.
+func (repo *repository) RotateSession(
+	dbConn *pgxpool.Conn,
+	sessionID string,
+	userUUID string,
+	oldRefreshJTI string,
+	newRefreshJTI string,
+	ipAddress string,
+	userAgent string,
+	expiresAt time.Time,
+) error {
+	q := `
+UPDATE
+	Session
+SET
+	refresh_jti = $1,
+	ip_address = $2,
+	user_agent = $3,
+	last_used_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+	expires_at = $4
+WHERE
+	id = $5
+	AND user_uuid = $6
+	AND refresh_jti = $7
+	AND revoked_at IS NULL
+	AND expires_at > (CURRENT_TIMESTAMP AT TIME ZONE 'UTC');
+	`
+
+	ct, err := dbConn.Exec(
+		context.Background(),
+		q,
+		newRefreshJTI,
+		ipAddress,
+		userAgent,
+		expiresAt,
+		sessionID,
+		userUUID,
+		oldRefreshJTI,
+	)
+
+	if err != nil {
+		return fmt.Errorf("RotateSession failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("RotateSession failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// TouchSession sets the last use time of an unrevoked session to the current time.
+// If no session is affected, error is returned.
+func (repo *repository) TouchSession(dbConn *pgxpool.Conn, sessionID string, userUUID string) error {
+	q := `
+UPDATE
+	Session
+SET
+	last_used_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+WHERE
+	id = $1
+	AND user_uuid = $2
+	AND revoked_at IS NULL;
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, sessionID, userUUID)
+
+	if err != nil {
+		return fmt.Errorf("TouchSession failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("TouchSession failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// RevokeSession revokes unrevoked session by ID.
+// If no session is affected, error is returned.
+func (repo *repository) RevokeSession(dbConn *pgxpool.Conn, sessionID string, userUUID string) error {
+	q := `
+UPDATE
+	Session
+SET
+	revoked_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+WHERE
+	id = $1
+	AND user_uuid = $2
+	AND revoked_at IS NULL;
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, sessionID, userUUID)
+
+	if err != nil {
+		return fmt.Errorf("RevokeSession failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("RevokeSession failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes all unrevoked sessions of a given User.
+func (repo *repository) RevokeUserSessions(querier database.Querier, userUUID string) error {
+	q := `
+UPDATE
+	Session
+SET
+	revoked_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+WHERE
+	user_uuid = $1
+	AND revoked_at IS NULL;
+	`
+
+	_, err := querier.Exec(context.Background(), q, userUUID)
+	if err != nil {
+		return fmt.Errorf("RevokeUserSessions failed to querier.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeOtherUserSessions revokes all unrevoked sessions of a given User, except for the session with given ID.
+func (repo *repository) RevokeOtherUserSessions(querier database.Querier, userUUID string, sessionID string) error {
+	q := `
+UPDATE
+	Session
+SET
+	revoked_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+WHERE
+	user_uuid = $1
+	AND id <> $2
+	AND revoked_at IS NULL;
+	`
+
+	_, err := querier.Exec(context.Background(), q, userUUID, sessionID)
+	if err != nil {
+		return fmt.Errorf("RevokeOtherUserSessions failed to querier.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredSessions deletes sessions that have expired, and returns the number of deleted sessions.
+func (repo *repository) DeleteExpiredSessions(dbConn *pgxpool.Conn) (int64, error) {
+	q := `
+DELETE FROM
+	Session
+WHERE
+	expires_at <= (CURRENT_TIMESTAMP AT TIME ZONE 'UTC');
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q)
+
+	if err != nil {
+		return 0, fmt.Errorf("DeleteExpiredSessions failed to dbConn.Exec: %w", err)
+	}
+
+	return ct.RowsAffected(), nil
+}
+
+// UpsertTOTPDevice creates unconfirmed TOTP device for a given User,
+// replacing any existing unconfirmed TOTP device of the User.
+// If the User already has a confirmed TOTP device, no rows are returned.
+func (repo *repository) UpsertTOTPDevice(dbConn *pgxpool.Conn, userUUID string, secret string) (*TOTPDevice, error) {
+	device := &TOTPDevice{}
+
+	q := `
+INSERT INTO TOTPDevice (
+	user_uuid,
+	secret
+)
+VALUES (
+	$1,
+	$2
+)
+ON CONFLICT (user_uuid) DO UPDATE
+SET
+	secret = EXCLUDED.secret,
+	created_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+	last_used_step = 0
+WHERE
+	TOTPDevice.confirmed_at IS NULL
+RETURNING
+	user_uuid,
+	secret,
+	created_at,
+	confirmed_at,
+	last_used_step;
+	`
+	err := dbConn.QueryRow(context.Background(), q, userUUID, secret).Scan(
+		&device.UserUUID,
+		&device.Secret,
+		&device.CreatedAt,
+		&device.ConfirmedAt,
+		&device.LastUsedStep,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("UpsertTOTPDevice failed to dbConn.Scan, %w: %w", errutils.ErrDatabaseNoRowsReturned, err)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("UpsertTOTPDevice failed to dbConn.Scan: %w", err)
+	}
+
+	return device, nil
+}
+
+// GetTOTPDevice fetches TOTP device of a given User.
+func (repo *repository) GetTOTPDevice(dbConn *pgxpool.Conn, userUUID string) (*TOTPDevice, error) {
+	device := &TOTPDevice{}
+
+	q := `
+SELECT
+	user_uuid,
+	secret,
+	created_at,
+	confirmed_at,
+	last_used_step
+FROM
+	TOTPDevice
+WHERE
+	user_uuid = $1;
+	`
+	err := dbConn.QueryRow(context.Background(), q, userUUID).Scan(
+		&device.UserUUID,
+		&device.Secret,
+		&device.CreatedAt,
+		&device.ConfirmedAt,
+		&device.LastUsedStep,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("GetTOTPDevice failed to dbConn.Scan, %w: %w", errutils.ErrDatabaseNoRowsReturned, err)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("GetTOTPDevice failed to dbConn.Scan: %w", err)
+	}
+
+	return device, nil
+}
+
+// ConfirmTOTPDevice confirms unconfirmed TOTP device of a given User,
+// recording the time step of the code used for confirmation.
+// If no device is affected, error is returned.
+func (repo *repository) ConfirmTOTPDevice(dbConn *pgxpool.Conn, userUUID string, step int64) error {
+	q := `
+UPDATE
+	TOTPDevice
+SET
+	confirmed_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+	last_used_step = $1
+WHERE
+	user_uuid = $2
+	AND confirmed_at IS NULL
+	AND last_used_step < $1;
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, step, userUUID)
+
+	if err != nil {
+		return fmt.Errorf("ConfirmTOTPDevice failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("ConfirmTOTPDevice failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// UseTOTPDeviceStep records the time step of a code used with the confirmed TOTP device of a given User,
+// given that no code of the same or a later time step has been used before.
+// If no device is affected, error is returned.
+func (repo *repository) UseTOTPDeviceStep(dbConn *pgxpool.Conn, userUUID string, step int64) error {
+	q := `
+UPDATE
+	TOTPDevice
+SET
+	last_used_step = $1
+WHERE
+	user_uuid = $2
+	AND confirmed_at IS NOT NULL
+	AND last_used_step < $1;
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, step, userUUID)
+
+	if err != nil {
+		return fmt.Errorf("UseTOTPDeviceStep failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("UseTOTPDeviceStep failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// DeleteTOTPDevice deletes TOTP device of a given User.
+// If no device is affected, error is returned.
+func (repo *repository) DeleteTOTPDevice(dbConn *pgxpool.Conn, userUUID string) error {
+	q := `
+DELETE FROM
+	TOTPDevice
+WHERE
+	user_uuid = $1;
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, userUUID)
+
+	if err != nil {
+		return fmt.Errorf("DeleteTOTPDevice failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("DeleteTOTPDevice failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// CreateRecoveryCodes creates recovery codes for a given User from hashed codes.
+func (repo *repository) CreateRecoveryCodes(dbConn *pgxpool.Conn, userUUID string, hashedCodes []string) error {
+	q := `
+INSERT INTO RecoveryCode (
+	user_uuid,
+	hashed_code
+)
+SELECT
+	$1,
+	UNNEST($2::VARCHAR(64)[]);
+	`
+
+	_, err := dbConn.Exec(context.Background(), q, userUUID, hashedCodes)
+
+	var pgErr *pgconn.PgError
+	ok := errors.As(err, &pgErr)
+
+	if ok && pgErr != nil && pgErr.Code == "23505" {
+		return fmt.Errorf("CreateRecoveryCodes failed to dbConn.Exec, %w: %w", errutils.ErrDatabaseUniqueViolation, pgErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("CreateRecoveryCodes failed to dbConn.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks unused recovery code of a given User as used.
+// If no recovery code is affected, error is returned.
+func (repo *repository) UseRecoveryCode(dbConn *pgxpool.Conn, userUUID string, hashedCode string) error {
+	q := `
+UPDATE
+	RecoveryCode
+SET
+	used_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+WHERE
+	user_uuid = $1
+	AND hashed_code = $2
+	AND used_at IS NULL;
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, userUUID, hashedCode)
+
+	if err != nil {
+		return fmt.Errorf("UseRecoveryCode failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("UseRecoveryCode failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// DeleteRecoveryCodes deletes all recovery codes of a given User.
+func (repo *repository) DeleteRecoveryCodes(dbConn *pgxpool.Conn, userUUID string) error {
+	q := `
+DELETE FROM
+	RecoveryCode
+WHERE
+	user_uuid = $1;
+	`
+
+	_, err := dbConn.Exec(context.Background(), q, userUUID)
+	if err != nil {
+		return fmt.Errorf("DeleteRecoveryCodes failed to dbConn.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// CreateOIDCAuthRequest records pending OpenID Connect authorization request from state, nonce, PKCE code verifier, and expiry date.
+func (repo *repository) CreateOIDCAuthRequest(dbConn *pgxpool.Conn, authRequest *OIDCAuthRequest) (*OIDCAuthRequest, error) {
+	createdAuthRequest := &OIDCAuthRequest{}
+
+	q := `
+INSERT INTO OIDCAuthRequest (
+	state,
+	nonce,
+	code_verifier,
+	expires_at
+)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4
+)
+RETURNING
+	state,
+	nonce,
+	code_verifier,
+	created_at,
+	expires_at;
+	`
+	err := dbConn.QueryRow(
+		context.Background(),
+		q,
+		authRequest.State,
+		authRequest.Nonce,
+		authRequest.CodeVerifier,
+		authRequest.ExpiresAt,
+	).Scan(
+		&createdAuthRequest.State,
+		&createdAuthRequest.Nonce,
+		&createdAuthRequest.CodeVerifier,
+		&createdAuthRequest.CreatedAt,
+		&createdAuthRequest.ExpiresAt,
+	)
+
+	var pgErr *pgconn.PgError
+	ok := errors.As(err, &pgErr)
+
+	if ok && pgErr != nil && pgErr.Code == "23505" {
+		return nil, fmt.Errorf("CreateOIDCAuthRequest failed to dbConn.Scan, %w: %w", errutils.ErrDatabaseUniqueViolation, pgErr)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateOIDCAuthRequest failed to dbConn.Scan: %w", err)
+	}
+
+	return createdAuthRequest, nil
+}
+
+// ConsumeOIDCAuthRequest deletes and returns unexpired OpenID Connect authorization request with a given state,
+// so that each authorization request can only be completed once.
+func (repo *repository) ConsumeOIDCAuthRequest(dbConn *pgxpool.Conn, state string) (*OIDCAuthRequest, error) {
+	authRequest := &OIDCAuthRequest{}
+
+	q := `
+DELETE FROM
+	OIDCAuthRequest
+WHERE
+	state = $1
+	AND expires_at > (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+RETURNING
+	state,
+	nonce,
+	code_verifier,
+	created_at,
+	expires_at;
+	`
+	err := dbConn.QueryRow(context.Background(), q, state).Scan(
+		&authRequest.State,
+		&authRequest.Nonce,
+		&authRequest.CodeVerifier,
+		&authRequest.CreatedAt,
+		&authRequest.ExpiresAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("ConsumeOIDCAuthRequest failed to dbConn.Scan, %w: %w", errutils.ErrDatabaseNoRowsReturned, err)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("ConsumeOIDCAuthRequest failed to dbConn.Scan: %w", err)
+	}
+
+	return authRequest, nil
+}
+
+// DeleteExpiredOIDCAuthRequests deletes OpenID Connect authorization requests that have expired,
+// and returns the number of deleted authorization requests.
+func (repo *repository) DeleteExpiredOIDCAuthRequests(dbConn *pgxpool.Conn) (int64, error) {
+	q := `
+DELETE FROM
+	OIDCAuthRequest
+WHERE
+	expires_at <= (CURRENT_TIMESTAMP AT TIME ZONE 'UTC');
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q)
+
+	if err != nil {
+		return 0, fmt.Errorf("DeleteExpiredOIDCAuthRequests failed to dbConn.Exec: %w", err)
+	}
+
+	return ct.RowsAffected(), nil
+}
+
+// GetLoginThrottle gets failed login attempts recorded for a given scope and identifier.
+func (repo *repository) GetLoginThrottle(dbConn *pgxpool.Conn, scope LoginThrottleScope, identifier string) (*LoginThrottle, error) {
+	throttle := &LoginThrottle{}
+
+	q := `
+SELECT
+	scope,
+	identifier,
+	failure_count,
+	last_failed_at,
+	locked_until
+FROM
+	LoginThrottle
+WHERE
+	scope = $1
+	AND identifier = $2;
+	`
+
+	err := dbConn.QueryRow(context.Background(), q, string(scope), identifier).Scan(
+		&throttle.Scope,
+		&throttle.Identifier,
+		&throttle.FailureCount,
+		&throttle.LastFailedAt,
+		&throttle.LockedUntil,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("GetLoginThrottle failed to dbConn.Scan, %w: %w", errutils.ErrDatabaseNoRowsReturned, err)
+		}
+
+		return nil, fmt.Errorf("GetLoginThrottle failed to dbConn.Scan: %w", err)
+	}
+
+	return throttle, nil
+}
+
+// RecordLoginFailure records a failed login attempt for a given scope and identifier.
+// Earlier failures are forgotten if the last failure was before a given time.
+func (repo *repository) RecordLoginFailure(
+	dbConn *pgxpool.Conn,
+	scope LoginThrottleScope,
+	identifier string,
+	failedAt time.Time,
+	lastFailedBefore time.Time,
+) (*LoginThrottle, error) {
+	throttle := &LoginThrottle{}
+
+	q := `
+INSERT INTO LoginThrottle (
+	scope,
+	identifier,
+	failure_count,
+	last_failed_at
+)
+VALUES (
+	$1,
+	$2,
+	1,
+	$3
+)
+ON CONFLICT (scope, identifier) DO UPDATE
+SET
+	failure_count = CASE
+		WHEN LoginThrottle.last_failed_at < $4 THEN 1
+		ELSE LoginThrottle.failure_count + 1
+	END,
+	last_failed_at = EXCLUDED.last_failed_at
+RETURNING
+	scope,
+	identifier,
+	failure_count,
+	last_failed_at,
+	locked_until;
+	`
+
+	err := dbConn.QueryRow(
+		context.Background(),
+		q,
+		string(scope),
+		identifier,
+		failedAt,
+		lastFailedBefore,
+	).Scan(
+		&throttle.Scope,
+		&throttle.Identifier,
+		&throttle.FailureCount,
+		&throttle.LastFailedAt,
+		&throttle.LockedUntil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("RecordLoginFailure failed to dbConn.Scan: %w", err)
+	}
+
+	return throttle, nil
+}
+
+// LockLoginThrottle blocks login attempts for a given scope and identifier until a given time.
+func (repo *repository) LockLoginThrottle(dbConn *pgxpool.Conn, scope LoginThrottleScope, identifier string, lockedUntil time.Time) error {
+	q := `
+UPDATE
+	LoginThrottle
+SET
+	locked_until = $1
+WHERE
+	scope = $2
+	AND identifier = $3;
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, lockedUntil, string(scope), identifier)
+	if err != nil {
+		return fmt.Errorf("LockLoginThrottle failed to dbConn.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("LockLoginThrottle failed: %w", errutils.ErrDatabaseNoRowsAffected)
+	}
+
+	return nil
+}
+
+// DeleteLoginThrottle deletes failed login attempts recorded for a given scope and identifier.
+func (repo *repository) DeleteLoginThrottle(dbConn *pgxpool.Conn, scope LoginThrottleScope, identifier string) error {
+	q := `
+DELETE FROM
+	LoginThrottle
+WHERE
+	scope = $1
+	AND identifier = $2;
+	`
+
+	_, err := dbConn.Exec(context.Background(), q, string(scope), identifier)
+	if err != nil {
+		return fmt.Errorf("DeleteLoginThrottle failed to dbConn.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredLoginThrottles deletes failed login attempts that are no longer locked
+// and whose last failure was before a given time.
+func (repo *repository) DeleteExpiredLoginThrottles(dbConn *pgxpool.Conn, lastFailedBefore time.Time) (int64, error) {
+	q := `
+DELETE FROM
+	LoginThrottle
+WHERE
+	last_failed_at < $1
+	AND (locked_until IS NULL OR locked_until <= (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'));
+	`
+
+	ct, err := dbConn.Exec(context.Background(), q, lastFailedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteExpiredLoginThrottles failed to dbConn.Exec: %w", err)
+	}
+
+	return ct.RowsAffected(), nil
+}
@@ -3,6 +3,7 @@ package auth_test
 import (
 	"context"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -94,7 +95,7 @@ func TestRepositoryActivateUserByUUIDSuccess(t *testing.T) {
 	_, err := repo.GetUserByEmail(dbConn, user.Email)
 	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
 
-	err = repo.ActivateUserByUUID(dbConn, user.UUID)
+	err = repo.ActivateUserByUUID(dbConn, user.UUID, time.Now().UTC())
 	require.NoError(t, err)
 
 	fetchedUser, err := repo.GetUserByEmail(dbConn, user.Email)
@@ -109,21 +110,113 @@ func TestRepositoryActivateUserByUUIDError(t *testing.T) {
 	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
+	resentUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := auth.NewRepository()
 
+	now := time.Now().UTC()
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, err := repo.RenewUserActivation(dbConn, resentUser.Email, now, now.Add(time.Second))
+	require.NoError(t, err)
+
 	testcases := []struct {
 		name     string
 		userUUID string
+		issuedAt time.Time
 	}{
 		{
 			name:     "No user under given UUID",
 			userUUID: uuid.NewString(),
+			issuedAt: now,
 		},
 		{
 			name:     "No inactive user under given UUID",
 			userUUID: activeUser.UUID,
+			issuedAt: now,
+		},
+		{
+			name:     "Issued before last activation email",
+			userUUID: resentUser.UUID,
+			issuedAt: now.Add(-time.Hour),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			err := repo.ActivateUserByUUID(dbConn, testcase.userUUID, testcase.issuedAt)
+			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+		})
+	}
+}
+
+func TestRepositoryRenewUserActivationSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	sentAt := time.Now().UTC()
+	renewedUser, err := repo.RenewUserActivation(dbConn, user.Email, sentAt, sentAt.Add(time.Second))
+	require.NoError(t, err)
+
+	require.Equal(t, user.UUID, renewedUser.UUID)
+	require.Equal(t, user.Email, renewedUser.Email)
+	require.False(t, renewedUser.IsActive)
+	require.True(t, renewedUser.ActivationSentAt.Valid)
+	testkit.RequireTimeAlmostEqual(t, sentAt, renewedUser.ActivationSentAt.Time)
+
+	_, err = repo.RenewUserActivation(dbConn, user.Email, sentAt, sentAt)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+}
+
+func TestRepositoryRenewUserActivationError(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := auth.NewRepository()
+
+	now := time.Now().UTC()
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, err := repo.RenewUserActivation(dbConn, inactiveUser.Email, now, now.Add(time.Second))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name           string
+		email          string
+		lastSentBefore time.Time
+	}{
+		{
+			name:           "No user under given email",
+			email:          testkit.GenerateFakeEmail(),
+			lastSentBefore: now.Add(time.Second),
+		},
+		{
+			name:           "No inactive user under given email",
+			email:          activeUser.Email,
+			lastSentBefore: now.Add(time.Second),
+		},
+		{
+			name:           "Activation email resent too recently",
+			email:          inactiveUser.Email,
+			lastSentBefore: now.Add(-time.Hour),
 		},
 	}
 
@@ -132,7 +225,7 @@ func TestRepositoryActivateUserByUUIDError(t *testing.T) {
 			t.Parallel()
 
 			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
-			err := repo.ActivateUserByUUID(dbConn, uuid.New().String())
+			_, err := repo.RenewUserActivation(dbConn, testcase.email, now, testcase.lastSentBefore)
 			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
 		})
 	}
@@ -377,7 +470,7 @@ func TestRepositoryUpdateUserError(t *testing.T) {
 	}
 }
 
-func TestRepositoryCreateAPIKeySuccess(t *testing.T) {
+func TestRepositoryUpdateUserPasswordSuccess(t *testing.T) {
 	t.Parallel()
 
 	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
@@ -388,177 +481,193 @@ func TestRepositoryCreateAPIKeySuccess(t *testing.T) {
 	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := auth.NewRepository()
 
-	apiKey := &auth.APIKey{
-		UserUUID:  user.UUID,
-		Prefix:    testkit.MustGenerateRandomString(8, true, true, true),
-		HashedKey: testkit.MustGenerateRandomString(16, true, true, true),
-		Name:      "My API Key",
-		ExpiresAt: pgtype.Timestamp{
-			Valid: false,
-		},
-	}
+	newHashedPassword := testkitinternal.MustHashPassword(testkit.GenerateFakePassword())
+	err := repo.UpdateUserPassword(dbConn, user.UUID, newHashedPassword)
+	require.NoError(t, err)
 
-	createdAPIKey, err := repo.CreateAPIKey(dbConn, apiKey)
+	fetchedUser, err := repo.GetUserByUUID(dbConn, user.UUID)
 	require.NoError(t, err)
 
-	require.Equal(t, apiKey.Prefix, createdAPIKey.Prefix)
-	require.Equal(t, apiKey.HashedKey, createdAPIKey.HashedKey)
-	require.Equal(t, apiKey.Name, createdAPIKey.Name)
-	require.False(t, apiKey.ExpiresAt.Valid)
+	require.Equal(t, newHashedPassword, fetchedUser.Password)
+	require.True(t, fetchedUser.PasswordChangedAt.Valid)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), fetchedUser.PasswordChangedAt.Time)
 }
 
-func TestRepositoryCreateAPIKeyDuplicateName(t *testing.T) {
+func TestRepositoryUpdateUserPasswordError(t *testing.T) {
 	t.Parallel()
 
-	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
-		u.IsActive = true
-	})
-
-	testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
-		k.Name = "My API Key"
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
 	})
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
-	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := auth.NewRepository()
 
-	apiKey := &auth.APIKey{
-		UserUUID:  user.UUID,
-		Prefix:    testkit.MustGenerateRandomString(8, true, true, true),
-		HashedKey: testkit.MustGenerateRandomString(16, true, true, true),
-		Name:      "My API Key",
-		ExpiresAt: pgtype.Timestamp{
-			Valid: false,
+	testcases := []struct {
+		name     string
+		userUUID string
+	}{
+		{
+			name:     "No user under given UUID",
+			userUUID: uuid.NewString(),
+		},
+		{
+			name:     "No active user under given UUID",
+			userUUID: inactiveUser.UUID,
 		},
 	}
 
-	_, err := repo.CreateAPIKey(dbConn, apiKey)
-	require.ErrorIs(t, err, errutils.ErrDatabaseUniqueViolation)
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			err := repo.UpdateUserPassword(
+				dbConn,
+				testcase.userUUID,
+				testkitinternal.MustHashPassword(testkit.GenerateFakePassword()),
+			)
+			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+		})
+	}
 }
 
-func TestRepositoryListAPIKeysByUserUUIDSuccess(t *testing.T) {
+func TestRepositoryRehashUserPasswordSuccess(t *testing.T) {
 	t.Parallel()
 
 	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
 
-	apiKey1, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
-	apiKey2, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
-
-	wantAPIKeys := []*auth.APIKey{apiKey1, apiKey2}
-	sort.Slice(wantAPIKeys, func(i, j int) bool {
-		return wantAPIKeys[i].Prefix < wantAPIKeys[j].Prefix
-	})
-
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := auth.NewRepository()
 
-	apiKeys, err := repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
+	newHashedPassword := testkitinternal.MustHashPassword(testkit.GenerateFakePassword())
+	err := repo.RehashUserPassword(dbConn, user.UUID, user.Password, newHashedPassword)
 	require.NoError(t, err)
-	require.Len(t, apiKeys, 2)
 
-	sort.Slice(apiKeys, func(i, j int) bool {
-		return apiKeys[i].Prefix < apiKeys[j].Prefix
-	})
+	fetchedUser, err := repo.GetUserByUUID(dbConn, user.UUID)
+	require.NoError(t, err)
 
-	for i, apiKey := range apiKeys {
-		wantKey := wantAPIKeys[i]
-		require.Equal(t, wantKey.ID, apiKey.ID)
-		require.Equal(t, wantKey.UserUUID, apiKey.UserUUID)
-		require.Equal(t, wantKey.Prefix, apiKey.Prefix)
-		require.Equal(t, wantKey.Name, apiKey.Name)
-		require.Equal(t, wantKey.CreatedAt, apiKey.CreatedAt)
-		require.Equal(t, wantKey.ExpiresAt, apiKey.ExpiresAt)
-	}
+	require.Equal(t, newHashedPassword, fetchedUser.Password)
+	require.Equal(t, user.PasswordChangedAt, fetchedUser.PasswordChangedAt)
 }
 
-func TestRepositoryListAPIKeysByUserUUIDInactiveUser(t *testing.T) {
+func TestRepositoryRehashUserPasswordError(t *testing.T) {
 	t.Parallel()
 
 	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
-		u.IsActive = false
+		u.IsActive = true
 	})
 
-	testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
-
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
-	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := auth.NewRepository()
 
-	apiKeys, err := repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
-	require.NoError(t, err)
-	require.Empty(t, apiKeys)
+	testcases := []struct {
+		name        string
+		userUUID    string
+		oldPassword string
+	}{
+		{
+			name:        "No user under given UUID",
+			userUUID:    uuid.NewString(),
+			oldPassword: user.Password,
+		},
+		{
+			name:        "Password changed since it was read",
+			userUUID:    user.UUID,
+			oldPassword: testkitinternal.MustHashPassword(testkit.GenerateFakePassword()),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			err := repo.RehashUserPassword(
+				dbConn,
+				testcase.userUUID,
+				testcase.oldPassword,
+				testkitinternal.MustHashPassword(testkit.GenerateFakePassword()),
+			)
+			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+		})
+	}
 }
 
-func TestRepositoryListActiveAPIKeysByPrefixSuccess(t *testing.T) {
+func TestRepositoryUpdateUserEmailSuccess(t *testing.T) {
 	t.Parallel()
 
 	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
 
-	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
-
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := auth.NewRepository()
 
-	apiKeys, err := repo.ListActiveAPIKeysByPrefix(dbConn, apiKey.Prefix)
+	newEmail := testkit.GenerateFakeEmail()
+	err := repo.UpdateUserEmail(dbConn, user.UUID, user.Email, newEmail)
 	require.NoError(t, err)
-	require.Len(t, apiKeys, 1)
 
-	var fetchedAPIKey *auth.APIKey
-	for _, k := range apiKeys {
-		if k.ID == apiKey.ID {
-			fetchedAPIKey = k
-			break
-		}
-	}
+	fetchedUser, err := repo.GetUserByUUID(dbConn, user.UUID)
+	require.NoError(t, err)
 
-	require.NotNil(t, fetchedAPIKey)
-	require.Equal(t, apiKey.ID, fetchedAPIKey.ID)
-	require.Equal(t, apiKey.Prefix, fetchedAPIKey.Prefix)
-	require.Equal(t, apiKey.HashedKey, fetchedAPIKey.HashedKey)
-	require.Equal(t, apiKey.Name, fetchedAPIKey.Name)
-	require.Equal(t, apiKey.ExpiresAt, fetchedAPIKey.ExpiresAt)
+	require.Equal(t, newEmail, fetchedUser.Email)
 }
 
-func TestRepositoryListActiveAPIKeysByPrefixEmpty(t *testing.T) {
+func TestRepositoryUpdateUserEmailError(t *testing.T) {
 	t.Parallel()
 
-	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
-
-	expiredAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
-		k.ExpiresAt = pgtype.Timestamp{
-			Time:  time.Now().UTC().AddDate(0, 0, -1),
-			Valid: true,
-		}
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
 	})
-
 	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = false
 	})
 
-	validAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
-
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := auth.NewRepository()
 
 	testcases := []struct {
-		name   string
-		apiKey *auth.APIKey
+		name     string
+		userUUID string
+		oldEmail string
+		newEmail string
+		wantErr  error
 	}{
 		{
-			name:   "Active user with expired API key",
-			apiKey: expiredAPIKey,
+			name:     "No user under given UUID",
+			userUUID: uuid.NewString(),
+			oldEmail: user.Email,
+			newEmail: testkit.GenerateFakeEmail(),
+			wantErr:  errutils.ErrDatabaseNoRowsAffected,
 		},
 		{
-			name:   "Inactive user with valid API key",
-			apiKey: validAPIKey,
+			name:     "No active user under given UUID",
+			userUUID: inactiveUser.UUID,
+			oldEmail: inactiveUser.Email,
+			newEmail: testkit.GenerateFakeEmail(),
+			wantErr:  errutils.ErrDatabaseNoRowsAffected,
+		},
+		{
+			name:     "Old email does not match",
+			userUUID: user.UUID,
+			oldEmail: testkit.GenerateFakeEmail(),
+			newEmail: testkit.GenerateFakeEmail(),
+			wantErr:  errutils.ErrDatabaseNoRowsAffected,
+		},
+		{
+			name:     "New email already taken",
+			userUUID: user.UUID,
+			oldEmail: user.Email,
+			newEmail: otherUser.Email,
+			wantErr:  errutils.ErrDatabaseUniqueViolation,
 		},
 	}
 
@@ -567,77 +676,29 @@ func TestRepositoryListActiveAPIKeysByPrefixEmpty(t *testing.T) {
 			t.Parallel()
 
 			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
-			apiKeys, err := repo.ListActiveAPIKeysByPrefix(dbConn, testcase.apiKey.Prefix)
-			require.NoError(t, err)
-
-			for _, k := range apiKeys {
-				require.NotEqual(t, testcase.apiKey.ID, k.ID)
-			}
+			err := repo.UpdateUserEmail(dbConn, testcase.userUUID, testcase.oldEmail, testcase.newEmail)
+			require.ErrorIs(t, err, testcase.wantErr)
 		})
 	}
 }
 
-func TestRepositoryUpdateAPIKeySuccess(t *testing.T) {
+func TestRepositoryGetAnyUserByUUIDSuccess(t *testing.T) {
 	t.Parallel()
 
-	startingName := "APIKeyName"
-	updatedName := "UpdatedAPIKeyName"
-	expiresAtNextMonth := pgtype.Timestamp{
-		Time:  time.Now().UTC().AddDate(0, 1, 0),
-		Valid: true,
-	}
-	expiresAtNextYear := pgtype.Timestamp{
-		Time:  time.Now().UTC().AddDate(1, 0, 0),
-		Valid: true,
-	}
-	expiresAtNull := pgtype.Timestamp{
-		Valid: false,
-	}
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := auth.NewRepository()
 
 	testcases := []struct {
-		name              string
-		startingName      string
-		updatedName       *string
-		startingExpiresAt pgtype.Timestamp
-		updatedExpiresAt  *pgtype.Timestamp
-		wantName          string
-		wantExpiresAt     pgtype.Timestamp
+		name     string
+		isActive bool
 	}{
 		{
-			name:              "Update name, update expires at from valid date to valid date",
-			startingName:      startingName,
-			updatedName:       &updatedName,
-			startingExpiresAt: expiresAtNextMonth,
-			updatedExpiresAt:  &expiresAtNextYear,
-			wantName:          updatedName,
-			wantExpiresAt:     expiresAtNextYear,
+			name:     "Active user",
+			isActive: true,
 		},
 		{
-			name:              "Update name only",
-			startingName:      startingName,
-			updatedName:       &updatedName,
-			startingExpiresAt: expiresAtNextMonth,
-			updatedExpiresAt:  nil,
-			wantName:          updatedName,
-			wantExpiresAt:     expiresAtNextMonth,
-		},
-		{
-			name:              "Update name, update expires at from null to valid date",
-			startingName:      startingName,
-			updatedName:       &updatedName,
-			startingExpiresAt: expiresAtNull,
-			updatedExpiresAt:  &expiresAtNextYear,
-			wantName:          updatedName,
-			wantExpiresAt:     expiresAtNextYear,
-		},
-		{
-			name:              "Update name, update expires at from valid date to null",
-			startingName:      startingName,
-			updatedName:       &updatedName,
-			startingExpiresAt: expiresAtNextMonth,
-			updatedExpiresAt:  &expiresAtNull,
-			wantName:          updatedName,
-			wantExpiresAt:     expiresAtNull,
+			name:     "Inactive user",
+			isActive: false,
 		},
 	}
 
@@ -646,95 +707,74 @@ func TestRepositoryUpdateAPIKeySuccess(t *testing.T) {
 			t.Parallel()
 
 			user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
-				u.IsActive = true
+				u.IsActive = testcase.isActive
 			})
 
-			apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
-				k.Name = testcase.startingName
-				k.ExpiresAt = testcase.startingExpiresAt
-			})
-
-			dbPool := testkitinternal.RequireCreateDatabasePool(t)
 			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
-			repo := auth.NewRepository()
-
-			updatedAPIKey, err := repo.UpdateAPIKey(dbConn, apiKey.ID, user.UUID, testcase.updatedName, testcase.updatedExpiresAt)
+			fetchedUser, err := repo.GetAnyUserByUUID(dbConn, user.UUID)
 			require.NoError(t, err)
 
-			require.Equal(t, apiKey.ID, updatedAPIKey.ID)
-			require.Equal(t, user.UUID, updatedAPIKey.UserUUID)
-			require.Equal(t, apiKey.Prefix, updatedAPIKey.Prefix)
-			require.Equal(t, apiKey.HashedKey, updatedAPIKey.HashedKey)
-			require.Equal(t, testcase.wantName, updatedAPIKey.Name)
-			require.Equal(t, apiKey.CreatedAt, updatedAPIKey.CreatedAt)
-			require.Equal(t, testcase.wantExpiresAt.Valid, updatedAPIKey.ExpiresAt.Valid)
-			if testcase.wantExpiresAt.Valid {
-				testkit.RequireTimeAlmostEqual(t, testcase.wantExpiresAt.Time, updatedAPIKey.ExpiresAt.Time)
-			}
+			require.Equal(t, user.UUID, fetchedUser.UUID)
+			require.Equal(t, user.Email, fetchedUser.Email)
+			require.Equal(t, testcase.isActive, fetchedUser.IsActive)
+			require.Equal(t, user.IsSuperUser, fetchedUser.IsSuperUser)
 		})
 	}
 }
 
-func TestRepositoryUpdateAPIKeyError(t *testing.T) {
+func TestRepositoryGetAnyUserByUUIDError(t *testing.T) {
 	t.Parallel()
 
-	updatedName := "UpdatedAPIKeyName"
-	updatedExpiresAt := pgtype.Timestamp{
-		Time:  time.Now().UTC().AddDate(1, 0, 0),
-		Valid: true,
-	}
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	_, err := repo.GetAnyUserByUUID(dbConn, uuid.NewString())
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+}
+
+func TestRepositoryListUsers(t *testing.T) {
+	t.Parallel()
+
+	search := testkit.MustGenerateRandomString(16, true, false, false)
 
 	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.FirstName = "A" + search
 		u.IsActive = true
 	})
-
-	activeUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
-		k.Name = "APIKeyName"
-		k.ExpiresAt = pgtype.Timestamp{
-			Time:  time.Now().UTC().AddDate(0, 1, 0),
-			Valid: true,
-		}
-	})
-
 	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.LastName = search + "Z"
 		u.IsActive = false
 	})
+	testkitinternal.MustCreateUser(t, nil)
 
-	inactiveUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, func(k *auth.APIKey) {
-		k.Name = "APIKeyName"
-		k.ExpiresAt = pgtype.Timestamp{
-			Time:  time.Now().UTC().AddDate(0, 1, 0),
-			Valid: true,
-		}
-	})
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := auth.NewRepository()
 
 	testcases := []struct {
-		name             string
-		apiKeyID         int
-		userUUID         string
-		updatedName      *string
-		updatedExpiresAt *pgtype.Timestamp
+		name          string
+		search        string
+		wantUserUUIDs []string
 	}{
 		{
-			name:             "Update neither name nor expires at",
-			apiKeyID:         activeUserAPIKey.ID,
-			userUUID:         activeUser.UUID,
-			updatedName:      nil,
-			updatedExpiresAt: nil,
+			name:          "Search matches active and inactive users",
+			search:        search,
+			wantUserUUIDs: []string{activeUser.UUID, inactiveUser.UUID},
 		},
 		{
-			name:             "Update non-existent API key",
-			apiKeyID:         314159,
-			userUUID:         activeUser.UUID,
-			updatedName:      &updatedName,
-			updatedExpiresAt: &updatedExpiresAt,
+			name:          "Search ignores case",
+			search:        strings.ToUpper(search),
+			wantUserUUIDs: []string{activeUser.UUID, inactiveUser.UUID},
 		},
 		{
-			name:             "Update API key for inactive user",
-			apiKeyID:         inactiveUserAPIKey.ID,
-			userUUID:         inactiveUser.UUID,
-			updatedName:      &updatedName,
-			updatedExpiresAt: &updatedExpiresAt,
+			name:          "Search by email",
+			search:        activeUser.Email,
+			wantUserUUIDs: []string{activeUser.UUID},
+		},
+		{
+			name:          "Search with no matches",
+			search:        search + "%",
+			wantUserUUIDs: []string{},
 		},
 	}
 
@@ -742,67 +782,150 @@ func TestRepositoryUpdateAPIKeyError(t *testing.T) {
 		t.Run(testcase.name, func(t *testing.T) {
 			t.Parallel()
 
-			dbPool := testkitinternal.RequireCreateDatabasePool(t)
 			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
-			repo := auth.NewRepository()
+			users, err := repo.ListUsers(dbConn, testcase.search)
+			require.NoError(t, err)
 
-			_, err := repo.UpdateAPIKey(dbConn, testcase.apiKeyID, testcase.userUUID, testcase.updatedName, testcase.updatedExpiresAt)
-			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected, err)
+			userUUIDs := make([]string, len(users))
+			for i, user := range users {
+				userUUIDs[i] = user.UUID
+			}
+
+			require.ElementsMatch(t, testcase.wantUserUUIDs, userUUIDs)
 		})
 	}
 }
 
-func TestRepositoryDeleteAPIKeySuccess(t *testing.T) {
+func TestRepositoryListUsersEmptySearch(t *testing.T) {
 	t.Parallel()
 
-	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
-
-	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := auth.NewRepository()
 
-	err := repo.DeleteAPIKey(dbConn, apiKey.ID, user.UUID)
+	users, err := repo.ListUsers(dbConn, "")
 	require.NoError(t, err)
 
-	apiKeys, err := repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
-	require.NoError(t, err)
-	require.Empty(t, apiKeys)
+	userUUIDs := make([]string, len(users))
+	for i, user := range users {
+		userUUIDs[i] = user.UUID
+	}
+
+	require.Contains(t, userUUIDs, activeUser.UUID)
+	require.Contains(t, userUUIDs, inactiveUser.UUID)
 }
 
-func TestRepositoryDeleteAPIKeyError(t *testing.T) {
+func TestRepositoryUpdateUserStatusSuccess(t *testing.T) {
 	t.Parallel()
 
-	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
-		u.IsActive = true
-	})
+	trueValue := true
+	falseValue := false
 
-	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
-		u.IsActive = false
-	})
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := auth.NewRepository()
 
-	inactiveUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+	testcases := []struct {
+		name            string
+		isActive        bool
+		isSuperUser     bool
+		newIsActive     *bool
+		newIsSuperUser  *bool
+		wantIsActive    bool
+		wantIsSuperUser bool
+	}{
+		{
+			name:            "Deactivate user",
+			isActive:        true,
+			isSuperUser:     false,
+			newIsActive:     &falseValue,
+			newIsSuperUser:  nil,
+			wantIsActive:    false,
+			wantIsSuperUser: false,
+		},
+		{
+			name:            "Activate user",
+			isActive:        false,
+			isSuperUser:     false,
+			newIsActive:     &trueValue,
+			newIsSuperUser:  nil,
+			wantIsActive:    true,
+			wantIsSuperUser: false,
+		},
+		{
+			name:            "Grant superuser",
+			isActive:        true,
+			isSuperUser:     false,
+			newIsActive:     nil,
+			newIsSuperUser:  &trueValue,
+			wantIsActive:    true,
+			wantIsSuperUser: true,
+		},
+		{
+			name:            "Revoke superuser and deactivate user",
+			isActive:        true,
+			isSuperUser:     true,
+			newIsActive:     &falseValue,
+			newIsSuperUser:  &falseValue,
+			wantIsActive:    false,
+			wantIsSuperUser: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+				u.IsActive = testcase.isActive
+				u.IsSuperUser = testcase.isSuperUser
+			})
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			updatedUser, err := repo.UpdateUserStatus(dbConn, user.UUID, testcase.newIsActive, testcase.newIsSuperUser)
+			require.NoError(t, err)
+
+			require.Equal(t, user.UUID, updatedUser.UUID)
+			require.Equal(t, user.Email, updatedUser.Email)
+			require.Equal(t, testcase.wantIsActive, updatedUser.IsActive)
+			require.Equal(t, testcase.wantIsSuperUser, updatedUser.IsSuperUser)
+		})
+	}
+}
+
+func TestRepositoryUpdateUserStatusError(t *testing.T) {
+	t.Parallel()
+
+	falseValue := false
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := auth.NewRepository()
 
 	testcases := []struct {
-		name     string
-		apiKeyID int
-		userUUID string
+		name        string
+		userUUID    string
+		isActive    *bool
+		isSuperUser *bool
 	}{
 		{
-			name:     "Active user with no API keys",
-			apiKeyID: 42,
-			userUUID: activeUser.UUID,
+			name:        "No user under UUID",
+			userUUID:    uuid.NewString(),
+			isActive:    &falseValue,
+			isSuperUser: nil,
 		},
 		{
-			name:     "Inactive user with valid API key",
-			apiKeyID: inactiveUserAPIKey.ID,
-			userUUID: inactiveUser.UUID,
+			name:        "No attributes to update",
+			userUUID:    user.UUID,
+			isActive:    nil,
+			isSuperUser: nil,
 		},
 	}
 
@@ -811,8 +934,2547 @@ func TestRepositoryDeleteAPIKeyError(t *testing.T) {
 			t.Parallel()
 
 			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
-			err := repo.DeleteAPIKey(dbConn, testcase.apiKeyID, testcase.userUUID)
+			_, err := repo.UpdateUserStatus(dbConn, testcase.userUUID, testcase.isActive, testcase.isSuperUser)
 			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
 		})
 	}
 }
+
+func TestRepositoryCreateAPIKeySuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	apiKey := &auth.APIKey{
+		UserUUID:  user.UUID,
+		Prefix:    testkit.MustGenerateRandomString(8, true, true, true),
+		HashedKey: testkit.MustGenerateRandomString(16, true, true, true),
+		KeyDigest: testkit.MustGenerateRandomString(64, true, true, true),
+		Name:      "My API Key",
+		Scopes:    []string{"flags:read", "user:read"},
+		ExpiresAt: pgtype.Timestamp{
+			Valid: false,
+		},
+	}
+
+	createdAPIKey, err := repo.CreateAPIKey(dbConn, apiKey)
+	require.NoError(t, err)
+
+	require.Equal(t, apiKey.Prefix, createdAPIKey.Prefix)
+	require.Equal(t, apiKey.HashedKey, createdAPIKey.HashedKey)
+	require.Equal(t, apiKey.KeyDigest, createdAPIKey.KeyDigest)
+	require.Equal(t, []string{}, createdAPIKey.AllowedCIDRs)
+	require.Equal(t, apiKey.Name, createdAPIKey.Name)
+	require.Equal(t, apiKey.Scopes, createdAPIKey.Scopes)
+	require.False(t, apiKey.ExpiresAt.Valid)
+}
+
+func TestRepositoryCreateAPIKeyDuplicateName(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.Name = "My API Key"
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	apiKey := &auth.APIKey{
+		UserUUID:  user.UUID,
+		Prefix:    testkit.MustGenerateRandomString(8, true, true, true),
+		HashedKey: testkit.MustGenerateRandomString(16, true, true, true),
+		Name:      "My API Key",
+		ExpiresAt: pgtype.Timestamp{
+			Valid: false,
+		},
+	}
+
+	_, err := repo.CreateAPIKey(dbConn, apiKey)
+	require.ErrorIs(t, err, errutils.ErrDatabaseUniqueViolation)
+}
+
+func TestRepositoryListAPIKeysByUserUUIDSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey1, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+	apiKey2, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	wantAPIKeys := []*auth.APIKey{apiKey1, apiKey2}
+	sort.Slice(wantAPIKeys, func(i, j int) bool {
+		return wantAPIKeys[i].Prefix < wantAPIKeys[j].Prefix
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	apiKeys, err := repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 2)
+
+	sort.Slice(apiKeys, func(i, j int) bool {
+		return apiKeys[i].Prefix < apiKeys[j].Prefix
+	})
+
+	for i, apiKey := range apiKeys {
+		wantKey := wantAPIKeys[i]
+		require.Equal(t, wantKey.ID, apiKey.ID)
+		require.Equal(t, wantKey.UserUUID, apiKey.UserUUID)
+		require.Equal(t, wantKey.Prefix, apiKey.Prefix)
+		require.Equal(t, wantKey.Name, apiKey.Name)
+		require.Equal(t, wantKey.Scopes, apiKey.Scopes)
+		require.Equal(t, wantKey.CreatedAt, apiKey.CreatedAt)
+		require.Equal(t, wantKey.ExpiresAt, apiKey.ExpiresAt)
+	}
+}
+
+func TestRepositoryListAPIKeysByUserUUIDInactiveUser(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	apiKeys, err := repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Empty(t, apiKeys)
+}
+
+func TestRepositoryListActiveAPIKeysByPrefixSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	apiKeys, err := repo.ListActiveAPIKeysByPrefix(dbConn, apiKey.Prefix)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 1)
+
+	var fetchedAPIKey *auth.APIKey
+	for _, k := range apiKeys {
+		if k.ID == apiKey.ID {
+			fetchedAPIKey = k
+			break
+		}
+	}
+
+	require.NotNil(t, fetchedAPIKey)
+	require.Equal(t, apiKey.ID, fetchedAPIKey.ID)
+	require.Equal(t, apiKey.Prefix, fetchedAPIKey.Prefix)
+	require.Equal(t, apiKey.HashedKey, fetchedAPIKey.HashedKey)
+	require.Equal(t, apiKey.KeyDigest, fetchedAPIKey.KeyDigest)
+	require.Equal(t, apiKey.Name, fetchedAPIKey.Name)
+	require.Equal(t, apiKey.ExpiresAt, fetchedAPIKey.ExpiresAt)
+}
+
+func TestRepositoryGetActiveAPIKeyByDigest(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	activeAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, nil)
+	expiredAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.ExpiresAt = pgtype.Timestamp{
+			Time:  time.Now().UTC().Add(-time.Hour),
+			Valid: true,
+		}
+	})
+	inactiveUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+	gracefulAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, nil)
+	expiredGraceAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	_, err := repo.RotateAPIKey(
+		dbConn,
+		gracefulAPIKey.ID,
+		activeUser.UUID,
+		testkit.MustGenerateRandomString(8, true, true, true),
+		testkit.MustGenerateRandomString(64, true, true, true),
+		time.Now().UTC().Add(time.Hour),
+	)
+	require.NoError(t, err)
+
+	_, err = repo.RotateAPIKey(
+		dbConn,
+		expiredGraceAPIKey.ID,
+		activeUser.UUID,
+		testkit.MustGenerateRandomString(8, true, true, true),
+		testkit.MustGenerateRandomString(64, true, true, true),
+		time.Now().UTC().Add(-time.Hour),
+	)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name      string
+		keyDigest string
+		wantID    int
+		wantErr   error
+	}{
+		{
+			name:      "Active API key",
+			keyDigest: activeAPIKey.KeyDigest,
+			wantID:    activeAPIKey.ID,
+			wantErr:   nil,
+		},
+		{
+			name:      "Previous key digest within grace period",
+			keyDigest: gracefulAPIKey.KeyDigest,
+			wantID:    gracefulAPIKey.ID,
+			wantErr:   nil,
+		},
+		{
+			name:      "Previous key digest after grace period",
+			keyDigest: expiredGraceAPIKey.KeyDigest,
+			wantErr:   errutils.ErrDatabaseNoRowsReturned,
+		},
+		{
+			name:      "Expired API key",
+			keyDigest: expiredAPIKey.KeyDigest,
+			wantErr:   errutils.ErrDatabaseNoRowsReturned,
+		},
+		{
+			name:      "API key for inactive user",
+			keyDigest: inactiveUserAPIKey.KeyDigest,
+			wantErr:   errutils.ErrDatabaseNoRowsReturned,
+		},
+		{
+			name:      "Non-existent key digest",
+			keyDigest: testkit.MustGenerateRandomString(64, true, true, true),
+			wantErr:   errutils.ErrDatabaseNoRowsReturned,
+		},
+		{
+			name:      "Empty key digest",
+			keyDigest: "",
+			wantErr:   errutils.ErrDatabaseNoRowsReturned,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbPool := testkitinternal.RequireCreateDatabasePool(t)
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			repo := auth.NewRepository()
+
+			apiKey, err := repo.GetActiveAPIKeyByDigest(dbConn, testcase.keyDigest)
+			if testcase.wantErr != nil {
+				require.ErrorIs(t, err, testcase.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantID, apiKey.ID)
+		})
+	}
+}
+
+func TestRepositoryMigrateAPIKeyDigest(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	hashedKey := testkit.MustGenerateRandomString(16, true, true, true)
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.HashedKey = hashedKey
+		k.KeyDigest = ""
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	keyDigest := testkit.MustGenerateRandomString(64, true, true, true)
+	err := repo.MigrateAPIKeyDigest(dbConn, apiKey.ID, testkit.MustGenerateRandomString(16, true, true, true), keyDigest)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	err = repo.MigrateAPIKeyDigest(dbConn, apiKey.ID, hashedKey, keyDigest)
+	require.NoError(t, err)
+
+	migratedAPIKey, err := repo.GetActiveAPIKeyByDigest(dbConn, keyDigest)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, migratedAPIKey.ID)
+	require.Empty(t, migratedAPIKey.HashedKey)
+	require.Equal(t, keyDigest, migratedAPIKey.KeyDigest)
+
+	err = repo.MigrateAPIKeyDigest(dbConn, apiKey.ID, hashedKey, keyDigest)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+}
+
+func TestRepositoryMigrateAPIKeyDigestPrevious(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	hashedKey := testkit.MustGenerateRandomString(16, true, true, true)
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.HashedKey = hashedKey
+		k.KeyDigest = ""
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	rotatedKeyDigest := testkit.MustGenerateRandomString(64, true, true, true)
+	_, err := repo.RotateAPIKey(
+		dbConn,
+		apiKey.ID,
+		user.UUID,
+		testkit.MustGenerateRandomString(8, true, true, true),
+		rotatedKeyDigest,
+		time.Now().UTC().Add(time.Hour),
+	)
+	require.NoError(t, err)
+
+	keyDigest := testkit.MustGenerateRandomString(64, true, true, true)
+	err = repo.MigrateAPIKeyDigest(dbConn, apiKey.ID, hashedKey, keyDigest)
+	require.NoError(t, err)
+
+	migratedAPIKey, err := repo.GetActiveAPIKeyByDigest(dbConn, keyDigest)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, migratedAPIKey.ID)
+	require.Equal(t, rotatedKeyDigest, migratedAPIKey.KeyDigest)
+	require.Empty(t, migratedAPIKey.PreviousHashedKey)
+	require.Equal(t, keyDigest, migratedAPIKey.PreviousKeyDigest)
+}
+
+func TestRepositoryListActiveAPIKeysByPrefixEmpty(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	expiredAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.ExpiresAt = pgtype.Timestamp{
+			Time:  time.Now().UTC().AddDate(0, 0, -1),
+			Valid: true,
+		}
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	validAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := auth.NewRepository()
+
+	testcases := []struct {
+		name   string
+		apiKey *auth.APIKey
+	}{
+		{
+			name:   "Active user with expired API key",
+			apiKey: expiredAPIKey,
+		},
+		{
+			name:   "Inactive user with valid API key",
+			apiKey: validAPIKey,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			apiKeys, err := repo.ListActiveAPIKeysByPrefix(dbConn, testcase.apiKey.Prefix)
+			require.NoError(t, err)
+
+			for _, k := range apiKeys {
+				require.NotEqual(t, testcase.apiKey.ID, k.ID)
+			}
+		})
+	}
+}
+
+func TestRepositoryUpdateAPIKeySuccess(t *testing.T) {
+	t.Parallel()
+
+	startingName := "APIKeyName"
+	updatedName := "UpdatedAPIKeyName"
+	expiresAtNextMonth := pgtype.Timestamp{
+		Time:  time.Now().UTC().AddDate(0, 1, 0),
+		Valid: true,
+	}
+	expiresAtNextYear := pgtype.Timestamp{
+		Time:  time.Now().UTC().AddDate(1, 0, 0),
+		Valid: true,
+	}
+	expiresAtNull := pgtype.Timestamp{
+		Valid: false,
+	}
+
+	testcases := []struct {
+		name              string
+		startingName      string
+		updatedName       *string
+		startingExpiresAt pgtype.Timestamp
+		updatedExpiresAt  *pgtype.Timestamp
+		wantName          string
+		wantExpiresAt     pgtype.Timestamp
+	}{
+		{
+			name:              "Update name, update expires at from valid date to valid date",
+			startingName:      startingName,
+			updatedName:       &updatedName,
+			startingExpiresAt: expiresAtNextMonth,
+			updatedExpiresAt:  &expiresAtNextYear,
+			wantName:          updatedName,
+			wantExpiresAt:     expiresAtNextYear,
+		},
+		{
+			name:              "Update name only",
+			startingName:      startingName,
+			updatedName:       &updatedName,
+			startingExpiresAt: expiresAtNextMonth,
+			updatedExpiresAt:  nil,
+			wantName:          updatedName,
+			wantExpiresAt:     expiresAtNextMonth,
+		},
+		{
+			name:              "Update name, update expires at from null to valid date",
+			startingName:      startingName,
+			updatedName:       &updatedName,
+			startingExpiresAt: expiresAtNull,
+			updatedExpiresAt:  &expiresAtNextYear,
+			wantName:          updatedName,
+			wantExpiresAt:     expiresAtNextYear,
+		},
+		{
+			name:              "Update name, update expires at from valid date to null",
+			startingName:      startingName,
+			updatedName:       &updatedName,
+			startingExpiresAt: expiresAtNextMonth,
+			updatedExpiresAt:  &expiresAtNull,
+			wantName:          updatedName,
+			wantExpiresAt:     expiresAtNull,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+				u.IsActive = true
+			})
+
+			apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+				k.Name = testcase.startingName
+				k.ExpiresAt = testcase.startingExpiresAt
+			})
+
+			dbPool := testkitinternal.RequireCreateDatabasePool(t)
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			repo := auth.NewRepository()
+
+			updatedAPIKey, err := repo.UpdateAPIKey(dbConn, apiKey.ID, user.UUID, testcase.updatedName, nil, nil, testcase.updatedExpiresAt)
+			require.NoError(t, err)
+
+			require.Equal(t, apiKey.ID, updatedAPIKey.ID)
+			require.Equal(t, user.UUID, updatedAPIKey.UserUUID)
+			require.Equal(t, apiKey.Prefix, updatedAPIKey.Prefix)
+			require.Equal(t, apiKey.HashedKey, updatedAPIKey.HashedKey)
+			require.Equal(t, testcase.wantName, updatedAPIKey.Name)
+			require.Equal(t, apiKey.CreatedAt, updatedAPIKey.CreatedAt)
+			require.Equal(t, testcase.wantExpiresAt.Valid, updatedAPIKey.ExpiresAt.Valid)
+			if testcase.wantExpiresAt.Valid {
+				testkit.RequireTimeAlmostEqual(t, testcase.wantExpiresAt.Time, updatedAPIKey.ExpiresAt.Time)
+			}
+		})
+	}
+}
+
+func TestRepositoryUpdateAPIKeyError(t *testing.T) {
+	t.Parallel()
+
+	updatedName := "UpdatedAPIKeyName"
+	updatedExpiresAt := pgtype.Timestamp{
+		Time:  time.Now().UTC().AddDate(1, 0, 0),
+		Valid: true,
+	}
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	activeUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Name = "APIKeyName"
+		k.ExpiresAt = pgtype.Timestamp{
+			Time:  time.Now().UTC().AddDate(0, 1, 0),
+			Valid: true,
+		}
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	inactiveUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, func(k *auth.APIKey) {
+		k.Name = "APIKeyName"
+		k.ExpiresAt = pgtype.Timestamp{
+			Time:  time.Now().UTC().AddDate(0, 1, 0),
+			Valid: true,
+		}
+	})
+
+	testcases := []struct {
+		name             string
+		apiKeyID         int
+		userUUID         string
+		updatedName      *string
+		updatedExpiresAt *pgtype.Timestamp
+	}{
+		{
+			name:             "Update neither name nor expires at",
+			apiKeyID:         activeUserAPIKey.ID,
+			userUUID:         activeUser.UUID,
+			updatedName:      nil,
+			updatedExpiresAt: nil,
+		},
+		{
+			name:             "Update non-existent API key",
+			apiKeyID:         314159,
+			userUUID:         activeUser.UUID,
+			updatedName:      &updatedName,
+			updatedExpiresAt: &updatedExpiresAt,
+		},
+		{
+			name:             "Update API key for inactive user",
+			apiKeyID:         inactiveUserAPIKey.ID,
+			userUUID:         inactiveUser.UUID,
+			updatedName:      &updatedName,
+			updatedExpiresAt: &updatedExpiresAt,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbPool := testkitinternal.RequireCreateDatabasePool(t)
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			repo := auth.NewRepository()
+
+			_, err := repo.UpdateAPIKey(dbConn, testcase.apiKeyID, testcase.userUUID, testcase.updatedName, nil, nil, testcase.updatedExpiresAt)
+			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected, err)
+		})
+	}
+}
+
+func TestRepositoryUpdateAPIKeyDuplicateName(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+	otherAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	_, err := repo.UpdateAPIKey(dbConn, apiKey.ID, user.UUID, &otherAPIKey.Name, nil, nil, nil)
+	require.ErrorIs(t, err, errutils.ErrDatabaseUniqueViolation)
+}
+
+func TestRepositoryUpdateAPIKeyAllowedCIDRs(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.AllowedCIDRs = []string{"10.0.0.0/8"}
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	require.Equal(t, []string{"10.0.0.0/8"}, apiKey.AllowedCIDRs)
+
+	allowedCIDRs := []string{"192.168.0.0/16", "2001:db8::/32"}
+	updatedAPIKey, err := repo.UpdateAPIKey(dbConn, apiKey.ID, user.UUID, nil, &allowedCIDRs, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, allowedCIDRs, updatedAPIKey.AllowedCIDRs)
+	require.Equal(t, apiKey.Name, updatedAPIKey.Name)
+
+	updatedName := "Renamed API Key"
+	updatedAPIKey, err = repo.UpdateAPIKey(dbConn, apiKey.ID, user.UUID, &updatedName, nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, allowedCIDRs, updatedAPIKey.AllowedCIDRs)
+
+	var noAllowedCIDRs []string
+	updatedAPIKey, err = repo.UpdateAPIKey(dbConn, apiKey.ID, user.UUID, nil, &noAllowedCIDRs, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{}, updatedAPIKey.AllowedCIDRs)
+}
+
+func TestRepositoryUpdateAPIKeyAllowedOrigins(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.AllowedOrigins = []string{"https://app.example.com"}
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	require.Equal(t, []string{"https://app.example.com"}, apiKey.AllowedOrigins)
+
+	allowedOrigins := []string{"https://www.example.com", "http://localhost:3000"}
+	updatedAPIKey, err := repo.UpdateAPIKey(dbConn, apiKey.ID, user.UUID, nil, nil, &allowedOrigins, nil)
+	require.NoError(t, err)
+	require.Equal(t, allowedOrigins, updatedAPIKey.AllowedOrigins)
+	require.Equal(t, apiKey.Name, updatedAPIKey.Name)
+
+	updatedName := "Renamed API Key"
+	updatedAPIKey, err = repo.UpdateAPIKey(dbConn, apiKey.ID, user.UUID, &updatedName, nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, allowedOrigins, updatedAPIKey.AllowedOrigins)
+
+	var noAllowedOrigins []string
+	updatedAPIKey, err = repo.UpdateAPIKey(dbConn, apiKey.ID, user.UUID, nil, nil, &noAllowedOrigins, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{}, updatedAPIKey.AllowedOrigins)
+}
+
+func TestRepositoryRotateAPIKeySuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	prefix := testkit.MustGenerateRandomString(8, true, true, true)
+	keyDigest := testkit.MustGenerateRandomString(64, true, true, true)
+	previousExpiresAt := time.Now().UTC().Add(time.Hour)
+
+	rotatedAPIKey, err := repo.RotateAPIKey(dbConn, apiKey.ID, user.UUID, prefix, keyDigest, previousExpiresAt)
+	require.NoError(t, err)
+
+	require.Equal(t, apiKey.ID, rotatedAPIKey.ID)
+	require.Equal(t, apiKey.Name, rotatedAPIKey.Name)
+	require.Equal(t, apiKey.Scopes, rotatedAPIKey.Scopes)
+	require.Equal(t, apiKey.ExpiresAt, rotatedAPIKey.ExpiresAt)
+	require.Equal(t, prefix, rotatedAPIKey.Prefix)
+	require.Empty(t, rotatedAPIKey.HashedKey)
+	require.Equal(t, keyDigest, rotatedAPIKey.KeyDigest)
+	require.Equal(t, apiKey.Prefix, rotatedAPIKey.PreviousPrefix)
+	require.Equal(t, apiKey.HashedKey, rotatedAPIKey.PreviousHashedKey)
+	require.Equal(t, apiKey.KeyDigest, rotatedAPIKey.PreviousKeyDigest)
+	require.True(t, rotatedAPIKey.PreviousExpiresAt.Valid)
+	testkit.RequireTimeAlmostEqual(t, previousExpiresAt, rotatedAPIKey.PreviousExpiresAt.Time)
+}
+
+func TestRepositoryRotateAPIKeyError(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	inactiveUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+
+	testcases := []struct {
+		name     string
+		apiKeyID int
+		userUUID string
+	}{
+		{
+			name:     "Rotate non-existent API key",
+			apiKeyID: 314159,
+			userUUID: activeUser.UUID,
+		},
+		{
+			name:     "Rotate API key for inactive user",
+			apiKeyID: inactiveUserAPIKey.ID,
+			userUUID: inactiveUser.UUID,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbPool := testkitinternal.RequireCreateDatabasePool(t)
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			repo := auth.NewRepository()
+
+			_, err := repo.RotateAPIKey(
+				dbConn,
+				testcase.apiKeyID,
+				testcase.userUUID,
+				testkit.MustGenerateRandomString(8, true, true, true),
+				testkit.MustGenerateRandomString(16, true, true, true),
+				time.Now().UTC().Add(time.Hour),
+			)
+			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected, err)
+		})
+	}
+}
+
+func TestRepositoryListActiveAPIKeysByPrefixRotated(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	gracefulAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+	expiredGraceAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	_, err := repo.RotateAPIKey(
+		dbConn,
+		gracefulAPIKey.ID,
+		user.UUID,
+		testkit.MustGenerateRandomString(8, true, true, true),
+		testkit.MustGenerateRandomString(16, true, true, true),
+		time.Now().UTC().Add(time.Hour),
+	)
+	require.NoError(t, err)
+
+	_, err = repo.RotateAPIKey(
+		dbConn,
+		expiredGraceAPIKey.ID,
+		user.UUID,
+		testkit.MustGenerateRandomString(8, true, true, true),
+		testkit.MustGenerateRandomString(16, true, true, true),
+		time.Now().UTC().Add(-time.Hour),
+	)
+	require.NoError(t, err)
+
+	apiKeys, err := repo.ListActiveAPIKeysByPrefix(dbConn, gracefulAPIKey.Prefix)
+	require.NoError(t, err)
+
+	found := false
+	for _, k := range apiKeys {
+		if k.ID == gracefulAPIKey.ID {
+			found = true
+			require.Equal(t, gracefulAPIKey.Prefix, k.PreviousPrefix)
+			require.Equal(t, gracefulAPIKey.HashedKey, k.PreviousHashedKey)
+			require.Equal(t, gracefulAPIKey.KeyDigest, k.PreviousKeyDigest)
+		}
+	}
+	require.True(t, found)
+
+	apiKeys, err = repo.ListActiveAPIKeysByPrefix(dbConn, expiredGraceAPIKey.Prefix)
+	require.NoError(t, err)
+
+	for _, k := range apiKeys {
+		require.NotEqual(t, expiredGraceAPIKey.ID, k.ID)
+	}
+}
+
+func TestRepositoryRecordAPIKeyUsage(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.AddDate(0, 0, -1)
+
+	err := repo.RecordAPIKeyUsage(dbConn, apiKey.ID, today, 2, today.Add(time.Hour), "10.0.0.1")
+	require.NoError(t, err)
+
+	err = repo.RecordAPIKeyUsage(dbConn, apiKey.ID, today, 3, today.Add(2*time.Hour), "10.0.0.2")
+	require.NoError(t, err)
+
+	// usage flushed out of order must not move last used time backwards
+	err = repo.RecordAPIKeyUsage(dbConn, apiKey.ID, yesterday, 1, yesterday.Add(time.Hour), "10.0.0.3")
+	require.NoError(t, err)
+
+	apiKeys, err := repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 1)
+
+	require.Equal(t, int64(6), apiKeys[0].RequestCount)
+	require.Equal(t, "10.0.0.2", apiKeys[0].LastUsedIP)
+	require.True(t, apiKeys[0].LastUsedAt.Valid)
+	testkit.RequireTimeAlmostEqual(t, today.Add(2*time.Hour), apiKeys[0].LastUsedAt.Time)
+
+	usage, err := repo.ListAPIKeyUsage(dbConn, apiKey.ID, user.UUID, yesterday, today)
+	require.NoError(t, err)
+	require.Len(t, usage, 2)
+	require.Equal(t, int64(1), usage[0].RequestCount)
+	require.Equal(t, int64(5), usage[1].RequestCount)
+}
+
+func TestRepositoryRecordAPIKeyUsageDeletedAPIKey(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	err := repo.DeleteAPIKey(dbConn, apiKey.ID, user.UUID)
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	err = repo.RecordAPIKeyUsage(dbConn, apiKey.ID, now.Truncate(24*time.Hour), 1, now, "10.0.0.1")
+	require.NoError(t, err)
+}
+
+func TestRepositoryListAPIKeyUsage(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	activeUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, nil)
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+	inactiveUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, -6)
+	testkitinternal.MustRecordAPIKeyUsage(t, activeUserAPIKey.ID, today.AddDate(0, 0, -3), 4)
+	testkitinternal.MustRecordAPIKeyUsage(t, inactiveUserAPIKey.ID, today, 4)
+
+	testcases := []struct {
+		name         string
+		apiKeyID     int
+		userUUID     string
+		wantDayCount int
+	}{
+		{
+			name:         "API key for active user",
+			apiKeyID:     activeUserAPIKey.ID,
+			userUUID:     activeUser.UUID,
+			wantDayCount: 7,
+		},
+		{
+			name:         "API key for another user",
+			apiKeyID:     activeUserAPIKey.ID,
+			userUUID:     inactiveUser.UUID,
+			wantDayCount: 0,
+		},
+		{
+			name:         "API key for inactive user",
+			apiKeyID:     inactiveUserAPIKey.ID,
+			userUUID:     inactiveUser.UUID,
+			wantDayCount: 0,
+		},
+		{
+			name:         "Non-existent API key",
+			apiKeyID:     314159,
+			userUUID:     activeUser.UUID,
+			wantDayCount: 0,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbPool := testkitinternal.RequireCreateDatabasePool(t)
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			repo := auth.NewRepository()
+
+			usage, err := repo.ListAPIKeyUsage(dbConn, testcase.apiKeyID, testcase.userUUID, since, today)
+			require.NoError(t, err)
+			require.Len(t, usage, testcase.wantDayCount)
+
+			for i, dailyUsage := range usage {
+				require.Equal(t, testcase.apiKeyID, dailyUsage.APIKeyID)
+				require.Equal(t, since.AddDate(0, 0, i), dailyUsage.Day.UTC())
+
+				wantRequestCount := int64(0)
+				if i == 3 {
+					wantRequestCount = 4
+				}
+				require.Equal(t, wantRequestCount, dailyUsage.RequestCount)
+			}
+		})
+	}
+}
+
+func TestRepositoryDeleteAPIKeySuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	err := repo.DeleteAPIKey(dbConn, apiKey.ID, user.UUID)
+	require.NoError(t, err)
+
+	apiKeys, err := repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Empty(t, apiKeys)
+}
+
+func TestRepositoryDeleteAPIKeyError(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	inactiveUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := auth.NewRepository()
+
+	testcases := []struct {
+		name     string
+		apiKeyID int
+		userUUID string
+	}{
+		{
+			name:     "Active user with no API keys",
+			apiKeyID: 42,
+			userUUID: activeUser.UUID,
+		},
+		{
+			name:     "Inactive user with valid API key",
+			apiKeyID: inactiveUserAPIKey.ID,
+			userUUID: inactiveUser.UUID,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			err := repo.DeleteAPIKey(dbConn, testcase.apiKeyID, testcase.userUUID)
+			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+		})
+	}
+}
+
+func TestRepositoryDeleteUserAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, nil)
+	testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+	testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.ServiceAccountUUID = pgtype.Text{
+			String: serviceAccount.UUID,
+			Valid:  true,
+		}
+	})
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, otherUser.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	deletedCount, err := repo.DeleteUserAPIKeys(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), deletedCount)
+
+	apiKeys, err := repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Empty(t, apiKeys)
+
+	otherUserAPIKeys, err := repo.ListAPIKeysByUserUUID(dbConn, otherUser.UUID)
+	require.NoError(t, err)
+	require.Len(t, otherUserAPIKeys, 1)
+	require.Equal(t, otherUserAPIKey.ID, otherUserAPIKeys[0].ID)
+}
+
+func TestRepositoryCreateServiceAccountSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	serviceAccount := &auth.ServiceAccount{
+		UUID:        uuid.NewString(),
+		OwnerUUID:   user.UUID,
+		Name:        "Deploy Pipeline",
+		Description: "Toggles flags during deployments",
+		Scopes:      []string{"flags:write"},
+		IsActive:    true,
+	}
+
+	createdServiceAccount, err := repo.CreateServiceAccount(dbConn, serviceAccount)
+	require.NoError(t, err)
+
+	require.Equal(t, serviceAccount.UUID, createdServiceAccount.UUID)
+	require.Equal(t, user.UUID, createdServiceAccount.OwnerUUID)
+	require.Equal(t, serviceAccount.Name, createdServiceAccount.Name)
+	require.Equal(t, serviceAccount.Description, createdServiceAccount.Description)
+	require.Equal(t, serviceAccount.Scopes, createdServiceAccount.Scopes)
+	require.True(t, createdServiceAccount.IsActive)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), createdServiceAccount.CreatedAt)
+}
+
+func TestRepositoryCreateServiceAccountDuplicateName(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	_, err := repo.CreateServiceAccount(dbConn, &auth.ServiceAccount{
+		UUID:      uuid.NewString(),
+		OwnerUUID: user.UUID,
+		Name:      serviceAccount.Name,
+		Scopes:    []string{"admin"},
+		IsActive:  true,
+	})
+	require.ErrorIs(t, err, errutils.ErrDatabaseUniqueViolation)
+}
+
+func TestRepositoryGetServiceAccountByUUID(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, activeUser.UUID, nil)
+	inactiveUserServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, inactiveUser.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := auth.NewRepository()
+
+	testcases := []struct {
+		name               string
+		serviceAccountUUID string
+		ownerUUID          string
+		wantErr            bool
+	}{
+		{
+			name:               "Service account of active user",
+			serviceAccountUUID: serviceAccount.UUID,
+			ownerUUID:          activeUser.UUID,
+			wantErr:            false,
+		},
+		{
+			name:               "Service account of another user",
+			serviceAccountUUID: serviceAccount.UUID,
+			ownerUUID:          otherUser.UUID,
+			wantErr:            true,
+		},
+		{
+			name:               "Service account of inactive user",
+			serviceAccountUUID: inactiveUserServiceAccount.UUID,
+			ownerUUID:          inactiveUser.UUID,
+			wantErr:            true,
+		},
+		{
+			name:               "Non-existent service account",
+			serviceAccountUUID: uuid.NewString(),
+			ownerUUID:          activeUser.UUID,
+			wantErr:            true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			fetchedServiceAccount, err := repo.GetServiceAccountByUUID(dbConn, testcase.serviceAccountUUID, testcase.ownerUUID)
+			if testcase.wantErr {
+				require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, serviceAccount, fetchedServiceAccount)
+		})
+	}
+}
+
+func TestRepositoryListServiceAccountsByOwnerUUID(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	serviceAccount1 := testkitinternal.MustCreateUserServiceAccount(t, activeUser.UUID, nil)
+	serviceAccount2 := testkitinternal.MustCreateUserServiceAccount(t, activeUser.UUID, func(sa *auth.ServiceAccount) {
+		sa.IsActive = false
+	})
+	testkitinternal.MustCreateUserServiceAccount(t, inactiveUser.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	serviceAccounts, err := repo.ListServiceAccountsByOwnerUUID(dbConn, activeUser.UUID)
+	require.NoError(t, err)
+	require.Equal(t, []*auth.ServiceAccount{serviceAccount1, serviceAccount2}, serviceAccounts)
+
+	serviceAccounts, err = repo.ListServiceAccountsByOwnerUUID(dbConn, inactiveUser.UUID)
+	require.NoError(t, err)
+	require.Empty(t, serviceAccounts)
+}
+
+func TestRepositoryUpdateServiceAccountSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, func(sa *auth.ServiceAccount) {
+		sa.Description = "Original description"
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	updatedName := "Renamed Service Account"
+	updatedServiceAccount, err := repo.UpdateServiceAccount(dbConn, serviceAccount.UUID, user.UUID, &updatedName, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, updatedName, updatedServiceAccount.Name)
+	require.Equal(t, "Original description", updatedServiceAccount.Description)
+	require.True(t, updatedServiceAccount.IsActive)
+
+	isActive := false
+	updatedDescription := ""
+	updatedServiceAccount, err = repo.UpdateServiceAccount(dbConn, serviceAccount.UUID, user.UUID, nil, &updatedDescription, &isActive)
+	require.NoError(t, err)
+	require.Equal(t, updatedName, updatedServiceAccount.Name)
+	require.Empty(t, updatedServiceAccount.Description)
+	require.False(t, updatedServiceAccount.IsActive)
+	require.Equal(t, serviceAccount.Scopes, updatedServiceAccount.Scopes)
+}
+
+func TestRepositoryUpdateServiceAccountError(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, activeUser.UUID, nil)
+	otherServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, activeUser.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := auth.NewRepository()
+
+	updatedName := "Renamed Service Account"
+
+	testcases := []struct {
+		name               string
+		serviceAccountUUID string
+		ownerUUID          string
+		updatedName        *string
+		wantErr            error
+	}{
+		{
+			name:               "No attributes to update",
+			serviceAccountUUID: serviceAccount.UUID,
+			ownerUUID:          activeUser.UUID,
+			updatedName:        nil,
+			wantErr:            errutils.ErrDatabaseNoRowsAffected,
+		},
+		{
+			name:               "Service account of another user",
+			serviceAccountUUID: serviceAccount.UUID,
+			ownerUUID:          otherUser.UUID,
+			updatedName:        &updatedName,
+			wantErr:            errutils.ErrDatabaseNoRowsAffected,
+		},
+		{
+			name:               "Duplicate name",
+			serviceAccountUUID: serviceAccount.UUID,
+			ownerUUID:          activeUser.UUID,
+			updatedName:        &otherServiceAccount.Name,
+			wantErr:            errutils.ErrDatabaseUniqueViolation,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			_, err := repo.UpdateServiceAccount(dbConn, testcase.serviceAccountUUID, testcase.ownerUUID, testcase.updatedName, nil, nil)
+			require.ErrorIs(t, err, testcase.wantErr)
+		})
+	}
+}
+
+func TestRepositoryDeleteServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, nil)
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.ServiceAccountUUID = pgtype.Text{
+			String: serviceAccount.UUID,
+			Valid:  true,
+		}
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	err := repo.DeleteServiceAccount(dbConn, serviceAccount.UUID, otherUser.UUID)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	err = repo.DeleteServiceAccount(dbConn, serviceAccount.UUID, user.UUID)
+	require.NoError(t, err)
+
+	err = repo.DeleteServiceAccount(dbConn, serviceAccount.UUID, user.UUID)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	_, err = repo.GetActiveAPIKeyByDigest(dbConn, apiKey.KeyDigest)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+}
+
+func TestRepositoryGetActiveAPIKeyByDigestServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	activeServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, activeUser.UUID, nil)
+	inactiveServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, activeUser.UUID, func(sa *auth.ServiceAccount) {
+		sa.IsActive = false
+	})
+	inactiveUserServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, inactiveUser.UUID, nil)
+
+	activeServiceAccountAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.ServiceAccountUUID = pgtype.Text{
+			String: activeServiceAccount.UUID,
+			Valid:  true,
+		}
+	})
+	inactiveServiceAccountAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.ServiceAccountUUID = pgtype.Text{
+			String: inactiveServiceAccount.UUID,
+			Valid:  true,
+		}
+	})
+	inactiveUserServiceAccountAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, func(k *auth.APIKey) {
+		k.ServiceAccountUUID = pgtype.Text{
+			String: inactiveUserServiceAccount.UUID,
+			Valid:  true,
+		}
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := auth.NewRepository()
+
+	testcases := []struct {
+		name      string
+		apiKey    *auth.APIKey
+		wantFound bool
+	}{
+		{
+			name:      "API key of active service account",
+			apiKey:    activeServiceAccountAPIKey,
+			wantFound: true,
+		},
+		{
+			name:      "API key of inactive service account",
+			apiKey:    inactiveServiceAccountAPIKey,
+			wantFound: false,
+		},
+		{
+			name:      "API key of active service account owned by inactive user",
+			apiKey:    inactiveUserServiceAccountAPIKey,
+			wantFound: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+
+			apiKey, err := repo.GetActiveAPIKeyByDigest(dbConn, testcase.apiKey.KeyDigest)
+			if !testcase.wantFound {
+				require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, testcase.apiKey.ID, apiKey.ID)
+				require.Equal(t, testcase.apiKey.ServiceAccountUUID, apiKey.ServiceAccountUUID)
+			}
+
+			apiKeys, err := repo.ListActiveAPIKeysByPrefix(dbConn, testcase.apiKey.Prefix)
+			require.NoError(t, err)
+			if testcase.wantFound {
+				require.Len(t, apiKeys, 1)
+			} else {
+				require.Empty(t, apiKeys)
+			}
+		})
+	}
+}
+
+func TestRepositoryCreateIssuedJWTSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	now := time.Now().UTC()
+	issuedJWT := &auth.IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(auth.JWTTypeActivation),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+
+	createdIssuedJWT, err := repo.CreateIssuedJWT(dbConn, issuedJWT)
+	require.NoError(t, err)
+
+	require.Equal(t, issuedJWT.JTI, createdIssuedJWT.JTI)
+	require.Equal(t, issuedJWT.UserUUID, createdIssuedJWT.UserUUID)
+	require.Equal(t, issuedJWT.TokenType, createdIssuedJWT.TokenType)
+	testkit.RequireTimeAlmostEqual(t, issuedJWT.CreatedAt, createdIssuedJWT.CreatedAt)
+	testkit.RequireTimeAlmostEqual(t, issuedJWT.ExpiresAt, createdIssuedJWT.ExpiresAt)
+	require.False(t, createdIssuedJWT.ConsumedAt.Valid)
+
+	_, err = repo.CreateIssuedJWT(dbConn, issuedJWT)
+	require.ErrorIs(t, err, errutils.ErrDatabaseUniqueViolation)
+}
+
+func TestRepositoryConsumeIssuedJWTSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	now := time.Now().UTC()
+	issuedJWT, err := repo.CreateIssuedJWT(dbConn, &auth.IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(auth.JWTTypePasswordReset),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	err = repo.ConsumeIssuedJWT(dbConn, issuedJWT.JTI, user.UUID, string(auth.JWTTypePasswordReset))
+	require.NoError(t, err)
+
+	err = repo.ConsumeIssuedJWT(dbConn, issuedJWT.JTI, user.UUID, string(auth.JWTTypePasswordReset))
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+}
+
+func TestRepositoryConsumeIssuedJWTError(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	now := time.Now().UTC()
+	validJWT, err := repo.CreateIssuedJWT(dbConn, &auth.IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(auth.JWTTypeActivation),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	expiredJWT, err := repo.CreateIssuedJWT(dbConn, &auth.IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(auth.JWTTypeActivation),
+		CreatedAt: now.Add(-2 * time.Hour),
+		ExpiresAt: now.Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name      string
+		jti       string
+		userUUID  string
+		tokenType auth.JWTType
+	}{
+		{
+			name:      "Non-existent JTI",
+			jti:       uuid.NewString(),
+			userUUID:  user.UUID,
+			tokenType: auth.JWTTypeActivation,
+		},
+		{
+			name:      "Expired JTI",
+			jti:       expiredJWT.JTI,
+			userUUID:  user.UUID,
+			tokenType: auth.JWTTypeActivation,
+		},
+		{
+			name:      "Incorrect user UUID",
+			jti:       validJWT.JTI,
+			userUUID:  uuid.NewString(),
+			tokenType: auth.JWTTypeActivation,
+		},
+		{
+			name:      "Incorrect token type",
+			jti:       validJWT.JTI,
+			userUUID:  user.UUID,
+			tokenType: auth.JWTTypePasswordReset,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			err := repo.ConsumeIssuedJWT(dbConn, testcase.jti, testcase.userUUID, string(testcase.tokenType))
+			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+		})
+	}
+}
+
+func TestRepositoryRecordIssuedJWTAttempt(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	now := time.Now().UTC()
+	issuedJWT, err := repo.CreateIssuedJWT(dbConn, &auth.IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(auth.JWTTypeTOTPChallenge),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, issuedJWT.AttemptCount)
+
+	for i := 1; i <= 3; i++ {
+		attemptCount, err := repo.RecordIssuedJWTAttempt(dbConn, issuedJWT.JTI, user.UUID, string(auth.JWTTypeTOTPChallenge), 3)
+		require.NoError(t, err)
+		require.Equal(t, i, attemptCount)
+	}
+
+	_, err = repo.RecordIssuedJWTAttempt(dbConn, issuedJWT.JTI, user.UUID, string(auth.JWTTypeTOTPChallenge), 3)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	consumedJWT, err := repo.CreateIssuedJWT(dbConn, &auth.IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(auth.JWTTypeTOTPChallenge),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	err = repo.ConsumeIssuedJWT(dbConn, consumedJWT.JTI, user.UUID, string(auth.JWTTypeTOTPChallenge))
+	require.NoError(t, err)
+
+	_, err = repo.RecordIssuedJWTAttempt(dbConn, consumedJWT.JTI, user.UUID, string(auth.JWTTypeTOTPChallenge), 3)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+}
+
+func TestRepositoryCountIssuedJWTs(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	now := time.Now().UTC()
+	issuedJWTs := []*auth.IssuedJWT{
+		{
+			JTI:       uuid.NewString(),
+			UserUUID:  user.UUID,
+			TokenType: string(auth.JWTTypeMagicLink),
+			CreatedAt: now.Add(-time.Hour),
+			ExpiresAt: now,
+		},
+		{
+			JTI:       uuid.NewString(),
+			UserUUID:  user.UUID,
+			TokenType: string(auth.JWTTypeMagicLink),
+			CreatedAt: now.Add(-time.Minute),
+			ExpiresAt: now.Add(time.Hour),
+		},
+		{
+			JTI:       uuid.NewString(),
+			UserUUID:  user.UUID,
+			TokenType: string(auth.JWTTypeMagicLink),
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Hour),
+		},
+		{
+			JTI:       uuid.NewString(),
+			UserUUID:  user.UUID,
+			TokenType: string(auth.JWTTypePasswordReset),
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Hour),
+		},
+	}
+
+	for _, issuedJWT := range issuedJWTs {
+		_, err := repo.CreateIssuedJWT(dbConn, issuedJWT)
+		require.NoError(t, err)
+	}
+
+	testcases := []struct {
+		name      string
+		userUUID  string
+		tokenType string
+		since     time.Time
+		wantCount int
+	}{
+		{
+			name:      "All magic link JWTs",
+			userUUID:  user.UUID,
+			tokenType: string(auth.JWTTypeMagicLink),
+			since:     now.Add(-2 * time.Hour),
+			wantCount: 3,
+		},
+		{
+			name:      "Recent magic link JWTs",
+			userUUID:  user.UUID,
+			tokenType: string(auth.JWTTypeMagicLink),
+			since:     now.Add(-30 * time.Minute),
+			wantCount: 2,
+		},
+		{
+			name:      "Password reset JWTs",
+			userUUID:  user.UUID,
+			tokenType: string(auth.JWTTypePasswordReset),
+			since:     now.Add(-2 * time.Hour),
+			wantCount: 1,
+		},
+		{
+			name:      "Different user",
+			userUUID:  uuid.NewString(),
+			tokenType: string(auth.JWTTypeMagicLink),
+			since:     now.Add(-2 * time.Hour),
+			wantCount: 0,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			count, err := repo.CountIssuedJWTs(dbConn, testcase.userUUID, testcase.tokenType, testcase.since)
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantCount, count)
+		})
+	}
+}
+
+func TestRepositoryDeleteExpiredIssuedJWTs(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	now := time.Now().UTC()
+	validJWT, err := repo.CreateIssuedJWT(dbConn, &auth.IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(auth.JWTTypeEmailChange),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	expiredJWT, err := repo.CreateIssuedJWT(dbConn, &auth.IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(auth.JWTTypeEmailChange),
+		CreatedAt: now.Add(-2 * time.Hour),
+		ExpiresAt: now.Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	count, err := repo.DeleteExpiredIssuedJWTs(dbConn)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	_, err = repo.CreateIssuedJWT(dbConn, expiredJWT)
+	require.NoError(t, err)
+
+	_, err = repo.CreateIssuedJWT(dbConn, validJWT)
+	require.ErrorIs(t, err, errutils.ErrDatabaseUniqueViolation)
+}
+
+func TestRepositoryCreateSessionSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	session := &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		IPAddress:  "198.51.100.7",
+		UserAgent:  "Mozilla/5.0 (X11; Linux x86_64)",
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	}
+
+	createdSession, err := repo.CreateSession(dbConn, session)
+	require.NoError(t, err)
+
+	require.Equal(t, session.ID, createdSession.ID)
+	require.Equal(t, session.UserUUID, createdSession.UserUUID)
+	require.Equal(t, session.RefreshJTI, createdSession.RefreshJTI)
+	require.Equal(t, session.IPAddress, createdSession.IPAddress)
+	require.Equal(t, session.UserAgent, createdSession.UserAgent)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), createdSession.CreatedAt)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), createdSession.LastUsedAt)
+	testkit.RequireTimeAlmostEqual(t, session.ExpiresAt, createdSession.ExpiresAt)
+	require.False(t, createdSession.RevokedAt.Valid)
+
+	_, err = repo.CreateSession(dbConn, session)
+	require.ErrorIs(t, err, errutils.ErrDatabaseUniqueViolation)
+}
+
+func TestRepositoryGetActiveSessionSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	session, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	fetchedSession, err := repo.GetActiveSession(dbConn, session.ID, user.UUID)
+	require.NoError(t, err)
+
+	require.Equal(t, session.ID, fetchedSession.ID)
+	require.Equal(t, session.UserUUID, fetchedSession.UserUUID)
+	require.Equal(t, session.RefreshJTI, fetchedSession.RefreshJTI)
+	require.Equal(t, session.IPAddress, fetchedSession.IPAddress)
+	require.Equal(t, session.UserAgent, fetchedSession.UserAgent)
+	testkit.RequireTimeAlmostEqual(t, session.CreatedAt, fetchedSession.CreatedAt)
+	testkit.RequireTimeAlmostEqual(t, session.LastUsedAt, fetchedSession.LastUsedAt)
+	testkit.RequireTimeAlmostEqual(t, session.ExpiresAt, fetchedSession.ExpiresAt)
+	require.False(t, fetchedSession.RevokedAt.Valid)
+}
+
+func TestRepositoryGetActiveSessionError(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	validSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	expiredSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	revokedSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	err = repo.RevokeSession(dbConn, revokedSession.ID, user.UUID)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name      string
+		sessionID string
+		userUUID  string
+	}{
+		{
+			name:      "Non-existent session",
+			sessionID: uuid.NewString(),
+			userUUID:  user.UUID,
+		},
+		{
+			name:      "Expired session",
+			sessionID: expiredSession.ID,
+			userUUID:  user.UUID,
+		},
+		{
+			name:      "Revoked session",
+			sessionID: revokedSession.ID,
+			userUUID:  user.UUID,
+		},
+		{
+			name:      "Incorrect user UUID",
+			sessionID: validSession.ID,
+			userUUID:  uuid.NewString(),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			_, err := repo.GetActiveSession(dbConn, testcase.sessionID, testcase.userUUID)
+			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+		})
+	}
+}
+
+func TestRepositoryRotateSession(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	session, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	newRefreshJTI := uuid.NewString()
+	newExpiresAt := time.Now().UTC().Add(2 * time.Hour)
+	err = repo.RotateSession(dbConn, session.ID, user.UUID, session.RefreshJTI, newRefreshJTI, "192.0.2.1", "curl/8.5.0", newExpiresAt)
+	require.NoError(t, err)
+
+	rotatedSession, err := repo.GetActiveSession(dbConn, session.ID, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, newRefreshJTI, rotatedSession.RefreshJTI)
+	require.Equal(t, "192.0.2.1", rotatedSession.IPAddress)
+	require.Equal(t, "curl/8.5.0", rotatedSession.UserAgent)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), rotatedSession.LastUsedAt)
+	testkit.RequireTimeAlmostEqual(t, newExpiresAt, rotatedSession.ExpiresAt)
+
+	err = repo.RotateSession(dbConn, session.ID, user.UUID, session.RefreshJTI, uuid.NewString(), "192.0.2.1", "curl/8.5.0", newExpiresAt)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	err = repo.RotateSession(dbConn, session.ID, uuid.NewString(), newRefreshJTI, uuid.NewString(), "192.0.2.1", "curl/8.5.0", newExpiresAt)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	err = repo.RevokeSession(dbConn, session.ID, user.UUID)
+	require.NoError(t, err)
+
+	err = repo.RotateSession(dbConn, session.ID, user.UUID, newRefreshJTI, uuid.NewString(), "192.0.2.1", "curl/8.5.0", newExpiresAt)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+}
+
+func TestRepositoryRevokeSession(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	session, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	err = repo.RevokeSession(dbConn, session.ID, uuid.NewString())
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	err = repo.RevokeSession(dbConn, session.ID, user.UUID)
+	require.NoError(t, err)
+
+	_, err = repo.GetActiveSession(dbConn, session.ID, user.UUID)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+
+	err = repo.RevokeSession(dbConn, session.ID, user.UUID)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+}
+
+func TestRepositoryRevokeUserSessions(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+	otherUser, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	sessions := make([]*auth.Session, 0, 2)
+	for range 2 {
+		session, err := repo.CreateSession(dbConn, &auth.Session{
+			ID:         uuid.NewString(),
+			UserUUID:   user.UUID,
+			RefreshJTI: uuid.NewString(),
+			ExpiresAt:  time.Now().UTC().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		sessions = append(sessions, session)
+	}
+
+	otherUserSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   otherUser.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	err = repo.RevokeUserSessions(dbConn, user.UUID)
+	require.NoError(t, err)
+
+	for _, session := range sessions {
+		_, err = repo.GetActiveSession(dbConn, session.ID, user.UUID)
+		require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+	}
+
+	_, err = repo.GetActiveSession(dbConn, otherUserSession.ID, otherUser.UUID)
+	require.NoError(t, err)
+}
+
+func TestRepositoryRevokeOtherUserSessions(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	currentSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	otherSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	err = repo.RevokeOtherUserSessions(dbConn, user.UUID, currentSession.ID)
+	require.NoError(t, err)
+
+	_, err = repo.GetActiveSession(dbConn, currentSession.ID, user.UUID)
+	require.NoError(t, err)
+
+	_, err = repo.GetActiveSession(dbConn, otherSession.ID, user.UUID)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+}
+
+func TestRepositoryDeleteExpiredSessions(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	validSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	expiredSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	count, err := repo.DeleteExpiredSessions(dbConn)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	_, err = repo.CreateSession(dbConn, expiredSession)
+	require.NoError(t, err)
+
+	_, err = repo.CreateSession(dbConn, validSession)
+	require.ErrorIs(t, err, errutils.ErrDatabaseUniqueViolation)
+}
+
+func TestRepositoryListActiveSessionsByUserUUID(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+	otherUser, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	olderSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		IPAddress:  "198.51.100.7",
+		UserAgent:  "Mozilla/5.0 (X11; Linux x86_64)",
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	newerSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		IPAddress:  "203.0.113.42",
+		UserAgent:  "curl/8.5.0",
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	revokedSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	err = repo.RevokeSession(dbConn, revokedSession.ID, user.UUID)
+	require.NoError(t, err)
+
+	_, err = repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   otherUser.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	sessions, err := repo.ListActiveSessionsByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	require.Equal(t, newerSession.ID, sessions[0].ID)
+	require.Equal(t, newerSession.IPAddress, sessions[0].IPAddress)
+	require.Equal(t, newerSession.UserAgent, sessions[0].UserAgent)
+	require.Equal(t, olderSession.ID, sessions[1].ID)
+	require.Equal(t, olderSession.IPAddress, sessions[1].IPAddress)
+	require.Equal(t, olderSession.UserAgent, sessions[1].UserAgent)
+
+	sessions, err = repo.ListActiveSessionsByUserUUID(dbConn, uuid.NewString())
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestRepositoryTouchSession(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	session, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	err = repo.TouchSession(dbConn, session.ID, user.UUID)
+	require.NoError(t, err)
+
+	touchedSession, err := repo.GetActiveSession(dbConn, session.ID, user.UUID)
+	require.NoError(t, err)
+	require.False(t, touchedSession.LastUsedAt.Before(session.LastUsedAt))
+
+	err = repo.TouchSession(dbConn, session.ID, uuid.NewString())
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	err = repo.RevokeSession(dbConn, session.ID, user.UUID)
+	require.NoError(t, err)
+
+	err = repo.TouchSession(dbConn, session.ID, user.UUID)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+}
+
+func TestRepositoryUpsertTOTPDevice(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	device, err := repo.UpsertTOTPDevice(dbConn, user.UUID, "JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	require.Equal(t, user.UUID, device.UserUUID)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", device.Secret)
+	require.False(t, device.ConfirmedAt.Valid)
+	require.Equal(t, int64(0), device.LastUsedStep)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), device.CreatedAt)
+
+	replacedDevice, err := repo.UpsertTOTPDevice(dbConn, user.UUID, "KRSXG5CTMVRXEZLU")
+	require.NoError(t, err)
+	require.Equal(t, "KRSXG5CTMVRXEZLU", replacedDevice.Secret)
+
+	fetchedDevice, err := repo.GetTOTPDevice(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, "KRSXG5CTMVRXEZLU", fetchedDevice.Secret)
+	require.False(t, fetchedDevice.ConfirmedAt.Valid)
+
+	err = repo.ConfirmTOTPDevice(dbConn, user.UUID, 1)
+	require.NoError(t, err)
+
+	_, err = repo.UpsertTOTPDevice(dbConn, user.UUID, "JBSWY3DPEHPK3PXP")
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+
+	fetchedDevice, err = repo.GetTOTPDevice(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, "KRSXG5CTMVRXEZLU", fetchedDevice.Secret)
+	require.True(t, fetchedDevice.ConfirmedAt.Valid)
+}
+
+func TestRepositoryGetTOTPDeviceError(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	_, err := repo.GetTOTPDevice(dbConn, uuid.NewString())
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+}
+
+func TestRepositoryConfirmTOTPDevice(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	err := repo.ConfirmTOTPDevice(dbConn, user.UUID, 42)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	_, err = repo.UpsertTOTPDevice(dbConn, user.UUID, "JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+
+	err = repo.ConfirmTOTPDevice(dbConn, user.UUID, 42)
+	require.NoError(t, err)
+
+	device, err := repo.GetTOTPDevice(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.True(t, device.ConfirmedAt.Valid)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), device.ConfirmedAt.Time)
+	require.Equal(t, int64(42), device.LastUsedStep)
+
+	err = repo.ConfirmTOTPDevice(dbConn, user.UUID, 43)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+}
+
+func TestRepositoryUseTOTPDeviceStep(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	_, err := repo.UpsertTOTPDevice(dbConn, user.UUID, "JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+
+	err = repo.UseTOTPDeviceStep(dbConn, user.UUID, 42)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	err = repo.ConfirmTOTPDevice(dbConn, user.UUID, 42)
+	require.NoError(t, err)
+
+	err = repo.UseTOTPDeviceStep(dbConn, user.UUID, 42)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	err = repo.UseTOTPDeviceStep(dbConn, user.UUID, 41)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	err = repo.UseTOTPDeviceStep(dbConn, user.UUID, 43)
+	require.NoError(t, err)
+
+	device, err := repo.GetTOTPDevice(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, int64(43), device.LastUsedStep)
+}
+
+func TestRepositoryDeleteTOTPDevice(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	_, err := repo.UpsertTOTPDevice(dbConn, user.UUID, "JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+
+	err = repo.DeleteTOTPDevice(dbConn, user.UUID)
+	require.NoError(t, err)
+
+	_, err = repo.GetTOTPDevice(dbConn, user.UUID)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+
+	err = repo.DeleteTOTPDevice(dbConn, user.UUID)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+}
+
+func TestRepositoryRecoveryCodes(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+	otherUser, _ := testkitinternal.MustCreateUser(t, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	hashedCodes := []string{
+		testkit.MustGenerateRandomString(64, false, true, true),
+		testkit.MustGenerateRandomString(64, false, true, true),
+	}
+
+	err := repo.CreateRecoveryCodes(dbConn, user.UUID, hashedCodes)
+	require.NoError(t, err)
+
+	err = repo.CreateRecoveryCodes(dbConn, user.UUID, hashedCodes[:1])
+	require.ErrorIs(t, err, errutils.ErrDatabaseUniqueViolation)
+
+	err = repo.UseRecoveryCode(dbConn, otherUser.UUID, hashedCodes[0])
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	err = repo.UseRecoveryCode(dbConn, user.UUID, hashedCodes[0])
+	require.NoError(t, err)
+
+	err = repo.UseRecoveryCode(dbConn, user.UUID, hashedCodes[0])
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	err = repo.DeleteRecoveryCodes(dbConn, user.UUID)
+	require.NoError(t, err)
+
+	err = repo.UseRecoveryCode(dbConn, user.UUID, hashedCodes[1])
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+}
+
+func TestRepositoryCreateOIDCAuthRequest(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	authRequest := &auth.OIDCAuthRequest{
+		State:        testkit.MustGenerateRandomString(32, true, true, true),
+		Nonce:        testkit.MustGenerateRandomString(32, true, true, true),
+		CodeVerifier: testkit.MustGenerateRandomString(43, true, true, true),
+		ExpiresAt:    time.Now().UTC().Add(time.Minute),
+	}
+
+	createdAuthRequest, err := repo.CreateOIDCAuthRequest(dbConn, authRequest)
+	require.NoError(t, err)
+	require.Equal(t, authRequest.State, createdAuthRequest.State)
+	require.Equal(t, authRequest.Nonce, createdAuthRequest.Nonce)
+	require.Equal(t, authRequest.CodeVerifier, createdAuthRequest.CodeVerifier)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), createdAuthRequest.CreatedAt)
+	testkit.RequireTimeAlmostEqual(t, authRequest.ExpiresAt, createdAuthRequest.ExpiresAt)
+
+	_, err = repo.CreateOIDCAuthRequest(dbConn, authRequest)
+	require.ErrorIs(t, err, errutils.ErrDatabaseUniqueViolation)
+}
+
+func TestRepositoryConsumeOIDCAuthRequest(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	authRequest, err := repo.CreateOIDCAuthRequest(dbConn, &auth.OIDCAuthRequest{
+		State:        testkit.MustGenerateRandomString(32, true, true, true),
+		Nonce:        testkit.MustGenerateRandomString(32, true, true, true),
+		CodeVerifier: testkit.MustGenerateRandomString(43, true, true, true),
+		ExpiresAt:    time.Now().UTC().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	expiredAuthRequest, err := repo.CreateOIDCAuthRequest(dbConn, &auth.OIDCAuthRequest{
+		State:        testkit.MustGenerateRandomString(32, true, true, true),
+		Nonce:        testkit.MustGenerateRandomString(32, true, true, true),
+		CodeVerifier: testkit.MustGenerateRandomString(43, true, true, true),
+		ExpiresAt:    time.Now().UTC().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	consumedAuthRequest, err := repo.ConsumeOIDCAuthRequest(dbConn, authRequest.State)
+	require.NoError(t, err)
+	require.Equal(t, authRequest.Nonce, consumedAuthRequest.Nonce)
+	require.Equal(t, authRequest.CodeVerifier, consumedAuthRequest.CodeVerifier)
+
+	_, err = repo.ConsumeOIDCAuthRequest(dbConn, authRequest.State)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+
+	_, err = repo.ConsumeOIDCAuthRequest(dbConn, expiredAuthRequest.State)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+}
+
+func TestRepositoryDeleteExpiredOIDCAuthRequests(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	authRequest, err := repo.CreateOIDCAuthRequest(dbConn, &auth.OIDCAuthRequest{
+		State:        testkit.MustGenerateRandomString(32, true, true, true),
+		Nonce:        testkit.MustGenerateRandomString(32, true, true, true),
+		CodeVerifier: testkit.MustGenerateRandomString(43, true, true, true),
+		ExpiresAt:    time.Now().UTC().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = repo.CreateOIDCAuthRequest(dbConn, &auth.OIDCAuthRequest{
+		State:        testkit.MustGenerateRandomString(32, true, true, true),
+		Nonce:        testkit.MustGenerateRandomString(32, true, true, true),
+		CodeVerifier: testkit.MustGenerateRandomString(43, true, true, true),
+		ExpiresAt:    time.Now().UTC().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	count, err := repo.DeleteExpiredOIDCAuthRequests(dbConn)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	_, err = repo.ConsumeOIDCAuthRequest(dbConn, authRequest.State)
+	require.NoError(t, err)
+}
+
+func TestRepositoryRecordLoginFailure(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	identifier := uuid.NewString()
+	now := time.Now().UTC()
+
+	throttle, err := repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeAccount, identifier, now.Add(-2*time.Hour), now.Add(-3*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, string(auth.LoginThrottleScopeAccount), throttle.Scope)
+	require.Equal(t, identifier, throttle.Identifier)
+	require.Equal(t, 1, throttle.FailureCount)
+	require.False(t, throttle.LockedUntil.Valid)
+
+	throttle, err = repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeAccount, identifier, now.Add(-time.Hour), now.Add(-3*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, throttle.FailureCount)
+	testkit.RequireTimeAlmostEqual(t, now.Add(-time.Hour), throttle.LastFailedAt)
+
+	throttle, err = repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeAccount, identifier, now, now.Add(-30*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, throttle.FailureCount)
+	testkit.RequireTimeAlmostEqual(t, now, throttle.LastFailedAt)
+
+	throttle, err = repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeIP, identifier, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, string(auth.LoginThrottleScopeIP), throttle.Scope)
+	require.Equal(t, 1, throttle.FailureCount)
+}
+
+func TestRepositoryLockLoginThrottle(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	identifier := uuid.NewString()
+	now := time.Now().UTC()
+
+	_, err := repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, identifier)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+
+	err = repo.LockLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, identifier, now.Add(time.Minute))
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	_, err = repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeAccount, identifier, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+
+	err = repo.LockLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, identifier, now.Add(time.Minute))
+	require.NoError(t, err)
+
+	throttle, err := repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, identifier)
+	require.NoError(t, err)
+	require.Equal(t, 1, throttle.FailureCount)
+	require.True(t, throttle.LockedUntil.Valid)
+	testkit.RequireTimeAlmostEqual(t, now.Add(time.Minute), throttle.LockedUntil.Time)
+
+	_, err = repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeIP, identifier)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+}
+
+func TestRepositoryDeleteLoginThrottle(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	identifier := uuid.NewString()
+	now := time.Now().UTC()
+
+	_, err := repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeAccount, identifier, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+
+	_, err = repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeIP, identifier, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+
+	err = repo.DeleteLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, identifier)
+	require.NoError(t, err)
+
+	_, err = repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, identifier)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+
+	_, err = repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeIP, identifier)
+	require.NoError(t, err)
+
+	err = repo.DeleteLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, identifier)
+	require.NoError(t, err)
+}
+
+func TestRepositoryDeleteExpiredLoginThrottles(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	now := time.Now().UTC()
+	recentIdentifier := uuid.NewString()
+	expiredIdentifier := uuid.NewString()
+	lockedIdentifier := uuid.NewString()
+
+	_, err := repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeAccount, recentIdentifier, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+
+	_, err = repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeAccount, expiredIdentifier, now.Add(-48*time.Hour), now.Add(-72*time.Hour))
+	require.NoError(t, err)
+
+	_, err = repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeAccount, lockedIdentifier, now.Add(-48*time.Hour), now.Add(-72*time.Hour))
+	require.NoError(t, err)
+
+	err = repo.LockLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, lockedIdentifier, now.Add(time.Hour))
+	require.NoError(t, err)
+
+	count, err := repo.DeleteExpiredLoginThrottles(dbConn, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	_, err = repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, recentIdentifier)
+	require.NoError(t, err)
+
+	_, err = repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, expiredIdentifier)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+
+	_, err = repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, lockedIdentifier)
+	require.NoError(t, err)
+}
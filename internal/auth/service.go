@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"sync"
@@ -9,9 +10,17 @@ import (
 
 	"github.com/alvii147/flagger-api/internal/env"
 	"github.com/alvii147/flagger-api/internal/templatesmanager"
+	"github.com/alvii147/flagger-api/pkg/api"
 	"github.com/alvii147/flagger-api/pkg/errutils"
+	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/alvii147/flagger-api/pkg/jwks"
 	"github.com/alvii147/flagger-api/pkg/logging"
 	"github.com/alvii147/flagger-api/pkg/mailclient"
+	"github.com/alvii147/flagger-api/pkg/oidc"
+	"github.com/alvii147/flagger-api/pkg/passhash"
+	"github.com/alvii147/flagger-api/pkg/totp"
+	"github.com/alvii147/flagger-api/pkg/utils"
+	"github.com/alvii147/flagger-api/pkg/validate"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -22,42 +31,113 @@ import (
 type Service interface {
 	CreateUser(ctx context.Context, wg *sync.WaitGroup, email string, password string, firstName string, lastName string) (*User, error)
 	ActivateUser(ctx context.Context, token string) error
+	ResendActivation(ctx context.Context, wg *sync.WaitGroup, email string) error
 	GetCurrentUser(ctx context.Context) (*User, error)
 	UpdateUser(ctx context.Context, firstName *string, lastName *string) (*User, error)
-	CreateJWT(ctx context.Context, email string, password string) (string, string, error)
-	RefreshJWT(ctx context.Context, token string) (string, error)
-	CreateAPIKey(ctx context.Context, name string, expiresAt pgtype.Timestamp) (*APIKey, string, error)
+	RequestPasswordReset(ctx context.Context, wg *sync.WaitGroup, email string) error
+	ResetPassword(ctx context.Context, token string, password string) error
+	ChangePassword(ctx context.Context, currentPassword string, newPassword string) error
+	RequestEmailChange(ctx context.Context, wg *sync.WaitGroup, email string, password string) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+	CreateJWT(ctx context.Context, wg *sync.WaitGroup, email string, password string, ipAddress string, userAgent string) (string, string, string, error)
+	VerifyTOTPChallenge(ctx context.Context, challengeToken string, code string, ipAddress string, userAgent string) (string, string, error)
+	RequestMagicLink(ctx context.Context, wg *sync.WaitGroup, email string) error
+	VerifyMagicLink(ctx context.Context, token string, ipAddress string, userAgent string) (string, string, string, error)
+	CreateOIDCAuthURL(ctx context.Context) (string, error)
+	CreateJWTFromOIDC(ctx context.Context, state string, code string, ipAddress string, userAgent string) (string, string, string, error)
+	RefreshJWT(ctx context.Context, token string, ipAddress string, userAgent string) (string, string, error)
+	AuthenticateJWT(ctx context.Context, token string) (*api.AuthJWTClaims, error)
+	ListSessions(ctx context.Context) ([]*Session, error)
+	RevokeSession(ctx context.Context, sessionID string) error
+	Logout(ctx context.Context) error
+	LogoutAll(ctx context.Context) error
+	CreateAPIKey(ctx context.Context, name string, scopes []string, allowedCIDRs []string, allowedOrigins []string, serviceAccountUUID *string, expiresAt pgtype.Timestamp) (*APIKey, string, error)
 	ListAPIKeys(ctx context.Context) ([]*APIKey, error)
 	FindAPIKey(ctx context.Context, rawKey string) (*APIKey, error)
+	UpdateAPIKey(ctx context.Context, apiKeyID int, name *string, allowedCIDRs *[]string, allowedOrigins *[]string, expiresAt *pgtype.Timestamp) (*APIKey, error)
+	RotateAPIKey(ctx context.Context, apiKeyID int) (*APIKey, string, error)
+	RecordAPIKeyUsage(apiKeyID int, ipAddress string)
+	FlushAPIKeyUsage(ctx context.Context) (int64, error)
+	GetAPIKeyUsage(ctx context.Context, apiKeyID int, days int) ([]*APIKeyUsage, error)
 	DeleteAPIKey(ctx context.Context, apiKeyID int) error
+	CreateServiceAccount(ctx context.Context, name string, description string, scopes []string) (*ServiceAccount, error)
+	ListServiceAccounts(ctx context.Context) ([]*ServiceAccount, error)
+	UpdateServiceAccount(ctx context.Context, serviceAccountUUID string, name *string, description *string, isActive *bool) (*ServiceAccount, error)
+	DeleteServiceAccount(ctx context.Context, serviceAccountUUID string) error
+	GetUser(ctx context.Context, userUUID string) (*User, error)
+	ListUsers(ctx context.Context, search string) ([]*User, error)
+	UpdateUserStatus(ctx context.Context, userUUID string, isActive *bool, isSuperUser *bool) (*User, error)
+	ForcePasswordReset(ctx context.Context, wg *sync.WaitGroup, userUUID string) error
+	RevokeUserCredentials(ctx context.Context, userUUID string) error
+	ImpersonateUser(ctx context.Context, userUUID string, readOnly bool) (string, *api.AuthJWTClaims, error)
+	PurgeExpiredJWTs(ctx context.Context) (int64, error)
+	PurgeExpiredSessions(ctx context.Context) (int64, error)
+	PurgeExpiredOIDCAuthRequests(ctx context.Context) (int64, error)
+	PurgeExpiredLoginThrottles(ctx context.Context) (int64, error)
+	EnrollTOTP(ctx context.Context) (string, string, error)
+	ConfirmTOTP(ctx context.Context, code string, ipAddress string) ([]string, error)
+	DisableTOTP(ctx context.Context, password string, code string, ipAddress string) error
 }
 
 // service implements Service.
 type service struct {
-	config      *env.Config
-	dbPool      *pgxpool.Pool
-	logger      logging.Logger
-	mailClient  mailclient.Client
-	tmplManager templatesmanager.Manager
-	repository  Repository
+	config            *env.Config
+	keySet            *jwks.KeySet
+	dbPool            *pgxpool.Pool
+	logger            logging.Logger
+	mailClient        mailclient.Client
+	tmplManager       templatesmanager.Manager
+	repository        Repository
+	oidcClient        *oidc.Client
+	usageBuffer       *apiKeyUsageBuffer
+	passwordHasher    passhash.Hasher
+	passwordPolicy    *validate.PasswordPolicy
+	dummyPasswordHash func() (string, error)
 }
 
 // NewService returns a new service.
 func NewService(
 	config *env.Config,
+	keySet *jwks.KeySet,
 	dbPool *pgxpool.Pool,
 	logger logging.Logger,
 	mailClient mailclient.Client,
 	tmplManager templatesmanager.Manager,
 	repo Repository,
 ) *service {
+	// single sign-on is disabled unless an OpenID Connect provider is configured
+	var oidcClient *oidc.Client
+	if config.OIDCIssuerURL != "" {
+		oidcClient = oidc.NewClient(
+			config.OIDCIssuerURL,
+			config.OIDCClientID,
+			config.OIDCClientSecret,
+			config.OIDCRedirectURL,
+			httputils.NewHTTPClient(nil),
+		)
+	}
+
+	passwordHasher := NewPasswordHasher(config)
+
+	// hash of a random password, used to verify passwords of unknown Users
+	// so that logins take just as long whether or not the User exists
+	dummyPasswordHash := sync.OnceValues(func() (string, error) {
+		return passwordHasher.Hash(uuid.NewString())
+	})
+
 	return &service{
-		config:      config,
-		dbPool:      dbPool,
-		logger:      logger,
-		mailClient:  mailClient,
-		tmplManager: tmplManager,
-		repository:  repo,
+		config:            config,
+		keySet:            keySet,
+		dbPool:            dbPool,
+		logger:            logger,
+		mailClient:        mailClient,
+		tmplManager:       tmplManager,
+		repository:        repo,
+		oidcClient:        oidcClient,
+		usageBuffer:       newAPIKeyUsageBuffer(),
+		passwordHasher:    passwordHasher,
+		passwordPolicy:    NewPasswordPolicy(config),
+		dummyPasswordHash: dummyPasswordHash,
 	}
 }
 
@@ -70,11 +150,15 @@ func (svc *service) CreateUser(
 	firstName string,
 	lastName string,
 ) (*User, error) {
-	hashedPasswordBytes, err := hashPassword(password, svc.config.HashingCost)
+	err := svc.validatePassword("password", password, email, firstName, lastName)
+	if err != nil {
+		return nil, fmt.Errorf("CreateUser failed to svc.validatePassword: %w", err)
+	}
+
+	hashedPassword, err := svc.passwordHasher.Hash(password)
 	if err != nil {
-		return nil, fmt.Errorf("CreateUser failed to hashPassword: %w", err)
+		return nil, fmt.Errorf("CreateUser failed to svc.passwordHasher.Hash: %w", err)
 	}
-	hashedPassword := string(hashedPasswordBytes)
 
 	user := &User{
 		UUID:        uuid.NewString(),
@@ -103,17 +187,30 @@ func (svc *service) CreateUser(
 		return nil, err
 	}
 
+	activationToken, issuedJWT, err := createActivationJWT(
+		user.UUID,
+		svc.config.SecretKey,
+		time.Duration(svc.config.ActivationLifetime*int64(time.Minute)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("CreateUser failed to createActivationJWT: %w", err)
+	}
+
+	_, err = svc.repository.CreateIssuedJWT(dbConn, issuedJWT)
+	if err != nil {
+		return nil, fmt.Errorf("CreateUser failed to svc.repository.CreateIssuedJWT: %w", err)
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		err := sendActivationMail(
 			user,
+			activationToken,
 			svc.mailClient,
 			svc.tmplManager,
 			svc.config.FrontendBaseURL,
 			svc.config.FrontendActivationRoute,
-			svc.config.SecretKey,
-			time.Duration(svc.config.ActivationLifetime*int64(time.Minute)),
 		)
 		if err != nil {
 			svc.logger.LogError("CreateUser failed to sendActivationMail:", err)
@@ -124,6 +221,8 @@ func (svc *service) CreateUser(
 }
 
 // ActivateUser activates User from activation JWT.
+// Activation JWTs can only be used once,
+// and those issued before the last resent activation email are rejected.
 func (svc *service) ActivateUser(ctx context.Context, token string) error {
 	claims, ok := validateActivationJWT(token, svc.config.SecretKey)
 	if !ok {
@@ -136,7 +235,18 @@ func (svc *service) ActivateUser(ctx context.Context, token string) error {
 	}
 	defer dbConn.Release()
 
-	err = svc.repository.ActivateUserByUUID(dbConn, claims.Subject)
+	err = svc.repository.ConsumeIssuedJWT(dbConn, claims.JWTID, claims.Subject, string(JWTTypeActivation))
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("ActivateUser failed to svc.repository.ConsumeIssuedJWT, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("ActivateUser failed to svc.repository.ConsumeIssuedJWT: %w", err)
+		}
+		return err
+	}
+
+	err = svc.repository.ActivateUserByUUID(dbConn, claims.Subject, time.Time(claims.IssuedAt))
 	if err != nil {
 		switch {
 		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
@@ -150,6 +260,58 @@ func (svc *service) ActivateUser(ctx context.Context, token string) error {
 	return nil
 }
 
+// ResendActivation sends a new activation email to inactive User with given email.
+// If no inactive User is found, or an activation email was resent too recently,
+// no email is sent and no error is returned, so that the existence of accounts is not revealed.
+func (svc *service) ResendActivation(ctx context.Context, wg *sync.WaitGroup, email string) error {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("ResendActivation failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	now := time.Now().UTC()
+	user, err := svc.repository.RenewUserActivation(dbConn, email, now, now.Add(-activationResendCooldown))
+	if err != nil {
+		if errors.Is(err, errutils.ErrDatabaseNoRowsAffected) {
+			return nil
+		}
+		return fmt.Errorf("ResendActivation failed to svc.repository.RenewUserActivation: %w", err)
+	}
+
+	activationToken, issuedJWT, err := createActivationJWT(
+		user.UUID,
+		svc.config.SecretKey,
+		time.Duration(svc.config.ActivationLifetime*int64(time.Minute)),
+	)
+	if err != nil {
+		return fmt.Errorf("ResendActivation failed to createActivationJWT: %w", err)
+	}
+
+	_, err = svc.repository.CreateIssuedJWT(dbConn, issuedJWT)
+	if err != nil {
+		return fmt.Errorf("ResendActivation failed to svc.repository.CreateIssuedJWT: %w", err)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := sendActivationMail(
+			user,
+			activationToken,
+			svc.mailClient,
+			svc.tmplManager,
+			svc.config.FrontendBaseURL,
+			svc.config.FrontendActivationRoute,
+		)
+		if err != nil {
+			svc.logger.LogError("ResendActivation failed to sendActivationMail:", err)
+		}
+	}()
+
+	return nil
+}
+
 // GetCurrentUser gets currently authenticated User.
 func (svc *service) GetCurrentUser(ctx context.Context) (*User, error) {
 	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
@@ -204,202 +366,2392 @@ func (svc *service) UpdateUser(ctx context.Context, firstName *string, lastName
 	return user, nil
 }
 
-// CreateJWT authenticates User and creates new access and refresh JWTs.
-func (svc *service) CreateJWT(
-	ctx context.Context,
-	email string,
-	password string,
-) (string, string, error) {
+// RequestPasswordReset sends password reset email to User with given email.
+// If no active User is found, no email is sent and no error is returned,
+// so that the existence of accounts is not revealed.
+// No email is sent either if one was already sent to the User within passwordResetCooldown.
+func (svc *service) RequestPasswordReset(ctx context.Context, wg *sync.WaitGroup, email string) error {
 	dbConn, err := svc.dbPool.Acquire(ctx)
 	if err != nil {
-		return "", "", fmt.Errorf("CreateJWT failed to svc.dbPool.Acquire: %w", err)
+		return fmt.Errorf("RequestPasswordReset failed to svc.dbPool.Acquire: %w", err)
 	}
 	defer dbConn.Release()
 
-	dummyUser := &User{}
-	dummyUser.UUID = "93c58b3c-f087-4e97-805a-1e4676cdd5ec"
-	dummyUser.Password = "$2a$14$078K5mTZHgbMDQ.K/U656OEb7v5HIyB9cBPLoXiQREAoXmCmgsywW"
-	failAuth := false
-
 	user, err := svc.repository.GetUserByEmail(dbConn, email)
-	if err != nil && !errors.Is(err, errutils.ErrDatabaseNoRowsReturned) {
-		return "", "", fmt.Errorf("CreateJWT failed to svc.repository.GetActiveUserByEmail: %w", err)
+	if err != nil {
+		if errors.Is(err, errutils.ErrDatabaseNoRowsReturned) {
+			return nil
+		}
+		return fmt.Errorf("RequestPasswordReset failed to svc.repository.GetUserByEmail: %w", err)
 	}
 
-	if err != nil || user == nil || !user.IsActive {
-		failAuth = true
-		user = dummyUser
+	recentCount, err := svc.repository.CountIssuedJWTs(
+		dbConn,
+		user.UUID,
+		string(JWTTypePasswordReset),
+		time.Now().UTC().Add(-passwordResetCooldown),
+	)
+	if err != nil {
+		return fmt.Errorf("RequestPasswordReset failed to svc.repository.CountIssuedJWTs: %w", err)
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
-		failAuth = true
-		user = dummyUser
+	if recentCount > 0 {
+		return nil
 	}
 
-	accessToken, err := createAuthJWT(
-		user.UUID,
-		JWTTypeAccess,
-		svc.config.SecretKey,
-		time.Duration(svc.config.AuthAccessLifetime*int64(time.Minute)),
-		time.Duration(svc.config.AuthRefreshLifetime*int64(time.Minute)),
-	)
+	err = svc.issuePasswordReset(dbConn, wg, user)
 	if err != nil {
-		return "", "", fmt.Errorf("CreateJWT failed to createAuthJWT of type %s: %w", JWTTypeAccess, err)
+		return fmt.Errorf("RequestPasswordReset failed to svc.issuePasswordReset: %w", err)
 	}
 
-	refreshToken, err := createAuthJWT(
-		user.UUID,
-		JWTTypeRefresh,
+	return nil
+}
+
+// issuePasswordReset creates a password reset JWT for User and sends it to them in a password reset email.
+func (svc *service) issuePasswordReset(dbConn *pgxpool.Conn, wg *sync.WaitGroup, user *User) error {
+	passwordResetToken, issuedJWT, err := createPasswordResetJWT(
+		user,
 		svc.config.SecretKey,
-		time.Duration(svc.config.AuthAccessLifetime*int64(time.Minute)),
-		time.Duration(svc.config.AuthRefreshLifetime*int64(time.Minute)),
+		time.Duration(svc.config.PasswordResetLifetime*int64(time.Minute)),
 	)
 	if err != nil {
-		return "", "", fmt.Errorf("CreateJWT failed to createAuthJWT of type %s: %w", JWTTypeRefresh, err)
+		return fmt.Errorf("issuePasswordReset failed to createPasswordResetJWT: %w", err)
 	}
 
-	if failAuth {
-		return "", "", fmt.Errorf("CreateJWT failed: %w", errutils.ErrInvalidCredentials)
+	_, err = svc.repository.CreateIssuedJWT(dbConn, issuedJWT)
+	if err != nil {
+		return fmt.Errorf("issuePasswordReset failed to svc.repository.CreateIssuedJWT: %w", err)
 	}
 
-	return accessToken, refreshToken, nil
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := sendPasswordResetMail(
+			user,
+			passwordResetToken,
+			svc.mailClient,
+			svc.tmplManager,
+			svc.config.FrontendBaseURL,
+			svc.config.FrontendPasswordResetRoute,
+		)
+		if err != nil {
+			svc.logger.LogError("issuePasswordReset failed to sendPasswordResetMail:", err)
+		}
+	}()
+
+	return nil
 }
 
-// RefreshJWT validates refresh token and creates new access token.
-func (svc *service) RefreshJWT(ctx context.Context, token string) (string, error) {
-	claims, ok := validateAuthJWT(token, JWTTypeRefresh, svc.config.SecretKey)
+// ResetPassword sets new password for User from password reset JWT and revokes all of the User's sessions.
+// The JWT can only be used once, and is rejected if the User's password has changed since it was issued.
+func (svc *service) ResetPassword(ctx context.Context, token string, password string) error {
+	claims, ok := validatePasswordResetJWT(token, svc.config.SecretKey)
 	if !ok {
-		return "", fmt.Errorf("RefreshJWT failed to validateAuthJWT %s: %w", token, errutils.ErrInvalidToken)
+		return fmt.Errorf("ResetPassword failed to validatePasswordResetJWT %s: %w", token, errutils.ErrInvalidToken)
 	}
 
-	accessToken, err := createAuthJWT(
-		claims.Subject,
-		JWTTypeAccess,
-		svc.config.SecretKey,
-		time.Duration(svc.config.AuthAccessLifetime*int64(time.Minute)),
-		time.Duration(svc.config.AuthRefreshLifetime*int64(time.Minute)),
-	)
+	dbConn, err := svc.dbPool.Acquire(ctx)
 	if err != nil {
-		return "", fmt.Errorf("RefreshJWT failed to createAuthJWT of type %s: %w", JWTTypeAccess, err)
+		return fmt.Errorf("ResetPassword failed to svc.dbPool.Acquire: %w", err)
 	}
+	defer dbConn.Release()
 
-	return accessToken, nil
-}
+	user, err := svc.repository.GetUserByUUID(dbConn, claims.Subject)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("ResetPassword failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("ResetPassword failed to svc.repository.GetUserByUUID: %w", err)
+		}
+		return err
+	}
 
-// CreateAPIKey creates new API key for User.
-func (svc *service) CreateAPIKey(ctx context.Context, name string, expiresAt pgtype.Timestamp) (*APIKey, string, error) {
-	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
-	if !ok {
-		return nil, "", errors.New("CreateAPIKey failed to ctx.Value user UUID from ctx")
+	fingerprint := passwordFingerprint(user.PasswordChangedAt, svc.config.SecretKey)
+	if subtle.ConstantTimeCompare([]byte(claims.PasswordFingerprint), []byte(fingerprint)) == 0 {
+		return fmt.Errorf("ResetPassword failed, password changed since token was issued: %w", errutils.ErrInvalidToken)
 	}
 
-	prefix, rawKey, hashedKey, err := createAPIKey(svc.config.HashingCost)
+	err = svc.validatePassword("password", password, user.Email, user.FirstName, user.LastName)
 	if err != nil {
-		return nil, "", fmt.Errorf("CreateAPIKey failed to generateAPIKey: %w", err)
+		return fmt.Errorf("ResetPassword failed to svc.validatePassword: %w", err)
 	}
 
-	apiKey := &APIKey{
-		UserUUID:  userUUID,
-		Prefix:    prefix,
-		HashedKey: hashedKey,
-		Name:      name,
-		ExpiresAt: expiresAt,
+	hashedPassword, err := svc.passwordHasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("ResetPassword failed to svc.passwordHasher.Hash: %w", err)
 	}
 
-	dbConn, err := svc.dbPool.Acquire(ctx)
+	// the token is consumed, the password is updated and sessions are revoked in a single transaction,
+	// so that a failure part way through does not use up the token while leaving the old password in place
+	tx, err := dbConn.Begin(ctx)
 	if err != nil {
-		return nil, "", fmt.Errorf("CreateAPIKey failed to svc.dbPool.Acquire: %w", err)
+		return fmt.Errorf("ResetPassword failed to dbConn.Begin: %w", err)
 	}
-	defer dbConn.Release()
+	defer tx.Rollback(ctx)
 
-	apiKey, err = svc.repository.CreateAPIKey(dbConn, apiKey)
+	err = svc.repository.ConsumeIssuedJWT(tx, claims.JWTID, claims.Subject, string(JWTTypePasswordReset))
 	if err != nil {
 		switch {
-		case errors.Is(err, errutils.ErrDatabaseUniqueViolation):
-			err = fmt.Errorf("CreateAPIKey failed to svc.repository.CreateAPIKey, %w: %w", errutils.ErrAPIKeyAlreadyExists, err)
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("ResetPassword failed to svc.repository.ConsumeIssuedJWT, %w: %w", errutils.ErrInvalidToken, err)
 		default:
-			err = fmt.Errorf("CreateAPIKey failed to svc.repository.CreateAPIKey: %w", err)
+			err = fmt.Errorf("ResetPassword failed to svc.repository.ConsumeIssuedJWT: %w", err)
 		}
-		return nil, "", err
+		return err
 	}
 
-	return apiKey, rawKey, nil
+	err = svc.repository.UpdateUserPassword(tx, user.UUID, hashedPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("ResetPassword failed to svc.repository.UpdateUserPassword, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("ResetPassword failed to svc.repository.UpdateUserPassword: %w", err)
+		}
+		return err
+	}
+
+	err = svc.repository.RevokeUserSessions(tx, user.UUID)
+	if err != nil {
+		return fmt.Errorf("ResetPassword failed to svc.repository.RevokeUserSessions: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("ResetPassword failed to tx.Commit: %w", err)
+	}
+
+	err = svc.repository.DeleteLoginThrottle(dbConn, LoginThrottleScopeAccount, loginAccountIdentifier(user.Email))
+	if err != nil {
+		return fmt.Errorf("ResetPassword failed to svc.repository.DeleteLoginThrottle: %w", err)
+	}
+
+	return nil
 }
 
-// ListAPIKeys retrieves API keys for currently authenticated User.
-func (svc *service) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+// ChangePassword changes currently authenticated User's password after verifying their current password.
+// Changing the password revokes all of the User's other sessions.
+func (svc *service) ChangePassword(ctx context.Context, currentPassword string, newPassword string) error {
 	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
 	if !ok {
-		return nil, errors.New("ListAPIKeys failed to ctx.Value user UUID from ctx")
+		return errors.New("ChangePassword failed to ctx.Value user UUID from ctx")
 	}
 
 	dbConn, err := svc.dbPool.Acquire(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("ListAPIKeys failed to svc.dbPool.Acquire: %w", err)
+		return fmt.Errorf("ChangePassword failed to svc.dbPool.Acquire: %w", err)
 	}
 	defer dbConn.Release()
 
-	apiKeys, err := svc.repository.ListAPIKeysByUserUUID(dbConn, userUUID)
+	user, err := svc.repository.GetUserByUUID(dbConn, userUUID)
 	if err != nil {
-		return nil, fmt.Errorf("ListAPIKeys failed to svc.repository.ListAPIKeysByUserUUID: %w", err)
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("ChangePassword failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrUserNotFound, err)
+		default:
+			err = fmt.Errorf("ChangePassword failed to svc.repository.GetUserByUUID: %w", err)
+		}
+		return err
 	}
 
-	return apiKeys, nil
-}
+	err = svc.passwordHasher.Verify(user.Password, currentPassword)
+	if err != nil {
+		return fmt.Errorf("ChangePassword failed to svc.passwordHasher.Verify, %w: %w", errutils.ErrInvalidCredentials, err)
+	}
 
-// FindAPIKey parses and finds an API Key.
-func (svc *service) FindAPIKey(ctx context.Context, rawKey string) (*APIKey, error) {
-	prefix, _, ok := parseAPIKey(rawKey)
-	if !ok {
-		return nil, errors.New("FindAPIKey failed to parseAPIKey")
+	err = svc.validatePassword("new_password", newPassword, user.Email, user.FirstName, user.LastName)
+	if err != nil {
+		return fmt.Errorf("ChangePassword failed to svc.validatePassword: %w", err)
 	}
 
-	dbConn, err := svc.dbPool.Acquire(ctx)
+	hashedPassword, err := svc.passwordHasher.Hash(newPassword)
 	if err != nil {
-		return nil, fmt.Errorf("FindAPIKey failed to svc.dbPool.Acquire: %w", err)
+		return fmt.Errorf("ChangePassword failed to svc.passwordHasher.Hash: %w", err)
 	}
-	defer dbConn.Release()
 
-	apiKeys, err := svc.repository.ListActiveAPIKeysByPrefix(dbConn, prefix)
+	// the password is updated and sessions are revoked in a single transaction,
+	// so that sessions started using the old password cannot outlive it
+	tx, err := dbConn.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("FindAPIKey failed to svc.repository.ListActiveAPIKeysByPrefix: %w", err)
+		return fmt.Errorf("ChangePassword failed to dbConn.Begin: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	keyBytes := []byte(rawKey)
-	for _, apiKey := range apiKeys {
-		err = bcrypt.CompareHashAndPassword([]byte(apiKey.HashedKey), keyBytes)
-		if err == nil {
-			return apiKey, nil
+	err = svc.repository.UpdateUserPassword(tx, user.UUID, hashedPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("ChangePassword failed to svc.repository.UpdateUserPassword, %w: %w", errutils.ErrUserNotFound, err)
+		default:
+			err = fmt.Errorf("ChangePassword failed to svc.repository.UpdateUserPassword: %w", err)
 		}
+		return err
 	}
 
-	return nil, fmt.Errorf("FindAPIKey failed to find API key: %w", errutils.ErrAPIKeyNotFound)
+	sessionID, ok := ctx.Value(AuthContextKeySessionID).(string)
+	if ok {
+		err = svc.repository.RevokeOtherUserSessions(tx, user.UUID, sessionID)
+	} else {
+		err = svc.repository.RevokeUserSessions(tx, user.UUID)
+	}
+	if err != nil {
+		return fmt.Errorf("ChangePassword failed to revoke sessions: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("ChangePassword failed to tx.Commit: %w", err)
+	}
+
+	return nil
 }
 
-// DeleteAPIKey deletes API key for currently authenticated User.
-func (svc *service) DeleteAPIKey(ctx context.Context, apiKeyID int) error {
+// RequestEmailChange sends verification email to the new email of currently authenticated User,
+// and notifies the current email of the request.
+// The email is only changed once the new email is verified.
+func (svc *service) RequestEmailChange(ctx context.Context, wg *sync.WaitGroup, email string, password string) error {
 	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
 	if !ok {
-		return errors.New("DeleteAPIKey failed to ctx.Value user UUID from ctx")
+		return errors.New("RequestEmailChange failed to ctx.Value user UUID from ctx")
 	}
 
 	dbConn, err := svc.dbPool.Acquire(ctx)
 	if err != nil {
-		return fmt.Errorf("DeleteAPIKey failed to svc.dbPool.Acquire: %w", err)
+		return fmt.Errorf("RequestEmailChange failed to svc.dbPool.Acquire: %w", err)
 	}
 	defer dbConn.Release()
 
-	err = svc.repository.DeleteAPIKey(dbConn, apiKeyID, userUUID)
+	user, err := svc.repository.GetUserByUUID(dbConn, userUUID)
 	if err != nil {
 		switch {
-		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
-			err = fmt.Errorf("DeleteAPIKey failed to svc.repository.DeleteAPIKey, %w: %w", errutils.ErrAPIKeyNotFound, err)
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("RequestEmailChange failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrUserNotFound, err)
 		default:
-			err = fmt.Errorf("DeleteAPIKey failed to svc.repository.DeleteAPIKey: %w", err)
+			err = fmt.Errorf("RequestEmailChange failed to svc.repository.GetUserByUUID: %w", err)
 		}
 		return err
 	}
 
+	err = svc.passwordHasher.Verify(user.Password, password)
+	if err != nil {
+		return fmt.Errorf("RequestEmailChange failed to svc.passwordHasher.Verify, %w: %w", errutils.ErrInvalidCredentials, err)
+	}
+
+	_, err = svc.repository.GetUserByEmail(dbConn, email)
+	if err == nil {
+		return fmt.Errorf("RequestEmailChange failed, email %s is taken: %w", email, errutils.ErrUserAlreadyExists)
+	}
+
+	if !errors.Is(err, errutils.ErrDatabaseNoRowsReturned) {
+		return fmt.Errorf("RequestEmailChange failed to svc.repository.GetUserByEmail: %w", err)
+	}
+
+	emailChangeToken, issuedJWT, err := createEmailChangeJWT(
+		user,
+		email,
+		svc.config.SecretKey,
+		time.Duration(svc.config.EmailChangeLifetime*int64(time.Minute)),
+	)
+	if err != nil {
+		return fmt.Errorf("RequestEmailChange failed to createEmailChangeJWT: %w", err)
+	}
+
+	_, err = svc.repository.CreateIssuedJWT(dbConn, issuedJWT)
+	if err != nil {
+		return fmt.Errorf("RequestEmailChange failed to svc.repository.CreateIssuedJWT: %w", err)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := sendEmailChangeMail(
+			email,
+			emailChangeToken,
+			svc.mailClient,
+			svc.tmplManager,
+			svc.config.FrontendBaseURL,
+			svc.config.FrontendEmailChangeRoute,
+		)
+		if err != nil {
+			svc.logger.LogError("RequestEmailChange failed to sendEmailChangeMail:", err)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := sendEmailChangeNoticeMail(user, email, svc.mailClient, svc.tmplManager)
+		if err != nil {
+			svc.logger.LogError("RequestEmailChange failed to sendEmailChangeNoticeMail:", err)
+		}
+	}()
+
+	return nil
+}
+
+// ConfirmEmailChange changes User email from email change JWT.
+// The JWT can only be used once, and is rejected if the User's email has changed since it was issued.
+func (svc *service) ConfirmEmailChange(ctx context.Context, token string) error {
+	claims, ok := validateEmailChangeJWT(token, svc.config.SecretKey)
+	if !ok {
+		return fmt.Errorf("ConfirmEmailChange failed to validateEmailChangeJWT %s: %w", token, errutils.ErrInvalidToken)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("ConfirmEmailChange failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	err = svc.repository.ConsumeIssuedJWT(dbConn, claims.JWTID, claims.Subject, string(JWTTypeEmailChange))
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("ConfirmEmailChange failed to svc.repository.ConsumeIssuedJWT, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("ConfirmEmailChange failed to svc.repository.ConsumeIssuedJWT: %w", err)
+		}
+		return err
+	}
+
+	err = svc.repository.UpdateUserEmail(dbConn, claims.Subject, claims.OldEmail, claims.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseUniqueViolation):
+			err = fmt.Errorf("ConfirmEmailChange failed to svc.repository.UpdateUserEmail, %w: %w", errutils.ErrUserAlreadyExists, err)
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("ConfirmEmailChange failed to svc.repository.UpdateUserEmail, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("ConfirmEmailChange failed to svc.repository.UpdateUserEmail: %w", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// startSession creates new session for User along with its access and refresh JWTs,
+// recording the IP address and user agent of the client in the session.
+func (svc *service) startSession(dbConn *pgxpool.Conn, userUUID string, ipAddress string, userAgent string) (string, string, error) {
+	sessionID := uuid.NewString()
+	accessToken, _, err := createAuthJWT(
+		userUUID,
+		sessionID,
+		JWTTypeAccess,
+		svc.keySet,
+		time.Duration(svc.config.AuthAccessLifetime*int64(time.Minute)),
+		time.Duration(svc.config.AuthRefreshLifetime*int64(time.Minute)),
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("startSession failed to createAuthJWT of type %s: %w", JWTTypeAccess, err)
+	}
+
+	refreshToken, refreshClaims, err := createAuthJWT(
+		userUUID,
+		sessionID,
+		JWTTypeRefresh,
+		svc.keySet,
+		time.Duration(svc.config.AuthAccessLifetime*int64(time.Minute)),
+		time.Duration(svc.config.AuthRefreshLifetime*int64(time.Minute)),
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("startSession failed to createAuthJWT of type %s: %w", JWTTypeRefresh, err)
+	}
+
+	_, err = svc.repository.CreateSession(dbConn, &Session{
+		ID:         sessionID,
+		UserUUID:   userUUID,
+		RefreshJTI: refreshClaims.JWTID,
+		IPAddress:  ipAddress,
+		UserAgent:  truncateUserAgent(userAgent),
+		ExpiresAt:  time.Time(refreshClaims.ExpiresAt),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("startSession failed to svc.repository.CreateSession: %w", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// checkLoginThrottle checks whether login attempts for a given scope and identifier are currently locked,
+// and returns the time remaining until they are unlocked.
+func (svc *service) checkLoginThrottle(dbConn *pgxpool.Conn, scope LoginThrottleScope, identifier string, now time.Time) (time.Duration, error) {
+	throttle, err := svc.repository.GetLoginThrottle(dbConn, scope, identifier)
+	if err != nil {
+		if errors.Is(err, errutils.ErrDatabaseNoRowsReturned) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("checkLoginThrottle failed to svc.repository.GetLoginThrottle: %w", err)
+	}
+
+	if !throttle.LockedUntil.Valid || !throttle.LockedUntil.Time.After(now) {
+		return 0, nil
+	}
+
+	return throttle.LockedUntil.Time.Sub(now), nil
+}
+
+// recordLoginFailure records a failed login attempt for a given scope and identifier,
+// and locks further attempts if the number of consecutive failures reaches a given threshold.
+func (svc *service) recordLoginFailure(
+	dbConn *pgxpool.Conn,
+	scope LoginThrottleScope,
+	identifier string,
+	threshold int,
+	now time.Time,
+) (*LoginThrottle, error) {
+	throttle, err := svc.repository.RecordLoginFailure(dbConn, scope, identifier, now, now.Add(-loginFailureWindow))
+	if err != nil {
+		return nil, fmt.Errorf("recordLoginFailure failed to svc.repository.RecordLoginFailure: %w", err)
+	}
+
+	lockout := loginLockoutDuration(throttle.FailureCount, threshold)
+	if lockout == 0 {
+		return throttle, nil
+	}
+
+	lockedUntil := now.Add(lockout)
+	err = svc.repository.LockLoginThrottle(dbConn, scope, identifier, lockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("recordLoginFailure failed to svc.repository.LockLoginThrottle: %w", err)
+	}
+
+	throttle.LockedUntil = pgtype.Timestamp{
+		Time:  lockedUntil,
+		Valid: true,
+	}
+
+	return throttle, nil
+}
+
+// checkLoginThrottles checks whether login attempts for a given account or IP address are currently locked,
+// and returns errutils.ErrTooManyLoginAttempts along with the time remaining until both are unlocked if so.
+func (svc *service) checkLoginThrottles(dbConn *pgxpool.Conn, accountIdentifier string, ipAddress string, now time.Time) error {
+	accountRetryAfter, err := svc.checkLoginThrottle(dbConn, LoginThrottleScopeAccount, accountIdentifier, now)
+	if err != nil {
+		return fmt.Errorf("checkLoginThrottles failed to svc.checkLoginThrottle: %w", err)
+	}
+
+	ipRetryAfter, err := svc.checkLoginThrottle(dbConn, LoginThrottleScopeIP, ipAddress, now)
+	if err != nil {
+		return fmt.Errorf("checkLoginThrottles failed to svc.checkLoginThrottle: %w", err)
+	}
+
+	retryAfter := max(accountRetryAfter, ipRetryAfter)
+	if retryAfter > 0 {
+		return &errutils.RetryAfterError{
+			Err:        errutils.ErrTooManyLoginAttempts,
+			RetryAfter: retryAfter,
+		}
+	}
+
+	return nil
+}
+
+// recordLoginFailures records a failed login attempt for both a given account and IP address,
+// and returns the throttle of the account.
+func (svc *service) recordLoginFailures(dbConn *pgxpool.Conn, accountIdentifier string, ipAddress string, now time.Time) (*LoginThrottle, error) {
+	accountThrottle, err := svc.recordLoginFailure(dbConn, LoginThrottleScopeAccount, accountIdentifier, loginAccountFailureThreshold, now)
+	if err != nil {
+		return nil, fmt.Errorf("recordLoginFailures failed to svc.recordLoginFailure: %w", err)
+	}
+
+	_, err = svc.recordLoginFailure(dbConn, LoginThrottleScopeIP, ipAddress, loginIPFailureThreshold, now)
+	if err != nil {
+		return nil, fmt.Errorf("recordLoginFailures failed to svc.recordLoginFailure: %w", err)
+	}
+
+	return accountThrottle, nil
+}
+
+// validatePassword validates that a new password satisfies the password policy,
+// given the email address and name of the User it belongs to.
+// Field is the name of the request field the password was given in, used to report validation failures.
+func (svc *service) validatePassword(field string, password string, email string, firstName string, lastName string) error {
+	v := validate.NewValidator()
+	v.ValidatePassword(field, password, svc.passwordPolicy, email, firstName, lastName)
+	if !v.Passed() {
+		return &errutils.ValidationError{
+			Err:      errutils.ErrWeakPassword,
+			Failures: v.Failures(),
+		}
+	}
+
+	return nil
+}
+
+// rehashPassword hashes a User's password using the current password hasher and replaces the stored hash.
+func (svc *service) rehashPassword(dbConn *pgxpool.Conn, user *User, password string) error {
+	hashedPassword, err := svc.passwordHasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("rehashPassword failed to svc.passwordHasher.Hash: %w", err)
+	}
+
+	err = svc.repository.RehashUserPassword(dbConn, user.UUID, user.Password, hashedPassword)
+	if err != nil {
+		return fmt.Errorf("rehashPassword failed to svc.repository.RehashUserPassword: %w", err)
+	}
+
+	user.Password = hashedPassword
+
+	return nil
+}
+
+// CreateJWT authenticates User and creates new access and refresh JWTs.
+// The IP address and user agent of the client are recorded in the created session.
+// If the User has two-factor authentication enabled, no session is created,
+// and a challenge JWT is returned instead, which must be verified along with a code using VerifyTOTPChallenge.
+// Failed attempts are counted against both the email and the IP address,
+// and once either is locked, attempts are rejected until the lockout expires, even with the correct password.
+// The owner of the account is notified by email when their account is first locked.
+// On successful authentication, passwords hashed using an outdated algorithm or parameters are rehashed.
+func (svc *service) CreateJWT(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	email string,
+	password string,
+	ipAddress string,
+	userAgent string,
+) (string, string, string, error) {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return "", "", "", fmt.Errorf("CreateJWT failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	now := time.Now().UTC()
+	accountIdentifier := loginAccountIdentifier(email)
+
+	err = svc.checkLoginThrottles(dbConn, accountIdentifier, ipAddress, now)
+	if err != nil {
+		return "", "", "", fmt.Errorf("CreateJWT failed to svc.checkLoginThrottles: %w", err)
+	}
+
+	dummyPasswordHash, err := svc.dummyPasswordHash()
+	if err != nil {
+		return "", "", "", fmt.Errorf("CreateJWT failed to svc.dummyPasswordHash: %w", err)
+	}
+
+	dummyUser := &User{}
+	dummyUser.UUID = "93c58b3c-f087-4e97-805a-1e4676cdd5ec"
+	dummyUser.Password = dummyPasswordHash
+	failAuth := false
+
+	user, err := svc.repository.GetUserByEmail(dbConn, email)
+	if err != nil && !errors.Is(err, errutils.ErrDatabaseNoRowsReturned) {
+		return "", "", "", fmt.Errorf("CreateJWT failed to svc.repository.GetActiveUserByEmail: %w", err)
+	}
+
+	if err != nil || user == nil || !user.IsActive {
+		failAuth = true
+		user = dummyUser
+	}
+	accountUser := user
+
+	err = svc.passwordHasher.Verify(user.Password, password)
+	if err != nil {
+		failAuth = true
+		user = dummyUser
+	}
+
+	if failAuth {
+		accountThrottle, err := svc.recordLoginFailures(dbConn, accountIdentifier, ipAddress, now)
+		if err != nil {
+			return "", "", "", fmt.Errorf("CreateJWT failed to svc.recordLoginFailures: %w", err)
+		}
+
+		// only the first lockout of a streak of failures is notified, to avoid flooding the owner's inbox
+		if accountThrottle.FailureCount == loginAccountFailureThreshold && accountUser != dummyUser {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := sendAccountLockoutMail(accountUser, ipAddress, accountThrottle.LockedUntil.Time, svc.mailClient, svc.tmplManager)
+				if err != nil {
+					svc.logger.LogError("CreateJWT failed to sendAccountLockoutMail:", err)
+				}
+			}()
+		}
+
+		return "", "", "", fmt.Errorf("CreateJWT failed: %w", errutils.ErrInvalidCredentials)
+	}
+
+	err = svc.repository.DeleteLoginThrottle(dbConn, LoginThrottleScopeAccount, accountIdentifier)
+	if err != nil {
+		return "", "", "", fmt.Errorf("CreateJWT failed to svc.repository.DeleteLoginThrottle: %w", err)
+	}
+
+	// passwords hashed using an outdated algorithm or parameters are upgraded while the plaintext password is at hand,
+	// failing to do so should not prevent the User from logging in
+	if svc.passwordHasher.NeedsRehash(user.Password) {
+		err = svc.rehashPassword(dbConn, user, password)
+		if err != nil {
+			svc.logger.LogWarn("CreateJWT failed to svc.rehashPassword:", err)
+		}
+	}
+
+	accessToken, refreshToken, challengeToken, err := svc.completeLogin(dbConn, user.UUID, ipAddress, userAgent)
+	if err != nil {
+		return "", "", "", fmt.Errorf("CreateJWT failed to svc.completeLogin: %w", err)
+	}
+
+	return accessToken, refreshToken, challengeToken, nil
+}
+
+// completeLogin completes authentication of User whose first factor has been verified.
+// If the User has two-factor authentication enabled, no session is created,
+// and a challenge JWT is returned instead, which must be verified along with a code using VerifyTOTPChallenge.
+// Otherwise, a new session is started, and its access and refresh JWTs are returned.
+func (svc *service) completeLogin(dbConn *pgxpool.Conn, userUUID string, ipAddress string, userAgent string) (string, string, string, error) {
+	device, err := svc.repository.GetTOTPDevice(dbConn, userUUID)
+	if err != nil && !errors.Is(err, errutils.ErrDatabaseNoRowsReturned) {
+		return "", "", "", fmt.Errorf("completeLogin failed to svc.repository.GetTOTPDevice: %w", err)
+	}
+
+	if err == nil && device.ConfirmedAt.Valid {
+		challengeToken, issuedJWT, err := createTOTPChallengeJWT(userUUID, svc.config.SecretKey, totpChallengeLifetime)
+		if err != nil {
+			return "", "", "", fmt.Errorf("completeLogin failed to createTOTPChallengeJWT: %w", err)
+		}
+
+		_, err = svc.repository.CreateIssuedJWT(dbConn, issuedJWT)
+		if err != nil {
+			return "", "", "", fmt.Errorf("completeLogin failed to svc.repository.CreateIssuedJWT: %w", err)
+		}
+
+		return "", "", challengeToken, nil
+	}
+
+	accessToken, refreshToken, err := svc.startSession(dbConn, userUUID, ipAddress, userAgent)
+	if err != nil {
+		return "", "", "", fmt.Errorf("completeLogin failed to svc.startSession: %w", err)
+	}
+
+	return accessToken, refreshToken, "", nil
+}
+
+// RequestMagicLink sends an email with a single-use passwordless login link to the active User with given email.
+// No error is returned when no such User exists,
+// or when a magic link was already sent to the User within the cooldown period,
+// so that the response does not reveal whether or not the account exists.
+func (svc *service) RequestMagicLink(ctx context.Context, wg *sync.WaitGroup, email string) error {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("RequestMagicLink failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	user, err := svc.repository.GetUserByEmail(dbConn, email)
+	if err != nil {
+		if errors.Is(err, errutils.ErrDatabaseNoRowsReturned) {
+			return nil
+		}
+		return fmt.Errorf("RequestMagicLink failed to svc.repository.GetUserByEmail: %w", err)
+	}
+
+	if !user.IsActive {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	recentCount, err := svc.repository.CountIssuedJWTs(dbConn, user.UUID, string(JWTTypeMagicLink), now.Add(-magicLinkCooldown))
+	if err != nil {
+		return fmt.Errorf("RequestMagicLink failed to svc.repository.CountIssuedJWTs: %w", err)
+	}
+
+	if recentCount > 0 {
+		return nil
+	}
+
+	lifetime := time.Duration(svc.config.MagicLinkLifetime * int64(time.Minute))
+	magicLinkToken, issuedJWT, err := createMagicLinkJWT(user, svc.config.SecretKey, lifetime)
+	if err != nil {
+		return fmt.Errorf("RequestMagicLink failed to createMagicLinkJWT: %w", err)
+	}
+
+	_, err = svc.repository.CreateIssuedJWT(dbConn, issuedJWT)
+	if err != nil {
+		return fmt.Errorf("RequestMagicLink failed to svc.repository.CreateIssuedJWT: %w", err)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := sendMagicLinkMail(
+			user,
+			magicLinkToken,
+			svc.mailClient,
+			svc.tmplManager,
+			svc.config.FrontendBaseURL,
+			svc.config.FrontendMagicLinkRoute,
+			lifetime,
+		)
+		if err != nil {
+			svc.logger.LogError("RequestMagicLink failed to sendMagicLinkMail:", err)
+		}
+	}()
+
+	return nil
+}
+
+// VerifyMagicLink validates magic link JWT and creates new access and refresh JWTs, just like CreateJWT.
+// The JWT can only be used once, and is rejected if the User's email has changed since it was issued.
+// If the User has two-factor authentication enabled, a challenge JWT is returned instead.
+// Since the User has proven ownership of their email, failed login attempts against their account are cleared.
+func (svc *service) VerifyMagicLink(ctx context.Context, token string, ipAddress string, userAgent string) (string, string, string, error) {
+	claims, ok := validateMagicLinkJWT(token, svc.config.SecretKey)
+	if !ok {
+		return "", "", "", fmt.Errorf("VerifyMagicLink failed to validateMagicLinkJWT %s: %w", token, errutils.ErrInvalidToken)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return "", "", "", fmt.Errorf("VerifyMagicLink failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	user, err := svc.repository.GetUserByUUID(dbConn, claims.Subject)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("VerifyMagicLink failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("VerifyMagicLink failed to svc.repository.GetUserByUUID: %w", err)
+		}
+		return "", "", "", err
+	}
+
+	if !user.IsActive {
+		return "", "", "", fmt.Errorf("VerifyMagicLink failed, user %s is inactive: %w", user.UUID, errutils.ErrInvalidToken)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Email), []byte(user.Email)) == 0 {
+		return "", "", "", fmt.Errorf("VerifyMagicLink failed, email changed since token was issued: %w", errutils.ErrInvalidToken)
+	}
+
+	err = svc.repository.ConsumeIssuedJWT(dbConn, claims.JWTID, claims.Subject, string(JWTTypeMagicLink))
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("VerifyMagicLink failed to svc.repository.ConsumeIssuedJWT, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("VerifyMagicLink failed to svc.repository.ConsumeIssuedJWT: %w", err)
+		}
+		return "", "", "", err
+	}
+
+	err = svc.repository.DeleteLoginThrottle(dbConn, LoginThrottleScopeAccount, loginAccountIdentifier(user.Email))
+	if err != nil {
+		return "", "", "", fmt.Errorf("VerifyMagicLink failed to svc.repository.DeleteLoginThrottle: %w", err)
+	}
+
+	accessToken, refreshToken, challengeToken, err := svc.completeLogin(dbConn, user.UUID, ipAddress, userAgent)
+	if err != nil {
+		return "", "", "", fmt.Errorf("VerifyMagicLink failed to svc.completeLogin: %w", err)
+	}
+
+	return accessToken, refreshToken, challengeToken, nil
+}
+
+// VerifyTOTPChallenge validates challenge JWT along with a TOTP code or recovery code,
+// and creates new access and refresh JWTs.
+// The challenge JWT can only be used once, and is invalidated after totpChallengeMaxAttempts codes have been tried against it.
+// Incorrect codes count towards the same account and IP address lockouts as incorrect passwords.
+func (svc *service) VerifyTOTPChallenge(
+	ctx context.Context,
+	challengeToken string,
+	code string,
+	ipAddress string,
+	userAgent string,
+) (string, string, error) {
+	claims, ok := validateTOTPChallengeJWT(challengeToken, svc.config.SecretKey)
+	if !ok {
+		return "", "", fmt.Errorf("VerifyTOTPChallenge failed to validateTOTPChallengeJWT %s: %w", challengeToken, errutils.ErrInvalidToken)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("VerifyTOTPChallenge failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	user, err := svc.repository.GetUserByUUID(dbConn, claims.Subject)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("VerifyTOTPChallenge failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("VerifyTOTPChallenge failed to svc.repository.GetUserByUUID: %w", err)
+		}
+		return "", "", err
+	}
+
+	if !user.IsActive {
+		return "", "", fmt.Errorf("VerifyTOTPChallenge failed, user %s is inactive: %w", user.UUID, errutils.ErrInvalidToken)
+	}
+
+	now := time.Now().UTC()
+	accountIdentifier := loginAccountIdentifier(user.Email)
+
+	err = svc.checkLoginThrottles(dbConn, accountIdentifier, ipAddress, now)
+	if err != nil {
+		return "", "", fmt.Errorf("VerifyTOTPChallenge failed to svc.checkLoginThrottles: %w", err)
+	}
+
+	attemptCount, err := svc.repository.RecordIssuedJWTAttempt(
+		dbConn,
+		claims.JWTID,
+		claims.Subject,
+		string(JWTTypeTOTPChallenge),
+		totpChallengeMaxAttempts,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("VerifyTOTPChallenge failed to svc.repository.RecordIssuedJWTAttempt, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("VerifyTOTPChallenge failed to svc.repository.RecordIssuedJWTAttempt: %w", err)
+		}
+		return "", "", err
+	}
+
+	err = svc.verifySecondFactor(dbConn, user.UUID, code)
+	if err != nil {
+		if errors.Is(err, errutils.ErrInvalidTOTPCode) {
+			_, recordErr := svc.recordLoginFailures(dbConn, accountIdentifier, ipAddress, now)
+			if recordErr != nil {
+				return "", "", fmt.Errorf("VerifyTOTPChallenge failed to svc.recordLoginFailures: %w", recordErr)
+			}
+		}
+
+		// the challenge is invalidated once it runs out of attempts, so the User has to log in again
+		if attemptCount >= totpChallengeMaxAttempts {
+			consumeErr := svc.repository.ConsumeIssuedJWT(dbConn, claims.JWTID, claims.Subject, string(JWTTypeTOTPChallenge))
+			if consumeErr != nil && !errors.Is(consumeErr, errutils.ErrDatabaseNoRowsAffected) {
+				return "", "", fmt.Errorf("VerifyTOTPChallenge failed to svc.repository.ConsumeIssuedJWT: %w", consumeErr)
+			}
+		}
+
+		return "", "", fmt.Errorf("VerifyTOTPChallenge failed to svc.verifySecondFactor: %w", err)
+	}
+
+	err = svc.repository.ConsumeIssuedJWT(dbConn, claims.JWTID, claims.Subject, string(JWTTypeTOTPChallenge))
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("VerifyTOTPChallenge failed to svc.repository.ConsumeIssuedJWT, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("VerifyTOTPChallenge failed to svc.repository.ConsumeIssuedJWT: %w", err)
+		}
+		return "", "", err
+	}
+
+	err = svc.repository.DeleteLoginThrottle(dbConn, LoginThrottleScopeAccount, accountIdentifier)
+	if err != nil {
+		return "", "", fmt.Errorf("VerifyTOTPChallenge failed to svc.repository.DeleteLoginThrottle: %w", err)
+	}
+
+	accessToken, refreshToken, err := svc.startSession(dbConn, user.UUID, ipAddress, userAgent)
+	if err != nil {
+		return "", "", fmt.Errorf("VerifyTOTPChallenge failed to svc.startSession: %w", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// CreateOIDCAuthURL starts single sign-on through the OpenID Connect provider,
+// and returns the URL of the provider that the User should be sent to.
+func (svc *service) CreateOIDCAuthURL(ctx context.Context) (string, error) {
+	if svc.oidcClient == nil {
+		return "", fmt.Errorf("CreateOIDCAuthURL failed: %w", errutils.ErrOIDCNotConfigured)
+	}
+
+	authRequest, err := createOIDCAuthRequest(oidcAuthRequestLifetime)
+	if err != nil {
+		return "", fmt.Errorf("CreateOIDCAuthURL failed to createOIDCAuthRequest: %w", err)
+	}
+
+	authURL, err := svc.oidcClient.AuthCodeURL(
+		ctx,
+		authRequest.State,
+		authRequest.Nonce,
+		oidc.CodeChallenge(authRequest.CodeVerifier),
+	)
+	if err != nil {
+		return "", fmt.Errorf("CreateOIDCAuthURL failed to svc.oidcClient.AuthCodeURL: %w", err)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return "", fmt.Errorf("CreateOIDCAuthURL failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	_, err = svc.repository.CreateOIDCAuthRequest(dbConn, authRequest)
+	if err != nil {
+		return "", fmt.Errorf("CreateOIDCAuthURL failed to svc.repository.CreateOIDCAuthRequest: %w", err)
+	}
+
+	return authURL, nil
+}
+
+// CreateJWTFromOIDC completes single sign-on using the state and authorization code
+// that the OpenID Connect provider redirected back with, and creates new access and refresh JWTs.
+// The User is matched by the email verified by the provider, and is created if it does not exist.
+// Inactive Users cannot log in through single sign-on.
+// If the User has two-factor authentication enabled, a challenge JWT is returned instead.
+// Each state can only be used once.
+func (svc *service) CreateJWTFromOIDC(
+	ctx context.Context,
+	state string,
+	code string,
+	ipAddress string,
+	userAgent string,
+) (string, string, string, error) {
+	if svc.oidcClient == nil {
+		return "", "", "", fmt.Errorf("CreateJWTFromOIDC failed: %w", errutils.ErrOIDCNotConfigured)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return "", "", "", fmt.Errorf("CreateJWTFromOIDC failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	authRequest, err := svc.repository.ConsumeOIDCAuthRequest(dbConn, state)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("CreateJWTFromOIDC failed to svc.repository.ConsumeOIDCAuthRequest, %w: %w", errutils.ErrInvalidOIDCState, err)
+		default:
+			err = fmt.Errorf("CreateJWTFromOIDC failed to svc.repository.ConsumeOIDCAuthRequest: %w", err)
+		}
+		return "", "", "", err
+	}
+
+	idToken, err := svc.oidcClient.Exchange(ctx, code, authRequest.CodeVerifier)
+	if err != nil {
+		return "", "", "", fmt.Errorf("CreateJWTFromOIDC failed to svc.oidcClient.Exchange, %w: %w", errutils.ErrOIDCAuthFailed, err)
+	}
+
+	claims, err := svc.oidcClient.VerifyIDToken(ctx, idToken, authRequest.Nonce)
+	if err != nil {
+		return "", "", "", fmt.Errorf("CreateJWTFromOIDC failed to svc.oidcClient.VerifyIDToken, %w: %w", errutils.ErrOIDCAuthFailed, err)
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return "", "", "", fmt.Errorf("CreateJWTFromOIDC failed, email of subject %s is not verified: %w", claims.Subject, errutils.ErrOIDCAuthFailed)
+	}
+
+	user, err := svc.repository.GetUserByEmail(dbConn, claims.Email)
+	if err != nil && !errors.Is(err, errutils.ErrDatabaseNoRowsReturned) {
+		return "", "", "", fmt.Errorf("CreateJWTFromOIDC failed to svc.repository.GetUserByEmail: %w", err)
+	}
+
+	if err != nil {
+		// the email may still belong to an inactive User, which is not looked up above and cannot be logged into
+		user, err = svc.provisionOIDCUser(dbConn, claims)
+		if err != nil {
+			switch {
+			case errors.Is(err, errutils.ErrDatabaseUniqueViolation):
+				err = fmt.Errorf("CreateJWTFromOIDC failed to svc.provisionOIDCUser, %w: %w", errutils.ErrOIDCAuthFailed, err)
+			default:
+				err = fmt.Errorf("CreateJWTFromOIDC failed to svc.provisionOIDCUser: %w", err)
+			}
+			return "", "", "", err
+		}
+	}
+
+	accessToken, refreshToken, challengeToken, err := svc.completeLogin(dbConn, user.UUID, ipAddress, userAgent)
+	if err != nil {
+		return "", "", "", fmt.Errorf("CreateJWTFromOIDC failed to svc.completeLogin: %w", err)
+	}
+
+	return accessToken, refreshToken, challengeToken, nil
+}
+
+// provisionOIDCUser creates active User from the claims of an ID token issued by the OpenID Connect provider.
+// The User is given a random password, which can be replaced through a password reset.
+func (svc *service) provisionOIDCUser(dbConn *pgxpool.Conn, claims *oidc.IDTokenClaims) (*User, error) {
+	password, err := utils.GenerateRandomString(32, true, true, true)
+	if err != nil {
+		return nil, fmt.Errorf("provisionOIDCUser failed to utils.GenerateRandomString: %w", err)
+	}
+
+	hashedPassword, err := svc.passwordHasher.Hash(password)
+	if err != nil {
+		return nil, fmt.Errorf("provisionOIDCUser failed to svc.passwordHasher.Hash: %w", err)
+	}
+
+	user, err := svc.repository.CreateUser(dbConn, &User{
+		UUID:        uuid.NewString(),
+		Email:       claims.Email,
+		Password:    hashedPassword,
+		FirstName:   truncateUserName(claims.GivenName),
+		LastName:    truncateUserName(claims.FamilyName),
+		IsActive:    true,
+		IsSuperUser: false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("provisionOIDCUser failed to svc.repository.CreateUser: %w", err)
+	}
+
+	return user, nil
+}
+
+// RefreshJWT validates refresh token and rotates it, creating new access and refresh tokens under the same session.
+// Each refresh token can only be used once.
+// If an already rotated refresh token is reused, the whole session is revoked.
+// The IP address and user agent of the client are recorded in the session.
+func (svc *service) RefreshJWT(ctx context.Context, token string, ipAddress string, userAgent string) (string, string, error) {
+	claims, ok := validateAuthJWT(token, JWTTypeRefresh, svc.keySet)
+	if !ok {
+		return "", "", fmt.Errorf("RefreshJWT failed to validateAuthJWT %s: %w", token, errutils.ErrInvalidToken)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("RefreshJWT failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	_, err = svc.repository.GetUserByUUID(dbConn, claims.Subject)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("RefreshJWT failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("RefreshJWT failed to svc.repository.GetUserByUUID: %w", err)
+		}
+		return "", "", err
+	}
+
+	accessToken, _, err := createAuthJWT(
+		claims.Subject,
+		claims.SessionID,
+		JWTTypeAccess,
+		svc.keySet,
+		time.Duration(svc.config.AuthAccessLifetime*int64(time.Minute)),
+		time.Duration(svc.config.AuthRefreshLifetime*int64(time.Minute)),
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("RefreshJWT failed to createAuthJWT of type %s: %w", JWTTypeAccess, err)
+	}
+
+	refreshToken, refreshClaims, err := createAuthJWT(
+		claims.Subject,
+		claims.SessionID,
+		JWTTypeRefresh,
+		svc.keySet,
+		time.Duration(svc.config.AuthAccessLifetime*int64(time.Minute)),
+		time.Duration(svc.config.AuthRefreshLifetime*int64(time.Minute)),
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("RefreshJWT failed to createAuthJWT of type %s: %w", JWTTypeRefresh, err)
+	}
+
+	session, err := svc.repository.GetActiveSession(dbConn, claims.SessionID, claims.Subject)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("RefreshJWT failed to svc.repository.GetActiveSession, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("RefreshJWT failed to svc.repository.GetActiveSession: %w", err)
+		}
+		return "", "", err
+	}
+
+	err = svc.repository.RotateSession(
+		dbConn,
+		session.ID,
+		session.UserUUID,
+		claims.JWTID,
+		refreshClaims.JWTID,
+		ipAddress,
+		truncateUserAgent(userAgent),
+		time.Time(refreshClaims.ExpiresAt),
+	)
+	if err != nil {
+		if !errors.Is(err, errutils.ErrDatabaseNoRowsAffected) {
+			return "", "", fmt.Errorf("RefreshJWT failed to svc.repository.RotateSession: %w", err)
+		}
+
+		revokeErr := svc.repository.RevokeSession(dbConn, session.ID, session.UserUUID)
+		if revokeErr != nil && !errors.Is(revokeErr, errutils.ErrDatabaseNoRowsAffected) {
+			return "", "", fmt.Errorf("RefreshJWT failed to svc.repository.RevokeSession: %w", revokeErr)
+		}
+
+		return "", "", fmt.Errorf("RefreshJWT failed, refresh token reused for session %s, %w: %w", session.ID, errutils.ErrInvalidToken, err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// AuthenticateJWT validates access token and checks that its session has not been revoked or expired.
+// The last use time of the session is updated at most once every sessionTouchInterval.
+func (svc *service) AuthenticateJWT(ctx context.Context, token string) (*api.AuthJWTClaims, error) {
+	claims, ok := validateAuthJWT(token, JWTTypeAccess, svc.keySet)
+	if !ok {
+		return nil, fmt.Errorf("AuthenticateJWT failed to validateAuthJWT %s: %w", token, errutils.ErrInvalidToken)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("AuthenticateJWT failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	// impersonation JWTs are issued under the superuser's session,
+	// and are only valid while the superuser is still an active superuser
+	sessionUserUUID := claims.Subject
+	if claims.Actor != nil {
+		actor, err := svc.repository.GetUserByUUID(dbConn, claims.Actor.Subject)
+		if err != nil {
+			switch {
+			case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+				err = fmt.Errorf("AuthenticateJWT failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrInvalidToken, err)
+			default:
+				err = fmt.Errorf("AuthenticateJWT failed to svc.repository.GetUserByUUID: %w", err)
+			}
+			return nil, err
+		}
+
+		if !actor.IsSuperUser {
+			return nil, fmt.Errorf("AuthenticateJWT failed, actor %s is not a superuser: %w", actor.UUID, errutils.ErrInvalidToken)
+		}
+
+		sessionUserUUID = actor.UUID
+	}
+
+	session, err := svc.repository.GetActiveSession(dbConn, claims.SessionID, sessionUserUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("AuthenticateJWT failed to svc.repository.GetActiveSession, %w: %w", errutils.ErrInvalidToken, err)
+		default:
+			err = fmt.Errorf("AuthenticateJWT failed to svc.repository.GetActiveSession: %w", err)
+		}
+		return nil, err
+	}
+
+	if time.Now().UTC().Sub(session.LastUsedAt) >= sessionTouchInterval {
+		err = svc.repository.TouchSession(dbConn, session.ID, session.UserUUID)
+		if err != nil {
+			switch {
+			case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+				err = fmt.Errorf("AuthenticateJWT failed to svc.repository.TouchSession, %w: %w", errutils.ErrInvalidToken, err)
+			default:
+				err = fmt.Errorf("AuthenticateJWT failed to svc.repository.TouchSession: %w", err)
+			}
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
+
+// ListSessions lists all active sessions of currently authenticated User.
+func (svc *service) ListSessions(ctx context.Context) ([]*Session, error) {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, errors.New("ListSessions failed to ctx.Value user UUID from ctx")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListSessions failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	sessions, err := svc.repository.ListActiveSessionsByUserUUID(dbConn, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ListSessions failed to svc.repository.ListActiveSessionsByUserUUID: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes session of currently authenticated User by session ID.
+func (svc *service) RevokeSession(ctx context.Context, sessionID string) error {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return errors.New("RevokeSession failed to ctx.Value user UUID from ctx")
+	}
+
+	_, err := uuid.Parse(sessionID)
+	if err != nil {
+		return fmt.Errorf("RevokeSession failed to uuid.Parse session ID %s, %w: %w", sessionID, errutils.ErrSessionNotFound, err)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("RevokeSession failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	err = svc.repository.RevokeSession(dbConn, sessionID, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("RevokeSession failed to svc.repository.RevokeSession, %w: %w", errutils.ErrSessionNotFound, err)
+		default:
+			err = fmt.Errorf("RevokeSession failed to svc.repository.RevokeSession: %w", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Logout revokes currently authenticated session.
+func (svc *service) Logout(ctx context.Context) error {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return errors.New("Logout failed to ctx.Value user UUID from ctx")
+	}
+
+	sessionID, ok := ctx.Value(AuthContextKeySessionID).(string)
+	if !ok {
+		return errors.New("Logout failed to ctx.Value session ID from ctx")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("Logout failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	err = svc.repository.RevokeSession(dbConn, sessionID, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("Logout failed to svc.repository.RevokeSession, %w: %w", errutils.ErrSessionNotFound, err)
+		default:
+			err = fmt.Errorf("Logout failed to svc.repository.RevokeSession: %w", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// LogoutAll revokes all sessions of currently authenticated User.
+func (svc *service) LogoutAll(ctx context.Context) error {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return errors.New("LogoutAll failed to ctx.Value user UUID from ctx")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("LogoutAll failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	err = svc.repository.RevokeUserSessions(dbConn, userUUID)
+	if err != nil {
+		return fmt.Errorf("LogoutAll failed to svc.repository.RevokeUserSessions: %w", err)
+	}
+
+	return nil
+}
+
+// CreateAPIKey creates new API key for User, or for one of the User's service accounts.
+// API keys of service accounts default to the service account's scopes,
+// and cannot be given scopes the service account does not have.
+func (svc *service) CreateAPIKey(
+	ctx context.Context,
+	name string,
+	scopes []string,
+	allowedCIDRs []string,
+	allowedOrigins []string,
+	serviceAccountUUID *string,
+	expiresAt pgtype.Timestamp,
+) (*APIKey, string, error) {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, "", errors.New("CreateAPIKey failed to ctx.Value user UUID from ctx")
+	}
+
+	allowedCIDRs, ok = normalizeAPIKeyCIDRs(allowedCIDRs)
+	if !ok {
+		return nil, "", fmt.Errorf("CreateAPIKey failed to normalizeAPIKeyCIDRs: %w", errutils.ErrInvalidAPIKeyCIDR)
+	}
+
+	allowedOrigins, ok = normalizeAPIKeyOrigins(allowedOrigins)
+	if !ok {
+		return nil, "", fmt.Errorf("CreateAPIKey failed to normalizeAPIKeyOrigins: %w", errutils.ErrInvalidAPIKeyOrigin)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("CreateAPIKey failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	var serviceAccount *ServiceAccount
+	if serviceAccountUUID != nil {
+		_, err = uuid.Parse(*serviceAccountUUID)
+		if err != nil {
+			return nil, "", fmt.Errorf("CreateAPIKey failed to uuid.Parse service account UUID %s, %w: %w", *serviceAccountUUID, errutils.ErrServiceAccountNotFound, err)
+		}
+
+		serviceAccount, err = svc.repository.GetServiceAccountByUUID(dbConn, *serviceAccountUUID, userUUID)
+		if err != nil {
+			switch {
+			case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+				err = fmt.Errorf("CreateAPIKey failed to svc.repository.GetServiceAccountByUUID, %w: %w", errutils.ErrServiceAccountNotFound, err)
+			default:
+				err = fmt.Errorf("CreateAPIKey failed to svc.repository.GetServiceAccountByUUID: %w", err)
+			}
+			return nil, "", err
+		}
+
+		if len(scopes) == 0 {
+			scopes = serviceAccount.Scopes
+		}
+	}
+
+	scopes, ok = normalizeAPIKeyScopes(scopes)
+	if !ok {
+		return nil, "", fmt.Errorf("CreateAPIKey failed to normalizeAPIKeyScopes: %w", errutils.ErrInvalidAPIKeyScope)
+	}
+
+	if serviceAccount != nil && !hasAPIKeyScopes(serviceAccount.Scopes, scopes) {
+		return nil, "", fmt.Errorf("CreateAPIKey failed to hasAPIKeyScopes: %w", errutils.ErrServiceAccountScopeDenied)
+	}
+
+	prefix, rawKey, keyDigest, err := createAPIKey(DigestPepper(svc.config))
+	if err != nil {
+		return nil, "", fmt.Errorf("CreateAPIKey failed to generateAPIKey: %w", err)
+	}
+
+	apiKey := &APIKey{
+		UserUUID:       userUUID,
+		Prefix:         prefix,
+		KeyDigest:      keyDigest,
+		Name:           name,
+		Scopes:         scopes,
+		AllowedCIDRs:   allowedCIDRs,
+		AllowedOrigins: allowedOrigins,
+		ExpiresAt:      expiresAt,
+	}
+
+	if serviceAccount != nil {
+		apiKey.ServiceAccountUUID = pgtype.Text{
+			String: serviceAccount.UUID,
+			Valid:  true,
+		}
+	}
+
+	apiKey, err = svc.repository.CreateAPIKey(dbConn, apiKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseUniqueViolation):
+			err = fmt.Errorf("CreateAPIKey failed to svc.repository.CreateAPIKey, %w: %w", errutils.ErrAPIKeyAlreadyExists, err)
+		default:
+			err = fmt.Errorf("CreateAPIKey failed to svc.repository.CreateAPIKey: %w", err)
+		}
+		return nil, "", err
+	}
+
+	return apiKey, rawKey, nil
+}
+
+// ListAPIKeys retrieves API keys for currently authenticated User.
+func (svc *service) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, errors.New("ListAPIKeys failed to ctx.Value user UUID from ctx")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListAPIKeys failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	apiKeys, err := svc.repository.ListAPIKeysByUserUUID(dbConn, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ListAPIKeys failed to svc.repository.ListAPIKeysByUserUUID: %w", err)
+	}
+
+	return apiKeys, nil
+}
+
+// FindAPIKey parses and finds an API Key.
+// API keys are looked up by key digest, falling back to comparing bcrypt hashed keys
+// for API keys created before key digests were introduced.
+// Such API keys are migrated to key digests on first successful use.
+func (svc *service) FindAPIKey(ctx context.Context, rawKey string) (*APIKey, error) {
+	prefix, _, ok := parseAPIKey(rawKey)
+	if !ok {
+		return nil, errors.New("FindAPIKey failed to parseAPIKey")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("FindAPIKey failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	keyDigest := apiKeyDigest(rawKey, DigestPepper(svc.config))
+	apiKey, err := svc.repository.GetActiveAPIKeyByDigest(dbConn, keyDigest)
+	if err == nil {
+		return apiKey, nil
+	}
+
+	if !errors.Is(err, errutils.ErrDatabaseNoRowsReturned) {
+		return nil, fmt.Errorf("FindAPIKey failed to svc.repository.GetActiveAPIKeyByDigest: %w", err)
+	}
+
+	apiKeys, err := svc.repository.ListActiveAPIKeysByPrefix(dbConn, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("FindAPIKey failed to svc.repository.ListActiveAPIKeysByPrefix: %w", err)
+	}
+
+	keyBytes := []byte(rawKey)
+	for _, apiKey := range apiKeys {
+		hashedKey := apiKey.HashedKey
+		if apiKey.Prefix != prefix {
+			hashedKey = apiKey.PreviousHashedKey
+		}
+
+		if hashedKey == "" {
+			continue
+		}
+
+		err = bcrypt.CompareHashAndPassword([]byte(hashedKey), keyBytes)
+		if err != nil {
+			continue
+		}
+
+		err = svc.repository.MigrateAPIKeyDigest(dbConn, apiKey.ID, hashedKey, keyDigest)
+		if err != nil {
+			svc.logger.LogWarn("FindAPIKey failed to svc.repository.MigrateAPIKeyDigest:", err)
+		}
+
+		return apiKey, nil
+	}
+
+	return nil, fmt.Errorf("FindAPIKey failed to find API key: %w", errutils.ErrAPIKeyNotFound)
+}
+
+// UpdateAPIKey updates name, allowed CIDR ranges, allowed origins, and expiration date of API key for currently authenticated User.
+func (svc *service) UpdateAPIKey(
+	ctx context.Context,
+	apiKeyID int,
+	name *string,
+	allowedCIDRs *[]string,
+	allowedOrigins *[]string,
+	expiresAt *pgtype.Timestamp,
+) (*APIKey, error) {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, errors.New("UpdateAPIKey failed to ctx.Value user UUID from ctx")
+	}
+
+	if allowedCIDRs != nil {
+		normalizedCIDRs, ok := normalizeAPIKeyCIDRs(*allowedCIDRs)
+		if !ok {
+			return nil, fmt.Errorf("UpdateAPIKey failed to normalizeAPIKeyCIDRs: %w", errutils.ErrInvalidAPIKeyCIDR)
+		}
+
+		allowedCIDRs = &normalizedCIDRs
+	}
+
+	if allowedOrigins != nil {
+		normalizedOrigins, ok := normalizeAPIKeyOrigins(*allowedOrigins)
+		if !ok {
+			return nil, fmt.Errorf("UpdateAPIKey failed to normalizeAPIKeyOrigins: %w", errutils.ErrInvalidAPIKeyOrigin)
+		}
+
+		allowedOrigins = &normalizedOrigins
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateAPIKey failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	apiKey, err := svc.repository.UpdateAPIKey(dbConn, apiKeyID, userUUID, name, allowedCIDRs, allowedOrigins, expiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("UpdateAPIKey failed to svc.repository.UpdateAPIKey, %w: %w", errutils.ErrAPIKeyNotFound, err)
+		case errors.Is(err, errutils.ErrDatabaseUniqueViolation):
+			err = fmt.Errorf("UpdateAPIKey failed to svc.repository.UpdateAPIKey, %w: %w", errutils.ErrAPIKeyAlreadyExists, err)
+		default:
+			err = fmt.Errorf("UpdateAPIKey failed to svc.repository.UpdateAPIKey: %w", err)
+		}
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// RotateAPIKey issues a new secret for API key of currently authenticated User, keeping its name and scopes.
+// The previous secret remains valid for the configured grace period.
+func (svc *service) RotateAPIKey(ctx context.Context, apiKeyID int) (*APIKey, string, error) {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, "", errors.New("RotateAPIKey failed to ctx.Value user UUID from ctx")
+	}
+
+	prefix, rawKey, keyDigest, err := createAPIKey(DigestPepper(svc.config))
+	if err != nil {
+		return nil, "", fmt.Errorf("RotateAPIKey failed to createAPIKey: %w", err)
+	}
+
+	previousExpiresAt := time.Now().UTC().Add(time.Duration(svc.config.APIKeyRotationGracePeriod * int64(time.Minute)))
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("RotateAPIKey failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	apiKey, err := svc.repository.RotateAPIKey(dbConn, apiKeyID, userUUID, prefix, keyDigest, previousExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("RotateAPIKey failed to svc.repository.RotateAPIKey, %w: %w", errutils.ErrAPIKeyNotFound, err)
+		default:
+			err = fmt.Errorf("RotateAPIKey failed to svc.repository.RotateAPIKey: %w", err)
+		}
+		return nil, "", err
+	}
+
+	return apiKey, rawKey, nil
+}
+
+// RecordAPIKeyUsage records a request made using an API key from a given IP address.
+// Usage is buffered in memory until it is written to the database by FlushAPIKeyUsage.
+func (svc *service) RecordAPIKeyUsage(apiKeyID int, ipAddress string) {
+	svc.usageBuffer.add(apiKeyID, time.Now().UTC(), ipAddress, 1)
+}
+
+// FlushAPIKeyUsage writes buffered API key usage to the database, and returns the number of requests written.
+// Usage that fails to be written is kept in the buffer for the next flush.
+func (svc *service) FlushAPIKeyUsage(ctx context.Context) (int64, error) {
+	records := svc.usageBuffer.drain()
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		for key, record := range records {
+			svc.usageBuffer.add(key.apiKeyID, record.lastUsedAt, record.lastUsedIP, record.requestCount)
+		}
+
+		return 0, fmt.Errorf("FlushAPIKeyUsage failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	var count int64
+	var flushErr error
+	for key, record := range records {
+		err = svc.repository.RecordAPIKeyUsage(
+			dbConn,
+			key.apiKeyID,
+			key.day,
+			record.requestCount,
+			record.lastUsedAt,
+			record.lastUsedIP,
+		)
+		if err != nil {
+			svc.usageBuffer.add(key.apiKeyID, record.lastUsedAt, record.lastUsedIP, record.requestCount)
+			flushErr = fmt.Errorf("FlushAPIKeyUsage failed to svc.repository.RecordAPIKeyUsage: %w", err)
+			continue
+		}
+
+		count += record.requestCount
+	}
+
+	return count, flushErr
+}
+
+// GetAPIKeyUsage retrieves daily request counts for the last given number of days, including today,
+// of API key for currently authenticated User.
+func (svc *service) GetAPIKeyUsage(ctx context.Context, apiKeyID int, days int) ([]*APIKeyUsage, error) {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, errors.New("GetAPIKeyUsage failed to ctx.Value user UUID from ctx")
+	}
+
+	until := time.Now().UTC().Truncate(24 * time.Hour)
+	since := until.AddDate(0, 0, 1-days)
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetAPIKeyUsage failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	usage, err := svc.repository.ListAPIKeyUsage(dbConn, apiKeyID, userUUID, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("GetAPIKeyUsage failed to svc.repository.ListAPIKeyUsage: %w", err)
+	}
+
+	if len(usage) == 0 {
+		return nil, fmt.Errorf("GetAPIKeyUsage failed to svc.repository.ListAPIKeyUsage: %w", errutils.ErrAPIKeyNotFound)
+	}
+
+	return usage, nil
+}
+
+// DeleteAPIKey deletes API key for currently authenticated User.
+func (svc *service) DeleteAPIKey(ctx context.Context, apiKeyID int) error {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return errors.New("DeleteAPIKey failed to ctx.Value user UUID from ctx")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("DeleteAPIKey failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	err = svc.repository.DeleteAPIKey(dbConn, apiKeyID, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("DeleteAPIKey failed to svc.repository.DeleteAPIKey, %w: %w", errutils.ErrAPIKeyNotFound, err)
+		default:
+			err = fmt.Errorf("DeleteAPIKey failed to svc.repository.DeleteAPIKey: %w", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// CreateServiceAccount creates new service account owned by currently authenticated User.
+func (svc *service) CreateServiceAccount(
+	ctx context.Context,
+	name string,
+	description string,
+	scopes []string,
+) (*ServiceAccount, error) {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, errors.New("CreateServiceAccount failed to ctx.Value user UUID from ctx")
+	}
+
+	scopes, ok = normalizeAPIKeyScopes(scopes)
+	if !ok {
+		return nil, fmt.Errorf("CreateServiceAccount failed to normalizeAPIKeyScopes: %w", errutils.ErrInvalidAPIKeyScope)
+	}
+
+	serviceAccount := &ServiceAccount{
+		UUID:        uuid.NewString(),
+		OwnerUUID:   userUUID,
+		Name:        name,
+		Description: description,
+		Scopes:      scopes,
+		IsActive:    true,
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("CreateServiceAccount failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	serviceAccount, err = svc.repository.CreateServiceAccount(dbConn, serviceAccount)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseUniqueViolation):
+			err = fmt.Errorf("CreateServiceAccount failed to svc.repository.CreateServiceAccount, %w: %w", errutils.ErrServiceAccountAlreadyExists, err)
+		default:
+			err = fmt.Errorf("CreateServiceAccount failed to svc.repository.CreateServiceAccount: %w", err)
+		}
+		return nil, err
+	}
+
+	return serviceAccount, nil
+}
+
+// ListServiceAccounts retrieves service accounts owned by currently authenticated User.
+func (svc *service) ListServiceAccounts(ctx context.Context) ([]*ServiceAccount, error) {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, errors.New("ListServiceAccounts failed to ctx.Value user UUID from ctx")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListServiceAccounts failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	serviceAccounts, err := svc.repository.ListServiceAccountsByOwnerUUID(dbConn, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ListServiceAccounts failed to svc.repository.ListServiceAccountsByOwnerUUID: %w", err)
+	}
+
+	return serviceAccounts, nil
+}
+
+// UpdateServiceAccount updates name, description, and active state of service account owned by currently authenticated User.
+// API keys of a deactivated service account cannot be used until it is activated again.
+func (svc *service) UpdateServiceAccount(
+	ctx context.Context,
+	serviceAccountUUID string,
+	name *string,
+	description *string,
+	isActive *bool,
+) (*ServiceAccount, error) {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, errors.New("UpdateServiceAccount failed to ctx.Value user UUID from ctx")
+	}
+
+	_, err := uuid.Parse(serviceAccountUUID)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateServiceAccount failed to uuid.Parse service account UUID %s, %w: %w", serviceAccountUUID, errutils.ErrServiceAccountNotFound, err)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateServiceAccount failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	serviceAccount, err := svc.repository.UpdateServiceAccount(dbConn, serviceAccountUUID, userUUID, name, description, isActive)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("UpdateServiceAccount failed to svc.repository.UpdateServiceAccount, %w: %w", errutils.ErrServiceAccountNotFound, err)
+		case errors.Is(err, errutils.ErrDatabaseUniqueViolation):
+			err = fmt.Errorf("UpdateServiceAccount failed to svc.repository.UpdateServiceAccount, %w: %w", errutils.ErrServiceAccountAlreadyExists, err)
+		default:
+			err = fmt.Errorf("UpdateServiceAccount failed to svc.repository.UpdateServiceAccount: %w", err)
+		}
+		return nil, err
+	}
+
+	return serviceAccount, nil
+}
+
+// DeleteServiceAccount deletes service account owned by currently authenticated User, along with its API keys.
+func (svc *service) DeleteServiceAccount(ctx context.Context, serviceAccountUUID string) error {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return errors.New("DeleteServiceAccount failed to ctx.Value user UUID from ctx")
+	}
+
+	_, err := uuid.Parse(serviceAccountUUID)
+	if err != nil {
+		return fmt.Errorf("DeleteServiceAccount failed to uuid.Parse service account UUID %s, %w: %w", serviceAccountUUID, errutils.ErrServiceAccountNotFound, err)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("DeleteServiceAccount failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	err = svc.repository.DeleteServiceAccount(dbConn, serviceAccountUUID, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("DeleteServiceAccount failed to svc.repository.DeleteServiceAccount, %w: %w", errutils.ErrServiceAccountNotFound, err)
+		default:
+			err = fmt.Errorf("DeleteServiceAccount failed to svc.repository.DeleteServiceAccount: %w", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetUser retrieves any User by UUID, active or not, without checking permissions, for use by superusers.
+func (svc *service) GetUser(ctx context.Context, userUUID string) (*User, error) {
+	_, err := uuid.Parse(userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("GetUser failed to uuid.Parse user UUID %s, %w: %w", userUUID, errutils.ErrUserNotFound, err)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetUser failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	user, err := svc.repository.GetAnyUserByUUID(dbConn, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("GetUser failed to svc.repository.GetAnyUserByUUID, %w: %w", errutils.ErrUserNotFound, err)
+		default:
+			err = fmt.Errorf("GetUser failed to svc.repository.GetAnyUserByUUID: %w", err)
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ListUsers lists all Users, active or not, whose email, first name, or last name contain a given search string.
+// Permissions are not checked, since only superusers can list other Users.
+func (svc *service) ListUsers(ctx context.Context, search string) ([]*User, error) {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListUsers failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	users, err := svc.repository.ListUsers(dbConn, search)
+	if err != nil {
+		return nil, fmt.Errorf("ListUsers failed to svc.repository.ListUsers: %w", err)
+	}
+
+	return users, nil
+}
+
+// UpdateUserStatus lets a superuser activate or deactivate any User, and grant or revoke their superuser status.
+// Currently authenticated User cannot update their own status, so that superusers cannot lock themselves out.
+// Deactivating a User revokes all of the User's sessions.
+func (svc *service) UpdateUserStatus(ctx context.Context, userUUID string, isActive *bool, isSuperUser *bool) (*User, error) {
+	currentUserUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, errors.New("UpdateUserStatus failed to ctx.Value user UUID from ctx")
+	}
+
+	_, err := uuid.Parse(userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateUserStatus failed to uuid.Parse user UUID %s, %w: %w", userUUID, errutils.ErrUserNotFound, err)
+	}
+
+	if userUUID == currentUserUUID {
+		return nil, fmt.Errorf("UpdateUserStatus failed, user %s cannot update own status: %w", userUUID, errutils.ErrCannotUpdateOwnStatus)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateUserStatus failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	user, err := svc.repository.UpdateUserStatus(dbConn, userUUID, isActive, isSuperUser)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("UpdateUserStatus failed to svc.repository.UpdateUserStatus, %w: %w", errutils.ErrUserNotFound, err)
+		default:
+			err = fmt.Errorf("UpdateUserStatus failed to svc.repository.UpdateUserStatus: %w", err)
+		}
+		return nil, err
+	}
+
+	if !user.IsActive {
+		err = svc.repository.RevokeUserSessions(dbConn, user.UUID)
+		if err != nil {
+			return nil, fmt.Errorf("UpdateUserStatus failed to svc.repository.RevokeUserSessions: %w", err)
+		}
+	}
+
+	return user, nil
+}
+
+// ForcePasswordReset replaces active User's password with a random password on behalf of a superuser,
+// revokes all of the User's sessions, and sends them a password reset email.
+func (svc *service) ForcePasswordReset(ctx context.Context, wg *sync.WaitGroup, userUUID string) error {
+	_, err := uuid.Parse(userUUID)
+	if err != nil {
+		return fmt.Errorf("ForcePasswordReset failed to uuid.Parse user UUID %s, %w: %w", userUUID, errutils.ErrUserNotFound, err)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("ForcePasswordReset failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	user, err := svc.repository.GetUserByUUID(dbConn, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("ForcePasswordReset failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrUserNotFound, err)
+		default:
+			err = fmt.Errorf("ForcePasswordReset failed to svc.repository.GetUserByUUID: %w", err)
+		}
+		return err
+	}
+
+	password, err := utils.GenerateRandomString(32, true, true, true)
+	if err != nil {
+		return fmt.Errorf("ForcePasswordReset failed to utils.GenerateRandomString: %w", err)
+	}
+
+	hashedPassword, err := svc.passwordHasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("ForcePasswordReset failed to svc.passwordHasher.Hash: %w", err)
+	}
+
+	// the password is replaced and sessions are revoked in a single transaction,
+	// so that a failure part way through cannot leave sessions open after the password is replaced
+	tx, err := dbConn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ForcePasswordReset failed to dbConn.Begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = svc.repository.UpdateUserPassword(tx, user.UUID, hashedPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("ForcePasswordReset failed to svc.repository.UpdateUserPassword, %w: %w", errutils.ErrUserNotFound, err)
+		default:
+			err = fmt.Errorf("ForcePasswordReset failed to svc.repository.UpdateUserPassword: %w", err)
+		}
+		return err
+	}
+	user.Password = hashedPassword
+
+	err = svc.repository.RevokeUserSessions(tx, user.UUID)
+	if err != nil {
+		return fmt.Errorf("ForcePasswordReset failed to svc.repository.RevokeUserSessions: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("ForcePasswordReset failed to tx.Commit: %w", err)
+	}
+
+	err = svc.issuePasswordReset(dbConn, wg, user)
+	if err != nil {
+		return fmt.Errorf("ForcePasswordReset failed to svc.issuePasswordReset: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeUserCredentials lets a superuser revoke all sessions of any User, active or not,
+// and delete all of their API keys, including API keys of their service accounts.
+func (svc *service) RevokeUserCredentials(ctx context.Context, userUUID string) error {
+	_, err := uuid.Parse(userUUID)
+	if err != nil {
+		return fmt.Errorf("RevokeUserCredentials failed to uuid.Parse user UUID %s, %w: %w", userUUID, errutils.ErrUserNotFound, err)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("RevokeUserCredentials failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	_, err = svc.repository.GetAnyUserByUUID(dbConn, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("RevokeUserCredentials failed to svc.repository.GetAnyUserByUUID, %w: %w", errutils.ErrUserNotFound, err)
+		default:
+			err = fmt.Errorf("RevokeUserCredentials failed to svc.repository.GetAnyUserByUUID: %w", err)
+		}
+		return err
+	}
+
+	err = svc.repository.RevokeUserSessions(dbConn, userUUID)
+	if err != nil {
+		return fmt.Errorf("RevokeUserCredentials failed to svc.repository.RevokeUserSessions: %w", err)
+	}
+
+	_, err = svc.repository.DeleteUserAPIKeys(dbConn, userUUID)
+	if err != nil {
+		return fmt.Errorf("RevokeUserCredentials failed to svc.repository.DeleteUserAPIKeys: %w", err)
+	}
+
+	return nil
+}
+
+// ImpersonateUser creates a short-lived access JWT for currently authenticated superuser to act as a given active User.
+// The JWT is tied to the superuser's current session, so it is revoked when that session is revoked.
+// Superusers cannot impersonate themselves or other superusers,
+// but whether the currently authenticated User is a superuser is left to the caller to check.
+func (svc *service) ImpersonateUser(ctx context.Context, userUUID string, readOnly bool) (string, *api.AuthJWTClaims, error) {
+	actorUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return "", nil, errors.New("ImpersonateUser failed to ctx.Value user UUID from ctx")
+	}
+
+	sessionID, ok := ctx.Value(AuthContextKeySessionID).(string)
+	if !ok {
+		return "", nil, errors.New("ImpersonateUser failed to ctx.Value session ID from ctx")
+	}
+
+	_, err := uuid.Parse(userUUID)
+	if err != nil {
+		return "", nil, fmt.Errorf("ImpersonateUser failed to uuid.Parse user UUID %s, %w: %w", userUUID, errutils.ErrUserNotFound, err)
+	}
+
+	if userUUID == actorUUID {
+		return "", nil, fmt.Errorf("ImpersonateUser failed, user %s cannot impersonate self: %w", userUUID, errutils.ErrCannotImpersonateUser)
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("ImpersonateUser failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	user, err := svc.repository.GetUserByUUID(dbConn, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("ImpersonateUser failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrUserNotFound, err)
+		default:
+			err = fmt.Errorf("ImpersonateUser failed to svc.repository.GetUserByUUID: %w", err)
+		}
+		return "", nil, err
+	}
+
+	if user.IsSuperUser {
+		return "", nil, fmt.Errorf("ImpersonateUser failed, user %s is a superuser: %w", userUUID, errutils.ErrCannotImpersonateUser)
+	}
+
+	token, claims, err := createImpersonationJWT(user.UUID, actorUUID, sessionID, readOnly, svc.keySet, impersonationLifetime)
+	if err != nil {
+		return "", nil, fmt.Errorf("ImpersonateUser failed to createImpersonationJWT: %w", err)
+	}
+
+	return token, claims, nil
+}
+
+// PurgeExpiredJWTs deletes records of expired single-use JWTs, and returns the number of purged records.
+func (svc *service) PurgeExpiredJWTs(ctx context.Context) (int64, error) {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeExpiredJWTs failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	count, err := svc.repository.DeleteExpiredIssuedJWTs(dbConn)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeExpiredJWTs failed to svc.repository.DeleteExpiredIssuedJWTs: %w", err)
+	}
+
+	return count, nil
+}
+
+// PurgeExpiredSessions deletes expired sessions, and returns the number of purged sessions.
+func (svc *service) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeExpiredSessions failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	count, err := svc.repository.DeleteExpiredSessions(dbConn)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeExpiredSessions failed to svc.repository.DeleteExpiredSessions: %w", err)
+	}
+
+	return count, nil
+}
+
+// PurgeExpiredOIDCAuthRequests deletes OpenID Connect authorization requests that have expired,
+// and returns the number of deleted authorization requests.
+func (svc *service) PurgeExpiredOIDCAuthRequests(ctx context.Context) (int64, error) {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeExpiredOIDCAuthRequests failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	count, err := svc.repository.DeleteExpiredOIDCAuthRequests(dbConn)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeExpiredOIDCAuthRequests failed to svc.repository.DeleteExpiredOIDCAuthRequests: %w", err)
+	}
+
+	return count, nil
+}
+
+// PurgeExpiredLoginThrottles deletes records of failed login attempts that are no longer locked or counted,
+// and returns the number of purged records.
+func (svc *service) PurgeExpiredLoginThrottles(ctx context.Context) (int64, error) {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeExpiredLoginThrottles failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	count, err := svc.repository.DeleteExpiredLoginThrottles(dbConn, time.Now().UTC().Add(-loginFailureWindow))
+	if err != nil {
+		return 0, fmt.Errorf("PurgeExpiredLoginThrottles failed to svc.repository.DeleteExpiredLoginThrottles: %w", err)
+	}
+
+	return count, nil
+}
+
+// verifySecondFactor verifies TOTP code or recovery code of User with two-factor authentication enabled.
+// TOTP codes are rejected if a code of the same or a later time step has already been used,
+// and recovery codes can only be used once.
+func (svc *service) verifySecondFactor(dbConn *pgxpool.Conn, userUUID string, code string) error {
+	device, err := svc.repository.GetTOTPDevice(dbConn, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("verifySecondFactor failed to svc.repository.GetTOTPDevice, %w: %w", errutils.ErrTOTPNotEnabled, err)
+		default:
+			err = fmt.Errorf("verifySecondFactor failed to svc.repository.GetTOTPDevice: %w", err)
+		}
+		return err
+	}
+
+	if !device.ConfirmedAt.Valid {
+		return fmt.Errorf("verifySecondFactor failed, totp device of user %s is unconfirmed: %w", userUUID, errutils.ErrTOTPNotEnabled)
+	}
+
+	if isTOTPCode(code) {
+		step, ok := totp.ValidateCode(device.Secret, code, time.Now().UTC(), totpSkew)
+		if !ok {
+			return fmt.Errorf("verifySecondFactor failed to totp.ValidateCode: %w", errutils.ErrInvalidTOTPCode)
+		}
+
+		err = svc.repository.UseTOTPDeviceStep(dbConn, userUUID, step)
+		if err != nil {
+			switch {
+			case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+				err = fmt.Errorf("verifySecondFactor failed to svc.repository.UseTOTPDeviceStep, %w: %w", errutils.ErrInvalidTOTPCode, err)
+			default:
+				err = fmt.Errorf("verifySecondFactor failed to svc.repository.UseTOTPDeviceStep: %w", err)
+			}
+			return err
+		}
+
+		return nil
+	}
+
+	err = svc.repository.UseRecoveryCode(dbConn, userUUID, hashRecoveryCode(code, DigestPepper(svc.config)))
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("verifySecondFactor failed to svc.repository.UseRecoveryCode, %w: %w", errutils.ErrInvalidTOTPCode, err)
+		default:
+			err = fmt.Errorf("verifySecondFactor failed to svc.repository.UseRecoveryCode: %w", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// EnrollTOTP creates new unconfirmed TOTP device for currently authenticated User,
+// and returns its secret and otpauth URI.
+// Two-factor authentication is not enabled until the device is confirmed using ConfirmTOTP.
+func (svc *service) EnrollTOTP(ctx context.Context) (string, string, error) {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return "", "", errors.New("EnrollTOTP failed to ctx.Value user UUID from ctx")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("EnrollTOTP failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	user, err := svc.repository.GetUserByUUID(dbConn, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("EnrollTOTP failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrUserNotFound, err)
+		default:
+			err = fmt.Errorf("EnrollTOTP failed to svc.repository.GetUserByUUID: %w", err)
+		}
+		return "", "", err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("EnrollTOTP failed to totp.GenerateSecret: %w", err)
+	}
+
+	_, err = svc.repository.UpsertTOTPDevice(dbConn, user.UUID, secret)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("EnrollTOTP failed to svc.repository.UpsertTOTPDevice, %w: %w", errutils.ErrTOTPAlreadyEnabled, err)
+		default:
+			err = fmt.Errorf("EnrollTOTP failed to svc.repository.UpsertTOTPDevice: %w", err)
+		}
+		return "", "", err
+	}
+
+	return secret, totp.BuildURI(totpIssuer, user.Email, secret), nil
+}
+
+// ConfirmTOTP confirms unconfirmed TOTP device of currently authenticated User using a code generated from it,
+// enabling two-factor authentication, and returns newly created recovery codes.
+// Incorrect codes count towards the same account and IP address lockouts as incorrect passwords.
+func (svc *service) ConfirmTOTP(ctx context.Context, code string, ipAddress string) ([]string, error) {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, errors.New("ConfirmTOTP failed to ctx.Value user UUID from ctx")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ConfirmTOTP failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	device, err := svc.repository.GetTOTPDevice(dbConn, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("ConfirmTOTP failed to svc.repository.GetTOTPDevice, %w: %w", errutils.ErrTOTPNotEnabled, err)
+		default:
+			err = fmt.Errorf("ConfirmTOTP failed to svc.repository.GetTOTPDevice: %w", err)
+		}
+		return nil, err
+	}
+
+	if device.ConfirmedAt.Valid {
+		return nil, fmt.Errorf("ConfirmTOTP failed, totp device of user %s is already confirmed: %w", userUUID, errutils.ErrTOTPAlreadyEnabled)
+	}
+
+	user, err := svc.repository.GetUserByUUID(dbConn, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("ConfirmTOTP failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrUserNotFound, err)
+		default:
+			err = fmt.Errorf("ConfirmTOTP failed to svc.repository.GetUserByUUID: %w", err)
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	accountIdentifier := loginAccountIdentifier(user.Email)
+
+	err = svc.checkLoginThrottles(dbConn, accountIdentifier, ipAddress, now)
+	if err != nil {
+		return nil, fmt.Errorf("ConfirmTOTP failed to svc.checkLoginThrottles: %w", err)
+	}
+
+	step, ok := totp.ValidateCode(device.Secret, code, now, totpSkew)
+	if !ok {
+		_, err = svc.recordLoginFailures(dbConn, accountIdentifier, ipAddress, now)
+		if err != nil {
+			return nil, fmt.Errorf("ConfirmTOTP failed to svc.recordLoginFailures: %w", err)
+		}
+
+		return nil, fmt.Errorf("ConfirmTOTP failed to totp.ValidateCode: %w", errutils.ErrInvalidTOTPCode)
+	}
+
+	recoveryCodes, err := createRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("ConfirmTOTP failed to createRecoveryCodes: %w", err)
+	}
+
+	hashedCodes := make([]string, len(recoveryCodes))
+	for i, recoveryCode := range recoveryCodes {
+		hashedCodes[i] = hashRecoveryCode(recoveryCode, DigestPepper(svc.config))
+	}
+
+	err = svc.repository.DeleteRecoveryCodes(dbConn, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ConfirmTOTP failed to svc.repository.DeleteRecoveryCodes: %w", err)
+	}
+
+	err = svc.repository.CreateRecoveryCodes(dbConn, userUUID, hashedCodes)
+	if err != nil {
+		return nil, fmt.Errorf("ConfirmTOTP failed to svc.repository.CreateRecoveryCodes: %w", err)
+	}
+
+	err = svc.repository.ConfirmTOTPDevice(dbConn, userUUID, step)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("ConfirmTOTP failed to svc.repository.ConfirmTOTPDevice, %w: %w", errutils.ErrInvalidTOTPCode, err)
+		default:
+			err = fmt.Errorf("ConfirmTOTP failed to svc.repository.ConfirmTOTPDevice: %w", err)
+		}
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// DisableTOTP disables two-factor authentication for currently authenticated User
+// after verifying their password and a TOTP code or recovery code,
+// deleting their TOTP device and recovery codes.
+// Incorrect passwords and codes count towards the same account and IP address lockouts as failed logins.
+func (svc *service) DisableTOTP(ctx context.Context, password string, code string, ipAddress string) error {
+	userUUID, ok := ctx.Value(AuthContextKeyUserUUID).(string)
+	if !ok {
+		return errors.New("DisableTOTP failed to ctx.Value user UUID from ctx")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("DisableTOTP failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	user, err := svc.repository.GetUserByUUID(dbConn, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("DisableTOTP failed to svc.repository.GetUserByUUID, %w: %w", errutils.ErrUserNotFound, err)
+		default:
+			err = fmt.Errorf("DisableTOTP failed to svc.repository.GetUserByUUID: %w", err)
+		}
+		return err
+	}
+
+	now := time.Now().UTC()
+	accountIdentifier := loginAccountIdentifier(user.Email)
+
+	err = svc.checkLoginThrottles(dbConn, accountIdentifier, ipAddress, now)
+	if err != nil {
+		return fmt.Errorf("DisableTOTP failed to svc.checkLoginThrottles: %w", err)
+	}
+
+	err = svc.passwordHasher.Verify(user.Password, password)
+	if err != nil {
+		_, recordErr := svc.recordLoginFailures(dbConn, accountIdentifier, ipAddress, now)
+		if recordErr != nil {
+			return fmt.Errorf("DisableTOTP failed to svc.recordLoginFailures: %w", recordErr)
+		}
+
+		return fmt.Errorf("DisableTOTP failed to svc.passwordHasher.Verify, %w: %w", errutils.ErrInvalidCredentials, err)
+	}
+
+	err = svc.verifySecondFactor(dbConn, user.UUID, code)
+	if err != nil {
+		if errors.Is(err, errutils.ErrInvalidTOTPCode) {
+			_, recordErr := svc.recordLoginFailures(dbConn, accountIdentifier, ipAddress, now)
+			if recordErr != nil {
+				return fmt.Errorf("DisableTOTP failed to svc.recordLoginFailures: %w", recordErr)
+			}
+		}
+
+		return fmt.Errorf("DisableTOTP failed to svc.verifySecondFactor: %w", err)
+	}
+
+	err = svc.repository.DeleteTOTPDevice(dbConn, user.UUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
+			err = fmt.Errorf("DisableTOTP failed to svc.repository.DeleteTOTPDevice, %w: %w", errutils.ErrTOTPNotEnabled, err)
+		default:
+			err = fmt.Errorf("DisableTOTP failed to svc.repository.DeleteTOTPDevice: %w", err)
+		}
+		return err
+	}
+
+	err = svc.repository.DeleteRecoveryCodes(dbConn, user.UUID)
+	if err != nil {
+		return fmt.Errorf("DisableTOTP failed to svc.repository.DeleteRecoveryCodes: %w", err)
+	}
+
 	return nil
 }
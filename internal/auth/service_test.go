@@ -3,6 +3,7 @@ package auth_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"regexp"
 	"sort"
 	"strings"
@@ -17,7 +18,9 @@ import (
 	"github.com/alvii147/flagger-api/pkg/api"
 	"github.com/alvii147/flagger-api/pkg/errutils"
 	"github.com/alvii147/flagger-api/pkg/mailclient"
+	"github.com/alvii147/flagger-api/pkg/passhash"
 	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/alvii147/flagger-api/pkg/totp"
 	"github.com/alvii147/flagger-api/pkg/utils"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
@@ -37,7 +40,8 @@ func TestServiceCreateUserSuccess(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
 	email := testkit.GenerateFakeEmail()
 	password := testkit.GenerateFakePassword()
@@ -58,7 +62,7 @@ func TestServiceCreateUserSuccess(t *testing.T) {
 
 	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), user.CreatedAt)
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	err = auth.NewPasswordHasher(config).Verify(user.Password, password)
 	require.NoError(t, err)
 
 	wg.Wait()
@@ -73,6 +77,19 @@ func TestServiceCreateUserSuccess(t *testing.T) {
 	mailMessage := string(lastMail.Message)
 	require.Contains(t, mailMessage, "Welcome to Flagger!")
 	require.Contains(t, mailMessage, "Flagger - Activate Your Account")
+
+	pattern := fmt.Sprintf(config.FrontendBaseURL+config.FrontendActivationRoute, `(\S+)`)
+	r, err := regexp.Compile(pattern)
+	require.NoError(t, err)
+
+	matches := r.FindStringSubmatch(mailMessage)
+	require.Len(t, matches, 2)
+
+	err = svc.ActivateUser(context.Background(), matches[1])
+	require.NoError(t, err)
+
+	err = svc.ActivateUser(context.Background(), matches[1])
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
 }
 
 func TestServiceCreateUserEmailExists(t *testing.T) {
@@ -90,7 +107,8 @@ func TestServiceCreateUserEmailExists(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
 	mailCount := len(mailClient.Logs)
 
@@ -108,6 +126,68 @@ func TestServiceCreateUserEmailExists(t *testing.T) {
 	require.Len(t, mailClient.Logs, mailCount)
 }
 
+func TestServiceCreateUserWeakPassword(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	firstName := testkit.MustGenerateRandomString(8, true, true, false)
+	lastName := testkit.MustGenerateRandomString(8, true, true, false)
+
+	testcases := []struct {
+		name     string
+		password string
+	}{
+		{
+			name:     "Too short",
+			password: "D34dB33f",
+		},
+		{
+			name:     "Too few character classes",
+			password: "deadbeefcoffee",
+		},
+		{
+			name:     "Contains name",
+			password: firstName + "D34dB33f",
+		},
+		{
+			name:     "Breached password",
+			password: "Password1234!",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			email := testkit.GenerateFakeEmail()
+
+			var wg sync.WaitGroup
+			_, err := svc.CreateUser(context.Background(), &wg, email, testcase.password, firstName, lastName)
+			require.ErrorIs(t, err, errutils.ErrWeakPassword)
+
+			var validationErr *errutils.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.NotEmpty(t, validationErr.Failures["password"])
+
+			_, err = repo.GetUserByEmail(dbConn, email)
+			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+		})
+	}
+
+	require.Empty(t, mailClient.Logs)
+}
+
 func TestServiceCreateUserEmailSendFails(t *testing.T) {
 	t.Parallel()
 
@@ -121,7 +201,8 @@ func TestServiceCreateUserEmailSendFails(t *testing.T) {
 	_, bufErr, logger := testkit.CreateTestLogger()
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, failingMailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, failingMailClient, tmplManager, repo)
 
 	email := testkit.GenerateFakeEmail()
 	password := testkit.GenerateFakePassword()
@@ -140,7 +221,7 @@ func TestServiceCreateUserEmailSendFails(t *testing.T) {
 
 	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), user.CreatedAt)
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	err = auth.NewPasswordHasher(config).Verify(user.Password, password)
 	require.NoError(t, err)
 
 	wg.Wait()
@@ -171,21 +252,10 @@ func TestServiceActivateUserSuccess(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
-	now := time.Now().UTC()
-	jti := uuid.NewString()
-	token, err := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		&api.ActivationJWTClaims{
-			Subject:   user.UUID,
-			TokenType: string(auth.JWTTypeActivation),
-			IssuedAt:  utils.JSONTimeStamp(now),
-			ExpiresAt: utils.JSONTimeStamp(now.Add(time.Hour)),
-			JWTID:     jti,
-		},
-	).SignedString([]byte(config.SecretKey))
-	require.NoError(t, err)
+	token := testkitinternal.MustCreateUserActivationJWT(t, user.UUID)
 
 	err = svc.ActivateUser(context.Background(), token)
 	require.NoError(t, err)
@@ -199,6 +269,9 @@ func TestServiceActivateUserSuccess(t *testing.T) {
 	require.Equal(t, user.LastName, activatedUser.LastName)
 	require.True(t, activatedUser.IsActive)
 	require.Equal(t, user.IsSuperUser, activatedUser.IsSuperUser)
+
+	err = svc.ActivateUser(context.Background(), token)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
 }
 
 func TestServiceActivateUserError(t *testing.T) {
@@ -212,7 +285,8 @@ func TestServiceActivateUserError(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
 	now := time.Now().UTC()
 	invalidToken := "ed0730889507fdb8549acfcd31548ee5"
@@ -227,18 +301,14 @@ func TestServiceActivateUserError(t *testing.T) {
 		},
 	).SignedString([]byte(config.SecretKey))
 	require.NoError(t, err)
-	validToken, err := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		&api.ActivationJWTClaims{
-			Subject:   uuid.NewString(),
-			TokenType: string(auth.JWTTypeActivation),
-			IssuedAt:  utils.JSONTimeStamp(now),
-			ExpiresAt: utils.JSONTimeStamp(now.Add(time.Hour)),
-			JWTID:     uuid.NewString(),
-		},
-	).SignedString([]byte(config.SecretKey))
+	unrecordedToken, _, err := auth.CreateActivationJWT(uuid.NewString(), config.SecretKey, time.Hour)
 	require.NoError(t, err)
 
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	activeUserToken := testkitinternal.MustCreateUserActivationJWT(t, activeUser.UUID)
+
 	testcases := []struct {
 		name    string
 		token   string
@@ -255,8 +325,13 @@ func TestServiceActivateUserError(t *testing.T) {
 			wantErr: errutils.ErrInvalidToken,
 		},
 		{
-			name:    "Valid token with invalid user UUID",
-			token:   validToken,
+			name:    "Valid token that was never issued",
+			token:   unrecordedToken,
+			wantErr: errutils.ErrInvalidToken,
+		},
+		{
+			name:    "Valid token of already active user",
+			token:   activeUserToken,
 			wantErr: errutils.ErrUserNotFound,
 		},
 	}
@@ -271,6 +346,129 @@ func TestServiceActivateUserError(t *testing.T) {
 	}
 }
 
+func TestServiceResendActivationSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	now := time.Now().UTC()
+	supersededJTI := uuid.NewString()
+	supersededToken, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.ActivationJWTClaims{
+			Subject:   user.UUID,
+			TokenType: string(auth.JWTTypeActivation),
+			IssuedAt:  utils.JSONTimeStamp(now.Add(-time.Hour)),
+			ExpiresAt: utils.JSONTimeStamp(now.Add(time.Hour)),
+			JWTID:     supersededJTI,
+		},
+	).SignedString([]byte(config.SecretKey))
+	require.NoError(t, err)
+
+	_, err = repo.CreateIssuedJWT(dbConn, &auth.IssuedJWT{
+		JTI:       supersededJTI,
+		UserUUID:  user.UUID,
+		TokenType: string(auth.JWTTypeActivation),
+		CreatedAt: now.Add(-time.Hour),
+		ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	err = svc.ResendActivation(context.Background(), &wg, user.Email)
+	require.NoError(t, err)
+
+	err = svc.ResendActivation(context.Background(), &wg, user.Email)
+	require.NoError(t, err)
+
+	wg.Wait()
+
+	require.Len(t, mailClient.Logs, 1)
+
+	lastMail := mailClient.Logs[len(mailClient.Logs)-1]
+	require.Equal(t, []string{user.Email}, lastMail.To)
+	require.Equal(t, "Welcome to Flagger!", lastMail.Subject)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), lastMail.SentAt)
+
+	mailMessage := string(lastMail.Message)
+	require.Contains(t, mailMessage, "Flagger - Activate Your Account")
+
+	pattern := fmt.Sprintf(config.FrontendBaseURL+config.FrontendActivationRoute, `(\S+)`)
+	r, err := regexp.Compile(pattern)
+	require.NoError(t, err)
+
+	matches := r.FindStringSubmatch(mailMessage)
+	require.Len(t, matches, 2)
+
+	err = svc.ActivateUser(context.Background(), supersededToken)
+	require.ErrorIs(t, err, errutils.ErrUserNotFound)
+
+	err = svc.ActivateUser(context.Background(), matches[1])
+	require.NoError(t, err)
+}
+
+func TestServiceResendActivationNoInactiveUser(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+
+	testcases := []struct {
+		name  string
+		email string
+	}{
+		{
+			name:  "No user",
+			email: testkit.GenerateFakeEmail(),
+		},
+		{
+			name:  "Already active user",
+			email: activeUser.Email,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			mailClient := mailclient.NewInMemClient("support@flagger.com")
+			keySet := testkitinternal.MustCreateKeySet(config)
+			svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+			var wg sync.WaitGroup
+			err := svc.ResendActivation(context.Background(), &wg, testcase.email)
+			require.NoError(t, err)
+
+			wg.Wait()
+
+			require.Len(t, mailClient.Logs, 0)
+		})
+	}
+}
+
 func TestServiceGetCurrentUserSuccess(t *testing.T) {
 	t.Parallel()
 
@@ -286,7 +484,8 @@ func TestServiceGetCurrentUserSuccess(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
 	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
 	currentUser, err := svc.GetCurrentUser(ctx)
@@ -311,7 +510,8 @@ func TestServiceGetCurrentUserError(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
 	testcases := []struct {
 		name    string
@@ -343,10 +543,10 @@ func TestServiceGetCurrentUserError(t *testing.T) {
 	}
 }
 
-func TestServiceCreateJWTSuccess(t *testing.T) {
+func TestServiceRequestPasswordResetSuccess(t *testing.T) {
 	t.Parallel()
 
-	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
 
@@ -358,44 +558,40 @@ func TestServiceCreateJWTSuccess(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
-	accessToken, refreshToken, err := svc.CreateJWT(context.Background(), user.Email, password)
+	var wg sync.WaitGroup
+	err = svc.RequestPasswordReset(context.Background(), &wg, user.Email)
 	require.NoError(t, err)
 
-	accessClaims := &api.AuthJWTClaims{}
-	parsedAccessToken, err := jwt.ParseWithClaims(accessToken, accessClaims, func(t *jwt.Token) (any, error) {
-		return []byte(config.SecretKey), nil
-	})
-	require.NoError(t, err)
+	wg.Wait()
 
-	require.NotNil(t, parsedAccessToken)
-	require.True(t, parsedAccessToken.Valid)
-	require.Equal(t, user.UUID, accessClaims.Subject)
-	require.Equal(t, string(auth.JWTTypeAccess), accessClaims.TokenType)
+	require.Len(t, mailClient.Logs, 1)
 
-	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(accessClaims.IssuedAt))
-	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthAccessLifetime*int64(time.Minute))), time.Time(accessClaims.ExpiresAt))
+	lastMail := mailClient.Logs[len(mailClient.Logs)-1]
+	require.Equal(t, []string{user.Email}, lastMail.To)
+	require.Equal(t, "Reset your Flagger password", lastMail.Subject)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), lastMail.SentAt)
 
-	refreshClaims := &api.AuthJWTClaims{}
-	parsedRefreshToken, err := jwt.ParseWithClaims(refreshToken, refreshClaims, func(t *jwt.Token) (any, error) {
-		return []byte(config.SecretKey), nil
-	})
+	mailMessage := string(lastMail.Message)
+	require.Contains(t, mailMessage, "Flagger - Reset Your Password")
+
+	pattern := fmt.Sprintf(config.FrontendBaseURL+config.FrontendPasswordResetRoute, `(\S+)`)
+	r, err := regexp.Compile(pattern)
 	require.NoError(t, err)
 
-	require.NotNil(t, parsedRefreshToken)
-	require.True(t, parsedRefreshToken.Valid)
-	require.Equal(t, user.UUID, refreshClaims.Subject)
-	require.Equal(t, string(auth.JWTTypeRefresh), refreshClaims.TokenType)
+	matches := r.FindStringSubmatch(mailMessage)
+	require.Len(t, matches, 2)
 
-	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(refreshClaims.IssuedAt))
-	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthRefreshLifetime*int64(time.Minute))), time.Time(refreshClaims.ExpiresAt))
+	err = svc.ResetPassword(context.Background(), matches[1], testkit.GenerateFakePassword())
+	require.NoError(t, err)
 }
 
-func TestServiceCreateJWTError(t *testing.T) {
+func TestServiceRequestPasswordResetCooldown(t *testing.T) {
 	t.Parallel()
 
-	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
 
@@ -407,25 +603,46 @@ func TestServiceCreateJWTError(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		err = svc.RequestPasswordReset(context.Background(), &wg, user.Email)
+		require.NoError(t, err)
+	}
+
+	wg.Wait()
+
+	require.Len(t, mailClient.Logs, 1)
+}
+
+func TestServiceRequestPasswordResetNoActiveUser(t *testing.T) {
+	t.Parallel()
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
 
 	testcases := []struct {
-		name     string
-		email    string
-		password string
-		wantErr  error
+		name  string
+		email string
 	}{
 		{
-			name:     "Incorrect email",
-			email:    testkit.GenerateFakeEmail(),
-			password: password,
-			wantErr:  errutils.ErrInvalidCredentials,
+			name:  "No user",
+			email: testkit.GenerateFakeEmail(),
 		},
 		{
-			name:     "Incorrect password",
-			email:    user.Email,
-			password: testkit.GenerateFakePassword(),
-			wantErr:  errutils.ErrInvalidCredentials,
+			name:  "No active user",
+			email: inactiveUser.Email,
 		},
 	}
 
@@ -433,104 +650,186 @@ func TestServiceCreateJWTError(t *testing.T) {
 		t.Run(testcase.name, func(t *testing.T) {
 			t.Parallel()
 
-			_, _, err := svc.CreateJWT(context.Background(), testcase.email, testcase.password)
-			require.ErrorIs(t, err, testcase.wantErr)
+			mailClient := mailclient.NewInMemClient("support@flagger.com")
+			keySet := testkitinternal.MustCreateKeySet(config)
+			svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+			var wg sync.WaitGroup
+			err := svc.RequestPasswordReset(context.Background(), &wg, testcase.email)
+			require.NoError(t, err)
+
+			wg.Wait()
+
+			require.Len(t, mailClient.Logs, 0)
 		})
 	}
 }
 
-func TestServiceRefreshJWTSuccess(t *testing.T) {
+func TestServiceResetPasswordSuccess(t *testing.T) {
 	t.Parallel()
 
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
 	config, err := env.NewConfig()
 	require.NoError(t, err)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	_, _, logger := testkit.CreateTestLogger()
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
-	userUUID := uuid.NewString()
-	_, refreshToken := testkitinternal.MustCreateUserAuthJWTs(userUUID)
+	token := testkitinternal.MustCreateUserPasswordResetJWT(t, user)
 
-	accessToken, err := svc.RefreshJWT(context.Background(), refreshToken)
+	_, err = repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeAccount, auth.LoginAccountIdentifier(user.Email), time.Now().UTC(), time.Now().UTC().Add(-auth.LoginFailureWindow))
 	require.NoError(t, err)
 
-	claims := &api.AuthJWTClaims{}
-	parsedAccessToken, err := jwt.ParseWithClaims(accessToken, claims, func(t *jwt.Token) (any, error) {
-		return []byte(config.SecretKey), nil
-	})
+	newPassword := testkit.GenerateFakePassword()
+	err = svc.ResetPassword(context.Background(), token, newPassword)
 	require.NoError(t, err)
 
-	require.NotNil(t, parsedAccessToken)
-	require.True(t, parsedAccessToken.Valid)
-	require.Equal(t, userUUID, claims.Subject)
-	require.Equal(t, string(auth.JWTTypeAccess), claims.TokenType)
+	_, err = repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, auth.LoginAccountIdentifier(user.Email))
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
 
-	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
-	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthAccessLifetime*int64(time.Minute))), time.Time(claims.ExpiresAt))
+	updatedUser, err := repo.GetUserByUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+
+	err = auth.NewPasswordHasher(config).Verify(updatedUser.Password, newPassword)
+	require.NoError(t, err)
+
+	err = svc.ResetPassword(context.Background(), token, testkit.GenerateFakePassword())
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
 }
 
-func TestServiceRefreshJWTError(t *testing.T) {
+func TestServiceResetPasswordWeakPassword(t *testing.T) {
 	t.Parallel()
 
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
 	config, err := env.NewConfig()
 	require.NoError(t, err)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	_, _, logger := testkit.CreateTestLogger()
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
-	userUUID := uuid.NewString()
-	jti := uuid.NewString()
-	now := time.Now().UTC()
+	token := testkitinternal.MustCreateUserPasswordResetJWT(t, user)
+
+	err = svc.ResetPassword(context.Background(), token, user.LastName+"D34dB33f")
+	require.ErrorIs(t, err, errutils.ErrWeakPassword)
+
+	var validationErr *errutils.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.NotEmpty(t, validationErr.Failures["password"])
+
+	unchangedUser, err := repo.GetUserByUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, user.Password, unchangedUser.Password)
+
+	err = svc.ResetPassword(context.Background(), token, testkit.GenerateFakePassword())
+	require.NoError(t, err)
+}
+
+func TestServiceResetPasswordError(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
 	invalidToken := "ed0730889507fdb8549acfcd31548ee5"
-	expiredToken, err := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		&api.AuthJWTClaims{
-			Subject:   userUUID,
-			TokenType: string(auth.JWTTypeRefresh),
-			IssuedAt:  utils.JSONTimeStamp(now.Add(-2 * time.Hour)),
-			ExpiresAt: utils.JSONTimeStamp(now.Add(-time.Hour)),
-			JWTID:     jti,
+	expiredToken, _, err := auth.CreatePasswordResetJWT(user, config.SecretKey, -time.Hour)
+	require.NoError(t, err)
+	unrecordedToken, _, err := auth.CreatePasswordResetJWT(user, config.SecretKey, time.Hour)
+	require.NoError(t, err)
+	nonExistentUserToken, _, err := auth.CreatePasswordResetJWT(
+		&auth.User{
+			UUID:     uuid.NewString(),
+			Password: user.Password,
 		},
-	).SignedString([]byte(config.SecretKey))
+		config.SecretKey,
+		time.Hour,
+	)
 	require.NoError(t, err)
+	stalePasswordToken, _, err := auth.CreatePasswordResetJWT(
+		&auth.User{
+			UUID:              user.UUID,
+			Password:          user.Password,
+			PasswordChangedAt: pgtype.Timestamp{Time: time.Now().UTC().Add(-time.Hour), Valid: true},
+		},
+		config.SecretKey,
+		time.Hour,
+	)
+	require.NoError(t, err)
+	activationToken := testkitinternal.MustCreateUserActivationJWT(t, user.UUID)
 
 	testcases := []struct {
 		name  string
 		token string
 	}{
 		{
-			name:  "Invalid refresh token",
+			name:  "Invalid token",
 			token: invalidToken,
 		},
 		{
-			name:  "Expired refresh token",
+			name:  "Expired token",
 			token: expiredToken,
 		},
+		{
+			name:  "Activation token",
+			token: activationToken,
+		},
+		{
+			name:  "Token that was never issued",
+			token: unrecordedToken,
+		},
+		{
+			name:  "Token with invalid user UUID",
+			token: nonExistentUserToken,
+		},
+		{
+			name:  "Token issued for previous password",
+			token: stalePasswordToken,
+		},
 	}
 
 	for _, testcase := range testcases {
 		t.Run(testcase.name, func(t *testing.T) {
 			t.Parallel()
 
-			_, err := svc.RefreshJWT(context.Background(), testcase.token)
+			err := svc.ResetPassword(context.Background(), testcase.token, testkit.GenerateFakePassword())
 			require.ErrorIs(t, err, errutils.ErrInvalidToken)
 		})
 	}
 }
 
-func TestServiceCreateAPIKeySuccess(t *testing.T) {
+func TestServiceChangePasswordSuccess(t *testing.T) {
 	t.Parallel()
 
-	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
 
@@ -538,48 +837,49 @@ func TestServiceCreateAPIKeySuccess(t *testing.T) {
 	require.NoError(t, err)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	_, _, logger := testkit.CreateTestLogger()
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	currentAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	otherAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	currentClaims, err := svc.AuthenticateJWT(context.Background(), currentAccessToken)
+	require.NoError(t, err)
 
 	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
-	name := "My API Key"
-	expiresAt := pgtype.Timestamp{
-		Valid: false,
-	}
-	now := time.Now().UTC()
-	apiKey, rawKey, err := svc.CreateAPIKey(ctx, name, expiresAt)
+	ctx = context.WithValue(ctx, auth.AuthContextKeySessionID, currentClaims.SessionID)
+	newPassword := testkit.GenerateFakePassword()
+	err = svc.ChangePassword(ctx, password, newPassword)
 	require.NoError(t, err)
 
-	require.NotNil(t, apiKey)
-	require.Equal(t, apiKey.UserUUID, user.UUID)
-	require.Equal(t, apiKey.Name, apiKey.Name)
-	require.False(t, apiKey.ExpiresAt.Valid)
-	testkit.RequireTimeAlmostEqual(t, now, apiKey.CreatedAt)
+	_, err = svc.AuthenticateJWT(context.Background(), currentAccessToken)
+	require.NoError(t, err)
+
+	_, err = svc.AuthenticateJWT(context.Background(), otherAccessToken)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
 
-	err = bcrypt.CompareHashAndPassword([]byte(apiKey.HashedKey), []byte(rawKey))
+	updatedUser, err := repo.GetUserByUUID(dbConn, user.UUID)
 	require.NoError(t, err)
 
-	r := regexp.MustCompile(`^(\S+)\.(\S+)$`)
-	matches := r.FindStringSubmatch(rawKey)
+	err = auth.NewPasswordHasher(config).Verify(updatedUser.Password, newPassword)
+	require.NoError(t, err)
 
-	require.Len(t, matches, 3)
-	require.Equal(t, apiKey.Prefix, matches[1])
+	require.True(t, updatedUser.PasswordChangedAt.Valid)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), updatedUser.PasswordChangedAt.Time)
 }
 
-func TestServiceCreateAPIKeyAlreadyExists(t *testing.T) {
+func TestServiceChangePasswordError(t *testing.T) {
 	t.Parallel()
 
-	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
 
-	testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
-		k.Name = "My API Key"
-	})
-
 	config, err := env.NewConfig()
 	require.NoError(t, err)
 
@@ -588,163 +888,172 @@ func TestServiceCreateAPIKeyAlreadyExists(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
-	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
-	name := "My API Key"
-	expiresAt := pgtype.Timestamp{
-		Valid: false,
+	testcases := []struct {
+		name            string
+		ctx             context.Context
+		currentPassword string
+		newPassword     string
+		wantErr         error
+	}{
+		{
+			name:            "Incorrect current password",
+			ctx:             context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID),
+			currentPassword: "1nc0rr3ctp455w0rd",
+			newPassword:     testkit.GenerateFakePassword(),
+			wantErr:         errutils.ErrInvalidCredentials,
+		},
+		{
+			name:            "Non-existent user",
+			ctx:             context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, uuid.NewString()),
+			currentPassword: password,
+			newPassword:     testkit.GenerateFakePassword(),
+			wantErr:         errutils.ErrUserNotFound,
+		},
+		{
+			name:            "Weak new password",
+			ctx:             context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID),
+			currentPassword: password,
+			newPassword:     "qwertyuiop123",
+			wantErr:         errutils.ErrWeakPassword,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := svc.ChangePassword(testcase.ctx, testcase.currentPassword, testcase.newPassword)
+			require.ErrorIs(t, err, testcase.wantErr)
+		})
 	}
-	_, _, err = svc.CreateAPIKey(ctx, name, expiresAt)
-	require.ErrorIs(t, err, errutils.ErrAPIKeyAlreadyExists)
 }
 
-func TestServiceListAPIKeys(t *testing.T) {
+func TestServiceRequestEmailChangeSuccess(t *testing.T) {
 	t.Parallel()
 
-	user1, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
-		u.IsActive = true
-	})
-
-	user2, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
 
-	user1Key1, _ := testkitinternal.MustCreateUserAPIKey(t, user1.UUID, func(k *auth.APIKey) {
-		k.Name = "User1APIKey1"
-		k.ExpiresAt = pgtype.Timestamp{
-			Valid: false,
-		}
-	})
-
-	user1Key2, _ := testkitinternal.MustCreateUserAPIKey(t, user1.UUID, func(k *auth.APIKey) {
-		k.Name = "User1APIKey2"
-		k.ExpiresAt = pgtype.Timestamp{
-			Time:  time.Now().UTC().AddDate(0, 0, 1),
-			Valid: true,
-		}
-	})
-
-	user2Key, _ := testkitinternal.MustCreateUserAPIKey(t, user2.UUID, func(k *auth.APIKey) {
-		k.Name = "User2APIKey"
-		k.ExpiresAt = pgtype.Timestamp{
-			Time:  time.Now().UTC().AddDate(0, 1, 0),
-			Valid: true,
-		}
-	})
-
-	user1Keys := []*auth.APIKey{user1Key1, user1Key2}
-	user2Keys := []*auth.APIKey{user2Key}
-
-	sort.Slice(user1Keys, func(i, j int) bool {
-		return user1Keys[i].Prefix < user1Keys[j].Prefix
-	})
-
 	config, err := env.NewConfig()
 	require.NoError(t, err)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	_, _, logger := testkit.CreateTestLogger()
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
-
-	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user1.UUID)
-	fetchedUser1Keys, err := svc.ListAPIKeys(ctx)
-	require.NoError(t, err)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
-	sort.Slice(fetchedUser1Keys, func(i, j int) bool {
-		return fetchedUser1Keys[i].Prefix < fetchedUser1Keys[j].Prefix
-	})
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	newEmail := testkit.GenerateFakeEmail()
 
-	ctx = context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user2.UUID)
-	fetchedUser2Keys, err := svc.ListAPIKeys(ctx)
+	var wg sync.WaitGroup
+	err = svc.RequestEmailChange(ctx, &wg, newEmail, password)
 	require.NoError(t, err)
 
-	require.Len(t, fetchedUser1Keys, 2)
-	require.Len(t, fetchedUser2Keys, 1)
+	wg.Wait()
 
-	for i, fetchedKey := range fetchedUser1Keys {
-		wantKey := user1Keys[i]
-		require.Equal(t, wantKey.ID, fetchedKey.ID)
-		require.Equal(t, wantKey.UserUUID, fetchedKey.UserUUID)
-		require.Equal(t, wantKey.Prefix, fetchedKey.Prefix)
-		require.Equal(t, wantKey.Name, fetchedKey.Name)
-		require.Equal(t, wantKey.CreatedAt, fetchedKey.CreatedAt)
-		require.Equal(t, wantKey.ExpiresAt, fetchedKey.ExpiresAt)
-	}
+	require.Len(t, mailClient.Logs, 2)
 
-	for i, fetchedKey := range fetchedUser2Keys {
-		wantKey := user2Keys[i]
-		require.Equal(t, wantKey.ID, fetchedKey.ID)
-		require.Equal(t, wantKey.UserUUID, fetchedKey.UserUUID)
-		require.Equal(t, wantKey.Prefix, fetchedKey.Prefix)
-		require.Equal(t, wantKey.Name, fetchedKey.Name)
-		require.Equal(t, wantKey.CreatedAt, fetchedKey.CreatedAt)
-		require.Equal(t, wantKey.ExpiresAt, fetchedKey.ExpiresAt)
+	subjectsByRecipient := make(map[string]string)
+	messagesByRecipient := make(map[string]string)
+	for _, mail := range mailClient.Logs {
+		require.Len(t, mail.To, 1)
+		subjectsByRecipient[mail.To[0]] = mail.Subject
+		messagesByRecipient[mail.To[0]] = string(mail.Message)
 	}
+
+	require.Equal(t, "Verify your new Flagger email", subjectsByRecipient[newEmail])
+	require.Contains(t, messagesByRecipient[newEmail], "Flagger - Verify Your New Email")
+
+	require.Equal(t, "Your Flagger email is being changed", subjectsByRecipient[user.Email])
+	require.Contains(t, messagesByRecipient[user.Email], newEmail)
+
+	unchangedUser, err := repo.GetUserByUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, user.Email, unchangedUser.Email)
 }
 
-func TestServiceFindAPIKeySuccess(t *testing.T) {
+func TestServiceRequestEmailChangeError(t *testing.T) {
 	t.Parallel()
 
-	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
-
-	apiKey, rawKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
 
 	config, err := env.NewConfig()
 	require.NoError(t, err)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	_, _, logger := testkit.CreateTestLogger()
-	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
-
-	foundAPIKey, err := svc.FindAPIKey(context.Background(), rawKey)
-	require.NoError(t, err)
 
-	require.Equal(t, apiKey.ID, foundAPIKey.ID)
-	require.Equal(t, apiKey.UserUUID, foundAPIKey.UserUUID)
-	require.Equal(t, apiKey.Prefix, foundAPIKey.Prefix)
-	require.Equal(t, apiKey.Name, foundAPIKey.Name)
-	require.Equal(t, apiKey.CreatedAt, foundAPIKey.CreatedAt)
-	require.Equal(t, apiKey.ExpiresAt, foundAPIKey.ExpiresAt)
-}
+	testcases := []struct {
+		name     string
+		ctx      context.Context
+		email    string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "Incorrect password",
+			ctx:      context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID),
+			email:    testkit.GenerateFakeEmail(),
+			password: "1nc0rr3ctp455w0rd",
+			wantErr:  errutils.ErrInvalidCredentials,
+		},
+		{
+			name:     "Email taken by another user",
+			ctx:      context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID),
+			email:    otherUser.Email,
+			password: password,
+			wantErr:  errutils.ErrUserAlreadyExists,
+		},
+		{
+			name:     "Non-existent user",
+			ctx:      context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, uuid.NewString()),
+			email:    testkit.GenerateFakeEmail(),
+			password: password,
+			wantErr:  errutils.ErrUserNotFound,
+		},
+	}
 
-func TestServiceFindAPIKeyNotFound(t *testing.T) {
-	t.Parallel()
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
 
-	config, err := env.NewConfig()
-	require.NoError(t, err)
+			mailClient := mailclient.NewInMemClient("support@flagger.com")
+			keySet := testkitinternal.MustCreateKeySet(config)
+			svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
-	_, rawKey, _, err := auth.CreateAPIKey(config.HashingCost)
-	require.NoError(t, err)
+			var wg sync.WaitGroup
+			err := svc.RequestEmailChange(testcase.ctx, &wg, testcase.email, testcase.password)
+			require.ErrorIs(t, err, testcase.wantErr)
 
-	dbPool := testkitinternal.RequireCreateDatabasePool(t)
-	_, _, logger := testkit.CreateTestLogger()
-	mailClient := mailclient.NewInMemClient("support@flagger.com")
-	tmplManager := templatesmanager.NewManager()
-	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+			wg.Wait()
 
-	_, err = svc.FindAPIKey(context.Background(), rawKey)
-	require.ErrorIs(t, err, errutils.ErrAPIKeyNotFound)
+			require.Len(t, mailClient.Logs, 0)
+		})
+	}
 }
 
-func TestServiceDeleteAPIKeySuccess(t *testing.T) {
+func TestServiceConfirmEmailChangeSuccess(t *testing.T) {
 	t.Parallel()
 
 	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
 
-	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
-
 	config, err := env.NewConfig()
 	require.NoError(t, err)
 
@@ -754,23 +1063,32 @@ func TestServiceDeleteAPIKeySuccess(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
-	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
-	err = svc.DeleteAPIKey(ctx, apiKey.ID)
+	newEmail := testkit.GenerateFakeEmail()
+	token := testkitinternal.MustCreateUserEmailChangeJWT(t, user, newEmail)
+
+	err = svc.ConfirmEmailChange(context.Background(), token)
 	require.NoError(t, err)
 
-	apiKeys, err := repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
+	updatedUser, err := repo.GetUserByUUID(dbConn, user.UUID)
 	require.NoError(t, err)
-	require.Empty(t, apiKeys)
+	require.Equal(t, newEmail, updatedUser.Email)
+
+	err = svc.ConfirmEmailChange(context.Background(), token)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
 }
 
-func TestServiceDeleteAPIKeyNotFound(t *testing.T) {
+func TestServiceConfirmEmailChangeError(t *testing.T) {
 	t.Parallel()
 
 	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
 
 	config, err := env.NewConfig()
 	require.NoError(t, err)
@@ -780,9 +1098,3680 @@ func TestServiceDeleteAPIKeyNotFound(t *testing.T) {
 	mailClient := mailclient.NewInMemClient("support@flagger.com")
 	tmplManager := templatesmanager.NewManager()
 	repo := auth.NewRepository()
-	svc := auth.NewService(config, dbPool, logger, mailClient, tmplManager, repo)
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
 
-	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
-	err = svc.DeleteAPIKey(ctx, 42)
-	require.ErrorIs(t, err, errutils.ErrAPIKeyNotFound)
+	expiredToken, _, err := auth.CreateEmailChangeJWT(user, testkit.GenerateFakeEmail(), config.SecretKey, -time.Hour)
+	require.NoError(t, err)
+	unrecordedToken, _, err := auth.CreateEmailChangeJWT(user, testkit.GenerateFakeEmail(), config.SecretKey, time.Hour)
+	require.NoError(t, err)
+	staleEmailToken := testkitinternal.MustCreateUserEmailChangeJWT(
+		t,
+		&auth.User{
+			UUID:  user.UUID,
+			Email: testkit.GenerateFakeEmail(),
+		},
+		testkit.GenerateFakeEmail(),
+	)
+	takenEmailToken := testkitinternal.MustCreateUserEmailChangeJWT(t, user, otherUser.Email)
+
+	testcases := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:    "Invalid token",
+			token:   "ed0730889507fdb8549acfcd31548ee5",
+			wantErr: errutils.ErrInvalidToken,
+		},
+		{
+			name:    "Expired token",
+			token:   expiredToken,
+			wantErr: errutils.ErrInvalidToken,
+		},
+		{
+			name:    "Token that was never issued",
+			token:   unrecordedToken,
+			wantErr: errutils.ErrInvalidToken,
+		},
+		{
+			name:    "Token issued for previous email",
+			token:   staleEmailToken,
+			wantErr: errutils.ErrInvalidToken,
+		},
+		{
+			name:    "New email taken by another user",
+			token:   takenEmailToken,
+			wantErr: errutils.ErrUserAlreadyExists,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := svc.ConfirmEmailChange(context.Background(), testcase.token)
+			require.ErrorIs(t, err, testcase.wantErr)
+		})
+	}
+}
+
+func TestServiceCreateJWTSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	accessToken, refreshToken, challengeToken, err := svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+	require.Empty(t, challengeToken)
+
+	accessClaims := &api.AuthJWTClaims{}
+	parsedAccessToken, err := jwt.ParseWithClaims(accessToken, accessClaims, func(t *jwt.Token) (any, error) {
+		return []byte(config.SecretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, parsedAccessToken)
+	require.True(t, parsedAccessToken.Valid)
+	require.Equal(t, user.UUID, accessClaims.Subject)
+	require.Equal(t, string(auth.JWTTypeAccess), accessClaims.TokenType)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(accessClaims.IssuedAt))
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthAccessLifetime*int64(time.Minute))), time.Time(accessClaims.ExpiresAt))
+
+	refreshClaims := &api.AuthJWTClaims{}
+	parsedRefreshToken, err := jwt.ParseWithClaims(refreshToken, refreshClaims, func(t *jwt.Token) (any, error) {
+		return []byte(config.SecretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, parsedRefreshToken)
+	require.True(t, parsedRefreshToken.Valid)
+	require.Equal(t, user.UUID, refreshClaims.Subject)
+	require.Equal(t, string(auth.JWTTypeRefresh), refreshClaims.TokenType)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(refreshClaims.IssuedAt))
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthRefreshLifetime*int64(time.Minute))), time.Time(refreshClaims.ExpiresAt))
+
+	require.NotEmpty(t, accessClaims.SessionID)
+	require.Equal(t, accessClaims.SessionID, refreshClaims.SessionID)
+
+	_, err = svc.AuthenticateJWT(context.Background(), accessToken)
+	require.NoError(t, err)
+
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	session, err := repo.GetActiveSession(dbConn, accessClaims.SessionID, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, refreshClaims.JWTID, session.RefreshJTI)
+	require.Equal(t, "203.0.113.42", session.IPAddress)
+	require.Equal(t, "curl/8.5.0", session.UserAgent)
+}
+
+func TestServiceCreateJWTError(t *testing.T) {
+	t.Parallel()
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	testcases := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "Incorrect email",
+			email:    testkit.GenerateFakeEmail(),
+			password: password,
+			wantErr:  errutils.ErrInvalidCredentials,
+		},
+		{
+			name:     "Incorrect password",
+			email:    user.Email,
+			password: testkit.GenerateFakePassword(),
+			wantErr:  errutils.ErrInvalidCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, _, err := svc.CreateJWT(context.Background(), &sync.WaitGroup{}, testcase.email, testcase.password, "203.0.113.42", "curl/8.5.0")
+			require.ErrorIs(t, err, testcase.wantErr)
+		})
+	}
+}
+
+func TestServiceCreateJWTAccountLockout(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < auth.LoginAccountFailureThreshold; i++ {
+		_, _, _, err := svc.CreateJWT(context.Background(), &wg, user.Email, "wrong"+password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+		require.ErrorIs(t, err, errutils.ErrInvalidCredentials)
+	}
+	wg.Wait()
+
+	require.Len(t, mailClient.Logs, 1)
+	lastMail := mailClient.Logs[len(mailClient.Logs)-1]
+	require.Equal(t, []string{user.Email}, lastMail.To)
+	require.Equal(t, "Your Flagger account was temporarily locked", lastMail.Subject)
+
+	_, _, _, err = svc.CreateJWT(context.Background(), &wg, strings.ToUpper(user.Email), password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrTooManyLoginAttempts)
+
+	var retryAfterErr *errutils.RetryAfterError
+	require.ErrorAs(t, err, &retryAfterErr)
+	require.Greater(t, retryAfterErr.RetryAfter, time.Duration(0))
+	require.LessOrEqual(t, retryAfterErr.RetryAfter, auth.LoginLockoutBaseDuration)
+
+	wg.Wait()
+	require.Len(t, mailClient.Logs, 1)
+}
+
+func TestServiceCreateJWTIPLockout(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	ipAddress := testkit.GenerateFakeIPAddress()
+
+	var wg sync.WaitGroup
+	for i := 0; i < auth.LoginIPFailureThreshold; i++ {
+		_, _, _, err := svc.CreateJWT(context.Background(), &wg, testkit.GenerateFakeEmail(), password, ipAddress, "curl/8.5.0")
+		require.ErrorIs(t, err, errutils.ErrInvalidCredentials)
+	}
+	wg.Wait()
+	require.Empty(t, mailClient.Logs)
+
+	_, _, _, err = svc.CreateJWT(context.Background(), &wg, user.Email, password, ipAddress, "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrTooManyLoginAttempts)
+
+	accessToken, _, _, err := svc.CreateJWT(context.Background(), &wg, user.Email, password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.NoError(t, err)
+	require.NotEmpty(t, accessToken)
+}
+
+func TestServiceCreateJWTSuccessResetsFailures(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < auth.LoginAccountFailureThreshold-1; i++ {
+		_, _, _, err := svc.CreateJWT(context.Background(), &wg, user.Email, "wrong"+password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+		require.ErrorIs(t, err, errutils.ErrInvalidCredentials)
+	}
+
+	_, _, _, err = svc.CreateJWT(context.Background(), &wg, user.Email, password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.NoError(t, err)
+
+	_, err = repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, auth.LoginAccountIdentifier(user.Email))
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+
+	_, _, _, err = svc.CreateJWT(context.Background(), &wg, user.Email, "wrong"+password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrInvalidCredentials)
+
+	wg.Wait()
+	require.Empty(t, mailClient.Logs)
+}
+
+func TestServiceCreateJWTRehashesPassword(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	password := testkit.GenerateFakePassword()
+	legacyHashedPassword, err := passhash.NewBcryptHasher(bcrypt.MinCost).Hash(password)
+	require.NoError(t, err)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.Password = legacyHashedPassword
+	})
+
+	hasher := auth.NewPasswordHasher(config)
+	require.True(t, hasher.NeedsRehash(user.Password))
+
+	passwordResetToken := testkitinternal.MustCreateUserPasswordResetJWT(t, user)
+
+	_, _, _, err = svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, "wrong"+password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrInvalidCredentials)
+
+	unchangedUser, err := repo.GetUserByUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, legacyHashedPassword, unchangedUser.Password)
+
+	_, _, _, err = svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.NoError(t, err)
+
+	rehashedUser, err := repo.GetUserByUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.NotEqual(t, legacyHashedPassword, rehashedUser.Password)
+	require.False(t, hasher.NeedsRehash(rehashedUser.Password))
+	require.Equal(t, user.PasswordChangedAt, rehashedUser.PasswordChangedAt)
+
+	err = hasher.Verify(rehashedUser.Password, password)
+	require.NoError(t, err)
+
+	_, _, _, err = svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.NoError(t, err)
+
+	unchangedUser, err = repo.GetUserByUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, rehashedUser.Password, unchangedUser.Password)
+
+	err = svc.ResetPassword(context.Background(), passwordResetToken, testkit.GenerateFakePassword())
+	require.NoError(t, err)
+}
+
+func TestServiceRefreshJWTSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userUUID := user.UUID
+	_, refreshToken := testkitinternal.MustCreateUserAuthJWTs(t, userUUID)
+
+	oldRefreshClaims, ok := auth.ValidateAuthJWT(refreshToken, auth.JWTTypeRefresh, keySet)
+	require.True(t, ok)
+
+	accessToken, newRefreshToken, err := svc.RefreshJWT(context.Background(), refreshToken, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+
+	claims := &api.AuthJWTClaims{}
+	parsedAccessToken, err := jwt.ParseWithClaims(accessToken, claims, func(t *jwt.Token) (any, error) {
+		return []byte(config.SecretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, parsedAccessToken)
+	require.True(t, parsedAccessToken.Valid)
+	require.Equal(t, userUUID, claims.Subject)
+	require.Equal(t, oldRefreshClaims.SessionID, claims.SessionID)
+	require.Equal(t, string(auth.JWTTypeAccess), claims.TokenType)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthAccessLifetime*int64(time.Minute))), time.Time(claims.ExpiresAt))
+
+	newRefreshClaims, ok := auth.ValidateAuthJWT(newRefreshToken, auth.JWTTypeRefresh, keySet)
+	require.True(t, ok)
+	require.Equal(t, userUUID, newRefreshClaims.Subject)
+	require.Equal(t, oldRefreshClaims.SessionID, newRefreshClaims.SessionID)
+	require.NotEqual(t, oldRefreshClaims.JWTID, newRefreshClaims.JWTID)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthRefreshLifetime*int64(time.Minute))), time.Time(newRefreshClaims.ExpiresAt))
+
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	session, err := repo.GetActiveSession(dbConn, newRefreshClaims.SessionID, userUUID)
+	require.NoError(t, err)
+	require.Equal(t, newRefreshClaims.JWTID, session.RefreshJTI)
+	require.Equal(t, "203.0.113.42", session.IPAddress)
+	require.Equal(t, "curl/8.5.0", session.UserAgent)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), session.LastUsedAt)
+
+	accessToken, newRefreshToken, err = svc.RefreshJWT(context.Background(), newRefreshToken, "198.51.100.7", "Mozilla/5.0 (X11; Linux x86_64)")
+	require.NoError(t, err)
+	require.NotEmpty(t, accessToken)
+	require.NotEmpty(t, newRefreshToken)
+
+	session, err = repo.GetActiveSession(dbConn, newRefreshClaims.SessionID, userUUID)
+	require.NoError(t, err)
+	require.Equal(t, "198.51.100.7", session.IPAddress)
+	require.Equal(t, "Mozilla/5.0 (X11; Linux x86_64)", session.UserAgent)
+}
+
+func TestServiceRefreshJWTReuseRevokesSession(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, refreshToken := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	accessToken, rotatedRefreshToken, err := svc.RefreshJWT(context.Background(), refreshToken, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+
+	_, _, err = svc.RefreshJWT(context.Background(), refreshToken, "203.0.113.42", "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	_, _, err = svc.RefreshJWT(context.Background(), rotatedRefreshToken, "203.0.113.42", "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	_, err = svc.AuthenticateJWT(context.Background(), accessToken)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+}
+
+func TestServiceRefreshJWTError(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	jti := uuid.NewString()
+	now := time.Now().UTC()
+
+	invalidToken := "ed0730889507fdb8549acfcd31548ee5"
+	expiredToken, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.AuthJWTClaims{
+			Subject:   user.UUID,
+			SessionID: uuid.NewString(),
+			TokenType: string(auth.JWTTypeRefresh),
+			IssuedAt:  utils.JSONTimeStamp(now.Add(-2 * time.Hour)),
+			ExpiresAt: utils.JSONTimeStamp(now.Add(-time.Hour)),
+			JWTID:     jti,
+		},
+	).SignedString([]byte(config.SecretKey))
+	require.NoError(t, err)
+
+	nonExistentUserToken, _, err := auth.CreateAuthJWT(
+		uuid.NewString(),
+		uuid.NewString(),
+		auth.JWTTypeRefresh,
+		keySet,
+		time.Minute,
+		time.Hour,
+	)
+	require.NoError(t, err)
+
+	unknownSessionToken, _, err := auth.CreateAuthJWT(
+		user.UUID,
+		uuid.NewString(),
+		auth.JWTTypeRefresh,
+		keySet,
+		time.Minute,
+		time.Hour,
+	)
+	require.NoError(t, err)
+
+	passwordChangedUser, passwordChangedUserPassword := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, staleToken := testkitinternal.MustCreateUserAuthJWTs(t, passwordChangedUser.UUID)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, passwordChangedUser.UUID)
+	err = svc.ChangePassword(ctx, passwordChangedUserPassword, testkit.GenerateFakePassword())
+	require.NoError(t, err)
+
+	loggedOutUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, loggedOutToken := testkitinternal.MustCreateUserAuthJWTs(t, loggedOutUser.UUID)
+
+	ctx = context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, loggedOutUser.UUID)
+	err = svc.LogoutAll(ctx)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name  string
+		token string
+	}{
+		{
+			name:  "Invalid refresh token",
+			token: invalidToken,
+		},
+		{
+			name:  "Expired refresh token",
+			token: expiredToken,
+		},
+		{
+			name:  "Refresh token of non-existent user",
+			token: nonExistentUserToken,
+		},
+		{
+			name:  "Refresh token of unknown session",
+			token: unknownSessionToken,
+		},
+		{
+			name:  "Refresh token issued before password change",
+			token: staleToken,
+		},
+		{
+			name:  "Refresh token of logged out session",
+			token: loggedOutToken,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := svc.RefreshJWT(context.Background(), testcase.token, "203.0.113.42", "curl/8.5.0")
+			require.ErrorIs(t, err, errutils.ErrInvalidToken)
+		})
+	}
+}
+
+func TestServiceListSessions(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	accessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	revokedAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	testkitinternal.MustCreateUserAuthJWTs(t, otherUser.UUID)
+
+	claims, err := svc.AuthenticateJWT(context.Background(), accessToken)
+	require.NoError(t, err)
+
+	revokedClaims, err := svc.AuthenticateJWT(context.Background(), revokedAccessToken)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	err = svc.RevokeSession(ctx, revokedClaims.SessionID)
+	require.NoError(t, err)
+
+	sessions, err := svc.ListSessions(ctx)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, claims.SessionID, sessions[0].ID)
+	require.Equal(t, user.UUID, sessions[0].UserUUID)
+}
+
+func TestServiceRevokeSessionSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	accessToken, refreshToken := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	claims, err := svc.AuthenticateJWT(context.Background(), accessToken)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	err = svc.RevokeSession(ctx, claims.SessionID)
+	require.NoError(t, err)
+
+	_, err = svc.AuthenticateJWT(context.Background(), accessToken)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	_, _, err = svc.RefreshJWT(context.Background(), refreshToken, "203.0.113.42", "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+}
+
+func TestServiceRevokeSessionError(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUserAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, otherUser.UUID)
+
+	otherUserClaims, err := svc.AuthenticateJWT(context.Background(), otherUserAccessToken)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name      string
+		sessionID string
+	}{
+		{
+			name:      "Non-existent session",
+			sessionID: uuid.NewString(),
+		},
+		{
+			name:      "Session of another user",
+			sessionID: otherUserClaims.SessionID,
+		},
+		{
+			name:      "Malformed session ID",
+			sessionID: "n0t-4-uu1d",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+			err := svc.RevokeSession(ctx, testcase.sessionID)
+			require.ErrorIs(t, err, errutils.ErrSessionNotFound)
+		})
+	}
+
+	_, err = svc.AuthenticateJWT(context.Background(), otherUserAccessToken)
+	require.NoError(t, err)
+}
+
+func TestServiceLogoutSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	accessToken, refreshToken := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	otherAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	claims, err := svc.AuthenticateJWT(context.Background(), accessToken)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	ctx = context.WithValue(ctx, auth.AuthContextKeySessionID, claims.SessionID)
+	err = svc.Logout(ctx)
+	require.NoError(t, err)
+
+	_, err = svc.AuthenticateJWT(context.Background(), accessToken)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	_, _, err = svc.RefreshJWT(context.Background(), refreshToken, "203.0.113.42", "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	_, err = svc.AuthenticateJWT(context.Background(), otherAccessToken)
+	require.NoError(t, err)
+
+	err = svc.Logout(ctx)
+	require.ErrorIs(t, err, errutils.ErrSessionNotFound)
+}
+
+func TestServiceLogoutAllSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	accessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	otherSessionAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	otherUserAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, otherUser.UUID)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	err = svc.LogoutAll(ctx)
+	require.NoError(t, err)
+
+	_, err = svc.AuthenticateJWT(context.Background(), accessToken)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	_, err = svc.AuthenticateJWT(context.Background(), otherSessionAccessToken)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	_, err = svc.AuthenticateJWT(context.Background(), otherUserAccessToken)
+	require.NoError(t, err)
+}
+
+func TestServiceCreateJWTTOTPChallenge(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	accessToken, refreshToken, challengeToken, err := svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+	require.Empty(t, accessToken)
+	require.Empty(t, refreshToken)
+
+	claims := &api.TOTPChallengeJWTClaims{}
+	parsedToken, err := jwt.ParseWithClaims(challengeToken, claims, func(t *jwt.Token) (any, error) {
+		return []byte(config.SecretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.True(t, parsedToken.Valid)
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, string(auth.JWTTypeTOTPChallenge), claims.TokenType)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(auth.TOTPChallengeLifetime), time.Time(claims.ExpiresAt))
+
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	sessions, err := repo.ListActiveSessionsByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestServiceRequestMagicLinkSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	var wg sync.WaitGroup
+	err = svc.RequestMagicLink(context.Background(), &wg, user.Email)
+	require.NoError(t, err)
+
+	wg.Wait()
+
+	require.Len(t, mailClient.Logs, 1)
+
+	lastMail := mailClient.Logs[len(mailClient.Logs)-1]
+	require.Equal(t, []string{user.Email}, lastMail.To)
+	require.Equal(t, "Log in to Flagger", lastMail.Subject)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), lastMail.SentAt)
+
+	mailMessage := string(lastMail.Message)
+	require.Contains(t, mailMessage, "Flagger - Log In")
+
+	pattern := fmt.Sprintf(config.FrontendBaseURL+config.FrontendMagicLinkRoute, `(\S+)`)
+	r, err := regexp.Compile(pattern)
+	require.NoError(t, err)
+
+	matches := r.FindStringSubmatch(mailMessage)
+	require.Len(t, matches, 2)
+
+	accessToken, refreshToken, challengeToken, err := svc.VerifyMagicLink(context.Background(), matches[1], "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+	require.NotEmpty(t, accessToken)
+	require.NotEmpty(t, refreshToken)
+	require.Empty(t, challengeToken)
+}
+
+func TestServiceRequestMagicLinkCooldown(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		err = svc.RequestMagicLink(context.Background(), &wg, user.Email)
+		require.NoError(t, err)
+	}
+
+	wg.Wait()
+
+	require.Len(t, mailClient.Logs, 1)
+}
+
+func TestServiceRequestMagicLinkNoActiveUser(t *testing.T) {
+	t.Parallel()
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+
+	testcases := []struct {
+		name  string
+		email string
+	}{
+		{
+			name:  "No user",
+			email: testkit.GenerateFakeEmail(),
+		},
+		{
+			name:  "No active user",
+			email: inactiveUser.Email,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			mailClient := mailclient.NewInMemClient("support@flagger.com")
+			keySet := testkitinternal.MustCreateKeySet(config)
+			svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+			var wg sync.WaitGroup
+			err := svc.RequestMagicLink(context.Background(), &wg, testcase.email)
+			require.NoError(t, err)
+
+			wg.Wait()
+
+			require.Len(t, mailClient.Logs, 0)
+		})
+	}
+}
+
+func TestServiceVerifyMagicLinkSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < auth.LoginAccountFailureThreshold-1; i++ {
+		_, _, _, err := svc.CreateJWT(context.Background(), &wg, user.Email, "wrong"+password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+		require.ErrorIs(t, err, errutils.ErrInvalidCredentials)
+	}
+	wg.Wait()
+
+	token := testkitinternal.MustCreateUserMagicLinkJWT(t, user)
+
+	accessToken, refreshToken, challengeToken, err := svc.VerifyMagicLink(context.Background(), token, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+	require.Empty(t, challengeToken)
+
+	accessClaims := &api.AuthJWTClaims{}
+	parsedAccessToken, err := jwt.ParseWithClaims(accessToken, accessClaims, func(t *jwt.Token) (any, error) {
+		return []byte(config.SecretKey), nil
+	})
+	require.NoError(t, err)
+	require.True(t, parsedAccessToken.Valid)
+	require.Equal(t, user.UUID, accessClaims.Subject)
+	require.Equal(t, string(auth.JWTTypeAccess), accessClaims.TokenType)
+
+	refreshClaims := &api.AuthJWTClaims{}
+	parsedRefreshToken, err := jwt.ParseWithClaims(refreshToken, refreshClaims, func(t *jwt.Token) (any, error) {
+		return []byte(config.SecretKey), nil
+	})
+	require.NoError(t, err)
+	require.True(t, parsedRefreshToken.Valid)
+	require.Equal(t, user.UUID, refreshClaims.Subject)
+	require.Equal(t, string(auth.JWTTypeRefresh), refreshClaims.TokenType)
+
+	session, err := repo.GetActiveSession(dbConn, accessClaims.SessionID, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, refreshClaims.JWTID, session.RefreshJTI)
+	require.Equal(t, "203.0.113.42", session.IPAddress)
+	require.Equal(t, "curl/8.5.0", session.UserAgent)
+
+	_, err = repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, auth.LoginAccountIdentifier(user.Email))
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+
+	_, _, _, err = svc.VerifyMagicLink(context.Background(), token, "203.0.113.42", "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+}
+
+func TestServiceVerifyMagicLinkTOTPChallenge(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	token := testkitinternal.MustCreateUserMagicLinkJWT(t, user)
+
+	accessToken, refreshToken, challengeToken, err := svc.VerifyMagicLink(context.Background(), token, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+	require.Empty(t, accessToken)
+	require.Empty(t, refreshToken)
+
+	claims, ok := auth.ValidateTOTPChallengeJWT(challengeToken, config.SecretKey)
+	require.True(t, ok)
+	require.Equal(t, user.UUID, claims.Subject)
+
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	sessions, err := repo.ListActiveSessionsByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestServiceVerifyMagicLinkError(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	expiredToken, _, err := auth.CreateMagicLinkJWT(user, config.SecretKey, -time.Hour)
+	require.NoError(t, err)
+	unrecordedToken, _, err := auth.CreateMagicLinkJWT(user, config.SecretKey, time.Hour)
+	require.NoError(t, err)
+	staleEmailToken := testkitinternal.MustCreateUserMagicLinkJWT(
+		t,
+		&auth.User{
+			UUID:  user.UUID,
+			Email: testkit.GenerateFakeEmail(),
+		},
+	)
+	inactiveUserToken := testkitinternal.MustCreateUserMagicLinkJWT(t, inactiveUser)
+	tokenOfInvalidType := testkitinternal.MustCreateUserPasswordResetJWT(t, user)
+
+	testcases := []struct {
+		name  string
+		token string
+	}{
+		{
+			name:  "Invalid token",
+			token: "ed0730889507fdb8549acfcd31548ee5",
+		},
+		{
+			name:  "Expired token",
+			token: expiredToken,
+		},
+		{
+			name:  "Token that was never issued",
+			token: unrecordedToken,
+		},
+		{
+			name:  "Token issued for previous email",
+			token: staleEmailToken,
+		},
+		{
+			name:  "Token for inactive user",
+			token: inactiveUserToken,
+		},
+		{
+			name:  "Token of incorrect type",
+			token: tokenOfInvalidType,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, _, err := svc.VerifyMagicLink(context.Background(), testcase.token, "203.0.113.42", "curl/8.5.0")
+			require.ErrorIs(t, err, errutils.ErrInvalidToken)
+		})
+	}
+}
+
+func TestServiceVerifyTOTPChallengeSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	secret, recoveryCodes := testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	now := time.Now().UTC()
+	previousCode, err := totp.GenerateCode(secret, now.Add(-totp.Period))
+	require.NoError(t, err)
+	currentCode, err := totp.GenerateCode(secret, now)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name string
+		code string
+	}{
+		{
+			name: "TOTP code of previous time step",
+			code: previousCode,
+		},
+		{
+			name: "TOTP code of current time step",
+			code: currentCode,
+		},
+		{
+			name: "Recovery code",
+			code: recoveryCodes[0],
+		},
+		{
+			name: "Lower case recovery code without separator",
+			code: strings.ToLower(strings.ReplaceAll(recoveryCodes[1], "-", "")),
+		},
+	}
+
+	for _, testcase := range testcases {
+		_, _, challengeToken, err := svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, "203.0.113.42", "curl/8.5.0")
+		require.NoError(t, err, testcase.name)
+
+		accessToken, refreshToken, err := svc.VerifyTOTPChallenge(context.Background(), challengeToken, testcase.code, "203.0.113.42", "curl/8.5.0")
+		require.NoError(t, err, testcase.name)
+
+		claims, err := svc.AuthenticateJWT(context.Background(), accessToken)
+		require.NoError(t, err, testcase.name)
+		require.Equal(t, user.UUID, claims.Subject)
+
+		_, _, err = svc.RefreshJWT(context.Background(), refreshToken, "203.0.113.42", "curl/8.5.0")
+		require.NoError(t, err, testcase.name)
+	}
+}
+
+func TestServiceVerifyTOTPChallengeAttemptsExhausted(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, recoveryCodes := testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	_, _, challengeToken, err := svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+
+	for i := 0; i < auth.TOTPChallengeMaxAttempts; i++ {
+		_, _, err = svc.VerifyTOTPChallenge(context.Background(), challengeToken, "000000", testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+		require.ErrorIs(t, err, errutils.ErrInvalidTOTPCode)
+	}
+
+	// incorrect codes also lock the account, which is lifted to check the challenge on its own
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	err = repo.DeleteLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, auth.LoginAccountIdentifier(user.Email))
+	require.NoError(t, err)
+
+	_, _, err = svc.VerifyTOTPChallenge(context.Background(), challengeToken, recoveryCodes[0], "203.0.113.42", "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	// the recovery code tried against the exhausted challenge is not used up
+	_, _, challengeToken, err = svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+
+	_, _, err = svc.VerifyTOTPChallenge(context.Background(), challengeToken, recoveryCodes[0], "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+}
+
+func TestServiceVerifyTOTPChallengeError(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	secret, recoveryCodes := testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	userWithoutTOTP, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	createChallenge := func() string {
+		_, _, challengeToken, err := svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, "203.0.113.42", "curl/8.5.0")
+		require.NoError(t, err)
+
+		return challengeToken
+	}
+
+	now := time.Now().UTC()
+	currentCode, err := totp.GenerateCode(secret, now)
+	require.NoError(t, err)
+	previousCode, err := totp.GenerateCode(secret, now.Add(-totp.Period))
+	require.NoError(t, err)
+	staleCode, err := totp.GenerateCode(secret, now.Add(-5*totp.Period))
+	require.NoError(t, err)
+
+	usedChallengeToken := createChallenge()
+	_, _, err = svc.VerifyTOTPChallenge(context.Background(), usedChallengeToken, currentCode, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+
+	_, _, err = svc.VerifyTOTPChallenge(context.Background(), createChallenge(), recoveryCodes[0], "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+
+	challengeTokenWithoutTOTP, issuedJWT, err := auth.CreateTOTPChallengeJWT(userWithoutTOTP.UUID, config.SecretKey, time.Minute)
+	require.NoError(t, err)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, err = repo.CreateIssuedJWT(dbConn, issuedJWT)
+	require.NoError(t, err)
+
+	accessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	testcases := []struct {
+		name           string
+		challengeToken string
+		code           string
+		wantErr        error
+	}{
+		{
+			name:           "Invalid challenge token",
+			challengeToken: "ed0730889507fdb8549acfcd31548ee5",
+			code:           recoveryCodes[1],
+			wantErr:        errutils.ErrInvalidToken,
+		},
+		{
+			name:           "Access token as challenge token",
+			challengeToken: accessToken,
+			code:           recoveryCodes[1],
+			wantErr:        errutils.ErrInvalidToken,
+		},
+		{
+			name:           "Already used challenge token",
+			challengeToken: usedChallengeToken,
+			code:           recoveryCodes[1],
+			wantErr:        errutils.ErrInvalidToken,
+		},
+		{
+			name:           "Incorrect TOTP code",
+			challengeToken: createChallenge(),
+			code:           "000000",
+			wantErr:        errutils.ErrInvalidTOTPCode,
+		},
+		{
+			name:           "Replayed TOTP code",
+			challengeToken: createChallenge(),
+			code:           currentCode,
+			wantErr:        errutils.ErrInvalidTOTPCode,
+		},
+		{
+			name:           "TOTP code older than last used code",
+			challengeToken: createChallenge(),
+			code:           previousCode,
+			wantErr:        errutils.ErrInvalidTOTPCode,
+		},
+		{
+			name:           "TOTP code outside tolerated drift",
+			challengeToken: createChallenge(),
+			code:           staleCode,
+			wantErr:        errutils.ErrInvalidTOTPCode,
+		},
+		{
+			name:           "Already used recovery code",
+			challengeToken: createChallenge(),
+			code:           recoveryCodes[0],
+			wantErr:        errutils.ErrInvalidTOTPCode,
+		},
+		{
+			name:           "Incorrect recovery code",
+			challengeToken: createChallenge(),
+			code:           "AAAAA-AAAAA",
+			wantErr:        errutils.ErrInvalidTOTPCode,
+		},
+		{
+			name:           "User without two-factor authentication",
+			challengeToken: challengeTokenWithoutTOTP,
+			code:           "123456",
+			wantErr:        errutils.ErrTOTPNotEnabled,
+		},
+	}
+
+	// incorrect codes count towards the account lockout, so cases run one at a time and the lockout is lifted after each
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			_, _, err := svc.VerifyTOTPChallenge(context.Background(), testcase.challengeToken, testcase.code, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+			require.ErrorIs(t, err, testcase.wantErr)
+
+			err = repo.DeleteLoginThrottle(dbConn, auth.LoginThrottleScopeAccount, auth.LoginAccountIdentifier(user.Email))
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestServiceVerifyTOTPChallengeLockout(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, recoveryCodes := testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	_, _, challengeToken, err := svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.NoError(t, err)
+
+	for i := 0; i < auth.LoginAccountFailureThreshold-1; i++ {
+		_, _, err = svc.VerifyTOTPChallenge(context.Background(), challengeToken, "000000", testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+		require.ErrorIs(t, err, errutils.ErrInvalidTOTPCode)
+	}
+
+	_, _, err = svc.VerifyTOTPChallenge(context.Background(), challengeToken, "AAAAA-AAAAA", testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrInvalidTOTPCode)
+
+	_, _, _, err = svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrTooManyLoginAttempts)
+
+	challengeToken, issuedJWT, err := auth.CreateTOTPChallengeJWT(user.UUID, config.SecretKey, time.Minute)
+	require.NoError(t, err)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, err = repo.CreateIssuedJWT(dbConn, issuedJWT)
+	require.NoError(t, err)
+
+	_, _, err = svc.VerifyTOTPChallenge(context.Background(), challengeToken, recoveryCodes[0], testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrTooManyLoginAttempts)
+
+	var retryAfterErr *errutils.RetryAfterError
+	require.ErrorAs(t, err, &retryAfterErr)
+	require.Greater(t, retryAfterErr.RetryAfter, time.Duration(0))
+	require.LessOrEqual(t, retryAfterErr.RetryAfter, auth.LoginLockoutBaseDuration)
+}
+
+func TestServiceEnrollTOTPSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	secret, uri, err := svc.EnrollTOTP(ctx)
+	require.NoError(t, err)
+	require.Equal(t, totp.BuildURI("Flagger", user.Email, secret), uri)
+
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	device, err := repo.GetTOTPDevice(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, secret, device.Secret)
+	require.False(t, device.ConfirmedAt.Valid)
+
+	newSecret, _, err := svc.EnrollTOTP(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, secret, newSecret)
+}
+
+func TestServiceEnrollTOTPError(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	userWithTOTP, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	testkitinternal.MustEnableUserTOTP(t, userWithTOTP.UUID)
+
+	testcases := []struct {
+		name     string
+		userUUID string
+		wantErr  error
+	}{
+		{
+			name:     "Two-factor authentication already enabled",
+			userUUID: userWithTOTP.UUID,
+			wantErr:  errutils.ErrTOTPAlreadyEnabled,
+		},
+		{
+			name:     "Non-existent user",
+			userUUID: uuid.NewString(),
+			wantErr:  errutils.ErrUserNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, testcase.userUUID)
+			_, _, err := svc.EnrollTOTP(ctx)
+			require.ErrorIs(t, err, testcase.wantErr)
+		})
+	}
+}
+
+func TestServiceConfirmTOTPSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	secret, _, err := svc.EnrollTOTP(ctx)
+	require.NoError(t, err)
+
+	code, err := totp.GenerateCode(secret, time.Now().UTC())
+	require.NoError(t, err)
+
+	recoveryCodes, err := svc.ConfirmTOTP(ctx, code, "203.0.113.42")
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, auth.RecoveryCodeCount)
+
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	device, err := repo.GetTOTPDevice(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.True(t, device.ConfirmedAt.Valid)
+	require.Equal(t, totp.TimeStep(time.Now().UTC()), device.LastUsedStep)
+
+	_, _, challengeToken, err := svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+	require.NotEmpty(t, challengeToken)
+
+	_, _, err = svc.VerifyTOTPChallenge(context.Background(), challengeToken, recoveryCodes[0], "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+}
+
+func TestServiceConfirmTOTPError(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	userWithTOTP, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	enabledSecret, _ := testkitinternal.MustEnableUserTOTP(t, userWithTOTP.UUID)
+	enabledCode, err := totp.GenerateCode(enabledSecret, time.Now().UTC())
+	require.NoError(t, err)
+
+	userWithoutTOTP, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	userWithPendingTOTP, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, _, err = svc.EnrollTOTP(context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, userWithPendingTOTP.UUID))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name     string
+		userUUID string
+		code     string
+		wantErr  error
+	}{
+		{
+			name:     "Two-factor authentication already enabled",
+			userUUID: userWithTOTP.UUID,
+			code:     enabledCode,
+			wantErr:  errutils.ErrTOTPAlreadyEnabled,
+		},
+		{
+			name:     "No enrolled TOTP device",
+			userUUID: userWithoutTOTP.UUID,
+			code:     "123456",
+			wantErr:  errutils.ErrTOTPNotEnabled,
+		},
+		{
+			name:     "Incorrect code",
+			userUUID: userWithPendingTOTP.UUID,
+			code:     "abcdef",
+			wantErr:  errutils.ErrInvalidTOTPCode,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, testcase.userUUID)
+			_, err := svc.ConfirmTOTP(ctx, testcase.code, testkit.GenerateFakeIPAddress())
+			require.ErrorIs(t, err, testcase.wantErr)
+		})
+	}
+}
+
+func TestServiceConfirmTOTPLockout(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	secret, _, err := svc.EnrollTOTP(ctx)
+	require.NoError(t, err)
+
+	for i := 0; i < auth.LoginAccountFailureThreshold; i++ {
+		_, err = svc.ConfirmTOTP(ctx, "000000", testkit.GenerateFakeIPAddress())
+		require.ErrorIs(t, err, errutils.ErrInvalidTOTPCode)
+	}
+
+	code, err := totp.GenerateCode(secret, time.Now().UTC())
+	require.NoError(t, err)
+
+	_, err = svc.ConfirmTOTP(ctx, code, testkit.GenerateFakeIPAddress())
+	require.ErrorIs(t, err, errutils.ErrTooManyLoginAttempts)
+
+	_, _, _, err = svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, testkit.GenerateFakeIPAddress(), "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrTooManyLoginAttempts)
+}
+
+func TestServiceDisableTOTPSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, recoveryCodes := testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	err = svc.DisableTOTP(ctx, password, recoveryCodes[0], "203.0.113.42")
+	require.NoError(t, err)
+
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, err = repo.GetTOTPDevice(dbConn, user.UUID)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+
+	err = repo.UseRecoveryCode(dbConn, user.UUID, auth.HashRecoveryCode(recoveryCodes[1], auth.DigestPepper(config)))
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
+
+	accessToken, refreshToken, challengeToken, err := svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+	require.NotEmpty(t, accessToken)
+	require.NotEmpty(t, refreshToken)
+	require.Empty(t, challengeToken)
+}
+
+func TestServiceDisableTOTPError(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	userWithTOTP, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, recoveryCodes := testkitinternal.MustEnableUserTOTP(t, userWithTOTP.UUID)
+
+	userWithoutTOTP, passwordWithoutTOTP := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	testcases := []struct {
+		name     string
+		userUUID string
+		password string
+		code     string
+		wantErr  error
+	}{
+		{
+			name:     "Incorrect password",
+			userUUID: userWithTOTP.UUID,
+			password: "incorrectpassword",
+			code:     recoveryCodes[0],
+			wantErr:  errutils.ErrInvalidCredentials,
+		},
+		{
+			name:     "Incorrect code",
+			userUUID: userWithTOTP.UUID,
+			password: password,
+			code:     "AAAAA-AAAAA",
+			wantErr:  errutils.ErrInvalidTOTPCode,
+		},
+		{
+			name:     "Two-factor authentication not enabled",
+			userUUID: userWithoutTOTP.UUID,
+			password: passwordWithoutTOTP,
+			code:     "123456",
+			wantErr:  errutils.ErrTOTPNotEnabled,
+		},
+		{
+			name:     "Non-existent user",
+			userUUID: uuid.NewString(),
+			password: password,
+			code:     recoveryCodes[0],
+			wantErr:  errutils.ErrUserNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, testcase.userUUID)
+			err := svc.DisableTOTP(ctx, testcase.password, testcase.code, testkit.GenerateFakeIPAddress())
+			require.ErrorIs(t, err, testcase.wantErr)
+		})
+	}
+}
+
+func TestServiceDisableTOTPLockout(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, recoveryCodes := testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	for i := 0; i < auth.LoginAccountFailureThreshold-1; i++ {
+		err = svc.DisableTOTP(ctx, "incorrectpassword", recoveryCodes[0], testkit.GenerateFakeIPAddress())
+		require.ErrorIs(t, err, errutils.ErrInvalidCredentials)
+	}
+
+	err = svc.DisableTOTP(ctx, password, "AAAAA-AAAAA", testkit.GenerateFakeIPAddress())
+	require.ErrorIs(t, err, errutils.ErrInvalidTOTPCode)
+
+	err = svc.DisableTOTP(ctx, password, recoveryCodes[0], testkit.GenerateFakeIPAddress())
+	require.ErrorIs(t, err, errutils.ErrTooManyLoginAttempts)
+
+	var retryAfterErr *errutils.RetryAfterError
+	require.ErrorAs(t, err, &retryAfterErr)
+	require.Greater(t, retryAfterErr.RetryAfter, time.Duration(0))
+
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, err = repo.GetTOTPDevice(dbConn, user.UUID)
+	require.NoError(t, err)
+}
+
+func TestServiceCreateAPIKeySuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	name := "My API Key"
+	expiresAt := pgtype.Timestamp{
+		Valid: false,
+	}
+	scopes := []string{
+		string(auth.APIKeyScopeFlagsEvaluate),
+		string(auth.APIKeyScopeUserRead),
+		string(auth.APIKeyScopeFlagsEvaluate),
+	}
+	now := time.Now().UTC()
+	apiKey, rawKey, err := svc.CreateAPIKey(
+		ctx,
+		name,
+		scopes,
+		[]string{"10.1.2.3", "10.0.0.0/8", "10.0.0.0/8"},
+		[]string{"https://App.example.com/", "http://localhost:3000", "https://app.example.com"},
+		nil,
+		expiresAt,
+	)
+	require.NoError(t, err)
+
+	require.NotNil(t, apiKey)
+	require.Equal(t, apiKey.UserUUID, user.UUID)
+	require.Equal(t, apiKey.Name, apiKey.Name)
+	require.Equal(t, []string{string(auth.APIKeyScopeFlagsEvaluate), string(auth.APIKeyScopeUserRead)}, apiKey.Scopes)
+	require.Equal(t, []string{"10.1.2.3/32", "10.0.0.0/8"}, apiKey.AllowedCIDRs)
+	require.Equal(t, []string{"https://app.example.com", "http://localhost:3000"}, apiKey.AllowedOrigins)
+	require.False(t, apiKey.ExpiresAt.Valid)
+	testkit.RequireTimeAlmostEqual(t, now, apiKey.CreatedAt)
+
+	require.Empty(t, apiKey.HashedKey)
+	require.Equal(t, auth.APIKeyDigest(rawKey, auth.DigestPepper(config)), apiKey.KeyDigest)
+
+	r := regexp.MustCompile(`^(\S+)\.(\S+)$`)
+	matches := r.FindStringSubmatch(rawKey)
+
+	require.Len(t, matches, 3)
+	require.Equal(t, apiKey.Prefix, matches[1])
+}
+
+func TestServiceCreateAPIKeyAlreadyExists(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.Name = "My API Key"
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	name := "My API Key"
+	expiresAt := pgtype.Timestamp{
+		Valid: false,
+	}
+	_, _, err = svc.CreateAPIKey(ctx, name, nil, nil, nil, nil, expiresAt)
+	require.ErrorIs(t, err, errutils.ErrAPIKeyAlreadyExists)
+}
+
+func TestServiceCreateAPIKeyDefaultScope(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	apiKey, _, err := svc.CreateAPIKey(ctx, "My API Key", nil, nil, nil, nil, pgtype.Timestamp{Valid: false})
+	require.NoError(t, err)
+	require.Equal(t, []string{string(auth.APIKeyScopeAdmin)}, apiKey.Scopes)
+	require.Equal(t, []string{}, apiKey.AllowedCIDRs)
+}
+
+func TestServiceCreateAPIKeyInvalidScope(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	_, _, err = svc.CreateAPIKey(ctx, "My API Key", []string{"flags:delete"}, nil, nil, nil, pgtype.Timestamp{Valid: false})
+	require.ErrorIs(t, err, errutils.ErrInvalidAPIKeyScope)
+}
+
+func TestServiceCreateAPIKeyInvalidCIDR(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	_, _, err = svc.CreateAPIKey(ctx, "My API Key", nil, []string{"10.0.0.0/33"}, nil, nil, pgtype.Timestamp{Valid: false})
+	require.ErrorIs(t, err, errutils.ErrInvalidAPIKeyCIDR)
+}
+
+func TestServiceListAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	user1, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	user2, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	user1Key1, _ := testkitinternal.MustCreateUserAPIKey(t, user1.UUID, func(k *auth.APIKey) {
+		k.Name = "User1APIKey1"
+		k.ExpiresAt = pgtype.Timestamp{
+			Valid: false,
+		}
+	})
+
+	user1Key2, _ := testkitinternal.MustCreateUserAPIKey(t, user1.UUID, func(k *auth.APIKey) {
+		k.Name = "User1APIKey2"
+		k.ExpiresAt = pgtype.Timestamp{
+			Time:  time.Now().UTC().AddDate(0, 0, 1),
+			Valid: true,
+		}
+	})
+
+	user2Key, _ := testkitinternal.MustCreateUserAPIKey(t, user2.UUID, func(k *auth.APIKey) {
+		k.Name = "User2APIKey"
+		k.ExpiresAt = pgtype.Timestamp{
+			Time:  time.Now().UTC().AddDate(0, 1, 0),
+			Valid: true,
+		}
+	})
+
+	user1Keys := []*auth.APIKey{user1Key1, user1Key2}
+	user2Keys := []*auth.APIKey{user2Key}
+
+	sort.Slice(user1Keys, func(i, j int) bool {
+		return user1Keys[i].Prefix < user1Keys[j].Prefix
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user1.UUID)
+	fetchedUser1Keys, err := svc.ListAPIKeys(ctx)
+	require.NoError(t, err)
+
+	sort.Slice(fetchedUser1Keys, func(i, j int) bool {
+		return fetchedUser1Keys[i].Prefix < fetchedUser1Keys[j].Prefix
+	})
+
+	ctx = context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user2.UUID)
+	fetchedUser2Keys, err := svc.ListAPIKeys(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, fetchedUser1Keys, 2)
+	require.Len(t, fetchedUser2Keys, 1)
+
+	for i, fetchedKey := range fetchedUser1Keys {
+		wantKey := user1Keys[i]
+		require.Equal(t, wantKey.ID, fetchedKey.ID)
+		require.Equal(t, wantKey.UserUUID, fetchedKey.UserUUID)
+		require.Equal(t, wantKey.Prefix, fetchedKey.Prefix)
+		require.Equal(t, wantKey.Name, fetchedKey.Name)
+		require.Equal(t, wantKey.CreatedAt, fetchedKey.CreatedAt)
+		require.Equal(t, wantKey.ExpiresAt, fetchedKey.ExpiresAt)
+	}
+
+	for i, fetchedKey := range fetchedUser2Keys {
+		wantKey := user2Keys[i]
+		require.Equal(t, wantKey.ID, fetchedKey.ID)
+		require.Equal(t, wantKey.UserUUID, fetchedKey.UserUUID)
+		require.Equal(t, wantKey.Prefix, fetchedKey.Prefix)
+		require.Equal(t, wantKey.Name, fetchedKey.Name)
+		require.Equal(t, wantKey.CreatedAt, fetchedKey.CreatedAt)
+		require.Equal(t, wantKey.ExpiresAt, fetchedKey.ExpiresAt)
+	}
+}
+
+func TestServiceFindAPIKeySuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, rawKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	foundAPIKey, err := svc.FindAPIKey(context.Background(), rawKey)
+	require.NoError(t, err)
+
+	require.Equal(t, apiKey.ID, foundAPIKey.ID)
+	require.Equal(t, apiKey.UserUUID, foundAPIKey.UserUUID)
+	require.Equal(t, apiKey.Prefix, foundAPIKey.Prefix)
+	require.Equal(t, apiKey.Name, foundAPIKey.Name)
+	require.Equal(t, apiKey.CreatedAt, foundAPIKey.CreatedAt)
+	require.Equal(t, apiKey.ExpiresAt, foundAPIKey.ExpiresAt)
+}
+
+func TestServiceFindAPIKeyLegacyHashedKey(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	_, rawKey, _, err := auth.CreateAPIKey(config.SecretKey)
+	require.NoError(t, err)
+
+	prefix, _, ok := auth.ParseAPIKey(rawKey)
+	require.True(t, ok)
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.Prefix = prefix
+		k.HashedKey = testkitinternal.MustHashPassword(rawKey)
+		k.KeyDigest = ""
+	})
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	_, err = svc.FindAPIKey(context.Background(), prefix+".wrong")
+	require.ErrorIs(t, err, errutils.ErrAPIKeyNotFound)
+
+	foundAPIKey, err := svc.FindAPIKey(context.Background(), rawKey)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, foundAPIKey.ID)
+
+	migratedAPIKey, err := repo.GetActiveAPIKeyByDigest(dbConn, auth.APIKeyDigest(rawKey, auth.DigestPepper(config)))
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, migratedAPIKey.ID)
+	require.Empty(t, migratedAPIKey.HashedKey)
+
+	foundAPIKey, err = svc.FindAPIKey(context.Background(), rawKey)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, foundAPIKey.ID)
+}
+
+func TestServiceFindAPIKeyNotFound(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	_, rawKey, _, err := auth.CreateAPIKey(config.SecretKey)
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	_, err = svc.FindAPIKey(context.Background(), rawKey)
+	require.ErrorIs(t, err, errutils.ErrAPIKeyNotFound)
+}
+
+func TestServiceDeleteAPIKeySuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	err = svc.DeleteAPIKey(ctx, apiKey.ID)
+	require.NoError(t, err)
+
+	apiKeys, err := repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Empty(t, apiKeys)
+}
+
+func TestServiceDeleteAPIKeyNotFound(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	err = svc.DeleteAPIKey(ctx, 42)
+	require.ErrorIs(t, err, errutils.ErrAPIKeyNotFound)
+}
+
+func TestServiceUpdateAPIKeySuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.ExpiresAt = pgtype.Timestamp{
+			Time:  time.Now().UTC().Add(time.Hour),
+			Valid: true,
+		}
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	name := "My Renamed API Key"
+	expiresAt := pgtype.Timestamp{
+		Valid: false,
+	}
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	allowedCIDRs := []string{"192.168.1.1", "2001:db8::1/32"}
+	allowedOrigins := []string{"HTTPS://app.example.com"}
+	updatedAPIKey, err := svc.UpdateAPIKey(ctx, apiKey.ID, &name, &allowedCIDRs, &allowedOrigins, &expiresAt)
+	require.NoError(t, err)
+
+	require.Equal(t, apiKey.ID, updatedAPIKey.ID)
+	require.Equal(t, apiKey.Prefix, updatedAPIKey.Prefix)
+	require.Equal(t, apiKey.Scopes, updatedAPIKey.Scopes)
+	require.Equal(t, name, updatedAPIKey.Name)
+	require.Equal(t, []string{"192.168.1.1/32", "2001:db8::/32"}, updatedAPIKey.AllowedCIDRs)
+	require.Equal(t, []string{"https://app.example.com"}, updatedAPIKey.AllowedOrigins)
+	require.False(t, updatedAPIKey.ExpiresAt.Valid)
+
+	invalidCIDRs := []string{"192.168.1.1/16", "not-an-ip"}
+	_, err = svc.UpdateAPIKey(ctx, apiKey.ID, nil, &invalidCIDRs, nil, nil)
+	require.ErrorIs(t, err, errutils.ErrInvalidAPIKeyCIDR)
+
+	invalidOrigins := []string{"https://app.example.com/path"}
+	_, err = svc.UpdateAPIKey(ctx, apiKey.ID, nil, nil, &invalidOrigins, nil)
+	require.ErrorIs(t, err, errutils.ErrInvalidAPIKeyOrigin)
+}
+
+func TestServiceUpdateAPIKeyError(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+	otherAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	testcases := []struct {
+		name     string
+		apiKeyID int
+		keyName  string
+		wantErr  error
+	}{
+		{
+			name:     "Non-existent API key",
+			apiKeyID: 4242,
+			keyName:  "My Renamed API Key",
+			wantErr:  errutils.ErrAPIKeyNotFound,
+		},
+		{
+			name:     "Duplicate API key name",
+			apiKeyID: apiKey.ID,
+			keyName:  otherAPIKey.Name,
+			wantErr:  errutils.ErrAPIKeyAlreadyExists,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+			_, err := svc.UpdateAPIKey(ctx, testcase.apiKeyID, &testcase.keyName, nil, nil, nil)
+			require.ErrorIs(t, err, testcase.wantErr)
+		})
+	}
+}
+
+func TestServiceRotateAPIKeySuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, rawKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"flags:evaluate"}
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	rotatedAt := time.Now().UTC()
+	rotatedAPIKey, rotatedRawKey, err := svc.RotateAPIKey(ctx, apiKey.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, apiKey.ID, rotatedAPIKey.ID)
+	require.Equal(t, apiKey.Name, rotatedAPIKey.Name)
+	require.Equal(t, apiKey.Scopes, rotatedAPIKey.Scopes)
+	require.NotEqual(t, apiKey.Prefix, rotatedAPIKey.Prefix)
+	require.NotEqual(t, rawKey, rotatedRawKey)
+	require.Equal(t, apiKey.Prefix, rotatedAPIKey.PreviousPrefix)
+	require.Equal(t, apiKey.HashedKey, rotatedAPIKey.PreviousHashedKey)
+	require.Equal(t, apiKey.KeyDigest, rotatedAPIKey.PreviousKeyDigest)
+	require.True(t, rotatedAPIKey.PreviousExpiresAt.Valid)
+	testkit.RequireTimeAlmostEqual(
+		t,
+		rotatedAt.Add(time.Duration(config.APIKeyRotationGracePeriod*int64(time.Minute))),
+		rotatedAPIKey.PreviousExpiresAt.Time,
+	)
+
+	foundAPIKey, err := svc.FindAPIKey(context.Background(), rotatedRawKey)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, foundAPIKey.ID)
+
+	foundAPIKey, err = svc.FindAPIKey(context.Background(), rawKey)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, foundAPIKey.ID)
+}
+
+func TestServiceRotateAPIKeyNoGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, rawKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+	config.APIKeyRotationGracePeriod = 0
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	_, rotatedRawKey, err := svc.RotateAPIKey(ctx, apiKey.ID)
+	require.NoError(t, err)
+
+	_, err = svc.FindAPIKey(context.Background(), rotatedRawKey)
+	require.NoError(t, err)
+
+	_, err = svc.FindAPIKey(context.Background(), rawKey)
+	require.ErrorIs(t, err, errutils.ErrAPIKeyNotFound)
+}
+
+func TestServiceRotateAPIKeyNotFound(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	_, _, err = svc.RotateAPIKey(ctx, 4242)
+	require.ErrorIs(t, err, errutils.ErrAPIKeyNotFound)
+}
+
+func TestServiceFlushAPIKeyUsage(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	count, err := svc.FlushAPIKeyUsage(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(0), count)
+
+	usedAt := time.Now().UTC()
+	svc.RecordAPIKeyUsage(apiKey.ID, "10.0.0.1")
+	svc.RecordAPIKeyUsage(apiKey.ID, "10.0.0.2")
+
+	apiKeys, err := repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 1)
+	require.Equal(t, int64(0), apiKeys[0].RequestCount)
+	require.False(t, apiKeys[0].LastUsedAt.Valid)
+
+	count, err = svc.FlushAPIKeyUsage(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	apiKeys, err = repo.ListAPIKeysByUserUUID(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 1)
+	require.Equal(t, int64(2), apiKeys[0].RequestCount)
+	require.Equal(t, "10.0.0.2", apiKeys[0].LastUsedIP)
+	require.True(t, apiKeys[0].LastUsedAt.Valid)
+	testkit.RequireTimeAlmostEqual(t, usedAt, apiKeys[0].LastUsedAt.Time)
+
+	count, err = svc.FlushAPIKeyUsage(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(0), count)
+}
+
+func TestServiceGetAPIKeyUsageSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	testkitinternal.MustRecordAPIKeyUsage(t, apiKey.ID, today, 7)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	usage, err := svc.GetAPIKeyUsage(ctx, apiKey.ID, 30)
+	require.NoError(t, err)
+	require.Len(t, usage, 30)
+	require.Equal(t, today.AddDate(0, 0, -29), usage[0].Day.UTC())
+	require.Equal(t, today, usage[29].Day.UTC())
+	require.Equal(t, int64(7), usage[29].RequestCount)
+}
+
+func TestServiceGetAPIKeyUsageNotFound(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	_, err = svc.GetAPIKeyUsage(ctx, 4242, 30)
+	require.ErrorIs(t, err, errutils.ErrAPIKeyNotFound)
+}
+
+func TestServiceCreateAPIKeyServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, func(sa *auth.ServiceAccount) {
+		sa.Scopes = []string{string(auth.APIKeyScopeFlagsRead)}
+	})
+	otherServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, otherUser.UUID, nil)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	invalidUUID := "1nv4l1d-uu1d"
+
+	testcases := []struct {
+		name               string
+		apiKeyName         string
+		scopes             []string
+		serviceAccountUUID *string
+		wantScopes         []string
+		wantErr            error
+	}{
+		{
+			name:               "Default scopes of service account",
+			apiKeyName:         "Service account default scopes",
+			scopes:             nil,
+			serviceAccountUUID: &serviceAccount.UUID,
+			wantScopes:         []string{string(auth.APIKeyScopeFlagsRead)},
+			wantErr:            nil,
+		},
+		{
+			name:               "Scope implied by service account scopes",
+			apiKeyName:         "Service account implied scope",
+			scopes:             []string{string(auth.APIKeyScopeFlagsEvaluate)},
+			serviceAccountUUID: &serviceAccount.UUID,
+			wantScopes:         []string{string(auth.APIKeyScopeFlagsEvaluate)},
+			wantErr:            nil,
+		},
+		{
+			name:               "Scope not granted to service account",
+			apiKeyName:         "Service account exceeding scope",
+			scopes:             []string{string(auth.APIKeyScopeFlagsWrite)},
+			serviceAccountUUID: &serviceAccount.UUID,
+			wantScopes:         nil,
+			wantErr:            errutils.ErrServiceAccountScopeDenied,
+		},
+		{
+			name:               "Service account of another user",
+			apiKeyName:         "Stolen service account",
+			scopes:             nil,
+			serviceAccountUUID: &otherServiceAccount.UUID,
+			wantScopes:         nil,
+			wantErr:            errutils.ErrServiceAccountNotFound,
+		},
+		{
+			name:               "Invalid service account UUID",
+			apiKeyName:         "Invalid service account",
+			scopes:             nil,
+			serviceAccountUUID: &invalidUUID,
+			wantScopes:         nil,
+			wantErr:            errutils.ErrServiceAccountNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+			apiKey, _, err := svc.CreateAPIKey(
+				ctx,
+				testcase.apiKeyName,
+				testcase.scopes,
+				nil,
+				nil,
+				testcase.serviceAccountUUID,
+				pgtype.Timestamp{Valid: false},
+			)
+			if testcase.wantErr != nil {
+				require.ErrorIs(t, err, testcase.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, user.UUID, apiKey.UserUUID)
+			require.Equal(t, testcase.wantScopes, apiKey.Scopes)
+			require.True(t, apiKey.ServiceAccountUUID.Valid)
+			require.Equal(t, serviceAccount.UUID, apiKey.ServiceAccountUUID.String)
+		})
+	}
+}
+
+func TestServiceCreateServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	testkitinternal.MustCreateUserServiceAccount(t, user.UUID, func(sa *auth.ServiceAccount) {
+		sa.Name = "Existing Service Account"
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	testcases := []struct {
+		name               string
+		serviceAccountName string
+		scopes             []string
+		wantScopes         []string
+		wantErr            error
+	}{
+		{
+			name:               "Service account with scopes",
+			serviceAccountName: "Scoped Service Account",
+			scopes:             []string{"flags:read", "flags:read"},
+			wantScopes:         []string{"flags:read"},
+			wantErr:            nil,
+		},
+		{
+			name:               "Service account with default scope",
+			serviceAccountName: "Admin Service Account",
+			scopes:             nil,
+			wantScopes:         []string{"admin"},
+			wantErr:            nil,
+		},
+		{
+			name:               "Invalid scope",
+			serviceAccountName: "Invalid Service Account",
+			scopes:             []string{"flags:delete"},
+			wantScopes:         nil,
+			wantErr:            errutils.ErrInvalidAPIKeyScope,
+		},
+		{
+			name:               "Duplicate name",
+			serviceAccountName: "Existing Service Account",
+			scopes:             nil,
+			wantScopes:         nil,
+			wantErr:            errutils.ErrServiceAccountAlreadyExists,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+			serviceAccount, err := svc.CreateServiceAccount(ctx, testcase.serviceAccountName, "Description", testcase.scopes)
+			if testcase.wantErr != nil {
+				require.ErrorIs(t, err, testcase.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, user.UUID, serviceAccount.OwnerUUID)
+			require.Equal(t, testcase.serviceAccountName, serviceAccount.Name)
+			require.Equal(t, "Description", serviceAccount.Description)
+			require.Equal(t, testcase.wantScopes, serviceAccount.Scopes)
+			require.True(t, serviceAccount.IsActive)
+		})
+	}
+}
+
+func TestServiceListServiceAccounts(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, nil)
+	testkitinternal.MustCreateUserServiceAccount(t, otherUser.UUID, nil)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	serviceAccounts, err := svc.ListServiceAccounts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []*auth.ServiceAccount{serviceAccount}, serviceAccounts)
+}
+
+func TestServiceUpdateServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, nil)
+	_, rawKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.ServiceAccountUUID = pgtype.Text{
+			String: serviceAccount.UUID,
+			Valid:  true,
+		}
+	})
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+
+	_, err = svc.FindAPIKey(context.Background(), rawKey)
+	require.NoError(t, err)
+
+	isActive := false
+	updatedServiceAccount, err := svc.UpdateServiceAccount(ctx, serviceAccount.UUID, nil, nil, &isActive)
+	require.NoError(t, err)
+	require.False(t, updatedServiceAccount.IsActive)
+
+	_, err = svc.FindAPIKey(context.Background(), rawKey)
+	require.ErrorIs(t, err, errutils.ErrAPIKeyNotFound)
+
+	isActive = true
+	updatedServiceAccount, err = svc.UpdateServiceAccount(ctx, serviceAccount.UUID, nil, nil, &isActive)
+	require.NoError(t, err)
+	require.True(t, updatedServiceAccount.IsActive)
+
+	_, err = svc.FindAPIKey(context.Background(), rawKey)
+	require.NoError(t, err)
+
+	_, err = svc.UpdateServiceAccount(ctx, uuid.NewString(), nil, nil, &isActive)
+	require.ErrorIs(t, err, errutils.ErrServiceAccountNotFound)
+
+	_, err = svc.UpdateServiceAccount(ctx, "1nv4l1d-uu1d", nil, nil, &isActive)
+	require.ErrorIs(t, err, errutils.ErrServiceAccountNotFound)
+}
+
+func TestServiceDeleteServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, nil)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+
+	err = svc.DeleteServiceAccount(ctx, serviceAccount.UUID)
+	require.NoError(t, err)
+
+	err = svc.DeleteServiceAccount(ctx, serviceAccount.UUID)
+	require.ErrorIs(t, err, errutils.ErrServiceAccountNotFound)
+
+	err = svc.DeleteServiceAccount(ctx, "1nv4l1d-uu1d")
+	require.ErrorIs(t, err, errutils.ErrServiceAccountNotFound)
+}
+
+func TestServiceGetUser(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	user, err := svc.GetUser(context.Background(), inactiveUser.UUID)
+	require.NoError(t, err)
+	require.Equal(t, inactiveUser.UUID, user.UUID)
+	require.False(t, user.IsActive)
+
+	_, err = svc.GetUser(context.Background(), uuid.NewString())
+	require.ErrorIs(t, err, errutils.ErrUserNotFound)
+
+	_, err = svc.GetUser(context.Background(), "1nv4l1d-uu1d")
+	require.ErrorIs(t, err, errutils.ErrUserNotFound)
+}
+
+func TestServiceListUsers(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	search := testkit.MustGenerateRandomString(16, true, false, false)
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.LastName = search
+	})
+
+	users, err := svc.ListUsers(context.Background(), search)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, user.UUID, users[0].UUID)
+}
+
+func TestServiceUpdateUserStatusSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	accessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, superUser.UUID)
+
+	isSuperUser := true
+	updatedUser, err := svc.UpdateUserStatus(ctx, user.UUID, nil, &isSuperUser)
+	require.NoError(t, err)
+	require.True(t, updatedUser.IsActive)
+	require.True(t, updatedUser.IsSuperUser)
+
+	_, err = svc.AuthenticateJWT(context.Background(), accessToken)
+	require.NoError(t, err)
+
+	isActive := false
+	updatedUser, err = svc.UpdateUserStatus(ctx, user.UUID, &isActive, nil)
+	require.NoError(t, err)
+	require.False(t, updatedUser.IsActive)
+	require.True(t, updatedUser.IsSuperUser)
+
+	_, err = svc.AuthenticateJWT(context.Background(), accessToken)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	isActive = true
+	updatedUser, err = svc.UpdateUserStatus(ctx, user.UUID, &isActive, nil)
+	require.NoError(t, err)
+	require.True(t, updatedUser.IsActive)
+}
+
+func TestServiceUpdateUserStatusError(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+
+	isActive := false
+	testcases := []struct {
+		name     string
+		userUUID string
+		wantErr  error
+	}{
+		{
+			name:     "Update own status",
+			userUUID: superUser.UUID,
+			wantErr:  errutils.ErrCannotUpdateOwnStatus,
+		},
+		{
+			name:     "Non-existent user",
+			userUUID: uuid.NewString(),
+			wantErr:  errutils.ErrUserNotFound,
+		},
+		{
+			name:     "Invalid user UUID",
+			userUUID: "1nv4l1d-uu1d",
+			wantErr:  errutils.ErrUserNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, superUser.UUID)
+			_, err := svc.UpdateUserStatus(ctx, testcase.userUUID, &isActive, nil)
+			require.ErrorIs(t, err, testcase.wantErr)
+		})
+	}
+}
+
+func TestServiceForcePasswordResetSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	accessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	var wg sync.WaitGroup
+	err = svc.ForcePasswordReset(context.Background(), &wg, user.UUID)
+	require.NoError(t, err)
+
+	wg.Wait()
+
+	_, err = svc.AuthenticateJWT(context.Background(), accessToken)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	_, _, _, err = svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, password, "203.0.113.42", "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrInvalidCredentials)
+
+	require.Len(t, mailClient.Logs, 1)
+
+	lastMail := mailClient.Logs[len(mailClient.Logs)-1]
+	require.Equal(t, []string{user.Email}, lastMail.To)
+	require.Equal(t, "Reset your Flagger password", lastMail.Subject)
+
+	pattern := fmt.Sprintf(config.FrontendBaseURL+config.FrontendPasswordResetRoute, `(\S+)`)
+	r, err := regexp.Compile(pattern)
+	require.NoError(t, err)
+
+	matches := r.FindStringSubmatch(string(lastMail.Message))
+	require.Len(t, matches, 2)
+
+	newPassword := testkit.GenerateFakePassword()
+	err = svc.ResetPassword(context.Background(), matches[1], newPassword)
+	require.NoError(t, err)
+
+	_, _, _, err = svc.CreateJWT(context.Background(), &sync.WaitGroup{}, user.Email, newPassword, "203.0.113.42", "curl/8.5.0")
+	require.NoError(t, err)
+}
+
+func TestServiceForcePasswordResetError(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	testcases := []struct {
+		name     string
+		userUUID string
+	}{
+		{
+			name:     "Inactive user",
+			userUUID: inactiveUser.UUID,
+		},
+		{
+			name:     "Non-existent user",
+			userUUID: uuid.NewString(),
+		},
+		{
+			name:     "Invalid user UUID",
+			userUUID: "1nv4l1d-uu1d",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			mailClient := mailclient.NewInMemClient("support@flagger.com")
+			keySet := testkitinternal.MustCreateKeySet(config)
+			svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+			var wg sync.WaitGroup
+			err := svc.ForcePasswordReset(context.Background(), &wg, testcase.userUUID)
+			require.ErrorIs(t, err, errutils.ErrUserNotFound)
+
+			wg.Wait()
+
+			require.Len(t, mailClient.Logs, 0)
+		})
+	}
+}
+
+func TestServiceRevokeUserCredentials(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	accessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	_, rawKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUserAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, otherUser.UUID)
+	_, otherUserRawKey := testkitinternal.MustCreateUserAPIKey(t, otherUser.UUID, nil)
+
+	err = svc.RevokeUserCredentials(context.Background(), user.UUID)
+	require.NoError(t, err)
+
+	_, err = svc.AuthenticateJWT(context.Background(), accessToken)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	_, err = svc.FindAPIKey(context.Background(), rawKey)
+	require.ErrorIs(t, err, errutils.ErrAPIKeyNotFound)
+
+	_, err = svc.AuthenticateJWT(context.Background(), otherUserAccessToken)
+	require.NoError(t, err)
+
+	_, err = svc.FindAPIKey(context.Background(), otherUserRawKey)
+	require.NoError(t, err)
+
+	err = svc.RevokeUserCredentials(context.Background(), uuid.NewString())
+	require.ErrorIs(t, err, errutils.ErrUserNotFound)
+
+	err = svc.RevokeUserCredentials(context.Background(), "1nv4l1d-uu1d")
+	require.ErrorIs(t, err, errutils.ErrUserNotFound)
+}
+
+func TestServiceImpersonateUserSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	superUserAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, superUser.UUID)
+	superUserClaims, ok := auth.ValidateAuthJWT(superUserAccessToken, auth.JWTTypeAccess, keySet)
+	require.True(t, ok)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, superUser.UUID)
+	ctx = context.WithValue(ctx, auth.AuthContextKeySessionID, superUserClaims.SessionID)
+
+	token, claims, err := svc.ImpersonateUser(ctx, user.UUID, true)
+	require.NoError(t, err)
+
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, superUserClaims.SessionID, claims.SessionID)
+	require.NotNil(t, claims.Actor)
+	require.Equal(t, superUser.UUID, claims.Actor.Subject)
+	require.True(t, claims.ReadOnly)
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(auth.ImpersonationLifetime), time.Time(claims.ExpiresAt))
+
+	authenticatedClaims, err := svc.AuthenticateJWT(context.Background(), token)
+	require.NoError(t, err)
+	require.Equal(t, user.UUID, authenticatedClaims.Subject)
+	require.Equal(t, superUser.UUID, authenticatedClaims.Actor.Subject)
+	require.True(t, authenticatedClaims.ReadOnly)
+
+	writableToken, writableClaims, err := svc.ImpersonateUser(ctx, user.UUID, false)
+	require.NoError(t, err)
+	require.False(t, writableClaims.ReadOnly)
+
+	_, err = svc.AuthenticateJWT(context.Background(), writableToken)
+	require.NoError(t, err)
+
+	err = repo.RevokeSession(dbConn, superUserClaims.SessionID, superUser.UUID)
+	require.NoError(t, err)
+
+	_, err = svc.AuthenticateJWT(context.Background(), token)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+}
+
+func TestServiceImpersonateUserActorNoLongerSuperUser(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	superUserAccessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, superUser.UUID)
+	superUserClaims, ok := auth.ValidateAuthJWT(superUserAccessToken, auth.JWTTypeAccess, keySet)
+	require.True(t, ok)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, superUser.UUID)
+	ctx = context.WithValue(ctx, auth.AuthContextKeySessionID, superUserClaims.SessionID)
+
+	token, _, err := svc.ImpersonateUser(ctx, user.UUID, true)
+	require.NoError(t, err)
+
+	isSuperUser := false
+	_, err = repo.UpdateUserStatus(dbConn, superUser.UUID, nil, &isSuperUser)
+	require.NoError(t, err)
+
+	_, err = svc.AuthenticateJWT(context.Background(), token)
+	require.ErrorIs(t, err, errutils.ErrInvalidToken)
+
+	_, err = svc.AuthenticateJWT(context.Background(), superUserAccessToken)
+	require.NoError(t, err)
+}
+
+func TestServiceImpersonateUserError(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	otherSuperUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	testcases := []struct {
+		name     string
+		userUUID string
+		wantErr  error
+	}{
+		{
+			name:     "Impersonate self",
+			userUUID: superUser.UUID,
+			wantErr:  errutils.ErrCannotImpersonateUser,
+		},
+		{
+			name:     "Impersonate other superuser",
+			userUUID: otherSuperUser.UUID,
+			wantErr:  errutils.ErrCannotImpersonateUser,
+		},
+		{
+			name:     "Impersonate inactive user",
+			userUUID: inactiveUser.UUID,
+			wantErr:  errutils.ErrUserNotFound,
+		},
+		{
+			name:     "Impersonate non-existent user",
+			userUUID: uuid.NewString(),
+			wantErr:  errutils.ErrUserNotFound,
+		},
+		{
+			name:     "Impersonate user with invalid UUID",
+			userUUID: "1nv4l1d-uu1d",
+			wantErr:  errutils.ErrUserNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, superUser.UUID)
+			ctx = context.WithValue(ctx, auth.AuthContextKeySessionID, uuid.NewString())
+
+			_, _, err := svc.ImpersonateUser(ctx, testcase.userUUID, true)
+			require.ErrorIs(t, err, testcase.wantErr)
+		})
+	}
+}
+
+func TestServicePurgeExpiredJWTs(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	now := time.Now().UTC()
+	expiredJWT, err := repo.CreateIssuedJWT(dbConn, &auth.IssuedJWT{
+		JTI:       uuid.NewString(),
+		UserUUID:  user.UUID,
+		TokenType: string(auth.JWTTypeActivation),
+		CreatedAt: now.Add(-2 * time.Hour),
+		ExpiresAt: now.Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	count, err := svc.PurgeExpiredJWTs(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	_, err = repo.CreateIssuedJWT(dbConn, expiredJWT)
+	require.NoError(t, err)
+}
+
+func TestServicePurgeExpiredSessions(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	expiredSession, err := repo.CreateSession(dbConn, &auth.Session{
+		ID:         uuid.NewString(),
+		UserUUID:   user.UUID,
+		RefreshJTI: uuid.NewString(),
+		ExpiresAt:  time.Now().UTC().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	count, err := svc.PurgeExpiredSessions(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	_, err = repo.CreateSession(dbConn, expiredSession)
+	require.NoError(t, err)
+}
+
+func TestServiceOIDCNotConfigured(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+	config.OIDCIssuerURL = ""
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	_, err = svc.CreateOIDCAuthURL(context.Background())
+	require.ErrorIs(t, err, errutils.ErrOIDCNotConfigured)
+
+	_, _, _, err = svc.CreateJWTFromOIDC(context.Background(), "somestate", "somecode", "203.0.113.42", "curl/8.5.0")
+	require.ErrorIs(t, err, errutils.ErrOIDCNotConfigured)
+}
+
+func TestServiceCreateJWTFromOIDCSuccess(t *testing.T) {
+	t.Parallel()
+
+	provider := testkit.MustCreateOIDCProvider("flagger", "s3cr3t")
+	t.Cleanup(provider.Close)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+	config.OIDCIssuerURL = provider.URL()
+	config.OIDCClientID = "flagger"
+	config.OIDCClientSecret = "s3cr3t"
+	config.OIDCRedirectURL = "http://localhost:3000/sso/callback"
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	existingUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	totpUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	testkitinternal.MustEnableUserTOTP(t, totpUser.UUID)
+	newUserEmail := testkit.GenerateFakeEmail()
+
+	testcases := []struct {
+		name          string
+		oidcUser      testkit.OIDCUser
+		wantUserUUID  string
+		wantFirstName string
+		wantLastName  string
+		wantChallenge bool
+	}{
+		{
+			name: "Existing user",
+			oidcUser: testkit.OIDCUser{
+				Subject:       uuid.NewString(),
+				Email:         existingUser.Email,
+				EmailVerified: true,
+				GivenName:     "Michael",
+				FamilyName:    "Scott",
+			},
+			wantUserUUID:  existingUser.UUID,
+			wantFirstName: existingUser.FirstName,
+			wantLastName:  existingUser.LastName,
+			wantChallenge: false,
+		},
+		{
+			name: "Existing user with two-factor authentication",
+			oidcUser: testkit.OIDCUser{
+				Subject:       uuid.NewString(),
+				Email:         totpUser.Email,
+				EmailVerified: true,
+				GivenName:     "Jim",
+				FamilyName:    "Halpert",
+			},
+			wantUserUUID:  totpUser.UUID,
+			wantFirstName: totpUser.FirstName,
+			wantLastName:  totpUser.LastName,
+			wantChallenge: true,
+		},
+		{
+			name: "New user",
+			oidcUser: testkit.OIDCUser{
+				Subject:       uuid.NewString(),
+				Email:         newUserEmail,
+				EmailVerified: true,
+				GivenName:     "Dwight",
+				FamilyName:    "Schrute",
+			},
+			wantUserUUID:  "",
+			wantFirstName: "Dwight",
+			wantLastName:  "Schrute",
+			wantChallenge: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			authURL, err := svc.CreateOIDCAuthURL(context.Background())
+			require.NoError(t, err)
+
+			code, state, err := provider.Authorize(authURL, testcase.oidcUser)
+			require.NoError(t, err)
+
+			accessToken, refreshToken, challengeToken, err := svc.CreateJWTFromOIDC(context.Background(), state, code, "203.0.113.42", "curl/8.5.0")
+			require.NoError(t, err)
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			user, err := repo.GetUserByEmail(dbConn, testcase.oidcUser.Email)
+			require.NoError(t, err)
+
+			if testcase.wantChallenge {
+				require.Empty(t, accessToken)
+				require.Empty(t, refreshToken)
+				require.NotEmpty(t, challengeToken)
+			} else {
+				require.Empty(t, challengeToken)
+				require.NotEmpty(t, refreshToken)
+
+				claims, err := svc.AuthenticateJWT(context.Background(), accessToken)
+				require.NoError(t, err)
+				require.Equal(t, user.UUID, claims.Subject)
+			}
+
+			require.True(t, user.IsActive)
+			require.Equal(t, testcase.wantFirstName, user.FirstName)
+			require.Equal(t, testcase.wantLastName, user.LastName)
+
+			if testcase.wantUserUUID != "" {
+				require.Equal(t, testcase.wantUserUUID, user.UUID)
+			}
+
+			_, _, _, err = svc.CreateJWTFromOIDC(context.Background(), state, code, "203.0.113.42", "curl/8.5.0")
+			require.ErrorIs(t, err, errutils.ErrInvalidOIDCState)
+		})
+	}
+}
+
+func TestServiceCreateJWTFromOIDCError(t *testing.T) {
+	t.Parallel()
+
+	provider := testkit.MustCreateOIDCProvider("flagger", "s3cr3t")
+	t.Cleanup(provider.Close)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+	config.OIDCIssuerURL = provider.URL()
+	config.OIDCClientID = "flagger"
+	config.OIDCClientSecret = "s3cr3t"
+	config.OIDCRedirectURL = "http://localhost:3000/sso/callback"
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	deactivatedUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	isActive := false
+	_, err = repo.UpdateUserStatus(dbConn, deactivatedUser.UUID, &isActive, nil)
+	require.NoError(t, err)
+
+	authorize := func(oidcUser testkit.OIDCUser) (string, string) {
+		authURL, err := svc.CreateOIDCAuthURL(context.Background())
+		require.NoError(t, err)
+
+		code, state, err := provider.Authorize(authURL, oidcUser)
+		require.NoError(t, err)
+
+		return state, code
+	}
+
+	verifiedUser := testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         testkit.GenerateFakeEmail(),
+		EmailVerified: true,
+	}
+
+	validState, _ := authorize(verifiedUser)
+	unverifiedState, unverifiedCode := authorize(testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         testkit.GenerateFakeEmail(),
+		EmailVerified: false,
+	})
+	inactiveState, inactiveCode := authorize(testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         inactiveUser.Email,
+		EmailVerified: true,
+	})
+	deactivatedState, deactivatedCode := authorize(testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         deactivatedUser.Email,
+		EmailVerified: true,
+	})
+	_, otherCode := authorize(verifiedUser)
+	mismatchedState, _ := authorize(verifiedUser)
+
+	testcases := []struct {
+		name    string
+		state   string
+		code    string
+		wantErr error
+	}{
+		{
+			name:    "Unknown state",
+			state:   "unknownstate",
+			code:    otherCode,
+			wantErr: errutils.ErrInvalidOIDCState,
+		},
+		{
+			name:    "Unknown code",
+			state:   validState,
+			code:    "unknowncode",
+			wantErr: errutils.ErrOIDCAuthFailed,
+		},
+		{
+			name:    "Code issued for another state",
+			state:   mismatchedState,
+			code:    otherCode,
+			wantErr: errutils.ErrOIDCAuthFailed,
+		},
+		{
+			name:    "Unverified email",
+			state:   unverifiedState,
+			code:    unverifiedCode,
+			wantErr: errutils.ErrOIDCAuthFailed,
+		},
+		{
+			name:    "Inactive user",
+			state:   inactiveState,
+			code:    inactiveCode,
+			wantErr: errutils.ErrOIDCAuthFailed,
+		},
+		{
+			name:    "Deactivated user",
+			state:   deactivatedState,
+			code:    deactivatedCode,
+			wantErr: errutils.ErrOIDCAuthFailed,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, _, err := svc.CreateJWTFromOIDC(context.Background(), testcase.state, testcase.code, "203.0.113.42", "curl/8.5.0")
+			require.ErrorIs(t, err, testcase.wantErr)
+		})
+	}
+}
+
+func TestServicePurgeExpiredOIDCAuthRequests(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	expiredAuthRequest, err := repo.CreateOIDCAuthRequest(dbConn, &auth.OIDCAuthRequest{
+		State:        testkit.MustGenerateRandomString(32, true, true, true),
+		Nonce:        testkit.MustGenerateRandomString(32, true, true, true),
+		CodeVerifier: testkit.MustGenerateRandomString(43, true, true, true),
+		ExpiresAt:    time.Now().UTC().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	count, err := svc.PurgeExpiredOIDCAuthRequests(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	_, err = repo.ConsumeOIDCAuthRequest(dbConn, expiredAuthRequest.State)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+}
+
+func TestServicePurgeExpiredLoginThrottles(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	_, _, logger := testkit.CreateTestLogger()
+	mailClient := mailclient.NewInMemClient("support@flagger.com")
+	tmplManager := templatesmanager.NewManager()
+	repo := auth.NewRepository()
+	keySet := testkitinternal.MustCreateKeySet(config)
+	svc := auth.NewService(config, keySet, dbPool, logger, mailClient, tmplManager, repo)
+
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	expiredIPAddress := testkit.GenerateFakeIPAddress()
+	failedAt := time.Now().UTC().Add(-auth.LoginFailureWindow - time.Hour)
+	_, err = repo.RecordLoginFailure(dbConn, auth.LoginThrottleScopeIP, expiredIPAddress, failedAt, failedAt.Add(-auth.LoginFailureWindow))
+	require.NoError(t, err)
+
+	count, err := svc.PurgeExpiredLoginThrottles(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	_, err = repo.GetLoginThrottle(dbConn, auth.LoginThrottleScopeIP, expiredIPAddress)
+	require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
 }
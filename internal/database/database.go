@@ -4,9 +4,20 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Querier runs queries against the database.
+// Querier is implemented by both *pgxpool.Conn and pgx.Tx,
+// so that repository methods taking Querier can be run inside transactions.
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // CreateConnString constructs PostgreSQL connection string from Config.
 func CreateConnString(
 	hostname string,
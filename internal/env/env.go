@@ -10,25 +10,54 @@ import (
 // Config represents config variables for the server.
 // Each field can be overridden using environment variables defined in field tags.
 type Config struct {
-	Hostname                string `env:"FLAGGERAPI_HOSTNAME"`
-	Port                    int    `env:"FLAGGERAPI_PORT"`
-	SecretKey               string `env:"FLAGGERAPI_SECRET_KEY"`
-	HashingCost             int    `env:"FLAGGERAPI_HASHING_COST"`
-	FrontendBaseURL         string `env:"FLAGGERAPI_FRONTEND_BASE_URL"`
-	FrontendActivationRoute string `env:"FLAGGERAPI_FRONTEND_ACTIVATION_ROUTE"`
-	AuthAccessLifetime      int64  `env:"FLAGGERAPI_AUTH_ACCESS_LIFETIME"`
-	AuthRefreshLifetime     int64  `env:"FLAGGERAPI_AUTH_REFRESH_LIFETIME"`
-	ActivationLifetime      int64  `env:"FLAGGERAPI_ACTIVATION_LIFETIME"`
-	PostgresHostname        string `env:"FLAGGERAPI_POSTGRES_HOSTNAME"`
-	PostgresPort            int    `env:"FLAGGERAPI_POSTGRES_PORT"`
-	PostgresUsername        string `env:"FLAGGERAPI_POSTGRES_USERNAME"`
-	PostgresPassword        string `env:"FLAGGERAPI_POSTGRES_PASSWORD"`
-	PostgresDatabaseName    string `env:"FLAGGERAPI_POSTGRES_DATABASE_NAME"`
-	SMTPHostname            string `env:"FLAGGERAPI_SMTP_HOSTNAME"`
-	SMTPPort                int    `env:"FLAGGERAPI_SMTP_PORT"`
-	SMTPUsername            string `env:"FLAGGERAPI_SMTP_USERNAME"`
-	SMTPPassword            string `env:"FLAGGERAPI_SMTP_PASSWORD"`
-	MailClientType          string `env:"FLAGGERAPI_MAIL_CLIENT_TYPE"`
+	Hostname                   string `env:"FLAGGERAPI_HOSTNAME"`
+	Port                       int    `env:"FLAGGERAPI_PORT"`
+	SecretKey                  string `env:"FLAGGERAPI_SECRET_KEY"`
+	DigestPepper               string `env:"FLAGGERAPI_DIGEST_PEPPER"`
+	PasswordHasherType         string `env:"FLAGGERAPI_PASSWORD_HASHER_TYPE"`
+	HashingCost                int    `env:"FLAGGERAPI_HASHING_COST"`
+	Argon2idMemory             int    `env:"FLAGGERAPI_ARGON2ID_MEMORY"`
+	Argon2idIterations         int    `env:"FLAGGERAPI_ARGON2ID_ITERATIONS"`
+	Argon2idParallelism        int    `env:"FLAGGERAPI_ARGON2ID_PARALLELISM"`
+	PasswordMinLength          int    `env:"FLAGGERAPI_PASSWORD_MIN_LENGTH"`
+	PasswordMinCharClasses     int    `env:"FLAGGERAPI_PASSWORD_MIN_CHARACTER_CLASSES"`
+	PasswordRejectPersonalInfo bool   `env:"FLAGGERAPI_PASSWORD_REJECT_PERSONAL_INFO"`
+	PasswordRejectBreached     bool   `env:"FLAGGERAPI_PASSWORD_REJECT_BREACHED"`
+	FrontendBaseURL            string `env:"FLAGGERAPI_FRONTEND_BASE_URL"`
+	FrontendActivationRoute    string `env:"FLAGGERAPI_FRONTEND_ACTIVATION_ROUTE"`
+	FrontendPasswordResetRoute string `env:"FLAGGERAPI_FRONTEND_PASSWORD_RESET_ROUTE"`
+	FrontendEmailChangeRoute   string `env:"FLAGGERAPI_FRONTEND_EMAIL_CHANGE_ROUTE"`
+	FrontendMagicLinkRoute     string `env:"FLAGGERAPI_FRONTEND_MAGIC_LINK_ROUTE"`
+	AuthAccessLifetime         int64  `env:"FLAGGERAPI_AUTH_ACCESS_LIFETIME"`
+	AuthRefreshLifetime        int64  `env:"FLAGGERAPI_AUTH_REFRESH_LIFETIME"`
+	AuthTokenTransport         string `env:"FLAGGERAPI_AUTH_TOKEN_TRANSPORT"`
+	AuthAccessTokenCookie      bool   `env:"FLAGGERAPI_AUTH_ACCESS_TOKEN_COOKIE"`
+	ActivationLifetime         int64  `env:"FLAGGERAPI_ACTIVATION_LIFETIME"`
+	PasswordResetLifetime      int64  `env:"FLAGGERAPI_PASSWORD_RESET_LIFETIME"`
+	EmailChangeLifetime        int64  `env:"FLAGGERAPI_EMAIL_CHANGE_LIFETIME"`
+	MagicLinkLifetime          int64  `env:"FLAGGERAPI_MAGIC_LINK_LIFETIME"`
+	APIKeyRotationGracePeriod  int64  `env:"FLAGGERAPI_API_KEY_ROTATION_GRACE_PERIOD"`
+	TrustedProxies             string `env:"FLAGGERAPI_TRUSTED_PROXIES"`
+	CORSAllowedOrigins         string `env:"FLAGGERAPI_CORS_ALLOWED_ORIGINS"`
+	CORSMaxAge                 int64  `env:"FLAGGERAPI_CORS_MAX_AGE"`
+	JWTSigningKeyFile          string `env:"FLAGGERAPI_JWT_SIGNING_KEY_FILE"`
+	JWTVerificationKeyFiles    string `env:"FLAGGERAPI_JWT_VERIFICATION_KEY_FILES"`
+	JWTVerifySecretKey         bool   `env:"FLAGGERAPI_JWT_VERIFY_SECRET_KEY"`
+	PostgresHostname           string `env:"FLAGGERAPI_POSTGRES_HOSTNAME"`
+	PostgresPort               int    `env:"FLAGGERAPI_POSTGRES_PORT"`
+	PostgresUsername           string `env:"FLAGGERAPI_POSTGRES_USERNAME"`
+	PostgresPassword           string `env:"FLAGGERAPI_POSTGRES_PASSWORD"`
+	PostgresDatabaseName       string `env:"FLAGGERAPI_POSTGRES_DATABASE_NAME"`
+	SMTPHostname               string `env:"FLAGGERAPI_SMTP_HOSTNAME"`
+	SMTPPort                   int    `env:"FLAGGERAPI_SMTP_PORT"`
+	SMTPUsername               string `env:"FLAGGERAPI_SMTP_USERNAME"`
+	SMTPPassword               string `env:"FLAGGERAPI_SMTP_PASSWORD"`
+	MailClientType             string `env:"FLAGGERAPI_MAIL_CLIENT_TYPE"`
+	RateLimitStoreType         string `env:"FLAGGERAPI_RATE_LIMIT_STORE_TYPE"`
+	OIDCIssuerURL              string `env:"FLAGGERAPI_OIDC_ISSUER_URL"`
+	OIDCClientID               string `env:"FLAGGERAPI_OIDC_CLIENT_ID"`
+	OIDCClientSecret           string `env:"FLAGGERAPI_OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL            string `env:"FLAGGERAPI_OIDC_REDIRECT_URL"`
 }
 
 // NewConfig reads environment variables and returns a new config
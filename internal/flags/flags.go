@@ -3,11 +3,13 @@ package flags
 import "time"
 
 // Flag represents database table of Flags.
+// Client-side Flags can be evaluated using client-side API keys, which are meant to be embedded in browser and mobile apps.
 type Flag struct {
-	ID        int       `db:"id"`
-	UserUUID  string    `db:"user_uuid"`
-	Name      string    `db:"name"`
-	IsEnabled bool      `db:"is_enabled"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID           int       `db:"id"`
+	UserUUID     string    `db:"user_uuid"`
+	Name         string    `db:"name"`
+	IsEnabled    bool      `db:"is_enabled"`
+	IsClientSide bool      `db:"is_client_side"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
 }
@@ -8,16 +8,29 @@ import (
 	"github.com/alvii147/flagger-api/pkg/errutils"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Repository is used to access and update Flags data.
+// Flags of inactive Users are hidden, unless they are accessed through an active service account owned by the User,
+// so that service account API keys keep working after their owner is deactivated.
 type Repository interface {
 	CreateFlag(dbConn *pgxpool.Conn, flag *Flag) (*Flag, error)
-	GetFlagByID(dbConn *pgxpool.Conn, flagID int, userUUID string) (*Flag, error)
-	GetFlagByName(dbConn *pgxpool.Conn, flagName string, userUUID string) (*Flag, error)
-	ListFlagsByUserUUID(dbConn *pgxpool.Conn, userUUID string) ([]*Flag, error)
-	UpdateFlag(dbConn *pgxpool.Conn, flag *Flag) (*Flag, error)
+	GetFlagByID(dbConn *pgxpool.Conn, flagID int, userUUID string, serviceAccountUUID pgtype.Text) (*Flag, error)
+	GetFlagByName(dbConn *pgxpool.Conn, flagName string, userUUID string, serviceAccountUUID pgtype.Text) (*Flag, error)
+	ListFlagsByUserUUID(dbConn *pgxpool.Conn, userUUID string, serviceAccountUUID pgtype.Text) ([]*Flag, error)
+	ListAllFlagsByUserUUID(dbConn *pgxpool.Conn, userUUID string) ([]*Flag, error)
+	GetClientSideFlagByName(dbConn *pgxpool.Conn, flagName string, userUUID string, serviceAccountUUID pgtype.Text) (*Flag, error)
+	ListClientSideFlagsByUserUUID(dbConn *pgxpool.Conn, userUUID string, serviceAccountUUID pgtype.Text) ([]*Flag, error)
+	UpdateFlag(
+		dbConn *pgxpool.Conn,
+		flagID int,
+		userUUID string,
+		serviceAccountUUID pgtype.Text,
+		isEnabled bool,
+		isClientSide *bool,
+	) (*Flag, error)
 }
 
 // repository implements Repository.
@@ -28,24 +41,27 @@ func NewRepository() *repository {
 	return &repository{}
 }
 
-// CreateFlag creates new Flag given User UUID and Flag name.
+// CreateFlag creates new Flag given User UUID, Flag name, and whether or not it is client-side.
 func (repo *repository) CreateFlag(dbConn *pgxpool.Conn, flag *Flag) (*Flag, error) {
 	createdFlag := &Flag{}
 
 	q := `
 INSERT INTO Flag (
 	user_uuid,
-	name
+	name,
+	is_client_side
 )
 VALUES (
 	$1,
-	$2
+	$2,
+	$3
 )
 RETURNING
 	id,
 	user_uuid,
 	name,
 	is_enabled,
+	is_client_side,
 	created_at,
 	updated_at;
 	`
@@ -55,11 +71,13 @@ RETURNING
 		q,
 		flag.UserUUID,
 		flag.Name,
+		flag.IsClientSide,
 	).Scan(
 		&createdFlag.ID,
 		&createdFlag.UserUUID,
 		&createdFlag.Name,
 		&createdFlag.IsEnabled,
+		&createdFlag.IsClientSide,
 		&createdFlag.CreatedAt,
 		&createdFlag.UpdatedAt,
 	)
@@ -80,7 +98,12 @@ RETURNING
 
 // GetFlagByID fetches Flag by ID.
 // If no Flag found, error is returned.
-func (repo *repository) GetFlagByID(dbConn *pgxpool.Conn, flagID int, userUUID string) (*Flag, error) {
+func (repo *repository) GetFlagByID(
+	dbConn *pgxpool.Conn,
+	flagID int,
+	userUUID string,
+	serviceAccountUUID pgtype.Text,
+) (*Flag, error) {
 	flag := &Flag{}
 
 	q := `
@@ -89,6 +112,7 @@ SELECT
 	f.user_uuid,
 	f.name,
 	f.is_enabled,
+	f.is_client_side,
 	f.created_at,
 	f.updated_at
 FROM
@@ -97,17 +121,23 @@ INNER JOIN
 	"User" u
 ON
 	f.user_uuid = u.uuid
+LEFT JOIN
+	ServiceAccount sa
+ON
+	sa.uuid = $3
+	AND sa.owner_uuid = f.user_uuid
 WHERE
 	f.id = $1
 	AND f.user_uuid = $2
-	AND u.is_active = TRUE;
+	AND (u.is_active = TRUE OR sa.is_active = TRUE);
 	`
 
-	err := dbConn.QueryRow(context.Background(), q, flagID, userUUID).Scan(
+	err := dbConn.QueryRow(context.Background(), q, flagID, userUUID, serviceAccountUUID).Scan(
 		&flag.ID,
 		&flag.UserUUID,
 		&flag.Name,
 		&flag.IsEnabled,
+		&flag.IsClientSide,
 		&flag.CreatedAt,
 		&flag.UpdatedAt,
 	)
@@ -125,7 +155,12 @@ WHERE
 
 // GetFlagByName fetches Flag by name.
 // If no Flag found, error is returned.
-func (repo *repository) GetFlagByName(dbConn *pgxpool.Conn, name string, userUUID string) (*Flag, error) {
+func (repo *repository) GetFlagByName(
+	dbConn *pgxpool.Conn,
+	name string,
+	userUUID string,
+	serviceAccountUUID pgtype.Text,
+) (*Flag, error) {
 	flag := &Flag{}
 
 	q := `
@@ -134,6 +169,7 @@ SELECT
 	f.user_uuid,
 	f.name,
 	f.is_enabled,
+	f.is_client_side,
 	f.created_at,
 	f.updated_at
 FROM
@@ -142,17 +178,23 @@ INNER JOIN
 	"User" u
 ON
 	f.user_uuid = u.uuid
+LEFT JOIN
+	ServiceAccount sa
+ON
+	sa.uuid = $3
+	AND sa.owner_uuid = f.user_uuid
 WHERE
 	f.name = $1
 	AND f.user_uuid = $2
-	AND u.is_active = TRUE;
+	AND (u.is_active = TRUE OR sa.is_active = TRUE);
 	`
 
-	err := dbConn.QueryRow(context.Background(), q, name, userUUID).Scan(
+	err := dbConn.QueryRow(context.Background(), q, name, userUUID, serviceAccountUUID).Scan(
 		&flag.ID,
 		&flag.UserUUID,
 		&flag.Name,
 		&flag.IsEnabled,
+		&flag.IsClientSide,
 		&flag.CreatedAt,
 		&flag.UpdatedAt,
 	)
@@ -169,7 +211,11 @@ WHERE
 }
 
 // ListFlagsByUserUUID fetches Flags under a given User UUID.
-func (repo *repository) ListFlagsByUserUUID(dbConn *pgxpool.Conn, userUUID string) ([]*Flag, error) {
+func (repo *repository) ListFlagsByUserUUID(
+	dbConn *pgxpool.Conn,
+	userUUID string,
+	serviceAccountUUID pgtype.Text,
+) ([]*Flag, error) {
 	flags := make([]*Flag, 0)
 
 	q := `
@@ -178,6 +224,7 @@ SELECT
 	f.user_uuid,
 	f.name,
 	f.is_enabled,
+	f.is_client_side,
 	f.created_at,
 	f.updated_at
 FROM
@@ -186,12 +233,17 @@ INNER JOIN
 	"User" u
 ON
 	f.user_uuid = u.uuid
+LEFT JOIN
+	ServiceAccount sa
+ON
+	sa.uuid = $2
+	AND sa.owner_uuid = f.user_uuid
 WHERE
 	f.user_uuid = $1
-	AND u.is_active = TRUE;
+	AND (u.is_active = TRUE OR sa.is_active = TRUE);
 	`
 
-	rows, err := dbConn.Query(context.Background(), q, userUUID)
+	rows, err := dbConn.Query(context.Background(), q, userUUID, serviceAccountUUID)
 	if err != nil {
 		return nil, fmt.Errorf("ListFlagsByUserUUID failed to dbConn.Query: %w", err)
 	}
@@ -204,6 +256,7 @@ WHERE
 			&flag.UserUUID,
 			&flag.Name,
 			&flag.IsEnabled,
+			&flag.IsClientSide,
 			&flag.CreatedAt,
 			&flag.UpdatedAt,
 		)
@@ -217,28 +270,207 @@ WHERE
 	return flags, nil
 }
 
-// UpdateFlag updates a Flag's enabled state.
+// ListAllFlagsByUserUUID fetches Flags under a given User UUID, whether the User is active or not.
+func (repo *repository) ListAllFlagsByUserUUID(dbConn *pgxpool.Conn, userUUID string) ([]*Flag, error) {
+	flags := make([]*Flag, 0)
+
+	q := `
+SELECT
+	id,
+	user_uuid,
+	name,
+	is_enabled,
+	is_client_side,
+	created_at,
+	updated_at
+FROM
+	Flag
+WHERE
+	user_uuid = $1;
+	`
+
+	rows, err := dbConn.Query(context.Background(), q, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ListAllFlagsByUserUUID failed to dbConn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		flag := &Flag{}
+		err := rows.Scan(
+			&flag.ID,
+			&flag.UserUUID,
+			&flag.Name,
+			&flag.IsEnabled,
+			&flag.IsClientSide,
+			&flag.CreatedAt,
+			&flag.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ListAllFlagsByUserUUID failed to rows.Scan: %w", err)
+		}
+
+		flags = append(flags, flag)
+	}
+
+	return flags, nil
+}
+
+// GetClientSideFlagByName fetches client-side Flag by name.
+// If no client-side Flag found, error is returned.
+func (repo *repository) GetClientSideFlagByName(
+	dbConn *pgxpool.Conn,
+	name string,
+	userUUID string,
+	serviceAccountUUID pgtype.Text,
+) (*Flag, error) {
+	flag := &Flag{}
+
+	q := `
+SELECT
+	f.id,
+	f.user_uuid,
+	f.name,
+	f.is_enabled,
+	f.is_client_side,
+	f.created_at,
+	f.updated_at
+FROM
+	Flag f
+INNER JOIN
+	"User" u
+ON
+	f.user_uuid = u.uuid
+LEFT JOIN
+	ServiceAccount sa
+ON
+	sa.uuid = $3
+	AND sa.owner_uuid = f.user_uuid
+WHERE
+	f.name = $1
+	AND f.user_uuid = $2
+	AND f.is_client_side = TRUE
+	AND (u.is_active = TRUE OR sa.is_active = TRUE);
+	`
+
+	err := dbConn.QueryRow(context.Background(), q, name, userUUID, serviceAccountUUID).Scan(
+		&flag.ID,
+		&flag.UserUUID,
+		&flag.Name,
+		&flag.IsEnabled,
+		&flag.IsClientSide,
+		&flag.CreatedAt,
+		&flag.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("GetClientSideFlagByName failed: %w", errutils.ErrDatabaseNoRowsReturned)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("GetClientSideFlagByName failed to dbConn.Scan: %w", err)
+	}
+
+	return flag, nil
+}
+
+// ListClientSideFlagsByUserUUID fetches client-side Flags under a given User UUID.
+func (repo *repository) ListClientSideFlagsByUserUUID(
+	dbConn *pgxpool.Conn,
+	userUUID string,
+	serviceAccountUUID pgtype.Text,
+) ([]*Flag, error) {
+	flags := make([]*Flag, 0)
+
+	q := `
+SELECT
+	f.id,
+	f.user_uuid,
+	f.name,
+	f.is_enabled,
+	f.is_client_side,
+	f.created_at,
+	f.updated_at
+FROM
+	Flag f
+INNER JOIN
+	"User" u
+ON
+	f.user_uuid = u.uuid
+LEFT JOIN
+	ServiceAccount sa
+ON
+	sa.uuid = $2
+	AND sa.owner_uuid = f.user_uuid
+WHERE
+	f.user_uuid = $1
+	AND f.is_client_side = TRUE
+	AND (u.is_active = TRUE OR sa.is_active = TRUE);
+	`
+
+	rows, err := dbConn.Query(context.Background(), q, userUUID, serviceAccountUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ListClientSideFlagsByUserUUID failed to dbConn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		flag := &Flag{}
+		err := rows.Scan(
+			&flag.ID,
+			&flag.UserUUID,
+			&flag.Name,
+			&flag.IsEnabled,
+			&flag.IsClientSide,
+			&flag.CreatedAt,
+			&flag.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ListClientSideFlagsByUserUUID failed to rows.Scan: %w", err)
+		}
+
+		flags = append(flags, flag)
+	}
+
+	return flags, nil
+}
+
+// UpdateFlag updates a Flag's enabled state, and whether or not it is client-side if given.
 // If no Flag is affected, error is returned.
-func (repo *repository) UpdateFlag(dbConn *pgxpool.Conn, flag *Flag) (*Flag, error) {
+func (repo *repository) UpdateFlag(
+	dbConn *pgxpool.Conn,
+	flagID int,
+	userUUID string,
+	serviceAccountUUID pgtype.Text,
+	isEnabled bool,
+	isClientSide *bool,
+) (*Flag, error) {
 	updatedFlag := &Flag{}
 
 	q := `
 UPDATE
 	Flag f
 SET
-	is_enabled = $1
+	is_enabled = $1,
+	is_client_side = COALESCE($2, f.is_client_side)
 FROM
 	"User" u
+LEFT JOIN
+	ServiceAccount sa
+ON
+	sa.uuid = $5
+	AND sa.owner_uuid = u.uuid
 WHERE
-	f.id = $2
-	AND f.user_uuid = $3
+	f.id = $3
+	AND f.user_uuid = $4
 	AND f.user_uuid = u.uuid
-	AND u.is_active = TRUE
+	AND (u.is_active = TRUE OR sa.is_active = TRUE)
 RETURNING
 	f.id,
 	f.user_uuid,
 	f.name,
 	f.is_enabled,
+	f.is_client_side,
 	f.created_at,
 	f.updated_at;
 	`
@@ -246,14 +478,17 @@ RETURNING
 	err := dbConn.QueryRow(
 		context.Background(),
 		q,
-		flag.IsEnabled,
-		flag.ID,
-		flag.UserUUID,
+		isEnabled,
+		isClientSide,
+		flagID,
+		userUUID,
+		serviceAccountUUID,
 	).Scan(
 		&updatedFlag.ID,
 		&updatedFlag.UserUUID,
 		&updatedFlag.Name,
 		&updatedFlag.IsEnabled,
+		&updatedFlag.IsClientSide,
 		&updatedFlag.CreatedAt,
 		&updatedFlag.UpdatedAt,
 	)
@@ -11,6 +11,7 @@ import (
 	"github.com/alvii147/flagger-api/internal/testkitinternal"
 	"github.com/alvii147/flagger-api/pkg/errutils"
 	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -48,7 +49,7 @@ func TestRepositoryCreateFlagDuplicateName(t *testing.T) {
 		u.IsActive = true
 	})
 
-	testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag")
+	testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag", false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
@@ -70,13 +71,13 @@ func TestRepositoryGetFlagByIDSuccess(t *testing.T) {
 		u.IsActive = true
 	})
 
-	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag")
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag", false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := flags.NewRepository()
 
-	fetchedFlag, err := repo.GetFlagByID(dbConn, flag.ID, user.UUID)
+	fetchedFlag, err := repo.GetFlagByID(dbConn, flag.ID, user.UUID, pgtype.Text{})
 	require.NoError(t, err)
 
 	require.Equal(t, flag.ID, fetchedFlag.ID)
@@ -98,7 +99,7 @@ func TestRepositoryGetFlagByIDError(t *testing.T) {
 		u.IsActive = false
 	})
 
-	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag")
+	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag", false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := flags.NewRepository()
@@ -125,7 +126,7 @@ func TestRepositoryGetFlagByIDError(t *testing.T) {
 			t.Parallel()
 
 			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
-			_, err := repo.GetFlagByID(dbConn, testcase.flagID, testcase.userUUID)
+			_, err := repo.GetFlagByID(dbConn, testcase.flagID, testcase.userUUID, pgtype.Text{})
 			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
 		})
 	}
@@ -138,13 +139,13 @@ func TestRepositoryGetFlagByNameSuccess(t *testing.T) {
 		u.IsActive = true
 	})
 
-	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag")
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag", false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := flags.NewRepository()
 
-	fetchedFlag, err := repo.GetFlagByName(dbConn, "my-flag", user.UUID)
+	fetchedFlag, err := repo.GetFlagByName(dbConn, "my-flag", user.UUID, pgtype.Text{})
 	require.NoError(t, err)
 
 	require.Equal(t, flag.ID, fetchedFlag.ID)
@@ -166,7 +167,7 @@ func TestRepositoryGetFlagByNameError(t *testing.T) {
 		u.IsActive = false
 	})
 
-	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag")
+	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag", false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := flags.NewRepository()
@@ -193,12 +194,86 @@ func TestRepositoryGetFlagByNameError(t *testing.T) {
 			t.Parallel()
 
 			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
-			_, err := repo.GetFlagByName(dbConn, testcase.flagName, testcase.userUUID)
+			_, err := repo.GetFlagByName(dbConn, testcase.flagName, testcase.userUUID, pgtype.Text{})
 			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
 		})
 	}
 }
 
+func TestRepositoryGetFlagByNameServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	flag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag", false)
+	activeServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, inactiveUser.UUID, nil)
+	inactiveServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, inactiveUser.UUID, func(sa *auth.ServiceAccount) {
+		sa.IsActive = false
+	})
+	otherUserServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, otherUser.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := flags.NewRepository()
+
+	testcases := []struct {
+		name               string
+		serviceAccountUUID pgtype.Text
+		wantErr            error
+	}{
+		{
+			name: "Active service account of inactive user",
+			serviceAccountUUID: pgtype.Text{
+				String: activeServiceAccount.UUID,
+				Valid:  true,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "Inactive service account of inactive user",
+			serviceAccountUUID: pgtype.Text{
+				String: inactiveServiceAccount.UUID,
+				Valid:  true,
+			},
+			wantErr: errutils.ErrDatabaseNoRowsReturned,
+		},
+		{
+			name: "Active service account of another user",
+			serviceAccountUUID: pgtype.Text{
+				String: otherUserServiceAccount.UUID,
+				Valid:  true,
+			},
+			wantErr: errutils.ErrDatabaseNoRowsReturned,
+		},
+		{
+			name:               "No service account",
+			serviceAccountUUID: pgtype.Text{},
+			wantErr:            errutils.ErrDatabaseNoRowsReturned,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			fetchedFlag, err := repo.GetFlagByName(dbConn, flag.Name, inactiveUser.UUID, testcase.serviceAccountUUID)
+			if testcase.wantErr != nil {
+				require.ErrorIs(t, err, testcase.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, flag.ID, fetchedFlag.ID)
+		})
+	}
+}
+
 func TestRepositoryListFlagsByUserUUIDSuccess(t *testing.T) {
 	t.Parallel()
 
@@ -206,8 +281,8 @@ func TestRepositoryListFlagsByUserUUIDSuccess(t *testing.T) {
 		u.IsActive = true
 	})
 
-	flag1 := testkitinternal.MustCreateUserFlag(t, user.UUID, "flag-1")
-	flag2 := testkitinternal.MustCreateUserFlag(t, user.UUID, "flag-2")
+	flag1 := testkitinternal.MustCreateUserFlag(t, user.UUID, "flag-1", false)
+	flag2 := testkitinternal.MustCreateUserFlag(t, user.UUID, "flag-2", false)
 
 	wantFlags := []*flags.Flag{flag1, flag2}
 	sort.Slice(wantFlags, func(i, j int) bool {
@@ -218,7 +293,7 @@ func TestRepositoryListFlagsByUserUUIDSuccess(t *testing.T) {
 	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := flags.NewRepository()
 
-	userFlags, err := repo.ListFlagsByUserUUID(dbConn, user.UUID)
+	userFlags, err := repo.ListFlagsByUserUUID(dbConn, user.UUID, pgtype.Text{})
 	require.NoError(t, err)
 	require.Len(t, userFlags, 2)
 
@@ -244,17 +319,212 @@ func TestRepositoryListFlagsByUserUUIDInactiveUser(t *testing.T) {
 		u.IsActive = false
 	})
 
-	testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag")
+	testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag", false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := flags.NewRepository()
 
-	userFlags, err := repo.ListFlagsByUserUUID(dbConn, user.UUID)
+	userFlags, err := repo.ListFlagsByUserUUID(dbConn, user.UUID, pgtype.Text{})
 	require.NoError(t, err)
 	require.Empty(t, userFlags)
 }
 
+func TestRepositoryListAllFlagsByUserUUID(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := flags.NewRepository()
+
+	testcases := []struct {
+		name     string
+		isActive bool
+	}{
+		{
+			name:     "Active user",
+			isActive: true,
+		},
+		{
+			name:     "Inactive user",
+			isActive: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+				u.IsActive = testcase.isActive
+			})
+			flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag", false)
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			userFlags, err := repo.ListAllFlagsByUserUUID(dbConn, user.UUID)
+			require.NoError(t, err)
+			require.Len(t, userFlags, 1)
+			require.Equal(t, flag.ID, userFlags[0].ID)
+			require.Equal(t, flag.UserUUID, userFlags[0].UserUUID)
+			require.Equal(t, flag.Name, userFlags[0].Name)
+		})
+	}
+}
+
+func TestRepositoryGetClientSideFlagByNameSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag", true)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := flags.NewRepository()
+
+	fetchedFlag, err := repo.GetClientSideFlagByName(dbConn, "my-flag", user.UUID, pgtype.Text{})
+	require.NoError(t, err)
+
+	require.Equal(t, flag.ID, fetchedFlag.ID)
+	require.Equal(t, flag.UserUUID, fetchedFlag.UserUUID)
+	require.Equal(t, flag.Name, fetchedFlag.Name)
+	require.False(t, fetchedFlag.IsEnabled)
+	require.True(t, fetchedFlag.IsClientSide)
+	require.Equal(t, flag.CreatedAt, fetchedFlag.CreatedAt)
+	require.Equal(t, flag.UpdatedAt, fetchedFlag.UpdatedAt)
+}
+
+func TestRepositoryGetClientSideFlagByNameError(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	activeUserFlag := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "my-flag", false)
+	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag", true)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := flags.NewRepository()
+
+	testcases := []struct {
+		name     string
+		flagName string
+		userUUID string
+	}{
+		{
+			name:     "Active user with no flags",
+			flagName: "unknown-flag",
+			userUUID: activeUser.UUID,
+		},
+		{
+			name:     "Active user with flag that is not client-side",
+			flagName: activeUserFlag.Name,
+			userUUID: activeUser.UUID,
+		},
+		{
+			name:     "Inactive user with valid flag",
+			flagName: inactiveUserFlag.Name,
+			userUUID: inactiveUser.UUID,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			_, err := repo.GetClientSideFlagByName(dbConn, testcase.flagName, testcase.userUUID, pgtype.Text{})
+			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsReturned)
+		})
+	}
+}
+
+func TestRepositoryListClientSideFlagsByUserUUID(t *testing.T) {
+	t.Parallel()
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	clientSideFlag := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "client-side-flag", true)
+	testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "private-flag", false)
+	testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "client-side-flag", true)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := flags.NewRepository()
+
+	testcases := []struct {
+		name      string
+		userUUID  string
+		wantFlags []*flags.Flag
+	}{
+		{
+			name:      "Active user",
+			userUUID:  activeUser.UUID,
+			wantFlags: []*flags.Flag{clientSideFlag},
+		},
+		{
+			name:      "Inactive user",
+			userUUID:  inactiveUser.UUID,
+			wantFlags: []*flags.Flag{},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+			userFlags, err := repo.ListClientSideFlagsByUserUUID(dbConn, testcase.userUUID, pgtype.Text{})
+			require.NoError(t, err)
+			require.Len(t, userFlags, len(testcase.wantFlags))
+
+			for i, flag := range userFlags {
+				wantFlag := testcase.wantFlags[i]
+				require.Equal(t, wantFlag.ID, flag.ID)
+				require.Equal(t, wantFlag.UserUUID, flag.UserUUID)
+				require.Equal(t, wantFlag.Name, flag.Name)
+				require.True(t, flag.IsClientSide)
+			}
+		})
+	}
+}
+
+func TestRepositoryUpdateFlagClientSide(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag", false)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := flags.NewRepository()
+
+	isClientSide := true
+	updatedFlag, err := repo.UpdateFlag(dbConn, flag.ID, user.UUID, pgtype.Text{}, false, &isClientSide)
+	require.NoError(t, err)
+	require.False(t, updatedFlag.IsEnabled)
+	require.True(t, updatedFlag.IsClientSide)
+
+	updatedFlag, err = repo.UpdateFlag(dbConn, flag.ID, user.UUID, pgtype.Text{}, true, nil)
+	require.NoError(t, err)
+	require.True(t, updatedFlag.IsEnabled)
+	require.True(t, updatedFlag.IsClientSide)
+}
+
 func TestRepositoryUpdateFlagSuccess(t *testing.T) {
 	t.Parallel()
 
@@ -264,7 +534,7 @@ func TestRepositoryUpdateFlagSuccess(t *testing.T) {
 
 	flagName := "my-flag"
 	createdAt := time.Now().UTC()
-	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, flagName)
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, flagName, false)
 
 	time.Sleep(4 * time.Second)
 
@@ -272,20 +542,15 @@ func TestRepositoryUpdateFlagSuccess(t *testing.T) {
 	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := flags.NewRepository()
 
-	flag = &flags.Flag{
-		ID:        flag.ID,
-		UserUUID:  user.UUID,
-		IsEnabled: true,
-	}
-
 	updatedAt := time.Now().UTC()
-	updatedFlag, err := repo.UpdateFlag(dbConn, flag)
+	updatedFlag, err := repo.UpdateFlag(dbConn, flag.ID, user.UUID, pgtype.Text{}, true, nil)
 	require.NoError(t, err)
 
 	require.Equal(t, flag.ID, updatedFlag.ID)
 	require.Equal(t, flag.UserUUID, updatedFlag.UserUUID)
 	require.Equal(t, flagName, updatedFlag.Name)
 	require.True(t, updatedFlag.IsEnabled)
+	require.False(t, updatedFlag.IsClientSide)
 	testkit.RequireTimeAlmostEqual(t, createdAt, updatedFlag.CreatedAt)
 	testkit.RequireTimeAlmostEqual(t, updatedAt, updatedFlag.UpdatedAt)
 }
@@ -301,7 +566,7 @@ func TestRepositoryUpdateFlagError(t *testing.T) {
 		u.IsActive = false
 	})
 
-	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag")
+	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag", false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := flags.NewRepository()
@@ -327,14 +592,8 @@ func TestRepositoryUpdateFlagError(t *testing.T) {
 		t.Run(testcase.name, func(t *testing.T) {
 			t.Parallel()
 
-			flag := &flags.Flag{
-				ID:        testcase.flagID,
-				UserUUID:  testcase.userUUID,
-				IsEnabled: true,
-			}
-
 			dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
-			_, err := repo.UpdateFlag(dbConn, flag)
+			_, err := repo.UpdateFlag(dbConn, testcase.flagID, testcase.userUUID, pgtype.Text{}, true, nil)
 			require.ErrorIs(t, err, errutils.ErrDatabaseNoRowsAffected)
 		})
 	}
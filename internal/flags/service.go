@@ -7,16 +7,20 @@ import (
 
 	"github.com/alvii147/flagger-api/internal/auth"
 	"github.com/alvii147/flagger-api/pkg/errutils"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Service performs all Flag related business logic.
 type Service interface {
-	CreateFlag(ctx context.Context, name string) (*Flag, error)
+	CreateFlag(ctx context.Context, name string, isClientSide bool) (*Flag, error)
 	GetFlagByID(ctx context.Context, flagID int) (*Flag, error)
 	GetFlagByName(ctx context.Context, name string) (*Flag, error)
 	ListFlags(ctx context.Context) ([]*Flag, error)
-	UpdateFlag(ctx context.Context, flagID int, isEnabled bool) (*Flag, error)
+	ListUserFlags(ctx context.Context, userUUID string) ([]*Flag, error)
+	GetClientSideFlagByName(ctx context.Context, name string) (*Flag, error)
+	ListClientSideFlags(ctx context.Context) ([]*Flag, error)
+	UpdateFlag(ctx context.Context, flagID int, isEnabled bool, isClientSide *bool) (*Flag, error)
 }
 
 // service implements Service.
@@ -33,16 +37,27 @@ func NewService(dbPool *pgxpool.Pool, repo Repository) *service {
 	}
 }
 
+// serviceAccountUUIDFromContext returns the UUID of the service account authenticated in context, if any.
+func serviceAccountUUIDFromContext(ctx context.Context) pgtype.Text {
+	serviceAccountUUID, ok := ctx.Value(auth.AuthContextKeyServiceAccountUUID).(string)
+
+	return pgtype.Text{
+		String: serviceAccountUUID,
+		Valid:  ok,
+	}
+}
+
 // CreateFlag creates new Flag for User.
-func (svc *service) CreateFlag(ctx context.Context, name string) (*Flag, error) {
+func (svc *service) CreateFlag(ctx context.Context, name string, isClientSide bool) (*Flag, error) {
 	userUUID, ok := ctx.Value(auth.AuthContextKeyUserUUID).(string)
 	if !ok {
 		return nil, errors.New("CreateFlag failed to ctx.Value user UUID from ctx")
 	}
 
 	flag := &Flag{
-		UserUUID: userUUID,
-		Name:     name,
+		UserUUID:     userUUID,
+		Name:         name,
+		IsClientSide: isClientSide,
 	}
 
 	dbConn, err := svc.dbPool.Acquire(ctx)
@@ -78,7 +93,7 @@ func (svc *service) GetFlagByID(ctx context.Context, flagID int) (*Flag, error)
 	}
 	defer dbConn.Release()
 
-	flag, err := svc.repository.GetFlagByID(dbConn, flagID, userUUID)
+	flag, err := svc.repository.GetFlagByID(dbConn, flagID, userUUID, serviceAccountUUIDFromContext(ctx))
 	if err != nil {
 		switch {
 		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
@@ -105,7 +120,7 @@ func (svc *service) GetFlagByName(ctx context.Context, name string) (*Flag, erro
 	}
 	defer dbConn.Release()
 
-	flag, err := svc.repository.GetFlagByName(dbConn, name, userUUID)
+	flag, err := svc.repository.GetFlagByName(dbConn, name, userUUID, serviceAccountUUIDFromContext(ctx))
 	if err != nil {
 		switch {
 		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
@@ -132,7 +147,7 @@ func (svc *service) ListFlags(ctx context.Context) ([]*Flag, error) {
 	}
 	defer dbConn.Release()
 
-	flags, err := svc.repository.ListFlagsByUserUUID(dbConn, userUUID)
+	flags, err := svc.repository.ListFlagsByUserUUID(dbConn, userUUID, serviceAccountUUIDFromContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("ListFlags failed to svc.repository.GetFlagsByUserUUID: %w", err)
 	}
@@ -140,8 +155,74 @@ func (svc *service) ListFlags(ctx context.Context) ([]*Flag, error) {
 	return flags, nil
 }
 
+// ListUserFlags retrieves Flags of any User, active or not, for superusers to inspect.
+func (svc *service) ListUserFlags(ctx context.Context, userUUID string) ([]*Flag, error) {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListUserFlags failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	flags, err := svc.repository.ListAllFlagsByUserUUID(dbConn, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ListUserFlags failed to svc.repository.ListAllFlagsByUserUUID: %w", err)
+	}
+
+	return flags, nil
+}
+
+// GetClientSideFlagByName retrieves client-side Flag by name for currently authenticated User.
+// Flags that are not client-side are treated as not found, so that their existence is not revealed.
+func (svc *service) GetClientSideFlagByName(ctx context.Context, name string) (*Flag, error) {
+	userUUID, ok := ctx.Value(auth.AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, errors.New("GetClientSideFlagByName failed to ctx.Value user UUID from ctx")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetClientSideFlagByName failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	flag, err := svc.repository.GetClientSideFlagByName(dbConn, name, userUUID, serviceAccountUUIDFromContext(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrDatabaseNoRowsReturned):
+			err = fmt.Errorf("GetClientSideFlagByName failed to svc.repository.GetClientSideFlagByName, %w: %w", errutils.ErrFlagNotFound, err)
+		default:
+			err = fmt.Errorf("GetClientSideFlagByName failed to svc.repository.GetClientSideFlagByName: %w", err)
+		}
+		return nil, err
+	}
+
+	return flag, nil
+}
+
+// ListClientSideFlags retrieves client-side Flags for currently authenticated User.
+func (svc *service) ListClientSideFlags(ctx context.Context) ([]*Flag, error) {
+	userUUID, ok := ctx.Value(auth.AuthContextKeyUserUUID).(string)
+	if !ok {
+		return nil, errors.New("ListClientSideFlags failed to ctx.Value user UUID from ctx")
+	}
+
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListClientSideFlags failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	flags, err := svc.repository.ListClientSideFlagsByUserUUID(dbConn, userUUID, serviceAccountUUIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("ListClientSideFlags failed to svc.repository.ListClientSideFlagsByUserUUID: %w", err)
+	}
+
+	return flags, nil
+}
+
 // UpdateFlag updates Flag by ID for currently authenticated User.
-func (svc *service) UpdateFlag(ctx context.Context, flagID int, isEnabled bool) (*Flag, error) {
+// Whether or not the Flag is client-side is left unchanged if isClientSide is nil.
+func (svc *service) UpdateFlag(ctx context.Context, flagID int, isEnabled bool, isClientSide *bool) (*Flag, error) {
 	userUUID, ok := ctx.Value(auth.AuthContextKeyUserUUID).(string)
 	if !ok {
 		return nil, errors.New("UpdateFlag failed to ctx.Value user UUID from ctx")
@@ -153,13 +234,14 @@ func (svc *service) UpdateFlag(ctx context.Context, flagID int, isEnabled bool)
 	}
 	defer dbConn.Release()
 
-	flag := &Flag{
-		ID:        flagID,
-		UserUUID:  userUUID,
-		IsEnabled: isEnabled,
-	}
-
-	flag, err = svc.repository.UpdateFlag(dbConn, flag)
+	flag, err := svc.repository.UpdateFlag(
+		dbConn,
+		flagID,
+		userUUID,
+		serviceAccountUUIDFromContext(ctx),
+		isEnabled,
+		isClientSide,
+	)
 	if err != nil {
 		switch {
 		case errors.Is(err, errutils.ErrDatabaseNoRowsAffected):
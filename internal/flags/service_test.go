@@ -28,12 +28,13 @@ func TestServiceCreateFlagSuccess(t *testing.T) {
 	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
 	name := "my-flag"
 	now := time.Now().UTC()
-	flag, err := svc.CreateFlag(ctx, name)
+	flag, err := svc.CreateFlag(ctx, name, true)
 	require.NoError(t, err)
 
 	require.Equal(t, user.UUID, flag.UserUUID)
 	require.Equal(t, name, flag.Name)
 	require.False(t, flag.IsEnabled)
+	require.True(t, flag.IsClientSide)
 	testkit.RequireTimeAlmostEqual(t, now, flag.CreatedAt)
 	testkit.RequireTimeAlmostEqual(t, now, flag.UpdatedAt)
 }
@@ -46,14 +47,14 @@ func TestServiceCreateFlagAlreadyExists(t *testing.T) {
 	})
 
 	name := "my-flag"
-	testkitinternal.MustCreateUserFlag(t, user.UUID, name)
+	testkitinternal.MustCreateUserFlag(t, user.UUID, name, false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := flags.NewRepository()
 	svc := flags.NewService(dbPool, repo)
 
 	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
-	_, err := svc.CreateFlag(ctx, name)
+	_, err := svc.CreateFlag(ctx, name, false)
 	require.ErrorIs(t, err, errutils.ErrFlagAlreadyExists)
 }
 
@@ -64,7 +65,7 @@ func TestServiceGetFlagByIDSuccess(t *testing.T) {
 		u.IsActive = true
 	})
 
-	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag")
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag", false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := flags.NewRepository()
@@ -97,8 +98,8 @@ func TestServiceGetFlagByIDError(t *testing.T) {
 		u.IsActive = false
 	})
 
-	activeUser1Flag := testkitinternal.MustCreateUserFlag(t, activeUser1.UUID, "my-flag")
-	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag")
+	activeUser1Flag := testkitinternal.MustCreateUserFlag(t, activeUser1.UUID, "my-flag", false)
+	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag", false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := flags.NewRepository()
@@ -157,7 +158,7 @@ func TestServiceGetFlagByNameSuccess(t *testing.T) {
 	})
 
 	name := "my-flag"
-	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, name)
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, name, false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := flags.NewRepository()
@@ -190,8 +191,8 @@ func TestServiceGetFlagByNameError(t *testing.T) {
 		u.IsActive = false
 	})
 
-	activeUser1Flag := testkitinternal.MustCreateUserFlag(t, activeUser1.UUID, "active-user-1-flag")
-	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "inactive-user-flag")
+	activeUser1Flag := testkitinternal.MustCreateUserFlag(t, activeUser1.UUID, "active-user-1-flag", false)
+	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "inactive-user-flag", false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := flags.NewRepository()
@@ -253,9 +254,9 @@ func TestServiceListFlags(t *testing.T) {
 		u.IsActive = true
 	})
 
-	user1Flag1 := testkitinternal.MustCreateUserFlag(t, user1.UUID, "user-1-flag-1")
-	user1Flag2 := testkitinternal.MustCreateUserFlag(t, user1.UUID, "user-1-flag-2")
-	user2Flag := testkitinternal.MustCreateUserFlag(t, user2.UUID, "user-2-flag")
+	user1Flag1 := testkitinternal.MustCreateUserFlag(t, user1.UUID, "user-1-flag-1", false)
+	user1Flag2 := testkitinternal.MustCreateUserFlag(t, user1.UUID, "user-1-flag-2", false)
+	user2Flag := testkitinternal.MustCreateUserFlag(t, user2.UUID, "user-2-flag", false)
 
 	user1Flags := []*flags.Flag{user1Flag1, user1Flag2}
 	user2Flags := []*flags.Flag{user2Flag}
@@ -304,6 +305,146 @@ func TestServiceListFlags(t *testing.T) {
 	}
 }
 
+func TestServiceListUserFlags(t *testing.T) {
+	t.Parallel()
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "inactive-user-flag", false)
+	testkitinternal.MustCreateUserFlag(t, otherUser.UUID, "other-user-flag", false)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := flags.NewRepository()
+	svc := flags.NewService(dbPool, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, otherUser.UUID)
+	fetchedFlags, err := svc.ListUserFlags(ctx, inactiveUser.UUID)
+	require.NoError(t, err)
+	require.Len(t, fetchedFlags, 1)
+	require.Equal(t, inactiveUserFlag.ID, fetchedFlags[0].ID)
+	require.Equal(t, inactiveUser.UUID, fetchedFlags[0].UserUUID)
+	require.Equal(t, inactiveUserFlag.Name, fetchedFlags[0].Name)
+}
+
+func TestServiceGetClientSideFlagByNameSuccess(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	name := "my-flag"
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, name, true)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := flags.NewRepository()
+	svc := flags.NewService(dbPool, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	fetchedFlag, err := svc.GetClientSideFlagByName(ctx, name)
+	require.NoError(t, err)
+
+	require.Equal(t, flag.ID, fetchedFlag.ID)
+	require.Equal(t, user.UUID, fetchedFlag.UserUUID)
+	require.Equal(t, flag.Name, fetchedFlag.Name)
+	require.Equal(t, flag.IsEnabled, fetchedFlag.IsEnabled)
+	require.True(t, fetchedFlag.IsClientSide)
+}
+
+func TestServiceGetClientSideFlagByNameError(t *testing.T) {
+	t.Parallel()
+
+	activeUser1, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	activeUser2, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	activeUser1ClientSideFlag := testkitinternal.MustCreateUserFlag(t, activeUser1.UUID, "client-side-flag", true)
+	activeUser1PrivateFlag := testkitinternal.MustCreateUserFlag(t, activeUser1.UUID, "private-flag", false)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := flags.NewRepository()
+	svc := flags.NewService(dbPool, repo)
+
+	testcases := []struct {
+		name     string
+		flagName string
+		ctx      context.Context
+		wantErr  error
+	}{
+		{
+			name:     "Flag not found",
+			flagName: "unknown-flag",
+			ctx:      context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, activeUser1.UUID),
+			wantErr:  errutils.ErrFlagNotFound,
+		},
+		{
+			name:     "Flag not client-side",
+			flagName: activeUser1PrivateFlag.Name,
+			ctx:      context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, activeUser1.UUID),
+			wantErr:  errutils.ErrFlagNotFound,
+		},
+		{
+			name:     "Flag not found for user",
+			flagName: activeUser1ClientSideFlag.Name,
+			ctx:      context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, activeUser2.UUID),
+			wantErr:  errutils.ErrFlagNotFound,
+		},
+		{
+			name:     "No user UUID in context",
+			flagName: activeUser1ClientSideFlag.Name,
+			ctx:      context.Background(),
+			wantErr:  nil,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := svc.GetClientSideFlagByName(testcase.ctx, testcase.flagName)
+			require.Error(t, err)
+			if testcase.wantErr != nil {
+				require.ErrorIs(t, err, testcase.wantErr)
+			}
+		})
+	}
+}
+
+func TestServiceListClientSideFlags(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	clientSideFlag := testkitinternal.MustCreateUserFlag(t, user.UUID, "client-side-flag", true)
+	testkitinternal.MustCreateUserFlag(t, user.UUID, "private-flag", false)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := flags.NewRepository()
+	svc := flags.NewService(dbPool, repo)
+
+	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
+	fetchedFlags, err := svc.ListClientSideFlags(ctx)
+	require.NoError(t, err)
+	require.Len(t, fetchedFlags, 1)
+	require.Equal(t, clientSideFlag.ID, fetchedFlags[0].ID)
+	require.Equal(t, clientSideFlag.Name, fetchedFlags[0].Name)
+	require.True(t, fetchedFlags[0].IsClientSide)
+
+	_, err = svc.ListClientSideFlags(context.Background())
+	require.Error(t, err)
+}
+
 func TestServiceUpdateFlagSuccess(t *testing.T) {
 	t.Parallel()
 
@@ -312,23 +453,25 @@ func TestServiceUpdateFlagSuccess(t *testing.T) {
 	})
 
 	flagName := "my-flag"
-	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, flagName)
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, flagName, false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := flags.NewRepository()
 	svc := flags.NewService(dbPool, repo)
 
 	updatedIsEnabled := true
+	updatedIsClientSide := true
 
 	updatedAt := time.Now().UTC()
 	ctx := context.WithValue(context.Background(), auth.AuthContextKeyUserUUID, user.UUID)
-	updatedFlag, err := svc.UpdateFlag(ctx, flag.ID, updatedIsEnabled)
+	updatedFlag, err := svc.UpdateFlag(ctx, flag.ID, updatedIsEnabled, &updatedIsClientSide)
 	require.NoError(t, err)
 
 	require.Equal(t, flag.ID, updatedFlag.ID)
 	require.Equal(t, user.UUID, updatedFlag.UserUUID)
 	require.Equal(t, flagName, updatedFlag.Name)
 	require.Equal(t, updatedIsEnabled, updatedFlag.IsEnabled)
+	require.Equal(t, updatedIsClientSide, updatedFlag.IsClientSide)
 	require.Equal(t, flag.CreatedAt, updatedFlag.CreatedAt)
 	testkit.RequireTimeAlmostEqual(t, updatedAt, updatedFlag.UpdatedAt)
 
@@ -349,8 +492,8 @@ func TestServiceUpdateFlagError(t *testing.T) {
 		u.IsActive = false
 	})
 
-	activeUser1Flag := testkitinternal.MustCreateUserFlag(t, activeUser1.UUID, "my-flag")
-	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag")
+	activeUser1Flag := testkitinternal.MustCreateUserFlag(t, activeUser1.UUID, "my-flag", false)
+	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "my-flag", false)
 
 	dbPool := testkitinternal.RequireCreateDatabasePool(t)
 	repo := flags.NewRepository()
@@ -392,7 +535,7 @@ func TestServiceUpdateFlagError(t *testing.T) {
 		t.Run(testcase.name, func(t *testing.T) {
 			t.Parallel()
 
-			_, err := svc.UpdateFlag(testcase.ctx, testcase.flagID, true)
+			_, err := svc.UpdateFlag(testcase.ctx, testcase.flagID, true, nil)
 			require.Error(t, err)
 			if testcase.wantErr != nil {
 				require.ErrorIs(t, err, testcase.wantErr)
@@ -0,0 +1,25 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// Store types used to select where rate limit buckets are kept.
+const (
+	StoreTypeInMemory = "inmem"
+	StoreTypePostgres = "postgres"
+	StoreTypeNone     = "none"
+)
+
+// staleBucketAge is the duration after which rate limit buckets that haven't been used are purged.
+// This must be longer than the longest rate limit period, so that purged buckets would have been full anyway.
+const staleBucketAge = 24 * time.Hour
+
+// RateLimitBucket represents database table of rate limit token buckets.
+// Allowed records whether or not the last attempt to take a token succeeded.
+type RateLimitBucket struct {
+	Key       string    `db:"key"`
+	Tokens    float64   `db:"tokens"`
+	Allowed   bool      `db:"allowed"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository is used to access and update rate limit data.
+type Repository interface {
+	TakeRateLimitToken(dbConn *pgxpool.Conn, key string, limit int, refillRate float64, now time.Time) (*RateLimitBucket, error)
+	DeleteStaleRateLimitBuckets(dbConn *pgxpool.Conn, updatedBefore time.Time) (int64, error)
+}
+
+// repository implements Repository.
+type repository struct{}
+
+// NewRepository returns a new repository.
+func NewRepository() *repository {
+	return &repository{}
+}
+
+// TakeRateLimitToken refills the bucket under a given key and takes a token from it if one is available.
+// Missing buckets are created full, and buckets are never refilled past limit tokens.
+// The bucket is refilled and its token taken in a single statement,
+// so concurrent requests from different servers cannot take the same token.
+func (repo *repository) TakeRateLimitToken(
+	dbConn *pgxpool.Conn,
+	key string,
+	limit int,
+	refillRate float64,
+	now time.Time,
+) (*RateLimitBucket, error) {
+	bucket := &RateLimitBucket{}
+
+	q := `
+INSERT INTO RateLimitBucket (
+	key,
+	tokens,
+	allowed,
+	updated_at
+)
+VALUES (
+	$1,
+	$2::DOUBLE PRECISION - 1,
+	TRUE,
+	$4
+)
+ON CONFLICT (key) DO UPDATE SET
+	tokens = refill_rate_limit_bucket(RateLimitBucket.tokens, RateLimitBucket.updated_at, $2, $3, $4)
+		- (refill_rate_limit_bucket(RateLimitBucket.tokens, RateLimitBucket.updated_at, $2, $3, $4) >= 1)::INT,
+	allowed = refill_rate_limit_bucket(RateLimitBucket.tokens, RateLimitBucket.updated_at, $2, $3, $4) >= 1,
+	updated_at = $4
+RETURNING
+	key,
+	tokens,
+	allowed,
+	updated_at;
+	`
+
+	row := dbConn.QueryRow(context.Background(), q, key, float64(limit), refillRate, now)
+	err := row.Scan(
+		&bucket.Key,
+		&bucket.Tokens,
+		&bucket.Allowed,
+		&bucket.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("TakeRateLimitToken failed to dbConn.Scan: %w", err)
+	}
+
+	return bucket, nil
+}
+
+// DeleteStaleRateLimitBuckets deletes rate limit buckets that haven't been used since a given time.
+func (repo *repository) DeleteStaleRateLimitBuckets(dbConn *pgxpool.Conn, updatedBefore time.Time) (int64, error) {
+	q := `
+DELETE FROM
+	RateLimitBucket
+WHERE
+	updated_at < $1;
+	`
+
+	result, err := dbConn.Exec(context.Background(), q, updatedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteStaleRateLimitBuckets failed to dbConn.Exec: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
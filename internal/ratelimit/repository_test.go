@@ -0,0 +1,81 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alvii147/flagger-api/internal/ratelimit"
+	"github.com/alvii147/flagger-api/internal/testkitinternal"
+	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryTakeRateLimitToken(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := ratelimit.NewRepository()
+
+	key := uuid.NewString()
+	now := time.Now().UTC()
+
+	bucket, err := repo.TakeRateLimitToken(dbConn, key, 2, 1, now)
+	require.NoError(t, err)
+	require.Equal(t, key, bucket.Key)
+	require.True(t, bucket.Allowed)
+	require.InDelta(t, 1, bucket.Tokens, 1e-6)
+	testkit.RequireTimeAlmostEqual(t, now, bucket.UpdatedAt)
+
+	bucket, err = repo.TakeRateLimitToken(dbConn, key, 2, 1, now)
+	require.NoError(t, err)
+	require.True(t, bucket.Allowed)
+	require.InDelta(t, 0, bucket.Tokens, 1e-6)
+
+	bucket, err = repo.TakeRateLimitToken(dbConn, key, 2, 1, now)
+	require.NoError(t, err)
+	require.False(t, bucket.Allowed)
+	require.InDelta(t, 0, bucket.Tokens, 1e-6)
+
+	bucket, err = repo.TakeRateLimitToken(dbConn, key, 2, 1, now.Add(1500*time.Millisecond))
+	require.NoError(t, err)
+	require.True(t, bucket.Allowed)
+	require.InDelta(t, 0.5, bucket.Tokens, 1e-6)
+
+	bucket, err = repo.TakeRateLimitToken(dbConn, key, 2, 1, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, bucket.Allowed)
+	require.InDelta(t, 1, bucket.Tokens, 1e-6)
+}
+
+func TestRepositoryDeleteStaleRateLimitBuckets(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := ratelimit.NewRepository()
+
+	now := time.Now().UTC()
+	staleKey := uuid.NewString()
+	recentKey := uuid.NewString()
+
+	_, err := repo.TakeRateLimitToken(dbConn, staleKey, 2, 1, now.Add(-48*time.Hour))
+	require.NoError(t, err)
+
+	_, err = repo.TakeRateLimitToken(dbConn, recentKey, 2, 1, now)
+	require.NoError(t, err)
+
+	count, err := repo.DeleteStaleRateLimitBuckets(dbConn, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	bucket, err := repo.TakeRateLimitToken(dbConn, staleKey, 2, 1, now)
+	require.NoError(t, err)
+	require.InDelta(t, 1, bucket.Tokens, 1e-6)
+
+	bucket, err = repo.TakeRateLimitToken(dbConn, recentKey, 2, 1, now)
+	require.NoError(t, err)
+	require.InDelta(t, 0, bucket.Tokens, 1e-6)
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Service performs all rate limit related business logic.
+// Service implements httputils.RateLimitStore, keeping buckets in the database
+// so that rate limits are shared between all servers.
+type Service interface {
+	Take(ctx context.Context, key string, policy httputils.RateLimitPolicy) (*httputils.RateLimitResult, error)
+	PurgeStaleBuckets(ctx context.Context) (int64, error)
+}
+
+// service implements Service.
+type service struct {
+	dbPool     *pgxpool.Pool
+	repository Repository
+}
+
+// NewService returns a new service.
+func NewService(dbPool *pgxpool.Pool, repo Repository) *service {
+	return &service{
+		dbPool:     dbPool,
+		repository: repo,
+	}
+}
+
+// Take takes a token from the bucket under a given key.
+func (svc *service) Take(ctx context.Context, key string, policy httputils.RateLimitPolicy) (*httputils.RateLimitResult, error) {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Take failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	bucket, err := svc.repository.TakeRateLimitToken(dbConn, key, policy.Limit, policy.RefillRate(), time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("Take failed to svc.repository.TakeRateLimitToken: %w", err)
+	}
+
+	return httputils.NewRateLimitResult(policy, bucket.Allowed, bucket.Tokens), nil
+}
+
+// PurgeStaleBuckets deletes rate limit buckets that haven't been used recently.
+func (svc *service) PurgeStaleBuckets(ctx context.Context) (int64, error) {
+	dbConn, err := svc.dbPool.Acquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeStaleBuckets failed to svc.dbPool.Acquire: %w", err)
+	}
+	defer dbConn.Release()
+
+	count, err := svc.repository.DeleteStaleRateLimitBuckets(dbConn, time.Now().UTC().Add(-staleBucketAge))
+	if err != nil {
+		return 0, fmt.Errorf("PurgeStaleBuckets failed to svc.repository.DeleteStaleRateLimitBuckets: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,84 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alvii147/flagger-api/internal/ratelimit"
+	"github.com/alvii147/flagger-api/internal/testkitinternal"
+	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceTake(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := ratelimit.NewRepository()
+	svc := ratelimit.NewService(dbPool, repo)
+
+	key := uuid.NewString()
+	policy := httputils.RateLimitPolicy{
+		Name:   "test",
+		Limit:  2,
+		Period: time.Hour,
+	}
+
+	result, err := svc.Take(context.Background(), key, policy)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 1, result.Remaining)
+
+	result, err = svc.Take(context.Background(), key, policy)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+
+	result, err = svc.Take(context.Background(), key, policy)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Greater(t, result.RetryAfter, time.Duration(0))
+	require.LessOrEqual(t, result.RetryAfter, 30*time.Minute)
+}
+
+func TestServiceTakeError(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	repo := ratelimit.NewRepository()
+	svc := ratelimit.NewService(dbPool, repo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := svc.Take(ctx, uuid.NewString(), httputils.RateLimitPolicy{
+		Name:   "test",
+		Limit:  2,
+		Period: time.Hour,
+	})
+	require.Error(t, err)
+}
+
+func TestServicePurgeStaleBuckets(t *testing.T) {
+	t.Parallel()
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := ratelimit.NewRepository()
+	svc := ratelimit.NewService(dbPool, repo)
+
+	staleKey := uuid.NewString()
+	_, err := repo.TakeRateLimitToken(dbConn, staleKey, 2, 1, time.Now().UTC().Add(-48*time.Hour))
+	require.NoError(t, err)
+
+	count, err := svc.PurgeStaleBuckets(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	bucket, err := repo.TakeRateLimitToken(dbConn, staleKey, 2, 1, time.Now().UTC())
+	require.NoError(t, err)
+	require.InDelta(t, 1, bucket.Tokens, 1e-6)
+}
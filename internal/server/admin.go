@@ -0,0 +1,406 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alvii147/flagger-api/internal/audit"
+	"github.com/alvii147/flagger-api/internal/auth"
+	"github.com/alvii147/flagger-api/pkg/api"
+	"github.com/alvii147/flagger-api/pkg/errutils"
+	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const UserUUIDParamKey = "uuid"
+
+const UsersSearchQueryKey = "search"
+
+// recordAdminAction records an action taken by currently authenticated superuser in the audit log.
+// The audit log entry is recorded under the affected User, or under the superuser if no single User is affected.
+func (ctrl *controller) recordAdminAction(r *http.Request, eventType audit.EventType, userUUID string, detail string) {
+	actorUUID, _ := r.Context().Value(auth.AuthContextKeyUserUUID).(string)
+	if userUUID == "" {
+		userUUID = actorUUID
+	}
+
+	ctrl.auditService.Record(r.Context(), &audit.AuditLog{
+		EventType: string(eventType),
+		UserUUID:  userUUID,
+		ActorUUID: pgtype.Text{
+			String: actorUUID,
+			Valid:  true,
+		},
+		IPAddress: httputils.GetClientIP(r),
+		Detail:    detail,
+	})
+}
+
+// newGetUserResponse returns a response body for a single User in superuser User retrieval requests.
+func newGetUserResponse(user *auth.User) *api.GetUserResponse {
+	return &api.GetUserResponse{
+		UUID:        user.UUID,
+		Email:       user.Email,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		IsActive:    user.IsActive,
+		IsSuperUser: user.IsSuperUser,
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+// handleAdminListUsers handles retrieval of all Users, optionally filtered by a search string.
+// Methods: GET
+// URL: /admin/users
+func (ctrl *controller) handleAdminListUsers(w *httputils.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get(UsersSearchQueryKey)
+	users, err := ctrl.authService.ListUsers(r.Context(), search)
+	if err != nil {
+		ctrl.logger.LogError("handleAdminListUsers failed to ctrl.authService.ListUsers:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInternalServerError,
+				Detail: api.ErrDetailInternalServerError,
+			},
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	ctrl.recordAdminAction(r, audit.EventTypeAdminUsersListed, "", fmt.Sprintf("search=%s", strconv.Quote(search)))
+
+	responseBody := &api.ListUsersResponse{
+		Users: make([]*api.GetUserResponse, len(users)),
+	}
+
+	for i, user := range users {
+		responseBody.Users[i] = newGetUserResponse(user)
+	}
+
+	w.WriteJSON(responseBody, http.StatusOK)
+}
+
+// handleAdminGetUser handles retrieval of any User.
+// Methods: GET
+// URL: /admin/users/{uuid}
+func (ctrl *controller) handleAdminGetUser(w *httputils.ResponseWriter, r *http.Request) {
+	userUUID := r.PathValue(UserUUIDParamKey)
+	user, err := ctrl.authService.GetUser(r.Context(), userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrUserNotFound):
+			ctrl.logger.LogWarn("handleAdminGetUser failed to ctrl.authService.GetUser:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailUserNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			ctrl.logger.LogError("handleAdminGetUser failed to ctrl.authService.GetUser:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	ctrl.recordAdminAction(r, audit.EventTypeAdminUserViewed, user.UUID, "")
+
+	w.WriteJSON(newGetUserResponse(user), http.StatusOK)
+}
+
+// handleAdminUpdateUserStatus handles activating, deactivating, granting superuser to, and revoking superuser from any User.
+// Methods: PUT
+// URL: /admin/users/{uuid}
+func (ctrl *controller) handleAdminUpdateUserStatus(w *httputils.ResponseWriter, r *http.Request) {
+	userUUID := r.PathValue(UserUUIDParamKey)
+
+	var req api.UpdateUserStatusRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleAdminUpdateUserStatus failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	if req.IsActive == nil && req.IsSuperUser == nil {
+		ctrl.logger.LogWarn("handleAdminUpdateUserStatus failed, no attributes to update")
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	user, err := ctrl.authService.UpdateUserStatus(r.Context(), userUUID, req.IsActive, req.IsSuperUser)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrCannotUpdateOwnStatus):
+			ctrl.logger.LogWarn("handleAdminUpdateUserStatus failed to ctrl.authService.UpdateUserStatus:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailCannotUpdateOwnStatus,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.Is(err, errutils.ErrUserNotFound):
+			ctrl.logger.LogWarn("handleAdminUpdateUserStatus failed to ctrl.authService.UpdateUserStatus:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailUserNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			ctrl.logger.LogError("handleAdminUpdateUserStatus failed to ctrl.authService.UpdateUserStatus:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	ctrl.recordAdminAction(
+		r,
+		audit.EventTypeAdminUserUpdated,
+		user.UUID,
+		fmt.Sprintf("is_active=%t is_superuser=%t", user.IsActive, user.IsSuperUser),
+	)
+
+	w.WriteJSON(newGetUserResponse(user), http.StatusOK)
+}
+
+// handleAdminForcePasswordReset handles replacing any active User's password,
+// revoking their sessions, and sending them a password reset email.
+// Methods: POST
+// URL: /admin/users/{uuid}/password-reset
+func (ctrl *controller) handleAdminForcePasswordReset(w *httputils.ResponseWriter, r *http.Request) {
+	userUUID := r.PathValue(UserUUIDParamKey)
+
+	var wg sync.WaitGroup
+	err := ctrl.authService.ForcePasswordReset(r.Context(), &wg, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrUserNotFound):
+			ctrl.logger.LogWarn("handleAdminForcePasswordReset failed to ctrl.authService.ForcePasswordReset:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailUserNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			ctrl.logger.LogError("handleAdminForcePasswordReset failed to ctrl.authService.ForcePasswordReset:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	ctrl.recordAdminAction(r, audit.EventTypeAdminPasswordResetForced, userUUID, "")
+
+	w.WriteJSON(nil, http.StatusAccepted)
+}
+
+// handleAdminRevokeUserCredentials handles revoking all sessions and deleting all API keys of any User.
+// Methods: POST
+// URL: /admin/users/{uuid}/revoke-credentials
+func (ctrl *controller) handleAdminRevokeUserCredentials(w *httputils.ResponseWriter, r *http.Request) {
+	userUUID := r.PathValue(UserUUIDParamKey)
+	err := ctrl.authService.RevokeUserCredentials(r.Context(), userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrUserNotFound):
+			ctrl.logger.LogWarn("handleAdminRevokeUserCredentials failed to ctrl.authService.RevokeUserCredentials:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailUserNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			ctrl.logger.LogError("handleAdminRevokeUserCredentials failed to ctrl.authService.RevokeUserCredentials:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	ctrl.recordAdminAction(r, audit.EventTypeAdminCredentialsRevoked, userUUID, "")
+
+	w.WriteJSON(nil, http.StatusNoContent)
+}
+
+// handleAdminListUserFlags handles retrieval of Flags of any User.
+// Methods: GET
+// URL: /admin/users/{uuid}/flags
+func (ctrl *controller) handleAdminListUserFlags(w *httputils.ResponseWriter, r *http.Request) {
+	userUUID := r.PathValue(UserUUIDParamKey)
+	user, err := ctrl.authService.GetUser(r.Context(), userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrUserNotFound):
+			ctrl.logger.LogWarn("handleAdminListUserFlags failed to ctrl.authService.GetUser:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailUserNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			ctrl.logger.LogError("handleAdminListUserFlags failed to ctrl.authService.GetUser:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	flags, err := ctrl.flagsService.ListUserFlags(r.Context(), user.UUID)
+	if err != nil {
+		ctrl.logger.LogError("handleAdminListUserFlags failed to ctrl.flagsService.ListUserFlags:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInternalServerError,
+				Detail: api.ErrDetailInternalServerError,
+			},
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	ctrl.recordAdminAction(r, audit.EventTypeAdminFlagsViewed, user.UUID, "")
+
+	responseBody := &api.ListFlagsResponse{
+		Flags: make([]*api.GetFlagByIDResponse, len(flags)),
+	}
+
+	for i, flag := range flags {
+		responseBody.Flags[i] = &api.GetFlagByIDResponse{
+			ID:        flag.ID,
+			UserUUID:  flag.UserUUID,
+			Name:      flag.Name,
+			IsEnabled: flag.IsEnabled,
+			CreatedAt: flag.CreatedAt,
+			UpdatedAt: flag.UpdatedAt,
+		}
+	}
+
+	w.WriteJSON(responseBody, http.StatusOK)
+}
+
+// handleAdminImpersonateUser handles creation of short-lived access JWTs for superusers to act as any active User.
+// Impersonation JWTs are read-only unless writes are explicitly allowed.
+// Methods: POST
+// URL: /admin/users/{uuid}/impersonate
+func (ctrl *controller) handleAdminImpersonateUser(w *httputils.ResponseWriter, r *http.Request) {
+	userUUID := r.PathValue(UserUUIDParamKey)
+
+	var req api.ImpersonateUserRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		ctrl.logger.LogWarn("handleAdminImpersonateUser failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	token, claims, err := ctrl.authService.ImpersonateUser(r.Context(), userUUID, !req.AllowWrites)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrCannotImpersonateUser):
+			ctrl.logger.LogWarn("handleAdminImpersonateUser failed to ctrl.authService.ImpersonateUser:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodePermissionDenied,
+					Detail: api.ErrDetailCannotImpersonateUser,
+				},
+				http.StatusForbidden,
+			)
+		case errors.Is(err, errutils.ErrUserNotFound):
+			ctrl.logger.LogWarn("handleAdminImpersonateUser failed to ctrl.authService.ImpersonateUser:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailUserNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			ctrl.logger.LogError("handleAdminImpersonateUser failed to ctrl.authService.ImpersonateUser:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	ctrl.recordAdminAction(
+		r,
+		audit.EventTypeAdminImpersonationStarted,
+		claims.Subject,
+		fmt.Sprintf("jti=%s read_only=%t", claims.JWTID, claims.ReadOnly),
+	)
+
+	w.WriteJSON(
+		&api.ImpersonateUserResponse{
+			Access:    token,
+			ReadOnly:  claims.ReadOnly,
+			ExpiresAt: time.Time(claims.ExpiresAt),
+		},
+		http.StatusCreated,
+	)
+}
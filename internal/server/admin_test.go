@@ -0,0 +1,831 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alvii147/flagger-api/internal/audit"
+	"github.com/alvii147/flagger-api/internal/auth"
+	"github.com/alvii147/flagger-api/internal/testkitinternal"
+	"github.com/alvii147/flagger-api/pkg/api"
+	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// requireAdminAuditLog checks that the latest audit log entry of a User was recorded by a superuser action.
+func requireAdminAuditLog(t *testing.T, userUUID string, superUserUUID string, eventType audit.EventType) {
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := audit.NewRepository()
+
+	auditLogs, err := repo.ListAuditLogsByUserUUID(dbConn, userUUID)
+	require.NoError(t, err)
+	require.NotEmpty(t, auditLogs)
+
+	auditLog := auditLogs[len(auditLogs)-1]
+	require.Equal(t, string(eventType), auditLog.EventType)
+	require.Equal(t, pgtype.Text{String: superUserUUID, Valid: true}, auditLog.ActorUUID)
+}
+
+func TestAdminRoutesRequireSuperUser(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = false
+	})
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, nil)
+
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/admin/users"},
+		{http.MethodGet, "/admin/users/" + otherUser.UUID},
+		{http.MethodPut, "/admin/users/" + otherUser.UUID},
+		{http.MethodPost, "/admin/users/" + otherUser.UUID + "/password-reset"},
+		{http.MethodPost, "/admin/users/" + otherUser.UUID + "/revoke-credentials"},
+		{http.MethodGet, "/admin/users/" + otherUser.UUID + "/flags"},
+		{http.MethodPost, "/admin/users/" + otherUser.UUID + "/impersonate"},
+	}
+
+	for _, route := range routes {
+		t.Run(fmt.Sprintf("%s %s", route.method, route.path), func(t *testing.T) {
+			t.Parallel()
+
+			testcases := []struct {
+				name           string
+				headers        map[string]string
+				wantStatusCode int
+				wantErrCode    string
+				wantErrDetail  string
+			}{
+				{
+					name: "Regular user",
+					headers: map[string]string{
+						"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+					},
+					wantStatusCode: http.StatusForbidden,
+					wantErrCode:    api.ErrCodePermissionDenied,
+					wantErrDetail:  api.ErrDetailSuperUserRequired,
+				},
+				{
+					name:           "Unauthenticated request",
+					headers:        map[string]string{},
+					wantStatusCode: http.StatusUnauthorized,
+					wantErrCode:    api.ErrCodeMissingCredentials,
+					wantErrDetail:  api.ErrDetailMissingCredentials,
+				},
+			}
+
+			for _, testcase := range testcases {
+				req, err := http.NewRequest(route.method, TestServerURL+route.path, bytes.NewReader([]byte(`{"is_active": false}`)))
+				require.NoError(t, err)
+
+				for key, value := range testcase.headers {
+					req.Header.Add(key, value)
+				}
+
+				res, err := httpClient.Do(req)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantStatusCode, res.StatusCode, testcase.name)
+
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+				require.NoError(t, res.Body.Close())
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code, testcase.name)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail, testcase.name)
+			}
+		})
+	}
+}
+
+func TestHandleAdminListUsers(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	superUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, superUser.UUID)
+
+	search := testkit.MustGenerateRandomString(16, true, false, false)
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.FirstName = search
+		u.IsActive = true
+	})
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.LastName = search
+		u.IsActive = false
+	})
+
+	req, err := http.NewRequest(
+		http.MethodGet,
+		TestServerURL+"/admin/users?"+url.Values{"search": {search}}.Encode(),
+		http.NoBody,
+	)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", superUserAccessJWT))
+
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := res.Body.Close()
+		require.NoError(t, err)
+	})
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var listUsersResp api.ListUsersResponse
+	err = json.NewDecoder(res.Body).Decode(&listUsersResp)
+	require.NoError(t, err)
+	require.Len(t, listUsersResp.Users, 2)
+
+	usersByUUID := make(map[string]*api.GetUserResponse)
+	for _, user := range listUsersResp.Users {
+		usersByUUID[user.UUID] = user
+	}
+
+	require.Contains(t, usersByUUID, activeUser.UUID)
+	require.Equal(t, activeUser.Email, usersByUUID[activeUser.UUID].Email)
+	require.True(t, usersByUUID[activeUser.UUID].IsActive)
+	require.False(t, usersByUUID[activeUser.UUID].IsSuperUser)
+
+	require.Contains(t, usersByUUID, inactiveUser.UUID)
+	require.Equal(t, inactiveUser.Email, usersByUUID[inactiveUser.UUID].Email)
+	require.False(t, usersByUUID[inactiveUser.UUID].IsActive)
+
+	requireAdminAuditLog(t, superUser.UUID, superUser.UUID, audit.EventTypeAdminUsersListed)
+}
+
+func TestHandleAdminGetUser(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	superUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, superUser.UUID)
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	testcases := []struct {
+		name           string
+		path           string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name:           "Get inactive user",
+			path:           "/admin/users/" + inactiveUser.UUID,
+			wantStatusCode: http.StatusOK,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name:           "Get non-existent user",
+			path:           "/admin/users/" + uuid.NewString(),
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailUserNotFound,
+		},
+		{
+			name:           "Get user with invalid UUID",
+			path:           "/admin/users/1nv4l1d",
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailUserNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, TestServerURL+testcase.path, http.NoBody)
+			require.NoError(t, err)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", superUserAccessJWT))
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var getUserResp api.GetUserResponse
+				err = json.NewDecoder(res.Body).Decode(&getUserResp)
+				require.NoError(t, err)
+
+				require.Equal(t, inactiveUser.UUID, getUserResp.UUID)
+				require.Equal(t, inactiveUser.Email, getUserResp.Email)
+				require.Equal(t, inactiveUser.FirstName, getUserResp.FirstName)
+				require.Equal(t, inactiveUser.LastName, getUserResp.LastName)
+				require.False(t, getUserResp.IsActive)
+				require.False(t, getUserResp.IsSuperUser)
+
+				requireAdminAuditLog(t, inactiveUser.UUID, superUser.UUID, audit.EventTypeAdminUserViewed)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleAdminUpdateUserStatus(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	superUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, superUser.UUID)
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+	otherSuperUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+
+	testcases := []struct {
+		name            string
+		userUUID        string
+		requestBody     string
+		wantStatusCode  int
+		wantIsActive    bool
+		wantIsSuperUser bool
+		wantErrCode     string
+		wantErrDetail   string
+	}{
+		{
+			name:            "Deactivate user",
+			userUUID:        activeUser.UUID,
+			requestBody:     `{"is_active": false}`,
+			wantStatusCode:  http.StatusOK,
+			wantIsActive:    false,
+			wantIsSuperUser: false,
+			wantErrCode:     "",
+			wantErrDetail:   "",
+		},
+		{
+			name:            "Activate user and grant superuser",
+			userUUID:        inactiveUser.UUID,
+			requestBody:     `{"is_active": true, "is_superuser": true}`,
+			wantStatusCode:  http.StatusOK,
+			wantIsActive:    true,
+			wantIsSuperUser: true,
+			wantErrCode:     "",
+			wantErrDetail:   "",
+		},
+		{
+			name:            "Revoke superuser",
+			userUUID:        otherSuperUser.UUID,
+			requestBody:     `{"is_superuser": false}`,
+			wantStatusCode:  http.StatusOK,
+			wantIsActive:    true,
+			wantIsSuperUser: false,
+			wantErrCode:     "",
+			wantErrDetail:   "",
+		},
+		{
+			name:           "Update own status",
+			userUUID:       superUser.UUID,
+			requestBody:    `{"is_superuser": false}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailCannotUpdateOwnStatus,
+		},
+		{
+			name:           "No attributes to update",
+			userUUID:       activeUser.UUID,
+			requestBody:    `{}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name:           "Non-existent user",
+			userUUID:       uuid.NewString(),
+			requestBody:    `{"is_active": false}`,
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailUserNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPut,
+				TestServerURL+"/admin/users/"+testcase.userUUID,
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", superUserAccessJWT))
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var getUserResp api.GetUserResponse
+				err = json.NewDecoder(res.Body).Decode(&getUserResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.userUUID, getUserResp.UUID)
+				require.Equal(t, testcase.wantIsActive, getUserResp.IsActive)
+				require.Equal(t, testcase.wantIsSuperUser, getUserResp.IsSuperUser)
+
+				requireAdminAuditLog(t, testcase.userUUID, superUser.UUID, audit.EventTypeAdminUserUpdated)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleAdminForcePasswordReset(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	superUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, superUser.UUID)
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	testcases := []struct {
+		name           string
+		userUUID       string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name:           "Force password reset for active user",
+			userUUID:       activeUser.UUID,
+			wantStatusCode: http.StatusAccepted,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name:           "Force password reset for inactive user",
+			userUUID:       inactiveUser.UUID,
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailUserNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/admin/users/"+testcase.userUUID+"/password-reset",
+				http.NoBody,
+			)
+			require.NoError(t, err)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", superUserAccessJWT))
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				requireAdminAuditLog(t, testcase.userUUID, superUser.UUID, audit.EventTypeAdminPasswordResetForced)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleAdminRevokeUserCredentials(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	superUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, superUser.UUID)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	_, userRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		TestServerURL+"/admin/users/"+user.UUID+"/revoke-credentials",
+		http.NoBody,
+	)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", superUserAccessJWT))
+
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	requireAdminAuditLog(t, user.UUID, superUser.UUID, audit.EventTypeAdminCredentialsRevoked)
+
+	req, err = http.NewRequest(http.MethodGet, TestServerURL+"/auth/users/me", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", userAccessJWT))
+
+	res, err = httpClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	req, err = http.NewRequest(http.MethodGet, TestServerURL+"/api/auth/users/me", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("X-API-Key %s", userRawAPIKey))
+
+	res, err = httpClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	req, err = http.NewRequest(
+		http.MethodPost,
+		TestServerURL+"/admin/users/"+uuid.NewString()+"/revoke-credentials",
+		http.NoBody,
+	)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", superUserAccessJWT))
+
+	res, err = httpClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestHandleAdminListUserFlags(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	superUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, superUser.UUID)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag", false)
+
+	testcases := []struct {
+		name           string
+		userUUID       string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name:           "List flags of inactive user",
+			userUUID:       user.UUID,
+			wantStatusCode: http.StatusOK,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name:           "List flags of non-existent user",
+			userUUID:       uuid.NewString(),
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailUserNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				TestServerURL+"/admin/users/"+testcase.userUUID+"/flags",
+				http.NoBody,
+			)
+			require.NoError(t, err)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", superUserAccessJWT))
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var listFlagsResp api.ListFlagsResponse
+				err = json.NewDecoder(res.Body).Decode(&listFlagsResp)
+				require.NoError(t, err)
+
+				require.Len(t, listFlagsResp.Flags, 1)
+				require.Equal(t, flag.ID, listFlagsResp.Flags[0].ID)
+				require.Equal(t, user.UUID, listFlagsResp.Flags[0].UserUUID)
+				require.Equal(t, flag.Name, listFlagsResp.Flags[0].Name)
+
+				requireAdminAuditLog(t, user.UUID, superUser.UUID, audit.EventTypeAdminFlagsViewed)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleAdminImpersonateUser(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	superUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, superUser.UUID)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherSuperUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+
+	testcases := []struct {
+		name           string
+		userUUID       string
+		requestBody    string
+		wantStatusCode int
+		wantReadOnly   bool
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name:           "Impersonate user without request body",
+			userUUID:       user.UUID,
+			requestBody:    "",
+			wantStatusCode: http.StatusCreated,
+			wantReadOnly:   true,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name:           "Impersonate user with writes allowed",
+			userUUID:       user.UUID,
+			requestBody:    `{"allow_writes": true}`,
+			wantStatusCode: http.StatusCreated,
+			wantReadOnly:   false,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name:           "Impersonate self",
+			userUUID:       superUser.UUID,
+			requestBody:    "",
+			wantStatusCode: http.StatusForbidden,
+			wantErrCode:    api.ErrCodePermissionDenied,
+			wantErrDetail:  api.ErrDetailCannotImpersonateUser,
+		},
+		{
+			name:           "Impersonate other superuser",
+			userUUID:       otherSuperUser.UUID,
+			requestBody:    "",
+			wantStatusCode: http.StatusForbidden,
+			wantErrCode:    api.ErrCodePermissionDenied,
+			wantErrDetail:  api.ErrDetailCannotImpersonateUser,
+		},
+		{
+			name:           "Impersonate non-existent user",
+			userUUID:       uuid.NewString(),
+			requestBody:    "",
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailUserNotFound,
+		},
+		{
+			name:           "Invalid request body",
+			userUUID:       user.UUID,
+			requestBody:    `{"allow_writes": "yes"}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/admin/users/"+testcase.userUUID+"/impersonate",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", superUserAccessJWT))
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var impersonateResp api.ImpersonateUserResponse
+				err = json.NewDecoder(res.Body).Decode(&impersonateResp)
+				require.NoError(t, err)
+
+				require.NotEmpty(t, impersonateResp.Access)
+				require.Equal(t, testcase.wantReadOnly, impersonateResp.ReadOnly)
+				require.True(t, impersonateResp.ExpiresAt.After(time.Now().UTC()))
+
+				requireAdminAuditLog(t, testcase.userUUID, superUser.UUID, audit.EventTypeAdminImpersonationStarted)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestImpersonatedRequests(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	superUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+		u.IsSuperUser = true
+	})
+	superUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, superUser.UUID)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	req, err := http.NewRequest(http.MethodPost, TestServerURL+"/admin/users/"+user.UUID+"/impersonate", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", superUserAccessJWT))
+
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	var impersonateResp api.ImpersonateUserResponse
+	err = json.NewDecoder(res.Body).Decode(&impersonateResp)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	req, err = http.NewRequest(http.MethodGet, TestServerURL+"/auth/users/me", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", impersonateResp.Access))
+
+	res, err = httpClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var getUserMeResp api.GetUserMeResponse
+	err = json.NewDecoder(res.Body).Decode(&getUserMeResp)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, user.UUID, getUserMeResp.UUID)
+
+	// impersonated requests are recorded after the response is written
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	auditRepo := audit.NewRepository()
+	require.Eventually(t, func() bool {
+		auditLogs, err := auditRepo.ListAuditLogsByUserUUID(dbConn, user.UUID)
+		require.NoError(t, err)
+
+		for _, auditLog := range auditLogs {
+			if auditLog.EventType == string(audit.EventTypeImpersonatedRequest) &&
+				auditLog.ActorUUID.String == superUser.UUID &&
+				auditLog.Detail == "GET /auth/users/me 200" {
+				return true
+			}
+		}
+
+		return false
+	}, time.Second, 10*time.Millisecond)
+
+	req, err = http.NewRequest(
+		http.MethodPost,
+		TestServerURL+"/flags",
+		bytes.NewReader([]byte(`{"name": "impersonated-flag"}`)),
+	)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", impersonateResp.Access))
+
+	res, err = httpClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	var errResp api.ErrorResponse
+	err = json.NewDecoder(res.Body).Decode(&errResp)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, api.ErrCodePermissionDenied, errResp.Code)
+	require.Equal(t, api.ErrDetailImpersonationReadOnly, errResp.Detail)
+
+	req, err = http.NewRequest(http.MethodGet, TestServerURL+"/admin/users", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", impersonateResp.Access))
+
+	res, err = httpClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+}
@@ -7,14 +7,33 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
+	"github.com/alvii147/flagger-api/internal/auth"
 	"github.com/alvii147/flagger-api/pkg/api"
 	"github.com/alvii147/flagger-api/pkg/errutils"
 	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const APIKeyIDParamKey = "id"
 
+const SessionIDParamKey = "id"
+
+const ServiceAccountUUIDParamKey = "uuid"
+
+const APIKeyUsageDaysQueryKey = "days"
+
+// apiKeyUsageDefaultDays is the number of days of API key usage returned when none is requested.
+const apiKeyUsageDefaultDays = 30
+
+// apiKeyUsageMaxDays is the maximum number of days of API key usage that can be requested.
+const apiKeyUsageMaxDays = 90
+
+// jwksMaxAge is the time for which clients may cache the published JSON Web Key Set.
+// Verification keys must stay published for at least this long before they start signing JWTs.
+const jwksMaxAge = 5 * time.Minute
+
 func getAPIKeyIDParam(r *http.Request) (int, error) {
 	param := r.PathValue(APIKeyIDParamKey)
 	apiKeyID, err := strconv.Atoi(param)
@@ -25,6 +44,56 @@ func getAPIKeyIDParam(r *http.Request) (int, error) {
 	return apiKeyID, nil
 }
 
+func getAPIKeyUsageDaysQuery(r *http.Request) (int, error) {
+	query := r.URL.Query().Get(APIKeyUsageDaysQueryKey)
+	if query == "" {
+		return apiKeyUsageDefaultDays, nil
+	}
+
+	days, err := strconv.Atoi(query)
+	if err != nil {
+		return 0, fmt.Errorf("getAPIKeyUsageDaysQuery failed to strconv.Atoi: %v", err)
+	}
+
+	if days < 1 || days > apiKeyUsageMaxDays {
+		return 0, fmt.Errorf("getAPIKeyUsageDaysQuery failed, days %d out of range", days)
+	}
+
+	return days, nil
+}
+
+// writeAuthTokens responds to successful authentication with new access and refresh JWTs.
+// With cookie token transport, the refresh JWT is set in a cookie along with a new CSRF token instead,
+// so that it is never exposed to scripts, and only the access JWT is included in the response body.
+// If access token cookies are enabled, the access JWT is set in a cookie as well, and the response has no body.
+func (ctrl *controller) writeAuthTokens(w *httputils.ResponseWriter, responseBody any, accessToken string, refreshToken string) {
+	if ctrl.config.AuthTokenTransport != auth.TokenTransportCookie {
+		w.WriteJSON(responseBody, http.StatusCreated)
+		return
+	}
+
+	err := auth.SetAuthCookies(w, refreshToken, time.Duration(ctrl.config.AuthRefreshLifetime*int64(time.Minute)))
+	if err != nil {
+		ctrl.logger.LogError("writeAuthTokens failed to auth.SetAuthCookies:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInternalServerError,
+				Detail: api.ErrDetailInternalServerError,
+			},
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	if ctrl.config.AuthAccessTokenCookie {
+		auth.SetAccessTokenCookie(w, accessToken, time.Duration(ctrl.config.AuthAccessLifetime*int64(time.Minute)))
+		w.WriteJSON(nil, http.StatusCreated)
+		return
+	}
+
+	w.WriteJSON(&api.CookieTokenResponse{Access: accessToken}, http.StatusCreated)
+}
+
 // handleCreateUser handles creation of new Users.
 // Methods: POST
 // URL: /auth/users
@@ -68,6 +137,7 @@ func (ctrl *controller) handleCreateUser(w *httputils.ResponseWriter, r *http.Re
 	)
 	if err != nil {
 		ctrl.logger.LogError("handleCreateUser failed to ctrl.authService.CreateUser:", err)
+		var validationErr *errutils.ValidationError
 		switch {
 		case errors.Is(err, errutils.ErrUserAlreadyExists):
 			w.WriteJSON(
@@ -77,6 +147,15 @@ func (ctrl *controller) handleCreateUser(w *httputils.ResponseWriter, r *http.Re
 				},
 				http.StatusConflict,
 			)
+		case errors.As(err, &validationErr):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:               api.ErrCodeInvalidRequest,
+					Detail:             api.ErrDetailInvalidRequestData,
+					ValidationFailures: validationErr.Failures,
+				},
+				http.StatusBadRequest,
+			)
 		default:
 			w.WriteJSON(
 				api.ErrorResponse{
@@ -170,6 +249,175 @@ func (ctrl *controller) handleActivateUser(w *httputils.ResponseWriter, r *http.
 	w.WriteJSON(nil, http.StatusOK)
 }
 
+// handleResendActivation handles resending of activation emails.
+// Responds with 202 regardless of whether or not an inactive User exists.
+// Methods: POST
+// URL: /auth/users/activate/resend
+func (ctrl *controller) handleResendActivation(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.ResendActivationRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleResendActivation failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleResendActivation failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	var wg sync.WaitGroup
+	err = ctrl.authService.ResendActivation(r.Context(), &wg, string(req.Email))
+	if err != nil {
+		ctrl.logger.LogError("handleResendActivation failed to ctrl.authService.ResendActivation:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInternalServerError,
+				Detail: api.ErrDetailInternalServerError,
+			},
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	w.WriteJSON(nil, http.StatusAccepted)
+}
+
+// handleRequestPasswordReset handles sending of password reset emails.
+// Responds with 202 regardless of whether or not the User exists.
+// Methods: POST
+// URL: /auth/password-reset
+func (ctrl *controller) handleRequestPasswordReset(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.RequestPasswordResetRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleRequestPasswordReset failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleRequestPasswordReset failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	var wg sync.WaitGroup
+	err = ctrl.authService.RequestPasswordReset(r.Context(), &wg, string(req.Email))
+	if err != nil {
+		ctrl.logger.LogError("handleRequestPasswordReset failed to ctrl.authService.RequestPasswordReset:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInternalServerError,
+				Detail: api.ErrDetailInternalServerError,
+			},
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	w.WriteJSON(nil, http.StatusAccepted)
+}
+
+// handleConfirmPasswordReset handles setting of new User password using password reset token.
+// Methods: POST
+// URL: /auth/password-reset/confirm
+func (ctrl *controller) handleConfirmPasswordReset(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.ConfirmPasswordResetRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleConfirmPasswordReset failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleConfirmPasswordReset failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	err = ctrl.authService.ResetPassword(r.Context(), string(req.Token), string(req.Password))
+	if err != nil {
+		ctrl.logger.LogError("handleConfirmPasswordReset failed to ctrl.authService.ResetPassword:", err)
+		var validationErr *errutils.ValidationError
+		switch {
+		case errors.Is(err, errutils.ErrInvalidToken):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidToken,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.As(err, &validationErr):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:               api.ErrCodeInvalidRequest,
+					Detail:             api.ErrDetailInvalidRequestData,
+					ValidationFailures: validationErr.Failures,
+				},
+				http.StatusBadRequest,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	w.WriteJSON(nil, http.StatusOK)
+}
+
 // handleGetUserMe handles retrieval of currently authenticated User.
 // Methods: GET
 // URL: /auth/users/me, /api/auth/users/me
@@ -209,14 +457,14 @@ func (ctrl *controller) handleGetUserMe(w *httputils.ResponseWriter, r *http.Req
 	w.WriteJSON(resp, http.StatusOK)
 }
 
-// handleCreateJWT handles authentication of User and creation of authentication JWTs.
+// handleChangePassword handles changing of currently authenticated User's password.
 // Methods: POST
-// URL: /auth/tokens
-func (ctrl *controller) handleCreateJWT(w *httputils.ResponseWriter, r *http.Request) {
-	var req api.CreateTokenRequest
+// URL: /auth/users/me/password
+func (ctrl *controller) handleChangePassword(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.ChangePasswordRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		ctrl.logger.LogWarn("handleCreateJWT failed to Decode:", err)
+		ctrl.logger.LogWarn("handleChangePassword failed to Decode:", err)
 		w.WriteJSON(
 			api.ErrorResponse{
 				Code:   api.ErrCodeInvalidRequest,
@@ -229,7 +477,7 @@ func (ctrl *controller) handleCreateJWT(w *httputils.ResponseWriter, r *http.Req
 
 	validationPassed, validationFailures := req.Validate()
 	if !validationPassed {
-		ctrl.logger.LogWarn("handleCreateJWT failed to Validate:", validationFailures)
+		ctrl.logger.LogWarn("handleChangePassword failed to Validate:", validationFailures)
 		w.WriteJSON(
 			api.ErrorResponse{
 				Code:               api.ErrCodeInvalidRequest,
@@ -241,22 +489,36 @@ func (ctrl *controller) handleCreateJWT(w *httputils.ResponseWriter, r *http.Req
 		return
 	}
 
-	accessToken, refreshToken, err := ctrl.authService.CreateJWT(
-		r.Context(),
-		string(req.Email),
-		string(req.Password),
-	)
+	err = ctrl.authService.ChangePassword(r.Context(), string(req.CurrentPassword), string(req.NewPassword))
 	if err != nil {
-		ctrl.logger.LogWarn("handleCreateJWT failed to ctrl.authService.CreateJWT:", err)
+		ctrl.logger.LogError("handleChangePassword failed to ctrl.authService.ChangePassword:", err)
+		var validationErr *errutils.ValidationError
 		switch {
 		case errors.Is(err, errutils.ErrInvalidCredentials):
 			w.WriteJSON(
 				api.ErrorResponse{
 					Code:   api.ErrCodeInvalidCredentials,
-					Detail: api.ErrDetailInvalidEmailOrPassword,
+					Detail: api.ErrDetailInvalidPassword,
 				},
 				http.StatusUnauthorized,
 			)
+		case errors.Is(err, errutils.ErrUserNotFound):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailUserNotFound,
+				},
+				http.StatusNotFound,
+			)
+		case errors.As(err, &validationErr):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:               api.ErrCodeInvalidRequest,
+					Detail:             api.ErrDetailInvalidRequestData,
+					ValidationFailures: validationErr.Failures,
+				},
+				http.StatusBadRequest,
+			)
 		default:
 			w.WriteJSON(
 				api.ErrorResponse{
@@ -269,22 +531,17 @@ func (ctrl *controller) handleCreateJWT(w *httputils.ResponseWriter, r *http.Req
 		return
 	}
 
-	responseBody := &api.CreateTokenResponse{
-		Access:  accessToken,
-		Refresh: refreshToken,
-	}
-
-	w.WriteJSON(responseBody, http.StatusCreated)
+	w.WriteJSON(nil, http.StatusOK)
 }
 
-// handleRefreshJWT handles creation of new access JWT.
+// handleChangeEmail handles sending of verification email for changing currently authenticated User's email.
 // Methods: POST
-// URL: /auth/tokens/refresh
-func (ctrl *controller) handleRefreshJWT(w *httputils.ResponseWriter, r *http.Request) {
-	var req api.RefreshTokenRequest
+// URL: /auth/users/me/email
+func (ctrl *controller) handleChangeEmail(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.ChangeEmailRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		ctrl.logger.LogWarn("handleRefreshJWT failed to Decode:", err)
+		ctrl.logger.LogWarn("handleChangeEmail failed to Decode:", err)
 		w.WriteJSON(
 			api.ErrorResponse{
 				Code:   api.ErrCodeInvalidRequest,
@@ -297,7 +554,7 @@ func (ctrl *controller) handleRefreshJWT(w *httputils.ResponseWriter, r *http.Re
 
 	validationPassed, validationFailures := req.Validate()
 	if !validationPassed {
-		ctrl.logger.LogWarn("handleRefreshJWT failed to Validate:", validationFailures)
+		ctrl.logger.LogWarn("handleChangeEmail failed to Validate:", validationFailures)
 		w.WriteJSON(
 			api.ErrorResponse{
 				Code:               api.ErrCodeInvalidRequest,
@@ -309,20 +566,34 @@ func (ctrl *controller) handleRefreshJWT(w *httputils.ResponseWriter, r *http.Re
 		return
 	}
 
-	accessToken, err := ctrl.authService.RefreshJWT(
-		r.Context(),
-		string(req.Refresh),
-	)
+	var wg sync.WaitGroup
+	err = ctrl.authService.RequestEmailChange(r.Context(), &wg, string(req.Email), string(req.Password))
 	if err != nil {
-		ctrl.logger.LogWarn("handleRefreshJWT failed to ctrl.authService.RefreshJWT:", err)
+		ctrl.logger.LogError("handleChangeEmail failed to ctrl.authService.RequestEmailChange:", err)
 		switch {
-		case errors.Is(err, errutils.ErrInvalidToken):
+		case errors.Is(err, errutils.ErrInvalidCredentials):
 			w.WriteJSON(
 				api.ErrorResponse{
-					Code:   api.ErrCodeInvalidRequest,
-					Detail: api.ErrDetailInvalidRequestData,
+					Code:   api.ErrCodeInvalidCredentials,
+					Detail: api.ErrDetailInvalidPassword,
 				},
-				http.StatusBadRequest,
+				http.StatusUnauthorized,
+			)
+		case errors.Is(err, errutils.ErrUserAlreadyExists):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceExists,
+					Detail: api.ErrDetailUserExists,
+				},
+				http.StatusConflict,
+			)
+		case errors.Is(err, errutils.ErrUserNotFound):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailUserNotFound,
+				},
+				http.StatusNotFound,
 			)
 		default:
 			w.WriteJSON(
@@ -336,21 +607,17 @@ func (ctrl *controller) handleRefreshJWT(w *httputils.ResponseWriter, r *http.Re
 		return
 	}
 
-	responseBody := &api.RefreshTokenResponse{
-		Access: accessToken,
-	}
-
-	w.WriteJSON(responseBody, http.StatusCreated)
+	w.WriteJSON(nil, http.StatusAccepted)
 }
 
-// handleCreateAPIKey handles creation of new User API Key.
+// handleConfirmEmailChange handles changing of User email using email change token.
 // Methods: POST
-// URL: /auth/api-keys
-func (ctrl *controller) handleCreateAPIKey(w *httputils.ResponseWriter, r *http.Request) {
-	var req api.CreateAPIKeyRequest
+// URL: /auth/users/email/confirm
+func (ctrl *controller) handleConfirmEmailChange(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.ConfirmEmailChangeRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		ctrl.logger.LogWarn("handleCreateAPIKey failed to Decode:", err)
+		ctrl.logger.LogWarn("handleConfirmEmailChange failed to Decode:", err)
 		w.WriteJSON(
 			api.ErrorResponse{
 				Code:   api.ErrCodeInvalidRequest,
@@ -363,7 +630,7 @@ func (ctrl *controller) handleCreateAPIKey(w *httputils.ResponseWriter, r *http.
 
 	validationPassed, validationFailures := req.Validate()
 	if !validationPassed {
-		ctrl.logger.LogWarn("handleCreateAPIKey failed to Validate:", validationFailures)
+		ctrl.logger.LogWarn("handleConfirmEmailChange failed to Validate:", validationFailures)
 		w.WriteJSON(
 			api.ErrorResponse{
 				Code:               api.ErrCodeInvalidRequest,
@@ -375,60 +642,1349 @@ func (ctrl *controller) handleCreateAPIKey(w *httputils.ResponseWriter, r *http.
 		return
 	}
 
-	apiKey, key, err := ctrl.authService.CreateAPIKey(r.Context(), string(req.Name), req.ExpiresAt)
+	err = ctrl.authService.ConfirmEmailChange(r.Context(), string(req.Token))
 	if err != nil {
-		ctrl.logger.LogWarn("handleCreateAPIKey failed to ctrl.authService.CreateAPIKey:", err)
-		w.WriteJSON(
-			api.ErrorResponse{
-				Code:   api.ErrCodeInternalServerError,
-				Detail: api.ErrDetailInternalServerError,
-			},
-			http.StatusInternalServerError,
-		)
-		return
-	}
-
+		ctrl.logger.LogError("handleConfirmEmailChange failed to ctrl.authService.ConfirmEmailChange:", err)
+		switch {
+		case errors.Is(err, errutils.ErrInvalidToken):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidToken,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.Is(err, errutils.ErrUserAlreadyExists):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceExists,
+					Detail: api.ErrDetailUserExists,
+				},
+				http.StatusConflict,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	w.WriteJSON(nil, http.StatusOK)
+}
+
+// handleCreateJWT handles authentication of User and creation of authentication JWTs.
+// Repeated failed attempts for an account or from an IP address are rejected with 429 until the lockout expires.
+// Methods: POST
+// URL: /auth/tokens
+func (ctrl *controller) handleCreateJWT(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.CreateTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleCreateJWT failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleCreateJWT failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	var wg sync.WaitGroup
+	accessToken, refreshToken, challengeToken, err := ctrl.authService.CreateJWT(
+		r.Context(),
+		&wg,
+		string(req.Email),
+		string(req.Password),
+		httputils.GetClientIP(r),
+		r.UserAgent(),
+	)
+	if err != nil {
+		ctrl.logger.LogWarn("handleCreateJWT failed to ctrl.authService.CreateJWT:", err)
+		switch {
+		case errors.Is(err, errutils.ErrInvalidCredentials):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidCredentials,
+					Detail: api.ErrDetailInvalidEmailOrPassword,
+				},
+				http.StatusUnauthorized,
+			)
+		case errors.Is(err, errutils.ErrTooManyLoginAttempts):
+			var retryAfterErr *errutils.RetryAfterError
+			if errors.As(err, &retryAfterErr) {
+				httputils.SetRetryAfterHeader(w.Header(), retryAfterErr.RetryAfter)
+			}
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeTooManyRequests,
+					Detail: api.ErrDetailTooManyLoginAttempts,
+				},
+				http.StatusTooManyRequests,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	if challengeToken != "" {
+		w.WriteJSON(&api.TOTPChallengeResponse{Challenge: challengeToken}, http.StatusAccepted)
+		return
+	}
+
+	responseBody := &api.CreateTokenResponse{
+		Access:  accessToken,
+		Refresh: refreshToken,
+	}
+
+	ctrl.writeAuthTokens(w, responseBody, accessToken, refreshToken)
+}
+
+// handleVerifyTOTPChallenge handles creation of access and refresh JWTs
+// for Users with two-factor authentication enabled.
+// Methods: POST
+// URL: /auth/tokens/totp
+func (ctrl *controller) handleVerifyTOTPChallenge(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.VerifyTOTPChallengeRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleVerifyTOTPChallenge failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleVerifyTOTPChallenge failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	accessToken, refreshToken, err := ctrl.authService.VerifyTOTPChallenge(
+		r.Context(),
+		string(req.Challenge),
+		string(req.Code),
+		httputils.GetClientIP(r),
+		r.UserAgent(),
+	)
+	if err != nil {
+		ctrl.logger.LogWarn("handleVerifyTOTPChallenge failed to ctrl.authService.VerifyTOTPChallenge:", err)
+		switch {
+		case errors.Is(err, errutils.ErrInvalidToken):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidToken,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.Is(err, errutils.ErrInvalidTOTPCode), errors.Is(err, errutils.ErrTOTPNotEnabled):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidCredentials,
+					Detail: api.ErrDetailInvalidTOTPCode,
+				},
+				http.StatusUnauthorized,
+			)
+		case errors.Is(err, errutils.ErrTooManyLoginAttempts):
+			var retryAfterErr *errutils.RetryAfterError
+			if errors.As(err, &retryAfterErr) {
+				httputils.SetRetryAfterHeader(w.Header(), retryAfterErr.RetryAfter)
+			}
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeTooManyRequests,
+					Detail: api.ErrDetailTooManyLoginAttempts,
+				},
+				http.StatusTooManyRequests,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	responseBody := &api.VerifyTOTPChallengeResponse{
+		Access:  accessToken,
+		Refresh: refreshToken,
+	}
+
+	ctrl.writeAuthTokens(w, responseBody, accessToken, refreshToken)
+}
+
+// handleRequestMagicLink handles sending of passwordless login emails.
+// The response is the same whether or not an account exists under the given email.
+// Methods: POST
+// URL: /auth/magic-link
+func (ctrl *controller) handleRequestMagicLink(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.RequestMagicLinkRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleRequestMagicLink failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleRequestMagicLink failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	var wg sync.WaitGroup
+	err = ctrl.authService.RequestMagicLink(r.Context(), &wg, string(req.Email))
+	if err != nil {
+		ctrl.logger.LogError("handleRequestMagicLink failed to ctrl.authService.RequestMagicLink:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInternalServerError,
+				Detail: api.ErrDetailInternalServerError,
+			},
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	w.WriteJSON(nil, http.StatusAccepted)
+}
+
+// handleVerifyMagicLink handles creation of access and refresh JWTs using magic link token.
+// Methods: POST
+// URL: /auth/magic-link/verify
+func (ctrl *controller) handleVerifyMagicLink(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.VerifyMagicLinkRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleVerifyMagicLink failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleVerifyMagicLink failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	accessToken, refreshToken, challengeToken, err := ctrl.authService.VerifyMagicLink(
+		r.Context(),
+		string(req.Token),
+		httputils.GetClientIP(r),
+		r.UserAgent(),
+	)
+	if err != nil {
+		ctrl.logger.LogWarn("handleVerifyMagicLink failed to ctrl.authService.VerifyMagicLink:", err)
+		switch {
+		case errors.Is(err, errutils.ErrInvalidToken):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidToken,
+				},
+				http.StatusBadRequest,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	if challengeToken != "" {
+		w.WriteJSON(&api.TOTPChallengeResponse{Challenge: challengeToken}, http.StatusAccepted)
+		return
+	}
+
+	responseBody := &api.CreateTokenResponse{
+		Access:  accessToken,
+		Refresh: refreshToken,
+	}
+
+	ctrl.writeAuthTokens(w, responseBody, accessToken, refreshToken)
+}
+
+// handleCreateOIDCAuthURL handles starting of single sign-on through the OpenID Connect provider.
+// Methods: POST
+// URL: /auth/oidc/authorize
+func (ctrl *controller) handleCreateOIDCAuthURL(w *httputils.ResponseWriter, r *http.Request) {
+	authURL, err := ctrl.authService.CreateOIDCAuthURL(r.Context())
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrOIDCNotConfigured):
+			ctrl.logger.LogWarn("handleCreateOIDCAuthURL failed to ctrl.authService.CreateOIDCAuthURL:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailOIDCNotConfigured,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			ctrl.logger.LogError("handleCreateOIDCAuthURL failed to ctrl.authService.CreateOIDCAuthURL:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	responseBody := &api.OIDCAuthURLResponse{
+		URL: authURL,
+	}
+
+	w.WriteJSON(responseBody, http.StatusOK)
+}
+
+// handleOIDCCallback handles creation of access and refresh JWTs
+// using the state and authorization code that the OpenID Connect provider redirected back with.
+// If the User has two-factor authentication enabled, a challenge JWT is returned instead.
+// Methods: POST
+// URL: /auth/oidc/callback
+func (ctrl *controller) handleOIDCCallback(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.OIDCCallbackRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleOIDCCallback failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleOIDCCallback failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	accessToken, refreshToken, challengeToken, err := ctrl.authService.CreateJWTFromOIDC(
+		r.Context(),
+		string(req.State),
+		string(req.Code),
+		httputils.GetClientIP(r),
+		r.UserAgent(),
+	)
+	if err != nil {
+		ctrl.logger.LogWarn("handleOIDCCallback failed to ctrl.authService.CreateJWTFromOIDC:", err)
+		switch {
+		case errors.Is(err, errutils.ErrOIDCNotConfigured):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailOIDCNotConfigured,
+				},
+				http.StatusNotFound,
+			)
+		case errors.Is(err, errutils.ErrInvalidOIDCState):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidOIDCState,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.Is(err, errutils.ErrOIDCAuthFailed):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidCredentials,
+					Detail: api.ErrDetailOIDCAuthFailed,
+				},
+				http.StatusUnauthorized,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	if challengeToken != "" {
+		w.WriteJSON(&api.TOTPChallengeResponse{Challenge: challengeToken}, http.StatusAccepted)
+		return
+	}
+
+	responseBody := &api.OIDCCallbackResponse{
+		Access:  accessToken,
+		Refresh: refreshToken,
+	}
+
+	ctrl.writeAuthTokens(w, responseBody, accessToken, refreshToken)
+}
+
+// handleRefreshJWT handles rotation of refresh JWT and creation of new access JWT.
+// With cookie token transport, the refresh JWT is read from its cookie,
+// and the request must pass the double-submit CSRF check.
+// Methods: POST
+// URL: /auth/tokens/refresh
+func (ctrl *controller) handleRefreshJWT(w *httputils.ResponseWriter, r *http.Request) {
+	var token string
+	if ctrl.config.AuthTokenTransport == auth.TokenTransportCookie {
+		var ok bool
+		token, ok = auth.GetAuthCookie(r, auth.RefreshTokenCookieName)
+		if !ok {
+			ctrl.logger.LogWarn("handleRefreshJWT failed to auth.GetAuthCookie")
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeMissingCredentials,
+					Detail: api.ErrDetailMissingCredentials,
+				},
+				http.StatusUnauthorized,
+			)
+			return
+		}
+
+		if !auth.CheckCSRFToken(r) {
+			ctrl.logger.LogWarn("handleRefreshJWT failed to auth.CheckCSRFToken")
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodePermissionDenied,
+					Detail: api.ErrDetailInvalidCSRFToken,
+				},
+				http.StatusForbidden,
+			)
+			return
+		}
+	} else {
+		var req api.RefreshTokenRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			ctrl.logger.LogWarn("handleRefreshJWT failed to Decode:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidRequestData,
+				},
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		validationPassed, validationFailures := req.Validate()
+		if !validationPassed {
+			ctrl.logger.LogWarn("handleRefreshJWT failed to Validate:", validationFailures)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:               api.ErrCodeInvalidRequest,
+					Detail:             api.ErrDetailInvalidRequestData,
+					ValidationFailures: validationFailures,
+				},
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		token = string(req.Refresh)
+	}
+
+	accessToken, refreshToken, err := ctrl.authService.RefreshJWT(
+		r.Context(),
+		token,
+		httputils.GetClientIP(r),
+		r.UserAgent(),
+	)
+	if err != nil {
+		ctrl.logger.LogWarn("handleRefreshJWT failed to ctrl.authService.RefreshJWT:", err)
+		switch {
+		case errors.Is(err, errutils.ErrInvalidToken):
+			if ctrl.config.AuthTokenTransport == auth.TokenTransportCookie {
+				auth.ClearAuthCookies(w)
+			}
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidRequestData,
+				},
+				http.StatusBadRequest,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	responseBody := &api.RefreshTokenResponse{
+		Access:  accessToken,
+		Refresh: refreshToken,
+	}
+
+	ctrl.writeAuthTokens(w, responseBody, accessToken, refreshToken)
+}
+
+// handleEnrollTOTP handles creation of unconfirmed TOTP device for currently authenticated User.
+// Methods: POST
+// URL: /auth/users/me/totp
+func (ctrl *controller) handleEnrollTOTP(w *httputils.ResponseWriter, r *http.Request) {
+	secret, uri, err := ctrl.authService.EnrollTOTP(r.Context())
+	if err != nil {
+		ctrl.logger.LogError("handleEnrollTOTP failed to ctrl.authService.EnrollTOTP:", err)
+		switch {
+		case errors.Is(err, errutils.ErrTOTPAlreadyEnabled):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceExists,
+					Detail: api.ErrDetailTOTPAlreadyEnabled,
+				},
+				http.StatusConflict,
+			)
+		case errors.Is(err, errutils.ErrUserNotFound):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailUserNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	responseBody := &api.EnrollTOTPResponse{
+		Secret: secret,
+		URI:    uri,
+	}
+
+	w.WriteJSON(responseBody, http.StatusCreated)
+}
+
+// handleConfirmTOTP handles confirmation of TOTP device of currently authenticated User.
+// Methods: POST
+// URL: /auth/users/me/totp/confirm
+func (ctrl *controller) handleConfirmTOTP(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.ConfirmTOTPRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleConfirmTOTP failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleConfirmTOTP failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	recoveryCodes, err := ctrl.authService.ConfirmTOTP(r.Context(), string(req.Code), httputils.GetClientIP(r))
+	if err != nil {
+		ctrl.logger.LogError("handleConfirmTOTP failed to ctrl.authService.ConfirmTOTP:", err)
+		switch {
+		case errors.Is(err, errutils.ErrInvalidTOTPCode):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidCredentials,
+					Detail: api.ErrDetailInvalidTOTPCode,
+				},
+				http.StatusUnauthorized,
+			)
+		case errors.Is(err, errutils.ErrTOTPAlreadyEnabled):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceExists,
+					Detail: api.ErrDetailTOTPAlreadyEnabled,
+				},
+				http.StatusConflict,
+			)
+		case errors.Is(err, errutils.ErrTOTPNotEnabled):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailTOTPNotEnabled,
+				},
+				http.StatusNotFound,
+			)
+		case errors.Is(err, errutils.ErrTooManyLoginAttempts):
+			var retryAfterErr *errutils.RetryAfterError
+			if errors.As(err, &retryAfterErr) {
+				httputils.SetRetryAfterHeader(w.Header(), retryAfterErr.RetryAfter)
+			}
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeTooManyRequests,
+					Detail: api.ErrDetailTooManyLoginAttempts,
+				},
+				http.StatusTooManyRequests,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	responseBody := &api.ConfirmTOTPResponse{
+		RecoveryCodes: recoveryCodes,
+	}
+
+	w.WriteJSON(responseBody, http.StatusOK)
+}
+
+// handleDisableTOTP handles disabling of two-factor authentication for currently authenticated User.
+// Methods: POST
+// URL: /auth/users/me/totp/disable
+func (ctrl *controller) handleDisableTOTP(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.DisableTOTPRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleDisableTOTP failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleDisableTOTP failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	err = ctrl.authService.DisableTOTP(r.Context(), string(req.Password), string(req.Code), httputils.GetClientIP(r))
+	if err != nil {
+		ctrl.logger.LogError("handleDisableTOTP failed to ctrl.authService.DisableTOTP:", err)
+		switch {
+		case errors.Is(err, errutils.ErrInvalidCredentials):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidCredentials,
+					Detail: api.ErrDetailInvalidPassword,
+				},
+				http.StatusUnauthorized,
+			)
+		case errors.Is(err, errutils.ErrInvalidTOTPCode):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidCredentials,
+					Detail: api.ErrDetailInvalidTOTPCode,
+				},
+				http.StatusUnauthorized,
+			)
+		case errors.Is(err, errutils.ErrTOTPNotEnabled):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailTOTPNotEnabled,
+				},
+				http.StatusNotFound,
+			)
+		case errors.Is(err, errutils.ErrUserNotFound):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailUserNotFound,
+				},
+				http.StatusNotFound,
+			)
+		case errors.Is(err, errutils.ErrTooManyLoginAttempts):
+			var retryAfterErr *errutils.RetryAfterError
+			if errors.As(err, &retryAfterErr) {
+				httputils.SetRetryAfterHeader(w.Header(), retryAfterErr.RetryAfter)
+			}
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeTooManyRequests,
+					Detail: api.ErrDetailTooManyLoginAttempts,
+				},
+				http.StatusTooManyRequests,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	w.WriteJSON(nil, http.StatusNoContent)
+}
+
+// handleListSessions handles listing of active sessions of currently authenticated User.
+// Methods: GET
+// URL: /auth/sessions
+func (ctrl *controller) handleListSessions(w *httputils.ResponseWriter, r *http.Request) {
+	sessions, err := ctrl.authService.ListSessions(r.Context())
+	if err != nil {
+		ctrl.logger.LogError("handleListSessions failed to ctrl.authService.ListSessions:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInternalServerError,
+				Detail: api.ErrDetailInternalServerError,
+			},
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	currentSessionID, _ := r.Context().Value(auth.AuthContextKeySessionID).(string)
+	responseBody := &api.ListSessionsResponse{
+		Sessions: make([]*api.GetSessionResponse, len(sessions)),
+	}
+
+	for i, session := range sessions {
+		responseBody.Sessions[i] = &api.GetSessionResponse{
+			ID:         session.ID,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		}
+	}
+
+	w.WriteJSON(responseBody, http.StatusOK)
+}
+
+// handleDeleteSession handles revocation of a session of currently authenticated User.
+// Methods: DELETE
+// URL: /auth/sessions/{id}
+func (ctrl *controller) handleDeleteSession(w *httputils.ResponseWriter, r *http.Request) {
+	sessionID := r.PathValue(SessionIDParamKey)
+	err := ctrl.authService.RevokeSession(r.Context(), sessionID)
+	if err != nil {
+		ctrl.logger.LogError("handleDeleteSession failed to ctrl.authService.RevokeSession:", err)
+		switch {
+		case errors.Is(err, errutils.ErrSessionNotFound):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailSessionNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	w.WriteJSON(nil, http.StatusNoContent)
+}
+
+// handleLogout handles revocation of currently authenticated session.
+// With cookie token transport, the authentication cookies are cleared.
+// Methods: POST
+// URL: /auth/logout
+func (ctrl *controller) handleLogout(w *httputils.ResponseWriter, r *http.Request) {
+	err := ctrl.authService.Logout(r.Context())
+	if err != nil {
+		ctrl.logger.LogError("handleLogout failed to ctrl.authService.Logout:", err)
+		switch {
+		case errors.Is(err, errutils.ErrSessionNotFound):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailSessionNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	if ctrl.config.AuthTokenTransport == auth.TokenTransportCookie {
+		auth.ClearAuthCookies(w)
+	}
+
+	w.WriteJSON(nil, http.StatusNoContent)
+}
+
+// handleLogoutAll handles revocation of all sessions of currently authenticated User.
+// With cookie token transport, the authentication cookies are cleared.
+// Methods: POST
+// URL: /auth/logout-all
+func (ctrl *controller) handleLogoutAll(w *httputils.ResponseWriter, r *http.Request) {
+	err := ctrl.authService.LogoutAll(r.Context())
+	if err != nil {
+		ctrl.logger.LogError("handleLogoutAll failed to ctrl.authService.LogoutAll:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInternalServerError,
+				Detail: api.ErrDetailInternalServerError,
+			},
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	if ctrl.config.AuthTokenTransport == auth.TokenTransportCookie {
+		auth.ClearAuthCookies(w)
+	}
+
+	w.WriteJSON(nil, http.StatusNoContent)
+}
+
+// handleCreateAPIKey handles creation of new User API Key.
+// Methods: POST
+// URL: /auth/api-keys
+func (ctrl *controller) handleCreateAPIKey(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.CreateAPIKeyRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleCreateAPIKey failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleCreateAPIKey failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	apiKey, key, err := ctrl.authService.CreateAPIKey(
+		r.Context(),
+		string(req.Name),
+		req.Scopes,
+		req.AllowedCIDRs,
+		req.AllowedOrigins,
+		req.ServiceAccountUUID,
+		req.ExpiresAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrInvalidAPIKeyScope):
+			ctrl.logger.LogWarn("handleCreateAPIKey failed to ctrl.authService.CreateAPIKey:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidAPIKeyScope,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.Is(err, errutils.ErrInvalidAPIKeyCIDR):
+			ctrl.logger.LogWarn("handleCreateAPIKey failed to ctrl.authService.CreateAPIKey:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidAPIKeyCIDR,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.Is(err, errutils.ErrInvalidAPIKeyOrigin):
+			ctrl.logger.LogWarn("handleCreateAPIKey failed to ctrl.authService.CreateAPIKey:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidAPIKeyOrigin,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.Is(err, errutils.ErrServiceAccountScopeDenied):
+			ctrl.logger.LogWarn("handleCreateAPIKey failed to ctrl.authService.CreateAPIKey:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailServiceAccountScopeDenied,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.Is(err, errutils.ErrServiceAccountNotFound):
+			ctrl.logger.LogWarn("handleCreateAPIKey failed to ctrl.authService.CreateAPIKey:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailServiceAccountNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			ctrl.logger.LogWarn("handleCreateAPIKey failed to ctrl.authService.CreateAPIKey:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
 	responseBody := &api.CreateAPIKeyResponse{
-		ID:        apiKey.ID,
-		RawKey:    key,
-		UserUUID:  apiKey.UserUUID,
-		Name:      apiKey.Name,
-		CreatedAt: apiKey.CreatedAt,
-		ExpiresAt: apiKey.ExpiresAt,
+		ID:                 apiKey.ID,
+		RawKey:             key,
+		UserUUID:           apiKey.UserUUID,
+		ServiceAccountUUID: apiKey.ServiceAccountUUID,
+		Name:               apiKey.Name,
+		Scopes:             apiKey.Scopes,
+		AllowedCIDRs:       apiKey.AllowedCIDRs,
+		AllowedOrigins:     apiKey.AllowedOrigins,
+		CreatedAt:          apiKey.CreatedAt,
+		ExpiresAt:          apiKey.ExpiresAt,
+	}
+
+	w.WriteJSON(responseBody, http.StatusCreated)
+}
+
+// handleListAPIKeys handles retrieval of API Keys for currently authenticated User.
+// Methods: GET
+// URL: /auth/api-keys
+func (ctrl *controller) handleListAPIKeys(w *httputils.ResponseWriter, r *http.Request) {
+	apiKeys, err := ctrl.authService.ListAPIKeys(r.Context())
+	if err != nil {
+		ctrl.logger.LogWarn("handleListAPIKeys failed to ctrl.authService.ListAPIKeys:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInternalServerError,
+				Detail: api.ErrDetailInternalServerError,
+			},
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	responseBody := &api.ListAPIKeysResponse{
+		Keys: make([]*api.GetAPIKeyResponse, len(apiKeys)),
+	}
+
+	for i, apiKey := range apiKeys {
+		responseBody.Keys[i] = &api.GetAPIKeyResponse{
+			ID:                   apiKey.ID,
+			UserUUID:             apiKey.UserUUID,
+			ServiceAccountUUID:   apiKey.ServiceAccountUUID,
+			Prefix:               apiKey.Prefix,
+			Name:                 apiKey.Name,
+			Scopes:               apiKey.Scopes,
+			AllowedCIDRs:         apiKey.AllowedCIDRs,
+			AllowedOrigins:       apiKey.AllowedOrigins,
+			CreatedAt:            apiKey.CreatedAt,
+			ExpiresAt:            apiKey.ExpiresAt,
+			PreviousKeyExpiresAt: apiKey.PreviousExpiresAt,
+			LastUsedAt:           apiKey.LastUsedAt,
+			LastUsedIP:           apiKey.LastUsedIP,
+			RequestCount:         apiKey.RequestCount,
+		}
+	}
+
+	w.WriteJSON(responseBody, http.StatusOK)
+}
+
+// handleUpdateAPIKey handles renaming of API Keys and changing of their expiration dates.
+// Methods: PUT
+// URL: /auth/api-keys/{id}
+func (ctrl *controller) handleUpdateAPIKey(w *httputils.ResponseWriter, r *http.Request) {
+	apiKeyID, err := getAPIKeyIDParam(r)
+	if err != nil {
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	var req api.UpdateAPIKeyRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleUpdateAPIKey failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleUpdateAPIKey failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	var expiresAt *pgtype.Timestamp
+	if req.ExpiresAt.Set {
+		expiresAt = &req.ExpiresAt.Value
+	}
+
+	if req.Name == nil && req.AllowedCIDRs == nil && req.AllowedOrigins == nil && expiresAt == nil {
+		ctrl.logger.LogWarn("handleUpdateAPIKey failed, no attributes to update")
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	apiKey, err := ctrl.authService.UpdateAPIKey(r.Context(), apiKeyID, req.Name, req.AllowedCIDRs, req.AllowedOrigins, expiresAt)
+	if err != nil {
+		ctrl.logger.LogWarn("handleUpdateAPIKey failed to ctrl.authService.UpdateAPIKey:", err)
+		switch {
+		case errors.Is(err, errutils.ErrInvalidAPIKeyCIDR):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidAPIKeyCIDR,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.Is(err, errutils.ErrInvalidAPIKeyOrigin):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidAPIKeyOrigin,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.Is(err, errutils.ErrAPIKeyNotFound):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailAPIKeyNotFound,
+				},
+				http.StatusNotFound,
+			)
+		case errors.Is(err, errutils.ErrAPIKeyAlreadyExists):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceExists,
+					Detail: api.ErrDetailAPIKeyExists,
+				},
+				http.StatusConflict,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	responseBody := &api.GetAPIKeyResponse{
+		ID:                   apiKey.ID,
+		UserUUID:             apiKey.UserUUID,
+		ServiceAccountUUID:   apiKey.ServiceAccountUUID,
+		Prefix:               apiKey.Prefix,
+		Name:                 apiKey.Name,
+		Scopes:               apiKey.Scopes,
+		AllowedCIDRs:         apiKey.AllowedCIDRs,
+		AllowedOrigins:       apiKey.AllowedOrigins,
+		CreatedAt:            apiKey.CreatedAt,
+		ExpiresAt:            apiKey.ExpiresAt,
+		PreviousKeyExpiresAt: apiKey.PreviousExpiresAt,
+		LastUsedAt:           apiKey.LastUsedAt,
+		LastUsedIP:           apiKey.LastUsedIP,
+		RequestCount:         apiKey.RequestCount,
+	}
+
+	w.WriteJSON(responseBody, http.StatusOK)
+}
+
+// handleRotateAPIKey handles issuing of new secrets for API Keys,
+// keeping the previous secret valid for a grace period.
+// Methods: POST
+// URL: /auth/api-keys/{id}/rotate
+func (ctrl *controller) handleRotateAPIKey(w *httputils.ResponseWriter, r *http.Request) {
+	apiKeyID, err := getAPIKeyIDParam(r)
+	if err != nil {
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
 	}
 
-	w.WriteJSON(responseBody, http.StatusCreated)
+	apiKey, key, err := ctrl.authService.RotateAPIKey(r.Context(), apiKeyID)
+	if err != nil {
+		ctrl.logger.LogWarn("handleRotateAPIKey failed to ctrl.authService.RotateAPIKey:", err)
+		switch {
+		case errors.Is(err, errutils.ErrAPIKeyNotFound):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailAPIKeyNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	responseBody := &api.RotateAPIKeyResponse{
+		ID:                   apiKey.ID,
+		RawKey:               key,
+		UserUUID:             apiKey.UserUUID,
+		ServiceAccountUUID:   apiKey.ServiceAccountUUID,
+		Name:                 apiKey.Name,
+		Scopes:               apiKey.Scopes,
+		AllowedCIDRs:         apiKey.AllowedCIDRs,
+		AllowedOrigins:       apiKey.AllowedOrigins,
+		CreatedAt:            apiKey.CreatedAt,
+		ExpiresAt:            apiKey.ExpiresAt,
+		PreviousKeyExpiresAt: apiKey.PreviousExpiresAt,
+	}
+
+	w.WriteJSON(responseBody, http.StatusOK)
 }
 
-// handleListAPIKeys handles retrieval of API Keys for currently authenticated User.
+// handleGetAPIKeyUsage handles retrieval of daily request counts of API Keys.
 // Methods: GET
-// URL: /auth/api-keys
-func (ctrl *controller) handleListAPIKeys(w *httputils.ResponseWriter, r *http.Request) {
-	apiKeys, err := ctrl.authService.ListAPIKeys(r.Context())
+// URL: /auth/api-keys/{id}/usage
+func (ctrl *controller) handleGetAPIKeyUsage(w *httputils.ResponseWriter, r *http.Request) {
+	apiKeyID, err := getAPIKeyIDParam(r)
 	if err != nil {
-		ctrl.logger.LogWarn("handleListAPIKeys failed to ctrl.authService.ListAPIKeys:", err)
 		w.WriteJSON(
 			api.ErrorResponse{
-				Code:   api.ErrCodeInternalServerError,
-				Detail: api.ErrDetailInternalServerError,
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
 			},
-			http.StatusInternalServerError,
+			http.StatusBadRequest,
 		)
 		return
 	}
 
-	responseBody := &api.ListAPIKeysResponse{
-		Keys: make([]*api.GetAPIKeyResponse, len(apiKeys)),
+	days, err := getAPIKeyUsageDaysQuery(r)
+	if err != nil {
+		ctrl.logger.LogWarn("handleGetAPIKeyUsage failed to getAPIKeyUsageDaysQuery:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
 	}
 
-	for i, apiKey := range apiKeys {
-		responseBody.Keys[i] = &api.GetAPIKeyResponse{
-			ID:        apiKey.ID,
-			UserUUID:  apiKey.UserUUID,
-			Prefix:    apiKey.Prefix,
-			Name:      apiKey.Name,
-			CreatedAt: apiKey.CreatedAt,
-			ExpiresAt: apiKey.ExpiresAt,
+	usage, err := ctrl.authService.GetAPIKeyUsage(r.Context(), apiKeyID, days)
+	if err != nil {
+		ctrl.logger.LogWarn("handleGetAPIKeyUsage failed to ctrl.authService.GetAPIKeyUsage:", err)
+		switch {
+		case errors.Is(err, errutils.ErrAPIKeyNotFound):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailAPIKeyNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	responseBody := &api.GetAPIKeyUsageResponse{
+		ID:    apiKeyID,
+		Usage: make([]*api.GetAPIKeyDailyUsageResponse, len(usage)),
+	}
+
+	for i, dailyUsage := range usage {
+		responseBody.Usage[i] = &api.GetAPIKeyDailyUsageResponse{
+			Date:         dailyUsage.Day.Format(time.DateOnly),
+			RequestCount: dailyUsage.RequestCount,
 		}
 	}
 
@@ -477,3 +2033,254 @@ func (ctrl *controller) handleDeleteAPIKey(w *httputils.ResponseWriter, r *http.
 
 	w.WriteJSON(nil, http.StatusNoContent)
 }
+
+// handleCreateServiceAccount handles creation of new service account owned by currently authenticated User.
+// Methods: POST
+// URL: /auth/service-accounts
+func (ctrl *controller) handleCreateServiceAccount(w *httputils.ResponseWriter, r *http.Request) {
+	var req api.CreateServiceAccountRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleCreateServiceAccount failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleCreateServiceAccount failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	serviceAccount, err := ctrl.authService.CreateServiceAccount(r.Context(), req.Name, req.Description, req.Scopes)
+	if err != nil {
+		ctrl.logger.LogWarn("handleCreateServiceAccount failed to ctrl.authService.CreateServiceAccount:", err)
+		switch {
+		case errors.Is(err, errutils.ErrInvalidAPIKeyScope):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInvalidRequest,
+					Detail: api.ErrDetailInvalidAPIKeyScope,
+				},
+				http.StatusBadRequest,
+			)
+		case errors.Is(err, errutils.ErrServiceAccountAlreadyExists):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceExists,
+					Detail: api.ErrDetailServiceAccountExists,
+				},
+				http.StatusConflict,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	responseBody := &api.CreateServiceAccountResponse{
+		UUID:        serviceAccount.UUID,
+		OwnerUUID:   serviceAccount.OwnerUUID,
+		Name:        serviceAccount.Name,
+		Description: serviceAccount.Description,
+		Scopes:      serviceAccount.Scopes,
+		IsActive:    serviceAccount.IsActive,
+		CreatedAt:   serviceAccount.CreatedAt,
+	}
+
+	w.WriteJSON(responseBody, http.StatusCreated)
+}
+
+// handleListServiceAccounts handles retrieval of service accounts owned by currently authenticated User.
+// Methods: GET
+// URL: /auth/service-accounts
+func (ctrl *controller) handleListServiceAccounts(w *httputils.ResponseWriter, r *http.Request) {
+	serviceAccounts, err := ctrl.authService.ListServiceAccounts(r.Context())
+	if err != nil {
+		ctrl.logger.LogWarn("handleListServiceAccounts failed to ctrl.authService.ListServiceAccounts:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInternalServerError,
+				Detail: api.ErrDetailInternalServerError,
+			},
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	responseBody := &api.ListServiceAccountsResponse{
+		ServiceAccounts: make([]*api.GetServiceAccountResponse, len(serviceAccounts)),
+	}
+
+	for i, serviceAccount := range serviceAccounts {
+		responseBody.ServiceAccounts[i] = &api.GetServiceAccountResponse{
+			UUID:        serviceAccount.UUID,
+			OwnerUUID:   serviceAccount.OwnerUUID,
+			Name:        serviceAccount.Name,
+			Description: serviceAccount.Description,
+			Scopes:      serviceAccount.Scopes,
+			IsActive:    serviceAccount.IsActive,
+			CreatedAt:   serviceAccount.CreatedAt,
+		}
+	}
+
+	w.WriteJSON(responseBody, http.StatusOK)
+}
+
+// handleUpdateServiceAccount handles renaming, describing, activating, and deactivating of service accounts.
+// Methods: PUT
+// URL: /auth/service-accounts/{uuid}
+func (ctrl *controller) handleUpdateServiceAccount(w *httputils.ResponseWriter, r *http.Request) {
+	serviceAccountUUID := r.PathValue(ServiceAccountUUIDParamKey)
+
+	var req api.UpdateServiceAccountRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ctrl.logger.LogWarn("handleUpdateServiceAccount failed to Decode:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	validationPassed, validationFailures := req.Validate()
+	if !validationPassed {
+		ctrl.logger.LogWarn("handleUpdateServiceAccount failed to Validate:", validationFailures)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:               api.ErrCodeInvalidRequest,
+				Detail:             api.ErrDetailInvalidRequestData,
+				ValidationFailures: validationFailures,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	if req.Name == nil && req.Description == nil && req.IsActive == nil {
+		ctrl.logger.LogWarn("handleUpdateServiceAccount failed, no attributes to update")
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	serviceAccount, err := ctrl.authService.UpdateServiceAccount(
+		r.Context(),
+		serviceAccountUUID,
+		req.Name,
+		req.Description,
+		req.IsActive,
+	)
+	if err != nil {
+		ctrl.logger.LogWarn("handleUpdateServiceAccount failed to ctrl.authService.UpdateServiceAccount:", err)
+		switch {
+		case errors.Is(err, errutils.ErrServiceAccountNotFound):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailServiceAccountNotFound,
+				},
+				http.StatusNotFound,
+			)
+		case errors.Is(err, errutils.ErrServiceAccountAlreadyExists):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceExists,
+					Detail: api.ErrDetailServiceAccountExists,
+				},
+				http.StatusConflict,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	responseBody := &api.GetServiceAccountResponse{
+		UUID:        serviceAccount.UUID,
+		OwnerUUID:   serviceAccount.OwnerUUID,
+		Name:        serviceAccount.Name,
+		Description: serviceAccount.Description,
+		Scopes:      serviceAccount.Scopes,
+		IsActive:    serviceAccount.IsActive,
+		CreatedAt:   serviceAccount.CreatedAt,
+	}
+
+	w.WriteJSON(responseBody, http.StatusOK)
+}
+
+// handleDeleteServiceAccount handles deletion of service accounts, along with their API Keys.
+// Methods: DELETE
+// URL: /auth/service-accounts/{uuid}
+func (ctrl *controller) handleDeleteServiceAccount(w *httputils.ResponseWriter, r *http.Request) {
+	serviceAccountUUID := r.PathValue(ServiceAccountUUIDParamKey)
+	err := ctrl.authService.DeleteServiceAccount(r.Context(), serviceAccountUUID)
+	if err != nil {
+		ctrl.logger.LogWarn("handleDeleteServiceAccount failed to ctrl.authService.DeleteServiceAccount:", err)
+		switch {
+		case errors.Is(err, errutils.ErrServiceAccountNotFound):
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeResourceNotFound,
+					Detail: api.ErrDetailServiceAccountNotFound,
+				},
+				http.StatusNotFound,
+			)
+		default:
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	w.WriteJSON(nil, http.StatusNoContent)
+}
+
+// handleGetJWKS handles publishing of the public keys used to sign access and refresh JWTs,
+// so that other services can validate them without the signing key.
+// Methods: GET
+// URL: /.well-known/jwks.json
+func (ctrl *controller) handleGetJWKS(w *httputils.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+	w.WriteJSON(ctrl.keySet.JSONWebKeySet(), http.StatusOK)
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -15,7 +17,9 @@ import (
 	"github.com/alvii147/flagger-api/internal/testkitinternal"
 	"github.com/alvii147/flagger-api/pkg/api"
 	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/alvii147/flagger-api/pkg/jwks"
 	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/alvii147/flagger-api/pkg/totp"
 	"github.com/alvii147/flagger-api/pkg/utils"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
@@ -78,6 +82,71 @@ func TestGetAPIKeyIDParam(t *testing.T) {
 	}
 }
 
+func TestGetAPIKeyUsageDaysQuery(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		rawQuery string
+		wantDays int
+		wantErr  bool
+	}{
+		{
+			name:     "No days defaults to 30",
+			rawQuery: "",
+			wantDays: 30,
+			wantErr:  false,
+		},
+		{
+			name:     "Valid days",
+			rawQuery: "days=7",
+			wantDays: 7,
+			wantErr:  false,
+		},
+		{
+			name:     "Maximum days",
+			rawQuery: "days=90",
+			wantDays: 90,
+			wantErr:  false,
+		},
+		{
+			name:     "Too many days",
+			rawQuery: "days=91",
+			wantDays: 0,
+			wantErr:  true,
+		},
+		{
+			name:     "Zero days",
+			rawQuery: "days=0",
+			wantDays: 0,
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid days",
+			rawQuery: "days=deadbeef",
+			wantDays: 0,
+			wantErr:  true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, "/auth/api-keys/1/usage?"+testcase.rawQuery, http.NoBody)
+			require.NoError(t, err)
+
+			days, err := server.GetAPIKeyUsageDaysQuery(req)
+			if testcase.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, testcase.wantDays, days)
+			}
+		})
+	}
+}
+
 func TestHandleCreateUser(t *testing.T) {
 	t.Parallel()
 
@@ -249,6 +318,34 @@ func TestHandleCreateUser(t *testing.T) {
 			wantErrCode:    api.ErrCodeInvalidRequest,
 			wantErrDetail:  api.ErrDetailInvalidRequestData,
 		},
+		{
+			name: "Breached password",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s",
+					"password": "Password1234!",
+					"first_name": "%s",
+					"last_name": "%s"
+				}
+			`, testkit.GenerateFakeEmail(), firstName, lastName),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Password containing name",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s",
+					"password": "%sD34dB33f",
+					"first_name": "%s",
+					"last_name": "%s"
+				}
+			`, testkit.GenerateFakeEmail(), firstName, firstName, lastName),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
 	}
 
 	for _, testcase := range testcases {
@@ -303,30 +400,21 @@ func TestHandleActivateUser(t *testing.T) {
 		u.IsActive = true
 	})
 
-	now := time.Now().UTC()
-	activeUserToken, err := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		&api.ActivationJWTClaims{
-			Subject:   activeUser.UUID,
-			TokenType: string(auth.JWTTypeActivation),
-			IssuedAt:  utils.JSONTimeStamp(now),
-			ExpiresAt: utils.JSONTimeStamp(now.Add(time.Duration(config.ActivationLifetime * int64(time.Minute)))),
-			JWTID:     uuid.NewString(),
-		},
-	).SignedString([]byte(config.SecretKey))
-	require.NoError(t, err)
+	activeUserToken := testkitinternal.MustCreateUserActivationJWT(t, activeUser.UUID)
 
 	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = false
 	})
 
-	inactiveUserToken, err := jwt.NewWithClaims(
+	inactiveUserToken := testkitinternal.MustCreateUserActivationJWT(t, inactiveUser.UUID)
+
+	unissuedToken, err := jwt.NewWithClaims(
 		jwt.SigningMethodHS256,
 		&api.ActivationJWTClaims{
 			Subject:   inactiveUser.UUID,
 			TokenType: string(auth.JWTTypeActivation),
-			IssuedAt:  utils.JSONTimeStamp(now),
-			ExpiresAt: utils.JSONTimeStamp(now.Add(time.Duration(config.ActivationLifetime * int64(time.Minute)))),
+			IssuedAt:  utils.JSONTimeStamp(time.Now().UTC()),
+			ExpiresAt: utils.JSONTimeStamp(time.Now().UTC().Add(time.Duration(config.ActivationLifetime * int64(time.Minute)))),
 			JWTID:     uuid.NewString(),
 		},
 	).SignedString([]byte(config.SecretKey))
@@ -361,6 +449,17 @@ func TestHandleActivateUser(t *testing.T) {
 			wantErrCode:    api.ErrCodeResourceNotFound,
 			wantErrDetail:  api.ErrDetailUserNotFound,
 		},
+		{
+			name: "Token that was never issued",
+			requestBody: fmt.Sprintf(`
+				{
+					"token": "%s"
+				}
+			`, unissuedToken),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidToken,
+		},
 		{
 			name: "Invalid token",
 			requestBody: `
@@ -415,84 +514,66 @@ func TestHandleActivateUser(t *testing.T) {
 	}
 }
 
-func TestHandleGetUserMe(t *testing.T) {
+func TestHandleResendActivation(t *testing.T) {
 	t.Parallel()
 
 	httpClient := httputils.NewHTTPClient(nil)
 
-	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
-		u.IsActive = true
-	})
-	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(activeUser.UUID)
-	_, activeUserRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, nil)
-
 	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = false
 	})
-	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(inactiveUser.UUID)
-	_, inactiveUserRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
 
 	testcases := []struct {
 		name           string
-		path           string
-		headers        map[string]string
-		user           *auth.User
+		requestBody    string
 		wantStatusCode int
 		wantErrCode    string
 		wantErrDetail  string
 	}{
 		{
-			name: "Get active user using JWT",
-			path: "/auth/users/me",
-			headers: map[string]string{
-				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
-			},
-			user:           activeUser,
-			wantStatusCode: http.StatusOK,
+			name: "Inactive user",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s"
+				}
+			`, inactiveUser.Email),
+			wantStatusCode: http.StatusAccepted,
 			wantErrCode:    "",
 			wantErrDetail:  "",
 		},
 		{
-			name: "Get active user using API key",
-			path: "/api/auth/users/me",
-			headers: map[string]string{
-				"Authorization": fmt.Sprintf("X-API-Key %s", activeUserRawAPIKey),
-			},
-			user:           activeUser,
-			wantStatusCode: http.StatusOK,
+			name: "Already active user",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s"
+				}
+			`, activeUser.Email),
+			wantStatusCode: http.StatusAccepted,
 			wantErrCode:    "",
 			wantErrDetail:  "",
 		},
 		{
-			name: "Get inactive user using JWT",
-			path: "/auth/users/me",
-			headers: map[string]string{
-				"Authorization": fmt.Sprintf("Bearer %s", inactiveUserAccessJWT),
-			},
-			user:           inactiveUser,
-			wantStatusCode: http.StatusNotFound,
-			wantErrCode:    api.ErrCodeResourceNotFound,
-			wantErrDetail:  api.ErrDetailUserNotFound,
-		},
-		{
-			name: "Get inactive user using API key",
-			path: "/api/auth/users/me",
-			headers: map[string]string{
-				"Authorization": fmt.Sprintf("X-API-Key %s", inactiveUserRawAPIKey),
-			},
-			user:           inactiveUser,
-			wantStatusCode: http.StatusUnauthorized,
-			wantErrCode:    api.ErrCodeInvalidCredentials,
-			wantErrDetail:  api.ErrDetailInvalidToken,
+			name: "Unknown email",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s"
+				}
+			`, testkit.GenerateFakeEmail()),
+			wantStatusCode: http.StatusAccepted,
+			wantErrCode:    "",
+			wantErrDetail:  "",
 		},
 		{
-			name:           "Get user without authentication",
-			path:           "/auth/users/me",
-			headers:        map[string]string{},
-			user:           nil,
-			wantStatusCode: http.StatusUnauthorized,
-			wantErrCode:    api.ErrCodeMissingCredentials,
-			wantErrDetail:  api.ErrDetailMissingCredentials,
+			name: "Missing email",
+			requestBody: `
+				{}
+			`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
 		},
 	}
 
@@ -500,13 +581,13 @@ func TestHandleGetUserMe(t *testing.T) {
 		t.Run(testcase.name, func(t *testing.T) {
 			t.Parallel()
 
-			req, err := http.NewRequest(http.MethodGet, TestServerURL+testcase.path, http.NoBody)
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/users/activate/resend",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
 			require.NoError(t, err)
 
-			for key, value := range testcase.headers {
-				req.Header.Add(key, value)
-			}
-
 			res, err := httpClient.Do(req)
 			require.NoError(t, err)
 			t.Cleanup(func() {
@@ -516,17 +597,7 @@ func TestHandleGetUserMe(t *testing.T) {
 
 			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
 
-			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
-				var getUserMeResp api.GetUserMeResponse
-				err = json.NewDecoder(res.Body).Decode(&getUserMeResp)
-				require.NoError(t, err)
-
-				require.Equal(t, testcase.user.UUID, getUserMeResp.UUID)
-				require.Equal(t, testcase.user.Email, getUserMeResp.Email)
-				require.Equal(t, testcase.user.FirstName, getUserMeResp.FirstName)
-				require.Equal(t, testcase.user.LastName, getUserMeResp.LastName)
-				require.Equal(t, testcase.user.CreatedAt, getUserMeResp.CreatedAt)
-			} else {
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
 				var errResp api.ErrorResponse
 				err = json.NewDecoder(res.Body).Decode(&errResp)
 				require.NoError(t, err)
@@ -538,15 +609,12 @@ func TestHandleGetUserMe(t *testing.T) {
 	}
 }
 
-func TestHandleCreateJWT(t *testing.T) {
+func TestHandleRequestPasswordReset(t *testing.T) {
 	t.Parallel()
 
-	config, err := env.NewConfig()
-	require.NoError(t, err)
-
 	httpClient := httputils.NewHTTPClient(nil)
 
-	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
 
@@ -558,83 +626,43 @@ func TestHandleCreateJWT(t *testing.T) {
 		wantErrDetail  string
 	}{
 		{
-			name: "Valid request",
+			name: "Active user",
 			requestBody: fmt.Sprintf(`
 				{
-					"email": "%s",
-					"password": "%s"
+					"email": "%s"
 				}
-			`, user.Email, password),
-			wantStatusCode: http.StatusCreated,
+			`, activeUser.Email),
+			wantStatusCode: http.StatusAccepted,
 			wantErrCode:    "",
 			wantErrDetail:  "",
 		},
 		{
-			name: "Incorrect password",
+			name: "Unknown email",
 			requestBody: fmt.Sprintf(`
 				{
-					"email": "%s",
-					"password": "1nc0rr3CTP455w0Rd"
+					"email": "%s"
 				}
-			`, user.Email),
-			wantStatusCode: http.StatusUnauthorized,
-			wantErrCode:    api.ErrCodeInvalidCredentials,
-			wantErrDetail:  api.ErrDetailInvalidEmailOrPassword,
+			`, testkit.GenerateFakeEmail()),
+			wantStatusCode: http.StatusAccepted,
+			wantErrCode:    "",
+			wantErrDetail:  "",
 		},
 		{
 			name: "Invalid email",
-			requestBody: fmt.Sprintf(`
+			requestBody: `
 				{
-					"email": "1nv4l1d3M4iL",
-					"password": "%s"
+					"email": "1nv4l1d3m41l"
 				}
-			`, password),
+			`,
 			wantStatusCode: http.StatusBadRequest,
 			wantErrCode:    api.ErrCodeInvalidRequest,
 			wantErrDetail:  api.ErrDetailInvalidRequestData,
 		},
 		{
 			name: "Missing email",
-			requestBody: fmt.Sprintf(`
-				{
-					"password": "%s"
-				}
-			`, password),
-			wantStatusCode: http.StatusBadRequest,
-			wantErrCode:    api.ErrCodeInvalidRequest,
-			wantErrDetail:  api.ErrDetailInvalidRequestData,
-		},
-		{
-			name: "Empty email",
-			requestBody: fmt.Sprintf(`
-				{
-					"email": "",
-					"password": "%s"
-				}
-			`, password),
-			wantStatusCode: http.StatusBadRequest,
-			wantErrCode:    api.ErrCodeInvalidRequest,
-			wantErrDetail:  api.ErrDetailInvalidRequestData,
-		},
-		{
-			name: "Missing password",
-			requestBody: fmt.Sprintf(`
-				{
-					"email": "%s"
-				}
-			`, user.Email),
-			wantStatusCode: http.StatusBadRequest,
-			wantErrCode:    api.ErrCodeInvalidRequest,
-			wantErrDetail:  api.ErrDetailInvalidRequestData,
-		},
-		{
-			name: "Empty password",
-			requestBody: fmt.Sprintf(`
-				{
-					"email": "%s",
-					"password": ""
-				}
-			`, user.Email),
+			requestBody: `
+				{}
+			`,
 			wantStatusCode: http.StatusBadRequest,
 			wantErrCode:    api.ErrCodeInvalidRequest,
 			wantErrDetail:  api.ErrDetailInvalidRequestData,
@@ -647,7 +675,7 @@ func TestHandleCreateJWT(t *testing.T) {
 
 			req, err := http.NewRequest(
 				http.MethodPost,
-				TestServerURL+"/auth/tokens",
+				TestServerURL+"/auth/password-reset",
 				bytes.NewReader([]byte(testcase.requestBody)),
 			)
 			require.NoError(t, err)
@@ -661,41 +689,9 @@ func TestHandleCreateJWT(t *testing.T) {
 
 			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
 
-			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
-				var createTokenResp api.CreateTokenResponse
-				err = json.NewDecoder(res.Body).Decode(&createTokenResp)
-				require.NoError(t, err)
-
-				accessClaims := &api.AuthJWTClaims{}
-				parsedAccessToken, err := jwt.ParseWithClaims(createTokenResp.Access, accessClaims, func(t *jwt.Token) (any, error) {
-					return []byte(config.SecretKey), nil
-				})
-				require.NoError(t, err)
-
-				require.NotNil(t, parsedAccessToken)
-				require.True(t, parsedAccessToken.Valid)
-				require.Equal(t, user.UUID, accessClaims.Subject)
-				require.Equal(t, string(auth.JWTTypeAccess), accessClaims.TokenType)
-
-				testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(accessClaims.IssuedAt))
-				testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthAccessLifetime*int64(time.Minute))), time.Time(accessClaims.ExpiresAt))
-
-				refreshClaims := &api.AuthJWTClaims{}
-				parsedRefreshToken, err := jwt.ParseWithClaims(createTokenResp.Refresh, refreshClaims, func(t *jwt.Token) (any, error) {
-					return []byte(config.SecretKey), nil
-				})
-				require.NoError(t, err)
-
-				require.NotNil(t, parsedRefreshToken)
-				require.True(t, parsedRefreshToken.Valid)
-				require.Equal(t, user.UUID, refreshClaims.Subject)
-				require.Equal(t, string(auth.JWTTypeRefresh), refreshClaims.TokenType)
-
-				testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(refreshClaims.IssuedAt))
-				testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthRefreshLifetime*int64(time.Minute))), time.Time(refreshClaims.ExpiresAt))
-			} else {
-				var errResp api.ErrorResponse
-				err = json.NewDecoder(res.Body).Decode(&errResp)
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
 				require.NoError(t, err)
 
 				require.Equal(t, testcase.wantErrCode, errResp.Code)
@@ -705,18 +701,23 @@ func TestHandleCreateJWT(t *testing.T) {
 	}
 }
 
-func TestHandleRefreshJWT(t *testing.T) {
+func TestHandleConfirmPasswordReset(t *testing.T) {
 	t.Parallel()
 
-	config, err := env.NewConfig()
-	require.NoError(t, err)
-
 	httpClient := httputils.NewHTTPClient(nil)
 
 	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
-	_, validRefreshToken := testkitinternal.MustCreateUserAuthJWTs(user.UUID)
+	validToken := testkitinternal.MustCreateUserPasswordResetJWT(t, user)
+
+	staleUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	staleToken := testkitinternal.MustCreateUserPasswordResetJWT(t, &auth.User{
+		UUID:     staleUser.UUID,
+		Password: testkitinternal.MustHashPassword(testkit.GenerateFakePassword()),
+	})
 
 	testcases := []struct {
 		name           string
@@ -726,32 +727,59 @@ func TestHandleRefreshJWT(t *testing.T) {
 		wantErrDetail  string
 	}{
 		{
-			name: "Valid request",
+			name: "Valid token",
 			requestBody: fmt.Sprintf(`
 				{
-					"refresh": "%s"
+					"token": "%s",
+					"password": "%s"
 				}
-			`, validRefreshToken),
-			wantStatusCode: http.StatusCreated,
+			`, validToken, testkit.GenerateFakePassword()),
+			wantStatusCode: http.StatusOK,
 			wantErrCode:    "",
 			wantErrDetail:  "",
 		},
+		{
+			name: "Token issued for previous password",
+			requestBody: fmt.Sprintf(`
+				{
+					"token": "%s",
+					"password": "%s"
+				}
+			`, staleToken, testkit.GenerateFakePassword()),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidToken,
+		},
 		{
 			name: "Invalid token",
-			requestBody: `
+			requestBody: fmt.Sprintf(`
 				{
-					"refresh": "iNv4liDT0k3N"
+					"token": "1nV4LiDT0k3n",
+					"password": "%s"
 				}
-			`,
+			`, testkit.GenerateFakePassword()),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidToken,
+		},
+		{
+			name: "Missing password",
+			requestBody: fmt.Sprintf(`
+				{
+					"token": "%s"
+				}
+			`, validToken),
 			wantStatusCode: http.StatusBadRequest,
 			wantErrCode:    api.ErrCodeInvalidRequest,
 			wantErrDetail:  api.ErrDetailInvalidRequestData,
 		},
 		{
 			name: "Missing token",
-			requestBody: `
-				{}
-			`,
+			requestBody: fmt.Sprintf(`
+				{
+					"password": "%s"
+				}
+			`, testkit.GenerateFakePassword()),
 			wantStatusCode: http.StatusBadRequest,
 			wantErrCode:    api.ErrCodeInvalidRequest,
 			wantErrDetail:  api.ErrDetailInvalidRequestData,
@@ -764,7 +792,7 @@ func TestHandleRefreshJWT(t *testing.T) {
 
 			req, err := http.NewRequest(
 				http.MethodPost,
-				TestServerURL+"/auth/tokens/refresh",
+				TestServerURL+"/auth/password-reset/confirm",
 				bytes.NewReader([]byte(testcase.requestBody)),
 			)
 			require.NoError(t, err)
@@ -778,24 +806,143 @@ func TestHandleRefreshJWT(t *testing.T) {
 
 			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
 
-			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
-				var refreshTokenResp api.RefreshTokenResponse
-				err = json.NewDecoder(res.Body).Decode(&refreshTokenResp)
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
 				require.NoError(t, err)
 
-				claims := &api.AuthJWTClaims{}
-				parsedToken, err := jwt.ParseWithClaims(refreshTokenResp.Access, claims, func(t *jwt.Token) (any, error) {
-					return []byte(config.SecretKey), nil
-				})
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleGetUserMe(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, activeUser.UUID)
+	_, activeUserRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, nil)
+	_, activeUserEvaluateOnlyRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"flags:evaluate"}
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, inactiveUser.UUID)
+	_, inactiveUserRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+
+	testcases := []struct {
+		name           string
+		path           string
+		headers        map[string]string
+		user           *auth.User
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Get active user using JWT",
+			path: "/auth/users/me",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			user:           activeUser,
+			wantStatusCode: http.StatusOK,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Get active user using API key",
+			path: "/api/auth/users/me",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("X-API-Key %s", activeUserRawAPIKey),
+			},
+			user:           activeUser,
+			wantStatusCode: http.StatusOK,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Get active user using API key without user scope",
+			path: "/api/auth/users/me",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("X-API-Key %s", activeUserEvaluateOnlyRawAPIKey),
+			},
+			user:           activeUser,
+			wantStatusCode: http.StatusForbidden,
+			wantErrCode:    api.ErrCodePermissionDenied,
+			wantErrDetail:  api.ErrDetailAPIKeyScopeDenied,
+		},
+		{
+			name: "Get inactive user using JWT",
+			path: "/auth/users/me",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", inactiveUserAccessJWT),
+			},
+			user:           inactiveUser,
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailUserNotFound,
+		},
+		{
+			name: "Get inactive user using API key",
+			path: "/api/auth/users/me",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("X-API-Key %s", inactiveUserRawAPIKey),
+			},
+			user:           inactiveUser,
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeInvalidCredentials,
+			wantErrDetail:  api.ErrDetailInvalidToken,
+		},
+		{
+			name:           "Get user without authentication",
+			path:           "/auth/users/me",
+			headers:        map[string]string{},
+			user:           nil,
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, TestServerURL+testcase.path, http.NoBody)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
 				require.NoError(t, err)
+			})
 
-				require.NotNil(t, parsedToken)
-				require.True(t, parsedToken.Valid)
-				require.Equal(t, user.UUID, claims.Subject)
-				require.Equal(t, string(auth.JWTTypeAccess), claims.TokenType)
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
 
-				testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
-				testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthAccessLifetime*int64(time.Minute))), time.Time(claims.ExpiresAt))
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var getUserMeResp api.GetUserMeResponse
+				err = json.NewDecoder(res.Body).Decode(&getUserMeResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.user.UUID, getUserMeResp.UUID)
+				require.Equal(t, testcase.user.Email, getUserMeResp.Email)
+				require.Equal(t, testcase.user.FirstName, getUserMeResp.FirstName)
+				require.Equal(t, testcase.user.LastName, getUserMeResp.LastName)
+				require.Equal(t, testcase.user.CreatedAt, getUserMeResp.CreatedAt)
 			} else {
 				var errResp api.ErrorResponse
 				err = json.NewDecoder(res.Body).Decode(&errResp)
@@ -808,120 +955,3995 @@ func TestHandleRefreshJWT(t *testing.T) {
 	}
 }
 
-func TestHandleCreateAPIKey(t *testing.T) {
+func TestHandleChangePassword(t *testing.T) {
 	t.Parallel()
 
 	httpClient := httputils.NewHTTPClient(nil)
 
-	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
-	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(user.UUID)
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
 
-	expirationDate := time.Date(2038, 1, 19, 3, 14, 8, 0, time.UTC)
-	expirationDateString := "2038-01-19T03:14:08Z"
+	otherUser, otherUserPassword := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, otherUser.UUID)
 
 	testcases := []struct {
-		name               string
-		headers            map[string]string
-		requestBody        string
-		wantStatusCode     int
-		wantAPIKeyName     string
-		wantExpirationDate pgtype.Timestamp
-		wantErrCode        string
-		wantErrDetail      string
+		name           string
+		headers        map[string]string
+		requestBody    string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
 	}{
 		{
-			name: "Valid request with no expiration date",
+			name: "Valid request",
 			headers: map[string]string{
 				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
 			},
-			requestBody: `
+			requestBody: fmt.Sprintf(`
 				{
-					"name": "My non-expiring API key"
+					"current_password": "%s",
+					"new_password": "%s"
 				}
-			`,
-			wantStatusCode: http.StatusCreated,
-			wantAPIKeyName: "My non-expiring API key",
-			wantExpirationDate: pgtype.Timestamp{
-				Valid: false,
+			`, password, testkit.GenerateFakePassword()),
+			wantStatusCode: http.StatusOK,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Incorrect current password",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", otherUserAccessJWT),
 			},
-			wantErrCode:   "",
-			wantErrDetail: "",
+			requestBody: fmt.Sprintf(`
+				{
+					"current_password": "1nc0rr3ctp455w0rd",
+					"new_password": "%s"
+				}
+			`, testkit.GenerateFakePassword()),
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeInvalidCredentials,
+			wantErrDetail:  api.ErrDetailInvalidPassword,
 		},
 		{
-			name: "Valid request with expiration date",
+			name: "Weak new password",
 			headers: map[string]string{
-				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+				"Authorization": fmt.Sprintf("Bearer %s", otherUserAccessJWT),
 			},
 			requestBody: fmt.Sprintf(`
 				{
-					"name": "My expiring API key",
-					"expires_at": "%s"
+					"current_password": "%s",
+					"new_password": "qwertyuiop123"
 				}
-			`, expirationDateString),
-			wantStatusCode: http.StatusCreated,
-			wantAPIKeyName: "My expiring API key",
-			wantExpirationDate: pgtype.Timestamp{
-				Time:  expirationDate,
-				Valid: true,
+			`, otherUserPassword),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Missing new password",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", otherUserAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"current_password": "%s"
+				}
+			`, otherUserPassword),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name:    "No authentication",
+			headers: map[string]string{},
+			requestBody: fmt.Sprintf(`
+				{
+					"current_password": "%s",
+					"new_password": "%s"
+				}
+			`, otherUserPassword, testkit.GenerateFakePassword()),
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/users/me/password",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleChangeEmail(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	testcases := []struct {
+		name           string
+		headers        map[string]string
+		requestBody    string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Valid request",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s",
+					"password": "%s"
+				}
+			`, testkit.GenerateFakeEmail(), password),
+			wantStatusCode: http.StatusAccepted,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Email taken by another user",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s",
+					"password": "%s"
+				}
+			`, otherUser.Email, password),
+			wantStatusCode: http.StatusConflict,
+			wantErrCode:    api.ErrCodeResourceExists,
+			wantErrDetail:  api.ErrDetailUserExists,
+		},
+		{
+			name: "Incorrect password",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s",
+					"password": "1nc0rr3ctp455w0rd"
+				}
+			`, testkit.GenerateFakeEmail()),
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeInvalidCredentials,
+			wantErrDetail:  api.ErrDetailInvalidPassword,
+		},
+		{
+			name: "Invalid email",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "1nv4l1d3m41l",
+					"password": "%s"
+				}
+			`, password),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name:    "No authentication",
+			headers: map[string]string{},
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s",
+					"password": "%s"
+				}
+			`, testkit.GenerateFakeEmail(), password),
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/users/me/email",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleConfirmEmailChange(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	takenEmailUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	validToken := testkitinternal.MustCreateUserEmailChangeJWT(t, user, testkit.GenerateFakeEmail())
+	takenEmailToken := testkitinternal.MustCreateUserEmailChangeJWT(t, otherUser, takenEmailUser.Email)
+
+	testcases := []struct {
+		name           string
+		requestBody    string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Valid token",
+			requestBody: fmt.Sprintf(`
+				{
+					"token": "%s"
+				}
+			`, validToken),
+			wantStatusCode: http.StatusOK,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "New email taken by another user",
+			requestBody: fmt.Sprintf(`
+				{
+					"token": "%s"
+				}
+			`, takenEmailToken),
+			wantStatusCode: http.StatusConflict,
+			wantErrCode:    api.ErrCodeResourceExists,
+			wantErrDetail:  api.ErrDetailUserExists,
+		},
+		{
+			name: "Invalid token",
+			requestBody: `
+				{
+					"token": "1nV4LiDT0k3n"
+				}
+			`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidToken,
+		},
+		{
+			name: "Missing token",
+			requestBody: `
+				{}
+			`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/users/email/confirm",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleCreateJWT(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	keySet := testkitinternal.MustCreateKeySet(config)
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	testcases := []struct {
+		name           string
+		requestBody    string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Valid request",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s",
+					"password": "%s"
+				}
+			`, user.Email, password),
+			wantStatusCode: http.StatusCreated,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Incorrect password",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s",
+					"password": "1nc0rr3CTP455w0Rd"
+				}
+			`, user.Email),
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeInvalidCredentials,
+			wantErrDetail:  api.ErrDetailInvalidEmailOrPassword,
+		},
+		{
+			name: "Invalid email",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "1nv4l1d3M4iL",
+					"password": "%s"
+				}
+			`, password),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Missing email",
+			requestBody: fmt.Sprintf(`
+				{
+					"password": "%s"
+				}
+			`, password),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Empty email",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "",
+					"password": "%s"
+				}
+			`, password),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Missing password",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s"
+				}
+			`, user.Email),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Empty password",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s",
+					"password": ""
+				}
+			`, user.Email),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/tokens",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var createTokenResp api.CreateTokenResponse
+				err = json.NewDecoder(res.Body).Decode(&createTokenResp)
+				require.NoError(t, err)
+
+				accessClaims := &api.AuthJWTClaims{}
+				parsedAccessToken, err := keySet.Parse(createTokenResp.Access, accessClaims)
+				require.NoError(t, err)
+
+				require.NotNil(t, parsedAccessToken)
+				require.True(t, parsedAccessToken.Valid)
+				require.Equal(t, user.UUID, accessClaims.Subject)
+				require.Equal(t, string(auth.JWTTypeAccess), accessClaims.TokenType)
+
+				testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(accessClaims.IssuedAt))
+				testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthAccessLifetime*int64(time.Minute))), time.Time(accessClaims.ExpiresAt))
+
+				refreshClaims := &api.AuthJWTClaims{}
+				parsedRefreshToken, err := keySet.Parse(createTokenResp.Refresh, refreshClaims)
+				require.NoError(t, err)
+
+				require.NotNil(t, parsedRefreshToken)
+				require.True(t, parsedRefreshToken.Valid)
+				require.Equal(t, user.UUID, refreshClaims.Subject)
+				require.Equal(t, string(auth.JWTTypeRefresh), refreshClaims.TokenType)
+
+				testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(refreshClaims.IssuedAt))
+				testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthRefreshLifetime*int64(time.Minute))), time.Time(refreshClaims.ExpiresAt))
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleCreateJWTAccountLockout(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	createJWT := func(password string) *http.Response {
+		req, err := http.NewRequest(
+			http.MethodPost,
+			TestServerURL+"/auth/tokens",
+			bytes.NewReader([]byte(fmt.Sprintf(`
+				{
+					"email": "%s",
+					"password": "%s"
+				}
+			`, user.Email, password))),
+		)
+		require.NoError(t, err)
+
+		res, err := httpClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			err := res.Body.Close()
+			require.NoError(t, err)
+		})
+
+		return res
+	}
+
+	for i := 0; i < 5; i++ {
+		res := createJWT("1nc0rr3CTP455w0Rd")
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	}
+
+	res := createJWT(password)
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	retryAfter, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	require.Greater(t, retryAfter, 0)
+	require.LessOrEqual(t, retryAfter, 60)
+
+	var errResp api.ErrorResponse
+	err = json.NewDecoder(res.Body).Decode(&errResp)
+	require.NoError(t, err)
+
+	require.Equal(t, api.ErrCodeTooManyRequests, errResp.Code)
+	require.Equal(t, api.ErrDetailTooManyLoginAttempts, errResp.Detail)
+}
+
+func TestHandleRefreshJWT(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	keySet := testkitinternal.MustCreateKeySet(config)
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, validRefreshToken := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	testcases := []struct {
+		name           string
+		requestBody    string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Valid request",
+			requestBody: fmt.Sprintf(`
+				{
+					"refresh": "%s"
+				}
+			`, validRefreshToken),
+			wantStatusCode: http.StatusCreated,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Invalid token",
+			requestBody: `
+				{
+					"refresh": "iNv4liDT0k3N"
+				}
+			`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Missing token",
+			requestBody: `
+				{}
+			`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/tokens/refresh",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var refreshTokenResp api.RefreshTokenResponse
+				err = json.NewDecoder(res.Body).Decode(&refreshTokenResp)
+				require.NoError(t, err)
+
+				claims := &api.AuthJWTClaims{}
+				parsedToken, err := keySet.Parse(refreshTokenResp.Access, claims)
+				require.NoError(t, err)
+
+				require.NotNil(t, parsedToken)
+				require.True(t, parsedToken.Valid)
+				require.Equal(t, user.UUID, claims.Subject)
+				require.Equal(t, string(auth.JWTTypeAccess), claims.TokenType)
+
+				testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
+				testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthAccessLifetime*int64(time.Minute))), time.Time(claims.ExpiresAt))
+
+				refreshClaims := &api.AuthJWTClaims{}
+				parsedRefreshToken, err := keySet.Parse(refreshTokenResp.Refresh, refreshClaims)
+				require.NoError(t, err)
+
+				require.NotNil(t, parsedRefreshToken)
+				require.True(t, parsedRefreshToken.Valid)
+				require.Equal(t, user.UUID, refreshClaims.Subject)
+				require.Equal(t, string(auth.JWTTypeRefresh), refreshClaims.TokenType)
+				require.Equal(t, claims.SessionID, refreshClaims.SessionID)
+
+				testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(refreshClaims.IssuedAt))
+				testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthRefreshLifetime*int64(time.Minute))), time.Time(refreshClaims.ExpiresAt))
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleLogout(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	accessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	otherSessionAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	testcases := []struct {
+		name                  string
+		headers               map[string]string
+		wantStatusCode        int
+		wantErrCode           string
+		wantErrDetail         string
+		wantRevokedAccessJWT  string
+		wantRetainedAccessJWT string
+	}{
+		{
+			name: "Logout current session",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessJWT),
+			},
+			wantStatusCode:        http.StatusNoContent,
+			wantErrCode:           "",
+			wantErrDetail:         "",
+			wantRevokedAccessJWT:  accessJWT,
+			wantRetainedAccessJWT: otherSessionAccessJWT,
+		},
+		{
+			name:           "Logout without authentication",
+			headers:        map[string]string{},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodPost, TestServerURL+"/auth/logout", http.NoBody)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+
+				return
+			}
+
+			requireAccessJWTStatus(t, httpClient, testcase.wantRevokedAccessJWT, http.StatusUnauthorized)
+			requireAccessJWTStatus(t, httpClient, testcase.wantRetainedAccessJWT, http.StatusOK)
+		})
+	}
+}
+
+// requireCookieTransportRequest sends a request to a server using cookie token transport,
+// with given access token, cookies and CSRF header, and returns the response along with the cookies it sets.
+func requireCookieTransportRequest(
+	t *testing.T,
+	httpClient *http.Client,
+	serverURL string,
+	method string,
+	path string,
+	body string,
+	accessToken string,
+	cookies map[string]string,
+	csrfToken string,
+) (*http.Response, map[string]*http.Cookie) {
+	req, err := http.NewRequest(method, serverURL+path, bytes.NewReader([]byte(body)))
+	require.NoError(t, err)
+
+	if accessToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	}
+
+	for name, value := range cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	if csrfToken != "" {
+		req.Header.Set(auth.CSRFTokenHeader, csrfToken)
+	}
+
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := res.Body.Close()
+		require.NoError(t, err)
+	})
+
+	setCookies := make(map[string]*http.Cookie)
+	for _, cookie := range res.Cookies() {
+		setCookies[cookie.Name] = cookie
+	}
+
+	return res, setCookies
+}
+
+func TestCookieTokenTransport(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	res, setCookies := requireCookieTransportRequest(
+		t,
+		httpClient,
+		TestCookieServerURL,
+		http.MethodPost,
+		"/auth/tokens",
+		fmt.Sprintf(`{"email": "%s", "password": "%s"}`, user.Email, password),
+		"",
+		nil,
+		"",
+	)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	var tokenResp map[string]any
+	err := json.NewDecoder(res.Body).Decode(&tokenResp)
+	require.NoError(t, err)
+	require.Len(t, tokenResp, 1)
+
+	accessToken, ok := tokenResp["access"].(string)
+	require.True(t, ok)
+	require.NotEmpty(t, accessToken)
+
+	require.Len(t, setCookies, 2)
+	refreshCookie, ok := setCookies[auth.RefreshTokenCookieName]
+	require.True(t, ok)
+	require.NotEmpty(t, refreshCookie.Value)
+	require.True(t, refreshCookie.HttpOnly)
+	require.True(t, refreshCookie.Secure)
+	require.Equal(t, http.SameSiteStrictMode, refreshCookie.SameSite)
+
+	csrfCookie, ok := setCookies[auth.CSRFTokenCookieName]
+	require.True(t, ok)
+	require.NotEmpty(t, csrfCookie.Value)
+	require.False(t, csrfCookie.HttpOnly)
+
+	cookies := map[string]string{
+		auth.AccessTokenCookieName:  accessToken,
+		auth.RefreshTokenCookieName: refreshCookie.Value,
+		auth.CSRFTokenCookieName:    csrfCookie.Value,
+	}
+
+	res, _ = requireCookieTransportRequest(t, httpClient, TestCookieServerURL, http.MethodGet, "/auth/users/me", "", "", cookies, "")
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res, _ = requireCookieTransportRequest(t, httpClient, TestCookieServerURL, http.MethodGet, "/auth/users/me", "", accessToken, cookies, "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var userResp api.GetUserMeResponse
+	err = json.NewDecoder(res.Body).Decode(&userResp)
+	require.NoError(t, err)
+	require.Equal(t, user.UUID, userResp.UUID)
+
+	res, _ = requireCookieTransportRequest(t, httpClient, TestCookieServerURL, http.MethodPost, "/auth/tokens/refresh", "", "", cookies, "")
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	res, setCookies = requireCookieTransportRequest(t, httpClient, TestCookieServerURL, http.MethodPost, "/auth/tokens/refresh", "", "", cookies, csrfCookie.Value)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Len(t, setCookies, 2)
+	require.NotEqual(t, cookies[auth.RefreshTokenCookieName], setCookies[auth.RefreshTokenCookieName].Value)
+	require.NotEqual(t, cookies[auth.CSRFTokenCookieName], setCookies[auth.CSRFTokenCookieName].Value)
+
+	var refreshResp api.CookieTokenResponse
+	err = json.NewDecoder(res.Body).Decode(&refreshResp)
+	require.NoError(t, err)
+	require.NotEmpty(t, refreshResp.Access)
+
+	res, setCookies = requireCookieTransportRequest(t, httpClient, TestCookieServerURL, http.MethodPost, "/auth/logout", "", refreshResp.Access, nil, "")
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	require.Len(t, setCookies, 3)
+	for _, cookie := range setCookies {
+		require.Empty(t, cookie.Value)
+		require.Less(t, cookie.MaxAge, 0)
+	}
+
+	res, _ = requireCookieTransportRequest(t, httpClient, TestCookieServerURL, http.MethodGet, "/auth/users/me", "", refreshResp.Access, nil, "")
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestCookieTokenTransportAccessTokenCookie(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	res, setCookies := requireCookieTransportRequest(
+		t,
+		httpClient,
+		TestAccessTokenCookieServerURL,
+		http.MethodPost,
+		"/auth/tokens",
+		fmt.Sprintf(`{"email": "%s", "password": "%s"}`, user.Email, password),
+		"",
+		nil,
+		"",
+	)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	responseBodyBytes, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Empty(t, responseBodyBytes)
+
+	require.Len(t, setCookies, 3)
+	for _, name := range []string{auth.AccessTokenCookieName, auth.RefreshTokenCookieName} {
+		cookie, ok := setCookies[name]
+		require.True(t, ok)
+		require.NotEmpty(t, cookie.Value)
+		require.True(t, cookie.HttpOnly)
+		require.True(t, cookie.Secure)
+		require.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	}
+
+	csrfCookie, ok := setCookies[auth.CSRFTokenCookieName]
+	require.True(t, ok)
+	require.NotEmpty(t, csrfCookie.Value)
+	require.False(t, csrfCookie.HttpOnly)
+
+	cookies := map[string]string{
+		auth.AccessTokenCookieName:  setCookies[auth.AccessTokenCookieName].Value,
+		auth.RefreshTokenCookieName: setCookies[auth.RefreshTokenCookieName].Value,
+		auth.CSRFTokenCookieName:    csrfCookie.Value,
+	}
+
+	res, _ = requireCookieTransportRequest(t, httpClient, TestAccessTokenCookieServerURL, http.MethodGet, "/auth/users/me", "", "", cookies, "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var userResp api.GetUserMeResponse
+	err = json.NewDecoder(res.Body).Decode(&userResp)
+	require.NoError(t, err)
+	require.Equal(t, user.UUID, userResp.UUID)
+
+	res, _ = requireCookieTransportRequest(t, httpClient, TestAccessTokenCookieServerURL, http.MethodPost, "/auth/tokens/refresh", "", "", cookies, "")
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	res, setCookies = requireCookieTransportRequest(t, httpClient, TestAccessTokenCookieServerURL, http.MethodPost, "/auth/tokens/refresh", "", "", cookies, csrfCookie.Value)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Len(t, setCookies, 3)
+	require.NotEqual(t, cookies[auth.RefreshTokenCookieName], setCookies[auth.RefreshTokenCookieName].Value)
+	require.NotEqual(t, cookies[auth.CSRFTokenCookieName], setCookies[auth.CSRFTokenCookieName].Value)
+
+	cookies = map[string]string{
+		auth.AccessTokenCookieName:  setCookies[auth.AccessTokenCookieName].Value,
+		auth.RefreshTokenCookieName: setCookies[auth.RefreshTokenCookieName].Value,
+		auth.CSRFTokenCookieName:    setCookies[auth.CSRFTokenCookieName].Value,
+	}
+
+	res, _ = requireCookieTransportRequest(t, httpClient, TestAccessTokenCookieServerURL, http.MethodPost, "/auth/logout", "", "", cookies, "")
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	var errResp api.ErrorResponse
+	err = json.NewDecoder(res.Body).Decode(&errResp)
+	require.NoError(t, err)
+	require.Equal(t, api.ErrCodePermissionDenied, errResp.Code)
+	require.Equal(t, api.ErrDetailInvalidCSRFToken, errResp.Detail)
+
+	res, setCookies = requireCookieTransportRequest(t, httpClient, TestAccessTokenCookieServerURL, http.MethodPost, "/auth/logout", "", "", cookies, cookies[auth.CSRFTokenCookieName])
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	require.Len(t, setCookies, 3)
+	for _, cookie := range setCookies {
+		require.Empty(t, cookie.Value)
+		require.Less(t, cookie.MaxAge, 0)
+	}
+
+	res, _ = requireCookieTransportRequest(t, httpClient, TestAccessTokenCookieServerURL, http.MethodGet, "/auth/users/me", "", "", cookies, "")
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestCookieTokenTransportRefreshError(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, refreshToken := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	testcases := []struct {
+		name           string
+		cookies        map[string]string
+		csrfToken      string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+		wantCleared    bool
+	}{
+		{
+			name:           "No refresh token cookie",
+			cookies:        map[string]string{auth.CSRFTokenCookieName: "c5rft0k3n"},
+			csrfToken:      "c5rft0k3n",
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+			wantCleared:    false,
+		},
+		{
+			name: "Mismatched CSRF token",
+			cookies: map[string]string{
+				auth.RefreshTokenCookieName: refreshToken,
+				auth.CSRFTokenCookieName:    "c5rft0k3n",
+			},
+			csrfToken:      "0th3rc5rft0k3n",
+			wantStatusCode: http.StatusForbidden,
+			wantErrCode:    api.ErrCodePermissionDenied,
+			wantErrDetail:  api.ErrDetailInvalidCSRFToken,
+			wantCleared:    false,
+		},
+		{
+			name: "Invalid refresh token",
+			cookies: map[string]string{
+				auth.RefreshTokenCookieName: "ed0730889507fdb8549acfcd31548ee5",
+				auth.CSRFTokenCookieName:    "c5rft0k3n",
+			},
+			csrfToken:      "c5rft0k3n",
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+			wantCleared:    true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, setCookies := requireCookieTransportRequest(
+				t,
+				httpClient,
+				TestCookieServerURL,
+				http.MethodPost,
+				"/auth/tokens/refresh",
+				"",
+				"",
+				testcase.cookies,
+				testcase.csrfToken,
+			)
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			var errResp api.ErrorResponse
+			err := json.NewDecoder(res.Body).Decode(&errResp)
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantErrCode, errResp.Code)
+			require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+
+			if testcase.wantCleared {
+				require.Len(t, setCookies, 3)
+			} else {
+				require.Empty(t, setCookies)
+			}
+		})
+	}
+}
+
+func TestHandleLogoutAll(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	accessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	otherSessionAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, otherUser.UUID)
+
+	testcases := []struct {
+		name                   string
+		headers                map[string]string
+		wantStatusCode         int
+		wantErrCode            string
+		wantErrDetail          string
+		wantRevokedAccessJWTs  []string
+		wantRetainedAccessJWTs []string
+	}{
+		{
+			name: "Logout all sessions",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessJWT),
+			},
+			wantStatusCode:         http.StatusNoContent,
+			wantErrCode:            "",
+			wantErrDetail:          "",
+			wantRevokedAccessJWTs:  []string{accessJWT, otherSessionAccessJWT},
+			wantRetainedAccessJWTs: []string{otherUserAccessJWT},
+		},
+		{
+			name:           "Logout all without authentication",
+			headers:        map[string]string{},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodPost, TestServerURL+"/auth/logout-all", http.NoBody)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+
+				return
+			}
+
+			for _, token := range testcase.wantRevokedAccessJWTs {
+				requireAccessJWTStatus(t, httpClient, token, http.StatusUnauthorized)
+			}
+
+			for _, token := range testcase.wantRetainedAccessJWTs {
+				requireAccessJWTStatus(t, httpClient, token, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestHandleListSessions(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	createTokenReq, err := http.NewRequest(
+		http.MethodPost,
+		TestServerURL+"/auth/tokens",
+		bytes.NewReader([]byte(fmt.Sprintf(`{"email": "%s", "password": "%s"}`, user.Email, password))),
+	)
+	require.NoError(t, err)
+	createTokenReq.Header.Set("User-Agent", "flagger-test-client/1.0")
+
+	createTokenRes, err := httpClient.Do(createTokenReq)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := createTokenRes.Body.Close()
+		require.NoError(t, err)
+	})
+	require.Equal(t, http.StatusCreated, createTokenRes.StatusCode)
+
+	var createTokenResp api.CreateTokenResponse
+	err = json.NewDecoder(createTokenRes.Body).Decode(&createTokenResp)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name              string
+		headers           map[string]string
+		wantStatusCode    int
+		wantErrCode       string
+		wantErrDetail     string
+		wantSessionsCount int
+	}{
+		{
+			name: "List sessions",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", createTokenResp.Access),
+			},
+			wantStatusCode:    http.StatusOK,
+			wantErrCode:       "",
+			wantErrDetail:     "",
+			wantSessionsCount: 2,
+		},
+		{
+			name:           "List sessions without authentication",
+			headers:        map[string]string{},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, TestServerURL+"/auth/sessions", http.NoBody)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var listSessionsResp api.ListSessionsResponse
+				err = json.NewDecoder(res.Body).Decode(&listSessionsResp)
+				require.NoError(t, err)
+
+				require.Len(t, listSessionsResp.Sessions, testcase.wantSessionsCount)
+
+				currentSessionsCount := 0
+				for _, session := range listSessionsResp.Sessions {
+					require.NotEmpty(t, session.ID)
+					testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), session.CreatedAt)
+					testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), session.LastUsedAt)
+
+					if session.Current {
+						currentSessionsCount++
+						require.NotEmpty(t, session.IPAddress)
+						require.Equal(t, "flagger-test-client/1.0", session.UserAgent)
+					}
+				}
+
+				require.Equal(t, 1, currentSessionsCount)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleDeleteSession(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	keySet := testkitinternal.MustCreateKeySet(config)
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	accessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	otherSessionAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, otherUser.UUID)
+
+	otherSessionClaims := &api.AuthJWTClaims{}
+	_, err = keySet.Parse(otherSessionAccessJWT, otherSessionClaims)
+	require.NoError(t, err)
+
+	otherUserClaims := &api.AuthJWTClaims{}
+	_, err = keySet.Parse(otherUserAccessJWT, otherUserClaims)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name                 string
+		path                 string
+		headers              map[string]string
+		wantStatusCode       int
+		wantErrCode          string
+		wantErrDetail        string
+		wantRevokedAccessJWT string
+	}{
+		{
+			name: "Delete other session",
+			path: "/auth/sessions/" + otherSessionClaims.SessionID,
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessJWT),
+			},
+			wantStatusCode:       http.StatusNoContent,
+			wantErrCode:          "",
+			wantErrDetail:        "",
+			wantRevokedAccessJWT: otherSessionAccessJWT,
+		},
+		{
+			name: "Delete session of another user",
+			path: "/auth/sessions/" + otherUserClaims.SessionID,
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessJWT),
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailSessionNotFound,
+		},
+		{
+			name: "Delete non-existent session",
+			path: "/auth/sessions/" + uuid.NewString(),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessJWT),
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailSessionNotFound,
+		},
+		{
+			name: "Delete session with malformed ID",
+			path: "/auth/sessions/n0t-4-uu1d",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessJWT),
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailSessionNotFound,
+		},
+		{
+			name:           "Delete session without authentication",
+			path:           "/auth/sessions/" + otherSessionClaims.SessionID,
+			headers:        map[string]string{},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodDelete, TestServerURL+testcase.path, http.NoBody)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+
+				return
+			}
+
+			requireAccessJWTStatus(t, httpClient, testcase.wantRevokedAccessJWT, http.StatusUnauthorized)
+		})
+	}
+}
+
+// requireAccessJWTStatus asserts that fetching the current User with the given access JWT returns the given status.
+func requireCreateTOTPChallenge(t *testing.T, httpClient *http.Client, email string, password string) string {
+	req, err := http.NewRequest(
+		http.MethodPost,
+		TestServerURL+"/auth/tokens",
+		bytes.NewReader([]byte(fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password))),
+	)
+	require.NoError(t, err)
+
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := res.Body.Close()
+		require.NoError(t, err)
+	})
+
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	var challengeResp api.TOTPChallengeResponse
+	err = json.NewDecoder(res.Body).Decode(&challengeResp)
+	require.NoError(t, err)
+
+	return challengeResp.Challenge
+}
+
+func TestHandleCreateJWTTOTPChallenge(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	challengeToken := requireCreateTOTPChallenge(t, httpClient, user.Email, password)
+
+	claims := &api.TOTPChallengeJWTClaims{}
+	parsedToken, err := jwt.ParseWithClaims(challengeToken, claims, func(t *jwt.Token) (any, error) {
+		return []byte(config.SecretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.True(t, parsedToken.Valid)
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, string(auth.JWTTypeTOTPChallenge), claims.TokenType)
+}
+
+func TestHandleVerifyTOTPChallenge(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	keySet := testkitinternal.MustCreateKeySet(config)
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	secret, recoveryCodes := testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	code, err := totp.GenerateCode(secret, time.Now().UTC())
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name           string
+		requestBody    string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Valid request with TOTP code",
+			requestBody: fmt.Sprintf(`
+				{
+					"challenge": "%s",
+					"code": "%s"
+				}
+			`, requireCreateTOTPChallenge(t, httpClient, user.Email, password), code),
+			wantStatusCode: http.StatusCreated,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Valid request with recovery code",
+			requestBody: fmt.Sprintf(`
+				{
+					"challenge": "%s",
+					"code": "%s"
+				}
+			`, requireCreateTOTPChallenge(t, httpClient, user.Email, password), recoveryCodes[0]),
+			wantStatusCode: http.StatusCreated,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Incorrect code",
+			requestBody: fmt.Sprintf(`
+				{
+					"challenge": "%s",
+					"code": "AAAAA-AAAAA"
+				}
+			`, requireCreateTOTPChallenge(t, httpClient, user.Email, password)),
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeInvalidCredentials,
+			wantErrDetail:  api.ErrDetailInvalidTOTPCode,
+		},
+		{
+			name: "Invalid challenge",
+			requestBody: fmt.Sprintf(`
+				{
+					"challenge": "ed0730889507fdb8549acfcd31548ee5",
+					"code": "%s"
+				}
+			`, recoveryCodes[1]),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidToken,
+		},
+		{
+			name: "Missing code",
+			requestBody: fmt.Sprintf(`
+				{
+					"challenge": "%s"
+				}
+			`, requireCreateTOTPChallenge(t, httpClient, user.Email, password)),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/tokens/totp",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var verifyResp api.VerifyTOTPChallengeResponse
+				err = json.NewDecoder(res.Body).Decode(&verifyResp)
+				require.NoError(t, err)
+
+				accessClaims := &api.AuthJWTClaims{}
+				parsedAccessToken, err := keySet.Parse(verifyResp.Access, accessClaims)
+				require.NoError(t, err)
+
+				require.True(t, parsedAccessToken.Valid)
+				require.Equal(t, user.UUID, accessClaims.Subject)
+				require.Equal(t, string(auth.JWTTypeAccess), accessClaims.TokenType)
+
+				requireAccessJWTStatus(t, httpClient, verifyResp.Access, http.StatusOK)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleVerifyTOTPChallengeAccountLockout(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, recoveryCodes := testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	challenge := requireCreateTOTPChallenge(t, httpClient, user.Email, password)
+	otherChallenge := requireCreateTOTPChallenge(t, httpClient, user.Email, password)
+
+	verifyTOTPChallenge := func(challenge string, code string) *http.Response {
+		req, err := http.NewRequest(
+			http.MethodPost,
+			TestServerURL+"/auth/tokens/totp",
+			bytes.NewReader([]byte(fmt.Sprintf(`
+				{
+					"challenge": "%s",
+					"code": "%s"
+				}
+			`, challenge, code))),
+		)
+		require.NoError(t, err)
+
+		res, err := httpClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			err := res.Body.Close()
+			require.NoError(t, err)
+		})
+
+		return res
+	}
+
+	for i := 0; i < 5; i++ {
+		res := verifyTOTPChallenge(challenge, "AAAAA-AAAAA")
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	}
+
+	res := verifyTOTPChallenge(otherChallenge, recoveryCodes[0])
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	retryAfter, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	require.Greater(t, retryAfter, 0)
+	require.LessOrEqual(t, retryAfter, 60)
+
+	var errResp api.ErrorResponse
+	err = json.NewDecoder(res.Body).Decode(&errResp)
+	require.NoError(t, err)
+
+	require.Equal(t, api.ErrCodeTooManyRequests, errResp.Code)
+	require.Equal(t, api.ErrDetailTooManyLoginAttempts, errResp.Detail)
+}
+
+func TestHandleEnrollTOTP(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	userWithTOTP, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	testkitinternal.MustEnableUserTOTP(t, userWithTOTP.UUID)
+	userWithTOTPAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, userWithTOTP.UUID)
+
+	testcases := []struct {
+		name           string
+		headers        map[string]string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Valid request",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			wantStatusCode: http.StatusCreated,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Two-factor authentication already enabled",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userWithTOTPAccessJWT),
+			},
+			wantStatusCode: http.StatusConflict,
+			wantErrCode:    api.ErrCodeResourceExists,
+			wantErrDetail:  api.ErrDetailTOTPAlreadyEnabled,
+		},
+		{
+			name:           "No authentication",
+			headers:        map[string]string{},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodPost, TestServerURL+"/auth/users/me/totp", nil)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var enrollResp api.EnrollTOTPResponse
+				err = json.NewDecoder(res.Body).Decode(&enrollResp)
+				require.NoError(t, err)
+
+				require.NotEmpty(t, enrollResp.Secret)
+				require.Equal(t, totp.BuildURI("Flagger", user.Email, enrollResp.Secret), enrollResp.URI)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleConfirmTOTP(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	req, err := http.NewRequest(http.MethodPost, TestServerURL+"/auth/users/me/totp", nil)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", userAccessJWT))
+
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := res.Body.Close()
+		require.NoError(t, err)
+	})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	var enrollResp api.EnrollTOTPResponse
+	err = json.NewDecoder(res.Body).Decode(&enrollResp)
+	require.NoError(t, err)
+
+	code, err := totp.GenerateCode(enrollResp.Secret, time.Now().UTC())
+	require.NoError(t, err)
+
+	userWithoutTOTP, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userWithoutTOTPAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, userWithoutTOTP.UUID)
+
+	userWithTOTP, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	testkitinternal.MustEnableUserTOTP(t, userWithTOTP.UUID)
+	userWithTOTPAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, userWithTOTP.UUID)
+
+	testcases := []struct {
+		name           string
+		headers        map[string]string
+		requestBody    string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Valid request",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"code": "%s"
+				}
+			`, code),
+			wantStatusCode: http.StatusOK,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Two-factor authentication already enabled",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userWithTOTPAccessJWT),
+			},
+			requestBody: `
+				{
+					"code": "123456"
+				}
+			`,
+			wantStatusCode: http.StatusConflict,
+			wantErrCode:    api.ErrCodeResourceExists,
+			wantErrDetail:  api.ErrDetailTOTPAlreadyEnabled,
+		},
+		{
+			name: "No enrolled TOTP device",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userWithoutTOTPAccessJWT),
+			},
+			requestBody: `
+				{
+					"code": "123456"
+				}
+			`,
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailTOTPNotEnabled,
+		},
+		{
+			name: "Missing code",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userWithoutTOTPAccessJWT),
+			},
+			requestBody:    `{}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name:    "No authentication",
+			headers: map[string]string{},
+			requestBody: `
+				{
+					"code": "123456"
+				}
+			`,
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/users/me/totp/confirm",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var confirmResp api.ConfirmTOTPResponse
+				err = json.NewDecoder(res.Body).Decode(&confirmResp)
+				require.NoError(t, err)
+
+				require.Len(t, confirmResp.RecoveryCodes, 10)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleDisableTOTP(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, password := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, recoveryCodes := testkitinternal.MustEnableUserTOTP(t, user.UUID)
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	otherUser, otherUserPassword := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, otherUserRecoveryCodes := testkitinternal.MustEnableUserTOTP(t, otherUser.UUID)
+	otherUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, otherUser.UUID)
+
+	userWithoutTOTP, userWithoutTOTPPassword := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userWithoutTOTPAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, userWithoutTOTP.UUID)
+
+	testcases := []struct {
+		name           string
+		headers        map[string]string
+		requestBody    string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Valid request",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"password": "%s",
+					"code": "%s"
+				}
+			`, password, recoveryCodes[0]),
+			wantStatusCode: http.StatusNoContent,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Incorrect password",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", otherUserAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"password": "1nc0rr3ctp455w0rd",
+					"code": "%s"
+				}
+			`, otherUserRecoveryCodes[0]),
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeInvalidCredentials,
+			wantErrDetail:  api.ErrDetailInvalidPassword,
+		},
+		{
+			name: "Incorrect code",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", otherUserAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"password": "%s",
+					"code": "AAAAA-AAAAA"
+				}
+			`, otherUserPassword),
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeInvalidCredentials,
+			wantErrDetail:  api.ErrDetailInvalidTOTPCode,
+		},
+		{
+			name: "Two-factor authentication not enabled",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userWithoutTOTPAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"password": "%s",
+					"code": "123456"
+				}
+			`, userWithoutTOTPPassword),
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailTOTPNotEnabled,
+		},
+		{
+			name: "Missing password",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", otherUserAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"code": "%s"
+				}
+			`, otherUserRecoveryCodes[0]),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name:    "No authentication",
+			headers: map[string]string{},
+			requestBody: fmt.Sprintf(`
+				{
+					"password": "%s",
+					"code": "%s"
+				}
+			`, otherUserPassword, otherUserRecoveryCodes[0]),
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/users/me/totp/disable",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func requireCreateOIDCAuthorization(t *testing.T, httpClient *http.Client, oidcUser testkit.OIDCUser) (string, string) {
+	req, err := http.NewRequest(http.MethodPost, TestServerURL+"/auth/oidc/authorize", nil)
+	require.NoError(t, err)
+
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := res.Body.Close()
+		require.NoError(t, err)
+	})
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var authURLResp api.OIDCAuthURLResponse
+	err = json.NewDecoder(res.Body).Decode(&authURLResp)
+	require.NoError(t, err)
+
+	code, state, err := TestOIDCProvider.Authorize(authURLResp.URL, oidcUser)
+	require.NoError(t, err)
+
+	return state, code
+}
+
+func TestHandleRequestMagicLink(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	testcases := []struct {
+		name           string
+		requestBody    string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Active user",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s"
+				}
+			`, activeUser.Email),
+			wantStatusCode: http.StatusAccepted,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Unknown email",
+			requestBody: fmt.Sprintf(`
+				{
+					"email": "%s"
+				}
+			`, testkit.GenerateFakeEmail()),
+			wantStatusCode: http.StatusAccepted,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Invalid email",
+			requestBody: `
+				{
+					"email": "1nv4l1d3m41l"
+				}
+			`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/magic-link",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleVerifyMagicLink(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	keySet := testkitinternal.MustCreateKeySet(config)
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	validToken := testkitinternal.MustCreateUserMagicLinkJWT(t, user)
+
+	totpUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	testkitinternal.MustEnableUserTOTP(t, totpUser.UUID)
+	totpUserToken := testkitinternal.MustCreateUserMagicLinkJWT(t, totpUser)
+
+	staleUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	staleToken := testkitinternal.MustCreateUserMagicLinkJWT(t, &auth.User{
+		UUID:  staleUser.UUID,
+		Email: testkit.GenerateFakeEmail(),
+	})
+
+	testcases := []struct {
+		name           string
+		requestBody    string
+		wantUserUUID   string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Valid token",
+			requestBody: fmt.Sprintf(`
+				{
+					"token": "%s"
+				}
+			`, validToken),
+			wantUserUUID:   user.UUID,
+			wantStatusCode: http.StatusCreated,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Valid token for user with two-factor authentication",
+			requestBody: fmt.Sprintf(`
+				{
+					"token": "%s"
+				}
+			`, totpUserToken),
+			wantUserUUID:   totpUser.UUID,
+			wantStatusCode: http.StatusAccepted,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Token issued for previous email",
+			requestBody: fmt.Sprintf(`
+				{
+					"token": "%s"
+				}
+			`, staleToken),
+			wantUserUUID:   "",
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidToken,
+		},
+		{
+			name: "Invalid token",
+			requestBody: `
+				{
+					"token": "1nV4LiDT0k3n"
+				}
+			`,
+			wantUserUUID:   "",
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidToken,
+		},
+		{
+			name: "Missing token",
+			requestBody: `
+				{}
+			`,
+			wantUserUUID:   "",
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/magic-link/verify",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			switch testcase.wantStatusCode {
+			case http.StatusCreated:
+				var createTokenResp api.CreateTokenResponse
+				err = json.NewDecoder(res.Body).Decode(&createTokenResp)
+				require.NoError(t, err)
+
+				accessClaims := &api.AuthJWTClaims{}
+				parsedAccessToken, err := keySet.Parse(createTokenResp.Access, accessClaims)
+				require.NoError(t, err)
+
+				require.True(t, parsedAccessToken.Valid)
+				require.Equal(t, testcase.wantUserUUID, accessClaims.Subject)
+				require.Equal(t, string(auth.JWTTypeAccess), accessClaims.TokenType)
+
+				refreshClaims := &api.AuthJWTClaims{}
+				parsedRefreshToken, err := keySet.Parse(createTokenResp.Refresh, refreshClaims)
+				require.NoError(t, err)
+
+				require.True(t, parsedRefreshToken.Valid)
+				require.Equal(t, testcase.wantUserUUID, refreshClaims.Subject)
+				require.Equal(t, string(auth.JWTTypeRefresh), refreshClaims.TokenType)
+			case http.StatusAccepted:
+				var challengeResp api.TOTPChallengeResponse
+				err = json.NewDecoder(res.Body).Decode(&challengeResp)
+				require.NoError(t, err)
+
+				claims := &api.TOTPChallengeJWTClaims{}
+				parsedToken, err := jwt.ParseWithClaims(challengeResp.Challenge, claims, func(t *jwt.Token) (any, error) {
+					return []byte(config.SecretKey), nil
+				})
+				require.NoError(t, err)
+
+				require.True(t, parsedToken.Valid)
+				require.Equal(t, testcase.wantUserUUID, claims.Subject)
+				require.Equal(t, string(auth.JWTTypeTOTPChallenge), claims.TokenType)
+			default:
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleCreateOIDCAuthURL(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	state, code := requireCreateOIDCAuthorization(t, httpClient, testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         testkit.GenerateFakeEmail(),
+		EmailVerified: true,
+	})
+	require.NotEmpty(t, state)
+	require.NotEmpty(t, code)
+}
+
+func TestHandleOIDCCallback(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	keySet := testkitinternal.MustCreateKeySet(config)
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+
+	totpUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	testkitinternal.MustEnableUserTOTP(t, totpUser.UUID)
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+
+	existingUserState, existingUserCode := requireCreateOIDCAuthorization(t, httpClient, testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         user.Email,
+		EmailVerified: true,
+	})
+	totpUserState, totpUserCode := requireCreateOIDCAuthorization(t, httpClient, testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         totpUser.Email,
+		EmailVerified: true,
+	})
+	newUserState, newUserCode := requireCreateOIDCAuthorization(t, httpClient, testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         testkit.GenerateFakeEmail(),
+		EmailVerified: true,
+		GivenName:     "Jim",
+		FamilyName:    "Halpert",
+	})
+	inactiveUserState, inactiveUserCode := requireCreateOIDCAuthorization(t, httpClient, testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         inactiveUser.Email,
+		EmailVerified: true,
+	})
+	unverifiedState, unverifiedCode := requireCreateOIDCAuthorization(t, httpClient, testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         testkit.GenerateFakeEmail(),
+		EmailVerified: false,
+	})
+	_, unusedCode := requireCreateOIDCAuthorization(t, httpClient, testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         testkit.GenerateFakeEmail(),
+		EmailVerified: true,
+	})
+	missingCodeState, _ := requireCreateOIDCAuthorization(t, httpClient, testkit.OIDCUser{
+		Subject:       uuid.NewString(),
+		Email:         testkit.GenerateFakeEmail(),
+		EmailVerified: true,
+	})
+
+	testcases := []struct {
+		name           string
+		requestBody    string
+		wantUserUUID   string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Valid request for existing user",
+			requestBody: fmt.Sprintf(`
+				{
+					"state": "%s",
+					"code": "%s"
+				}
+			`, existingUserState, existingUserCode),
+			wantUserUUID:   user.UUID,
+			wantStatusCode: http.StatusCreated,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Valid request for user with two-factor authentication",
+			requestBody: fmt.Sprintf(`
+				{
+					"state": "%s",
+					"code": "%s"
+				}
+			`, totpUserState, totpUserCode),
+			wantUserUUID:   totpUser.UUID,
+			wantStatusCode: http.StatusAccepted,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Valid request for new user",
+			requestBody: fmt.Sprintf(`
+				{
+					"state": "%s",
+					"code": "%s"
+				}
+			`, newUserState, newUserCode),
+			wantUserUUID:   "",
+			wantStatusCode: http.StatusCreated,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Inactive user",
+			requestBody: fmt.Sprintf(`
+				{
+					"state": "%s",
+					"code": "%s"
+				}
+			`, inactiveUserState, inactiveUserCode),
+			wantUserUUID:   "",
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeInvalidCredentials,
+			wantErrDetail:  api.ErrDetailOIDCAuthFailed,
+		},
+		{
+			name: "Unverified email",
+			requestBody: fmt.Sprintf(`
+				{
+					"state": "%s",
+					"code": "%s"
+				}
+			`, unverifiedState, unverifiedCode),
+			wantUserUUID:   "",
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeInvalidCredentials,
+			wantErrDetail:  api.ErrDetailOIDCAuthFailed,
+		},
+		{
+			name: "Invalid state",
+			requestBody: fmt.Sprintf(`
+				{
+					"state": "ed0730889507fdb8549acfcd31548ee5",
+					"code": "%s"
+				}
+			`, unusedCode),
+			wantUserUUID:   "",
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidOIDCState,
+		},
+		{
+			name: "Missing code",
+			requestBody: fmt.Sprintf(`
+				{
+					"state": "%s"
+				}
+			`, missingCodeState),
+			wantUserUUID:   "",
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/oidc/callback",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if testcase.wantStatusCode == http.StatusAccepted {
+				var challengeResp api.TOTPChallengeResponse
+				err = json.NewDecoder(res.Body).Decode(&challengeResp)
+				require.NoError(t, err)
+
+				require.NotEmpty(t, challengeResp.Challenge)
+			} else if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var callbackResp api.OIDCCallbackResponse
+				err = json.NewDecoder(res.Body).Decode(&callbackResp)
+				require.NoError(t, err)
+
+				accessClaims := &api.AuthJWTClaims{}
+				parsedAccessToken, err := keySet.Parse(callbackResp.Access, accessClaims)
+				require.NoError(t, err)
+
+				require.True(t, parsedAccessToken.Valid)
+				require.Equal(t, string(auth.JWTTypeAccess), accessClaims.TokenType)
+				if testcase.wantUserUUID != "" {
+					require.Equal(t, testcase.wantUserUUID, accessClaims.Subject)
+				}
+
+				requireAccessJWTStatus(t, httpClient, callbackResp.Access, http.StatusOK)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func requireAccessJWTStatus(t *testing.T, httpClient *http.Client, accessJWT string, wantStatusCode int) {
+	req, err := http.NewRequest(http.MethodGet, TestServerURL+"/auth/users/me", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", accessJWT))
+
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := res.Body.Close()
+		require.NoError(t, err)
+	})
+
+	require.Equal(t, wantStatusCode, res.StatusCode)
+}
+
+func TestHandleCreateAPIKey(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	expirationDate := time.Date(2038, 1, 19, 3, 14, 8, 0, time.UTC)
+	expirationDateString := "2038-01-19T03:14:08Z"
+
+	testcases := []struct {
+		name               string
+		headers            map[string]string
+		requestBody        string
+		wantStatusCode     int
+		wantAPIKeyName     string
+		wantScopes         []string
+		wantAllowedCIDRs   []string
+		wantAllowedOrigins []string
+		wantExpirationDate pgtype.Timestamp
+		wantErrCode        string
+		wantErrDetail      string
+	}{
+		{
+			name: "Valid request with no expiration date",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "My non-expiring API key"
+				}
+			`,
+			wantStatusCode:   http.StatusCreated,
+			wantAPIKeyName:   "My non-expiring API key",
+			wantScopes:       []string{"admin"},
+			wantAllowedCIDRs: []string{},
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantErrCode:   "",
+			wantErrDetail: "",
+		},
+		{
+			name: "Valid request with expiration date",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"name": "My expiring API key",
+					"expires_at": "%s"
+				}
+			`, expirationDateString),
+			wantStatusCode:   http.StatusCreated,
+			wantAPIKeyName:   "My expiring API key",
+			wantScopes:       []string{"admin"},
+			wantAllowedCIDRs: []string{},
+			wantExpirationDate: pgtype.Timestamp{
+				Time:  expirationDate,
+				Valid: true,
+			},
+			wantErrCode:   "",
+			wantErrDetail: "",
+		},
+		{
+			name: "Valid request with scopes",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "My evaluate-only API key",
+					"scopes": ["flags:evaluate", "flags:evaluate"]
+				}
+			`,
+			wantStatusCode:   http.StatusCreated,
+			wantAPIKeyName:   "My evaluate-only API key",
+			wantScopes:       []string{"flags:evaluate"},
+			wantAllowedCIDRs: []string{},
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantErrCode:   "",
+			wantErrDetail: "",
+		},
+		{
+			name: "Invalid scope",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "My invalidly-scoped API key",
+					"scopes": ["flags:delete"]
+				}
+			`,
+			wantStatusCode:   http.StatusBadRequest,
+			wantAPIKeyName:   "My invalidly-scoped API key",
+			wantScopes:       nil,
+			wantAllowedCIDRs: []string{},
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantErrCode:   api.ErrCodeInvalidRequest,
+			wantErrDetail: api.ErrDetailInvalidAPIKeyScope,
+		},
+		{
+			name: "Valid request with allowed CIDR ranges",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "My IP-restricted API key",
+					"allowed_cidrs": ["10.1.2.3/8", "203.0.113.42", "10.0.0.0/8"]
+				}
+			`,
+			wantStatusCode:   http.StatusCreated,
+			wantAPIKeyName:   "My IP-restricted API key",
+			wantScopes:       []string{"admin"},
+			wantAllowedCIDRs: []string{"10.0.0.0/8", "203.0.113.42/32"},
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantErrCode:   "",
+			wantErrDetail: "",
+		},
+		{
+			name: "Invalid allowed CIDR range",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "My invalidly-restricted API key",
+					"allowed_cidrs": ["10.0.0.0/33"]
+				}
+			`,
+			wantStatusCode:   http.StatusBadRequest,
+			wantAPIKeyName:   "My invalidly-restricted API key",
+			wantScopes:       nil,
+			wantAllowedCIDRs: nil,
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantErrCode:   api.ErrCodeInvalidRequest,
+			wantErrDetail: api.ErrDetailInvalidAPIKeyCIDR,
+		},
+		{
+			name: "Valid request with allowed origins",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "My origin-restricted API key",
+					"allowed_origins": ["https://App.example.com/", "http://localhost:3000"]
+				}
+			`,
+			wantStatusCode:     http.StatusCreated,
+			wantAPIKeyName:     "My origin-restricted API key",
+			wantScopes:         []string{"admin"},
+			wantAllowedCIDRs:   []string{},
+			wantAllowedOrigins: []string{"https://app.example.com", "http://localhost:3000"},
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantErrCode:   "",
+			wantErrDetail: "",
+		},
+		{
+			name: "Invalid allowed origin",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "My invalidly-origin-restricted API key",
+					"allowed_origins": ["app.example.com"]
+				}
+			`,
+			wantStatusCode:     http.StatusBadRequest,
+			wantAPIKeyName:     "My invalidly-origin-restricted API key",
+			wantScopes:         nil,
+			wantAllowedCIDRs:   nil,
+			wantAllowedOrigins: nil,
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantErrCode:   api.ErrCodeInvalidRequest,
+			wantErrDetail: api.ErrDetailInvalidAPIKeyOrigin,
+		},
+		{
+			name: "Name missing",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: fmt.Sprintf(`
+				{
+					"expires_at": "%s"
+				}
+			`, expirationDateString),
+			wantStatusCode:   http.StatusBadRequest,
+			wantAPIKeyName:   "My nameless API key",
+			wantScopes:       []string{"admin"},
+			wantAllowedCIDRs: []string{},
+			wantExpirationDate: pgtype.Timestamp{
+				Time:  expirationDate,
+				Valid: true,
+			},
+			wantErrCode:   api.ErrCodeInvalidRequest,
+			wantErrDetail: api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Invalid expiration date",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "My invalidly-expiring API key",
+					"expires_at": "1nv4l1dd4t3"
+				}
+			`,
+			wantStatusCode:   http.StatusBadRequest,
+			wantAPIKeyName:   "My invalidly-expiring API key",
+			wantScopes:       []string{"admin"},
+			wantAllowedCIDRs: []string{},
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantErrCode:   api.ErrCodeInvalidRequest,
+			wantErrDetail: api.ErrDetailInvalidRequestData,
+		},
+		{
+			name:    "Unauthenticated request",
+			headers: map[string]string{},
+			requestBody: `
+				{
+					"name": "My unauthenticated API key"
+				}
+			`,
+			wantStatusCode:   http.StatusUnauthorized,
+			wantAPIKeyName:   "My unauthenticated API key",
+			wantScopes:       []string{"admin"},
+			wantAllowedCIDRs: []string{},
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantErrCode:   api.ErrCodeMissingCredentials,
+			wantErrDetail: api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/api-keys",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			apiKeyCreatedAt := time.Now().UTC()
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var createAPIKeyResp api.CreateAPIKeyResponse
+				err = json.NewDecoder(res.Body).Decode(&createAPIKeyResp)
+				require.NoError(t, err)
+
+				require.Equal(t, user.UUID, createAPIKeyResp.UserUUID)
+				require.Equal(t, testcase.wantAPIKeyName, createAPIKeyResp.Name)
+				require.Equal(t, testcase.wantScopes, createAPIKeyResp.Scopes)
+				require.Equal(t, testcase.wantAllowedCIDRs, createAPIKeyResp.AllowedCIDRs)
+				require.ElementsMatch(t, testcase.wantAllowedOrigins, createAPIKeyResp.AllowedOrigins)
+				testkit.RequireTimeAlmostEqual(t, apiKeyCreatedAt, createAPIKeyResp.CreatedAt)
+				require.Equal(t, testcase.wantExpirationDate.Valid, createAPIKeyResp.ExpiresAt.Valid)
+				if testcase.wantExpirationDate.Valid {
+					require.Equal(t, testcase.wantExpirationDate, createAPIKeyResp.ExpiresAt)
+				}
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleCreateAPIKeyServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, func(sa *auth.ServiceAccount) {
+		sa.Scopes = []string{string(auth.APIKeyScopeFlagsRead)}
+	})
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUserServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, otherUser.UUID, nil)
+
+	testcases := []struct {
+		name           string
+		requestBody    string
+		wantStatusCode int
+		wantScopes     []string
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Service account key defaults to service account scopes",
+			requestBody: fmt.Sprintf(`
+				{
+					"name": "My service account API key",
+					"service_account_uuid": "%s"
+				}
+			`, serviceAccount.UUID),
+			wantStatusCode: http.StatusCreated,
+			wantScopes:     []string{"flags:read"},
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Service account key with narrower scope",
+			requestBody: fmt.Sprintf(`
+				{
+					"name": "My evaluate-only service account API key",
+					"scopes": ["flags:evaluate"],
+					"service_account_uuid": "%s"
+				}
+			`, serviceAccount.UUID),
+			wantStatusCode: http.StatusCreated,
+			wantScopes:     []string{"flags:evaluate"},
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Service account key with scope beyond service account",
+			requestBody: fmt.Sprintf(`
+				{
+					"name": "My over-privileged service account API key",
+					"scopes": ["flags:write"],
+					"service_account_uuid": "%s"
+				}
+			`, serviceAccount.UUID),
+			wantStatusCode: http.StatusBadRequest,
+			wantScopes:     nil,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailServiceAccountScopeDenied,
+		},
+		{
+			name: "Service account owned by another user",
+			requestBody: fmt.Sprintf(`
+				{
+					"name": "My stolen service account API key",
+					"service_account_uuid": "%s"
+				}
+			`, otherUserServiceAccount.UUID),
+			wantStatusCode: http.StatusNotFound,
+			wantScopes:     nil,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailServiceAccountNotFound,
+		},
+		{
+			name: "Invalid service account UUID",
+			requestBody: `
+				{
+					"name": "My invalid service account API key",
+					"service_account_uuid": "1nv4l1d"
+				}
+			`,
+			wantStatusCode: http.StatusNotFound,
+			wantScopes:     nil,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailServiceAccountNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				TestServerURL+"/auth/api-keys",
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", userAccessJWT))
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var createAPIKeyResp api.CreateAPIKeyResponse
+				err = json.NewDecoder(res.Body).Decode(&createAPIKeyResp)
+				require.NoError(t, err)
+
+				require.Equal(t, user.UUID, createAPIKeyResp.UserUUID)
+				require.True(t, createAPIKeyResp.ServiceAccountUUID.Valid)
+				require.Equal(t, serviceAccount.UUID, createAPIKeyResp.ServiceAccountUUID.String)
+				require.Equal(t, testcase.wantScopes, createAPIKeyResp.Scopes)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleListAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, activeUser.UUID)
+	activeUserAPIKey, activeUserRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, nil)
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, inactiveUser.UUID)
+	testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+
+	testcases := []struct {
+		name                  string
+		headers               map[string]string
+		wantStatusCode        int
+		wantAPIKeysInResponse bool
+		wantErrCode           string
+		wantErrDetail         string
+	}{
+		{
+			name: "List API keys for active user",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			wantStatusCode:        http.StatusOK,
+			wantAPIKeysInResponse: true,
+			wantErrCode:           "",
+			wantErrDetail:         "",
+		},
+		{
+			name: "List API keys for inactive user",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", inactiveUserAccessJWT),
+			},
+			wantStatusCode:        http.StatusOK,
+			wantAPIKeysInResponse: false,
+			wantErrCode:           api.ErrCodeResourceNotFound,
+			wantErrDetail:         api.ErrDetailUserNotFound,
+		},
+		{
+			name:                  "List API keys without authentication",
+			headers:               map[string]string{},
+			wantStatusCode:        http.StatusUnauthorized,
+			wantAPIKeysInResponse: false,
+			wantErrCode:           api.ErrCodeMissingCredentials,
+			wantErrDetail:         api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				TestServerURL+"/auth/api-keys",
+				http.NoBody,
+			)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var listAPIKeysResp api.ListAPIKeysResponse
+				err = json.NewDecoder(res.Body).Decode(&listAPIKeysResp)
+				require.NoError(t, err)
+
+				if testcase.wantAPIKeysInResponse {
+					require.Len(t, listAPIKeysResp.Keys, 1)
+					require.Equal(t, activeUserAPIKey.ID, listAPIKeysResp.Keys[0].ID)
+					require.Equal(t, activeUser.UUID, listAPIKeysResp.Keys[0].UserUUID)
+					require.True(t, strings.HasPrefix(activeUserRawAPIKey, listAPIKeysResp.Keys[0].Prefix))
+					require.Equal(t, activeUserAPIKey.Name, listAPIKeysResp.Keys[0].Name)
+					require.Equal(t, activeUserAPIKey.CreatedAt, listAPIKeysResp.Keys[0].CreatedAt)
+					require.Equal(t, activeUserAPIKey.ExpiresAt, listAPIKeysResp.Keys[0].ExpiresAt)
+				} else {
+					require.Len(t, listAPIKeysResp.Keys, 0)
+				}
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleUpdateAPIKey(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, activeUser.UUID)
+	activeUserAPIKey1, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Name = "MyAPIKey1"
+		k.ExpiresAt = pgtype.Timestamp{
+			Time:  time.Date(2038, 1, 19, 3, 14, 7, 0, time.UTC),
+			Valid: true,
+		}
+	})
+	activeUserAPIKey2, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Name = "MyAPIKey2"
+	})
+	activeUserAPIKey3, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Name = "MyAPIKey3"
+		k.ExpiresAt = pgtype.Timestamp{
+			Time:  time.Date(2038, 1, 19, 3, 14, 7, 0, time.UTC),
+			Valid: true,
+		}
+	})
+	activeUserAPIKey4, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Name = "MyAPIKey4"
+	})
+	activeUserAPIKey5, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Name = "MyAPIKey5"
+		k.AllowedCIDRs = []string{"10.0.0.0/8"}
+	})
+	activeUserAPIKey6, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Name = "MyAPIKey6"
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, inactiveUser.UUID)
+
+	testcases := []struct {
+		name               string
+		path               string
+		headers            map[string]string
+		requestBody        string
+		wantStatusCode     int
+		wantAPIKeyName     string
+		wantExpirationDate pgtype.Timestamp
+		wantAllowedCIDRs   []string
+		wantAllowedOrigins []string
+		wantErrCode        string
+		wantErrDetail      string
+	}{
+		{
+			name: "Rename API key",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey1.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "MyRenamedAPIKey1"
+				}
+			`,
+			wantStatusCode:     http.StatusOK,
+			wantAPIKeyName:     "MyRenamedAPIKey1",
+			wantExpirationDate: activeUserAPIKey1.ExpiresAt,
+			wantAllowedCIDRs:   []string{},
+			wantErrCode:        "",
+			wantErrDetail:      "",
+		},
+		{
+			name: "Set API key expiration date",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey2.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"expires_at": "2038-01-19T03:14:08Z"
+				}
+			`,
+			wantStatusCode: http.StatusOK,
+			wantAPIKeyName: "MyAPIKey2",
+			wantExpirationDate: pgtype.Timestamp{
+				Time:  time.Date(2038, 1, 19, 3, 14, 8, 0, time.UTC),
+				Valid: true,
+			},
+			wantAllowedCIDRs: []string{},
+			wantErrCode:      "",
+			wantErrDetail:    "",
+		},
+		{
+			name: "Remove API key expiration date",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey3.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"expires_at": null
+				}
+			`,
+			wantStatusCode: http.StatusOK,
+			wantAPIKeyName: "MyAPIKey3",
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantAllowedCIDRs: []string{},
+			wantErrCode:      "",
+			wantErrDetail:    "",
+		},
+		{
+			name: "Restrict API key to allowed CIDR ranges",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey4.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"allowed_cidrs": ["192.168.1.7/24", "2001:db8::1"]
+				}
+			`,
+			wantStatusCode: http.StatusOK,
+			wantAPIKeyName: "MyAPIKey4",
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantAllowedCIDRs: []string{"192.168.1.0/24", "2001:db8::1/128"},
+			wantErrCode:      "",
+			wantErrDetail:    "",
+		},
+		{
+			name: "Remove API key allowed CIDR ranges",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey5.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"allowed_cidrs": []
+				}
+			`,
+			wantStatusCode: http.StatusOK,
+			wantAPIKeyName: "MyAPIKey5",
+			wantExpirationDate: pgtype.Timestamp{
+				Valid: false,
+			},
+			wantAllowedCIDRs: []string{},
+			wantErrCode:      "",
+			wantErrDetail:    "",
+		},
+		{
+			name: "Invalid allowed CIDR range",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey2.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"allowed_cidrs": ["not-an-ip"]
+				}
+			`,
+			wantStatusCode:     http.StatusBadRequest,
+			wantAPIKeyName:     "",
+			wantExpirationDate: pgtype.Timestamp{},
+			wantAllowedCIDRs:   nil,
+			wantErrCode:        api.ErrCodeInvalidRequest,
+			wantErrDetail:      api.ErrDetailInvalidAPIKeyCIDR,
+		},
+		{
+			name: "Update allowed origins",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey6.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"allowed_origins": ["https://app.example.com"]
+				}
+			`,
+			wantStatusCode:     http.StatusOK,
+			wantAPIKeyName:     "MyAPIKey6",
+			wantExpirationDate: pgtype.Timestamp{},
+			wantAllowedCIDRs:   []string{},
+			wantAllowedOrigins: []string{"https://app.example.com"},
+			wantErrCode:        "",
+			wantErrDetail:      "",
+		},
+		{
+			name: "Invalid allowed origin",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey6.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"allowed_origins": ["https://app.example.com/dashboard"]
+				}
+			`,
+			wantStatusCode:     http.StatusBadRequest,
+			wantAPIKeyName:     "",
+			wantExpirationDate: pgtype.Timestamp{},
+			wantAllowedCIDRs:   nil,
+			wantAllowedOrigins: nil,
+			wantErrCode:        api.ErrCodeInvalidRequest,
+			wantErrDetail:      api.ErrDetailInvalidAPIKeyOrigin,
+		},
+		{
+			name: "Rename API key to existing name",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey2.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "MyAPIKey3"
+				}
+			`,
+			wantStatusCode:     http.StatusConflict,
+			wantAPIKeyName:     "",
+			wantExpirationDate: pgtype.Timestamp{},
+			wantAllowedCIDRs:   nil,
+			wantErrCode:        api.ErrCodeResourceExists,
+			wantErrDetail:      api.ErrDetailAPIKeyExists,
+		},
+		{
+			name: "Blank name",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey2.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "  "
+				}
+			`,
+			wantStatusCode:     http.StatusBadRequest,
+			wantAPIKeyName:     "",
+			wantExpirationDate: pgtype.Timestamp{},
+			wantAllowedCIDRs:   nil,
+			wantErrCode:        api.ErrCodeInvalidRequest,
+			wantErrDetail:      api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "No attributes to update",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey2.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody:        `{}`,
+			wantStatusCode:     http.StatusBadRequest,
+			wantAPIKeyName:     "",
+			wantExpirationDate: pgtype.Timestamp{},
+			wantAllowedCIDRs:   nil,
+			wantErrCode:        api.ErrCodeInvalidRequest,
+			wantErrDetail:      api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Update API key for another user",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey2.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", inactiveUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "MyStolenAPIKey"
+				}
+			`,
+			wantStatusCode:     http.StatusNotFound,
+			wantAPIKeyName:     "",
+			wantExpirationDate: pgtype.Timestamp{},
+			wantAllowedCIDRs:   nil,
+			wantErrCode:        api.ErrCodeResourceNotFound,
+			wantErrDetail:      api.ErrDetailAPIKeyNotFound,
+		},
+		{
+			name: "Update non-existent API key",
+			path: fmt.Sprintf("/auth/api-keys/%d", 314159),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "MyMissingAPIKey"
+				}
+			`,
+			wantStatusCode:     http.StatusNotFound,
+			wantAPIKeyName:     "",
+			wantExpirationDate: pgtype.Timestamp{},
+			wantAllowedCIDRs:   nil,
+			wantErrCode:        api.ErrCodeResourceNotFound,
+			wantErrDetail:      api.ErrDetailAPIKeyNotFound,
+		},
+		{
+			name:    "Update API key without authentication",
+			path:    fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey2.ID),
+			headers: map[string]string{},
+			requestBody: `
+				{
+					"name": "MyUnauthenticatedAPIKey"
+				}
+			`,
+			wantStatusCode:     http.StatusUnauthorized,
+			wantAPIKeyName:     "",
+			wantExpirationDate: pgtype.Timestamp{},
+			wantAllowedCIDRs:   nil,
+			wantErrCode:        api.ErrCodeMissingCredentials,
+			wantErrDetail:      api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPut,
+				TestServerURL+testcase.path,
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var getAPIKeyResp api.GetAPIKeyResponse
+				err = json.NewDecoder(res.Body).Decode(&getAPIKeyResp)
+				require.NoError(t, err)
+
+				require.Equal(t, activeUser.UUID, getAPIKeyResp.UserUUID)
+				require.Equal(t, testcase.wantAPIKeyName, getAPIKeyResp.Name)
+				require.Equal(t, testcase.wantAllowedCIDRs, getAPIKeyResp.AllowedCIDRs)
+				require.ElementsMatch(t, testcase.wantAllowedOrigins, getAPIKeyResp.AllowedOrigins)
+				require.Equal(t, testcase.wantExpirationDate.Valid, getAPIKeyResp.ExpiresAt.Valid)
+				if testcase.wantExpirationDate.Valid {
+					require.Equal(t, testcase.wantExpirationDate.Time, getAPIKeyResp.ExpiresAt.Time)
+				}
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleRotateAPIKey(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, activeUser.UUID)
+	activeUserAPIKey1, activeUserRawAPIKey1 := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"user:read"}
+	})
+	activeUserAPIKey2, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, nil)
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, inactiveUser.UUID)
+
+	testcases := []struct {
+		name           string
+		path           string
+		headers        map[string]string
+		apiKey         *auth.APIKey
+		rawAPIKey      string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Rotate API key for active user",
+			path: fmt.Sprintf("/auth/api-keys/%d/rotate", activeUserAPIKey1.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			apiKey:         activeUserAPIKey1,
+			rawAPIKey:      activeUserRawAPIKey1,
+			wantStatusCode: http.StatusOK,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Rotate API key for another user",
+			path: fmt.Sprintf("/auth/api-keys/%d/rotate", activeUserAPIKey2.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", inactiveUserAccessJWT),
+			},
+			apiKey:         nil,
+			rawAPIKey:      "",
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailAPIKeyNotFound,
+		},
+		{
+			name: "Rotate non-existent API key",
+			path: fmt.Sprintf("/auth/api-keys/%d/rotate", 314159),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			apiKey:         nil,
+			rawAPIKey:      "",
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailAPIKeyNotFound,
+		},
+		{
+			name:           "Rotate API key without authentication",
+			path:           fmt.Sprintf("/auth/api-keys/%d/rotate", activeUserAPIKey2.ID),
+			headers:        map[string]string{},
+			apiKey:         nil,
+			rawAPIKey:      "",
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodPost, TestServerURL+testcase.path, http.NoBody)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			rotatedAt := time.Now().UTC()
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+				return
+			}
+
+			var rotateAPIKeyResp api.RotateAPIKeyResponse
+			err = json.NewDecoder(res.Body).Decode(&rotateAPIKeyResp)
+			require.NoError(t, err)
+
+			require.Equal(t, testcase.apiKey.ID, rotateAPIKeyResp.ID)
+			require.Equal(t, testcase.apiKey.Name, rotateAPIKeyResp.Name)
+			require.Equal(t, testcase.apiKey.Scopes, rotateAPIKeyResp.Scopes)
+			require.NotEqual(t, testcase.rawAPIKey, rotateAPIKeyResp.RawKey)
+			require.True(t, rotateAPIKeyResp.PreviousKeyExpiresAt.Valid)
+			require.True(t, rotateAPIKeyResp.PreviousKeyExpiresAt.Time.After(rotatedAt))
+
+			for _, rawAPIKey := range []string{rotateAPIKeyResp.RawKey, testcase.rawAPIKey} {
+				req, err := http.NewRequest(http.MethodGet, TestServerURL+"/api/auth/users/me", http.NoBody)
+				require.NoError(t, err)
+				req.Header.Add("Authorization", fmt.Sprintf("X-API-Key %s", rawAPIKey))
+
+				res, err := httpClient.Do(req)
+				require.NoError(t, err)
+				t.Cleanup(func() {
+					err := res.Body.Close()
+					require.NoError(t, err)
+				})
+
+				require.Equal(t, http.StatusOK, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandleGetAPIKeyUsage(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, activeUser.UUID)
+	activeUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, nil)
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, inactiveUser.UUID)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	testkitinternal.MustRecordAPIKeyUsage(t, activeUserAPIKey.ID, today, 3)
+	testkitinternal.MustRecordAPIKeyUsage(t, activeUserAPIKey.ID, today.AddDate(0, 0, -2), 5)
+
+	testcases := []struct {
+		name           string
+		path           string
+		headers        map[string]string
+		wantStatusCode int
+		wantUsage      []*api.GetAPIKeyDailyUsageResponse
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Get usage for last 3 days",
+			path: fmt.Sprintf("/auth/api-keys/%d/usage?days=3", activeUserAPIKey.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			wantStatusCode: http.StatusOK,
+			wantUsage: []*api.GetAPIKeyDailyUsageResponse{
+				{
+					Date:         today.AddDate(0, 0, -2).Format(time.DateOnly),
+					RequestCount: 5,
+				},
+				{
+					Date:         today.AddDate(0, 0, -1).Format(time.DateOnly),
+					RequestCount: 0,
+				},
+				{
+					Date:         today.Format(time.DateOnly),
+					RequestCount: 3,
+				},
+			},
+			wantErrCode:   "",
+			wantErrDetail: "",
+		},
+		{
+			name: "Get usage for today",
+			path: fmt.Sprintf("/auth/api-keys/%d/usage?days=1", activeUserAPIKey.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			wantStatusCode: http.StatusOK,
+			wantUsage: []*api.GetAPIKeyDailyUsageResponse{
+				{
+					Date:         today.Format(time.DateOnly),
+					RequestCount: 3,
+				},
+			},
+			wantErrCode:   "",
+			wantErrDetail: "",
+		},
+		{
+			name: "Get usage with invalid days",
+			path: fmt.Sprintf("/auth/api-keys/%d/usage?days=365", activeUserAPIKey.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantUsage:      nil,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Get usage for another user's API key",
+			path: fmt.Sprintf("/auth/api-keys/%d/usage", activeUserAPIKey.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", inactiveUserAccessJWT),
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantUsage:      nil,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailAPIKeyNotFound,
+		},
+		{
+			name: "Get usage for non-existent API key",
+			path: fmt.Sprintf("/auth/api-keys/%d/usage", 314159),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantUsage:      nil,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailAPIKeyNotFound,
+		},
+		{
+			name:           "Get usage without authentication",
+			path:           fmt.Sprintf("/auth/api-keys/%d/usage", activeUserAPIKey.ID),
+			headers:        map[string]string{},
+			wantStatusCode: http.StatusUnauthorized,
+			wantUsage:      nil,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, TestServerURL+testcase.path, http.NoBody)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var getAPIKeyUsageResp api.GetAPIKeyUsageResponse
+				err = json.NewDecoder(res.Body).Decode(&getAPIKeyUsageResp)
+				require.NoError(t, err)
+
+				require.Equal(t, activeUserAPIKey.ID, getAPIKeyUsageResp.ID)
+				require.Equal(t, testcase.wantUsage, getAPIKeyUsageResp.Usage)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleDeleteAPIKey(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, activeUser.UUID)
+	activeUserAPIKey1, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Name = "MyAPIKey1"
+	})
+	activeUserAPIKey2, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Name = "MyAPIKey2"
+	})
+
+	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, inactiveUser.UUID)
+	inactiveUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+
+	testcases := []struct {
+		name           string
+		path           string
+		headers        map[string]string
+		wantStatusCode int
+		wantErrCode    string
+		wantErrDetail  string
+	}{
+		{
+			name: "Delete API key for active user",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey1.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			wantStatusCode: http.StatusNoContent,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
+		{
+			name: "Delete API key for inactive user",
+			path: fmt.Sprintf("/auth/api-keys/%d", inactiveUserAPIKey.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", inactiveUserAccessJWT),
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailAPIKeyNotFound,
+		},
+		{
+			name: "Delete API key for another user",
+			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey2.ID),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", inactiveUserAccessJWT),
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailAPIKeyNotFound,
+		},
+		{
+			name: "Delete non-existent API key",
+			path: fmt.Sprintf("/auth/api-keys/%d", 314159),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailAPIKeyNotFound,
+		},
+		{
+			name:           "Delete API key without authentication",
+			path:           fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey2.ID),
+			headers:        map[string]string{},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodDelete, TestServerURL+testcase.path, http.NoBody)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Add(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleCreateServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	testkitinternal.MustCreateUserServiceAccount(t, user.UUID, func(sa *auth.ServiceAccount) {
+		sa.Name = "ExistingServiceAccount"
+	})
+
+	testcases := []struct {
+		name            string
+		headers         map[string]string
+		requestBody     string
+		wantStatusCode  int
+		wantName        string
+		wantDescription string
+		wantScopes      []string
+		wantErrCode     string
+		wantErrDetail   string
+	}{
+		{
+			name: "Valid request with default scopes",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "deploy-pipeline",
+					"description": "Used by the deploy pipeline"
+				}
+			`,
+			wantStatusCode:  http.StatusCreated,
+			wantName:        "deploy-pipeline",
+			wantDescription: "Used by the deploy pipeline",
+			wantScopes:      []string{"admin"},
+			wantErrCode:     "",
+			wantErrDetail:   "",
+		},
+		{
+			name: "Valid request with scopes",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
 			},
-			wantErrCode:   "",
-			wantErrDetail: "",
+			requestBody: `
+				{
+					"name": "edge-evaluator",
+					"scopes": ["flags:evaluate"]
+				}
+			`,
+			wantStatusCode:  http.StatusCreated,
+			wantName:        "edge-evaluator",
+			wantDescription: "",
+			wantScopes:      []string{"flags:evaluate"},
+			wantErrCode:     "",
+			wantErrDetail:   "",
 		},
 		{
-			name: "Name missing",
+			name: "Invalid scope",
 			headers: map[string]string{
 				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
 			},
-			requestBody: fmt.Sprintf(`
+			requestBody: `
 				{
-					"expires_at": "%s"
+					"name": "invalid-scope",
+					"scopes": ["flags:destroy"]
 				}
-			`, expirationDateString),
+			`,
 			wantStatusCode: http.StatusBadRequest,
-			wantAPIKeyName: "My nameless API key",
-			wantExpirationDate: pgtype.Timestamp{
-				Time:  expirationDate,
-				Valid: true,
-			},
-			wantErrCode:   api.ErrCodeInvalidRequest,
-			wantErrDetail: api.ErrDetailInvalidRequestData,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidAPIKeyScope,
 		},
 		{
-			name: "Invalid expiration date",
+			name: "Missing name",
 			headers: map[string]string{
 				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
 			},
 			requestBody: `
 				{
-					"name": "My invalidly-expiring API key",
-					"expires_at": "1nv4l1dd4t3"
+					"description": "Nameless"
 				}
 			`,
 			wantStatusCode: http.StatusBadRequest,
-			wantAPIKeyName: "My invalidly-expiring API key",
-			wantExpirationDate: pgtype.Timestamp{
-				Valid: false,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Existing name",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
 			},
-			wantErrCode:   api.ErrCodeInvalidRequest,
-			wantErrDetail: api.ErrDetailInvalidRequestData,
+			requestBody: `
+				{
+					"name": "ExistingServiceAccount"
+				}
+			`,
+			wantStatusCode: http.StatusConflict,
+			wantErrCode:    api.ErrCodeResourceExists,
+			wantErrDetail:  api.ErrDetailServiceAccountExists,
 		},
 		{
 			name:    "Unauthenticated request",
 			headers: map[string]string{},
 			requestBody: `
 				{
-					"name": "My unauthenticated API key"
+					"name": "unauthenticated"
 				}
 			`,
 			wantStatusCode: http.StatusUnauthorized,
-			wantAPIKeyName: "My unauthenticated API key",
-			wantExpirationDate: pgtype.Timestamp{
-				Valid: false,
-			},
-			wantErrCode:   api.ErrCodeMissingCredentials,
-			wantErrDetail: api.ErrDetailMissingCredentials,
+			wantErrCode:    api.ErrCodeMissingCredentials,
+			wantErrDetail:  api.ErrDetailMissingCredentials,
 		},
 	}
 
@@ -931,7 +4953,7 @@ func TestHandleCreateAPIKey(t *testing.T) {
 
 			req, err := http.NewRequest(
 				http.MethodPost,
-				TestServerURL+"/auth/api-keys",
+				TestServerURL+"/auth/service-accounts",
 				bytes.NewReader([]byte(testcase.requestBody)),
 			)
 			require.NoError(t, err)
@@ -940,7 +4962,7 @@ func TestHandleCreateAPIKey(t *testing.T) {
 				req.Header.Add(key, value)
 			}
 
-			apiKeyCreatedAt := time.Now().UTC()
+			serviceAccountCreatedAt := time.Now().UTC()
 			res, err := httpClient.Do(req)
 			require.NoError(t, err)
 			t.Cleanup(func() {
@@ -951,17 +4973,18 @@ func TestHandleCreateAPIKey(t *testing.T) {
 			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
 
 			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
-				var createAPIKeyResp api.CreateAPIKeyResponse
-				err = json.NewDecoder(res.Body).Decode(&createAPIKeyResp)
+				var createServiceAccountResp api.CreateServiceAccountResponse
+				err = json.NewDecoder(res.Body).Decode(&createServiceAccountResp)
 				require.NoError(t, err)
 
-				require.Equal(t, user.UUID, createAPIKeyResp.UserUUID)
-				require.Equal(t, testcase.wantAPIKeyName, createAPIKeyResp.Name)
-				testkit.RequireTimeAlmostEqual(t, apiKeyCreatedAt, createAPIKeyResp.CreatedAt)
-				require.Equal(t, testcase.wantExpirationDate.Valid, createAPIKeyResp.ExpiresAt.Valid)
-				if testcase.wantExpirationDate.Valid {
-					require.Equal(t, testcase.wantExpirationDate, createAPIKeyResp.ExpiresAt)
-				}
+				_, err = uuid.Parse(createServiceAccountResp.UUID)
+				require.NoError(t, err)
+				require.Equal(t, user.UUID, createServiceAccountResp.OwnerUUID)
+				require.Equal(t, testcase.wantName, createServiceAccountResp.Name)
+				require.Equal(t, testcase.wantDescription, createServiceAccountResp.Description)
+				require.Equal(t, testcase.wantScopes, createServiceAccountResp.Scopes)
+				require.True(t, createServiceAccountResp.IsActive)
+				testkit.RequireTimeAlmostEqual(t, serviceAccountCreatedAt, createServiceAccountResp.CreatedAt)
 			} else {
 				var errResp api.ErrorResponse
 				err = json.NewDecoder(res.Body).Decode(&errResp)
@@ -974,7 +4997,7 @@ func TestHandleCreateAPIKey(t *testing.T) {
 	}
 }
 
-func TestHandleListAPIKeys(t *testing.T) {
+func TestHandleListServiceAccounts(t *testing.T) {
 	t.Parallel()
 
 	httpClient := httputils.NewHTTPClient(nil)
@@ -982,50 +5005,49 @@ func TestHandleListAPIKeys(t *testing.T) {
 	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
-	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(activeUser.UUID)
-	activeUserAPIKey, activeUserRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, nil)
+	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, activeUser.UUID)
+	activeUserServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, activeUser.UUID, nil)
 
-	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
-		u.IsActive = false
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
 	})
-	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(inactiveUser.UUID)
-	testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+	otherUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, otherUser.UUID)
 
 	testcases := []struct {
-		name                  string
-		headers               map[string]string
-		wantStatusCode        int
-		wantAPIKeysInResponse bool
-		wantErrCode           string
-		wantErrDetail         string
+		name                          string
+		headers                       map[string]string
+		wantStatusCode                int
+		wantServiceAccountsInResponse bool
+		wantErrCode                   string
+		wantErrDetail                 string
 	}{
 		{
-			name: "List API keys for active user",
+			name: "List service accounts for user with service accounts",
 			headers: map[string]string{
 				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
 			},
-			wantStatusCode:        http.StatusOK,
-			wantAPIKeysInResponse: true,
-			wantErrCode:           "",
-			wantErrDetail:         "",
+			wantStatusCode:                http.StatusOK,
+			wantServiceAccountsInResponse: true,
+			wantErrCode:                   "",
+			wantErrDetail:                 "",
 		},
 		{
-			name: "List API keys for inactive user",
+			name: "List service accounts for user without service accounts",
 			headers: map[string]string{
-				"Authorization": fmt.Sprintf("Bearer %s", inactiveUserAccessJWT),
+				"Authorization": fmt.Sprintf("Bearer %s", otherUserAccessJWT),
 			},
-			wantStatusCode:        http.StatusOK,
-			wantAPIKeysInResponse: false,
-			wantErrCode:           api.ErrCodeResourceNotFound,
-			wantErrDetail:         api.ErrDetailUserNotFound,
+			wantStatusCode:                http.StatusOK,
+			wantServiceAccountsInResponse: false,
+			wantErrCode:                   "",
+			wantErrDetail:                 "",
 		},
 		{
-			name:                  "List API keys without authentication",
-			headers:               map[string]string{},
-			wantStatusCode:        http.StatusUnauthorized,
-			wantAPIKeysInResponse: false,
-			wantErrCode:           api.ErrCodeMissingCredentials,
-			wantErrDetail:         api.ErrDetailMissingCredentials,
+			name:                          "List service accounts without authentication",
+			headers:                       map[string]string{},
+			wantStatusCode:                http.StatusUnauthorized,
+			wantServiceAccountsInResponse: false,
+			wantErrCode:                   api.ErrCodeMissingCredentials,
+			wantErrDetail:                 api.ErrDetailMissingCredentials,
 		},
 	}
 
@@ -1035,7 +5057,7 @@ func TestHandleListAPIKeys(t *testing.T) {
 
 			req, err := http.NewRequest(
 				http.MethodGet,
-				TestServerURL+"/auth/api-keys",
+				TestServerURL+"/auth/service-accounts",
 				http.NoBody,
 			)
 			require.NoError(t, err)
@@ -1054,20 +5076,19 @@ func TestHandleListAPIKeys(t *testing.T) {
 			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
 
 			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
-				var listAPIKeysResp api.ListAPIKeysResponse
-				err = json.NewDecoder(res.Body).Decode(&listAPIKeysResp)
+				var listServiceAccountsResp api.ListServiceAccountsResponse
+				err = json.NewDecoder(res.Body).Decode(&listServiceAccountsResp)
 				require.NoError(t, err)
 
-				if testcase.wantAPIKeysInResponse {
-					require.Len(t, listAPIKeysResp.Keys, 1)
-					require.Equal(t, activeUserAPIKey.ID, listAPIKeysResp.Keys[0].ID)
-					require.Equal(t, activeUser.UUID, listAPIKeysResp.Keys[0].UserUUID)
-					require.True(t, strings.HasPrefix(activeUserRawAPIKey, listAPIKeysResp.Keys[0].Prefix))
-					require.Equal(t, activeUserAPIKey.Name, listAPIKeysResp.Keys[0].Name)
-					require.Equal(t, activeUserAPIKey.CreatedAt, listAPIKeysResp.Keys[0].CreatedAt)
-					require.Equal(t, activeUserAPIKey.ExpiresAt, listAPIKeysResp.Keys[0].ExpiresAt)
+				if testcase.wantServiceAccountsInResponse {
+					require.Len(t, listServiceAccountsResp.ServiceAccounts, 1)
+					require.Equal(t, activeUserServiceAccount.UUID, listServiceAccountsResp.ServiceAccounts[0].UUID)
+					require.Equal(t, activeUser.UUID, listServiceAccountsResp.ServiceAccounts[0].OwnerUUID)
+					require.Equal(t, activeUserServiceAccount.Name, listServiceAccountsResp.ServiceAccounts[0].Name)
+					require.Equal(t, activeUserServiceAccount.Scopes, listServiceAccountsResp.ServiceAccounts[0].Scopes)
+					require.True(t, listServiceAccountsResp.ServiceAccounts[0].IsActive)
 				} else {
-					require.Len(t, listAPIKeysResp.Keys, 0)
+					require.Len(t, listServiceAccountsResp.ServiceAccounts, 0)
 				}
 			} else {
 				var errResp api.ErrorResponse
@@ -1081,27 +5102,177 @@ func TestHandleListAPIKeys(t *testing.T) {
 	}
 }
 
-func TestHandleDeleteAPIKey(t *testing.T) {
+func TestHandleUpdateServiceAccount(t *testing.T) {
 	t.Parallel()
 
 	httpClient := httputils.NewHTTPClient(nil)
 
-	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
-	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(activeUser.UUID)
-	activeUserAPIKey1, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
-		k.Name = "MyAPIKey1"
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	serviceAccount1 := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, func(sa *auth.ServiceAccount) {
+		sa.Name = "MyServiceAccount1"
 	})
-	activeUserAPIKey2, _ := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
-		k.Name = "MyAPIKey2"
+	serviceAccount2 := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, func(sa *auth.ServiceAccount) {
+		sa.Name = "MyServiceAccount2"
+	})
+	serviceAccount3 := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, func(sa *auth.ServiceAccount) {
+		sa.Name = "MyServiceAccount3"
 	})
 
-	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
-		u.IsActive = false
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
 	})
-	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(inactiveUser.UUID)
-	inactiveUserAPIKey, _ := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
+	otherUserServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, otherUser.UUID, nil)
+
+	testcases := []struct {
+		name            string
+		path            string
+		requestBody     string
+		wantStatusCode  int
+		wantName        string
+		wantDescription string
+		wantIsActive    bool
+		wantErrCode     string
+		wantErrDetail   string
+	}{
+		{
+			name: "Rename and describe service account",
+			path: "/auth/service-accounts/" + serviceAccount1.UUID,
+			requestBody: `
+				{
+					"name": "MyRenamedServiceAccount",
+					"description": "Renamed"
+				}
+			`,
+			wantStatusCode:  http.StatusOK,
+			wantName:        "MyRenamedServiceAccount",
+			wantDescription: "Renamed",
+			wantIsActive:    true,
+			wantErrCode:     "",
+			wantErrDetail:   "",
+		},
+		{
+			name: "Deactivate service account",
+			path: "/auth/service-accounts/" + serviceAccount2.UUID,
+			requestBody: `
+				{
+					"is_active": false
+				}
+			`,
+			wantStatusCode:  http.StatusOK,
+			wantName:        "MyServiceAccount2",
+			wantDescription: "",
+			wantIsActive:    false,
+			wantErrCode:     "",
+			wantErrDetail:   "",
+		},
+		{
+			name: "Rename to existing name",
+			path: "/auth/service-accounts/" + serviceAccount3.UUID,
+			requestBody: `
+				{
+					"name": "MyServiceAccount2"
+				}
+			`,
+			wantStatusCode: http.StatusConflict,
+			wantErrCode:    api.ErrCodeResourceExists,
+			wantErrDetail:  api.ErrDetailServiceAccountExists,
+		},
+		{
+			name: "No attributes to update",
+			path: "/auth/service-accounts/" + serviceAccount3.UUID,
+			requestBody: `
+				{}
+			`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    api.ErrCodeInvalidRequest,
+			wantErrDetail:  api.ErrDetailInvalidRequestData,
+		},
+		{
+			name: "Update service account owned by another user",
+			path: "/auth/service-accounts/" + otherUserServiceAccount.UUID,
+			requestBody: `
+				{
+					"is_active": false
+				}
+			`,
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailServiceAccountNotFound,
+		},
+		{
+			name: "Update service account with invalid UUID",
+			path: "/auth/service-accounts/1nv4l1d",
+			requestBody: `
+				{
+					"is_active": false
+				}
+			`,
+			wantStatusCode: http.StatusNotFound,
+			wantErrCode:    api.ErrCodeResourceNotFound,
+			wantErrDetail:  api.ErrDetailServiceAccountNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodPut,
+				TestServerURL+testcase.path,
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", userAccessJWT))
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				var getServiceAccountResp api.GetServiceAccountResponse
+				err = json.NewDecoder(res.Body).Decode(&getServiceAccountResp)
+				require.NoError(t, err)
+
+				require.Equal(t, user.UUID, getServiceAccountResp.OwnerUUID)
+				require.Equal(t, testcase.wantName, getServiceAccountResp.Name)
+				require.Equal(t, testcase.wantDescription, getServiceAccountResp.Description)
+				require.Equal(t, testcase.wantIsActive, getServiceAccountResp.IsActive)
+			} else {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, testcase.wantErrCode, errResp.Code)
+				require.Equal(t, testcase.wantErrDetail, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestHandleDeleteServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, user.UUID, nil)
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUserServiceAccount := testkitinternal.MustCreateUserServiceAccount(t, otherUser.UUID, nil)
 
 	testcases := []struct {
 		name           string
@@ -1112,48 +5283,38 @@ func TestHandleDeleteAPIKey(t *testing.T) {
 		wantErrDetail  string
 	}{
 		{
-			name: "Delete API key for active user",
-			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey1.ID),
+			name: "Delete service account",
+			path: "/auth/service-accounts/" + serviceAccount.UUID,
 			headers: map[string]string{
-				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
 			},
 			wantStatusCode: http.StatusNoContent,
 			wantErrCode:    "",
 			wantErrDetail:  "",
 		},
 		{
-			name: "Delete API key for inactive user",
-			path: fmt.Sprintf("/auth/api-keys/%d", inactiveUserAPIKey.ID),
-			headers: map[string]string{
-				"Authorization": fmt.Sprintf("Bearer %s", inactiveUserAccessJWT),
-			},
-			wantStatusCode: http.StatusNotFound,
-			wantErrCode:    api.ErrCodeResourceNotFound,
-			wantErrDetail:  api.ErrDetailAPIKeyNotFound,
-		},
-		{
-			name: "Delete API key for another user",
-			path: fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey2.ID),
+			name: "Delete service account owned by another user",
+			path: "/auth/service-accounts/" + otherUserServiceAccount.UUID,
 			headers: map[string]string{
-				"Authorization": fmt.Sprintf("Bearer %s", inactiveUserAccessJWT),
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
 			},
 			wantStatusCode: http.StatusNotFound,
 			wantErrCode:    api.ErrCodeResourceNotFound,
-			wantErrDetail:  api.ErrDetailAPIKeyNotFound,
+			wantErrDetail:  api.ErrDetailServiceAccountNotFound,
 		},
 		{
-			name: "Delete non-existent API key",
-			path: fmt.Sprintf("/auth/api-keys/%d", 314159),
+			name: "Delete non-existent service account",
+			path: "/auth/service-accounts/" + uuid.NewString(),
 			headers: map[string]string{
-				"Authorization": fmt.Sprintf("Bearer %s", activeUserAccessJWT),
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
 			},
 			wantStatusCode: http.StatusNotFound,
 			wantErrCode:    api.ErrCodeResourceNotFound,
-			wantErrDetail:  api.ErrDetailAPIKeyNotFound,
+			wantErrDetail:  api.ErrDetailServiceAccountNotFound,
 		},
 		{
-			name:           "Delete API key without authentication",
-			path:           fmt.Sprintf("/auth/api-keys/%d", activeUserAPIKey2.ID),
+			name:           "Delete service account without authentication",
+			path:           "/auth/service-accounts/" + otherUserServiceAccount.UUID,
 			headers:        map[string]string{},
 			wantStatusCode: http.StatusUnauthorized,
 			wantErrCode:    api.ErrCodeMissingCredentials,
@@ -1191,3 +5352,55 @@ func TestHandleDeleteAPIKey(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleGetJWKS(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	keySet := testkitinternal.MustCreateKeySet(config)
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	accessToken, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+
+	req, err := http.NewRequest(http.MethodGet, TestServerURL+jwks.Path, nil)
+	require.NoError(t, err)
+
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := res.Body.Close()
+		require.NoError(t, err)
+	})
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "public, max-age=300", res.Header.Get("Cache-Control"))
+
+	var jsonWebKeySet jwks.JSONWebKeySet
+	err = json.NewDecoder(res.Body).Decode(&jsonWebKeySet)
+	require.NoError(t, err)
+
+	require.Equal(t, keySet.JSONWebKeySet(), &jsonWebKeySet)
+	require.Len(t, jsonWebKeySet.Keys, 2)
+
+	// validate access JWT using only the published keys, as another service would
+	claims := &api.AuthJWTClaims{}
+	parsedToken, err := jwt.ParseWithClaims(accessToken, claims, func(t *jwt.Token) (any, error) {
+		for _, key := range jsonWebKeySet.Keys {
+			if key.KeyID == t.Header["kid"] && key.Algorithm == t.Method.Alg() {
+				return key.PublicKey()
+			}
+		}
+
+		return nil, fmt.Errorf("unknown key ID %v", t.Header["kid"])
+	})
+	require.NoError(t, err)
+
+	require.True(t, parsedToken.Valid)
+	require.Equal(t, jsonWebKeySet.Keys[0].KeyID, parsedToken.Header["kid"])
+	require.Equal(t, user.UUID, claims.Subject)
+}
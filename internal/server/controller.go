@@ -1,19 +1,24 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 	"sync"
 
+	"github.com/alvii147/flagger-api/internal/audit"
 	"github.com/alvii147/flagger-api/internal/auth"
 	"github.com/alvii147/flagger-api/internal/database"
 	"github.com/alvii147/flagger-api/internal/env"
 	"github.com/alvii147/flagger-api/internal/flags"
+	"github.com/alvii147/flagger-api/internal/ratelimit"
 	"github.com/alvii147/flagger-api/internal/templatesmanager"
 	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/alvii147/flagger-api/pkg/jwks"
 	"github.com/alvii147/flagger-api/pkg/logging"
 	"github.com/alvii147/flagger-api/pkg/mailclient"
+	"github.com/alvii147/flagger-api/pkg/passhash"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -26,14 +31,23 @@ type Controller interface {
 
 // controller implements Controller.
 type controller struct {
-	config       *env.Config
-	router       httputils.Router
-	dbPool       *pgxpool.Pool
-	logger       logging.Logger
-	mailClient   mailclient.Client
-	tmplManager  templatesmanager.Manager
-	authService  auth.Service
-	flagsService flags.Service
+	config           *env.Config
+	keySet           *jwks.KeySet
+	router           httputils.Router
+	handler          http.Handler
+	dbPool           *pgxpool.Pool
+	logger           logging.Logger
+	mailClient       mailclient.Client
+	tmplManager      templatesmanager.Manager
+	authService      auth.Service
+	flagsService     flags.Service
+	auditService     audit.Service
+	rateLimitService ratelimit.Service
+	rateLimitStore   httputils.RateLimitStore
+	corsOrigins      []string
+	jobsCtx          context.Context
+	cancelJobs       context.CancelFunc
+	jobsWG           sync.WaitGroup
 }
 
 // NewController sets up the server and returns a new controller.
@@ -43,6 +57,16 @@ func NewController() (*controller, error) {
 		return nil, fmt.Errorf("NewController failed to env.NewConfig: %w", err)
 	}
 
+	trustedProxies, err := httputils.ParseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("NewController failed to httputils.ParseTrustedProxies: %w", err)
+	}
+
+	corsAllowedOrigins, err := httputils.ParseCORSOrigins(config.CORSAllowedOrigins)
+	if err != nil {
+		return nil, fmt.Errorf("NewController failed to httputils.ParseCORSOrigins: %w", err)
+	}
+
 	router := httputils.NewRouter()
 	dbPool, err := database.CreatePool(
 		config.PostgresHostname,
@@ -74,11 +98,33 @@ func NewController() (*controller, error) {
 		return nil, fmt.Errorf("NewController failed, unknown mail client type %s", config.MailClientType)
 	}
 
+	switch config.PasswordHasherType {
+	case passhash.HasherTypeArgon2id, passhash.HasherTypeBcrypt:
+	default:
+		return nil, fmt.Errorf("NewController failed, unknown password hasher type %s", config.PasswordHasherType)
+	}
+
+	switch config.AuthTokenTransport {
+	case auth.TokenTransportHeader, auth.TokenTransportCookie:
+	default:
+		return nil, fmt.Errorf("NewController failed, unknown auth token transport %s", config.AuthTokenTransport)
+	}
+
+	if config.AuthAccessTokenCookie && config.AuthTokenTransport != auth.TokenTransportCookie {
+		return nil, fmt.Errorf("NewController failed, access token cookie requires auth token transport %s", auth.TokenTransportCookie)
+	}
+
 	tmplManager := templatesmanager.NewManager()
 
+	keySet, err := auth.NewKeySet(config)
+	if err != nil {
+		return nil, fmt.Errorf("NewController failed to auth.NewKeySet: %w", err)
+	}
+
 	authRepository := auth.NewRepository()
 	authService := auth.NewService(
 		config,
+		keySet,
 		dbPool,
 		logger,
 		mailClient,
@@ -89,18 +135,47 @@ func NewController() (*controller, error) {
 	flagsRepository := flags.NewRepository()
 	flagsService := flags.NewService(dbPool, flagsRepository)
 
+	auditRepository := audit.NewRepository()
+	auditService := audit.NewService(dbPool, logger, auditRepository)
+
+	rateLimitRepository := ratelimit.NewRepository()
+	rateLimitService := ratelimit.NewService(dbPool, rateLimitRepository)
+
+	var rateLimitStore httputils.RateLimitStore
+	switch config.RateLimitStoreType {
+	case ratelimit.StoreTypePostgres:
+		rateLimitStore = rateLimitService
+	case ratelimit.StoreTypeInMemory:
+		rateLimitStore = httputils.NewInMemRateLimitStore()
+	case ratelimit.StoreTypeNone:
+		rateLimitStore = nil
+	default:
+		return nil, fmt.Errorf("NewController failed, unknown rate limit store type %s", config.RateLimitStoreType)
+	}
+
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+
 	ctrl := &controller{
-		config:       config,
-		router:       router,
-		dbPool:       dbPool,
-		logger:       logger,
-		mailClient:   mailClient,
-		tmplManager:  tmplManager,
-		authService:  authService,
-		flagsService: flagsService,
+		config:           config,
+		keySet:           keySet,
+		router:           router,
+		handler:          httputils.ClientIPHandler(router, trustedProxies),
+		dbPool:           dbPool,
+		logger:           logger,
+		mailClient:       mailClient,
+		tmplManager:      tmplManager,
+		authService:      authService,
+		flagsService:     flagsService,
+		auditService:     auditService,
+		rateLimitService: rateLimitService,
+		rateLimitStore:   rateLimitStore,
+		corsOrigins:      corsAllowedOrigins,
+		jobsCtx:          jobsCtx,
+		cancelJobs:       cancelJobs,
 	}
 
 	ctrl.route()
+	ctrl.startJobs()
 
 	return ctrl, nil
 }
@@ -109,7 +184,7 @@ func NewController() (*controller, error) {
 func (ctrl *controller) Serve() error {
 	addr := fmt.Sprintf("%s:%d", ctrl.config.Hostname, ctrl.config.Port)
 	ctrl.logger.LogInfo("Server running on", addr)
-	err := http.ListenAndServe(addr, ctrl.router)
+	err := http.ListenAndServe(addr, ctrl.handler)
 	if err != nil {
 		return fmt.Errorf("Serve failed to http.ListenAndServe %s: %w", addr, err)
 	}
@@ -119,11 +194,17 @@ func (ctrl *controller) Serve() error {
 
 // ServeHTTP takes in a given request and writes a response.
 func (ctrl *controller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ctrl.router.ServeHTTP(w, r)
+	ctrl.handler.ServeHTTP(w, r)
 }
 
-// Close closes the Controller and its connections.
+// Close stops the Controller's background jobs and closes its connections.
 func (ctrl *controller) Close() {
+	ctrl.cancelJobs()
+	ctrl.jobsWG.Wait()
+
+	// write any API key usage buffered since the last flush before the database pool closes
+	ctrl.flushAPIKeyUsage(context.Background())
+
 	var wg sync.WaitGroup
 
 	wg.Add(1)
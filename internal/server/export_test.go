@@ -1,7 +1,8 @@
 package server
 
 var (
-	GetAPIKeyIDParam = getAPIKeyIDParam
-	GetFlagIDParam   = getFlagIDParam
-	GetFlagNameParam = getFlagNameParam
+	GetAPIKeyIDParam        = getAPIKeyIDParam
+	GetAPIKeyUsageDaysQuery = getAPIKeyUsageDaysQuery
+	GetFlagIDParam          = getFlagIDParam
+	GetFlagNameParam        = getFlagNameParam
 )
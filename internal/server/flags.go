@@ -69,7 +69,7 @@ func (ctrl *controller) handleCreateFlag(w *httputils.ResponseWriter, r *http.Re
 		return
 	}
 
-	flag, err := ctrl.flagsService.CreateFlag(r.Context(), string(req.Name))
+	flag, err := ctrl.flagsService.CreateFlag(r.Context(), string(req.Name), req.IsClientSide)
 	if err != nil {
 		ctrl.logger.LogWarn("handleCreateFlag failed to ctrl.flagsService.CreateFlag:", err)
 		w.WriteJSON(
@@ -83,12 +83,13 @@ func (ctrl *controller) handleCreateFlag(w *httputils.ResponseWriter, r *http.Re
 	}
 
 	responseBody := &api.CreateFlagResponse{
-		ID:        flag.ID,
-		UserUUID:  flag.UserUUID,
-		Name:      flag.Name,
-		IsEnabled: flag.IsEnabled,
-		CreatedAt: flag.CreatedAt,
-		UpdatedAt: flag.UpdatedAt,
+		ID:           flag.ID,
+		UserUUID:     flag.UserUUID,
+		Name:         flag.Name,
+		IsEnabled:    flag.IsEnabled,
+		IsClientSide: flag.IsClientSide,
+		CreatedAt:    flag.CreatedAt,
+		UpdatedAt:    flag.UpdatedAt,
 	}
 
 	w.WriteJSON(responseBody, http.StatusCreated)
@@ -135,12 +136,13 @@ func (ctrl *controller) handleGetFlagByID(w *httputils.ResponseWriter, r *http.R
 	}
 
 	resp := &api.GetFlagByIDResponse{
-		ID:        flag.ID,
-		UserUUID:  flag.UserUUID,
-		Name:      flag.Name,
-		IsEnabled: flag.IsEnabled,
-		CreatedAt: flag.CreatedAt,
-		UpdatedAt: flag.UpdatedAt,
+		ID:           flag.ID,
+		UserUUID:     flag.UserUUID,
+		Name:         flag.Name,
+		IsEnabled:    flag.IsEnabled,
+		IsClientSide: flag.IsClientSide,
+		CreatedAt:    flag.CreatedAt,
+		UpdatedAt:    flag.UpdatedAt,
 	}
 
 	w.WriteJSON(resp, http.StatusOK)
@@ -236,12 +238,13 @@ func (ctrl *controller) handleListFlags(w *httputils.ResponseWriter, r *http.Req
 
 	for i, flag := range flags {
 		responseBody.Flags[i] = &api.GetFlagByIDResponse{
-			ID:        flag.ID,
-			UserUUID:  flag.UserUUID,
-			Name:      flag.Name,
-			IsEnabled: flag.IsEnabled,
-			CreatedAt: flag.CreatedAt,
-			UpdatedAt: flag.UpdatedAt,
+			ID:           flag.ID,
+			UserUUID:     flag.UserUUID,
+			Name:         flag.Name,
+			IsEnabled:    flag.IsEnabled,
+			IsClientSide: flag.IsClientSide,
+			CreatedAt:    flag.CreatedAt,
+			UpdatedAt:    flag.UpdatedAt,
 		}
 	}
 
@@ -292,7 +295,7 @@ func (ctrl *controller) handleUpdateFlag(w *httputils.ResponseWriter, r *http.Re
 		return
 	}
 
-	flag, err := ctrl.flagsService.UpdateFlag(r.Context(), flagID, req.IsEnabled)
+	flag, err := ctrl.flagsService.UpdateFlag(r.Context(), flagID, req.IsEnabled, req.IsClientSide)
 	if err != nil {
 		ctrl.logger.LogError("handleUpdateFlag failed to ctrl.flagsService.UpdateFlag:", err)
 		switch {
@@ -317,13 +320,92 @@ func (ctrl *controller) handleUpdateFlag(w *httputils.ResponseWriter, r *http.Re
 	}
 
 	resp := &api.UpdateFlagResponse{
-		ID:        flag.ID,
-		UserUUID:  flag.UserUUID,
-		Name:      flag.Name,
-		IsEnabled: flag.IsEnabled,
-		CreatedAt: flag.CreatedAt,
-		UpdatedAt: flag.UpdatedAt,
+		ID:           flag.ID,
+		UserUUID:     flag.UserUUID,
+		Name:         flag.Name,
+		IsEnabled:    flag.IsEnabled,
+		IsClientSide: flag.IsClientSide,
+		CreatedAt:    flag.CreatedAt,
+		UpdatedAt:    flag.UpdatedAt,
+	}
+
+	w.WriteJSON(resp, http.StatusOK)
+}
+
+// handleEvaluateClientSideFlag handles evaluation of client-side Flag of currently authenticated User using Flag name.
+// Only the evaluated value is returned, and Flags that are not client-side evaluate to false as if they did not exist.
+// Methods: GET
+// URL: /api/client/flags/{name}
+func (ctrl *controller) handleEvaluateClientSideFlag(w *httputils.ResponseWriter, r *http.Request) {
+	flagName, err := getFlagNameParam(r)
+	if err != nil {
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInvalidRequest,
+				Detail: api.ErrDetailInvalidRequestData,
+			},
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	flag, err := ctrl.flagsService.GetClientSideFlagByName(r.Context(), flagName)
+	if err != nil {
+		switch {
+		case errors.Is(err, errutils.ErrFlagNotFound):
+			resp := &api.EvaluateClientSideFlagResponse{
+				Name:  flagName,
+				Value: false,
+			}
+			w.WriteJSON(resp, http.StatusOK)
+		default:
+			ctrl.logger.LogError("handleEvaluateClientSideFlag failed to ctrl.flagsService.GetClientSideFlagByName:", err)
+			w.WriteJSON(
+				api.ErrorResponse{
+					Code:   api.ErrCodeInternalServerError,
+					Detail: api.ErrDetailInternalServerError,
+				},
+				http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
+	resp := &api.EvaluateClientSideFlagResponse{
+		Name:  flag.Name,
+		Value: flag.IsEnabled,
 	}
 
 	w.WriteJSON(resp, http.StatusOK)
 }
+
+// handleEvaluateClientSideFlags handles evaluation of all client-side Flags of currently authenticated User.
+// Methods: GET
+// URL: /api/client/flags
+func (ctrl *controller) handleEvaluateClientSideFlags(w *httputils.ResponseWriter, r *http.Request) {
+	flags, err := ctrl.flagsService.ListClientSideFlags(r.Context())
+	if err != nil {
+		ctrl.logger.LogWarn("handleEvaluateClientSideFlags failed to ctrl.flagsService.ListClientSideFlags:", err)
+		w.WriteJSON(
+			api.ErrorResponse{
+				Code:   api.ErrCodeInternalServerError,
+				Detail: api.ErrDetailInternalServerError,
+			},
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	responseBody := &api.EvaluateClientSideFlagsResponse{
+		Flags: make([]*api.EvaluateClientSideFlagResponse, len(flags)),
+	}
+
+	for i, flag := range flags {
+		responseBody.Flags[i] = &api.EvaluateClientSideFlagResponse{
+			Name:  flag.Name,
+			Value: flag.IsEnabled,
+		}
+	}
+
+	w.WriteJSON(responseBody, http.StatusOK)
+}
@@ -2,6 +2,7 @@ package server_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -16,6 +17,7 @@ import (
 	"github.com/alvii147/flagger-api/pkg/api"
 	"github.com/alvii147/flagger-api/pkg/httputils"
 	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -129,16 +131,17 @@ func TestHandleCreateFlag(t *testing.T) {
 	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
-	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(user.UUID)
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
 
 	testcases := []struct {
-		name           string
-		headers        map[string]string
-		requestBody    string
-		wantStatusCode int
-		wantFlagName   string
-		wantErrCode    string
-		wantErrDetail  string
+		name             string
+		headers          map[string]string
+		requestBody      string
+		wantStatusCode   int
+		wantFlagName     string
+		wantIsClientSide bool
+		wantErrCode      string
+		wantErrDetail    string
 	}{
 		{
 			name: "Valid request",
@@ -150,10 +153,28 @@ func TestHandleCreateFlag(t *testing.T) {
 					"name": "my-flag"
 				}
 			`,
-			wantStatusCode: http.StatusCreated,
-			wantFlagName:   "my-flag",
-			wantErrCode:    "",
-			wantErrDetail:  "",
+			wantStatusCode:   http.StatusCreated,
+			wantFlagName:     "my-flag",
+			wantIsClientSide: false,
+			wantErrCode:      "",
+			wantErrDetail:    "",
+		},
+		{
+			name: "Valid client-side request",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", userAccessJWT),
+			},
+			requestBody: `
+				{
+					"name": "my-client-side-flag",
+					"is_client_side": true
+				}
+			`,
+			wantStatusCode:   http.StatusCreated,
+			wantFlagName:     "my-client-side-flag",
+			wantIsClientSide: true,
+			wantErrCode:      "",
+			wantErrDetail:    "",
 		},
 		{
 			name: "Name missing",
@@ -229,6 +250,7 @@ func TestHandleCreateFlag(t *testing.T) {
 				require.Equal(t, user.UUID, createFlagResp.UserUUID)
 				require.Equal(t, testcase.wantFlagName, createFlagResp.Name)
 				require.False(t, createFlagResp.IsEnabled)
+				require.Equal(t, testcase.wantIsClientSide, createFlagResp.IsClientSide)
 				testkit.RequireTimeAlmostEqual(t, flagCreatedAt, createFlagResp.CreatedAt)
 				testkit.RequireTimeAlmostEqual(t, flagCreatedAt, createFlagResp.UpdatedAt)
 			} else {
@@ -251,14 +273,14 @@ func TestHandleGetFlagByID(t *testing.T) {
 	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
-	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(activeUser.UUID)
-	activeUserFlag := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "active-user-flag")
+	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, activeUser.UUID)
+	activeUserFlag := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "active-user-flag", false)
 
 	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = false
 	})
-	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(inactiveUser.UUID)
-	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "inactive-user-flag")
+	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, inactiveUser.UUID)
+	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "inactive-user-flag", false)
 
 	testcases := []struct {
 		name           string
@@ -369,13 +391,16 @@ func TestHandleGetFlagByName(t *testing.T) {
 		u.IsActive = true
 	})
 	_, activeUserRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, nil)
-	activeUserFlag := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "active-user-flag")
+	_, activeUserEvaluateOnlyRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"flags:evaluate"}
+	})
+	activeUserFlag := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "active-user-flag", false)
 
 	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = false
 	})
 	_, inactiveUserRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, inactiveUser.UUID, nil)
-	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "inactive-user-flag")
+	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "inactive-user-flag", false)
 
 	testcases := []struct {
 		name           string
@@ -399,6 +424,18 @@ func TestHandleGetFlagByName(t *testing.T) {
 			wantErrCode:    "",
 			wantErrDetail:  "",
 		},
+		{
+			name: "Get flag for active user using evaluate-only API key",
+			path: fmt.Sprintf("/api/flags/%s", activeUserFlag.Name),
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("X-API-Key %s", activeUserEvaluateOnlyRawAPIKey),
+			},
+			wantStatusCode: http.StatusOK,
+			wantName:       activeUserFlag.Name,
+			wantValid:      true,
+			wantErrCode:    "",
+			wantErrDetail:  "",
+		},
 		{
 			name: "Get flag for inactive user",
 			path: fmt.Sprintf("/api/flags/%s", inactiveUserFlag.Name),
@@ -512,15 +549,15 @@ func TestHandleListFlags(t *testing.T) {
 	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
-	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(activeUser.UUID)
-	activeUserFlag1 := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "active-user-flag-1")
-	activeUserFlag2 := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "active-user-flag-2")
+	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, activeUser.UUID)
+	activeUserFlag1 := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "active-user-flag-1", false)
+	activeUserFlag2 := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "active-user-flag-2", false)
 
 	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = false
 	})
-	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(inactiveUser.UUID)
-	testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "inactive-user-flag-1")
+	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, inactiveUser.UUID)
+	testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "inactive-user-flag-1", false)
 
 	testcases := []struct {
 		name           string
@@ -626,14 +663,14 @@ func TestHandleUpdateFlag(t *testing.T) {
 	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = true
 	})
-	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(activeUser.UUID)
-	activeUserFlag := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "active-user-flag")
+	activeUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, activeUser.UUID)
+	activeUserFlag := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "active-user-flag", false)
 
 	inactiveUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
 		u.IsActive = false
 	})
-	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(inactiveUser.UUID)
-	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "inactive-user-flag-1")
+	inactiveUserAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, inactiveUser.UUID)
+	inactiveUserFlag := testkitinternal.MustCreateUserFlag(t, inactiveUser.UUID, "inactive-user-flag-1", false)
 
 	testcases := []struct {
 		name           string
@@ -787,3 +824,773 @@ func TestHandleUpdateFlag(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIKeyFlagRoutesRequireScopes(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "scoped-api-key-flag", false)
+	_, evaluateRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"flags:evaluate"}
+	})
+	_, readRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"flags:read"}
+	})
+	_, writeRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"flags:write"}
+	})
+	_, userReadRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"user:read"}
+	})
+	_, clientRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"flags:client"}
+	})
+
+	testcases := []struct {
+		name           string
+		method         string
+		path           string
+		rawAPIKey      string
+		requestBody    string
+		wantStatusCode int
+	}{
+		{
+			name:           "Evaluate-only API key cannot list flags",
+			method:         http.MethodGet,
+			path:           "/api/flags",
+			rawAPIKey:      evaluateRawAPIKey,
+			requestBody:    "",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Read-only API key can list flags",
+			method:         http.MethodGet,
+			path:           "/api/flags",
+			rawAPIKey:      readRawAPIKey,
+			requestBody:    "",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "Read-only API key can evaluate flags",
+			method:         http.MethodGet,
+			path:           fmt.Sprintf("/api/flags/%s", flag.Name),
+			rawAPIKey:      readRawAPIKey,
+			requestBody:    "",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "User-only API key cannot evaluate flags",
+			method:         http.MethodGet,
+			path:           fmt.Sprintf("/api/flags/%s", flag.Name),
+			rawAPIKey:      userReadRawAPIKey,
+			requestBody:    "",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Evaluate-only API key cannot create flags",
+			method:         http.MethodPost,
+			path:           "/api/flags",
+			rawAPIKey:      evaluateRawAPIKey,
+			requestBody:    `{"name": "evaluate-only-created-flag"}`,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Read-only API key cannot create flags",
+			method:         http.MethodPost,
+			path:           "/api/flags",
+			rawAPIKey:      readRawAPIKey,
+			requestBody:    `{"name": "read-only-created-flag"}`,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Write API key can create flags",
+			method:         http.MethodPost,
+			path:           "/api/flags",
+			rawAPIKey:      writeRawAPIKey,
+			requestBody:    `{"name": "write-created-flag"}`,
+			wantStatusCode: http.StatusCreated,
+		},
+		{
+			name:           "Client-side API key cannot list flags",
+			method:         http.MethodGet,
+			path:           "/api/flags",
+			rawAPIKey:      clientRawAPIKey,
+			requestBody:    "",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Client-side API key cannot evaluate flags through server-side route",
+			method:         http.MethodGet,
+			path:           fmt.Sprintf("/api/flags/%s", flag.Name),
+			rawAPIKey:      clientRawAPIKey,
+			requestBody:    "",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Client-side API key cannot read user",
+			method:         http.MethodGet,
+			path:           "/api/auth/users/me",
+			rawAPIKey:      clientRawAPIKey,
+			requestBody:    "",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Client-side API key can evaluate client-side flags",
+			method:         http.MethodGet,
+			path:           "/api/client/flags",
+			rawAPIKey:      clientRawAPIKey,
+			requestBody:    "",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "Evaluate-only API key cannot evaluate client-side flags",
+			method:         http.MethodGet,
+			path:           fmt.Sprintf("/api/client/flags/%s", flag.Name),
+			rawAPIKey:      evaluateRawAPIKey,
+			requestBody:    "",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Read-only API key cannot update flags",
+			method:         http.MethodPut,
+			path:           fmt.Sprintf("/api/flags/%d", flag.ID),
+			rawAPIKey:      readRawAPIKey,
+			requestBody:    `{"is_enabled": true}`,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				testcase.method,
+				TestServerURL+testcase.path,
+				bytes.NewReader([]byte(testcase.requestBody)),
+			)
+			require.NoError(t, err)
+
+			req.Header.Add("Authorization", fmt.Sprintf("X-API-Key %s", testcase.rawAPIKey))
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if testcase.wantStatusCode == http.StatusForbidden {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, api.ErrCodePermissionDenied, errResp.Code)
+				require.Equal(t, api.ErrDetailAPIKeyScopeDenied, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestAPIKeyFlagRoutesRequireAllowedIP(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, loopbackRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.AllowedCIDRs = []string{"127.0.0.0/8", "::1/128"}
+	})
+	_, externalRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.AllowedCIDRs = []string{"203.0.113.0/24"}
+	})
+
+	testcases := []struct {
+		name           string
+		rawAPIKey      string
+		forwardedFor   string
+		wantStatusCode int
+	}{
+		{
+			name:           "API key allowed from client IP",
+			rawAPIKey:      loopbackRawAPIKey,
+			forwardedFor:   "",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "API key denied from client IP",
+			rawAPIKey:      externalRawAPIKey,
+			forwardedFor:   "",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Forwarded IP from untrusted proxy is ignored for denied API key",
+			rawAPIKey:      externalRawAPIKey,
+			forwardedFor:   "203.0.113.7",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Forwarded IP from untrusted proxy is ignored for allowed API key",
+			rawAPIKey:      loopbackRawAPIKey,
+			forwardedFor:   "203.0.113.7",
+			wantStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				TestServerURL+"/api/flags",
+				http.NoBody,
+			)
+			require.NoError(t, err)
+
+			req.Header.Add("Authorization", fmt.Sprintf("X-API-Key %s", testcase.rawAPIKey))
+			if testcase.forwardedFor != "" {
+				req.Header.Add("X-Forwarded-For", testcase.forwardedFor)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+
+			if testcase.wantStatusCode == http.StatusForbidden {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, api.ErrCodePermissionDenied, errResp.Code)
+				require.Equal(t, api.ErrDetailAPIKeyIPDenied, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestAPIKeyFlagRoutesRequireAllowedOrigin(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, unrestrictedRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+	_, restrictedRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.AllowedOrigins = []string{"https://app.example.com"}
+	})
+
+	testcases := []struct {
+		name            string
+		rawAPIKey       string
+		origin          string
+		wantStatusCode  int
+		wantAllowOrigin string
+	}{
+		{
+			name:            "Unrestricted API key from any origin",
+			rawAPIKey:       unrestrictedRawAPIKey,
+			origin:          "https://evil.example.com",
+			wantStatusCode:  http.StatusOK,
+			wantAllowOrigin: "*",
+		},
+		{
+			name:            "Restricted API key from allowed origin",
+			rawAPIKey:       restrictedRawAPIKey,
+			origin:          "https://app.example.com",
+			wantStatusCode:  http.StatusOK,
+			wantAllowOrigin: "*",
+		},
+		{
+			name:            "Restricted API key without origin",
+			rawAPIKey:       restrictedRawAPIKey,
+			origin:          "",
+			wantStatusCode:  http.StatusOK,
+			wantAllowOrigin: "",
+		},
+		{
+			name:            "Restricted API key from disallowed origin",
+			rawAPIKey:       restrictedRawAPIKey,
+			origin:          "https://evil.example.com",
+			wantStatusCode:  http.StatusForbidden,
+			wantAllowOrigin: "*",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				TestServerURL+"/api/flags",
+				http.NoBody,
+			)
+			require.NoError(t, err)
+
+			req.Header.Add("Authorization", fmt.Sprintf("X-API-Key %s", testcase.rawAPIKey))
+			if testcase.origin != "" {
+				req.Header.Add("Origin", testcase.origin)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+			require.Equal(t, testcase.wantAllowOrigin, res.Header.Get("Access-Control-Allow-Origin"))
+
+			if testcase.wantStatusCode == http.StatusForbidden {
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(res.Body).Decode(&errResp)
+				require.NoError(t, err)
+
+				require.Equal(t, api.ErrCodePermissionDenied, errResp.Code)
+				require.Equal(t, api.ErrDetailAPIKeyOriginDenied, errResp.Detail)
+			}
+		})
+	}
+}
+
+func TestAPIKeyFlagRoutesPreflight(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	testcases := []struct {
+		name             string
+		path             string
+		requestMethod    string
+		wantAllowMethods string
+	}{
+		{
+			name:             "Preflight for flag evaluation",
+			path:             "/api/flags/my-flag",
+			requestMethod:    http.MethodGet,
+			wantAllowMethods: "GET, PUT",
+		},
+		{
+			name:             "Preflight for flag update",
+			path:             "/api/flags/42",
+			requestMethod:    http.MethodPut,
+			wantAllowMethods: "GET, PUT",
+		},
+		{
+			name:             "Preflight for flag creation",
+			path:             "/api/flags",
+			requestMethod:    http.MethodPost,
+			wantAllowMethods: "GET, POST",
+		},
+		{
+			name:             "Preflight for client-side flag evaluation",
+			path:             "/api/client/flags/my-flag",
+			requestMethod:    http.MethodGet,
+			wantAllowMethods: "GET",
+		},
+		{
+			name:             "Preflight for client-side flags evaluation",
+			path:             "/api/client/flags",
+			requestMethod:    http.MethodGet,
+			wantAllowMethods: "GET",
+		},
+		{
+			name:             "Preflight for current user",
+			path:             "/api/auth/users/me",
+			requestMethod:    http.MethodGet,
+			wantAllowMethods: "GET",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodOptions,
+				TestServerURL+testcase.path,
+				http.NoBody,
+			)
+			require.NoError(t, err)
+
+			req.Header.Add("Origin", "https://app.example.com")
+			req.Header.Add("Access-Control-Request-Method", testcase.requestMethod)
+			req.Header.Add("Access-Control-Request-Headers", "authorization")
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, http.StatusNoContent, res.StatusCode)
+			require.Equal(t, "*", res.Header.Get("Access-Control-Allow-Origin"))
+			require.Equal(t, testcase.wantAllowMethods, res.Header.Get("Access-Control-Allow-Methods"))
+			require.Equal(t, "Authorization, Content-Type", res.Header.Get("Access-Control-Allow-Headers"))
+			require.Equal(t, "600", res.Header.Get("Access-Control-Max-Age"))
+		})
+	}
+}
+
+func TestHandleUpdateFlagClientSide(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	userAccessJWT, _ := testkitinternal.MustCreateUserAuthJWTs(t, user.UUID)
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, "my-flag", false)
+
+	// requests are sent in order, since each one updates the same flag
+	steps := []struct {
+		requestBody      string
+		wantIsEnabled    bool
+		wantIsClientSide bool
+	}{
+		{
+			requestBody:      `{"is_enabled": true, "is_client_side": true}`,
+			wantIsEnabled:    true,
+			wantIsClientSide: true,
+		},
+		{
+			requestBody:      `{"is_enabled": false}`,
+			wantIsEnabled:    false,
+			wantIsClientSide: true,
+		},
+		{
+			requestBody:      `{"is_enabled": false, "is_client_side": false}`,
+			wantIsEnabled:    false,
+			wantIsClientSide: false,
+		},
+	}
+
+	for _, step := range steps {
+		req, err := http.NewRequest(
+			http.MethodPut,
+			TestServerURL+fmt.Sprintf("/flags/%d", flag.ID),
+			bytes.NewReader([]byte(step.requestBody)),
+		)
+		require.NoError(t, err)
+
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", userAccessJWT))
+
+		res, err := httpClient.Do(req)
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var updateFlagResp api.UpdateFlagResponse
+		err = json.NewDecoder(res.Body).Decode(&updateFlagResp)
+		require.NoError(t, err)
+
+		err = res.Body.Close()
+		require.NoError(t, err)
+
+		require.Equal(t, flag.ID, updateFlagResp.ID)
+		require.Equal(t, step.wantIsEnabled, updateFlagResp.IsEnabled)
+		require.Equal(t, step.wantIsClientSide, updateFlagResp.IsClientSide)
+	}
+}
+
+func TestHandleEvaluateClientSideFlag(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	activeUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, activeUserClientRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, activeUser.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"flags:client"}
+	})
+	enabledClientSideFlag := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "enabled-client-side-flag", true)
+	disabledClientSideFlag := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "disabled-client-side-flag", true)
+	enabledPrivateFlag := testkitinternal.MustCreateUserFlag(t, activeUser.UUID, "enabled-private-flag", false)
+
+	otherUser, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	otherUserFlag := testkitinternal.MustCreateUserFlag(t, otherUser.UUID, "other-user-client-side-flag", true)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := flags.NewRepository()
+	for _, flag := range []*flags.Flag{enabledClientSideFlag, enabledPrivateFlag, otherUserFlag} {
+		_, err := repo.UpdateFlag(dbConn, flag.ID, flag.UserUUID, pgtype.Text{}, true, nil)
+		require.NoError(t, err)
+	}
+
+	testcases := []struct {
+		name           string
+		flagName       string
+		rawAPIKey      string
+		wantStatusCode int
+		wantValue      bool
+	}{
+		{
+			name:           "Enabled client-side flag",
+			flagName:       enabledClientSideFlag.Name,
+			rawAPIKey:      activeUserClientRawAPIKey,
+			wantStatusCode: http.StatusOK,
+			wantValue:      true,
+		},
+		{
+			name:           "Disabled client-side flag",
+			flagName:       disabledClientSideFlag.Name,
+			rawAPIKey:      activeUserClientRawAPIKey,
+			wantStatusCode: http.StatusOK,
+			wantValue:      false,
+		},
+		{
+			name:           "Enabled private flag",
+			flagName:       enabledPrivateFlag.Name,
+			rawAPIKey:      activeUserClientRawAPIKey,
+			wantStatusCode: http.StatusOK,
+			wantValue:      false,
+		},
+		{
+			name:           "Client-side flag of another user",
+			flagName:       otherUserFlag.Name,
+			rawAPIKey:      activeUserClientRawAPIKey,
+			wantStatusCode: http.StatusOK,
+			wantValue:      false,
+		},
+		{
+			name:           "Non-existent flag",
+			flagName:       "non-existent-flag",
+			rawAPIKey:      activeUserClientRawAPIKey,
+			wantStatusCode: http.StatusOK,
+			wantValue:      false,
+		},
+		{
+			name:           "Invalid API key",
+			flagName:       enabledClientSideFlag.Name,
+			rawAPIKey:      "invalid-api-key",
+			wantStatusCode: http.StatusUnauthorized,
+			wantValue:      false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				TestServerURL+fmt.Sprintf("/api/client/flags/%s", testcase.flagName),
+				http.NoBody,
+			)
+			require.NoError(t, err)
+
+			req.Header.Add("Authorization", fmt.Sprintf("X-API-Key %s", testcase.rawAPIKey))
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+			if !httputils.IsHTTPSuccess(testcase.wantStatusCode) {
+				return
+			}
+
+			// only the flag name and its evaluated value are exposed
+			var respBody map[string]any
+			err = json.NewDecoder(res.Body).Decode(&respBody)
+			require.NoError(t, err)
+
+			require.Equal(t, map[string]any{
+				"name":  testcase.flagName,
+				"value": testcase.wantValue,
+			}, respBody)
+		})
+	}
+}
+
+func TestHandleEvaluateClientSideFlags(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	_, clientRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, user.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"flags:client"}
+	})
+	enabledClientSideFlag := testkitinternal.MustCreateUserFlag(t, user.UUID, "enabled-client-side-flag", true)
+	testkitinternal.MustCreateUserFlag(t, user.UUID, "disabled-client-side-flag", true)
+	enabledPrivateFlag := testkitinternal.MustCreateUserFlag(t, user.UUID, "enabled-private-flag", false)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := flags.NewRepository()
+	for _, flag := range []*flags.Flag{enabledClientSideFlag, enabledPrivateFlag} {
+		_, err := repo.UpdateFlag(dbConn, flag.ID, flag.UserUUID, pgtype.Text{}, true, nil)
+		require.NoError(t, err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, TestServerURL+"/api/client/flags", http.NoBody)
+	require.NoError(t, err)
+
+	req.Header.Add("Authorization", fmt.Sprintf("X-API-Key %s", clientRawAPIKey))
+	req.Header.Add("Origin", "https://app.example.com")
+
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := res.Body.Close()
+		require.NoError(t, err)
+	})
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "*", res.Header.Get("Access-Control-Allow-Origin"))
+
+	var respBody map[string][]map[string]any
+	err = json.NewDecoder(res.Body).Decode(&respBody)
+	require.NoError(t, err)
+
+	require.ElementsMatch(t, []map[string]any{
+		{
+			"name":  "enabled-client-side-flag",
+			"value": true,
+		},
+		{
+			"name":  "disabled-client-side-flag",
+			"value": false,
+		},
+	}, respBody["flags"])
+}
+
+func TestServiceAccountAPIKeyFlagRoutesAfterOwnerDeactivated(t *testing.T) {
+	t.Parallel()
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	owner, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	flag := testkitinternal.MustCreateUserFlag(t, owner.UUID, "service-account-flag", true)
+	serviceAccount := testkitinternal.MustCreateUserServiceAccount(t, owner.UUID, nil)
+	_, serviceAccountRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, owner.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"flags:read"}
+		k.ServiceAccountUUID = pgtype.Text{
+			String: serviceAccount.UUID,
+			Valid:  true,
+		}
+	})
+	_, serviceAccountClientRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, owner.UUID, func(k *auth.APIKey) {
+		k.Scopes = []string{"flags:client"}
+		k.ServiceAccountUUID = pgtype.Text{
+			String: serviceAccount.UUID,
+			Valid:  true,
+		}
+	})
+	_, ownerRawAPIKey := testkitinternal.MustCreateUserAPIKey(t, owner.UUID, nil)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+
+	_, err := flags.NewRepository().UpdateFlag(dbConn, flag.ID, owner.UUID, pgtype.Text{}, true, nil)
+	require.NoError(t, err)
+
+	isActive := false
+	_, err = auth.NewRepository().UpdateUserStatus(dbConn, owner.UUID, &isActive, nil)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name           string
+		path           string
+		rawAPIKey      string
+		wantStatusCode int
+		wantBody       map[string]any
+	}{
+		{
+			name:           "Service account API key evaluates flag",
+			path:           fmt.Sprintf("/api/flags/%s", flag.Name),
+			rawAPIKey:      serviceAccountRawAPIKey,
+			wantStatusCode: http.StatusOK,
+			wantBody: map[string]any{
+				"is_enabled": true,
+				"valid":      true,
+			},
+		},
+		{
+			name:           "Service account API key lists flags",
+			path:           "/api/flags",
+			rawAPIKey:      serviceAccountRawAPIKey,
+			wantStatusCode: http.StatusOK,
+			wantBody:       nil,
+		},
+		{
+			name:           "Service account client-side API key evaluates flag",
+			path:           fmt.Sprintf("/api/client/flags/%s", flag.Name),
+			rawAPIKey:      serviceAccountClientRawAPIKey,
+			wantStatusCode: http.StatusOK,
+			wantBody: map[string]any{
+				"name":  flag.Name,
+				"value": true,
+			},
+		},
+		{
+			name:           "Owner API key is rejected",
+			path:           fmt.Sprintf("/api/flags/%s", flag.Name),
+			rawAPIKey:      ownerRawAPIKey,
+			wantStatusCode: http.StatusUnauthorized,
+			wantBody:       nil,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, TestServerURL+testcase.path, http.NoBody)
+			require.NoError(t, err)
+
+			req.Header.Add("Authorization", fmt.Sprintf("X-API-Key %s", testcase.rawAPIKey))
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+			if testcase.wantBody == nil {
+				return
+			}
+
+			var respBody map[string]any
+			err = json.NewDecoder(res.Body).Decode(&respBody)
+			require.NoError(t, err)
+
+			for key, value := range testcase.wantBody {
+				require.Equal(t, value, respBody[key])
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+// jwtPurgeInterval is the interval between purges of expired single-use JWT records.
+const jwtPurgeInterval = time.Hour
+
+// sessionPurgeInterval is the interval between purges of expired sessions.
+const sessionPurgeInterval = time.Hour
+
+// oidcAuthRequestPurgeInterval is the interval between purges of expired OpenID Connect authorization requests.
+const oidcAuthRequestPurgeInterval = time.Hour
+
+// loginThrottlePurgeInterval is the interval between purges of expired failed login attempt records.
+const loginThrottlePurgeInterval = time.Hour
+
+// rateLimitBucketPurgeInterval is the interval between purges of stale rate limit buckets.
+const rateLimitBucketPurgeInterval = time.Hour
+
+// apiKeyUsageFlushInterval is the interval between writes of buffered API key usage to the database.
+const apiKeyUsageFlushInterval = 10 * time.Second
+
+// runPeriodicJob runs job every interval until the Controller is closed.
+func (ctrl *controller) runPeriodicJob(interval time.Duration, job func(ctx context.Context)) {
+	ctrl.jobsWG.Add(1)
+	go func() {
+		defer ctrl.jobsWG.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctrl.jobsCtx.Done():
+				return
+			case <-ticker.C:
+				job(ctrl.jobsCtx)
+			}
+		}
+	}()
+}
+
+// purgeExpiredJWTs deletes records of expired single-use JWTs.
+func (ctrl *controller) purgeExpiredJWTs(ctx context.Context) {
+	count, err := ctrl.authService.PurgeExpiredJWTs(ctx)
+	if err != nil {
+		ctrl.logger.LogError("purgeExpiredJWTs failed to ctrl.authService.PurgeExpiredJWTs:", err)
+		return
+	}
+
+	ctrl.logger.LogInfo("Purged expired JWT records:", count)
+}
+
+// purgeExpiredSessions deletes expired sessions.
+func (ctrl *controller) purgeExpiredSessions(ctx context.Context) {
+	count, err := ctrl.authService.PurgeExpiredSessions(ctx)
+	if err != nil {
+		ctrl.logger.LogError("purgeExpiredSessions failed to ctrl.authService.PurgeExpiredSessions:", err)
+		return
+	}
+
+	ctrl.logger.LogInfo("Purged expired sessions:", count)
+}
+
+// purgeExpiredOIDCAuthRequests deletes expired OpenID Connect authorization requests.
+func (ctrl *controller) purgeExpiredOIDCAuthRequests(ctx context.Context) {
+	count, err := ctrl.authService.PurgeExpiredOIDCAuthRequests(ctx)
+	if err != nil {
+		ctrl.logger.LogError("purgeExpiredOIDCAuthRequests failed to ctrl.authService.PurgeExpiredOIDCAuthRequests:", err)
+		return
+	}
+
+	ctrl.logger.LogInfo("Purged expired OIDC authorization requests:", count)
+}
+
+// purgeExpiredLoginThrottles deletes expired failed login attempt records.
+func (ctrl *controller) purgeExpiredLoginThrottles(ctx context.Context) {
+	count, err := ctrl.authService.PurgeExpiredLoginThrottles(ctx)
+	if err != nil {
+		ctrl.logger.LogError("purgeExpiredLoginThrottles failed to ctrl.authService.PurgeExpiredLoginThrottles:", err)
+		return
+	}
+
+	ctrl.logger.LogInfo("Purged expired login throttle records:", count)
+}
+
+// purgeStaleRateLimitBuckets deletes rate limit buckets that haven't been used recently.
+func (ctrl *controller) purgeStaleRateLimitBuckets(ctx context.Context) {
+	count, err := ctrl.rateLimitService.PurgeStaleBuckets(ctx)
+	if err != nil {
+		ctrl.logger.LogError("purgeStaleRateLimitBuckets failed to ctrl.rateLimitService.PurgeStaleBuckets:", err)
+		return
+	}
+
+	ctrl.logger.LogInfo("Purged stale rate limit buckets:", count)
+}
+
+// flushAPIKeyUsage writes buffered API key usage to the database.
+func (ctrl *controller) flushAPIKeyUsage(ctx context.Context) {
+	_, err := ctrl.authService.FlushAPIKeyUsage(ctx)
+	if err != nil {
+		ctrl.logger.LogError("flushAPIKeyUsage failed to ctrl.authService.FlushAPIKeyUsage:", err)
+	}
+}
+
+// startJobs starts all periodic background jobs.
+func (ctrl *controller) startJobs() {
+	ctrl.runPeriodicJob(jwtPurgeInterval, ctrl.purgeExpiredJWTs)
+	ctrl.runPeriodicJob(sessionPurgeInterval, ctrl.purgeExpiredSessions)
+	ctrl.runPeriodicJob(oidcAuthRequestPurgeInterval, ctrl.purgeExpiredOIDCAuthRequests)
+	ctrl.runPeriodicJob(loginThrottlePurgeInterval, ctrl.purgeExpiredLoginThrottles)
+	ctrl.runPeriodicJob(rateLimitBucketPurgeInterval, ctrl.purgeStaleRateLimitBuckets)
+	ctrl.runPeriodicJob(apiKeyUsageFlushInterval, ctrl.flushAPIKeyUsage)
+}
@@ -1,36 +1,156 @@
 package server
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/alvii147/flagger-api/internal/auth"
+	"github.com/alvii147/flagger-api/pkg/api"
 	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/alvii147/flagger-api/pkg/jwks"
 	"github.com/alvii147/flagger-api/pkg/logging"
 )
 
+// handleRateLimitExceeded responds to requests rejected by rate limit middleware.
+func (ctrl *controller) handleRateLimitExceeded(w *httputils.ResponseWriter, r *http.Request) {
+	w.WriteJSON(
+		api.ErrorResponse{
+			Code:   api.ErrCodeTooManyRequests,
+			Detail: api.ErrDetailRateLimitExceeded,
+		},
+		http.StatusTooManyRequests,
+	)
+}
+
+// handleOptions responds to OPTIONS requests that are not answered by CORS middleware,
+// such as preflight requests from origins that are not allowed.
+func (ctrl *controller) handleOptions(w *httputils.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // route sets up routes for the controller.
 func (ctrl *controller) route() {
 	loggerMiddleware := func(next httputils.HandlerFunc) httputils.HandlerFunc {
 		return logging.LoggerMiddleware(next, ctrl.logger)
 	}
 	jwtMiddleware := func(next httputils.HandlerFunc) httputils.HandlerFunc {
-		return auth.JWTAuthMiddleware(next, ctrl.config.SecretKey)
+		return auth.JWTAuthMiddleware(next, ctrl.authService, ctrl.auditService, ctrl.config.AuthAccessTokenCookie)
 	}
 	apiKeyMiddleware := func(next httputils.HandlerFunc) httputils.HandlerFunc {
-		return auth.APIKeyAuthMiddleware(next, ctrl.authService)
+		return auth.APIKeyAuthMiddleware(next, ctrl.authService, ctrl.auditService)
 	}
+	apiKeyScopeMiddleware := func(scope auth.APIKeyScope) httputils.MiddlewareFunc {
+		return func(next httputils.HandlerFunc) httputils.HandlerFunc {
+			return auth.RequireAPIKeyScope(next, scope)
+		}
+	}
+	superUserMiddleware := func(next httputils.HandlerFunc) httputils.HandlerFunc {
+		return auth.RequireSuperUser(next, ctrl.authService)
+	}
+	rateLimitMiddleware := func(policy httputils.RateLimitPolicy) httputils.MiddlewareFunc {
+		return httputils.RateLimitMiddleware(ctrl.rateLimitStore, policy, ctrl.handleRateLimitExceeded, ctrl.logger.LogError)
+	}
+	authIPRateLimitMiddleware := rateLimitMiddleware(httputils.RateLimitPolicy{
+		Name:   "auth_ip",
+		Limit:  60,
+		Period: time.Minute,
+		Key:    httputils.RateLimitKeyClientIP,
+	})
+	apiKeyRateLimitMiddleware := rateLimitMiddleware(httputils.RateLimitPolicy{
+		Name:   "api_key",
+		Limit:  600,
+		Period: time.Minute,
+		Key:    auth.RateLimitKeyAPIKey,
+	})
+	clientAPIKeyRateLimitMiddleware := rateLimitMiddleware(httputils.RateLimitPolicy{
+		Name:   "client_api_key",
+		Limit:  60,
+		Period: time.Minute,
+		Key:    auth.RateLimitKeyAPIKeyClientIP,
+	})
+	magicLinkRateLimitMiddleware := rateLimitMiddleware(httputils.RateLimitPolicy{
+		Name:   "magic_link",
+		Limit:  5,
+		Period: 15 * time.Minute,
+		Key:    httputils.RateLimitKeyClientIP,
+	})
+	userMutationRateLimitMiddleware := rateLimitMiddleware(httputils.RateLimitPolicy{
+		Name:   "user_mutation",
+		Limit:  120,
+		Period: time.Minute,
+		Key:    auth.RateLimitKeyUser,
+	})
+	corsMiddleware := func(methods ...string) httputils.MiddlewareFunc {
+		return httputils.CORSMiddleware(httputils.CORSPolicy{
+			AllowedOrigins: ctrl.corsOrigins,
+			AllowedMethods: methods,
+			AllowedHeaders: []string{"Authorization", "Content-Type"},
+			ExposedHeaders: []string{"RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+			MaxAge:         time.Duration(ctrl.config.CORSMaxAge) * time.Second,
+		})
+	}
+	apiUserCORSMiddleware := corsMiddleware(http.MethodGet)
+	apiFlagsCORSMiddleware := corsMiddleware(http.MethodGet, http.MethodPost)
+	apiFlagCORSMiddleware := corsMiddleware(http.MethodGet, http.MethodPut)
+	apiClientCORSMiddleware := corsMiddleware(http.MethodGet)
 
-	ctrl.router.POST("/auth/users", ctrl.handleCreateUser, loggerMiddleware)
-	ctrl.router.GET("/auth/users/me", ctrl.handleGetUserMe, jwtMiddleware, loggerMiddleware)
-	ctrl.router.GET("/api/auth/users/me", ctrl.handleGetUserMe, apiKeyMiddleware, loggerMiddleware)
-	ctrl.router.POST("/auth/users/activate", ctrl.handleActivateUser, loggerMiddleware)
-	ctrl.router.POST("/auth/tokens", ctrl.handleCreateJWT, loggerMiddleware)
-	ctrl.router.POST("/auth/tokens/refresh", ctrl.handleRefreshJWT, loggerMiddleware)
-	ctrl.router.POST("/auth/api-keys", ctrl.handleCreateAPIKey, jwtMiddleware, loggerMiddleware)
-	ctrl.router.GET("/auth/api-keys", ctrl.handleListAPIKeys, jwtMiddleware, loggerMiddleware)
-	ctrl.router.DELETE("/auth/api-keys/{id}", ctrl.handleDeleteAPIKey, jwtMiddleware, loggerMiddleware)
+	ctrl.router.GET(jwks.Path, ctrl.handleGetJWKS, loggerMiddleware)
+	ctrl.router.POST("/auth/users", ctrl.handleCreateUser, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.GET("/auth/users/me", ctrl.handleGetUserMe, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.GET("/api/auth/users/me", ctrl.handleGetUserMe, apiKeyScopeMiddleware(auth.APIKeyScopeUserRead), apiKeyRateLimitMiddleware, apiKeyMiddleware, apiUserCORSMiddleware, loggerMiddleware)
+	ctrl.router.OPTIONS("/api/auth/users/me", ctrl.handleOptions, apiUserCORSMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/users/me/password", ctrl.handleChangePassword, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/users/me/email", ctrl.handleChangeEmail, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/users/me/totp", ctrl.handleEnrollTOTP, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/users/me/totp/confirm", ctrl.handleConfirmTOTP, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/users/me/totp/disable", ctrl.handleDisableTOTP, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/users/activate", ctrl.handleActivateUser, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/users/activate/resend", ctrl.handleResendActivation, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/users/email/confirm", ctrl.handleConfirmEmailChange, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/password-reset", ctrl.handleRequestPasswordReset, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/password-reset/confirm", ctrl.handleConfirmPasswordReset, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/tokens", ctrl.handleCreateJWT, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/tokens/totp", ctrl.handleVerifyTOTPChallenge, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/magic-link", ctrl.handleRequestMagicLink, magicLinkRateLimitMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/magic-link/verify", ctrl.handleVerifyMagicLink, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/oidc/authorize", ctrl.handleCreateOIDCAuthURL, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/oidc/callback", ctrl.handleOIDCCallback, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/tokens/refresh", ctrl.handleRefreshJWT, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/logout", ctrl.handleLogout, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/logout-all", ctrl.handleLogoutAll, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.GET("/auth/sessions", ctrl.handleListSessions, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.DELETE("/auth/sessions/{id}", ctrl.handleDeleteSession, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/api-keys", ctrl.handleCreateAPIKey, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.GET("/auth/api-keys", ctrl.handleListAPIKeys, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.PUT("/auth/api-keys/{id}", ctrl.handleUpdateAPIKey, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.DELETE("/auth/api-keys/{id}", ctrl.handleDeleteAPIKey, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/api-keys/{id}/rotate", ctrl.handleRotateAPIKey, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.GET("/auth/api-keys/{id}/usage", ctrl.handleGetAPIKeyUsage, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.POST("/auth/service-accounts", ctrl.handleCreateServiceAccount, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.GET("/auth/service-accounts", ctrl.handleListServiceAccounts, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.PUT("/auth/service-accounts/{uuid}", ctrl.handleUpdateServiceAccount, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
+	ctrl.router.DELETE("/auth/service-accounts/{uuid}", ctrl.handleDeleteServiceAccount, userMutationRateLimitMiddleware, jwtMiddleware, authIPRateLimitMiddleware, loggerMiddleware)
 
 	ctrl.router.GET("/flags", ctrl.handleListFlags, jwtMiddleware, loggerMiddleware)
-	ctrl.router.POST("/flags", ctrl.handleCreateFlag, jwtMiddleware, loggerMiddleware)
+	ctrl.router.POST("/flags", ctrl.handleCreateFlag, userMutationRateLimitMiddleware, jwtMiddleware, loggerMiddleware)
 	ctrl.router.GET("/flags/{id}", ctrl.handleGetFlagByID, jwtMiddleware, loggerMiddleware)
-	ctrl.router.GET("/api/flags/{name}", ctrl.handleGetFlagByName, apiKeyMiddleware, loggerMiddleware)
-	ctrl.router.PUT("/flags/{id}", ctrl.handleUpdateFlag, jwtMiddleware, loggerMiddleware)
+	ctrl.router.GET("/api/flags", ctrl.handleListFlags, apiKeyScopeMiddleware(auth.APIKeyScopeFlagsRead), apiKeyRateLimitMiddleware, apiKeyMiddleware, apiFlagsCORSMiddleware, loggerMiddleware)
+	ctrl.router.POST("/api/flags", ctrl.handleCreateFlag, apiKeyScopeMiddleware(auth.APIKeyScopeFlagsWrite), apiKeyRateLimitMiddleware, apiKeyMiddleware, apiFlagsCORSMiddleware, loggerMiddleware)
+	ctrl.router.OPTIONS("/api/flags", ctrl.handleOptions, apiFlagsCORSMiddleware, loggerMiddleware)
+	ctrl.router.GET("/api/flags/{name}", ctrl.handleGetFlagByName, apiKeyScopeMiddleware(auth.APIKeyScopeFlagsEvaluate), apiKeyRateLimitMiddleware, apiKeyMiddleware, apiFlagCORSMiddleware, loggerMiddleware)
+	ctrl.router.PUT("/api/flags/{id}", ctrl.handleUpdateFlag, apiKeyScopeMiddleware(auth.APIKeyScopeFlagsWrite), apiKeyRateLimitMiddleware, apiKeyMiddleware, apiFlagCORSMiddleware, loggerMiddleware)
+	ctrl.router.OPTIONS("/api/flags/{name}", ctrl.handleOptions, apiFlagCORSMiddleware, loggerMiddleware)
+	ctrl.router.GET("/api/client/flags", ctrl.handleEvaluateClientSideFlags, apiKeyScopeMiddleware(auth.APIKeyScopeFlagsClient), clientAPIKeyRateLimitMiddleware, apiKeyMiddleware, apiClientCORSMiddleware, loggerMiddleware)
+	ctrl.router.OPTIONS("/api/client/flags", ctrl.handleOptions, apiClientCORSMiddleware, loggerMiddleware)
+	ctrl.router.GET("/api/client/flags/{name}", ctrl.handleEvaluateClientSideFlag, apiKeyScopeMiddleware(auth.APIKeyScopeFlagsClient), clientAPIKeyRateLimitMiddleware, apiKeyMiddleware, apiClientCORSMiddleware, loggerMiddleware)
+	ctrl.router.OPTIONS("/api/client/flags/{name}", ctrl.handleOptions, apiClientCORSMiddleware, loggerMiddleware)
+	ctrl.router.PUT("/flags/{id}", ctrl.handleUpdateFlag, userMutationRateLimitMiddleware, jwtMiddleware, loggerMiddleware)
+
+	ctrl.router.GET("/admin/users", ctrl.handleAdminListUsers, superUserMiddleware, jwtMiddleware, loggerMiddleware)
+	ctrl.router.GET("/admin/users/{uuid}", ctrl.handleAdminGetUser, superUserMiddleware, jwtMiddleware, loggerMiddleware)
+	ctrl.router.PUT("/admin/users/{uuid}", ctrl.handleAdminUpdateUserStatus, superUserMiddleware, userMutationRateLimitMiddleware, jwtMiddleware, loggerMiddleware)
+	ctrl.router.POST("/admin/users/{uuid}/password-reset", ctrl.handleAdminForcePasswordReset, superUserMiddleware, userMutationRateLimitMiddleware, jwtMiddleware, loggerMiddleware)
+	ctrl.router.POST("/admin/users/{uuid}/revoke-credentials", ctrl.handleAdminRevokeUserCredentials, superUserMiddleware, userMutationRateLimitMiddleware, jwtMiddleware, loggerMiddleware)
+	ctrl.router.GET("/admin/users/{uuid}/flags", ctrl.handleAdminListUserFlags, superUserMiddleware, jwtMiddleware, loggerMiddleware)
+	ctrl.router.POST("/admin/users/{uuid}/impersonate", ctrl.handleAdminImpersonateUser, superUserMiddleware, userMutationRateLimitMiddleware, jwtMiddleware, loggerMiddleware)
 }
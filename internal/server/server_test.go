@@ -4,17 +4,64 @@ import (
 	"os"
 	"testing"
 
+	"github.com/alvii147/flagger-api/internal/auth"
+	"github.com/alvii147/flagger-api/internal/ratelimit"
 	"github.com/alvii147/flagger-api/internal/testkitinternal"
+	"github.com/alvii147/flagger-api/pkg/testkit"
 )
 
 var TestServerURL = ""
 
+var TestCookieServerURL = ""
+
+var TestAccessTokenCookieServerURL = ""
+
+var TestOIDCProvider *testkit.OIDCProvider
+
 func TestMain(m *testing.M) {
+	TestOIDCProvider = testkit.MustCreateOIDCProvider("flagger", "s3cr3t")
+	os.Setenv("FLAGGERAPI_OIDC_ISSUER_URL", TestOIDCProvider.URL())
+	os.Setenv("FLAGGERAPI_OIDC_CLIENT_ID", TestOIDCProvider.ClientID)
+	os.Setenv("FLAGGERAPI_OIDC_CLIENT_SECRET", TestOIDCProvider.ClientSecret)
+
+	// every test request comes from the same IP address, so rate limits would be shared across all tests
+	os.Setenv("FLAGGERAPI_RATE_LIMIT_STORE_TYPE", ratelimit.StoreTypeNone)
+
+	// allow cross-origin requests from any origin, so that CORS headers are predictable
+	os.Setenv("FLAGGERAPI_CORS_ALLOWED_ORIGINS", "*")
+	os.Setenv("FLAGGERAPI_CORS_MAX_AGE", "600")
+
+	// sign access and refresh JWTs using asymmetric keys, with a previously rotated key still accepted
+	keysDir, err := os.MkdirTemp("", "flaggerapi-keys-")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("FLAGGERAPI_JWT_SIGNING_KEY_FILE", testkit.MustWriteKeyFile(keysDir, testkit.MustGenerateEd25519Key()))
+	os.Setenv("FLAGGERAPI_JWT_VERIFICATION_KEY_FILES", testkit.MustWriteKeyFile(keysDir, testkit.MustGenerateEd25519Key().Public()))
+
 	ctrl, srv := testkitinternal.MustCreateTestServer()
 	TestServerURL = srv.URL
 
+	// serve the same database using cookie token transport, as browser frontends would
+	tokenTransport := os.Getenv("FLAGGERAPI_AUTH_TOKEN_TRANSPORT")
+	os.Setenv("FLAGGERAPI_AUTH_TOKEN_TRANSPORT", auth.TokenTransportCookie)
+	cookieCtrl, cookieSrv := testkitinternal.MustCreateTestServer()
+	TestCookieServerURL = cookieSrv.URL
+
+	// serve the same database with access tokens also set in cookies
+	accessTokenCookie := os.Getenv("FLAGGERAPI_AUTH_ACCESS_TOKEN_COOKIE")
+	os.Setenv("FLAGGERAPI_AUTH_ACCESS_TOKEN_COOKIE", "true")
+	accessTokenCookieCtrl, accessTokenCookieSrv := testkitinternal.MustCreateTestServer()
+	TestAccessTokenCookieServerURL = accessTokenCookieSrv.URL
+	os.Setenv("FLAGGERAPI_AUTH_ACCESS_TOKEN_COOKIE", accessTokenCookie)
+	os.Setenv("FLAGGERAPI_AUTH_TOKEN_TRANSPORT", tokenTransport)
+
 	code := m.Run()
 
+	testkitinternal.MustCloseTestServer(accessTokenCookieCtrl, accessTokenCookieSrv)
+	testkitinternal.MustCloseTestServer(cookieCtrl, cookieSrv)
 	testkitinternal.MustCloseTestServer(ctrl, srv)
+	TestOIDCProvider.Close()
+	os.RemoveAll(keysDir)
 	os.Exit(code)
 }
@@ -5,3 +5,35 @@ type ActivationEmailTemplateData struct {
 	RecipientEmail string
 	ActivationURL  string
 }
+
+// PasswordResetEmailTemplateData represents data for User password reset email templates.
+type PasswordResetEmailTemplateData struct {
+	RecipientEmail   string
+	PasswordResetURL string
+}
+
+// MagicLinkEmailTemplateData represents data for User magic link login email templates.
+type MagicLinkEmailTemplateData struct {
+	RecipientEmail string
+	MagicLinkURL   string
+	Lifetime       int64
+}
+
+// EmailChangeEmailTemplateData represents data for User email change verification email templates.
+type EmailChangeEmailTemplateData struct {
+	RecipientEmail string
+	EmailChangeURL string
+}
+
+// EmailChangeNoticeEmailTemplateData represents data for User email change notice email templates.
+type EmailChangeNoticeEmailTemplateData struct {
+	RecipientEmail string
+	NewEmail       string
+}
+
+// AccountLockoutEmailTemplateData represents data for User account lockout notice email templates.
+type AccountLockoutEmailTemplateData struct {
+	RecipientEmail string
+	IPAddress      string
+	LockedUntil    string
+}
@@ -10,12 +10,48 @@ import (
 func TestManagerLoadSuccess(t *testing.T) {
 	t.Parallel()
 
-	tmplManager := templatesmanager.NewManager()
-	textTmpl, htmlTmpl, err := tmplManager.Load("activation")
+	testcases := []struct {
+		name     string
+		tmplName string
+	}{
+		{
+			name:     "Activation templates",
+			tmplName: "activation",
+		},
+		{
+			name:     "Password reset templates",
+			tmplName: "password_reset",
+		},
+		{
+			name:     "Email change templates",
+			tmplName: "email_change",
+		},
+		{
+			name:     "Email change notice templates",
+			tmplName: "email_change_notice",
+		},
+		{
+			name:     "Account lockout templates",
+			tmplName: "account_lockout",
+		},
+		{
+			name:     "Magic link templates",
+			tmplName: "magic_link",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmplManager := templatesmanager.NewManager()
+			textTmpl, htmlTmpl, err := tmplManager.Load(testcase.tmplName)
 
-	require.NoError(t, err)
-	require.NotNil(t, textTmpl)
-	require.NotNil(t, htmlTmpl)
+			require.NoError(t, err)
+			require.NotNil(t, textTmpl)
+			require.NotNil(t, htmlTmpl)
+		})
+	}
 }
 
 func TestManagerLoadError(t *testing.T) {
@@ -2,13 +2,20 @@ package testkitinternal
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/alvii147/flagger-api/internal/auth"
 	"github.com/alvii147/flagger-api/internal/env"
 	"github.com/alvii147/flagger-api/pkg/api"
+	"github.com/alvii147/flagger-api/pkg/jwks"
 	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/alvii147/flagger-api/pkg/totp"
 	"github.com/alvii147/flagger-api/pkg/utils"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
@@ -63,47 +70,220 @@ func MustCreateUser(t testkit.TestingT, modifier func(u *auth.User)) (*auth.User
 	return user, password
 }
 
-// MustCreateUserAuthJWTs creates and returns access and refresh JWTs for User and panics on error.
-func MustCreateUserAuthJWTs(userUUID string) (string, string) {
+// MustCreateKeySet creates the KeySet configured for signing access and refresh JWTs and panics on error.
+func MustCreateKeySet(config *env.Config) *jwks.KeySet {
+	keySet, err := auth.NewKeySet(config)
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateKeySet failed to auth.NewKeySet: %v", err))
+	}
+
+	return keySet
+}
+
+// MustCreateUserAuthJWTs creates a new session for User,
+// and returns access and refresh JWTs for it and panics on error.
+func MustCreateUserAuthJWTs(t testkit.TestingT, userUUID string) (string, string) {
 	config, err := env.NewConfig()
 	if err != nil {
 		panic(fmt.Sprintf("MustCreateUserAuthJWTs failed to env.NewConfig: %v", err))
 	}
 
+	dbPool := RequireCreateDatabasePool(t)
+	dbConn := RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+	keySet := MustCreateKeySet(config)
+
 	now := time.Now().UTC()
-	accessToken, err := jwt.NewWithClaims(
+	sessionID := uuid.NewString()
+	accessToken, err := keySet.Sign(&api.AuthJWTClaims{
+		Subject:   userUUID,
+		SessionID: sessionID,
+		TokenType: string(auth.JWTTypeAccess),
+		IssuedAt:  utils.JSONTimeStamp(now),
+		ExpiresAt: utils.JSONTimeStamp(now.Add(time.Duration(config.AuthAccessLifetime * int64(time.Minute)))),
+		JWTID:     uuid.NewString(),
+	})
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateUserAuthJWTs failed to keySet.Sign: %v", err))
+	}
+
+	refreshJTI := uuid.NewString()
+	refreshExpiresAt := now.Add(time.Duration(config.AuthRefreshLifetime * int64(time.Minute)))
+	refreshToken, err := keySet.Sign(&api.AuthJWTClaims{
+		Subject:   userUUID,
+		SessionID: sessionID,
+		TokenType: string(auth.JWTTypeRefresh),
+		IssuedAt:  utils.JSONTimeStamp(now),
+		ExpiresAt: utils.JSONTimeStamp(refreshExpiresAt),
+		JWTID:     refreshJTI,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateUserAuthJWTs failed to keySet.Sign: %v", err))
+	}
+
+	_, err = repo.CreateSession(dbConn, &auth.Session{
+		ID:         sessionID,
+		UserUUID:   userUUID,
+		RefreshJTI: refreshJTI,
+		ExpiresAt:  refreshExpiresAt,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateUserAuthJWTs failed to repo.CreateSession: %v", err))
+	}
+
+	return accessToken, refreshToken
+}
+
+// mustRecordIssuedJWT records an issued single-use JWT and panics on error.
+func mustRecordIssuedJWT(t testkit.TestingT, jti string, userUUID string, tokenType auth.JWTType, createdAt time.Time, expiresAt time.Time) {
+	dbPool := RequireCreateDatabasePool(t)
+	dbConn := RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	_, err := repo.CreateIssuedJWT(dbConn, &auth.IssuedJWT{
+		JTI:       jti,
+		UserUUID:  userUUID,
+		TokenType: string(tokenType),
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("mustRecordIssuedJWT failed to repo.CreateIssuedJWT: %v", err))
+	}
+}
+
+// MustCreateUserActivationJWT creates, records and returns an activation JWT for User and panics on error.
+func MustCreateUserActivationJWT(t testkit.TestingT, userUUID string) string {
+	config, err := env.NewConfig()
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateUserActivationJWT failed to env.NewConfig: %v", err))
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Duration(config.ActivationLifetime * int64(time.Minute)))
+	jti := uuid.NewString()
+	token, err := jwt.NewWithClaims(
 		jwt.SigningMethodHS256,
-		&api.AuthJWTClaims{
+		&api.ActivationJWTClaims{
 			Subject:   userUUID,
-			TokenType: string(auth.JWTTypeAccess),
+			TokenType: string(auth.JWTTypeActivation),
 			IssuedAt:  utils.JSONTimeStamp(now),
-			ExpiresAt: utils.JSONTimeStamp(now.Add(time.Duration(config.AuthAccessLifetime * int64(time.Minute)))),
-			JWTID:     uuid.NewString(),
+			ExpiresAt: utils.JSONTimeStamp(expiresAt),
+			JWTID:     jti,
 		},
 	).SignedString([]byte(config.SecretKey))
 	if err != nil {
-		panic(fmt.Sprintf("MustCreateUserAPIKey failed to jwt.Token.SignedString: %v", err))
+		panic(fmt.Sprintf("MustCreateUserActivationJWT failed to jwt.Token.SignedString: %v", err))
+	}
+
+	mustRecordIssuedJWT(t, jti, userUUID, auth.JWTTypeActivation, now, expiresAt)
+
+	return token
+}
+
+// MustCreateUserPasswordResetJWT creates, records and returns a password reset JWT for User and panics on error.
+func MustCreateUserPasswordResetJWT(t testkit.TestingT, user *auth.User) string {
+	config, err := env.NewConfig()
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateUserPasswordResetJWT failed to env.NewConfig: %v", err))
+	}
+
+	mac := hmac.New(sha256.New, []byte(config.SecretKey))
+	if user.PasswordChangedAt.Valid {
+		mac.Write([]byte(strconv.FormatInt(user.PasswordChangedAt.Time.UnixMicro(), 10)))
 	}
 
-	refreshToken, err := jwt.NewWithClaims(
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Duration(config.PasswordResetLifetime * int64(time.Minute)))
+	jti := uuid.NewString()
+	token, err := jwt.NewWithClaims(
 		jwt.SigningMethodHS256,
-		&api.AuthJWTClaims{
-			Subject:   userUUID,
-			TokenType: string(auth.JWTTypeRefresh),
+		&api.PasswordResetJWTClaims{
+			Subject:             user.UUID,
+			TokenType:           string(auth.JWTTypePasswordReset),
+			PasswordFingerprint: base64.RawURLEncoding.EncodeToString(mac.Sum(nil)),
+			IssuedAt:            utils.JSONTimeStamp(now),
+			ExpiresAt:           utils.JSONTimeStamp(expiresAt),
+			JWTID:               jti,
+		},
+	).SignedString([]byte(config.SecretKey))
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateUserPasswordResetJWT failed to jwt.Token.SignedString: %v", err))
+	}
+
+	mustRecordIssuedJWT(t, jti, user.UUID, auth.JWTTypePasswordReset, now, expiresAt)
+
+	return token
+}
+
+// MustCreateUserEmailChangeJWT creates, records and returns an email change JWT for User and panics on error.
+func MustCreateUserEmailChangeJWT(t testkit.TestingT, user *auth.User, newEmail string) string {
+	config, err := env.NewConfig()
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateUserEmailChangeJWT failed to env.NewConfig: %v", err))
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Duration(config.EmailChangeLifetime * int64(time.Minute)))
+	jti := uuid.NewString()
+	token, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.EmailChangeJWTClaims{
+			Subject:   user.UUID,
+			TokenType: string(auth.JWTTypeEmailChange),
+			Email:     newEmail,
+			OldEmail:  user.Email,
 			IssuedAt:  utils.JSONTimeStamp(now),
-			ExpiresAt: utils.JSONTimeStamp(now.Add(time.Duration(config.AuthRefreshLifetime * int64(time.Minute)))),
-			JWTID:     uuid.NewString(),
+			ExpiresAt: utils.JSONTimeStamp(expiresAt),
+			JWTID:     jti,
 		},
 	).SignedString([]byte(config.SecretKey))
 	if err != nil {
-		panic(fmt.Sprintf("MustCreateUserAPIKey failed to jwt.Token.SignedString: %v", err))
+		panic(fmt.Sprintf("MustCreateUserEmailChangeJWT failed to jwt.Token.SignedString: %v", err))
 	}
 
-	return accessToken, refreshToken
+	mustRecordIssuedJWT(t, jti, user.UUID, auth.JWTTypeEmailChange, now, expiresAt)
+
+	return token
+}
+
+// MustCreateUserMagicLinkJWT creates, records and returns a magic link login JWT for User and panics on error.
+func MustCreateUserMagicLinkJWT(t testkit.TestingT, user *auth.User) string {
+	config, err := env.NewConfig()
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateUserMagicLinkJWT failed to env.NewConfig: %v", err))
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Duration(config.MagicLinkLifetime * int64(time.Minute)))
+	jti := uuid.NewString()
+	token, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&api.MagicLinkJWTClaims{
+			Subject:   user.UUID,
+			TokenType: string(auth.JWTTypeMagicLink),
+			Email:     user.Email,
+			IssuedAt:  utils.JSONTimeStamp(now),
+			ExpiresAt: utils.JSONTimeStamp(expiresAt),
+			JWTID:     jti,
+		},
+	).SignedString([]byte(config.SecretKey))
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateUserMagicLinkJWT failed to jwt.Token.SignedString: %v", err))
+	}
+
+	mustRecordIssuedJWT(t, jti, user.UUID, auth.JWTTypeMagicLink, now, expiresAt)
+
+	return token
 }
 
 // MustCreateUserAPIKey creates and returns a new API key for User and panics on error.
 func MustCreateUserAPIKey(t testkit.TestingT, userUUID string, modifier func(k *auth.APIKey)) (*auth.APIKey, string) {
+	config, err := env.NewConfig()
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateUserAPIKey failed to env.NewConfig: %v", err))
+	}
+
 	dbPool := RequireCreateDatabasePool(t)
 	dbConn := RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := auth.NewRepository()
@@ -112,13 +292,17 @@ func MustCreateUserAPIKey(t testkit.TestingT, userUUID string, modifier func(k *
 	prefix := testkit.MustGenerateRandomString(8, true, true, true)
 	secret := testkit.MustGenerateRandomString(32, true, true, true)
 	rawKey := fmt.Sprintf("%s.%s", prefix, secret)
-	hashedKey := MustHashPassword(rawKey)
+
+	mac := hmac.New(sha256.New, []byte(auth.DigestPepper(config)))
+	mac.Write([]byte(rawKey))
+	keyDigest := hex.EncodeToString(mac.Sum(nil))
 
 	apiKey := &auth.APIKey{
 		UserUUID:  userUUID,
 		Prefix:    prefix,
-		HashedKey: hashedKey,
+		KeyDigest: keyDigest,
 		Name:      name,
+		Scopes:    []string{string(auth.APIKeyScopeAdmin)},
 		ExpiresAt: pgtype.Timestamp{
 			Valid: false,
 		},
@@ -128,10 +312,95 @@ func MustCreateUserAPIKey(t testkit.TestingT, userUUID string, modifier func(k *
 		modifier(apiKey)
 	}
 
-	apiKey, err := repo.CreateAPIKey(dbConn, apiKey)
+	apiKey, err = repo.CreateAPIKey(dbConn, apiKey)
 	if err != nil {
 		panic(fmt.Sprintf("MustCreateUserAPIKey failed to repo.CreateAPIKey: %v", err))
 	}
 
 	return apiKey, rawKey
 }
+
+// MustCreateUserServiceAccount creates and returns a new service account owned by User and panics on error.
+func MustCreateUserServiceAccount(t testkit.TestingT, ownerUUID string, modifier func(sa *auth.ServiceAccount)) *auth.ServiceAccount {
+	dbPool := RequireCreateDatabasePool(t)
+	dbConn := RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	serviceAccount := &auth.ServiceAccount{
+		UUID:        uuid.NewString(),
+		OwnerUUID:   ownerUUID,
+		Name:        testkit.MustGenerateRandomString(12, true, true, true),
+		Description: "",
+		Scopes:      []string{string(auth.APIKeyScopeAdmin)},
+		IsActive:    true,
+	}
+
+	if modifier != nil {
+		modifier(serviceAccount)
+	}
+
+	serviceAccount, err := repo.CreateServiceAccount(dbConn, serviceAccount)
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateUserServiceAccount failed to repo.CreateServiceAccount: %v", err))
+	}
+
+	return serviceAccount
+}
+
+// MustRecordAPIKeyUsage records requests made using an API key on a given day and panics on error.
+func MustRecordAPIKeyUsage(t testkit.TestingT, apiKeyID int, day time.Time, requestCount int64) {
+	dbPool := RequireCreateDatabasePool(t)
+	dbConn := RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	err := repo.RecordAPIKeyUsage(dbConn, apiKeyID, day, requestCount, day, "127.0.0.1")
+	if err != nil {
+		panic(fmt.Sprintf("MustRecordAPIKeyUsage failed to repo.RecordAPIKeyUsage: %v", err))
+	}
+}
+
+// MustEnableUserTOTP creates and confirms a TOTP device for User along with recovery codes,
+// and returns the TOTP secret and recovery codes and panics on error.
+func MustEnableUserTOTP(t testkit.TestingT, userUUID string) (string, []string) {
+	config, err := env.NewConfig()
+	if err != nil {
+		panic(fmt.Sprintf("MustEnableUserTOTP failed to env.NewConfig: %v", err))
+	}
+
+	dbPool := RequireCreateDatabasePool(t)
+	dbConn := RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		panic(fmt.Sprintf("MustEnableUserTOTP failed to totp.GenerateSecret: %v", err))
+	}
+
+	_, err = repo.UpsertTOTPDevice(dbConn, userUUID, secret)
+	if err != nil {
+		panic(fmt.Sprintf("MustEnableUserTOTP failed to repo.UpsertTOTPDevice: %v", err))
+	}
+
+	err = repo.ConfirmTOTPDevice(dbConn, userUUID, 1)
+	if err != nil {
+		panic(fmt.Sprintf("MustEnableUserTOTP failed to repo.ConfirmTOTPDevice: %v", err))
+	}
+
+	recoveryCodes := make([]string, 10)
+	hashedCodes := make([]string, len(recoveryCodes))
+	for i := range recoveryCodes {
+		code := testkit.MustGenerateRandomString(10, false, true, true)
+		recoveryCodes[i] = code[:5] + "-" + code[5:]
+
+		mac := hmac.New(sha256.New, []byte(auth.DigestPepper(config)))
+		mac.Write([]byte(code))
+		hashedCodes[i] = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	err = repo.CreateRecoveryCodes(dbConn, userUUID, hashedCodes)
+	if err != nil {
+		panic(fmt.Sprintf("MustEnableUserTOTP failed to repo.CreateRecoveryCodes: %v", err))
+	}
+
+	return secret, recoveryCodes
+}
@@ -1,6 +1,10 @@
 package testkitinternal_test
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"strings"
 	"testing"
 	"time"
@@ -11,7 +15,6 @@ import (
 	"github.com/alvii147/flagger-api/pkg/api"
 	"github.com/alvii147/flagger-api/pkg/testkit"
 	"github.com/golang-jwt/jwt"
-	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -73,19 +76,48 @@ func TestMustCreateUserDuplicateEmail(t *testing.T) {
 	})
 }
 
+func TestMustCreateKeySetSuccess(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	keySet := testkitinternal.MustCreateKeySet(config)
+
+	token, err := keySet.Sign(&api.AuthJWTClaims{Subject: "somesubject"})
+	require.NoError(t, err)
+
+	_, err = keySet.Parse(token, &api.AuthJWTClaims{})
+	require.NoError(t, err)
+}
+
+func TestMustCreateKeySetPanic(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		r := recover()
+		require.NotNil(t, r)
+	}()
+
+	testkitinternal.MustCreateKeySet(&env.Config{
+		JWTSigningKeyFile: "missing.pem",
+	})
+}
+
 func TestMustCreateUserAuthJWTs(t *testing.T) {
 	t.Parallel()
 
 	config, err := env.NewConfig()
 	require.NoError(t, err)
 
-	userUUID := uuid.NewString()
-	accessToken, refreshToken := testkitinternal.MustCreateUserAuthJWTs(userUUID)
+	keySet := testkitinternal.MustCreateKeySet(config)
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+	userUUID := user.UUID
+	accessToken, refreshToken := testkitinternal.MustCreateUserAuthJWTs(t, userUUID)
 
 	accessClaims := &api.AuthJWTClaims{}
-	parsedAccessToken, err := jwt.ParseWithClaims(accessToken, accessClaims, func(t *jwt.Token) (any, error) {
-		return []byte(config.SecretKey), nil
-	})
+	parsedAccessToken, err := keySet.Parse(accessToken, accessClaims)
 	require.NoError(t, err)
 
 	require.NotNil(t, parsedAccessToken)
@@ -97,20 +129,98 @@ func TestMustCreateUserAuthJWTs(t *testing.T) {
 	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthAccessLifetime*int64(time.Minute))), time.Time(accessClaims.ExpiresAt))
 
 	refreshClaims := &api.AuthJWTClaims{}
-	parsedRefreshToken, err := jwt.ParseWithClaims(refreshToken, refreshClaims, func(t *jwt.Token) (any, error) {
-		return []byte(config.SecretKey), nil
-	})
+	parsedRefreshToken, err := keySet.Parse(refreshToken, refreshClaims)
 	require.NoError(t, err)
 
 	require.NotNil(t, parsedRefreshToken)
 	require.True(t, parsedRefreshToken.Valid)
 	require.Equal(t, userUUID, refreshClaims.Subject)
 	require.Equal(t, string(auth.JWTTypeRefresh), refreshClaims.TokenType)
+	require.NotEmpty(t, refreshClaims.SessionID)
+	require.Equal(t, accessClaims.SessionID, refreshClaims.SessionID)
 
 	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(refreshClaims.IssuedAt))
 	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.AuthRefreshLifetime*int64(time.Minute))), time.Time(refreshClaims.ExpiresAt))
 }
 
+func TestMustCreateUserActivationJWT(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = false
+	})
+	token := testkitinternal.MustCreateUserActivationJWT(t, user.UUID)
+
+	claims := &api.ActivationJWTClaims{}
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(config.SecretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, parsedToken)
+	require.True(t, parsedToken.Valid)
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, string(auth.JWTTypeActivation), claims.TokenType)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.ActivationLifetime*int64(time.Minute))), time.Time(claims.ExpiresAt))
+}
+
+func TestMustCreateUserPasswordResetJWT(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+	token := testkitinternal.MustCreateUserPasswordResetJWT(t, user)
+
+	claims := &api.PasswordResetJWTClaims{}
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(config.SecretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, parsedToken)
+	require.True(t, parsedToken.Valid)
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, string(auth.JWTTypePasswordReset), claims.TokenType)
+	require.NotEmpty(t, claims.PasswordFingerprint)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.PasswordResetLifetime*int64(time.Minute))), time.Time(claims.ExpiresAt))
+}
+
+func TestMustCreateUserEmailChangeJWT(t *testing.T) {
+	t.Parallel()
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+	newEmail := testkit.GenerateFakeEmail()
+	token := testkitinternal.MustCreateUserEmailChangeJWT(t, user, newEmail)
+
+	claims := &api.EmailChangeJWTClaims{}
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(config.SecretKey), nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, parsedToken)
+	require.True(t, parsedToken.Valid)
+	require.Equal(t, user.UUID, claims.Subject)
+	require.Equal(t, string(auth.JWTTypeEmailChange), claims.TokenType)
+	require.Equal(t, newEmail, claims.Email)
+	require.Equal(t, user.Email, claims.OldEmail)
+
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC(), time.Time(claims.IssuedAt))
+	testkit.RequireTimeAlmostEqual(t, time.Now().UTC().Add(time.Duration(config.EmailChangeLifetime*int64(time.Minute))), time.Time(claims.ExpiresAt))
+}
+
 func TestMustCreateUserAPIKeySuccess(t *testing.T) {
 	t.Parallel()
 
@@ -124,9 +234,14 @@ func TestMustCreateUserAPIKeySuccess(t *testing.T) {
 	})
 
 	require.Equal(t, name, apiKey.Name)
+	require.Empty(t, apiKey.HashedKey)
 
-	err := bcrypt.CompareHashAndPassword([]byte(apiKey.HashedKey), []byte(rawKey))
+	config, err := env.NewConfig()
 	require.NoError(t, err)
+
+	mac := hmac.New(sha256.New, []byte(config.SecretKey))
+	mac.Write([]byte(rawKey))
+	require.Equal(t, hex.EncodeToString(mac.Sum(nil)), apiKey.KeyDigest)
 }
 
 func TestMustCreateUserAPIKeyWrongUserUUID(t *testing.T) {
@@ -143,3 +258,53 @@ func TestMustCreateUserAPIKeyWrongUserUUID(t *testing.T) {
 
 	testkitinternal.MustCreateUserAPIKey(t, "dead-beef-dead-beef", nil)
 }
+
+func TestMustRecordAPIKeyUsage(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, func(u *auth.User) {
+		u.IsActive = true
+	})
+	apiKey, _ := testkitinternal.MustCreateUserAPIKey(t, user.UUID, nil)
+
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	testkitinternal.MustRecordAPIKeyUsage(t, apiKey.ID, day, 2)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	usage, err := repo.ListAPIKeyUsage(dbConn, apiKey.ID, user.UUID, day, day)
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	require.Equal(t, int64(2), usage[0].RequestCount)
+}
+
+func TestMustEnableUserTOTP(t *testing.T) {
+	t.Parallel()
+
+	user, _ := testkitinternal.MustCreateUser(t, nil)
+	secret, recoveryCodes := testkitinternal.MustEnableUserTOTP(t, user.UUID)
+
+	dbPool := testkitinternal.RequireCreateDatabasePool(t)
+	dbConn := testkitinternal.RequireCreateDatabaseConn(t, dbPool, context.Background())
+	repo := auth.NewRepository()
+
+	device, err := repo.GetTOTPDevice(dbConn, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, secret, device.Secret)
+	require.True(t, device.ConfirmedAt.Valid)
+
+	config, err := env.NewConfig()
+	require.NoError(t, err)
+
+	require.Len(t, recoveryCodes, 10)
+	for _, recoveryCode := range recoveryCodes {
+		require.Regexp(t, `^[A-Z0-9]{5}-[A-Z0-9]{5}$`, recoveryCode)
+
+		mac := hmac.New(sha256.New, []byte(config.SecretKey))
+		mac.Write([]byte(strings.ReplaceAll(recoveryCode, "-", "")))
+		err = repo.UseRecoveryCode(dbConn, user.UUID, hex.EncodeToString(mac.Sum(nil)))
+		require.NoError(t, err)
+	}
+}
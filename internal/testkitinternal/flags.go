@@ -9,14 +9,15 @@ import (
 )
 
 // MustCreateUserFlag creates and returns a new Flag for User and panics on error.
-func MustCreateUserFlag(t testkit.TestingT, userUUID string, name string) *flags.Flag {
+func MustCreateUserFlag(t testkit.TestingT, userUUID string, name string, isClientSide bool) *flags.Flag {
 	dbPool := RequireCreateDatabasePool(t)
 	dbConn := RequireCreateDatabaseConn(t, dbPool, context.Background())
 	repo := flags.NewRepository()
 
 	flag := &flags.Flag{
-		UserUUID: userUUID,
-		Name:     name,
+		UserUUID:     userUUID,
+		Name:         name,
+		IsClientSide: isClientSide,
 	}
 
 	flag, err := repo.CreateFlag(dbConn, flag)
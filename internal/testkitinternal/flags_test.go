@@ -16,7 +16,7 @@ func TestMustCreateUserFlagSuccess(t *testing.T) {
 	})
 
 	name := "deadbeef"
-	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, name)
+	flag := testkitinternal.MustCreateUserFlag(t, user.UUID, name, false)
 
 	require.Equal(t, name, flag.Name)
 }
@@ -33,5 +33,5 @@ func TestMustCreateUserFlagWrongUserUUID(t *testing.T) {
 		require.NotNil(t, r)
 	}()
 
-	testkitinternal.MustCreateUserFlag(t, "dead-beef-dead-beef", "deadbeef")
+	testkitinternal.MustCreateUserFlag(t, "dead-beef-dead-beef", "deadbeef", false)
 }
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"time"
+)
+
+// GetUserResponse represents the response body for a single User in superuser User retrieval requests.
+type GetUserResponse struct {
+	UUID        string    `json:"uuid"`
+	Email       string    `json:"email"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	IsActive    bool      `json:"is_active"`
+	IsSuperUser bool      `json:"is_superuser"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ListUsersResponse represents the response body for superuser User retrieval requests.
+type ListUsersResponse struct {
+	Users []*GetUserResponse `json:"users"`
+}
+
+// UpdateUserStatusRequest represents the request body for superuser User status update requests.
+// Fields that are absent are left unchanged.
+type UpdateUserStatusRequest struct {
+	IsActive    *bool `json:"is_active"`
+	IsSuperUser *bool `json:"is_superuser"`
+}
+
+// ImpersonateUserRequest represents the request body for superuser impersonation requests.
+// Impersonation JWTs are read-only unless writes are explicitly allowed.
+type ImpersonateUserRequest struct {
+	AllowWrites bool `json:"allow_writes"`
+}
+
+// ImpersonateUserResponse represents the response body for superuser impersonation requests.
+type ImpersonateUserResponse struct {
+	Access    string    `json:"access"`
+	ReadOnly  bool      `json:"read_only"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -10,15 +10,24 @@ import (
 )
 
 // AuthJWTClaims represents claims in JWTs used for User authentication.
+// Actor is only set in JWTs issued to superusers impersonating the subject User.
 type AuthJWTClaims struct {
 	Subject   string              `json:"sub"`
+	SessionID string              `json:"sid"`
 	TokenType string              `json:"token_type"`
 	IssuedAt  utils.JSONTimeStamp `json:"iat"`
 	ExpiresAt utils.JSONTimeStamp `json:"exp"`
 	JWTID     string              `json:"jti"`
+	Actor     *ActorClaims        `json:"act,omitempty"`
+	ReadOnly  bool                `json:"read_only,omitempty"`
 	jwt.StandardClaims
 }
 
+// ActorClaims represents the party acting on behalf of the subject of a JWT.
+type ActorClaims struct {
+	Subject string `json:"sub"`
+}
+
 // ActivationJWTClaims represents claims in JWTs used for User activation.
 type ActivationJWTClaims struct {
 	Subject   string              `json:"sub"`
@@ -29,6 +38,57 @@ type ActivationJWTClaims struct {
 	jwt.StandardClaims
 }
 
+// TOTPChallengeJWTClaims represents claims in JWTs used for completing authentication
+// of Users with two-factor authentication enabled.
+type TOTPChallengeJWTClaims struct {
+	Subject   string              `json:"sub"`
+	TokenType string              `json:"token_type"`
+	IssuedAt  utils.JSONTimeStamp `json:"iat"`
+	ExpiresAt utils.JSONTimeStamp `json:"exp"`
+	JWTID     string              `json:"jti"`
+	jwt.StandardClaims
+}
+
+// PasswordResetJWTClaims represents claims in JWTs used for User password reset.
+// PasswordFingerprint binds the token to the password it was issued for,
+// so that the token is no longer valid once the password changes.
+type PasswordResetJWTClaims struct {
+	Subject             string              `json:"sub"`
+	TokenType           string              `json:"token_type"`
+	PasswordFingerprint string              `json:"pwd"`
+	IssuedAt            utils.JSONTimeStamp `json:"iat"`
+	ExpiresAt           utils.JSONTimeStamp `json:"exp"`
+	JWTID               string              `json:"jti"`
+	jwt.StandardClaims
+}
+
+// MagicLinkJWTClaims represents claims in JWTs used for User passwordless login.
+// Email binds the token to the email it was sent to,
+// so that the token is no longer valid once the email changes.
+type MagicLinkJWTClaims struct {
+	Subject   string              `json:"sub"`
+	TokenType string              `json:"token_type"`
+	Email     string              `json:"email"`
+	IssuedAt  utils.JSONTimeStamp `json:"iat"`
+	ExpiresAt utils.JSONTimeStamp `json:"exp"`
+	JWTID     string              `json:"jti"`
+	jwt.StandardClaims
+}
+
+// EmailChangeJWTClaims represents claims in JWTs used for User email change.
+// OldEmail binds the token to the email it was issued for,
+// so that the token is no longer valid once the email changes.
+type EmailChangeJWTClaims struct {
+	Subject   string              `json:"sub"`
+	TokenType string              `json:"token_type"`
+	Email     string              `json:"email"`
+	OldEmail  string              `json:"old_email"`
+	IssuedAt  utils.JSONTimeStamp `json:"iat"`
+	ExpiresAt utils.JSONTimeStamp `json:"exp"`
+	JWTID     string              `json:"jti"`
+	jwt.StandardClaims
+}
+
 // CreateUserRequest represents the request body for create User requests.
 type CreateUserRequest struct {
 	Email     string `json:"email"`
@@ -71,6 +131,93 @@ func (r *ActivateUserRequest) Validate() (bool, map[string][]string) {
 	return v.Passed(), v.Failures()
 }
 
+// ResendActivationRequest represents the request body for resend activation requests.
+type ResendActivationRequest struct {
+	Email string `json:"email"`
+}
+
+// Validate validates fields in ResendActivationRequest.
+func (r *ResendActivationRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringEmail("email", r.Email)
+	v.ValidateStringNotBlank("email", r.Email)
+
+	return v.Passed(), v.Failures()
+}
+
+// RequestPasswordResetRequest represents the request body for password reset requests.
+type RequestPasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// Validate validates fields in RequestPasswordResetRequest.
+func (r *RequestPasswordResetRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringEmail("email", r.Email)
+	v.ValidateStringNotBlank("email", r.Email)
+
+	return v.Passed(), v.Failures()
+}
+
+// ConfirmPasswordResetRequest represents the request body for password reset confirmation requests.
+type ConfirmPasswordResetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Validate validates fields in ConfirmPasswordResetRequest.
+func (r *ConfirmPasswordResetRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringNotBlank("token", r.Token)
+	v.ValidateStringNotBlank("password", r.Password)
+
+	return v.Passed(), v.Failures()
+}
+
+// ChangePasswordRequest represents the request body for password change requests.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Validate validates fields in ChangePasswordRequest.
+func (r *ChangePasswordRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringNotBlank("current_password", r.CurrentPassword)
+	v.ValidateStringNotBlank("new_password", r.NewPassword)
+
+	return v.Passed(), v.Failures()
+}
+
+// ChangeEmailRequest represents the request body for email change requests.
+type ChangeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Validate validates fields in ChangeEmailRequest.
+func (r *ChangeEmailRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringEmail("email", r.Email)
+	v.ValidateStringNotBlank("email", r.Email)
+	v.ValidateStringNotBlank("password", r.Password)
+
+	return v.Passed(), v.Failures()
+}
+
+// ConfirmEmailChangeRequest represents the request body for email change confirmation requests.
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token"`
+}
+
+// Validate validates fields in ConfirmEmailChangeRequest.
+func (r *ConfirmEmailChangeRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringNotBlank("token", r.Token)
+
+	return v.Passed(), v.Failures()
+}
+
 // GetUserMeResponse represents the request body for get current User requests.
 type GetUserMeResponse struct {
 	UUID      string    `json:"uuid"`
@@ -102,6 +249,92 @@ type CreateTokenResponse struct {
 	Refresh string `json:"refresh"`
 }
 
+// CookieTokenResponse represents the response body for requests that create tokens using cookie token transport.
+// The refresh token is only ever set in a cookie.
+type CookieTokenResponse struct {
+	Access string `json:"access"`
+}
+
+// RequestMagicLinkRequest represents the request body for magic link login requests.
+type RequestMagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// Validate validates fields in RequestMagicLinkRequest.
+func (r *RequestMagicLinkRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringEmail("email", r.Email)
+	v.ValidateStringNotBlank("email", r.Email)
+
+	return v.Passed(), v.Failures()
+}
+
+// VerifyMagicLinkRequest represents the request body for magic link verification requests.
+type VerifyMagicLinkRequest struct {
+	Token string `json:"token"`
+}
+
+// Validate validates fields in VerifyMagicLinkRequest.
+func (r *VerifyMagicLinkRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringNotBlank("token", r.Token)
+
+	return v.Passed(), v.Failures()
+}
+
+// TOTPChallengeResponse represents the response body for create token requests
+// of Users with two-factor authentication enabled.
+type TOTPChallengeResponse struct {
+	Challenge string `json:"challenge"`
+}
+
+// VerifyTOTPChallengeRequest represents the request body for two-factor authentication challenge requests.
+type VerifyTOTPChallengeRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+// Validate validates fields in VerifyTOTPChallengeRequest.
+func (r *VerifyTOTPChallengeRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringNotBlank("challenge", r.Challenge)
+	v.ValidateStringNotBlank("code", r.Code)
+
+	return v.Passed(), v.Failures()
+}
+
+// VerifyTOTPChallengeResponse represents the response body for two-factor authentication challenge requests.
+type VerifyTOTPChallengeResponse struct {
+	Access  string `json:"access"`
+	Refresh string `json:"refresh"`
+}
+
+// OIDCAuthURLResponse represents the response body for single sign-on start requests.
+type OIDCAuthURLResponse struct {
+	URL string `json:"url"`
+}
+
+// OIDCCallbackRequest represents the request body for single sign-on callback requests.
+type OIDCCallbackRequest struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+}
+
+// Validate validates fields in OIDCCallbackRequest.
+func (r *OIDCCallbackRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringNotBlank("state", r.State)
+	v.ValidateStringNotBlank("code", r.Code)
+
+	return v.Passed(), v.Failures()
+}
+
+// OIDCCallbackResponse represents the response body for single sign-on callback requests.
+type OIDCCallbackResponse struct {
+	Access  string `json:"access"`
+	Refresh string `json:"refresh"`
+}
+
 // RefreshTokenRequest represents the request body for refresh token requests.
 type RefreshTokenRequest struct {
 	Refresh string `json:"refresh"`
@@ -117,13 +350,74 @@ func (r *RefreshTokenRequest) Validate() (bool, map[string][]string) {
 
 // RefreshTokenResponse represents the response body for refresh token requests.
 type RefreshTokenResponse struct {
-	Access string `json:"access"`
+	Access  string `json:"access"`
+	Refresh string `json:"refresh"`
+}
+
+// EnrollTOTPResponse represents the response body for TOTP enrollment requests.
+type EnrollTOTPResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// ConfirmTOTPRequest represents the request body for TOTP enrollment confirmation requests.
+type ConfirmTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+// Validate validates fields in ConfirmTOTPRequest.
+func (r *ConfirmTOTPRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringNotBlank("code", r.Code)
+
+	return v.Passed(), v.Failures()
+}
+
+// ConfirmTOTPResponse represents the response body for TOTP enrollment confirmation requests.
+type ConfirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// DisableTOTPRequest represents the request body for two-factor authentication disabling requests.
+type DisableTOTPRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// Validate validates fields in DisableTOTPRequest.
+func (r *DisableTOTPRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringNotBlank("password", r.Password)
+	v.ValidateStringNotBlank("code", r.Code)
+
+	return v.Passed(), v.Failures()
+}
+
+// GetSessionResponse represents the response body for a single session in session retrieval requests.
+type GetSessionResponse struct {
+	ID         string    `json:"id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// ListSessionsResponse represents the response body for session retrieval requests.
+type ListSessionsResponse struct {
+	Sessions []*GetSessionResponse `json:"sessions"`
 }
 
 // CreateAPIKeyRequest represents the request body for API Key creation requests.
+// API keys created with a service account UUID belong to that service account.
 type CreateAPIKeyRequest struct {
-	Name      string           `json:"name"`
-	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	Name               string           `json:"name"`
+	Scopes             []string         `json:"scopes"`
+	AllowedCIDRs       []string         `json:"allowed_cidrs"`
+	AllowedOrigins     []string         `json:"allowed_origins"`
+	ServiceAccountUUID *string          `json:"service_account_uuid"`
+	ExpiresAt          pgtype.Timestamp `json:"expires_at"`
 }
 
 // Validate validates fields in CreateAPIKeyRequest.
@@ -136,25 +430,144 @@ func (r *CreateAPIKeyRequest) Validate() (bool, map[string][]string) {
 
 // CreateAPIKeyResponse represents the response body for API Key creation requests.
 type CreateAPIKeyResponse struct {
-	ID        int              `json:"id"`
-	RawKey    string           `json:"raw_key"`
-	UserUUID  string           `json:"user_uuid"`
-	Name      string           `json:"name"`
-	CreatedAt time.Time        `json:"created_at"`
-	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	ID                 int              `json:"id"`
+	RawKey             string           `json:"raw_key"`
+	UserUUID           string           `json:"user_uuid"`
+	ServiceAccountUUID pgtype.Text      `json:"service_account_uuid"`
+	Name               string           `json:"name"`
+	Scopes             []string         `json:"scopes"`
+	AllowedCIDRs       []string         `json:"allowed_cidrs"`
+	AllowedOrigins     []string         `json:"allowed_origins"`
+	CreatedAt          time.Time        `json:"created_at"`
+	ExpiresAt          pgtype.Timestamp `json:"expires_at"`
 }
 
 // GetAPIKeyResponse represents the response body for a single API Key in API Key retrieval requests.
 type GetAPIKeyResponse struct {
-	ID        int              `json:"id"`
-	UserUUID  string           `json:"user_uuid"`
-	Prefix    string           `json:"prefix"`
-	Name      string           `json:"name"`
-	CreatedAt time.Time        `json:"created_at"`
-	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	ID                   int              `json:"id"`
+	UserUUID             string           `json:"user_uuid"`
+	ServiceAccountUUID   pgtype.Text      `json:"service_account_uuid"`
+	Prefix               string           `json:"prefix"`
+	Name                 string           `json:"name"`
+	Scopes               []string         `json:"scopes"`
+	AllowedCIDRs         []string         `json:"allowed_cidrs"`
+	AllowedOrigins       []string         `json:"allowed_origins"`
+	CreatedAt            time.Time        `json:"created_at"`
+	ExpiresAt            pgtype.Timestamp `json:"expires_at"`
+	PreviousKeyExpiresAt pgtype.Timestamp `json:"previous_key_expires_at"`
+	LastUsedAt           pgtype.Timestamp `json:"last_used_at"`
+	LastUsedIP           string           `json:"last_used_ip"`
+	RequestCount         int64            `json:"request_count"`
 }
 
 // ListAPIKeysResponse represents the response body for API Key retrieval requests.
 type ListAPIKeysResponse struct {
 	Keys []*GetAPIKeyResponse `json:"keys"`
 }
+
+// UpdateAPIKeyRequest represents the request body for API Key update requests.
+// Fields that are absent are left unchanged, a null expiration date removes the expiration,
+// and an empty list of allowed CIDR ranges or origins removes the IP or origin restriction.
+type UpdateAPIKeyRequest struct {
+	Name           *string                              `json:"name"`
+	AllowedCIDRs   *[]string                            `json:"allowed_cidrs"`
+	AllowedOrigins *[]string                            `json:"allowed_origins"`
+	ExpiresAt      utils.JSONOptional[pgtype.Timestamp] `json:"expires_at"`
+}
+
+// Validate validates fields in UpdateAPIKeyRequest.
+func (r *UpdateAPIKeyRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	if r.Name != nil {
+		v.ValidateStringNotBlank("name", *r.Name)
+	}
+
+	return v.Passed(), v.Failures()
+}
+
+// RotateAPIKeyResponse represents the response body for API Key rotation requests.
+type RotateAPIKeyResponse struct {
+	ID                   int              `json:"id"`
+	RawKey               string           `json:"raw_key"`
+	UserUUID             string           `json:"user_uuid"`
+	ServiceAccountUUID   pgtype.Text      `json:"service_account_uuid"`
+	Name                 string           `json:"name"`
+	Scopes               []string         `json:"scopes"`
+	AllowedCIDRs         []string         `json:"allowed_cidrs"`
+	AllowedOrigins       []string         `json:"allowed_origins"`
+	CreatedAt            time.Time        `json:"created_at"`
+	ExpiresAt            pgtype.Timestamp `json:"expires_at"`
+	PreviousKeyExpiresAt pgtype.Timestamp `json:"previous_key_expires_at"`
+}
+
+// GetAPIKeyDailyUsageResponse represents the response body for a single day in API Key usage retrieval requests.
+type GetAPIKeyDailyUsageResponse struct {
+	Date         string `json:"date"`
+	RequestCount int64  `json:"request_count"`
+}
+
+// GetAPIKeyUsageResponse represents the response body for API Key usage retrieval requests.
+type GetAPIKeyUsageResponse struct {
+	ID    int                            `json:"id"`
+	Usage []*GetAPIKeyDailyUsageResponse `json:"usage"`
+}
+
+// CreateServiceAccountRequest represents the request body for service account creation requests.
+type CreateServiceAccountRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Scopes      []string `json:"scopes"`
+}
+
+// Validate validates fields in CreateServiceAccountRequest.
+func (r *CreateServiceAccountRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	v.ValidateStringNotBlank("name", r.Name)
+
+	return v.Passed(), v.Failures()
+}
+
+// CreateServiceAccountResponse represents the response body for service account creation requests.
+type CreateServiceAccountResponse struct {
+	UUID        string    `json:"uuid"`
+	OwnerUUID   string    `json:"owner_uuid"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Scopes      []string  `json:"scopes"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// GetServiceAccountResponse represents the response body for a single service account in service account retrieval requests.
+type GetServiceAccountResponse struct {
+	UUID        string    `json:"uuid"`
+	OwnerUUID   string    `json:"owner_uuid"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Scopes      []string  `json:"scopes"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ListServiceAccountsResponse represents the response body for service account retrieval requests.
+type ListServiceAccountsResponse struct {
+	ServiceAccounts []*GetServiceAccountResponse `json:"service_accounts"`
+}
+
+// UpdateServiceAccountRequest represents the request body for service account update requests.
+// Fields that are absent are left unchanged.
+type UpdateServiceAccountRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+// Validate validates fields in UpdateServiceAccountRequest.
+func (r *UpdateServiceAccountRequest) Validate() (bool, map[string][]string) {
+	v := validate.NewValidator()
+	if r.Name != nil {
+		v.ValidateStringNotBlank("name", *r.Name)
+	}
+
+	return v.Passed(), v.Failures()
+}
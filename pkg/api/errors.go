@@ -7,20 +7,47 @@ const (
 	ErrCodeResourceNotFound    = "resource_not_found"
 	ErrCodeInvalidCredentials  = "invalid_credentials"
 	ErrCodeMissingCredentials  = "missing_credentials"
+	ErrCodePermissionDenied    = "permission_denied"
+	ErrCodeTooManyRequests     = "too_many_requests"
 	ErrCodeInternalServerError = "internal_server_error"
 )
 
 // Error details.
 const (
-	ErrDetailInvalidRequestData     = "Invalid or malformed request data."
-	ErrDetailUserExists             = "User already exists"
-	ErrDetailUserNotFound           = "User not found"
-	ErrDetailInvalidEmailOrPassword = "Incorrect email or password."
-	ErrDetailInvalidToken           = "Provided token is invalid"
-	ErrDetailMissingCredentials     = "No credentials were provided"
-	ErrDetailInternalServerError    = "Internal server error occurred."
-	ErrDetailAPIKeyNotFound         = "API key not found"
-	ErrDetailFlagNotFound           = "Flag not found"
+	ErrDetailInvalidRequestData        = "Invalid or malformed request data."
+	ErrDetailUserExists                = "User already exists"
+	ErrDetailUserNotFound              = "User not found"
+	ErrDetailCannotUpdateOwnStatus     = "Cannot change own active or superuser status"
+	ErrDetailSuperUserRequired         = "Superuser privileges are required"
+	ErrDetailCannotImpersonateUser     = "Cannot impersonate self or other superusers"
+	ErrDetailImpersonationReadOnly     = "Impersonated session is read-only"
+	ErrDetailInvalidEmailOrPassword    = "Incorrect email or password."
+	ErrDetailInvalidPassword           = "Incorrect password."
+	ErrDetailTooManyLoginAttempts      = "Too many failed login attempts. Try again later."
+	ErrDetailRateLimitExceeded         = "Rate limit exceeded. Try again later."
+	ErrDetailInvalidToken              = "Provided token is invalid"
+	ErrDetailMissingCredentials        = "No credentials were provided"
+	ErrDetailInvalidCSRFToken          = "CSRF token is missing or invalid"
+	ErrDetailInternalServerError       = "Internal server error occurred."
+	ErrDetailAPIKeyExists              = "API key already exists"
+	ErrDetailAPIKeyNotFound            = "API key not found"
+	ErrDetailInvalidAPIKeyScope        = "Invalid API key scope"
+	ErrDetailAPIKeyScopeDenied         = "API key does not have the required scope"
+	ErrDetailInvalidAPIKeyCIDR         = "Invalid API key allowed CIDR range"
+	ErrDetailAPIKeyIPDenied            = "API key cannot be used from this IP address"
+	ErrDetailInvalidAPIKeyOrigin       = "Invalid API key allowed origin"
+	ErrDetailAPIKeyOriginDenied        = "API key cannot be used from this origin"
+	ErrDetailServiceAccountExists      = "Service account already exists"
+	ErrDetailServiceAccountNotFound    = "Service account not found"
+	ErrDetailServiceAccountScopeDenied = "Service account does not have the requested scope"
+	ErrDetailSessionNotFound           = "Session not found"
+	ErrDetailTOTPAlreadyEnabled        = "Two-factor authentication is already enabled"
+	ErrDetailTOTPNotEnabled            = "Two-factor authentication is not enabled"
+	ErrDetailInvalidTOTPCode           = "Incorrect two-factor authentication code."
+	ErrDetailOIDCNotConfigured         = "Single sign-on is not configured"
+	ErrDetailInvalidOIDCState          = "Single sign-on request is invalid or expired"
+	ErrDetailOIDCAuthFailed            = "Single sign-on authentication failed."
+	ErrDetailFlagNotFound              = "Flag not found"
 )
 
 // ErrorResponse represents the general error response body.
@@ -8,8 +8,10 @@ import (
 )
 
 // CreateFlagRequest represents the request body for Flag creation requests.
+// Client-side Flags can be evaluated using client-side API keys.
 type CreateFlagRequest struct {
-	Name string `json:"name"`
+	Name         string `json:"name"`
+	IsClientSide bool   `json:"is_client_side"`
 }
 
 // Validate validates fields in CreateFlagRequest.
@@ -23,22 +25,24 @@ func (r *CreateFlagRequest) Validate() (bool, map[string][]string) {
 
 // CreateFlagResponse represents the response body for Flag creation requests.
 type CreateFlagResponse struct {
-	ID        int       `json:"id"`
-	UserUUID  string    `json:"user_uuid"`
-	Name      string    `json:"name"`
-	IsEnabled bool      `json:"is_enabled"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           int       `json:"id"`
+	UserUUID     string    `json:"user_uuid"`
+	Name         string    `json:"name"`
+	IsEnabled    bool      `json:"is_enabled"`
+	IsClientSide bool      `json:"is_client_side"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // GetFlagByIDResponse represents the response body for a single Flag in Flag retrieval requests.
 type GetFlagByIDResponse struct {
-	ID        int       `json:"id"`
-	UserUUID  string    `json:"user_uuid"`
-	Name      string    `json:"name"`
-	IsEnabled bool      `json:"is_enabled"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           int       `json:"id"`
+	UserUUID     string    `json:"user_uuid"`
+	Name         string    `json:"name"`
+	IsEnabled    bool      `json:"is_enabled"`
+	IsClientSide bool      `json:"is_client_side"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // GetFlagByNameResponse represents the response body for a single Flag in Flag retrieval requests.
@@ -78,8 +82,10 @@ type DisableFlagResponse struct {
 }
 
 // UpdateFlagRequest represents the request body for Flag update requests.
+// Whether or not the Flag is client-side is left unchanged if absent.
 type UpdateFlagRequest struct {
-	IsEnabled bool `json:"is_enabled"`
+	IsEnabled    bool  `json:"is_enabled"`
+	IsClientSide *bool `json:"is_client_side"`
 }
 
 // Validate validates fields in UpdateFlagRequest.
@@ -91,10 +97,23 @@ func (r *UpdateFlagRequest) Validate() (bool, map[string][]string) {
 
 // UpdateFlagResponse represents the response body for a single Flag in Flag update requests.
 type UpdateFlagResponse struct {
-	ID        int       `json:"id"`
-	UserUUID  string    `json:"user_uuid"`
-	Name      string    `json:"name"`
-	IsEnabled bool      `json:"is_enabled"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           int       `json:"id"`
+	UserUUID     string    `json:"user_uuid"`
+	Name         string    `json:"name"`
+	IsEnabled    bool      `json:"is_enabled"`
+	IsClientSide bool      `json:"is_client_side"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// EvaluateClientSideFlagResponse represents the response body for client-side Flag evaluation requests.
+// It only holds the evaluated value of the Flag, since client-side API keys are public.
+type EvaluateClientSideFlagResponse struct {
+	Name  string `json:"name"`
+	Value bool   `json:"value"`
+}
+
+// EvaluateClientSideFlagsResponse represents the response body for evaluation requests of all client-side Flags.
+type EvaluateClientSideFlagsResponse struct {
+	Flags []*EvaluateClientSideFlagResponse `json:"flags"`
 }
@@ -1,15 +1,70 @@
 package errutils
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // General shared errors.
 var (
-	ErrInvalidToken        = errors.New("invalid token")
-	ErrInvalidCredentials  = errors.New("invalid credentials")
-	ErrUserAlreadyExists   = errors.New("user already exists")
-	ErrUserNotFound        = errors.New("user not found")
-	ErrAPIKeyAlreadyExists = errors.New("api key already exists")
-	ErrAPIKeyNotFound      = errors.New("api key not found")
-	ErrFlagAlreadyExists   = errors.New("flag already exists")
-	ErrFlagNotFound        = errors.New("flag not found")
+	ErrInvalidToken                = errors.New("invalid token")
+	ErrInvalidCredentials          = errors.New("invalid credentials")
+	ErrTooManyLoginAttempts        = errors.New("too many login attempts")
+	ErrWeakPassword                = errors.New("weak password")
+	ErrUserAlreadyExists           = errors.New("user already exists")
+	ErrUserNotFound                = errors.New("user not found")
+	ErrCannotUpdateOwnStatus       = errors.New("cannot update own status")
+	ErrCannotImpersonateUser       = errors.New("cannot impersonate user")
+	ErrAPIKeyAlreadyExists         = errors.New("api key already exists")
+	ErrAPIKeyNotFound              = errors.New("api key not found")
+	ErrInvalidAPIKeyScope          = errors.New("invalid api key scope")
+	ErrAPIKeyScopeDenied           = errors.New("api key scope denied")
+	ErrInvalidAPIKeyCIDR           = errors.New("invalid api key cidr")
+	ErrAPIKeyIPDenied              = errors.New("api key ip denied")
+	ErrInvalidAPIKeyOrigin         = errors.New("invalid api key origin")
+	ErrServiceAccountAlreadyExists = errors.New("service account already exists")
+	ErrServiceAccountNotFound      = errors.New("service account not found")
+	ErrServiceAccountScopeDenied   = errors.New("service account scope denied")
+	ErrSessionNotFound             = errors.New("session not found")
+	ErrTOTPAlreadyEnabled          = errors.New("totp already enabled")
+	ErrTOTPNotEnabled              = errors.New("totp not enabled")
+	ErrInvalidTOTPCode             = errors.New("invalid totp code")
+	ErrOIDCNotConfigured           = errors.New("oidc not configured")
+	ErrInvalidOIDCState            = errors.New("invalid oidc state")
+	ErrOIDCAuthFailed              = errors.New("oidc authentication failed")
+	ErrFlagAlreadyExists           = errors.New("flag already exists")
+	ErrFlagNotFound                = errors.New("flag not found")
 )
+
+// ValidationError represents an error for a request with fields that failed validation.
+type ValidationError struct {
+	Err      error
+	Failures map[string][]string
+}
+
+// Error returns the error message along with the validation failures.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s, validation failures %v", e.Err, e.Failures)
+}
+
+// Unwrap returns the underlying error.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfterError represents an error for a request that can be retried after some time.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+// Error returns the error message along with the time after which the request can be retried.
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Err, e.RetryAfter)
+}
+
+// Unwrap returns the underlying error.
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
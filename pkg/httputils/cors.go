@@ -0,0 +1,151 @@
+package httputils
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSAnyOrigin is the wildcard origin that matches any origin.
+const CORSAnyOrigin = "*"
+
+// CORSPolicy represents the cross-origin requests allowed on a route.
+// AllowedOrigins may contain CORSAnyOrigin to allow requests from any origin.
+type CORSPolicy struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// AllowsOrigin determines whether or not a given origin is allowed by the policy.
+func (policy CORSPolicy) AllowsOrigin(origin string) bool {
+	origin, ok := NormalizeOrigin(origin)
+	if !ok {
+		return false
+	}
+
+	for _, allowedOrigin := range policy.AllowedOrigins {
+		if allowedOrigin == CORSAnyOrigin || allowedOrigin == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NormalizeOrigin validates an origin of the form scheme://host[:port]
+// and returns it with its scheme and host in lowercase.
+func NormalizeOrigin(origin string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil {
+		return "", false
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+
+	if u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return "", false
+	}
+
+	return strings.ToLower(u.Scheme + "://" + u.Host), true
+}
+
+// ParseCORSOrigins parses a comma-separated list of allowed origins.
+func ParseCORSOrigins(s string) ([]string, error) {
+	origins := make([]string, 0)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if entry == CORSAnyOrigin {
+			origins = append(origins, CORSAnyOrigin)
+			continue
+		}
+
+		origin, ok := NormalizeOrigin(entry)
+		if !ok {
+			return nil, fmt.Errorf("ParseCORSOrigins failed to NormalizeOrigin %s", entry)
+		}
+
+		origins = append(origins, origin)
+	}
+
+	return origins, nil
+}
+
+// IsCORSPreflight determines whether or not a request is a CORS preflight request.
+func IsCORSPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// CORSMiddleware creates middleware that sets CORS headers on requests from origins allowed by a given policy.
+// Preflight requests are answered directly, without calling the next handler.
+// Requests from origins that are not allowed are passed on without CORS headers, so browsers block their responses.
+func CORSMiddleware(policy CORSPolicy) MiddlewareFunc {
+	allowsAnyOrigin := slices.Contains(policy.AllowedOrigins, CORSAnyOrigin)
+
+	return func(next HandlerFunc) HandlerFunc {
+		return HandlerFunc(func(w *ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Add("Vary", "Origin")
+
+			if !policy.AllowsOrigin(origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// credentialed requests cannot use the wildcard origin, so the origin is echoed instead
+			if allowsAnyOrigin && !policy.AllowCredentials {
+				header.Set("Access-Control-Allow-Origin", CORSAnyOrigin)
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if policy.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !IsCORSPreflight(r) {
+				if len(policy.ExposedHeaders) > 0 {
+					header.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+
+			if len(policy.AllowedHeaders) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+			}
+
+			if policy.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.FormatInt(int64(policy.MaxAge/time.Second), 10))
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package httputils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeOrigin(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name       string
+		origin     string
+		wantOrigin string
+		wantOk     bool
+	}{
+		{
+			name:       "HTTPS origin",
+			origin:     "https://app.example.com",
+			wantOrigin: "https://app.example.com",
+			wantOk:     true,
+		},
+		{
+			name:       "HTTP origin with port",
+			origin:     "http://localhost:3000",
+			wantOrigin: "http://localhost:3000",
+			wantOk:     true,
+		},
+		{
+			name:       "Uppercase origin with trailing slash",
+			origin:     " HTTPS://App.Example.com/ ",
+			wantOrigin: "https://app.example.com",
+			wantOk:     true,
+		},
+		{
+			name:       "Origin with path",
+			origin:     "https://app.example.com/path",
+			wantOrigin: "",
+			wantOk:     false,
+		},
+		{
+			name:       "Origin with query",
+			origin:     "https://app.example.com?q=1",
+			wantOrigin: "",
+			wantOk:     false,
+		},
+		{
+			name:       "Origin with user info",
+			origin:     "https://user@app.example.com",
+			wantOrigin: "",
+			wantOk:     false,
+		},
+		{
+			name:       "Unsupported scheme",
+			origin:     "ftp://app.example.com",
+			wantOrigin: "",
+			wantOk:     false,
+		},
+		{
+			name:       "Missing host",
+			origin:     "https://",
+			wantOrigin: "",
+			wantOk:     false,
+		},
+		{
+			name:       "Null origin",
+			origin:     "null",
+			wantOrigin: "",
+			wantOk:     false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			origin, ok := httputils.NormalizeOrigin(testcase.origin)
+			require.Equal(t, testcase.wantOk, ok)
+			require.Equal(t, testcase.wantOrigin, origin)
+		})
+	}
+}
+
+func TestParseCORSOrigins(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		s           string
+		wantOrigins []string
+		wantErr     bool
+	}{
+		{
+			name:        "Empty string",
+			s:           "",
+			wantOrigins: []string{},
+			wantErr:     false,
+		},
+		{
+			name:        "Wildcard origin",
+			s:           "*",
+			wantOrigins: []string{"*"},
+			wantErr:     false,
+		},
+		{
+			name:        "Multiple origins",
+			s:           "https://app.example.com, http://localhost:3000,",
+			wantOrigins: []string{"https://app.example.com", "http://localhost:3000"},
+			wantErr:     false,
+		},
+		{
+			name:        "Invalid origin",
+			s:           "https://app.example.com,app.example.com",
+			wantOrigins: nil,
+			wantErr:     true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			origins, err := httputils.ParseCORSOrigins(testcase.s)
+			if testcase.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantOrigins, origins)
+		})
+	}
+}
+
+func TestCORSPolicyAllowsOrigin(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name           string
+		allowedOrigins []string
+		origin         string
+		wantAllowed    bool
+	}{
+		{
+			name:           "Listed origin",
+			allowedOrigins: []string{"https://app.example.com"},
+			origin:         "https://app.example.com",
+			wantAllowed:    true,
+		},
+		{
+			name:           "Listed origin in different case",
+			allowedOrigins: []string{"https://app.example.com"},
+			origin:         "https://APP.example.com",
+			wantAllowed:    true,
+		},
+		{
+			name:           "Unlisted origin",
+			allowedOrigins: []string{"https://app.example.com"},
+			origin:         "https://evil.example.com",
+			wantAllowed:    false,
+		},
+		{
+			name:           "Different scheme",
+			allowedOrigins: []string{"https://app.example.com"},
+			origin:         "http://app.example.com",
+			wantAllowed:    false,
+		},
+		{
+			name:           "Wildcard origin",
+			allowedOrigins: []string{"*"},
+			origin:         "https://evil.example.com",
+			wantAllowed:    true,
+		},
+		{
+			name:           "Invalid origin with wildcard",
+			allowedOrigins: []string{"*"},
+			origin:         "null",
+			wantAllowed:    false,
+		},
+		{
+			name:           "No allowed origins",
+			allowedOrigins: []string{},
+			origin:         "https://app.example.com",
+			wantAllowed:    false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			policy := httputils.CORSPolicy{AllowedOrigins: testcase.allowedOrigins}
+			require.Equal(t, testcase.wantAllowed, policy.AllowsOrigin(testcase.origin))
+		})
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	t.Parallel()
+
+	handler := func(w *httputils.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	optionsHandler := func(w *httputils.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+	policy := httputils.CORSPolicy{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPut},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"RateLimit-Remaining"},
+		MaxAge:         10 * time.Minute,
+	}
+	anyOriginPolicy := httputils.CORSPolicy{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{http.MethodGet},
+	}
+	credentialsPolicy := httputils.CORSPolicy{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{http.MethodGet},
+		AllowCredentials: true,
+	}
+
+	router := httputils.NewRouter()
+	router.GET("/cors", handler, httputils.CORSMiddleware(policy))
+	router.OPTIONS("/cors", optionsHandler, httputils.CORSMiddleware(policy))
+	router.GET("/cors/any", handler, httputils.CORSMiddleware(anyOriginPolicy))
+	router.GET("/cors/credentials", handler, httputils.CORSMiddleware(credentialsPolicy))
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	testcases := []struct {
+		name              string
+		method            string
+		path              string
+		headers           map[string]string
+		wantStatusCode    int
+		wantAllowOrigin   string
+		wantAllowMethods  string
+		wantAllowHeaders  string
+		wantExposeHeaders string
+		wantMaxAge        string
+		wantCredentials   string
+		wantVary          []string
+	}{
+		{
+			name:           "Request without origin",
+			method:         http.MethodGet,
+			path:           "/cors",
+			headers:        map[string]string{},
+			wantStatusCode: http.StatusOK,
+			wantVary:       nil,
+		},
+		{
+			name:   "Request from allowed origin",
+			method: http.MethodGet,
+			path:   "/cors",
+			headers: map[string]string{
+				"Origin": "https://app.example.com",
+			},
+			wantStatusCode:    http.StatusOK,
+			wantAllowOrigin:   "https://app.example.com",
+			wantExposeHeaders: "RateLimit-Remaining",
+			wantVary:          []string{"Origin"},
+		},
+		{
+			name:   "Request from disallowed origin",
+			method: http.MethodGet,
+			path:   "/cors",
+			headers: map[string]string{
+				"Origin": "https://evil.example.com",
+			},
+			wantStatusCode: http.StatusOK,
+			wantVary:       []string{"Origin"},
+		},
+		{
+			name:   "Preflight from allowed origin",
+			method: http.MethodOptions,
+			path:   "/cors",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPut,
+				"Access-Control-Request-Headers": "authorization",
+			},
+			wantStatusCode:   http.StatusNoContent,
+			wantAllowOrigin:  "https://app.example.com",
+			wantAllowMethods: "GET, PUT",
+			wantAllowHeaders: "Authorization, Content-Type",
+			wantMaxAge:       "600",
+			wantVary:         []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name:   "Preflight from disallowed origin",
+			method: http.MethodOptions,
+			path:   "/cors",
+			headers: map[string]string{
+				"Origin":                        "https://evil.example.com",
+				"Access-Control-Request-Method": http.MethodPut,
+			},
+			wantStatusCode: http.StatusNoContent,
+			wantVary:       []string{"Origin"},
+		},
+		{
+			name:   "Request to route allowing any origin",
+			method: http.MethodGet,
+			path:   "/cors/any",
+			headers: map[string]string{
+				"Origin": "https://evil.example.com",
+			},
+			wantStatusCode:  http.StatusOK,
+			wantAllowOrigin: "*",
+			wantVary:        []string{"Origin"},
+		},
+		{
+			name:   "Request to route allowing credentials from any origin",
+			method: http.MethodGet,
+			path:   "/cors/credentials",
+			headers: map[string]string{
+				"Origin": "https://evil.example.com",
+			},
+			wantStatusCode:  http.StatusOK,
+			wantAllowOrigin: "https://evil.example.com",
+			wantCredentials: "true",
+			wantVary:        []string{"Origin"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(testcase.method, srv.URL+testcase.path, http.NoBody)
+			require.NoError(t, err)
+
+			for key, value := range testcase.headers {
+				req.Header.Set(key, value)
+			}
+
+			res, err := httpClient.Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				err := res.Body.Close()
+				require.NoError(t, err)
+			})
+
+			require.Equal(t, testcase.wantStatusCode, res.StatusCode)
+			require.Equal(t, testcase.wantAllowOrigin, res.Header.Get("Access-Control-Allow-Origin"))
+			require.Equal(t, testcase.wantAllowMethods, res.Header.Get("Access-Control-Allow-Methods"))
+			require.Equal(t, testcase.wantAllowHeaders, res.Header.Get("Access-Control-Allow-Headers"))
+			require.Equal(t, testcase.wantExposeHeaders, res.Header.Get("Access-Control-Expose-Headers"))
+			require.Equal(t, testcase.wantMaxAge, res.Header.Get("Access-Control-Max-Age"))
+			require.Equal(t, testcase.wantCredentials, res.Header.Get("Access-Control-Allow-Credentials"))
+			require.Equal(t, testcase.wantVary, res.Header.Values("Vary"))
+		})
+	}
+}
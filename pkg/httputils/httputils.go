@@ -1,7 +1,13 @@
 package httputils
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -9,6 +15,12 @@ import (
 // HTTPClientDefaultTimeout is the default timeout for HTTP clients.
 const HTTPClientDefaultTimeout = 60 * time.Second
 
+// contextKey is the type for keys of values set in request context by httputils.
+type contextKey string
+
+// contextKeyClientIP is the context key for the client IP address resolved by ClientIPHandler.
+const contextKeyClientIP contextKey = "clientIP"
+
 // HandlerFunc takes in a request and response writer and implements a handler.
 type HandlerFunc func(w *ResponseWriter, r *http.Request)
 
@@ -27,11 +39,152 @@ func GetAuthorizationHeader(header http.Header, authType string) (string, bool)
 	return strings.TrimSpace(token), true
 }
 
+// GetClientIP returns the IP address of the client that sent the request.
+// If the request went through ClientIPHandler, the IP address it resolved is returned,
+// otherwise the address of the request's immediate peer is returned.
+func GetClientIP(r *http.Request) string {
+	clientIP, ok := r.Context().Value(contextKeyClientIP).(string)
+	if ok {
+		return clientIP
+	}
+
+	return getRemoteIP(r)
+}
+
+// getRemoteIP returns the IP address of the request's immediate peer.
+func getRemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// ParseIPPrefix parses an IP address or CIDR range into a masked prefix.
+// IP addresses are parsed as single-address prefixes.
+func ParseIPPrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("ParseIPPrefix failed to netip.ParsePrefix %s: %w", s, err)
+		}
+
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("ParseIPPrefix failed to netip.ParseAddr %s: %w", s, err)
+	}
+
+	addr = addr.Unmap()
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and CIDR ranges of trusted proxies.
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	trustedProxies := make([]netip.Prefix, 0)
+	for _, entry := range strings.Split(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		prefix, err := ParseIPPrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("ParseTrustedProxies failed to ParseIPPrefix: %w", err)
+		}
+
+		trustedProxies = append(trustedProxies, prefix)
+	}
+
+	return trustedProxies, nil
+}
+
+// PrefixesContainIP determines whether or not an IP address is within any of the given prefixes.
+func PrefixesContainIP(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveClientIP determines the IP address of the client that sent the request.
+// If the request's immediate peer is a trusted proxy, the X-Forwarded-For header is walked from right to left,
+// skipping trusted proxies, and the first untrusted address is returned.
+// Addresses left of that are set by the client and cannot be trusted.
+func resolveClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	clientIP := getRemoteIP(r)
+	if !PrefixesContainIP(trustedProxies, clientIP) {
+		return clientIP
+	}
+
+	forwardedIPs := make([]string, 0)
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwardedIPs = append(forwardedIPs, strings.Split(header, ",")...)
+	}
+
+	for i := len(forwardedIPs) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwardedIPs[i]))
+		if err != nil {
+			break
+		}
+
+		clientIP = addr.Unmap().String()
+		if !PrefixesContainIP(trustedProxies, clientIP) {
+			break
+		}
+	}
+
+	return clientIP
+}
+
+// ClientIPHandler wraps a handler and resolves the IP address of the client that sent each request,
+// using the X-Forwarded-For header of requests sent through trusted proxies.
+// The resolved IP address can then be retrieved using GetClientIP.
+func ClientIPHandler(next http.Handler, trustedProxies []netip.Prefix) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), contextKeyClientIP, resolveClientIP(r, trustedProxies))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // IsHTTPSuccess determines whether or not a given status code is 2xx
 func IsHTTPSuccess(statusCode int) bool {
 	return statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices
 }
 
+// IsSafeMethod determines whether or not a given HTTP method is safe, i.e. read-only.
+func IsSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetRetryAfterHeader sets Retry-After header to a given duration, rounded up to the nearest second.
+func SetRetryAfterHeader(header http.Header, retryAfter time.Duration) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	header.Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
 // NewHTTPClient creates and returns a new HTTP client.
 func NewHTTPClient(modifier func(c *http.Client)) *http.Client {
 	client := &http.Client{
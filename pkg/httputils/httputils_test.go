@@ -2,6 +2,8 @@ package httputils_test
 
 import (
 	"net/http"
+	"net/http/httptest"
+	"net/netip"
 	"testing"
 	"time"
 
@@ -68,6 +70,333 @@ func TestGetAuthorizationHeader(t *testing.T) {
 	}
 }
 
+func TestGetClientIP(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name       string
+		remoteAddr string
+		wantIP     string
+	}{
+		{
+			name:       "IPv4 address with port",
+			remoteAddr: "203.0.113.42:54321",
+			wantIP:     "203.0.113.42",
+		},
+		{
+			name:       "IPv6 address with port",
+			remoteAddr: "[2001:db8::1]:54321",
+			wantIP:     "2001:db8::1",
+		},
+		{
+			name:       "Address without port",
+			remoteAddr: "203.0.113.42",
+			wantIP:     "203.0.113.42",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, "/", http.NoBody)
+			require.NoError(t, err)
+			req.RemoteAddr = testcase.remoteAddr
+
+			require.Equal(t, testcase.wantIP, httputils.GetClientIP(req))
+		})
+	}
+}
+
+func TestParseIPPrefix(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name       string
+		s          string
+		wantPrefix string
+		wantErr    bool
+	}{
+		{
+			name:       "IPv4 CIDR range",
+			s:          "10.0.0.0/8",
+			wantPrefix: "10.0.0.0/8",
+			wantErr:    false,
+		},
+		{
+			name:       "IPv4 CIDR range with host bits",
+			s:          "10.1.2.3/16",
+			wantPrefix: "10.1.0.0/16",
+			wantErr:    false,
+		},
+		{
+			name:       "IPv6 CIDR range",
+			s:          "2001:db8::/32",
+			wantPrefix: "2001:db8::/32",
+			wantErr:    false,
+		},
+		{
+			name:       "IPv4 address",
+			s:          " 203.0.113.42 ",
+			wantPrefix: "203.0.113.42/32",
+			wantErr:    false,
+		},
+		{
+			name:       "IPv4-mapped IPv6 address",
+			s:          "::ffff:203.0.113.42",
+			wantPrefix: "203.0.113.42/32",
+			wantErr:    false,
+		},
+		{
+			name:       "IPv6 address",
+			s:          "2001:db8::1",
+			wantPrefix: "2001:db8::1/128",
+			wantErr:    false,
+		},
+		{
+			name:    "Invalid CIDR range",
+			s:       "10.0.0.0/33",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid IP address",
+			s:       "example.com",
+			wantErr: true,
+		},
+		{
+			name:    "Empty string",
+			s:       "",
+			wantErr: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			prefix, err := httputils.ParseIPPrefix(testcase.s)
+			if testcase.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantPrefix, prefix.String())
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name         string
+		s            string
+		wantPrefixes []string
+		wantErr      bool
+	}{
+		{
+			name:         "Empty string",
+			s:            "",
+			wantPrefixes: []string{},
+			wantErr:      false,
+		},
+		{
+			name:         "Single CIDR range",
+			s:            "10.0.0.0/8",
+			wantPrefixes: []string{"10.0.0.0/8"},
+			wantErr:      false,
+		},
+		{
+			name:         "Multiple addresses and CIDR ranges",
+			s:            "10.0.0.0/8, 172.16.0.1,,fd00::/8",
+			wantPrefixes: []string{"10.0.0.0/8", "172.16.0.1/32", "fd00::/8"},
+			wantErr:      false,
+		},
+		{
+			name:    "Invalid entry",
+			s:       "10.0.0.0/8,localhost",
+			wantErr: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			prefixes, err := httputils.ParseTrustedProxies(testcase.s)
+			if testcase.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			gotPrefixes := make([]string, len(prefixes))
+			for i, prefix := range prefixes {
+				gotPrefixes[i] = prefix.String()
+			}
+			require.Equal(t, testcase.wantPrefixes, gotPrefixes)
+		})
+	}
+}
+
+func TestPrefixesContainIP(t *testing.T) {
+	t.Parallel()
+
+	prefixes := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+
+	testcases := []struct {
+		name         string
+		prefixes     []netip.Prefix
+		ip           string
+		wantContains bool
+	}{
+		{
+			name:         "IPv4 address in range",
+			prefixes:     prefixes,
+			ip:           "10.1.2.3",
+			wantContains: true,
+		},
+		{
+			name:         "IPv4-mapped IPv6 address in range",
+			prefixes:     prefixes,
+			ip:           "::ffff:10.1.2.3",
+			wantContains: true,
+		},
+		{
+			name:         "IPv6 address in range",
+			prefixes:     prefixes,
+			ip:           "2001:db8::42",
+			wantContains: true,
+		},
+		{
+			name:         "IPv4 address out of range",
+			prefixes:     prefixes,
+			ip:           "11.0.0.1",
+			wantContains: false,
+		},
+		{
+			name:         "No prefixes",
+			prefixes:     nil,
+			ip:           "10.1.2.3",
+			wantContains: false,
+		},
+		{
+			name:         "Invalid IP address",
+			prefixes:     prefixes,
+			ip:           "10.1.2",
+			wantContains: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testcase.wantContains, httputils.PrefixesContainIP(testcase.prefixes, testcase.ip))
+		})
+	}
+}
+
+func TestClientIPHandler(t *testing.T) {
+	t.Parallel()
+
+	trustedProxies := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+
+	testcases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		wantIP       string
+	}{
+		{
+			name:         "Untrusted peer without X-Forwarded-For",
+			remoteAddr:   "203.0.113.42:54321",
+			forwardedFor: nil,
+			wantIP:       "203.0.113.42",
+		},
+		{
+			name:         "Untrusted peer with spoofed X-Forwarded-For",
+			remoteAddr:   "203.0.113.42:54321",
+			forwardedFor: []string{"198.51.100.7"},
+			wantIP:       "203.0.113.42",
+		},
+		{
+			name:         "Trusted proxy with X-Forwarded-For",
+			remoteAddr:   "10.0.0.1:54321",
+			forwardedFor: []string{"198.51.100.7"},
+			wantIP:       "198.51.100.7",
+		},
+		{
+			name:         "Chain of trusted proxies",
+			remoteAddr:   "10.0.0.1:54321",
+			forwardedFor: []string{"198.51.100.7, 10.0.0.3", "10.0.0.2"},
+			wantIP:       "198.51.100.7",
+		},
+		{
+			name:         "Client-supplied X-Forwarded-For entries are ignored",
+			remoteAddr:   "10.0.0.1:54321",
+			forwardedFor: []string{"192.0.2.1, 198.51.100.7"},
+			wantIP:       "198.51.100.7",
+		},
+		{
+			name:         "IPv6 trusted proxy",
+			remoteAddr:   "[2001:db8::1]:54321",
+			forwardedFor: []string{"2001:db9::7"},
+			wantIP:       "2001:db9::7",
+		},
+		{
+			name:         "Trusted proxy without X-Forwarded-For",
+			remoteAddr:   "10.0.0.1:54321",
+			forwardedFor: nil,
+			wantIP:       "10.0.0.1",
+		},
+		{
+			name:         "Only trusted proxies in X-Forwarded-For",
+			remoteAddr:   "10.0.0.1:54321",
+			forwardedFor: []string{"10.0.0.3, 10.0.0.2"},
+			wantIP:       "10.0.0.3",
+		},
+		{
+			name:         "Malformed X-Forwarded-For entry",
+			remoteAddr:   "10.0.0.1:54321",
+			forwardedFor: []string{"198.51.100.7, unknown, 10.0.0.2"},
+			wantIP:       "10.0.0.2",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotIP string
+			handler := httputils.ClientIPHandler(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					gotIP = httputils.GetClientIP(r)
+				}),
+				trustedProxies,
+			)
+
+			req, err := http.NewRequest(http.MethodGet, "/", http.NoBody)
+			require.NoError(t, err)
+			req.RemoteAddr = testcase.remoteAddr
+			for _, forwardedFor := range testcase.forwardedFor {
+				req.Header.Add("X-Forwarded-For", forwardedFor)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			require.Equal(t, testcase.wantIP, gotIP)
+		})
+	}
+}
+
 func TestIsHTTPSuccess(t *testing.T) {
 	t.Parallel()
 
@@ -137,6 +466,101 @@ func TestIsHTTPSuccess(t *testing.T) {
 	}
 }
 
+func TestIsSafeMethod(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		method   string
+		wantSafe bool
+	}{
+		{
+			name:     "GET is safe",
+			method:   http.MethodGet,
+			wantSafe: true,
+		},
+		{
+			name:     "HEAD is safe",
+			method:   http.MethodHead,
+			wantSafe: true,
+		},
+		{
+			name:     "OPTIONS is safe",
+			method:   http.MethodOptions,
+			wantSafe: true,
+		},
+		{
+			name:     "POST is not safe",
+			method:   http.MethodPost,
+			wantSafe: false,
+		},
+		{
+			name:     "PUT is not safe",
+			method:   http.MethodPut,
+			wantSafe: false,
+		},
+		{
+			name:     "PATCH is not safe",
+			method:   http.MethodPatch,
+			wantSafe: false,
+		},
+		{
+			name:     "DELETE is not safe",
+			method:   http.MethodDelete,
+			wantSafe: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, testcase.wantSafe, httputils.IsSafeMethod(testcase.method))
+		})
+	}
+}
+
+func TestSetRetryAfterHeader(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name           string
+		retryAfter     time.Duration
+		wantRetryAfter string
+	}{
+		{
+			name:           "Whole seconds",
+			retryAfter:     2 * time.Minute,
+			wantRetryAfter: "120",
+		},
+		{
+			name:           "Fractional seconds are rounded up",
+			retryAfter:     1500 * time.Millisecond,
+			wantRetryAfter: "2",
+		},
+		{
+			name:           "Less than a second is rounded up to one second",
+			retryAfter:     time.Millisecond,
+			wantRetryAfter: "1",
+		},
+		{
+			name:           "Zero duration is set to one second",
+			retryAfter:     0,
+			wantRetryAfter: "1",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			header := http.Header{}
+			httputils.SetRetryAfterHeader(header, testcase.retryAfter)
+			require.Equal(t, testcase.wantRetryAfter, header.Get("Retry-After"))
+		})
+	}
+}
+
 func TestNewHTTPClient(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,191 @@
+package httputils
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// inMemRateLimitSweepInterval is the minimum interval between sweeps of full buckets in in-memory stores.
+const inMemRateLimitSweepInterval = time.Minute
+
+// RateLimitKeyFunc extracts the key that identifies the bucket a request is counted against.
+// If no key can be extracted, the request is not rate limited.
+type RateLimitKeyFunc func(r *http.Request) (string, bool)
+
+// RateLimitKeyClientIP extracts the IP address of the client that sent the request.
+func RateLimitKeyClientIP(r *http.Request) (string, bool) {
+	clientIP := GetClientIP(r)
+	if clientIP == "" {
+		return "", false
+	}
+
+	return clientIP, true
+}
+
+// RateLimitPolicy represents a token bucket quota.
+// Each bucket holds up to Limit tokens and is refilled at a rate of Limit tokens per Period.
+// Routes sharing a policy name share buckets.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+	Key    RateLimitKeyFunc
+}
+
+// RefillRate returns the number of tokens added to a bucket per second.
+func (policy RateLimitPolicy) RefillRate() float64 {
+	return float64(policy.Limit) / policy.Period.Seconds()
+}
+
+// RateLimitResult represents the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// NewRateLimitResult computes the outcome of taking a token from a bucket
+// from whether or not the token was taken and the number of tokens left in the bucket.
+func NewRateLimitResult(policy RateLimitPolicy, allowed bool, tokens float64) *RateLimitResult {
+	rate := policy.RefillRate()
+	result := &RateLimitResult{
+		Allowed:    allowed,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(policy.Limit) - tokens) / rate * float64(time.Second)),
+	}
+
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+
+	return result
+}
+
+// RefillRateLimitBucket computes the number of tokens in a bucket at a given time,
+// given the number of tokens it held when it was last updated.
+func RefillRateLimitBucket(policy RateLimitPolicy, tokens float64, updatedAt time.Time, now time.Time) float64 {
+	elapsed := max(now.Sub(updatedAt).Seconds(), 0)
+
+	return min(float64(policy.Limit), tokens+elapsed*policy.RefillRate())
+}
+
+// RateLimitStore stores token buckets and takes tokens from them.
+// Implementations must take tokens atomically.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, policy RateLimitPolicy) (*RateLimitResult, error)
+}
+
+// inMemRateLimitBucket represents a token bucket stored in memory.
+type inMemRateLimitBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+// inMemRateLimitStore implements RateLimitStore.
+// Buckets are stored in the memory of the current process,
+// so it is only suitable for servers running as a single node.
+type inMemRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*inMemRateLimitBucket
+	sweptAt time.Time
+}
+
+// NewInMemRateLimitStore returns a new inMemRateLimitStore.
+func NewInMemRateLimitStore() *inMemRateLimitStore {
+	return &inMemRateLimitStore{
+		buckets: make(map[string]*inMemRateLimitBucket),
+	}
+}
+
+// Take takes a token from the bucket under a given key.
+// Buckets that have been refilled completely are swept periodically, since they are equivalent to missing buckets.
+func (store *inMemRateLimitStore) Take(ctx context.Context, key string, policy RateLimitPolicy) (*RateLimitResult, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now().UTC()
+	if now.Sub(store.sweptAt) >= inMemRateLimitSweepInterval {
+		for bucketKey, bucket := range store.buckets {
+			if !now.Before(bucket.fullAt) {
+				delete(store.buckets, bucketKey)
+			}
+		}
+		store.sweptAt = now
+	}
+
+	tokens := float64(policy.Limit)
+	bucket, ok := store.buckets[key]
+	if ok {
+		tokens = RefillRateLimitBucket(policy, bucket.tokens, bucket.updatedAt, now)
+	}
+
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	result := NewRateLimitResult(policy, allowed, tokens)
+	store.buckets[key] = &inMemRateLimitBucket{
+		tokens:    tokens,
+		updatedAt: now,
+		fullAt:    now.Add(result.ResetAfter),
+	}
+
+	return result, nil
+}
+
+// setRateLimitHeaders sets RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, and RateLimit-Reset headers.
+// If the request is rejected, Retry-After header is also set.
+func setRateLimitHeaders(header http.Header, policy RateLimitPolicy, result *RateLimitResult) {
+	header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int64(math.Ceil(policy.Period.Seconds()))))
+	header.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(result.ResetAfter.Seconds())), 10))
+
+	if !result.Allowed {
+		SetRetryAfterHeader(header, result.RetryAfter)
+	}
+}
+
+// RateLimitMiddleware creates middleware that takes a token from the bucket of each request under a given policy.
+// Requests are rejected using the limited handler once the bucket is empty.
+// If store is nil, or the store fails to take a token, requests are let through without rate limiting,
+// so that the store becoming unavailable doesn't take down the routes it protects.
+// Failures to take tokens are reported using logError.
+func RateLimitMiddleware(store RateLimitStore, policy RateLimitPolicy, limited HandlerFunc, logError func(v ...any)) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		if store == nil {
+			return next
+		}
+
+		return HandlerFunc(func(w *ResponseWriter, r *http.Request) {
+			key, ok := policy.Key(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := store.Take(r.Context(), fmt.Sprintf("%s:%s", policy.Name, key), policy)
+			if err != nil {
+				logError("RateLimitMiddleware failed to store.Take:", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setRateLimitHeaders(w.Header(), policy, result)
+			if !result.Allowed {
+				limited.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
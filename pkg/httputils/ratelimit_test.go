@@ -0,0 +1,262 @@
+package httputils_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitKeyClientIP(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.RemoteAddr = "203.0.113.7:4000"
+
+	key, ok := httputils.RateLimitKeyClientIP(req)
+	require.True(t, ok)
+	require.Equal(t, "203.0.113.7", key)
+}
+
+func TestRefillRateLimitBucket(t *testing.T) {
+	t.Parallel()
+
+	policy := httputils.RateLimitPolicy{
+		Name:   "test",
+		Limit:  10,
+		Period: 10 * time.Second,
+	}
+	now := time.Now().UTC()
+
+	testcases := []struct {
+		name       string
+		tokens     float64
+		updatedAt  time.Time
+		wantTokens float64
+	}{
+		{
+			name:       "No time elapsed",
+			tokens:     2,
+			updatedAt:  now,
+			wantTokens: 2,
+		},
+		{
+			name:       "Partially refilled",
+			tokens:     2,
+			updatedAt:  now.Add(-3 * time.Second),
+			wantTokens: 5,
+		},
+		{
+			name:       "Refill capped at limit",
+			tokens:     2,
+			updatedAt:  now.Add(-time.Hour),
+			wantTokens: 10,
+		},
+		{
+			name:       "Updated in the future",
+			tokens:     2,
+			updatedAt:  now.Add(time.Minute),
+			wantTokens: 2,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			tokens := httputils.RefillRateLimitBucket(policy, testcase.tokens, testcase.updatedAt, now)
+			require.InDelta(t, testcase.wantTokens, tokens, 1e-9)
+		})
+	}
+}
+
+func TestNewRateLimitResult(t *testing.T) {
+	t.Parallel()
+
+	policy := httputils.RateLimitPolicy{
+		Name:   "test",
+		Limit:  10,
+		Period: 10 * time.Second,
+	}
+
+	testcases := []struct {
+		name           string
+		allowed        bool
+		tokens         float64
+		wantRemaining  int
+		wantResetAfter time.Duration
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:           "Allowed",
+			allowed:        true,
+			tokens:         7.5,
+			wantRemaining:  7,
+			wantResetAfter: 2500 * time.Millisecond,
+			wantRetryAfter: 0,
+		},
+		{
+			name:           "Rejected",
+			allowed:        false,
+			tokens:         0.25,
+			wantRemaining:  0,
+			wantResetAfter: 9750 * time.Millisecond,
+			wantRetryAfter: 750 * time.Millisecond,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := httputils.NewRateLimitResult(policy, testcase.allowed, testcase.tokens)
+			require.Equal(t, testcase.allowed, result.Allowed)
+			require.Equal(t, testcase.wantRemaining, result.Remaining)
+			require.InDelta(t, testcase.wantResetAfter, result.ResetAfter, float64(time.Millisecond))
+			require.InDelta(t, testcase.wantRetryAfter, result.RetryAfter, float64(time.Millisecond))
+		})
+	}
+}
+
+func TestInMemRateLimitStoreTake(t *testing.T) {
+	t.Parallel()
+
+	store := httputils.NewInMemRateLimitStore()
+	policy := httputils.RateLimitPolicy{
+		Name:   "test",
+		Limit:  2,
+		Period: time.Hour,
+	}
+
+	result, err := store.Take(context.Background(), "key1", policy)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 1, result.Remaining)
+
+	result, err = store.Take(context.Background(), "key1", policy)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+
+	result, err = store.Take(context.Background(), "key1", policy)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Greater(t, result.RetryAfter, time.Duration(0))
+	require.LessOrEqual(t, result.RetryAfter, 30*time.Minute)
+
+	result, err = store.Take(context.Background(), "key2", policy)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 1, result.Remaining)
+}
+
+// failingRateLimitStore implements RateLimitStore and always fails to take tokens.
+type failingRateLimitStore struct{}
+
+// Take fails to take a token.
+func (store *failingRateLimitStore) Take(ctx context.Context, key string, policy httputils.RateLimitPolicy) (*httputils.RateLimitResult, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Parallel()
+
+	limitedHandler := func(w *httputils.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+	handler := func(w *httputils.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	keyFromHeader := func(r *http.Request) (string, bool) {
+		key := r.Header.Get("X-Rate-Limit-Key")
+		return key, key != ""
+	}
+	policy := httputils.RateLimitPolicy{
+		Name:   "test",
+		Limit:  2,
+		Period: time.Minute,
+		Key:    keyFromHeader,
+	}
+
+	var loggedErrorsMu sync.Mutex
+	loggedErrors := []string{}
+	logError := func(v ...any) {
+		loggedErrorsMu.Lock()
+		defer loggedErrorsMu.Unlock()
+		loggedErrors = append(loggedErrors, fmt.Sprintln(v...))
+	}
+
+	router := httputils.NewRouter()
+	router.GET("/limited", handler, httputils.RateLimitMiddleware(httputils.NewInMemRateLimitStore(), policy, limitedHandler, logError))
+	router.GET("/unlimited", handler, httputils.RateLimitMiddleware(nil, policy, limitedHandler, logError))
+	router.GET("/failing", handler, httputils.RateLimitMiddleware(&failingRateLimitStore{}, policy, limitedHandler, logError))
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	httpClient := httputils.NewHTTPClient(nil)
+
+	doRequest := func(path string, key string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, http.NoBody)
+		require.NoError(t, err)
+
+		if key != "" {
+			req.Header.Set("X-Rate-Limit-Key", key)
+		}
+
+		res, err := httpClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			err := res.Body.Close()
+			require.NoError(t, err)
+		})
+
+		return res
+	}
+
+	res := doRequest("/limited", "deadbeef")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "2;w=60", res.Header.Get("RateLimit-Policy"))
+	require.Equal(t, "2", res.Header.Get("RateLimit-Limit"))
+	require.Equal(t, "1", res.Header.Get("RateLimit-Remaining"))
+	require.Equal(t, "30", res.Header.Get("RateLimit-Reset"))
+	require.Empty(t, res.Header.Get("Retry-After"))
+
+	res = doRequest("/limited", "deadbeef")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "0", res.Header.Get("RateLimit-Remaining"))
+
+	res = doRequest("/limited", "deadbeef")
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	require.Equal(t, "0", res.Header.Get("RateLimit-Remaining"))
+	require.Equal(t, "30", res.Header.Get("Retry-After"))
+
+	res = doRequest("/limited", "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Empty(t, res.Header.Get("RateLimit-Limit"))
+
+	for i := 0; i < 3; i++ {
+		res = doRequest("/unlimited", "deadbeef")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Empty(t, res.Header.Get("RateLimit-Limit"))
+
+		res = doRequest("/failing", "deadbeef")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Empty(t, res.Header.Get("RateLimit-Limit"))
+	}
+
+	loggedErrorsMu.Lock()
+	defer loggedErrorsMu.Unlock()
+	require.Len(t, loggedErrors, 3)
+	for _, loggedError := range loggedErrors {
+		require.Contains(t, loggedError, "store unavailable")
+	}
+}
@@ -15,6 +15,7 @@ type Router interface {
 	POST(pattern string, handler HandlerFunc, middleware ...MiddlewareFunc)
 	PUT(pattern string, handler HandlerFunc, middleware ...MiddlewareFunc)
 	DELETE(pattern string, handler HandlerFunc, middleware ...MiddlewareFunc)
+	OPTIONS(pattern string, handler HandlerFunc, middleware ...MiddlewareFunc)
 	ServeHTTP(http.ResponseWriter, *http.Request)
 }
 
@@ -88,3 +89,13 @@ func (r *router) DELETE(
 ) {
 	r.handleWithMiddleware(http.MethodDelete, pattern, handler, middleware...)
 }
+
+// OPTIONS wraps a handler with a set of middleware
+// and registers it for a given pattern and HTTP OPTIONS method.
+func (r *router) OPTIONS(
+	pattern string,
+	handler HandlerFunc,
+	middleware ...MiddlewareFunc,
+) {
+	r.handleWithMiddleware(http.MethodOptions, pattern, handler, middleware...)
+}
@@ -36,11 +36,18 @@ func TestRouterMethods(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	}
 
+	optionsHandlerCallCount := 0
+	optionsHandler := func(w *httputils.ResponseWriter, r *http.Request) {
+		optionsHandlerCallCount++
+		w.WriteHeader(http.StatusNoContent)
+	}
+
 	router := httputils.NewRouter()
 	router.GET("/path/get", getHandler)
 	router.POST("/path/post", postHandler)
 	router.PUT("/path/put", putHandler)
 	router.DELETE("/path/delete", deleteHandler)
+	router.OPTIONS("/path/options", optionsHandler)
 
 	srv := httptest.NewServer(router)
 	t.Cleanup(srv.Close)
@@ -77,6 +84,12 @@ func TestRouterMethods(t *testing.T) {
 			path:           "/path/delete",
 			wantStatusCode: http.StatusOK,
 		},
+		{
+			name:           "OPTIONS request",
+			method:         http.MethodOptions,
+			path:           "/path/options",
+			wantStatusCode: http.StatusNoContent,
+		},
 		{
 			name:           "Unregistered path",
 			method:         http.MethodGet,
@@ -118,6 +131,7 @@ func TestRouterMethods(t *testing.T) {
 		require.Equal(t, postHandlerCallCount, 1)
 		require.Equal(t, putHandlerCallCount, 1)
 		require.Equal(t, deleteHandlerCallCount, 1)
+		require.Equal(t, optionsHandlerCallCount, 1)
 	})
 }
 
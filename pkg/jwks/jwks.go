@@ -0,0 +1,343 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Path is the well-known path where a JSON Web Key Set is conventionally published.
+const Path = "/.well-known/jwks.json"
+
+// Key types of JSON Web Keys.
+const (
+	KeyTypeRSA = "RSA"
+	KeyTypeOKP = "OKP"
+)
+
+// curveEd25519 is the curve name of Ed25519 JSON Web Keys.
+const curveEd25519 = "Ed25519"
+
+// JSONWebKey represents a single public key in a JSON Web Key Set.
+// RSA keys use modulus and exponent, Ed25519 keys use curve and x.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Modulus   string `json:"n,omitempty"`
+	Exponent  string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet represents a JSON Web Key Set.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// PublicKey parses the public key of the JSON Web Key,
+// returning *rsa.PublicKey for RSA keys and ed25519.PublicKey for Ed25519 keys.
+func (k JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case KeyTypeRSA:
+		modulus, err := base64.RawURLEncoding.DecodeString(k.Modulus)
+		if err != nil {
+			return nil, fmt.Errorf("PublicKey failed to base64.RawURLEncoding.DecodeString modulus: %w", err)
+		}
+
+		exponent, err := base64.RawURLEncoding.DecodeString(k.Exponent)
+		if err != nil {
+			return nil, fmt.Errorf("PublicKey failed to base64.RawURLEncoding.DecodeString exponent: %w", err)
+		}
+
+		e := new(big.Int).SetBytes(exponent)
+		if !e.IsInt64() || e.Int64() > 1<<31-1 || e.Int64() < 3 {
+			return nil, errors.New("PublicKey failed, invalid exponent")
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(e.Int64()),
+		}, nil
+	case KeyTypeOKP:
+		if k.Curve != curveEd25519 {
+			return nil, fmt.Errorf("PublicKey failed, unsupported curve %s", k.Curve)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("PublicKey failed to base64.RawURLEncoding.DecodeString x: %w", err)
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("PublicKey failed, invalid Ed25519 key size")
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("PublicKey failed, unsupported key type %s", k.KeyType)
+	}
+}
+
+// Key is a JWT signing or verification key.
+// Keys parsed from private keys can both sign and verify,
+// while keys parsed from public keys can only verify.
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	privateKey any
+	publicKey  any
+}
+
+// NewHMACKey returns a symmetric HS256 Key with no key ID.
+// Symmetric keys are never published in a JSON Web Key Set.
+func NewHMACKey(secret []byte) *Key {
+	return &Key{
+		ID:         "",
+		Method:     jwt.SigningMethodHS256,
+		privateKey: secret,
+		publicKey:  secret,
+	}
+}
+
+// NewKey returns a Key for a given RSA or Ed25519 private or public key,
+// using RS256 for RSA keys and EdDSA for Ed25519 keys.
+// The key ID is the RFC 7638 thumbprint of the public key.
+func NewKey(key any) (*Key, error) {
+	k := &Key{}
+
+	switch typedKey := key.(type) {
+	case *rsa.PrivateKey:
+		k.Method = jwt.SigningMethodRS256
+		k.privateKey = typedKey
+		k.publicKey = &typedKey.PublicKey
+	case *rsa.PublicKey:
+		k.Method = jwt.SigningMethodRS256
+		k.publicKey = typedKey
+	case ed25519.PrivateKey:
+		k.Method = jwt.SigningMethodEdDSA
+		k.privateKey = typedKey
+		k.publicKey = typedKey.Public().(ed25519.PublicKey)
+	case ed25519.PublicKey:
+		k.Method = jwt.SigningMethodEdDSA
+		k.publicKey = typedKey
+	default:
+		return nil, fmt.Errorf("NewKey failed, unsupported key type %T", key)
+	}
+
+	jsonWebKey, _ := k.JSONWebKey()
+	k.ID = thumbprint(jsonWebKey)
+
+	return k, nil
+}
+
+// ParseKeyPEM parses a PEM-encoded PKCS #1, PKCS #8 or PKIX key into a Key.
+func ParseKeyPEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("ParseKeyPEM failed to pem.Decode, no PEM data found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("ParseKeyPEM failed, unsupported PEM block type %s", block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("ParseKeyPEM failed to parse %s: %w", block.Type, err)
+	}
+
+	k, err := NewKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("ParseKeyPEM failed to NewKey: %w", err)
+	}
+
+	return k, nil
+}
+
+// ReadKeyFile reads a PEM-encoded key file into a Key.
+func ReadKeyFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ReadKeyFile failed to os.ReadFile %s: %w", path, err)
+	}
+
+	k, err := ParseKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("ReadKeyFile failed to ParseKeyPEM %s: %w", path, err)
+	}
+
+	return k, nil
+}
+
+// CanSign determines whether or not the Key holds a private or symmetric key that can sign JWTs.
+func (k *Key) CanSign() bool {
+	return k.privateKey != nil
+}
+
+// JSONWebKey returns the public JSON Web Key of the Key,
+// and returns false when the Key is symmetric and cannot be published.
+func (k *Key) JSONWebKey() (JSONWebKey, bool) {
+	switch publicKey := k.publicKey.(type) {
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			KeyType:   KeyTypeRSA,
+			KeyID:     k.ID,
+			Use:       "sig",
+			Algorithm: k.Method.Alg(),
+			Modulus:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JSONWebKey{
+			KeyType:   KeyTypeOKP,
+			KeyID:     k.ID,
+			Use:       "sig",
+			Algorithm: k.Method.Alg(),
+			Curve:     curveEd25519,
+			X:         base64.RawURLEncoding.EncodeToString(publicKey),
+		}, true
+	default:
+		return JSONWebKey{}, false
+	}
+}
+
+// thumbprint computes the RFC 7638 thumbprint of a JSON Web Key,
+// which hashes the required members in lexicographic order.
+func thumbprint(k JSONWebKey) string {
+	var members any
+	switch k.KeyType {
+	case KeyTypeRSA:
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.Exponent, k.KeyType, k.Modulus}
+	case KeyTypeOKP:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{k.Curve, k.KeyType, k.X}
+	default:
+		return ""
+	}
+
+	// encoding members of these structs cannot fail
+	data, _ := json.Marshal(members)
+	digest := sha256.Sum256(data)
+
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// KeySet holds the Key used to sign new JWTs,
+// along with every Key whose JWTs are still accepted.
+type KeySet struct {
+	signingKey *Key
+	keys       []*Key
+}
+
+// NewKeySet returns a new KeySet that signs using a given signing key
+// and additionally verifies JWTs signed by given verification keys.
+// Returns error when the signing key cannot sign or when key IDs collide.
+func NewKeySet(signingKey *Key, verificationKeys ...*Key) (*KeySet, error) {
+	if !signingKey.CanSign() {
+		return nil, fmt.Errorf("NewKeySet failed, signing key %s has no private key", signingKey.ID)
+	}
+
+	keys := append([]*Key{signingKey}, verificationKeys...)
+	keyIDs := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if keyIDs[key.ID] {
+			return nil, fmt.Errorf("NewKeySet failed, duplicate key ID %q", key.ID)
+		}
+
+		keyIDs[key.ID] = true
+	}
+
+	return &KeySet{
+		signingKey: signingKey,
+		keys:       keys,
+	}, nil
+}
+
+// Sign signs given claims using the signing Key,
+// setting the kid header for asymmetric keys.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signingKey.Method, claims)
+	if s.signingKey.ID != "" {
+		token.Header["kid"] = s.signingKey.ID
+	}
+
+	signedToken, err := token.SignedString(s.signingKey.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("Sign failed to token.SignedString: %w", err)
+	}
+
+	return signedToken, nil
+}
+
+// Parse parses a JWT into given claims, verifying its signature using the Key matching its kid header.
+// Tokens whose algorithm differs from the Key's algorithm are rejected.
+func (s *KeySet) Parse(token string, claims jwt.Claims) (*jwt.Token, error) {
+	parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		keyID, _ := t.Header["kid"].(string)
+		for _, key := range s.keys {
+			if key.ID != keyID {
+				continue
+			}
+
+			if t.Method.Alg() != key.Method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method %s for key %q", t.Method.Alg(), keyID)
+			}
+
+			return key.publicKey, nil
+		}
+
+		return nil, fmt.Errorf("unknown key ID %q", keyID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Parse failed to jwt.ParseWithClaims: %w", err)
+	}
+
+	return parsedToken, nil
+}
+
+// JSONWebKeySet returns the public keys of the KeySet as a JSON Web Key Set,
+// leaving out symmetric keys.
+func (s *KeySet) JSONWebKeySet() *JSONWebKeySet {
+	keySet := &JSONWebKeySet{
+		Keys: make([]JSONWebKey, 0, len(s.keys)),
+	}
+
+	for _, key := range s.keys {
+		jsonWebKey, ok := key.JSONWebKey()
+		if ok {
+			keySet.Keys = append(keySet.Keys, jsonWebKey)
+		}
+	}
+
+	return keySet
+}
@@ -0,0 +1,515 @@
+package jwks_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alvii147/flagger-api/pkg/jwks"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func mustGenerateRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return key
+}
+
+func mustGenerateEd25519Key(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return key
+}
+
+func mustEncodePEM(t *testing.T, blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  blockType,
+		Bytes: der,
+	})
+}
+
+func mustNewKey(t *testing.T, key any) *jwks.Key {
+	k, err := jwks.NewKey(key)
+	require.NoError(t, err)
+
+	return k
+}
+
+func TestJSONWebKeyPublicKey(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := mustGenerateRSAKey(t)
+	rsaJSONWebKey, ok := mustNewKey(t, rsaKey).JSONWebKey()
+	require.True(t, ok)
+
+	ed25519Key := mustGenerateEd25519Key(t)
+	ed25519JSONWebKey, ok := mustNewKey(t, ed25519Key).JSONWebKey()
+	require.True(t, ok)
+
+	testcases := []struct {
+		name          string
+		jsonWebKey    jwks.JSONWebKey
+		wantPublicKey any
+		wantErr       bool
+	}{
+		{
+			name:          "RSA key",
+			jsonWebKey:    rsaJSONWebKey,
+			wantPublicKey: &rsaKey.PublicKey,
+			wantErr:       false,
+		},
+		{
+			name:          "Ed25519 key",
+			jsonWebKey:    ed25519JSONWebKey,
+			wantPublicKey: ed25519Key.Public(),
+			wantErr:       false,
+		},
+		{
+			name: "Invalid RSA exponent",
+			jsonWebKey: jwks.JSONWebKey{
+				KeyType:  jwks.KeyTypeRSA,
+				Modulus:  rsaJSONWebKey.Modulus,
+				Exponent: "AQ",
+			},
+			wantPublicKey: nil,
+			wantErr:       true,
+		},
+		{
+			name: "Unsupported curve",
+			jsonWebKey: jwks.JSONWebKey{
+				KeyType: jwks.KeyTypeOKP,
+				Curve:   "X25519",
+				X:       ed25519JSONWebKey.X,
+			},
+			wantPublicKey: nil,
+			wantErr:       true,
+		},
+		{
+			name: "Invalid Ed25519 key size",
+			jsonWebKey: jwks.JSONWebKey{
+				KeyType: jwks.KeyTypeOKP,
+				Curve:   "Ed25519",
+				X:       "AQAB",
+			},
+			wantPublicKey: nil,
+			wantErr:       true,
+		},
+		{
+			name: "Unsupported key type",
+			jsonWebKey: jwks.JSONWebKey{
+				KeyType: "EC",
+			},
+			wantPublicKey: nil,
+			wantErr:       true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			publicKey, err := testcase.jsonWebKey.PublicKey()
+			if testcase.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantPublicKey, publicKey)
+		})
+	}
+}
+
+func TestNewKeyThumbprint(t *testing.T) {
+	t.Parallel()
+
+	// test vector from RFC 7638 section 3.1
+	publicKey, err := jwks.JSONWebKey{
+		KeyType:  jwks.KeyTypeRSA,
+		Modulus:  "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		Exponent: "AQAB",
+	}.PublicKey()
+	require.NoError(t, err)
+
+	key := mustNewKey(t, publicKey)
+	require.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", key.ID)
+	require.Equal(t, jwt.SigningMethodRS256, key.Method)
+	require.False(t, key.CanSign())
+}
+
+func TestParseKeyPEM(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := mustGenerateRSAKey(t)
+	rsaPKCS8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	require.NoError(t, err)
+	rsaPKIX, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+
+	ed25519Key := mustGenerateEd25519Key(t)
+	ed25519PKCS8, err := x509.MarshalPKCS8PrivateKey(ed25519Key)
+	require.NoError(t, err)
+	ed25519PKIX, err := x509.MarshalPKIXPublicKey(ed25519Key.Public())
+	require.NoError(t, err)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecdsaPKCS8, err := x509.MarshalPKCS8PrivateKey(ecdsaKey)
+	require.NoError(t, err)
+
+	rsaKeyID := mustNewKey(t, rsaKey).ID
+	ed25519KeyID := mustNewKey(t, ed25519Key).ID
+
+	testcases := []struct {
+		name        string
+		data        []byte
+		wantKeyID   string
+		wantMethod  jwt.SigningMethod
+		wantCanSign bool
+		wantErr     bool
+	}{
+		{
+			name:        "PKCS #1 RSA private key",
+			data:        mustEncodePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+			wantKeyID:   rsaKeyID,
+			wantMethod:  jwt.SigningMethodRS256,
+			wantCanSign: true,
+			wantErr:     false,
+		},
+		{
+			name:        "PKCS #8 RSA private key",
+			data:        mustEncodePEM(t, "PRIVATE KEY", rsaPKCS8),
+			wantKeyID:   rsaKeyID,
+			wantMethod:  jwt.SigningMethodRS256,
+			wantCanSign: true,
+			wantErr:     false,
+		},
+		{
+			name:        "PKCS #1 RSA public key",
+			data:        mustEncodePEM(t, "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)),
+			wantKeyID:   rsaKeyID,
+			wantMethod:  jwt.SigningMethodRS256,
+			wantCanSign: false,
+			wantErr:     false,
+		},
+		{
+			name:        "PKIX RSA public key",
+			data:        mustEncodePEM(t, "PUBLIC KEY", rsaPKIX),
+			wantKeyID:   rsaKeyID,
+			wantMethod:  jwt.SigningMethodRS256,
+			wantCanSign: false,
+			wantErr:     false,
+		},
+		{
+			name:        "PKCS #8 Ed25519 private key",
+			data:        mustEncodePEM(t, "PRIVATE KEY", ed25519PKCS8),
+			wantKeyID:   ed25519KeyID,
+			wantMethod:  jwt.SigningMethodEdDSA,
+			wantCanSign: true,
+			wantErr:     false,
+		},
+		{
+			name:        "PKIX Ed25519 public key",
+			data:        mustEncodePEM(t, "PUBLIC KEY", ed25519PKIX),
+			wantKeyID:   ed25519KeyID,
+			wantMethod:  jwt.SigningMethodEdDSA,
+			wantCanSign: false,
+			wantErr:     false,
+		},
+		{
+			name:        "Unsupported ECDSA key",
+			data:        mustEncodePEM(t, "PRIVATE KEY", ecdsaPKCS8),
+			wantKeyID:   "",
+			wantMethod:  nil,
+			wantCanSign: false,
+			wantErr:     true,
+		},
+		{
+			name:        "Unsupported PEM block type",
+			data:        mustEncodePEM(t, "CERTIFICATE", rsaPKIX),
+			wantKeyID:   "",
+			wantMethod:  nil,
+			wantCanSign: false,
+			wantErr:     true,
+		},
+		{
+			name:        "Invalid PEM block",
+			data:        mustEncodePEM(t, "PRIVATE KEY", []byte("notakey")),
+			wantKeyID:   "",
+			wantMethod:  nil,
+			wantCanSign: false,
+			wantErr:     true,
+		},
+		{
+			name:        "No PEM data",
+			data:        []byte("notapem"),
+			wantKeyID:   "",
+			wantMethod:  nil,
+			wantCanSign: false,
+			wantErr:     true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			key, err := jwks.ParseKeyPEM(testcase.data)
+			if testcase.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantKeyID, key.ID)
+			require.Equal(t, testcase.wantMethod, key.Method)
+			require.Equal(t, testcase.wantCanSign, key.CanSign())
+		})
+	}
+}
+
+func TestReadKeyFile(t *testing.T) {
+	t.Parallel()
+
+	ed25519Key := mustGenerateEd25519Key(t)
+	ed25519PKCS8, err := x509.MarshalPKCS8PrivateKey(ed25519Key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "signing.pem")
+	err = os.WriteFile(path, mustEncodePEM(t, "PRIVATE KEY", ed25519PKCS8), 0o600)
+	require.NoError(t, err)
+
+	key, err := jwks.ReadKeyFile(path)
+	require.NoError(t, err)
+	require.Equal(t, mustNewKey(t, ed25519Key).ID, key.ID)
+	require.True(t, key.CanSign())
+
+	_, err = jwks.ReadKeyFile(filepath.Join(t.TempDir(), "missing.pem"))
+	require.Error(t, err)
+}
+
+func TestNewKeySetError(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := mustGenerateRSAKey(t)
+
+	testcases := []struct {
+		name             string
+		signingKey       *jwks.Key
+		verificationKeys []*jwks.Key
+	}{
+		{
+			name:             "Signing key without private key",
+			signingKey:       mustNewKey(t, &rsaKey.PublicKey),
+			verificationKeys: nil,
+		},
+		{
+			name:             "Duplicate key ID",
+			signingKey:       mustNewKey(t, rsaKey),
+			verificationKeys: []*jwks.Key{mustNewKey(t, &rsaKey.PublicKey)},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := jwks.NewKeySet(testcase.signingKey, testcase.verificationKeys...)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestKeySetSignParse(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := mustGenerateRSAKey(t)
+	ed25519Key := mustGenerateEd25519Key(t)
+	otherRSAKey := mustGenerateRSAKey(t)
+
+	testcases := []struct {
+		name       string
+		signingKey *jwks.Key
+		wantMethod jwt.SigningMethod
+		wantKeyID  bool
+	}{
+		{
+			name:       "RSA signing key",
+			signingKey: mustNewKey(t, rsaKey),
+			wantMethod: jwt.SigningMethodRS256,
+			wantKeyID:  true,
+		},
+		{
+			name:       "Ed25519 signing key",
+			signingKey: mustNewKey(t, ed25519Key),
+			wantMethod: jwt.SigningMethodEdDSA,
+			wantKeyID:  true,
+		},
+		{
+			name:       "HMAC signing key",
+			signingKey: jwks.NewHMACKey([]byte("s3cr3t")),
+			wantMethod: jwt.SigningMethodHS256,
+			wantKeyID:  false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			keySet, err := jwks.NewKeySet(testcase.signingKey)
+			require.NoError(t, err)
+
+			token, err := keySet.Sign(&jwt.StandardClaims{
+				Subject:   "somesubject",
+				ExpiresAt: time.Now().UTC().Add(time.Hour).Unix(),
+			})
+			require.NoError(t, err)
+
+			claims := &jwt.StandardClaims{}
+			parsedToken, err := keySet.Parse(token, claims)
+			require.NoError(t, err)
+			require.True(t, parsedToken.Valid)
+			require.Equal(t, testcase.wantMethod, parsedToken.Method)
+			require.Equal(t, "somesubject", claims.Subject)
+
+			keyID, ok := parsedToken.Header["kid"]
+			require.Equal(t, testcase.wantKeyID, ok)
+			if testcase.wantKeyID {
+				require.Equal(t, testcase.signingKey.ID, keyID)
+			}
+
+			otherKeySet, err := jwks.NewKeySet(mustNewKey(t, otherRSAKey))
+			require.NoError(t, err)
+
+			_, err = otherKeySet.Parse(token, &jwt.StandardClaims{})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestKeySetParseRotatedKey(t *testing.T) {
+	t.Parallel()
+
+	oldKey := mustNewKey(t, mustGenerateRSAKey(t))
+	newKey := mustNewKey(t, mustGenerateEd25519Key(t))
+
+	oldKeySet, err := jwks.NewKeySet(oldKey)
+	require.NoError(t, err)
+
+	oldToken, err := oldKeySet.Sign(&jwt.StandardClaims{Subject: "somesubject"})
+	require.NoError(t, err)
+
+	rotatedKeySet, err := jwks.NewKeySet(newKey, oldKey)
+	require.NoError(t, err)
+
+	newToken, err := rotatedKeySet.Sign(&jwt.StandardClaims{Subject: "somesubject"})
+	require.NoError(t, err)
+
+	_, err = rotatedKeySet.Parse(oldToken, &jwt.StandardClaims{})
+	require.NoError(t, err)
+
+	_, err = rotatedKeySet.Parse(newToken, &jwt.StandardClaims{})
+	require.NoError(t, err)
+
+	_, err = oldKeySet.Parse(newToken, &jwt.StandardClaims{})
+	require.Error(t, err)
+}
+
+func TestKeySetParseRejectsAlgorithmMismatch(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := mustGenerateRSAKey(t)
+	key := mustNewKey(t, rsaKey)
+	keySet, err := jwks.NewKeySet(key)
+	require.NoError(t, err)
+
+	publicKeyPEM := mustEncodePEM(t, "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))
+
+	testcases := []struct {
+		name   string
+		method jwt.SigningMethod
+		keyID  any
+		key    any
+	}{
+		{
+			name:   "HMAC token signed with public key",
+			method: jwt.SigningMethodHS256,
+			keyID:  key.ID,
+			key:    publicKeyPEM,
+		},
+		{
+			name:   "Unsigned token",
+			method: jwt.SigningMethodNone,
+			keyID:  key.ID,
+			key:    jwt.UnsafeAllowNoneSignatureType,
+		},
+		{
+			name:   "Unknown key ID",
+			method: jwt.SigningMethodRS256,
+			keyID:  "unknown",
+			key:    rsaKey,
+		},
+		{
+			name:   "Missing key ID",
+			method: jwt.SigningMethodRS256,
+			keyID:  nil,
+			key:    rsaKey,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			token := jwt.NewWithClaims(testcase.method, &jwt.StandardClaims{Subject: "somesubject"})
+			if testcase.keyID != nil {
+				token.Header["kid"] = testcase.keyID
+			}
+
+			signedToken, err := token.SignedString(testcase.key)
+			require.NoError(t, err)
+
+			_, err = keySet.Parse(signedToken, &jwt.StandardClaims{})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestKeySetJSONWebKeySet(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := mustNewKey(t, mustGenerateRSAKey(t))
+	ed25519Key := mustNewKey(t, mustGenerateEd25519Key(t).Public())
+
+	keySet, err := jwks.NewKeySet(rsaKey, ed25519Key, jwks.NewHMACKey([]byte("s3cr3t")))
+	require.NoError(t, err)
+
+	jsonWebKeySet := keySet.JSONWebKeySet()
+	require.Len(t, jsonWebKeySet.Keys, 2)
+
+	require.Equal(t, jwks.KeyTypeRSA, jsonWebKeySet.Keys[0].KeyType)
+	require.Equal(t, rsaKey.ID, jsonWebKeySet.Keys[0].KeyID)
+	require.Equal(t, "RS256", jsonWebKeySet.Keys[0].Algorithm)
+	require.Equal(t, "sig", jsonWebKeySet.Keys[0].Use)
+
+	require.Equal(t, jwks.KeyTypeOKP, jsonWebKeySet.Keys[1].KeyType)
+	require.Equal(t, ed25519Key.ID, jsonWebKeySet.Keys[1].KeyID)
+	require.Equal(t, "EdDSA", jsonWebKeySet.Keys[1].Algorithm)
+	require.Equal(t, "Ed25519", jsonWebKeySet.Keys[1].Curve)
+
+	hmacKeySet, err := jwks.NewKeySet(jwks.NewHMACKey([]byte("s3cr3t")))
+	require.NoError(t, err)
+	require.Empty(t, hmacKeySet.JSONWebKeySet().Keys)
+}
@@ -1,25 +1,54 @@
 package logging
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/alvii147/flagger-api/pkg/httputils"
 )
 
-// logTraffic logs HTTP traffic, including http method, URL, protocol, and status code.
+// LoggingContextKey is a string representing context keys.
+type LoggingContextKey string
+
+// LoggingContextKeyTrafficTags is the key in context where tags added to the traffic log of a request are stored.
+const LoggingContextKeyTrafficTags LoggingContextKey = "trafficTags"
+
+// AddTrafficTag adds a key-value tag to the traffic log of the request with the given context.
+// It does nothing if the request is not handled by LoggerMiddleware.
+func AddTrafficTag(ctx context.Context, key string, value string) {
+	tags, ok := ctx.Value(LoggingContextKeyTrafficTags).(*[]string)
+	if !ok {
+		return
+	}
+
+	*tags = append(*tags, key+"="+value)
+}
+
+// logTraffic logs HTTP traffic, including http method, URL, protocol, status code, and any traffic tags.
 func logTraffic(logger Logger, w *httputils.ResponseWriter, request *http.Request) {
+	msgs := []any{request.Method, request.URL, request.Proto, w.StatusCode, http.StatusText(w.StatusCode)}
+	if tags, ok := request.Context().Value(LoggingContextKeyTrafficTags).(*[]string); ok {
+		for _, tag := range *tags {
+			msgs = append(msgs, tag)
+		}
+	}
+
 	if w.StatusCode < 400 {
-		logger.LogInfo(request.Method, request.URL, request.Proto, w.StatusCode, http.StatusText(w.StatusCode))
+		logger.LogInfo(msgs...)
 	} else if w.StatusCode < 500 {
-		logger.LogWarn(request.Method, request.URL, request.Proto, w.StatusCode, http.StatusText(w.StatusCode))
+		logger.LogWarn(msgs...)
 	} else {
-		logger.LogError(request.Method, request.URL, request.Proto, w.StatusCode, http.StatusText(w.StatusCode))
+		logger.LogError(msgs...)
 	}
 }
 
 // LoggerMiddleware handles logging of HTTP traffic.
+// Tags added to the request context using AddTrafficTag by later handlers are included in the log.
 func LoggerMiddleware(next httputils.HandlerFunc, logger Logger) httputils.HandlerFunc {
 	return httputils.HandlerFunc(func(w *httputils.ResponseWriter, r *http.Request) {
+		tags := make([]string, 0)
+		r = r.Clone(context.WithValue(r.Context(), LoggingContextKeyTrafficTags, &tags))
+
 		defer func() {
 			logTraffic(logger, w, r)
 		}()
@@ -234,3 +234,40 @@ func TestLoggerMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestLoggerMiddlewareTrafficTags(t *testing.T) {
+	t.Parallel()
+
+	var next httputils.HandlerFunc = func(w *httputils.ResponseWriter, r *http.Request) {
+		logging.AddTrafficTag(r.Context(), "impersonated_by", "c9a8a4a3-4b3a-4cf6-9b0a-0b7e0a6b3a2e")
+		logging.AddTrafficTag(r.Context(), "read_only", "true")
+		w.WriteHeader(http.StatusOK)
+	}
+
+	bufOut, _, logger := testkit.CreateTestLogger()
+
+	url := "/request/url/path"
+	rec := httptest.NewRecorder()
+	w := &httputils.ResponseWriter{
+		ResponseWriter: rec,
+		StatusCode:     http.StatusOK,
+	}
+	r := httptest.NewRequest(http.MethodGet, url, http.NoBody)
+
+	logging.LoggerMiddleware(next, logger)(w, r)
+
+	stdoutMessages := strings.Split(strings.TrimSpace(bufOut.String()), "\n")
+	require.Len(t, stdoutMessages, 1)
+
+	_, _, _, logMsg := testkit.MustParseLogMessage(stdoutMessages[0])
+	require.Regexp(t, `^GET\s+`+url+`\s+HTTP/1.1\s+200\s+OK\s+impersonated_by=c9a8a4a3-4b3a-4cf6-9b0a-0b7e0a6b3a2e\s+read_only=true$`, logMsg)
+}
+
+func TestAddTrafficTagWithoutLoggerMiddleware(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/request/url/path", http.NoBody)
+	require.NotPanics(t, func() {
+		logging.AddTrafficTag(r.Context(), "key", "value")
+	})
+}
@@ -0,0 +1,372 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alvii147/flagger-api/pkg/jwks"
+	"github.com/alvii147/flagger-api/pkg/utils"
+	"github.com/golang-jwt/jwt"
+)
+
+// DiscoveryPath is the path under the issuer URL where the provider configuration is published.
+const DiscoveryPath = "/.well-known/openid-configuration"
+
+// ClockSkew is the amount of clock drift tolerated when validating ID token timestamps.
+const ClockSkew = time.Minute
+
+// codeVerifierSize is the number of random bytes in generated PKCE code verifiers.
+const codeVerifierSize = 32
+
+// ProviderConfig represents the configuration published by an OpenID Connect provider.
+type ProviderConfig struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Audience represents the aud claim, which can either be a single string or an array of strings.
+type Audience []string
+
+// UnmarshalJSON parses either a single string or an array of strings into Audience.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	err := json.Unmarshal(data, &single)
+	if err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var multiple []string
+	err = json.Unmarshal(data, &multiple)
+	if err != nil {
+		return fmt.Errorf("UnmarshalJSON failed to json.Unmarshal: %w", err)
+	}
+
+	*a = Audience(multiple)
+
+	return nil
+}
+
+// Contains determines whether or not the Audience contains a given client ID.
+func (a Audience) Contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IDTokenClaims represents claims in ID tokens issued by OpenID Connect providers.
+type IDTokenClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      Audience `json:"aud"`
+	ExpiresAt     int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	GivenName     string   `json:"given_name"`
+	FamilyName    string   `json:"family_name"`
+}
+
+// Valid validates time-based claims of the ID token.
+func (c *IDTokenClaims) Valid() error {
+	now := time.Now().UTC()
+
+	if c.ExpiresAt == 0 || now.After(time.Unix(c.ExpiresAt, 0).Add(ClockSkew)) {
+		return errors.New("Valid failed, token is expired")
+	}
+
+	if c.IssuedAt != 0 && now.Add(ClockSkew).Before(time.Unix(c.IssuedAt, 0)) {
+		return errors.New("Valid failed, token is used before issued")
+	}
+
+	return nil
+}
+
+// tokenResponse represents the response of the token endpoint.
+type tokenResponse struct {
+	IDToken     string `json:"id_token"`
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// Client is an OpenID Connect relying party using the authorization code flow with PKCE.
+// Provider configuration and keys are discovered lazily and cached.
+type Client struct {
+	issuerURL    string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	httpClient   *http.Client
+	mu           sync.Mutex
+	provider     *ProviderConfig
+	keys         map[string]*rsa.PublicKey
+}
+
+// NewClient returns a new Client.
+func NewClient(issuerURL string, clientID string, clientSecret string, redirectURL string, httpClient *http.Client) *Client {
+	return &Client{
+		issuerURL:    strings.TrimRight(issuerURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		httpClient:   httpClient,
+	}
+}
+
+// GenerateCodeVerifier generates new random PKCE code verifier.
+func GenerateCodeVerifier() (string, error) {
+	verifierBytes, err := utils.GenerateRandomBytes(codeVerifierSize)
+	if err != nil {
+		return "", fmt.Errorf("GenerateCodeVerifier failed to utils.GenerateRandomBytes: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(verifierBytes), nil
+}
+
+// CodeChallenge computes the S256 PKCE code challenge of a given code verifier.
+func CodeChallenge(codeVerifier string) string {
+	digest := sha256.Sum256([]byte(codeVerifier))
+
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// getJSON fetches a given URL and decodes its JSON response into v.
+func (c *Client) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("getJSON failed to http.NewRequestWithContext: %w", err)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("getJSON failed to c.httpClient.Do: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("getJSON failed, %s responded with status %d", u, res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("getJSON failed to Decode: %w", err)
+	}
+
+	return nil
+}
+
+// Discover fetches and caches the provider configuration.
+func (c *Client) Discover(ctx context.Context) (*ProviderConfig, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider != nil {
+		return c.provider, nil
+	}
+
+	provider := &ProviderConfig{}
+	err := c.getJSON(ctx, c.issuerURL+DiscoveryPath, provider)
+	if err != nil {
+		return nil, fmt.Errorf("Discover failed to c.getJSON: %w", err)
+	}
+
+	if provider.Issuer != c.issuerURL {
+		return nil, fmt.Errorf("Discover failed, issuer %s does not match %s", provider.Issuer, c.issuerURL)
+	}
+
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("Discover failed, provider configuration is missing endpoints")
+	}
+
+	c.provider = provider
+
+	return provider, nil
+}
+
+// AuthCodeURL builds the URL of the provider's authorization endpoint that the User should be sent to.
+func (c *Client) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	provider, err := c.Discover(ctx)
+	if err != nil {
+		return "", fmt.Errorf("AuthCodeURL failed to c.Discover: %w", err)
+	}
+
+	u, err := url.Parse(provider.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("AuthCodeURL failed to url.Parse %s: %w", provider.AuthorizationEndpoint, err)
+	}
+
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", c.clientID)
+	query.Set("redirect_uri", c.redirectURL)
+	query.Set("scope", "openid email profile")
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// Exchange exchanges an authorization code for an ID token at the provider's token endpoint.
+func (c *Client) Exchange(ctx context.Context, code string, codeVerifier string) (string, error) {
+	provider, err := c.Discover(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Exchange failed to c.Discover: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.redirectURL)
+	form.Set("client_id", c.clientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("Exchange failed to http.NewRequestWithContext: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if c.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Exchange failed to c.httpClient.Do: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Exchange failed, token endpoint responded with status %d", res.StatusCode)
+	}
+
+	tokenRes := &tokenResponse{}
+	err = json.NewDecoder(res.Body).Decode(tokenRes)
+	if err != nil {
+		return "", fmt.Errorf("Exchange failed to Decode: %w", err)
+	}
+
+	if tokenRes.IDToken == "" {
+		return "", errors.New("Exchange failed, token endpoint did not return an ID token")
+	}
+
+	return tokenRes.IDToken, nil
+}
+
+// fetchKeys fetches the provider's JSON Web Key Set and caches its RSA signing keys.
+func (c *Client) fetchKeys(ctx context.Context, jwksURI string) error {
+	keySet := &jwks.JSONWebKeySet{}
+	err := c.getJSON(ctx, jwksURI, keySet)
+	if err != nil {
+		return fmt.Errorf("fetchKeys failed to c.getJSON: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, key := range keySet.Keys {
+		if key.KeyType != jwks.KeyTypeRSA || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+
+		publicKey, err := key.PublicKey()
+		if err != nil {
+			return fmt.Errorf("fetchKeys failed to key.PublicKey %s: %w", key.KeyID, err)
+		}
+
+		keys[key.KeyID] = publicKey.(*rsa.PublicKey)
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+
+	return nil
+}
+
+// getKey returns the cached signing key with a given key ID,
+// refetching the provider's keys if the key ID is unknown, since the provider may have rotated its keys.
+func (c *Client) getKey(ctx context.Context, jwksURI string, keyID string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	key, ok := c.keys[keyID]
+	c.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	err := c.fetchKeys(ctx, jwksURI)
+	if err != nil {
+		return nil, fmt.Errorf("getKey failed to c.fetchKeys: %w", err)
+	}
+
+	c.mu.Lock()
+	key, ok = c.keys[keyID]
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("getKey failed, unknown key ID %s", keyID)
+	}
+
+	return key, nil
+}
+
+// VerifyIDToken validates the signature and claims of an ID token,
+// making sure it was issued by the provider to this client for the given nonce.
+func (c *Client) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (*IDTokenClaims, error) {
+	provider, err := c.Discover(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("VerifyIDToken failed to c.Discover: %w", err)
+	}
+
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (any, error) {
+		if t.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+
+		keyID, _ := t.Header["kid"].(string)
+
+		return c.getKey(ctx, provider.JWKSURI, keyID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("VerifyIDToken failed to jwt.ParseWithClaims: %w", err)
+	}
+
+	if claims.Issuer != provider.Issuer {
+		return nil, fmt.Errorf("VerifyIDToken failed, unexpected issuer %s", claims.Issuer)
+	}
+
+	if !claims.Audience.Contains(c.clientID) {
+		return nil, fmt.Errorf("VerifyIDToken failed, token was not issued to client %s", c.clientID)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) == 0 {
+		return nil, errors.New("VerifyIDToken failed, nonce does not match")
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("VerifyIDToken failed, missing subject")
+	}
+
+	return claims, nil
+}
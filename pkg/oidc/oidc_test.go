@@ -0,0 +1,324 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alvii147/flagger-api/pkg/httputils"
+	"github.com/alvii147/flagger-api/pkg/oidc"
+	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCodeVerifier(t *testing.T) {
+	t.Parallel()
+
+	verifier, err := oidc.GenerateCodeVerifier()
+	require.NoError(t, err)
+	require.Len(t, verifier, 43)
+	require.Regexp(t, `^[A-Za-z0-9_-]+$`, verifier)
+
+	otherVerifier, err := oidc.GenerateCodeVerifier()
+	require.NoError(t, err)
+	require.NotEqual(t, verifier, otherVerifier)
+}
+
+func TestCodeChallenge(t *testing.T) {
+	t.Parallel()
+
+	// test vector from RFC 7636 appendix B
+	require.Equal(
+		t,
+		"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		oidc.CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"),
+	)
+
+	digest := sha256.Sum256([]byte("verifier"))
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(digest[:]), oidc.CodeChallenge("verifier"))
+}
+
+func TestAudienceUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name         string
+		rawAudience  string
+		wantAudience oidc.Audience
+		wantErr      bool
+	}{
+		{
+			name:         "Single audience",
+			rawAudience:  `"flagger"`,
+			wantAudience: oidc.Audience{"flagger"},
+			wantErr:      false,
+		},
+		{
+			name:         "Multiple audiences",
+			rawAudience:  `["flagger", "other"]`,
+			wantAudience: oidc.Audience{"flagger", "other"},
+			wantErr:      false,
+		},
+		{
+			name:         "Invalid audience",
+			rawAudience:  `42`,
+			wantAudience: nil,
+			wantErr:      true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var audience oidc.Audience
+			err := json.Unmarshal([]byte(testcase.rawAudience), &audience)
+			if testcase.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantAudience, audience)
+			require.True(t, audience.Contains("flagger"))
+			require.False(t, audience.Contains("unknown"))
+		})
+	}
+}
+
+func TestClientAuthorizationCodeFlow(t *testing.T) {
+	t.Parallel()
+
+	provider := testkit.MustCreateOIDCProvider("flagger", "s3cr3t")
+	t.Cleanup(provider.Close)
+
+	client := oidc.NewClient(provider.URL(), "flagger", "s3cr3t", "http://localhost:3000/sso/callback", httputils.NewHTTPClient(nil))
+
+	verifier, err := oidc.GenerateCodeVerifier()
+	require.NoError(t, err)
+
+	authURL, err := client.AuthCodeURL(context.Background(), "somestate", "somenonce", oidc.CodeChallenge(verifier))
+	require.NoError(t, err)
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	require.Equal(t, "flagger", u.Query().Get("client_id"))
+	require.Equal(t, "http://localhost:3000/sso/callback", u.Query().Get("redirect_uri"))
+	require.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+
+	user := testkit.OIDCUser{
+		Subject:       "248289761001",
+		Email:         "michael.scott@dundermifflin.com",
+		EmailVerified: true,
+		GivenName:     "Michael",
+		FamilyName:    "Scott",
+	}
+
+	code, state, err := provider.Authorize(authURL, user)
+	require.NoError(t, err)
+	require.Equal(t, "somestate", state)
+
+	idToken, err := client.Exchange(context.Background(), code, verifier)
+	require.NoError(t, err)
+
+	claims, err := client.VerifyIDToken(context.Background(), idToken, "somenonce")
+	require.NoError(t, err)
+	require.Equal(t, provider.URL(), claims.Issuer)
+	require.Equal(t, user.Subject, claims.Subject)
+	require.Equal(t, user.Email, claims.Email)
+	require.True(t, claims.EmailVerified)
+	require.Equal(t, user.GivenName, claims.GivenName)
+	require.Equal(t, user.FamilyName, claims.FamilyName)
+
+	_, err = client.Exchange(context.Background(), code, verifier)
+	require.Error(t, err)
+}
+
+func TestClientExchangeError(t *testing.T) {
+	t.Parallel()
+
+	provider := testkit.MustCreateOIDCProvider("flagger", "s3cr3t")
+	t.Cleanup(provider.Close)
+
+	testcases := []struct {
+		name             string
+		clientSecret     string
+		redirectURL      string
+		useWrongVerifier bool
+	}{
+		{
+			name:             "Incorrect client secret",
+			clientSecret:     "wr0ngs3cr3t",
+			redirectURL:      "http://localhost:3000/sso/callback",
+			useWrongVerifier: false,
+		},
+		{
+			name:             "Incorrect code verifier",
+			clientSecret:     "s3cr3t",
+			redirectURL:      "http://localhost:3000/sso/callback",
+			useWrongVerifier: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := oidc.NewClient(provider.URL(), "flagger", testcase.clientSecret, testcase.redirectURL, httputils.NewHTTPClient(nil))
+
+			verifier, err := oidc.GenerateCodeVerifier()
+			require.NoError(t, err)
+
+			authURL, err := client.AuthCodeURL(context.Background(), "somestate", "somenonce", oidc.CodeChallenge(verifier))
+			require.NoError(t, err)
+
+			code, _, err := provider.Authorize(authURL, testkit.OIDCUser{Subject: "248289761001"})
+			require.NoError(t, err)
+
+			if testcase.useWrongVerifier {
+				verifier, err = oidc.GenerateCodeVerifier()
+				require.NoError(t, err)
+			}
+
+			_, err = client.Exchange(context.Background(), code, verifier)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestClientVerifyIDToken(t *testing.T) {
+	t.Parallel()
+
+	provider := testkit.MustCreateOIDCProvider("flagger", "s3cr3t")
+	t.Cleanup(provider.Close)
+
+	otherProvider := testkit.MustCreateOIDCProvider("flagger", "s3cr3t")
+	t.Cleanup(otherProvider.Close)
+
+	client := oidc.NewClient(provider.URL(), "flagger", "s3cr3t", "http://localhost:3000/sso/callback", httputils.NewHTTPClient(nil))
+
+	now := time.Now().UTC()
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   provider.URL(),
+			"sub":   "248289761001",
+			"aud":   []string{"flagger", "other"},
+			"exp":   now.Add(time.Hour).Unix(),
+			"iat":   now.Unix(),
+			"nonce": "somenonce",
+		}
+	}
+
+	withClaim := func(key string, value any) jwt.MapClaims {
+		claims := validClaims()
+		claims[key] = value
+
+		return claims
+	}
+
+	unsignedToken, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name    string
+		idToken string
+		wantErr bool
+	}{
+		{
+			name:    "Valid ID token",
+			idToken: provider.SignIDToken(validClaims()),
+			wantErr: false,
+		},
+		{
+			name:    "ID token signed by another provider",
+			idToken: otherProvider.SignIDToken(validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "Unsigned ID token",
+			idToken: unsignedToken,
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect issuer",
+			idToken: provider.SignIDToken(withClaim("iss", otherProvider.URL())),
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect audience",
+			idToken: provider.SignIDToken(withClaim("aud", "other")),
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect nonce",
+			idToken: provider.SignIDToken(withClaim("nonce", "othernonce")),
+			wantErr: true,
+		},
+		{
+			name:    "Expired ID token",
+			idToken: provider.SignIDToken(withClaim("exp", now.Add(-time.Hour).Unix())),
+			wantErr: true,
+		},
+		{
+			name:    "ID token issued in the future",
+			idToken: provider.SignIDToken(withClaim("iat", now.Add(time.Hour).Unix())),
+			wantErr: true,
+		},
+		{
+			name:    "Missing subject",
+			idToken: provider.SignIDToken(withClaim("sub", "")),
+			wantErr: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			claims, err := client.VerifyIDToken(context.Background(), testcase.idToken, "somenonce")
+			if testcase.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "248289761001", claims.Subject)
+		})
+	}
+}
+
+func TestClientDiscoverError(t *testing.T) {
+	t.Parallel()
+
+	provider := testkit.MustCreateOIDCProvider("flagger", "s3cr3t")
+	t.Cleanup(provider.Close)
+
+	testcases := []struct {
+		name      string
+		issuerURL string
+	}{
+		{
+			name:      "Issuer mismatch",
+			issuerURL: provider.URL() + "/tenant",
+		},
+		{
+			name:      "Unreachable issuer",
+			issuerURL: "http://127.0.0.1:1",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := oidc.NewClient(testcase.issuerURL, "flagger", "s3cr3t", "http://localhost:3000/sso/callback", httputils.NewHTTPClient(nil))
+			_, err := client.Discover(context.Background())
+			require.Error(t, err)
+		})
+	}
+}
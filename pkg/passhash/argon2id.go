@@ -0,0 +1,148 @@
+package passhash
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/alvii147/flagger-api/pkg/utils"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// argon2idSaltLength is the number of random bytes in salts of argon2id hashes.
+	argon2idSaltLength = 16
+	// argon2idKeyLength is the number of bytes in keys derived by argon2id.
+	argon2idKeyLength = 32
+	// argon2idPrefix is the prefix of argon2id hashes in PHC string format.
+	argon2idPrefix = "$argon2id$"
+)
+
+// argon2idEncoding is the encoding used for salts and keys in PHC string format, which is unpadded base64.
+var argon2idEncoding = base64.RawStdEncoding
+
+// argon2idHash represents the parsed components of an argon2id hash.
+type argon2idHash struct {
+	version     int
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// parseArgon2idHash parses an argon2id hash in PHC string format,
+// such as $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>.
+func parseArgon2idHash(hashedPassword string) (*argon2idHash, error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != HasherTypeArgon2id {
+		return nil, fmt.Errorf("parseArgon2idHash failed, invalid number of fields: %w", ErrMalformedHash)
+	}
+
+	hash := &argon2idHash{}
+	_, err := fmt.Sscanf(parts[2], "v=%d", &hash.version)
+	if err != nil {
+		return nil, fmt.Errorf("parseArgon2idHash failed to fmt.Sscanf version %s, %w: %w", parts[2], ErrMalformedHash, err)
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hash.memory, &hash.iterations, &hash.parallelism)
+	if err != nil {
+		return nil, fmt.Errorf("parseArgon2idHash failed to fmt.Sscanf parameters %s, %w: %w", parts[3], ErrMalformedHash, err)
+	}
+
+	hash.salt, err = argon2idEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("parseArgon2idHash failed to argon2idEncoding.DecodeString salt, %w: %w", ErrMalformedHash, err)
+	}
+
+	hash.key, err = argon2idEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, fmt.Errorf("parseArgon2idHash failed to argon2idEncoding.DecodeString key, %w: %w", ErrMalformedHash, err)
+	}
+
+	if len(hash.key) == 0 {
+		return nil, fmt.Errorf("parseArgon2idHash failed, empty key: %w", ErrMalformedHash)
+	}
+
+	return hash, nil
+}
+
+// argon2idHasher implements a Hasher that hashes passwords using argon2id, encoded in PHC string format.
+type argon2idHasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// NewArgon2idHasher returns a new argon2idHasher.
+// Memory is in KiB.
+func NewArgon2idHasher(memory uint32, iterations uint32, parallelism uint8) *argon2idHasher {
+	return &argon2idHasher{
+		memory:      memory,
+		iterations:  iterations,
+		parallelism: parallelism,
+	}
+}
+
+// Hash hashes a password using a random salt.
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt, err := utils.GenerateRandomBytes(argon2idSaltLength)
+	if err != nil {
+		return "", fmt.Errorf("Hash failed to utils.GenerateRandomBytes: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.iterations, h.memory, h.parallelism, argon2idKeyLength)
+
+	hashedPassword := fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		h.memory,
+		h.iterations,
+		h.parallelism,
+		argon2idEncoding.EncodeToString(salt),
+		argon2idEncoding.EncodeToString(key),
+	)
+
+	return hashedPassword, nil
+}
+
+// Verify verifies a password against a hash, using the parameters encoded in the hash.
+func (h *argon2idHasher) Verify(hashedPassword string, password string) error {
+	hash, err := parseArgon2idHash(hashedPassword)
+	if err != nil {
+		return fmt.Errorf("Verify failed to parseArgon2idHash: %w", err)
+	}
+
+	if hash.version != argon2.Version {
+		return fmt.Errorf("Verify failed, unsupported argon2 version %d: %w", hash.version, ErrMalformedHash)
+	}
+
+	key := argon2.IDKey([]byte(password), hash.salt, hash.iterations, hash.memory, hash.parallelism, uint32(len(hash.key)))
+	if subtle.ConstantTimeCompare(key, hash.key) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+
+	return nil
+}
+
+// Recognizes determines whether or not a hash is an argon2id hash.
+func (h *argon2idHasher) Recognizes(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, argon2idPrefix)
+}
+
+// NeedsRehash determines whether or not an argon2id hash was produced using different parameters.
+func (h *argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	hash, err := parseArgon2idHash(hashedPassword)
+	if err != nil {
+		return true
+	}
+
+	return hash.version != argon2.Version ||
+		hash.memory != h.memory ||
+		hash.iterations != h.iterations ||
+		hash.parallelism != h.parallelism ||
+		len(hash.salt) != argon2idSaltLength ||
+		len(hash.key) != argon2idKeyLength
+}
@@ -0,0 +1,162 @@
+package passhash_test
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/alvii147/flagger-api/pkg/passhash"
+	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArgon2idHasherHash(t *testing.T) {
+	t.Parallel()
+
+	hasher := passhash.NewArgon2idHasher(1024, 2, 1)
+	password := testkit.GenerateFakePassword()
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.Regexp(t, regexp.MustCompile(`^\$argon2id\$v=19\$m=1024,t=2,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`), hashedPassword)
+
+	otherHashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.NotEqual(t, hashedPassword, otherHashedPassword)
+}
+
+func TestArgon2idHasherVerify(t *testing.T) {
+	t.Parallel()
+
+	hasher := passhash.NewArgon2idHasher(1024, 2, 1)
+	password := testkit.GenerateFakePassword()
+	longPassword := strings.Repeat("a", 72) + "b"
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+
+	hashedLongPassword, err := hasher.Hash(longPassword)
+	require.NoError(t, err)
+
+	otherParamsHashedPassword, err := passhash.NewArgon2idHasher(2048, 1, 2).Hash(password)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name           string
+		hashedPassword string
+		password       string
+		wantErr        error
+	}{
+		{
+			name:           "Correct password",
+			hashedPassword: hashedPassword,
+			password:       password,
+			wantErr:        nil,
+		},
+		{
+			name:           "Correct password hashed using other parameters",
+			hashedPassword: otherParamsHashedPassword,
+			password:       password,
+			wantErr:        nil,
+		},
+		{
+			name:           "Incorrect password",
+			hashedPassword: hashedPassword,
+			password:       "1nc0rr3CTP455w0Rd",
+			wantErr:        passhash.ErrMismatchedHashAndPassword,
+		},
+		{
+			name:           "Password differing after 72 bytes",
+			hashedPassword: hashedLongPassword,
+			password:       strings.Repeat("a", 72) + "c",
+			wantErr:        passhash.ErrMismatchedHashAndPassword,
+		},
+		{
+			name:           "Missing fields",
+			hashedPassword: "$argon2id$v=19$m=1024,t=2,p=1$c2FsdA",
+			password:       password,
+			wantErr:        passhash.ErrMalformedHash,
+		},
+		{
+			name:           "Invalid parameters",
+			hashedPassword: strings.Replace(hashedPassword, "m=1024,t=2,p=1", "m=x,t=2,p=1", 1),
+			password:       password,
+			wantErr:        passhash.ErrMalformedHash,
+		},
+		{
+			name:           "Unsupported version",
+			hashedPassword: strings.Replace(hashedPassword, "v=19", "v=16", 1),
+			password:       password,
+			wantErr:        passhash.ErrMalformedHash,
+		},
+		{
+			name:           "Invalid salt encoding",
+			hashedPassword: fmt.Sprintf("$argon2id$v=19$m=1024,t=2,p=1$!!!$%s", strings.Split(hashedPassword, "$")[5]),
+			password:       password,
+			wantErr:        passhash.ErrMalformedHash,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := hasher.Verify(testcase.hashedPassword, testcase.password)
+			if testcase.wantErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, testcase.wantErr)
+			}
+		})
+	}
+}
+
+func TestArgon2idHasherRecognizes(t *testing.T) {
+	t.Parallel()
+
+	hasher := passhash.NewArgon2idHasher(1024, 2, 1)
+
+	require.True(t, hasher.Recognizes("$argon2id$v=19$m=1024,t=2,p=1$c2FsdA$a2V5"))
+	require.False(t, hasher.Recognizes("$argon2i$v=19$m=1024,t=2,p=1$c2FsdA$a2V5"))
+	require.False(t, hasher.Recognizes("$2a$04$oMY2EBYBbrLIy84s8NwUieqk6gLBzEKGHAfJ7T2pdeGYTFRHwnyFq"))
+}
+
+func TestArgon2idHasherNeedsRehash(t *testing.T) {
+	t.Parallel()
+
+	hasher := passhash.NewArgon2idHasher(1024, 2, 1)
+	password := testkit.GenerateFakePassword()
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	testcases := []struct {
+		name   string
+		hasher passhash.Hasher
+	}{
+		{
+			name:   "Different memory",
+			hasher: passhash.NewArgon2idHasher(2048, 2, 1),
+		},
+		{
+			name:   "Different iterations",
+			hasher: passhash.NewArgon2idHasher(1024, 3, 1),
+		},
+		{
+			name:   "Different parallelism",
+			hasher: passhash.NewArgon2idHasher(1024, 2, 2),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.True(t, testcase.hasher.NeedsRehash(hashedPassword))
+		})
+	}
+
+	require.True(t, hasher.NeedsRehash("$argon2id$v=19$m=1024,t=2,p=1$c2FsdA"))
+}
@@ -0,0 +1,70 @@
+package passhash
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptPrefixes are the prefixes of bcrypt hashes in modular crypt format.
+var bcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+// bcryptHasher implements a Hasher that hashes passwords using bcrypt.
+// bcrypt only uses the first 72 bytes of passwords.
+type bcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher returns a new bcryptHasher.
+func NewBcryptHasher(cost int) *bcryptHasher {
+	return &bcryptHasher{
+		cost: cost,
+	}
+}
+
+// Hash hashes a password.
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", fmt.Errorf("Hash failed to bcrypt.GenerateFromPassword: %w", err)
+	}
+
+	return string(hashedPasswordBytes), nil
+}
+
+// Verify verifies a password against a hash.
+func (h *bcryptHasher) Verify(hashedPassword string, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatchedHashAndPassword
+		}
+
+		return fmt.Errorf("Verify failed to bcrypt.CompareHashAndPassword, %w: %w", ErrMalformedHash, err)
+	}
+
+	return nil
+}
+
+// Recognizes determines whether or not a hash is a bcrypt hash.
+func (h *bcryptHasher) Recognizes(hashedPassword string) bool {
+	for _, prefix := range bcryptPrefixes {
+		if strings.HasPrefix(hashedPassword, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NeedsRehash determines whether or not a bcrypt hash was produced using a different cost.
+func (h *bcryptHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
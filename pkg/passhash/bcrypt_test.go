@@ -0,0 +1,70 @@
+package passhash_test
+
+import (
+	"testing"
+
+	"github.com/alvii147/flagger-api/pkg/passhash"
+	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcryptHasherHash(t *testing.T) {
+	t.Parallel()
+
+	hasher := passhash.NewBcryptHasher(bcrypt.MinCost)
+	password := testkit.GenerateFakePassword()
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	require.NoError(t, err)
+	require.Equal(t, bcrypt.MinCost, cost)
+
+	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	require.NoError(t, err)
+}
+
+func TestBcryptHasherVerify(t *testing.T) {
+	t.Parallel()
+
+	hasher := passhash.NewBcryptHasher(bcrypt.MinCost)
+	password := testkit.GenerateFakePassword()
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+
+	err = hasher.Verify(hashedPassword, password)
+	require.NoError(t, err)
+
+	err = hasher.Verify(hashedPassword, "1nc0rr3CTP455w0Rd")
+	require.ErrorIs(t, err, passhash.ErrMismatchedHashAndPassword)
+
+	err = hasher.Verify("$2a$04$invalid", password)
+	require.ErrorIs(t, err, passhash.ErrMalformedHash)
+}
+
+func TestBcryptHasherRecognizes(t *testing.T) {
+	t.Parallel()
+
+	hasher := passhash.NewBcryptHasher(bcrypt.MinCost)
+
+	require.True(t, hasher.Recognizes("$2a$04$oMY2EBYBbrLIy84s8NwUieqk6gLBzEKGHAfJ7T2pdeGYTFRHwnyFq"))
+	require.True(t, hasher.Recognizes("$2b$04$oMY2EBYBbrLIy84s8NwUieqk6gLBzEKGHAfJ7T2pdeGYTFRHwnyFq"))
+	require.True(t, hasher.Recognizes("$2y$04$oMY2EBYBbrLIy84s8NwUieqk6gLBzEKGHAfJ7T2pdeGYTFRHwnyFq"))
+	require.False(t, hasher.Recognizes("$argon2id$v=19$m=1024,t=2,p=1$c2FsdA$a2V5"))
+}
+
+func TestBcryptHasherNeedsRehash(t *testing.T) {
+	t.Parallel()
+
+	hasher := passhash.NewBcryptHasher(bcrypt.MinCost)
+
+	hashedPassword, err := hasher.Hash(testkit.GenerateFakePassword())
+	require.NoError(t, err)
+
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+	require.True(t, passhash.NewBcryptHasher(bcrypt.MinCost+1).NeedsRehash(hashedPassword))
+	require.True(t, hasher.NeedsRehash("$2a$invalid"))
+}
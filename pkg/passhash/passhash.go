@@ -0,0 +1,77 @@
+package passhash
+
+import (
+	"errors"
+)
+
+// password hasher types.
+const (
+	HasherTypeArgon2id = "argon2id"
+	HasherTypeBcrypt   = "bcrypt"
+)
+
+// ErrMismatchedHashAndPassword is returned when a password does not match a hash.
+var ErrMismatchedHashAndPassword = errors.New("hashed password does not match password")
+
+// ErrUnknownHashFormat is returned when a hash is not in the format of any supported hasher.
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// ErrMalformedHash is returned when a hash is in the format of a supported hasher but cannot be parsed.
+var ErrMalformedHash = errors.New("malformed password hash")
+
+// Hasher is used to hash passwords and verify passwords against hashes.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(hashedPassword string, password string) error
+	Recognizes(hashedPassword string) bool
+	NeedsRehash(hashedPassword string) bool
+}
+
+// multiHasher implements a Hasher that hashes passwords using its current Hasher,
+// and verifies passwords against hashes of any of its Hashers.
+// This allows hashing algorithms and parameters to be changed without invalidating existing hashes.
+type multiHasher struct {
+	current Hasher
+	hashers []Hasher
+}
+
+// NewMultiHasher returns a new multiHasher.
+func NewMultiHasher(current Hasher, legacy ...Hasher) *multiHasher {
+	return &multiHasher{
+		current: current,
+		hashers: append([]Hasher{current}, legacy...),
+	}
+}
+
+// Hash hashes a password using the current Hasher.
+func (mh *multiHasher) Hash(password string) (string, error) {
+	return mh.current.Hash(password)
+}
+
+// Verify verifies a password against a hash using the first Hasher that recognizes the hash.
+func (mh *multiHasher) Verify(hashedPassword string, password string) error {
+	for _, hasher := range mh.hashers {
+		if hasher.Recognizes(hashedPassword) {
+			return hasher.Verify(hashedPassword, password)
+		}
+	}
+
+	return ErrUnknownHashFormat
+}
+
+// Recognizes determines whether or not any of the Hashers recognizes a hash.
+func (mh *multiHasher) Recognizes(hashedPassword string) bool {
+	for _, hasher := range mh.hashers {
+		if hasher.Recognizes(hashedPassword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NeedsRehash determines whether or not a hash should be replaced,
+// because it was not produced by the current Hasher, or was produced using different parameters.
+func (mh *multiHasher) NeedsRehash(hashedPassword string) bool {
+	return !mh.current.Recognizes(hashedPassword) || mh.current.NeedsRehash(hashedPassword)
+}
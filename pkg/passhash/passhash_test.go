@@ -0,0 +1,104 @@
+package passhash_test
+
+import (
+	"testing"
+
+	"github.com/alvii147/flagger-api/pkg/passhash"
+	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMultiHasher(t *testing.T) {
+	t.Parallel()
+
+	argon2idHasher := passhash.NewArgon2idHasher(1024, 2, 1)
+	bcryptHasher := passhash.NewBcryptHasher(bcrypt.MinCost)
+	hasher := passhash.NewMultiHasher(argon2idHasher, bcryptHasher)
+
+	password := testkit.GenerateFakePassword()
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.True(t, argon2idHasher.Recognizes(hashedPassword))
+
+	bcryptHashedPassword, err := bcryptHasher.Hash(password)
+	require.NoError(t, err)
+
+	outdatedHashedPassword, err := passhash.NewArgon2idHasher(2048, 2, 1).Hash(password)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name            string
+		hashedPassword  string
+		password        string
+		wantErr         error
+		wantRecognizes  bool
+		wantNeedsRehash bool
+	}{
+		{
+			name:            "Current hash",
+			hashedPassword:  hashedPassword,
+			password:        password,
+			wantErr:         nil,
+			wantRecognizes:  true,
+			wantNeedsRehash: false,
+		},
+		{
+			name:            "Current hash with incorrect password",
+			hashedPassword:  hashedPassword,
+			password:        "1nc0rr3CTP455w0Rd",
+			wantErr:         passhash.ErrMismatchedHashAndPassword,
+			wantRecognizes:  true,
+			wantNeedsRehash: false,
+		},
+		{
+			name:            "Current hasher with outdated parameters",
+			hashedPassword:  outdatedHashedPassword,
+			password:        password,
+			wantErr:         nil,
+			wantRecognizes:  true,
+			wantNeedsRehash: true,
+		},
+		{
+			name:            "Legacy hash",
+			hashedPassword:  bcryptHashedPassword,
+			password:        password,
+			wantErr:         nil,
+			wantRecognizes:  true,
+			wantNeedsRehash: true,
+		},
+		{
+			name:            "Legacy hash with incorrect password",
+			hashedPassword:  bcryptHashedPassword,
+			password:        "1nc0rr3CTP455w0Rd",
+			wantErr:         passhash.ErrMismatchedHashAndPassword,
+			wantRecognizes:  true,
+			wantNeedsRehash: true,
+		},
+		{
+			name:            "Unknown hash",
+			hashedPassword:  "$scrypt$ln=16,r=8,p=1$c2FsdA$a2V5",
+			password:        password,
+			wantErr:         passhash.ErrUnknownHashFormat,
+			wantRecognizes:  false,
+			wantNeedsRehash: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := hasher.Verify(testcase.hashedPassword, testcase.password)
+			if testcase.wantErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, testcase.wantErr)
+			}
+
+			require.Equal(t, testcase.wantRecognizes, hasher.Recognizes(testcase.hashedPassword))
+			require.Equal(t, testcase.wantNeedsRehash, hasher.NeedsRehash(testcase.hashedPassword))
+		})
+	}
+}
@@ -31,9 +31,21 @@ func GenerateFakeEmail() string {
 	)
 }
 
-// GenerateFakeEmail generates a randomized password.
+// GenerateFakePassword generates a randomized password
+// containing lowercase letters, uppercase letters and digits.
 func GenerateFakePassword() string {
-	password := MustGenerateRandomString(20, true, true, true)
+	password := MustGenerateRandomString(8, true, false, false) +
+		MustGenerateRandomString(6, false, true, false) +
+		MustGenerateRandomString(6, false, false, true)
 
 	return password
 }
+
+// GenerateFakeIPAddress generates a randomized IPv6 address in the documentation range 2001:db8::/32.
+func GenerateFakeIPAddress() string {
+	return fmt.Sprintf(
+		"2001:db8:%s:%s::1",
+		MustGenerateRandomString(4, false, false, true),
+		MustGenerateRandomString(4, false, false, true),
+	)
+}
@@ -2,6 +2,7 @@ package testkit_test
 
 import (
 	"net/mail"
+	"net/netip"
 	"testing"
 
 	"github.com/alvii147/flagger-api/pkg/testkit"
@@ -42,5 +43,18 @@ func TestGenerateFakePassword(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		password := testkit.GenerateFakePassword()
 		require.Greater(t, len(password), 0)
+		require.Regexp(t, `[a-z]`, password)
+		require.Regexp(t, `[A-Z]`, password)
+		require.Regexp(t, `[0-9]`, password)
+	}
+}
+
+func TestGenerateFakeIPAddress(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 10; i++ {
+		ipAddress := testkit.GenerateFakeIPAddress()
+		_, err := netip.ParseAddr(ipAddress)
+		require.NoError(t, err)
 	}
 }
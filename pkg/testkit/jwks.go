@@ -0,0 +1,45 @@
+package testkit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MustGenerateEd25519Key generates a new Ed25519 private key and panics on error.
+func MustGenerateEd25519Key() ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(fmt.Sprintf("MustGenerateEd25519Key failed to ed25519.GenerateKey: %v", err))
+	}
+
+	return key
+}
+
+// MustWriteKeyFile writes a given private key as PKCS #8 PEM,
+// or a given public key as PKIX PEM, to a new file in a given directory,
+// returns the path of the file, and panics on error.
+func MustWriteKeyFile(dir string, key any) string {
+	blockType := "PRIVATE KEY"
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		blockType = "PUBLIC KEY"
+		der, err = x509.MarshalPKIXPublicKey(key)
+	}
+
+	if err != nil {
+		panic(fmt.Sprintf("MustWriteKeyFile failed to marshal key of type %T: %v", key, err))
+	}
+
+	path := filepath.Join(dir, MustGenerateRandomString(12, true, false, true)+".pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600)
+	if err != nil {
+		panic(fmt.Sprintf("MustWriteKeyFile failed to os.WriteFile %s: %v", path, err))
+	}
+
+	return path
+}
@@ -0,0 +1,45 @@
+package testkit_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/alvii147/flagger-api/pkg/jwks"
+	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMustGenerateEd25519Key(t *testing.T) {
+	t.Parallel()
+
+	key := testkit.MustGenerateEd25519Key()
+	require.Len(t, key, ed25519.PrivateKeySize)
+	require.NotEqual(t, key, testkit.MustGenerateEd25519Key())
+}
+
+func TestMustWriteKeyFile(t *testing.T) {
+	t.Parallel()
+
+	key := testkit.MustGenerateEd25519Key()
+
+	privateKey, err := jwks.ReadKeyFile(testkit.MustWriteKeyFile(t.TempDir(), key))
+	require.NoError(t, err)
+	require.True(t, privateKey.CanSign())
+
+	publicKey, err := jwks.ReadKeyFile(testkit.MustWriteKeyFile(t.TempDir(), key.Public()))
+	require.NoError(t, err)
+	require.False(t, publicKey.CanSign())
+
+	require.Equal(t, privateKey.ID, publicKey.ID)
+}
+
+func TestMustWriteKeyFilePanic(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		r := recover()
+		require.NotNil(t, r)
+	}()
+
+	testkit.MustWriteKeyFile(t.TempDir(), "notakey")
+}
@@ -0,0 +1,226 @@
+package testkit
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alvii147/flagger-api/pkg/jwks"
+	"github.com/alvii147/flagger-api/pkg/oidc"
+	"github.com/golang-jwt/jwt"
+)
+
+// OIDCUser represents a User signing in at OIDCProvider.
+type OIDCUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+// oidcAuthorization represents an authorization code issued by OIDCProvider.
+type oidcAuthorization struct {
+	user          OIDCUser
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+// OIDCProvider is a stub OpenID Connect provider served by httptest,
+// implementing discovery, the authorization code flow with PKCE, and a JSON Web Key Set.
+type OIDCProvider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+	KeyID        string
+	key          *rsa.PrivateKey
+	mu           sync.Mutex
+	codes        map[string]*oidcAuthorization
+}
+
+// MustCreateOIDCProvider starts a new OIDCProvider for a given client and panics on error.
+func MustCreateOIDCProvider(clientID string, clientSecret string) *OIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("MustCreateOIDCProvider failed to rsa.GenerateKey: %v", err))
+	}
+
+	p := &OIDCProvider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		KeyID:        MustGenerateRandomString(16, true, false, true),
+		key:          key,
+		codes:        make(map[string]*oidcAuthorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(oidc.DiscoveryPath, p.handleDiscovery)
+	mux.HandleFunc("/keys", p.handleKeys)
+	mux.HandleFunc("/token", p.handleToken)
+	p.Server = httptest.NewServer(mux)
+
+	return p
+}
+
+// URL returns the issuer URL of the OIDCProvider.
+func (p *OIDCProvider) URL() string {
+	return p.Server.URL
+}
+
+// Close shuts down the OIDCProvider.
+func (p *OIDCProvider) Close() {
+	p.Server.Close()
+}
+
+// Authorize simulates a User signing in at the authorization endpoint using a given authorization URL,
+// and returns the authorization code and state that the provider would redirect back with.
+func (p *OIDCProvider) Authorize(authURL string, user OIDCUser) (string, string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", fmt.Errorf("Authorize failed to url.Parse %s: %w", authURL, err)
+	}
+
+	if u.Scheme+"://"+u.Host != p.URL() || u.Path != "/authorize" {
+		return "", "", fmt.Errorf("Authorize failed, %s is not the authorization endpoint", authURL)
+	}
+
+	query := u.Query()
+	switch {
+	case query.Get("response_type") != "code":
+		return "", "", errors.New("Authorize failed, unsupported response type")
+	case query.Get("client_id") != p.ClientID:
+		return "", "", errors.New("Authorize failed, unknown client")
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		return "", "", errors.New("Authorize failed, missing S256 code challenge")
+	case !strings.Contains(query.Get("scope"), "openid"):
+		return "", "", errors.New("Authorize failed, missing openid scope")
+	}
+
+	code := MustGenerateRandomString(32, true, true, true)
+
+	p.mu.Lock()
+	p.codes[code] = &oidcAuthorization{
+		user:          user,
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		redirectURI:   query.Get("redirect_uri"),
+	}
+	p.mu.Unlock()
+
+	return code, query.Get("state"), nil
+}
+
+// SignIDToken signs given claims as an ID token using the OIDCProvider's key.
+func (p *OIDCProvider) SignIDToken(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.KeyID
+
+	signedToken, err := token.SignedString(p.key)
+	if err != nil {
+		panic(fmt.Sprintf("SignIDToken failed to token.SignedString: %v", err))
+	}
+
+	return signedToken
+}
+
+// handleDiscovery serves the provider configuration.
+func (p *OIDCProvider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, &oidc.ProviderConfig{
+		Issuer:                p.URL(),
+		AuthorizationEndpoint: p.URL() + "/authorize",
+		TokenEndpoint:         p.URL() + "/token",
+		JWKSURI:               p.URL() + "/keys",
+	}, http.StatusOK)
+}
+
+// handleKeys serves the JSON Web Key Set.
+func (p *OIDCProvider) handleKeys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, &jwks.JSONWebKeySet{
+		Keys: []jwks.JSONWebKey{
+			{
+				KeyType:   jwks.KeyTypeRSA,
+				KeyID:     p.KeyID,
+				Use:       "sig",
+				Algorithm: "RS256",
+				Modulus:   base64.RawURLEncoding.EncodeToString(p.key.PublicKey.N.Bytes()),
+				Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.PublicKey.E)).Bytes()),
+			},
+		},
+	}, http.StatusOK)
+}
+
+// handleToken exchanges authorization codes for ID tokens.
+func (p *OIDCProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, map[string]string{"error": "invalid_request"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if p.ClientSecret != "" {
+		clientID, clientSecret, ok := r.BasicAuth()
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+		if !ok || clientID != p.ClientID || clientSecret != p.ClientSecret {
+			writeJSON(w, map[string]string{"error": "invalid_client"}, http.StatusUnauthorized)
+			return
+		}
+	}
+
+	err := r.ParseForm()
+	if err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, map[string]string{"error": "invalid_request"}, http.StatusBadRequest)
+		return
+	}
+
+	code := r.PostForm.Get("code")
+
+	p.mu.Lock()
+	authorization, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	if !ok ||
+		authorization.redirectURI != r.PostForm.Get("redirect_uri") ||
+		oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != authorization.codeChallenge {
+		writeJSON(w, map[string]string{"error": "invalid_grant"}, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	idToken := p.SignIDToken(jwt.MapClaims{
+		"iss":            p.URL(),
+		"sub":            authorization.user.Subject,
+		"aud":            p.ClientID,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          authorization.nonce,
+		"email":          authorization.user.Email,
+		"email_verified": authorization.user.EmailVerified,
+		"given_name":     authorization.user.GivenName,
+		"family_name":    authorization.user.FamilyName,
+	})
+
+	writeJSON(w, map[string]string{
+		"id_token":     idToken,
+		"access_token": MustGenerateRandomString(32, true, true, true),
+		"token_type":   "Bearer",
+	}, http.StatusOK)
+}
+
+// writeJSON writes JSON response with given status code.
+func writeJSON(w http.ResponseWriter, v any, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
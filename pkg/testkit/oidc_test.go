@@ -0,0 +1,115 @@
+package testkit_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/alvii147/flagger-api/pkg/oidc"
+	"github.com/alvii147/flagger-api/pkg/testkit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMustCreateOIDCProviderDiscovery(t *testing.T) {
+	t.Parallel()
+
+	provider := testkit.MustCreateOIDCProvider("flagger", "s3cr3t")
+	t.Cleanup(provider.Close)
+
+	res, err := http.Get(provider.URL() + oidc.DiscoveryPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := res.Body.Close()
+		require.NoError(t, err)
+	})
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var providerConfig oidc.ProviderConfig
+	err = json.NewDecoder(res.Body).Decode(&providerConfig)
+	require.NoError(t, err)
+
+	require.Equal(t, provider.URL(), providerConfig.Issuer)
+	require.Equal(t, provider.URL()+"/authorize", providerConfig.AuthorizationEndpoint)
+	require.Equal(t, provider.URL()+"/token", providerConfig.TokenEndpoint)
+	require.Equal(t, provider.URL()+"/keys", providerConfig.JWKSURI)
+}
+
+func TestOIDCProviderAuthorize(t *testing.T) {
+	t.Parallel()
+
+	provider := testkit.MustCreateOIDCProvider("flagger", "s3cr3t")
+	t.Cleanup(provider.Close)
+
+	buildURL := func(modifier func(query url.Values)) string {
+		query := url.Values{}
+		query.Set("response_type", "code")
+		query.Set("client_id", "flagger")
+		query.Set("redirect_uri", "http://localhost:3000/sso/callback")
+		query.Set("scope", "openid email profile")
+		query.Set("state", "somestate")
+		query.Set("nonce", "somenonce")
+		query.Set("code_challenge", oidc.CodeChallenge("verifier"))
+		query.Set("code_challenge_method", "S256")
+
+		if modifier != nil {
+			modifier(query)
+		}
+
+		return provider.URL() + "/authorize?" + query.Encode()
+	}
+
+	testcases := []struct {
+		name    string
+		authURL string
+		wantErr bool
+	}{
+		{
+			name:    "Valid authorization URL",
+			authURL: buildURL(nil),
+			wantErr: false,
+		},
+		{
+			name:    "Unknown client",
+			authURL: buildURL(func(query url.Values) { query.Set("client_id", "unknown") }),
+			wantErr: true,
+		},
+		{
+			name:    "Missing code challenge",
+			authURL: buildURL(func(query url.Values) { query.Del("code_challenge") }),
+			wantErr: true,
+		},
+		{
+			name:    "Plain code challenge method",
+			authURL: buildURL(func(query url.Values) { query.Set("code_challenge_method", "plain") }),
+			wantErr: true,
+		},
+		{
+			name:    "Missing openid scope",
+			authURL: buildURL(func(query url.Values) { query.Set("scope", "email") }),
+			wantErr: true,
+		},
+		{
+			name:    "Another host",
+			authURL: "http://localhost:1/authorize",
+			wantErr: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, state, err := provider.Authorize(testcase.authURL, testkit.OIDCUser{Subject: "248289761001"})
+			if testcase.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotEmpty(t, code)
+			require.Equal(t, "somestate", state)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alvii147/flagger-api/pkg/utils"
+)
+
+const (
+	// Digits is the number of digits in generated codes.
+	Digits = 6
+	// Period is the duration for which each code is valid.
+	Period = 30 * time.Second
+	// SecretSize is the number of random bytes in generated secrets.
+	SecretSize = 20
+)
+
+// secretEncoding is the encoding used for secrets, which is unpadded base32 as expected by authenticator apps.
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates new random base32-encoded secret.
+func GenerateSecret() (string, error) {
+	secretBytes, err := utils.GenerateRandomBytes(SecretSize)
+	if err != nil {
+		return "", fmt.Errorf("GenerateSecret failed to utils.GenerateRandomBytes: %w", err)
+	}
+
+	return secretEncoding.EncodeToString(secretBytes), nil
+}
+
+// decodeSecret decodes base32-encoded secret, ignoring case, spaces, and padding.
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+
+	key, err := secretEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("decodeSecret failed to secretEncoding.DecodeString: %w", err)
+	}
+
+	return key, nil
+}
+
+// TimeStep returns the time step that the given time falls in.
+func TimeStep(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// codeAtStep computes the code for a given key and time step, as described in RFC 4226 and RFC 6238.
+func codeAtStep(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	binCode := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulus := uint32(1)
+	for range Digits {
+		modulus *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, binCode%modulus)
+}
+
+// GenerateCode generates code for given secret at given time.
+func GenerateCode(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", fmt.Errorf("GenerateCode failed to decodeSecret: %w", err)
+	}
+
+	return codeAtStep(key, TimeStep(t)), nil
+}
+
+// ValidateCode checks code against the codes for given secret at given time,
+// allowing for up to skew time steps of clock drift in either direction.
+// If the code is valid, the time step it matched is returned, so that callers can reject reused codes.
+func ValidateCode(secret string, code string, t time.Time, skew int) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	currentStep := TimeStep(t)
+	matchedStep := int64(0)
+	ok := false
+
+	for i := -skew; i <= skew; i++ {
+		step := currentStep + int64(i)
+		if subtle.ConstantTimeCompare([]byte(codeAtStep(key, step)), []byte(code)) == 1 && !ok {
+			matchedStep = step
+			ok = true
+		}
+	}
+
+	return matchedStep, ok
+}
+
+// BuildURI builds otpauth URI for given issuer, account name, and secret,
+// which can be encoded into a QR code and scanned by authenticator apps.
+func BuildURI(issuer string, accountName string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprintf("%d", Digits))
+	query.Set("period", fmt.Sprintf("%d", int(Period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
@@ -0,0 +1,209 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alvii147/flagger-api/pkg/totp"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the shared secret used for SHA1 test vectors in RFC 6238, "12345678901234567890", encoded in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateSecret(t *testing.T) {
+	t.Parallel()
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	require.NoError(t, err)
+	require.Len(t, key, totp.SecretSize)
+
+	otherSecret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, secret, otherSecret)
+}
+
+func TestGenerateCode(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		secret   string
+		time     time.Time
+		wantCode string
+		wantErr  bool
+	}{
+		{
+			name:     "RFC 6238 test vector at 59",
+			secret:   rfcSecret,
+			time:     time.Unix(59, 0),
+			wantCode: "287082",
+			wantErr:  false,
+		},
+		{
+			name:     "RFC 6238 test vector at 1111111109",
+			secret:   rfcSecret,
+			time:     time.Unix(1111111109, 0),
+			wantCode: "081804",
+			wantErr:  false,
+		},
+		{
+			name:     "RFC 6238 test vector at 1234567890",
+			secret:   rfcSecret,
+			time:     time.Unix(1234567890, 0),
+			wantCode: "005924",
+			wantErr:  false,
+		},
+		{
+			name:     "RFC 6238 test vector at 20000000000",
+			secret:   rfcSecret,
+			time:     time.Unix(20000000000, 0),
+			wantCode: "353130",
+			wantErr:  false,
+		},
+		{
+			name:     "Lowercase secret with spaces and padding",
+			secret:   "gezd gnbv gy3t qojq gezd gnbv gy3t qojq====",
+			time:     time.Unix(59, 0),
+			wantCode: "287082",
+			wantErr:  false,
+		},
+		{
+			name:     "Invalid secret",
+			secret:   "n0t-b4s3-32!",
+			time:     time.Unix(59, 0),
+			wantCode: "",
+			wantErr:  true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, err := totp.GenerateCode(testcase.secret, testcase.time)
+			if testcase.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantCode, code)
+		})
+	}
+}
+
+func TestValidateCode(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1111111109, 0)
+	currentCode, err := totp.GenerateCode(rfcSecret, now)
+	require.NoError(t, err)
+
+	previousCode, err := totp.GenerateCode(rfcSecret, now.Add(-totp.Period))
+	require.NoError(t, err)
+
+	staleCode, err := totp.GenerateCode(rfcSecret, now.Add(-2*totp.Period))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name     string
+		secret   string
+		code     string
+		skew     int
+		wantStep int64
+		wantOk   bool
+	}{
+		{
+			name:     "Current code",
+			secret:   rfcSecret,
+			code:     currentCode,
+			skew:     1,
+			wantStep: totp.TimeStep(now),
+			wantOk:   true,
+		},
+		{
+			name:     "Previous code within skew",
+			secret:   rfcSecret,
+			code:     previousCode,
+			skew:     1,
+			wantStep: totp.TimeStep(now) - 1,
+			wantOk:   true,
+		},
+		{
+			name:     "Previous code without skew",
+			secret:   rfcSecret,
+			code:     previousCode,
+			skew:     0,
+			wantStep: 0,
+			wantOk:   false,
+		},
+		{
+			name:     "Code outside skew",
+			secret:   rfcSecret,
+			code:     staleCode,
+			skew:     1,
+			wantStep: 0,
+			wantOk:   false,
+		},
+		{
+			name:     "Incorrect code",
+			secret:   rfcSecret,
+			code:     "000000",
+			skew:     1,
+			wantStep: 0,
+			wantOk:   false,
+		},
+		{
+			name:     "Code of wrong length",
+			secret:   rfcSecret,
+			code:     "12345",
+			skew:     1,
+			wantStep: 0,
+			wantOk:   false,
+		},
+		{
+			name:     "Invalid secret",
+			secret:   "n0t-b4s3-32!",
+			code:     currentCode,
+			skew:     1,
+			wantStep: 0,
+			wantOk:   false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			step, ok := totp.ValidateCode(testcase.secret, testcase.code, now, testcase.skew)
+			require.Equal(t, testcase.wantOk, ok)
+			require.Equal(t, testcase.wantStep, step)
+		})
+	}
+}
+
+func TestBuildURI(t *testing.T) {
+	t.Parallel()
+
+	uri := totp.BuildURI("Flagger", "michael.scott@dundermifflin.com", rfcSecret)
+
+	parsedURI, err := url.Parse(uri)
+	require.NoError(t, err)
+
+	require.Equal(t, "otpauth", parsedURI.Scheme)
+	require.Equal(t, "totp", parsedURI.Host)
+	require.Equal(t, "/Flagger:michael.scott@dundermifflin.com", parsedURI.Path)
+
+	query := parsedURI.Query()
+	require.Equal(t, rfcSecret, query.Get("secret"))
+	require.Equal(t, "Flagger", query.Get("issuer"))
+	require.Equal(t, "SHA1", query.Get("algorithm"))
+	require.Equal(t, "6", query.Get("digits"))
+	require.Equal(t, "30", query.Get("period"))
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
@@ -26,3 +27,23 @@ func (jt *JSONTimeStamp) UnmarshalJSON(p []byte) error {
 
 	return nil
 }
+
+// JSONOptional represents an optional JSON field,
+// distinguishing between a field that is absent and a field that is explicitly null.
+type JSONOptional[T any] struct {
+	Set   bool
+	Value T
+}
+
+// UnmarshalJSON marks the field as set and converts bytes into its value.
+// This is only called when the field is present in JSON, including when it is null.
+func (jo *JSONOptional[T]) UnmarshalJSON(p []byte) error {
+	err := json.Unmarshal(p, &jo.Value)
+	if err != nil {
+		return fmt.Errorf("UnmarshalJSON failed to json.Unmarshal: %w", err)
+	}
+
+	jo.Set = true
+
+	return nil
+}
@@ -47,3 +47,64 @@ func TestJSONTimeStampUnmarshalJSONError(t *testing.T) {
 	err := json.Unmarshal([]byte(`{"timestamp":"string value"}`), &s)
 	require.Error(t, err)
 }
+
+func TestJSONOptionalUnmarshalJSONSuccess(t *testing.T) {
+	t.Parallel()
+
+	type jsonStruct struct {
+		Name utils.JSONOptional[*string] `json:"name"`
+	}
+
+	name := "Dwight"
+
+	testcases := []struct {
+		name      string
+		data      string
+		wantSet   bool
+		wantValue *string
+	}{
+		{
+			name:      "Present field is set",
+			data:      `{"name":"Dwight"}`,
+			wantSet:   true,
+			wantValue: &name,
+		},
+		{
+			name:      "Null field is set",
+			data:      `{"name":null}`,
+			wantSet:   true,
+			wantValue: nil,
+		},
+		{
+			name:      "Absent field is not set",
+			data:      `{}`,
+			wantSet:   false,
+			wantValue: nil,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := jsonStruct{}
+			err := json.Unmarshal([]byte(testcase.data), &s)
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantSet, s.Name.Set)
+			require.Equal(t, testcase.wantValue, s.Name.Value)
+		})
+	}
+}
+
+func TestJSONOptionalUnmarshalJSONError(t *testing.T) {
+	t.Parallel()
+
+	type jsonStruct struct {
+		Count utils.JSONOptional[int] `json:"count"`
+	}
+
+	s := jsonStruct{}
+	err := json.Unmarshal([]byte(`{"count":"string value"}`), &s)
+	require.Error(t, err)
+	require.False(t, s.Count.Set)
+}
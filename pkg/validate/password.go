@@ -0,0 +1,164 @@
+package validate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minPersonalInfoLength is the minimum length of personal information, such as names,
+// for passwords containing it to be rejected.
+// Shorter values are too likely to appear by coincidence.
+const minPersonalInfoLength = 3
+
+// breachedPasswordsGzip is a gzip-compressed list of common and breached passwords.
+// Each entry is the first 8 bytes of the SHA-1 hash of a lowercase password,
+// sorted in ascending order and encoded as big-endian unsigned integers.
+//
+//go:embed breached_passwords.gz
+var breachedPasswordsGzip []byte
+
+// loadBreachedPasswords decompresses and decodes the bundled list of breached password hash prefixes.
+var loadBreachedPasswords = sync.OnceValues(func() ([]uint64, error) {
+	r, err := gzip.NewReader(bytes.NewReader(breachedPasswordsGzip))
+	if err != nil {
+		return nil, fmt.Errorf("loadBreachedPasswords failed to gzip.NewReader: %w", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("loadBreachedPasswords failed to io.ReadAll: %w", err)
+	}
+
+	if len(data)%8 != 0 {
+		return nil, fmt.Errorf("loadBreachedPasswords failed, invalid data length %d", len(data))
+	}
+
+	prefixes := make([]uint64, len(data)/8)
+	for i := range prefixes {
+		prefixes[i] = binary.BigEndian.Uint64(data[i*8:])
+	}
+
+	return prefixes, nil
+})
+
+// IsBreachedPassword determines whether or not a password, ignoring case,
+// is in the bundled list of common and breached passwords.
+func IsBreachedPassword(password string) (bool, error) {
+	prefixes, err := loadBreachedPasswords()
+	if err != nil {
+		return false, fmt.Errorf("IsBreachedPassword failed to loadBreachedPasswords: %w", err)
+	}
+
+	hash := sha1.Sum([]byte(strings.ToLower(password)))
+	_, found := slices.BinarySearch(prefixes, binary.BigEndian.Uint64(hash[:8]))
+
+	return found, nil
+}
+
+// PasswordPolicy represents the requirements passwords must satisfy.
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters.
+	MinLength int
+	// MinCharacterClasses is the minimum number of character classes,
+	// out of lowercase letters, uppercase letters, digits and symbols.
+	MinCharacterClasses int
+	// RejectPersonalInfo determines whether or not passwords containing personal information,
+	// such as email address or name, are rejected.
+	RejectPersonalInfo bool
+	// RejectBreached determines whether or not common and breached passwords are rejected.
+	RejectBreached bool
+}
+
+// countCharacterClasses counts the number of character classes in a given string,
+// out of lowercase letters, uppercase letters, digits and symbols.
+func countCharacterClasses(value string) int {
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range value {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	count := 0
+	for _, has := range []bool{hasLower, hasUpper, hasDigit, hasSymbol} {
+		if has {
+			count++
+		}
+	}
+
+	return count
+}
+
+// containsPersonalInfo determines whether or not a password contains any of given personal information, ignoring case.
+// Email addresses are checked both in full and by their local part.
+func containsPersonalInfo(password string, personalInfo []string) bool {
+	password = strings.ToLower(password)
+	for _, info := range personalInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+		candidates := []string{info}
+		localPart, _, found := strings.Cut(info, "@")
+		if found {
+			candidates = append(candidates, localPart)
+		}
+
+		for _, candidate := range candidates {
+			if utf8.RuneCountInString(candidate) < minPersonalInfoLength {
+				continue
+			}
+
+			if strings.Contains(password, candidate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ValidatePassword validates that a given password satisfies a given policy.
+// Personal information, such as the email address and name of the password owner,
+// is used to reject passwords containing it.
+// When the list of breached passwords cannot be loaded, the password is not checked against it.
+func (v *Validator) ValidatePassword(field string, password string, policy *PasswordPolicy, personalInfo ...string) {
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		v.addFailure(field, "\"%s\" must be at least %d characters long", field, policy.MinLength)
+	}
+
+	if countCharacterClasses(password) < policy.MinCharacterClasses {
+		v.addFailure(
+			field,
+			"\"%s\" must contain at least %d of lowercase letters, uppercase letters, digits and symbols",
+			field,
+			policy.MinCharacterClasses,
+		)
+	}
+
+	if policy.RejectPersonalInfo && containsPersonalInfo(password, personalInfo) {
+		v.addFailure(field, "\"%s\" cannot contain email address or name", field)
+	}
+
+	if policy.RejectBreached {
+		breached, err := IsBreachedPassword(password)
+		if err == nil && breached {
+			v.addFailure(field, "\"%s\" is too common and has appeared in data breaches", field)
+		}
+	}
+}
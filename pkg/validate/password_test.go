@@ -0,0 +1,163 @@
+package validate_test
+
+import (
+	"testing"
+
+	"github.com/alvii147/flagger-api/pkg/validate"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsBreachedPassword(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name         string
+		password     string
+		wantBreached bool
+	}{
+		{
+			name:         "Common password",
+			password:     "password",
+			wantBreached: true,
+		},
+		{
+			name:         "Common password with different case",
+			password:     "PassWord123",
+			wantBreached: true,
+		},
+		{
+			name:         "Common password with suffix",
+			password:     "qwertyuiop123",
+			wantBreached: true,
+		},
+		{
+			name:         "Uncommon password",
+			password:     "Fl4gg3rD34dB33fC0ff33",
+			wantBreached: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			breached, err := validate.IsBreachedPassword(testcase.password)
+			require.NoError(t, err)
+			require.Equal(t, testcase.wantBreached, breached)
+		})
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	t.Parallel()
+
+	policy := &validate.PasswordPolicy{
+		MinLength:           12,
+		MinCharacterClasses: 3,
+		RejectPersonalInfo:  true,
+		RejectBreached:      true,
+	}
+
+	testcases := []struct {
+		name         string
+		password     string
+		policy       *validate.PasswordPolicy
+		personalInfo []string
+		wantFailures int
+	}{
+		{
+			name:         "Valid password",
+			password:     "Fl4gg3rD34dB33f",
+			policy:       policy,
+			personalInfo: []string{"john.doe@example.com", "John", "Doe"},
+			wantFailures: 0,
+		},
+		{
+			name:         "Too short",
+			password:     "D34dB33f",
+			policy:       policy,
+			personalInfo: nil,
+			wantFailures: 1,
+		},
+		{
+			name:         "Too few character classes",
+			password:     "deadbeefcoffee",
+			policy:       policy,
+			personalInfo: nil,
+			wantFailures: 1,
+		},
+		{
+			name:         "Symbols count as character class",
+			password:     "d34d-b33f-c0ff33",
+			policy:       policy,
+			personalInfo: nil,
+			wantFailures: 0,
+		},
+		{
+			name:         "Contains name",
+			password:     "JohnD34dB33f!",
+			policy:       policy,
+			personalInfo: []string{"john.doe@example.com", "John", "Doe"},
+			wantFailures: 1,
+		},
+		{
+			name:         "Contains local part of email",
+			password:     "J0hn.Doe.D34dB33f",
+			policy:       policy,
+			personalInfo: []string{"j0hn.doe@example.com", "Jon", "Do"},
+			wantFailures: 1,
+		},
+		{
+			name:         "Short personal info is ignored",
+			password:     "DoD34dB33f!!",
+			policy:       policy,
+			personalInfo: []string{"jd@example.com", "Jo", "Do"},
+			wantFailures: 0,
+		},
+		{
+			name:         "Breached password",
+			password:     "Password1234!",
+			policy:       policy,
+			personalInfo: nil,
+			wantFailures: 1,
+		},
+		{
+			name:         "Breached password with short and simple policy",
+			password:     "Password1234!",
+			policy:       &validate.PasswordPolicy{RejectBreached: true},
+			personalInfo: nil,
+			wantFailures: 1,
+		},
+		{
+			name:     "Every check disabled",
+			password: "john",
+			policy: &validate.PasswordPolicy{
+				MinLength:           0,
+				MinCharacterClasses: 0,
+				RejectPersonalInfo:  false,
+				RejectBreached:      false,
+			},
+			personalInfo: []string{"john.doe@example.com", "John", "Doe"},
+			wantFailures: 0,
+		},
+		{
+			name:         "Multiple failures",
+			password:     "dragon",
+			policy:       policy,
+			personalInfo: []string{"dragon@example.com", "Puff", "Dragon"},
+			wantFailures: 4,
+		},
+	}
+
+	field := "password"
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := validate.NewValidator()
+			v.ValidatePassword(field, testcase.password, testcase.policy, testcase.personalInfo...)
+			require.Equal(t, testcase.wantFailures == 0, v.Passed())
+			require.Len(t, v.Failures()[field], testcase.wantFailures)
+		})
+	}
+}